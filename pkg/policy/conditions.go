@@ -0,0 +1,482 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Conditions narrow a tool policy to calls whose input matches. A policy's
+// conditions are an object whose entries must all match:
+//
+//	{"command": "rm\\s+-rf"}                                  param: regex
+//	{"command": {"contains": "sudo", "starts_with": "git"}}   param: operators
+//	{"param_path": "edits[*].new_string", "operator": "contains", "value": "TODO"}
+//	{"any": [...]}, {"all": [...]}, {"not": {...}}            combinators
+//
+// Entries of "any" and "all", and the entry of "not", are condition objects
+// themselves. Param names are paths into the tool input: "a.b" reads a
+// nested field, "a[0]" an array element and "a[*]" every element. A
+// condition on a path holding several values matches if any value does.
+// Missing params never match, except {"exists": false}.
+//
+// Reserved keys such as "rate_limit" or "schedule" configure the policy and
+// can sit next to any of these. The API rejects conditions that don't
+// compile when they are saved; the proxy compiles them once, when policies
+// load, and a policy whose conditions don't compile never matches.
+
+// reservedConditions are keys of a policy's conditions that configure the
+// policy rather than match tool input.
+var reservedConditions = map[string]bool{
+	"rate_limit":     true,
+	"allowed_models": true,
+	"fallback_model": true,
+	"allowed_paths":  true,
+	"schedule":       true,
+}
+
+// IsReserved reports whether a key of a policy's conditions configures the
+// policy rather than matching tool input.
+func IsReserved(key string) bool {
+	return reservedConditions[key]
+}
+
+// maxConditionDepth limits how deeply combinators can nest.
+const maxConditionDepth = 10
+
+// Condition is a compiled condition object, ready to match tool input.
+type Condition interface {
+	Match(input map[string]interface{}) bool
+}
+
+// allOf matches when every condition does; anyOf when one does.
+type allOf []Condition
+type anyOf []Condition
+
+// notOf matches when its condition doesn't.
+type notOf struct{ Condition }
+
+// paramCondition matches when any value at a param path satisfies test.
+// With exists set, it matches on whether the path has values at all.
+type paramCondition struct {
+	path   []pathStep
+	test   func(value interface{}) bool
+	exists *bool
+}
+
+// never is the condition of a policy whose conditions don't compile.
+type never struct{}
+
+func (c allOf) Match(input map[string]interface{}) bool {
+	for _, cond := range c {
+		if !cond.Match(input) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c anyOf) Match(input map[string]interface{}) bool {
+	for _, cond := range c {
+		if cond.Match(input) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c notOf) Match(input map[string]interface{}) bool {
+	return !c.Condition.Match(input)
+}
+
+func (c paramCondition) Match(input map[string]interface{}) bool {
+	values := lookupPath(input, c.path)
+	if c.exists != nil {
+		return (len(values) > 0) == *c.exists
+	}
+	for _, value := range values {
+		if c.test(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (never) Match(map[string]interface{}) bool { return false }
+
+// Compile compiles a policy's conditions, leaving out reserved keys. It
+// returns nil when they have nothing to match, such as conditions holding
+// only a rate limit. Errors name the entry at fault, as in
+// "conditions.any[0].command: invalid regex".
+func Compile(conditions map[string]interface{}) (Condition, error) {
+	match := make(map[string]interface{}, len(conditions))
+	for key, raw := range conditions {
+		if !reservedConditions[key] {
+			match[key] = raw
+		}
+	}
+	if len(match) == 0 {
+		return nil, nil
+	}
+
+	// Conditions built in Go, or read from YAML, can hold ints and typed
+	// slices; the grammar is defined on JSON values
+	data, err := json.Marshal(match)
+	if err != nil {
+		return nil, fmt.Errorf("conditions: %v", err)
+	}
+	var expr map[string]interface{}
+	if err := json.Unmarshal(data, &expr); err != nil {
+		return nil, fmt.Errorf("conditions: %v", err)
+	}
+	return compileExpression("conditions", expr, 0)
+}
+
+// Validate checks a policy's conditions as the API does before saving them:
+// the schedule, if any, must parse and the rest must compile.
+func Validate(conditions map[string]interface{}) error {
+	if _, err := ParseSchedule(conditions); err != nil {
+		return err
+	}
+	_, err := Compile(conditions)
+	return err
+}
+
+// CompileOrNever compiles a policy's conditions; conditions that don't compile
+// never match.
+func CompileOrNever(conditions map[string]interface{}) Condition {
+	cond, err := Compile(conditions)
+	if err != nil {
+		return never{}
+	}
+	return cond
+}
+
+// Matches reports whether tool input matches a policy's conditions,
+// compiling them first. Evaluation of loaded policies uses their compiled
+// conditions instead.
+func Matches(input map[string]interface{}, conditions map[string]interface{}) bool {
+	cond, err := Compile(conditions)
+	return err == nil && (cond == nil || cond.Match(input))
+}
+
+// compileExpression compiles a condition object: a param_path leaf or
+// entries that must all match.
+func compileExpression(at string, expr interface{}, depth int) (Condition, error) {
+	if depth > maxConditionDepth {
+		return nil, fmt.Errorf("%s: conditions nest more than %d levels deep", at, maxConditionDepth)
+	}
+	obj, ok := expr.(map[string]interface{})
+	if !ok || len(obj) == 0 {
+		return nil, fmt.Errorf("%s must be a condition object", at)
+	}
+
+	if raw, ok := obj["param_path"]; ok {
+		param, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s.param_path: invalid path %v", at, raw)
+		}
+		for key := range obj {
+			if key != "param_path" && key != "operator" && key != "value" {
+				return nil, fmt.Errorf("%s: unexpected %q next to param_path", at, key)
+			}
+		}
+		path, ok := parsePath(param)
+		if !ok {
+			return nil, fmt.Errorf("%s.param_path: invalid path %v", at, raw)
+		}
+		operator, _ := obj["operator"].(string)
+		return compileParam(at, path, operator, obj["value"])
+	}
+
+	all := make(allOf, 0, len(obj))
+	for key, raw := range obj {
+		cond, err := compileEntry(at, key, raw, depth)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, cond)
+	}
+	return all, nil
+}
+
+// compileEntry compiles one entry of a condition object.
+func compileEntry(at, key string, raw interface{}, depth int) (Condition, error) {
+	switch key {
+	case "all", "any":
+		list, ok := raw.([]interface{})
+		if !ok || len(list) == 0 {
+			return nil, fmt.Errorf("%s.%s must be a non-empty list of conditions", at, key)
+		}
+		conds := make([]Condition, 0, len(list))
+		for i, expr := range list {
+			cond, err := compileExpression(fmt.Sprintf("%s.%s[%d]", at, key, i), expr, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			conds = append(conds, cond)
+		}
+		if key == "all" {
+			return allOf(conds), nil
+		}
+		return anyOf(conds), nil
+	case "not":
+		cond, err := compileExpression(at+".not", raw, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		return notOf{cond}, nil
+	}
+
+	at += "." + key
+	path, ok := parsePath(key)
+	if !ok {
+		return nil, fmt.Errorf("%s: invalid param path %q", at, key)
+	}
+	switch c := raw.(type) {
+	case string:
+		return compileParam(at, path, "matches", c)
+	case map[string]interface{}:
+		if len(c) == 0 {
+			return nil, fmt.Errorf("%s must set an operator", at)
+		}
+		all := make(allOf, 0, len(c))
+		for operator, expected := range c {
+			cond, err := compileParam(at, path, operator, expected)
+			if err != nil {
+				return nil, err
+			}
+			all = append(all, cond)
+		}
+		return all, nil
+	}
+	return nil, fmt.Errorf("%s must be a regex or an object of operators", at)
+}
+
+// compileParam compiles an operator applied to a param path.
+func compileParam(at string, path []pathStep, operator string, expected interface{}) (Condition, error) {
+	if operator == "exists" {
+		want, ok := expected.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s: exists needs true or false", at)
+		}
+		return paramCondition{path: path, exists: &want}, nil
+	}
+
+	test, err := compileOperator(at, operator, expected)
+	if err != nil {
+		return nil, err
+	}
+	return paramCondition{path: path, test: test}, nil
+}
+
+// compileOperator returns a test applying an operator to one value from the
+// tool input. Regexes and shell conditions are parsed here, once.
+func compileOperator(at, operator string, expected interface{}) (func(value interface{}) bool, error) {
+	switch operator {
+	case "matches":
+		pattern, ok := expected.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: matches needs a string", at)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid regex %q: %v", at, pattern, err)
+		}
+		return func(v interface{}) bool { return re.MatchString(valueString(v)) }, nil
+	case "contains", "starts_with", "ends_with":
+		s, ok := expected.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: %s needs a string", at, operator)
+		}
+		test := map[string]func(string, string) bool{
+			"contains":    strings.Contains,
+			"starts_with": strings.HasPrefix,
+			"ends_with":   strings.HasSuffix,
+		}[operator]
+		return func(v interface{}) bool { return test(valueString(v), s) }, nil
+	case "equals":
+		if !isScalar(expected) {
+			return nil, fmt.Errorf("%s: equals needs a string, number or boolean", at)
+		}
+		return func(v interface{}) bool { return equalValues(v, expected) }, nil
+	case "in":
+		list, ok := expected.([]interface{})
+		if !ok || len(list) == 0 {
+			return nil, fmt.Errorf("%s: in needs a non-empty list", at)
+		}
+		for _, e := range list {
+			if !isScalar(e) {
+				return nil, fmt.Errorf("%s: in needs a list of strings, numbers or booleans", at)
+			}
+		}
+		return func(v interface{}) bool {
+			for _, e := range list {
+				if equalValues(v, e) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case "gt", "gte", "lt", "lte":
+		limit, ok := expected.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%s: %s needs a number", at, operator)
+		}
+		compare := map[string]func(float64) bool{
+			"gt":  func(n float64) bool { return n > limit },
+			"gte": func(n float64) bool { return n >= limit },
+			"lt":  func(n float64) bool { return n < limit },
+			"lte": func(n float64) bool { return n <= limit },
+		}[operator]
+		return func(v interface{}) bool {
+			n, ok := toNumber(v)
+			return ok && compare(n)
+		}, nil
+	case "shell":
+		cond, err := parseShellCondition(expected)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", at, err)
+		}
+		return func(v interface{}) bool { return cond.matchesShell(valueString(v)) }, nil
+	case "":
+		return nil, fmt.Errorf("%s: operator is required", at)
+	}
+	return nil, fmt.Errorf("%s: unknown operator %q", at, operator)
+}
+
+// isScalar reports whether a JSON value is a string, number or boolean.
+func isScalar(value interface{}) bool {
+	switch value.(type) {
+	case string, float64, bool:
+		return true
+	}
+	return false
+}
+
+// valueString converts a value from the tool input to the string operators
+// match against. Arrays and objects become their JSON.
+func valueString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64, bool:
+		return fmt.Sprintf("%v", v)
+	case nil:
+		return "null"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// equalValues compares numbers numerically, so 10 equals "10" and 1e1, and
+// anything else by its string form.
+func equalValues(value, expected interface{}) bool {
+	if a, ok := toNumber(value); ok {
+		if b, ok := toNumber(expected); ok {
+			return a == b
+		}
+	}
+	return valueString(value) == valueString(expected)
+}
+
+// toNumber converts a JSON number, or a string holding one, to a float.
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// pathStep is one step of a param path: a field name, an array index, or
+// every array element (index -1).
+type pathStep struct {
+	field string
+	index int
+	isIdx bool
+}
+
+// parsePath splits a param path such as "edits[*].old_string" into steps.
+func parsePath(p string) ([]pathStep, bool) {
+	if p == "" {
+		return nil, false
+	}
+
+	var steps []pathStep
+	for _, segment := range strings.Split(p, ".") {
+		name, rest, bracket := strings.Cut(segment, "[")
+		if name == "" {
+			return nil, false // As in "a..b" or "[0]"; every segment starts with a field
+		}
+		steps = append(steps, pathStep{field: name})
+		if bracket && rest == "" {
+			return nil, false
+		}
+
+		for rest != "" {
+			index, after, ok := strings.Cut(rest, "]")
+			if !ok {
+				return nil, false
+			}
+			step := pathStep{index: -1, isIdx: true}
+			if index != "*" {
+				n, err := strconv.Atoi(index)
+				if err != nil || n < 0 {
+					return nil, false
+				}
+				step.index = n
+			}
+			steps = append(steps, step)
+
+			if after == "" {
+				break
+			}
+			if after[0] != '[' {
+				return nil, false
+			}
+			rest = after[1:]
+		}
+	}
+	return steps, true
+}
+
+// lookupPath returns the values a parsed param path reads from the tool
+// input.
+func lookupPath(input map[string]interface{}, steps []pathStep) []interface{} {
+	values := []interface{}{input}
+	for _, step := range steps {
+		var next []interface{}
+		for _, value := range values {
+			if !step.isIdx {
+				if obj, ok := value.(map[string]interface{}); ok {
+					if v, ok := obj[step.field]; ok {
+						next = append(next, v)
+					}
+				}
+				continue
+			}
+			list, ok := value.([]interface{})
+			if !ok {
+				continue
+			}
+			if step.index < 0 {
+				next = append(next, list...)
+			} else if step.index < len(list) {
+				next = append(next, list[step.index])
+			}
+		}
+		values = next
+	}
+	return values
+}
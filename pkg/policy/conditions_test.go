@@ -0,0 +1,129 @@
+package policy
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func conditionInput(t *testing.T, s string) map[string]interface{} {
+	t.Helper()
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(s), &m))
+	return m
+}
+
+func TestMatches(t *testing.T) {
+	input := `{
+		"command": "git push --force origin main",
+		"timeout": 600000,
+		"run_in_background": true,
+		"edits": [{"old_string": "a", "new_string": "TODO: fix"}, {"old_string": "b", "new_string": "done"}],
+		"options": {"env": {"AWS_PROFILE": "prod"}}
+	}`
+
+	tests := []struct {
+		name       string
+		conditions string
+		want       bool
+	}{
+		{"regex shorthand", `{"command": "push\\s+--force"}`, true},
+		{"regex shorthand mismatch", `{"command": "^rm"}`, false},
+		{"operator shorthand", `{"command": {"starts_with": "git", "contains": "--force"}}`, true},
+		{"all operators must match", `{"command": {"starts_with": "git", "ends_with": "develop"}}`, false},
+		{"missing param", `{"description": ".*"}`, false},
+		{"leaf", `{"param_path": "command", "operator": "contains", "value": "--force"}`, true},
+		{"any", `{"any": [{"param_path": "command", "operator": "equals", "value": "ls"}, {"param_path": "command", "operator": "matches", "value": "force"}]}`, true},
+		{"any without a match", `{"any": [{"param_path": "command", "operator": "equals", "value": "ls"}]}`, false},
+		{"empty any", `{"any": []}`, false},
+		{"all", `{"all": [{"command": {"contains": "push"}}, {"param_path": "timeout", "operator": "gt", "value": 60000}]}`, true},
+		{"not", `{"not": {"command": {"contains": "--force-with-lease"}}}`, true},
+		{"nested", `{"any": [{"not": {"run_in_background": {"equals": true}}}, {"all": [{"timeout": {"gte": 600000}}, {"timeout": {"lte": 600000}}]}]}`, true},
+		{"nested path", `{"options.env.AWS_PROFILE": {"in": ["prod", "production"]}}`, true},
+		{"array index", `{"edits[1].new_string": {"equals": "done"}}`, true},
+		{"array wildcard", `{"param_path": "edits[*].new_string", "operator": "starts_with", "value": "TODO"}`, true},
+		{"not over a wildcard", `{"not": {"edits[*].old_string": {"equals": "b"}}}`, false},
+		{"index out of range", `{"edits[5].new_string": ".*"}`, false},
+		{"numeric equals", `{"timeout": {"equals": 6e5}}`, true},
+		{"numeric string limit", `{"timeout": {"lt": "1000000"}}`, false},
+		{"numeric on a string", `{"command": {"gt": 1}}`, false},
+		{"bool equals", `{"run_in_background": {"equals": true}}`, true},
+		{"exists", `{"options.env": {"exists": true}, "description": {"exists": false}}`, true},
+		{"shell", `{"command": {"shell": {"command": "git", "args": ["push"], "flags": ["-f|--force"]}}}`, true},
+		{"unknown operator", `{"command": {"regex": ".*"}}`, false},
+		{"invalid path", `{"edits[x]": ".*"}`, false},
+		{"reserved keys are ignored", `{"rate_limit": {"max_calls": 1}, "allowed_paths": ["**"]}`, true},
+		{"reserved keys next to a leaf", `{"param_path": "command", "operator": "contains", "value": "push", "schedule": {"windows": []}}`, true},
+	}
+
+	in := conditionInput(t, input)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Matches(in, conditionInput(t, tt.conditions)))
+		})
+	}
+}
+
+func TestCompile(t *testing.T) {
+	cond, err := Compile(map[string]interface{}{"rate_limit": map[string]interface{}{"max_calls": 5}})
+	require.NoError(t, err)
+	assert.Nil(t, cond, "a rate limit isn't a condition")
+
+	// Conditions built in Go compile as their JSON would
+	cond, err = Compile(map[string]interface{}{
+		"timeout": map[string]interface{}{"gt": 60000},
+		"command": map[string]interface{}{"shell": map[string]interface{}{"command": "git", "args": []string{"push"}}},
+	})
+	require.NoError(t, err)
+	assert.True(t, cond.Match(map[string]interface{}{"timeout": 600000.0, "command": "git push"}))
+
+	assert.Equal(t, never{}, CompileOrNever(map[string]interface{}{"command": "(["}))
+}
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions string
+		wantError  string
+	}{
+		{"unknown operator", `{"command": {"regex": "rm"}}`, `conditions.command: unknown operator "regex"`},
+		{"missing operator", `{"any": [{"param_path": "command", "value": "rm"}]}`, "conditions.any[0]: operator is required"},
+		{"number for a string operator", `{"command": {"contains": 5}}`, "contains needs a string"},
+		{"string for a number operator", `{"timeout": {"gt": "60000"}}`, "gt needs a number"},
+		{"empty any", `{"any": []}`, "conditions.any must be a non-empty list"},
+		{"not a list", `{"all": {"command": "rm"}}`, "conditions.all must be a non-empty list"},
+		{"invalid path", `{"edits[x].old_string": "TODO"}`, "invalid param path"},
+		{"nested error", `{"not": {"any": [{"mode": {"in": "safe"}}]}}`, "conditions.not.any[0].mode: in needs a non-empty list"},
+		{"invalid regex", `{"command": "rm\\s+(-rf"}`, `conditions.command: invalid regex "rm\\s+(-rf": error parsing regexp: missing closing )`},
+		{"invalid regex in a leaf", `{"any": [{"param_path": "url", "operator": "matches", "value": "*.internal"}]}`, "conditions.any[0]: invalid regex"},
+		{"unexpected key in a leaf", `{"any": [{"param_path": "url", "operator": "contains", "value": "x", "schedule": {}}]}`, `unexpected "schedule" next to param_path`},
+		{"object for equals", `{"mode": {"equals": {"a": 1}}}`, "equals needs a string, number or boolean"},
+		{"shell without command", `{"command": {"shell": {"flags": ["-f"]}}}`, "shell needs a command"},
+		{"unknown shell field", `{"command": {"shell": {"command": "git", "subcommand": "push"}}}`, `unknown shell field "subcommand"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile(conditionInput(t, tt.conditions))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantError)
+		})
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	steps, ok := parsePath("edits[*].old_string")
+	require.True(t, ok)
+	assert.Equal(t, []pathStep{{field: "edits"}, {index: -1, isIdx: true}, {field: "old_string"}}, steps)
+
+	steps, ok = parsePath("matrix[0][2]")
+	require.True(t, ok)
+	assert.Equal(t, []pathStep{{field: "matrix"}, {index: 0, isIdx: true}, {index: 2, isIdx: true}}, steps)
+
+	for _, invalid := range []string{"", "a..b", "[0]", "a.[0]", "a[", "a[-1]", "a[0]b", "a[x]"} {
+		_, ok := parsePath(invalid)
+		assert.False(t, ok, invalid)
+	}
+}
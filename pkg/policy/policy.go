@@ -0,0 +1,16 @@
+// Package policy evaluates tool policies the same way wherever they are
+// evaluated: the proxy enforces them as tool calls stream by
+// (services/cli/internal/control), and the API validates them when they are
+// saved and replays stored tool calls against them when simulating.
+package policy
+
+import "strings"
+
+// MatchesToolName reports whether a policy's tool name matches, ignoring case.
+// A name ending in % matches any tool starting with the prefix.
+func MatchesToolName(pattern, toolName string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "%"); ok {
+		return strings.HasPrefix(strings.ToLower(toolName), strings.ToLower(prefix))
+	}
+	return strings.EqualFold(pattern, toolName)
+}
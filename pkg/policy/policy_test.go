@@ -0,0 +1,15 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchesToolName(t *testing.T) {
+	assert.True(t, MatchesToolName("Bash", "bash"))
+	assert.True(t, MatchesToolName("mcp__github__%", "MCP__GitHub__create_issue"))
+	assert.True(t, MatchesToolName("%", "Read"))
+	assert.False(t, MatchesToolName("mcp__github__%", "mcp__gitlab__create_issue"))
+	assert.False(t, MatchesToolName("Bash", "BashOutput"))
+}
@@ -0,0 +1,66 @@
+package policy
+
+import (
+	"math"
+	"strings"
+)
+
+// Tool policies are set for the organization, a team or one employee. An
+// allow policy exempts the tool calls it matches from the deny,
+// require_approval and rate_limit policies it outranks, so a team can be let
+// through an organization-wide block. Policies are ranked by:
+//
+//  1. scope: employee over team over organization
+//  2. tool name: an exact name over a % prefix, a longer prefix over a shorter one
+//  3. conditions: a policy with conditions over one without
+//
+// A restriction that ties with an allow policy still applies. The API sorts
+// the policies it sends to the proxy by this ranking, and the proxy and the
+// API's simulation resolve tool calls with it.
+
+// Scopes a policy can be set for.
+const (
+	ScopeOrganization = "organization"
+	ScopeTeam         = "team"
+	ScopeEmployee     = "employee"
+)
+
+// ScopeRank ranks scopes from organization (1) to employee (3).
+func ScopeRank(scope string) int {
+	switch scope {
+	case ScopeEmployee:
+		return 3
+	case ScopeTeam:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// ToolSpecificity ranks how narrowly a tool name pattern matches: an exact
+// name above any prefix, and longer prefixes above shorter ones.
+func ToolSpecificity(pattern string) int {
+	if prefix, ok := strings.CutSuffix(pattern, "%"); ok {
+		return len(prefix)
+	}
+	return math.MaxInt
+}
+
+// Rank is what a tool policy is ranked by.
+type Rank struct {
+	Scope       string // ScopeOrganization, ScopeTeam or ScopeEmployee
+	ToolName    string // Tool name pattern
+	Conditional bool   // Has conditions on the tool input, not only reserved keys
+}
+
+// Outranks reports whether a policy ranked r takes precedence over one ranked
+// other. Neither outranks the other when they tie.
+func (r Rank) Outranks(other Rank) bool {
+	if a, b := ScopeRank(r.Scope), ScopeRank(other.Scope); a != b {
+		return a > b
+	}
+	if a, b := ToolSpecificity(r.ToolName), ToolSpecificity(other.ToolName); a != b {
+		return a > b
+	}
+	return r.Conditional && !other.Conditional
+}
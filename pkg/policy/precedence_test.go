@@ -0,0 +1,35 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRank_Outranks(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Rank
+		want bool
+	}{
+		{"employee over team", Rank{Scope: ScopeEmployee, ToolName: "%"}, Rank{Scope: ScopeTeam, ToolName: "Bash"}, true},
+		{"team over organization", Rank{Scope: ScopeTeam, ToolName: "%"}, Rank{Scope: ScopeOrganization, ToolName: "Bash"}, true},
+		{"scope comes before the tool name", Rank{Scope: ScopeOrganization, ToolName: "Bash"}, Rank{Scope: ScopeTeam, ToolName: "%"}, false},
+		{"exact name over a prefix", Rank{ToolName: "Bash"}, Rank{ToolName: "Ba%"}, true},
+		{"longer prefix over a shorter one", Rank{ToolName: "mcp__github__%"}, Rank{ToolName: "mcp__%"}, true},
+		{"conditions over none", Rank{ToolName: "Bash", Conditional: true}, Rank{ToolName: "Bash"}, true},
+		{"ties", Rank{ToolName: "Bash"}, Rank{ToolName: "Bash"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.a.Outranks(tt.b))
+		})
+	}
+}
+
+func TestScopeRank(t *testing.T) {
+	assert.Equal(t, 3, ScopeRank(ScopeEmployee))
+	assert.Equal(t, 2, ScopeRank(ScopeTeam))
+	assert.Equal(t, 1, ScopeRank(ScopeOrganization))
+	assert.Equal(t, 1, ScopeRank(""), "policies without a scope are the organization's")
+}
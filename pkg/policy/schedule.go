@@ -0,0 +1,276 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A policy's conditions can hold a "schedule" limiting when it applies:
+//
+//	{"schedule": {
+//	    "active_from": "2026-12-18T00:00:00Z",
+//	    "active_until": "2027-01-04T00:00:00Z",
+//	    "windows": [{"start": "0 16 * * 5", "end": "0 8 * * 1"}]
+//	}}
+//
+// active_from and active_until bound when it applies at all; windows, if any,
+// narrow that to recurring periods, each open from a match of its start cron
+// expression to the next match of its end, read in the organization's
+// timezone. A schedule can sit next to any conditions on the tool input.
+//
+// The API only returns active policies from GET /employees/me/tool-policies,
+// but sends the proxy every policy with the organization's timezone: the proxy
+// applies schedules itself, so they start and stop on time while it's
+// disconnected.
+
+// scheduleHorizon is how far ahead a cron expression is searched for its next
+// match. Every valid expression matches within it (February 29th included).
+const scheduleHorizon = 5 * 366 * 24 * time.Hour
+
+// Schedule limits when a tool policy applies.
+type Schedule struct {
+	ActiveFrom  *time.Time `json:"active_from,omitempty"`
+	ActiveUntil *time.Time `json:"active_until,omitempty"`
+	Windows     []Window   `json:"windows,omitempty"`
+}
+
+// Window is a recurring period, from each match of Start to the following
+// match of End.
+type Window struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+
+	start, end cronSchedule
+}
+
+// ParseSchedule reads and validates the schedule of a policy's conditions. It
+// returns nil when the conditions have no schedule.
+func ParseSchedule(conditions map[string]interface{}) (*Schedule, error) {
+	raw, ok := conditions["schedule"]
+	if !ok {
+		return nil, nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule")
+	}
+
+	var schedule Schedule
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&schedule); err != nil {
+		return nil, fmt.Errorf("invalid schedule: %v", err)
+	}
+
+	if schedule.ActiveFrom == nil && schedule.ActiveUntil == nil && len(schedule.Windows) == 0 {
+		return nil, fmt.Errorf("schedule needs active_from, active_until or windows")
+	}
+	if schedule.ActiveFrom != nil && schedule.ActiveUntil != nil && !schedule.ActiveFrom.Before(*schedule.ActiveUntil) {
+		return nil, fmt.Errorf("schedule.active_until must be after active_from")
+	}
+	for i := range schedule.Windows {
+		w := &schedule.Windows[i]
+		if w.start, err = parseCron(w.Start); err != nil {
+			return nil, fmt.Errorf("schedule.windows[%d].start: %v", i, err)
+		}
+		if w.end, err = parseCron(w.End); err != nil {
+			return nil, fmt.Errorf("schedule.windows[%d].end: %v", i, err)
+		}
+	}
+	return &schedule, nil
+}
+
+// ActiveAt reports whether the schedule has its policy apply at t, reading
+// windows in loc.
+func (s *Schedule) ActiveAt(t time.Time, loc *time.Location) bool {
+	if s.ActiveFrom != nil && t.Before(*s.ActiveFrom) {
+		return false
+	}
+	if s.ActiveUntil != nil && !t.Before(*s.ActiveUntil) {
+		return false
+	}
+	if len(s.Windows) == 0 {
+		return true
+	}
+
+	local := t.In(loc)
+	for _, w := range s.Windows {
+		if w.openAt(local) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextChange returns the first time after t the schedule may start or stop
+// having its policy apply, reading windows in loc, or false if it never will.
+func (s *Schedule) NextChange(t time.Time, loc *time.Location) (time.Time, bool) {
+	var next time.Time
+	consider := func(at time.Time) {
+		if at.After(t) && (next.IsZero() || at.Before(next)) {
+			next = at
+		}
+	}
+
+	if s.ActiveFrom != nil {
+		consider(*s.ActiveFrom)
+	}
+	if s.ActiveUntil != nil {
+		consider(*s.ActiveUntil)
+	}
+	local := t.In(loc)
+	for _, w := range s.Windows {
+		if opens, ok := w.start.next(local); ok {
+			consider(opens)
+		}
+		if closes, ok := w.end.next(local); ok {
+			consider(closes)
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// openAt reports whether the window is open at t: it is when the window next
+// closes before it next opens. At the minute it opens it is open; at the
+// minute it closes it is not.
+func (w Window) openAt(t time.Time) bool {
+	closes, ok := w.end.next(t)
+	if !ok {
+		return false
+	}
+	opens, ok := w.start.next(t)
+	return !ok || closes.Before(opens)
+}
+
+// cronSchedule is a parsed five-field cron expression: minute, hour, day of
+// month, month and day of week, each a bit set of the values it matches.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// With both day fields restricted, a day matching either one matches
+	// (as in cron); otherwise a day must match both
+	domAny, dowAny bool
+}
+
+// parseCron parses a five-field cron expression. Fields take *, numbers,
+// ranges (1-5), lists (1,3,5) and steps (*/15, 0-30/10). Days of the week run
+// from 0 (Sunday) to 6, with 7 also Sunday.
+func parseCron(spec string) (cronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return cronSchedule{}, fmt.Errorf("cron expression %q must have 5 fields: minute hour day-of-month month day-of-week", spec)
+	}
+
+	var c cronSchedule
+	var err error
+	if c.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return cronSchedule{}, fmt.Errorf("minute: %v", err)
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return cronSchedule{}, fmt.Errorf("hour: %v", err)
+	}
+	if c.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return cronSchedule{}, fmt.Errorf("day of month: %v", err)
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return cronSchedule{}, fmt.Errorf("month: %v", err)
+	}
+	if c.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return cronSchedule{}, fmt.Errorf("day of week: %v", err)
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domAny = strings.HasPrefix(fields[2], "*")
+	c.dowAny = strings.HasPrefix(fields[4], "*")
+
+	// Rule out days that never come, such as February 30th
+	if _, ok := c.next(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)); !ok {
+		return cronSchedule{}, fmt.Errorf("cron expression %q never matches", spec)
+	}
+	return c, nil
+}
+
+// parseCronField parses one field into the set of values it matches.
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		values, step, hasStep := strings.Cut(part, "/")
+		every := 1
+		if hasStep {
+			n, err := strconv.Atoi(step)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step %q", step)
+			}
+			every = n
+		}
+
+		lo, hi := min, max
+		switch {
+		case values == "*":
+		case strings.Contains(values, "-"):
+			from, to, _ := strings.Cut(values, "-")
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(from)
+			hi, err2 = strconv.Atoi(to)
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("invalid range %q", values)
+			}
+		default:
+			n, err := strconv.Atoi(values)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", values)
+			}
+			lo = n
+			if !hasStep {
+				hi = n
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is outside %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += every {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// next returns the first minute after t that the expression matches, in t's
+// location, or false if there is none within the horizon.
+func (c cronSchedule) next(t time.Time) (time.Time, bool) {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(scheduleHorizon)
+
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = t.Add(time.Hour - time.Duration(t.Minute())*time.Minute)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// dayMatches reports whether t's day matches the day of month and day of
+// week fields.
+func (c cronSchedule) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
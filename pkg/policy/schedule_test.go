@@ -0,0 +1,190 @@
+package policy
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mustParseSchedule parses a schedule given as JSON.
+func mustParseSchedule(t *testing.T, raw string) *Schedule {
+	t.Helper()
+	var conditions map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{"schedule": `+raw+`}`), &conditions))
+	schedule, err := ParseSchedule(conditions)
+	require.NoError(t, err)
+	return schedule
+}
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule string
+	}{
+		{"empty", `{}`},
+		{"not an object", `"weekends"`},
+		{"unknown field", `{"windows": [], "timezone": "UTC"}`},
+		{"bad timestamp", `{"active_from": "tomorrow"}`},
+		{"ends before it starts", `{"active_from": "2026-02-01T00:00:00Z", "active_until": "2026-01-01T00:00:00Z"}`},
+		{"four fields", `{"windows": [{"start": "0 9 * *", "end": "0 17 * * *"}]}`},
+		{"hour out of range", `{"windows": [{"start": "0 24 * * *", "end": "0 17 * * *"}]}`},
+		{"bad step", `{"windows": [{"start": "*/0 9 * * *", "end": "0 17 * * *"}]}`},
+		{"reversed range", `{"windows": [{"start": "0 9 * * 5-1", "end": "0 17 * * *"}]}`},
+		{"never matches", `{"windows": [{"start": "0 9 30 2 *", "end": "0 17 * * *"}]}`},
+		{"missing end", `{"windows": [{"start": "0 9 * * *"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var conditions map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(`{"schedule": `+tt.schedule+`}`), &conditions))
+			_, err := ParseSchedule(conditions)
+			assert.Error(t, err)
+		})
+	}
+
+	schedule, err := ParseSchedule(map[string]interface{}{"command": "deploy"})
+	assert.NoError(t, err)
+	assert.Nil(t, schedule)
+}
+
+func TestSchedule_ActiveAt(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	freeze := mustParseSchedule(t, `{"windows": [{"start": "0 16 * * 5", "end": "0 8 * * 1"}]}`)
+	business := mustParseSchedule(t, `{"windows": [{"start": "0 9 * * 1-5", "end": "0 17 * * 1-5"}]}`)
+	bounded := mustParseSchedule(t, `{"active_from": "2026-12-18T00:00:00Z", "active_until": "2027-01-04T00:00:00Z"}`)
+	boundedFreeze := mustParseSchedule(t, `{
+		"active_from": "2026-12-01T00:00:00Z",
+		"windows": [{"start": "0 16 * * 5", "end": "0 8 * * 1"}]
+	}`)
+
+	// 2026-10-16 is a Friday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 10, day, hour, minute, 0, 0, berlin)
+	}
+	tests := []struct {
+		name     string
+		schedule *Schedule
+		t        time.Time
+		want     bool
+	}{
+		{"freeze: Friday before 16:00", freeze, at(16, 15, 59), false},
+		{"freeze: Friday at 16:00", freeze, at(16, 16, 0), true},
+		{"freeze: Saturday", freeze, at(17, 12, 0), true},
+		{"freeze: Monday before 08:00", freeze, at(19, 7, 59), true},
+		{"freeze: Monday at 08:00", freeze, at(19, 8, 0), false},
+		{"freeze: Wednesday", freeze, at(21, 12, 0), false},
+		{"business: Tuesday morning", business, at(20, 10, 30), true},
+		{"business: Tuesday night", business, at(20, 22, 0), false},
+		{"business: Saturday", business, at(17, 10, 30), false},
+		{"bounded: before", bounded, time.Date(2026, 12, 17, 23, 59, 0, 0, time.UTC), false},
+		{"bounded: from", bounded, time.Date(2026, 12, 18, 0, 0, 0, 0, time.UTC), true},
+		{"bounded: until", bounded, time.Date(2027, 1, 4, 0, 0, 0, 0, time.UTC), false},
+		{"bounded freeze: window before active_from", boundedFreeze, at(17, 12, 0), false},
+		{"bounded freeze: window after active_from", boundedFreeze, time.Date(2026, 12, 5, 12, 0, 0, 0, berlin), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.schedule.ActiveAt(tt.t, berlin))
+		})
+	}
+
+	// Windows are read in the organization's timezone: 16:30 UTC on a Friday
+	// is 18:30 in Berlin, but 09:30 in Los Angeles.
+	la, err := time.LoadLocation("America/Los_Angeles")
+	require.NoError(t, err)
+	friday := time.Date(2026, 10, 16, 16, 30, 0, 0, time.UTC)
+	assert.True(t, freeze.ActiveAt(friday, berlin))
+	assert.False(t, freeze.ActiveAt(friday, la))
+}
+
+func TestSchedule_NextChange(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	freeze := mustParseSchedule(t, `{"active_until": "2026-10-20T00:00:00Z", "windows": [{"start": "0 16 * * 5", "end": "0 8 * * 1"}]}`)
+
+	// Thursday: the freeze starts Friday at 16:00 Berlin time
+	next, ok := freeze.NextChange(time.Date(2026, 10, 15, 12, 0, 0, 0, berlin), berlin)
+	require.True(t, ok)
+	assert.True(t, next.Equal(time.Date(2026, 10, 16, 16, 0, 0, 0, berlin)))
+
+	// Saturday: it ends Monday at 08:00
+	next, ok = freeze.NextChange(time.Date(2026, 10, 17, 12, 0, 0, 0, berlin), berlin)
+	require.True(t, ok)
+	assert.True(t, next.Equal(time.Date(2026, 10, 19, 8, 0, 0, 0, berlin)))
+
+	bounded := mustParseSchedule(t, `{"active_from": "2026-12-18T00:00:00Z", "active_until": "2027-01-04T00:00:00Z"}`)
+	_, ok = bounded.NextChange(time.Date(2027, 2, 1, 0, 0, 0, 0, time.UTC), time.UTC)
+	assert.False(t, ok, "a past bounded schedule never changes again")
+}
+
+func TestCronSchedule_Next(t *testing.T) {
+	tests := []struct {
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2026, 10, 16, 10, 7, 30, 0, time.UTC), time.Date(2026, 10, 16, 10, 15, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC), time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)},
+		{"30 8 1 * *", time.Date(2026, 12, 15, 0, 0, 0, 0, time.UTC), time.Date(2027, 1, 1, 8, 30, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: either matches.
+		{"0 0 1 * 1", time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			c, err := parseCron(tt.spec)
+			require.NoError(t, err)
+			got, ok := c.next(tt.from)
+			require.True(t, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidate_ScheduleNextToConditions(t *testing.T) {
+	schedule := `"schedule": {"windows": [{"start": "0 16 * * 5", "end": "0 8 * * 1"}]}`
+	tests := []struct {
+		name       string
+		conditions string
+		input      map[string]interface{}
+		want       bool
+	}{
+		{"param", `{"command": "^deploy", ` + schedule + `}`, map[string]interface{}{"command": "deploy prod"}, true},
+		{"leaf", `{"param_path": "command", "operator": "contains", "value": "deploy", ` + schedule + `}`, map[string]interface{}{"command": "deploy prod"}, true},
+		{"any", `{"any": [{"command": "^deploy"}, {"command": "^release"}], ` + schedule + `}`, map[string]interface{}{"command": "release"}, true},
+		{"all", `{"all": [{"command": "^deploy"}, {"env": "prod"}], ` + schedule + `}`, map[string]interface{}{"command": "deploy", "env": "staging"}, false},
+		{"not", `{"not": {"command": "^git status"}, ` + schedule + `}`, map[string]interface{}{"command": "git push"}, true},
+		{"shell", `{"command": {"shell": {"command": "kubectl", "args": ["apply"]}}, ` + schedule + `}`, map[string]interface{}{"command": "kubectl apply -f x.yaml"}, true},
+		{"rate limit", `{"rate_limit": {"max_calls": 5, "window_seconds": 60}, ` + schedule + `}`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var conditions map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(tt.conditions), &conditions))
+			require.NoError(t, Validate(conditions))
+
+			s, err := ParseSchedule(conditions)
+			require.NoError(t, err)
+			assert.True(t, s.ActiveAt(time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC), time.UTC))
+
+			cond := CompileOrNever(conditions)
+			if tt.input == nil {
+				assert.Nil(t, cond, "only reserved keys")
+				return
+			}
+			assert.Equal(t, tt.want, cond.Match(tt.input))
+		})
+	}
+
+	bad := map[string]interface{}{"command": "^deploy", "schedule": map[string]interface{}{}}
+	assert.EqualError(t, Validate(bad), "schedule needs active_from, active_until or windows")
+	bad = map[string]interface{}{"command": map[string]interface{}{"regex": "rm"}, "schedule": map[string]interface{}{"active_until": "2027-01-04T00:00:00Z"}}
+	assert.EqualError(t, Validate(bad), `conditions.command: unknown operator "regex"`)
+}
@@ -0,0 +1,132 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/rastrigin-systems/arfa/pkg/shell"
+)
+
+// shellCondition is the "shell" operator of a condition on a shell command
+// parameter such as Bash's command:
+//
+//	{"command": {"shell": {"command": "git", "args": ["push"], "flags": ["--force|-f"]}}}
+//
+// The command line is split into the commands it runs (pipelines, lists,
+// subshells, $(...), sh -c, env and sudo prefixes, xargs), and the condition
+// matches when any one of them has the executable, arguments and flags given.
+type shellCondition struct {
+	Command string   `json:"command"`            // Executable name; * and ? match as in a glob
+	Args    []string `json:"args,omitempty"`     // Arguments that must appear, in order
+	Flags   []string `json:"flags,omitempty"`    // Flags that must all be present; "-f|--force" accepts either
+	PipedTo []string `json:"piped_to,omitempty"` // The output must be piped into one of these
+}
+
+// parseShellCondition decodes a "shell" operator.
+func parseShellCondition(raw interface{}) (shellCondition, error) {
+	spec, ok := raw.(map[string]interface{})
+	if !ok {
+		return shellCondition{}, fmt.Errorf("shell needs an object")
+	}
+	if command, ok := spec["command"].(string); !ok || command == "" {
+		return shellCondition{}, fmt.Errorf("shell needs a command")
+	}
+	for key, v := range spec {
+		switch key {
+		case "command":
+		case "args", "flags", "piped_to":
+			list, ok := v.([]interface{})
+			if !ok {
+				return shellCondition{}, fmt.Errorf("shell %s must be a list of strings", key)
+			}
+			for _, item := range list {
+				if s, ok := item.(string); !ok || s == "" {
+					return shellCondition{}, fmt.Errorf("shell %s must be a list of strings", key)
+				}
+			}
+		default:
+			return shellCondition{}, fmt.Errorf("unknown shell field %q", key)
+		}
+	}
+
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return shellCondition{}, fmt.Errorf("invalid shell condition: %v", err)
+	}
+	var cond shellCondition
+	if err := json.Unmarshal(data, &cond); err != nil {
+		return shellCondition{}, fmt.Errorf("invalid shell condition: %v", err)
+	}
+	return cond, nil
+}
+
+// matchesShell reports whether any command the command line runs matches.
+func (cond shellCondition) matchesShell(line string) bool {
+	for _, c := range shell.Parse(line) {
+		if cond.matchesCommand(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesCommand reports whether a single command matches.
+func (cond shellCondition) matchesCommand(c shell.Command) bool {
+	if ok, _ := path.Match(cond.Command, c.Name()); !ok {
+		return false
+	}
+
+	// Arguments in order, with anything in between (git -C dir push)
+	operands := c.Operands()
+	for _, arg := range cond.Args {
+		i := 0
+		for i < len(operands) && operands[i] != arg {
+			i++
+		}
+		if i == len(operands) {
+			return false
+		}
+		operands = operands[i+1:]
+	}
+
+	flags := make(map[string]bool)
+	for _, f := range c.Flags() {
+		flags[f] = true
+	}
+	for _, want := range cond.Flags {
+		if !hasAnyFlag(flags, want) {
+			return false
+		}
+	}
+
+	if len(cond.PipedTo) > 0 {
+		for _, target := range cond.PipedTo {
+			if ok, _ := path.Match(target, c.PipedTo); ok && c.PipedTo != "" {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// hasAnyFlag reports whether one of the "|"-separated alternatives is set.
+// An alternative of combined short flags ("-rf") needs each of them.
+func hasAnyFlag(flags map[string]bool, alternatives string) bool {
+	for _, alt := range strings.Split(alternatives, "|") {
+		split := shell.SplitFlag(alt)
+		if len(split) == 0 {
+			continue
+		}
+		all := true
+		for _, f := range split {
+			all = all && flags[f]
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
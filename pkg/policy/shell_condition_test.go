@@ -0,0 +1,48 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShellCondition(t *testing.T) {
+	forcePush := shellCondition{Command: "git", Args: []string{"push"}, Flags: []string{"--force|-f"}}
+	recursiveDelete := shellCondition{Command: "rm", Flags: []string{"-r|-R|--recursive", "-f|--force"}}
+	pipeToShell := shellCondition{Command: "curl", PipedTo: []string{"sh", "bash", "zsh"}}
+
+	tests := []struct {
+		name string
+		cond shellCondition
+		line string
+		want bool
+	}{
+		{"force push", forcePush, "git push --force origin main", true},
+		{"force push short flag", forcePush, "git push -f", true},
+		{"force push combined flags", forcePush, "git push -uf origin main", true},
+		{"force push with global options", forcePush, "git -C repo push --force", true},
+		{"force push in a chain", forcePush, "git add . && git commit -m wip && git push --force", true},
+		{"force push via sh -c", forcePush, `sh -c "cd repo; git push -f"`, true},
+		{"force push in a subshell", forcePush, "(git push --force)", true},
+		{"force push via env", forcePush, "env GIT_TRACE=1 git push --force", true},
+		{"plain push", forcePush, "git push origin main", false},
+		{"force flag on another subcommand", forcePush, "git push origin && git fetch --force", false},
+		{"force in a commit message", forcePush, `git commit -m "git push --force"`, false},
+		{"rm -rf", recursiveDelete, "rm -rf build", true},
+		{"rm -r -f", recursiveDelete, "rm -r -f build", true},
+		{"rm long flags", recursiveDelete, "/bin/rm --recursive --force build", true},
+		{"rm via xargs", recursiveDelete, "find . -name node_modules | xargs rm -fR", true},
+		{"rm via command substitution", recursiveDelete, "echo $(sudo rm -rf /)", true},
+		{"rm without force", recursiveDelete, "rm -r build", false},
+		{"curl piped to sh", pipeToShell, "curl -fsSL https://get.example.com | sh", true},
+		{"curl piped to sudo bash", pipeToShell, "curl -s https://x | sudo -E bash -s", true},
+		{"curl to a file", pipeToShell, "curl -o install.sh https://x && less install.sh", false},
+		{"curl piped to jq", pipeToShell, "curl -s https://api | jq .", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.cond.matchesShell(tt.line))
+		})
+	}
+}
@@ -0,0 +1,43 @@
+// Package pricing estimates the USD cost of LLM usage from token counts. The
+// proxy prices usage to enforce budgets as responses stream by, and the API
+// prices the usage it records rather than trusting the client's figure.
+package pricing
+
+import "strings"
+
+// Price is what a model family costs, in USD per 1M tokens.
+type Price struct {
+	Family string // Matched anywhere in the model name
+	Input  float64
+	Output float64
+}
+
+// Prices lists model families by price. A model is priced by the first
+// family its name contains.
+var Prices = []Price{
+	{Family: "opus", Input: 15.0, Output: 75.0},
+	{Family: "sonnet", Input: 3.0, Output: 15.0},
+	{Family: "haiku", Input: 0.25, Output: 1.25},
+}
+
+// DefaultPrice is what models of no listed family cost (Sonnet's price).
+var DefaultPrice = Price{Input: 3.0, Output: 15.0}
+
+// PriceOf returns the price of a model.
+func PriceOf(model string) Price {
+	for _, p := range Prices {
+		if strings.Contains(model, p.Family) {
+			return p
+		}
+	}
+	return DefaultPrice
+}
+
+// EstimateCost returns the estimated cost in USD of a response from model.
+// Unknown models are priced like Sonnet.
+func EstimateCost(model string, inputTokens, outputTokens int) float64 {
+	price := PriceOf(model)
+	inputCost := (float64(inputTokens) / 1_000_000) * price.Input
+	outputCost := (float64(outputTokens) / 1_000_000) * price.Output
+	return inputCost + outputCost
+}
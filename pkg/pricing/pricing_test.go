@@ -0,0 +1,25 @@
+package pricing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateCost(t *testing.T) {
+	tests := []struct {
+		model string
+		want  float64
+	}{
+		{"claude-opus-4-1", 15.0 + 75.0},
+		{"claude-sonnet-4-5", 3.0 + 15.0},
+		{"claude-3-5-haiku-latest", 0.25 + 1.25},
+		{"gpt-4o", 3.0 + 15.0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			assert.InDelta(t, tt.want, EstimateCost(tt.model, 1_000_000, 1_000_000), 1e-9)
+		})
+	}
+}
@@ -0,0 +1,523 @@
+// Package shell splits shell command lines into the simple commands they run,
+// so policies can match on what a command does rather than how it is written.
+package shell
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// maxDepth bounds how deep sh -c strings, eval and $(...) are followed.
+// Deeper command lines are split on whitespace only.
+const maxDepth = 8
+
+// Command is one simple command of a command line: the executable and its
+// arguments, after environment assignments and wrappers such as env, sudo
+// and xargs are removed.
+type Command struct {
+	Args []string
+
+	// PipedTo is the name of the command this one's output is piped into,
+	// if any.
+	PipedTo string
+}
+
+// Name returns the executable's base name, e.g. "rm" for /bin/rm.
+func (c Command) Name() string {
+	if len(c.Args) == 0 {
+		return ""
+	}
+	return path.Base(c.Args[0])
+}
+
+// Flags returns the command's flags. Long flags lose any =value and combined
+// short flags are split, so "-rf" yields -r and -f. Flags end at "--".
+func (c Command) Flags() []string {
+	var flags []string
+	for _, arg := range c.args() {
+		if arg == "--" {
+			break
+		}
+		flags = append(flags, SplitFlag(arg)...)
+	}
+	return flags
+}
+
+// Operands returns the command's arguments that aren't flags.
+func (c Command) Operands() []string {
+	var operands []string
+	afterDashes := false
+	for _, arg := range c.args() {
+		switch {
+		case afterDashes:
+			operands = append(operands, arg)
+		case arg == "--":
+			afterDashes = true
+		case !isFlag(arg):
+			operands = append(operands, arg)
+		}
+	}
+	return operands
+}
+
+func (c Command) args() []string {
+	if len(c.Args) < 2 {
+		return nil
+	}
+	return c.Args[1:]
+}
+
+// SplitFlag splits a flag argument into flags: "--force=yes" is --force and
+// "-rf" is -r and -f. Arguments that aren't flags yield nothing.
+func SplitFlag(arg string) []string {
+	if !isFlag(arg) {
+		return nil
+	}
+	if strings.HasPrefix(arg, "--") {
+		name, _, _ := strings.Cut(arg, "=")
+		return []string{name}
+	}
+	flags := make([]string, 0, len(arg)-1)
+	for _, r := range arg[1:] {
+		flags = append(flags, "-"+string(r))
+	}
+	return flags
+}
+
+// isFlag reports whether an argument is a flag. A lone "-" (stdin) and
+// "--" aren't.
+func isFlag(arg string) bool {
+	return len(arg) > 1 && arg[0] == '-' && arg != "--"
+}
+
+// Parse returns every simple command a command line runs: each part of a
+// pipeline or list (|, ;, &&, ||, &), subshells, command substitutions
+// ($(...) and backticks), and the command strings given to sh -c, bash -c
+// and eval. Quotes and escapes are removed as the shell would. Parsing never
+// fails; malformed input is split as well as possible.
+func Parse(line string) []Command {
+	return parse(line, 0)
+}
+
+func parse(line string, depth int) []Command {
+	if depth > maxDepth {
+		return []Command{{Args: strings.Fields(line)}}
+	}
+
+	l := lexer{input: line}
+	l.run()
+
+	var commands []Command
+	for _, nested := range l.nested {
+		commands = append(commands, parse(nested, depth+1)...)
+	}
+
+	// Each pipeline is unwrapped first, so PipedTo names the command that
+	// really runs (sudo sh -> sh).
+	var pipeline []Command
+	flush := func() {
+		for i := range pipeline {
+			if i+1 < len(pipeline) {
+				pipeline[i].PipedTo = pipeline[i+1].Name()
+			}
+		}
+		commands = append(commands, pipeline...)
+		pipeline = nil
+	}
+
+	for _, simple := range l.commands {
+		args, nested := unwrap(simple.words)
+		for _, n := range nested {
+			commands = append(commands, parse(n, depth+1)...)
+		}
+		if len(args) > 0 {
+			pipeline = append(pipeline, Command{Args: args})
+		}
+		if !simple.piped {
+			flush()
+		}
+	}
+	flush()
+	return commands
+}
+
+// simpleCommand is a run of words up to an operator, as split by the lexer.
+type simpleCommand struct {
+	words []string
+	piped bool // Followed by | or |&
+}
+
+// lexer splits a command line into words and simple commands.
+type lexer struct {
+	input    string
+	pos      int
+	word     strings.Builder
+	inWord   bool
+	skipNext bool // The next word is a redirection target
+	words    []string
+	commands []simpleCommand
+	nested   []string // Command and process substitutions
+}
+
+func (l *lexer) run() {
+	for l.pos < len(l.input) {
+		c := l.input[l.pos]
+		switch {
+		case c == ' ' || c == '\t':
+			l.endWord()
+			l.pos++
+		case c == '\\':
+			if l.pos+1 < len(l.input) {
+				if l.input[l.pos+1] != '\n' { // Line continuation
+					l.add(l.input[l.pos+1])
+				}
+				l.pos += 2
+			} else {
+				l.pos++
+			}
+		case c == '\'':
+			end := strings.IndexByte(l.input[l.pos+1:], '\'')
+			if end < 0 {
+				end = len(l.input) - l.pos - 1
+			}
+			l.addString(l.input[l.pos+1 : l.pos+1+end])
+			l.inWord = true
+			l.pos += end + 2
+		case c == '"':
+			l.inWord = true
+			l.doubleQuoted()
+		case c == '$' && l.peek(1) == '(':
+			if l.peek(2) == '(' { // Arithmetic $((...)) runs nothing
+				l.addString(l.balanced(l.pos + 1))
+				continue
+			}
+			l.nested = append(l.nested, l.balanced(l.pos+1))
+		case c == '`':
+			l.nested = append(l.nested, l.backticks())
+		case c == '#' && !l.inWord:
+			end := strings.IndexByte(l.input[l.pos:], '\n')
+			if end < 0 {
+				end = len(l.input) - l.pos
+			}
+			l.pos += end
+		case c == '>' || c == '<' || (c == '&' && l.peek(1) == '>'):
+			l.redirect()
+		case c == '|' || c == ';' || c == '&' || c == '\n' || c == '(' || c == ')':
+			l.operator()
+		default:
+			l.add(c)
+			l.pos++
+		}
+	}
+	l.endCommand(false)
+}
+
+func (l *lexer) peek(n int) byte {
+	if l.pos+n < len(l.input) {
+		return l.input[l.pos+n]
+	}
+	return 0
+}
+
+func (l *lexer) add(c byte) {
+	l.word.WriteByte(c)
+	l.inWord = true
+}
+
+func (l *lexer) addString(s string) {
+	l.word.WriteString(s)
+	l.inWord = true
+}
+
+func (l *lexer) endWord() {
+	if !l.inWord {
+		return
+	}
+	if l.skipNext {
+		l.skipNext = false
+	} else {
+		l.words = append(l.words, l.word.String())
+	}
+	l.word.Reset()
+	l.inWord = false
+}
+
+func (l *lexer) endCommand(piped bool) {
+	l.endWord()
+	l.skipNext = false
+	if len(l.words) > 0 {
+		l.commands = append(l.commands, simpleCommand{words: l.words, piped: piped})
+	}
+	l.words = nil
+}
+
+// doubleQuoted reads a "..." string. Backslash only escapes $, `, " and \,
+// and command substitutions still run.
+func (l *lexer) doubleQuoted() {
+	l.pos++ // Opening quote
+	for l.pos < len(l.input) {
+		c := l.input[l.pos]
+		switch {
+		case c == '"':
+			l.pos++
+			return
+		case c == '\\' && strings.IndexByte("$`\"\\\n", l.peek(1)) >= 0 && l.peek(1) != 0:
+			if l.peek(1) != '\n' {
+				l.add(l.peek(1))
+			}
+			l.pos += 2
+		case c == '$' && l.peek(1) == '(':
+			l.nested = append(l.nested, l.balanced(l.pos+1))
+		case c == '`':
+			l.nested = append(l.nested, l.backticks())
+		default:
+			l.add(c)
+			l.pos++
+		}
+	}
+}
+
+// balanced returns the contents of the parentheses opening at start and moves
+// past the closing one, skipping over quoted parentheses.
+func (l *lexer) balanced(start int) string {
+	depth := 0
+	quote := byte(0)
+	for i := start; i < len(l.input); i++ {
+		c := l.input[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\\':
+			i++
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				l.pos = i + 1
+				return l.input[start+1 : i]
+			}
+		}
+	}
+	l.pos = len(l.input)
+	if start+1 > len(l.input) {
+		return ""
+	}
+	return l.input[start+1:]
+}
+
+// backticks returns the contents of a `...` substitution and moves past it.
+func (l *lexer) backticks() string {
+	var b strings.Builder
+	for i := l.pos + 1; i < len(l.input); i++ {
+		c := l.input[i]
+		if c == '\\' && i+1 < len(l.input) {
+			b.WriteByte(l.input[i+1])
+			i++
+			continue
+		}
+		if c == '`' {
+			l.pos = i + 1
+			return b.String()
+		}
+		b.WriteByte(c)
+	}
+	l.pos = len(l.input)
+	return b.String()
+}
+
+// redirect skips a redirection such as >file, 2>&1 or &>log, which isn't
+// an argument of the command.
+func (l *lexer) redirect() {
+	// A file descriptor number before the operator belongs to it
+	if l.inWord && isDigits(l.word.String()) {
+		l.word.Reset()
+		l.inWord = false
+	} else {
+		l.endWord()
+	}
+
+	for l.pos < len(l.input) && strings.IndexByte("<>&|", l.input[l.pos]) >= 0 {
+		l.pos++
+	}
+	if l.pos < len(l.input) && l.input[l.pos] == '(' { // Process substitution <(...)
+		l.nested = append(l.nested, l.balanced(l.pos))
+		return
+	}
+	l.skipNext = true
+}
+
+// operator ends the current simple command at |, ;, &&, ||, &, a newline or
+// a parenthesis. Subshells are flattened into the commands around them.
+func (l *lexer) operator() {
+	c := l.input[l.pos]
+	piped := c == '|' && l.peek(1) != '|'
+	l.pos++
+	if (c == '|' && l.peek(0) == '|') || (c == '&' && l.peek(0) == '&') || (c == '|' && l.peek(0) == '&') {
+		l.pos++
+	}
+	l.endCommand(piped)
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+var assignment = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+// wrapper describes a command that runs the rest of its arguments as another
+// command. valueOptions take the following argument as their value; a
+// positional wrapper also consumes that many operands (timeout's duration).
+type wrapper struct {
+	valueOptions string // Short options with a value, e.g. "u" for sudo -u root
+	longValues   []string
+	positional   int
+}
+
+var wrappers = map[string]wrapper{
+	"env":     {valueOptions: "uCS", longValues: []string{"--unset", "--chdir", "--split-string"}},
+	"sudo":    {valueOptions: "ugCDprtTU", longValues: []string{"--user", "--group", "--chdir", "--host", "--prompt", "--role", "--type", "--other-user"}},
+	"doas":    {valueOptions: "uC"},
+	"nohup":   {},
+	"time":    {valueOptions: "fo"},
+	"nice":    {valueOptions: "n", longValues: []string{"--adjustment"}},
+	"ionice":  {valueOptions: "cnp"},
+	"timeout": {valueOptions: "ks", longValues: []string{"--kill-after", "--signal"}, positional: 1},
+	"stdbuf":  {valueOptions: "ioe", longValues: []string{"--input", "--output", "--error"}},
+	"command": {},
+	"exec":    {valueOptions: "a"},
+	"builtin": {},
+	"xargs":   {valueOptions: "aEdILnPs", longValues: []string{"--arg-file", "--delimiter", "--max-args", "--max-lines", "--max-procs", "--max-chars", "--replace", "--eof"}},
+}
+
+// shells run their -c argument as a command line.
+var shells = map[string]bool{"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true, "fish": true}
+
+// unwrap removes environment assignments and wrapper commands from a simple
+// command's words. Command lines the command runs itself (sh -c, eval,
+// env -S) are returned as nested.
+func unwrap(words []string) ([]string, []string) {
+	var nested []string
+	for len(words) > 0 {
+		if assignment.MatchString(words[0]) {
+			words = words[1:]
+			continue
+		}
+
+		name := path.Base(words[0])
+		if name == "{" || name == "}" || name == "!" {
+			words = words[1:]
+			continue
+		}
+
+		if name == "eval" {
+			return nil, append(nested, strings.Join(words[1:], " "))
+		}
+
+		if shells[name] {
+			if script, ok := shellScript(words[1:]); ok {
+				return words, append(nested, script)
+			}
+			return words, nested
+		}
+
+		w, ok := wrappers[name]
+		if !ok {
+			return words, nested
+		}
+		rest, split := w.skipOptions(words[1:])
+		if split != "" {
+			nested = append(nested, split)
+		}
+		words = rest
+	}
+	return words, nested
+}
+
+// skipOptions drops a wrapper's options and positional operands, returning
+// the wrapped command. env -S's string is returned to be parsed.
+func (w wrapper) skipOptions(args []string) ([]string, string) {
+	var split string
+	for len(args) > 0 {
+		arg := args[0]
+		if arg == "--" {
+			args = args[1:]
+			break
+		}
+		if !isFlag(arg) {
+			if assignment.MatchString(arg) { // env NAME=value
+				args = args[1:]
+				continue
+			}
+			break
+		}
+
+		args = args[1:]
+		if strings.HasPrefix(arg, "--") {
+			for _, long := range w.longValues {
+				if arg == long && len(args) > 0 {
+					if long == "--split-string" {
+						split = args[0]
+					}
+					args = args[1:]
+				} else if value, ok := strings.CutPrefix(arg, long+"="); ok && long == "--split-string" {
+					split = value
+				}
+			}
+			continue
+		}
+
+		// -u root, -uroot, or a value option at the end of combined flags
+		for i := 1; i < len(arg); i++ {
+			if strings.IndexByte(w.valueOptions, arg[i]) < 0 {
+				continue
+			}
+			value := arg[i+1:]
+			if value == "" && len(args) > 0 {
+				value = args[0]
+				args = args[1:]
+			}
+			if arg[i] == 'S' {
+				split = value
+			}
+			break
+		}
+	}
+
+	for i := 0; i < w.positional && len(args) > 0; i++ {
+		args = args[1:]
+	}
+	return args, split
+}
+
+// shellScript returns the command line of sh -c, including combined flags
+// such as bash -lc or sh -ec.
+func shellScript(args []string) (string, bool) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-o" || arg == "-O" || arg == "+o" || arg == "+O":
+			i++ // -o pipefail
+		case !isFlag(arg):
+			return "", false
+		case !strings.HasPrefix(arg, "--") && strings.ContainsRune(arg[1:], 'c') && i+1 < len(args):
+			return args[i+1], true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,77 @@
+package shell
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// argsOf returns the arguments of every command Parse finds.
+func argsOf(line string) [][]string {
+	var args [][]string
+	for _, c := range Parse(line) {
+		args = append(args, c.Args)
+	}
+	return args
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want [][]string
+	}{
+		{"simple", "rm -rf /tmp/x", [][]string{{"rm", "-rf", "/tmp/x"}}},
+		{"quotes", `echo "a b" 'c d' e\ f`, [][]string{{"echo", "a b", "c d", "e f"}}},
+		{"list", "cd /tmp && rm -r -f x; ls || true", [][]string{{"cd", "/tmp"}, {"rm", "-r", "-f", "x"}, {"ls"}, {"true"}}},
+		{"pipeline", "curl -s https://x.sh | sudo bash", [][]string{{"curl", "-s", "https://x.sh"}, {"bash"}}},
+		{"subshell", "(cd /repo; git push --force)", [][]string{{"cd", "/repo"}, {"git", "push", "--force"}}},
+		{"group", "{ rm -rf build; }", [][]string{{"rm", "-rf", "build"}}},
+		{"command substitution", "echo $(rm -rf /) `id -u`", [][]string{{"rm", "-rf", "/"}, {"id", "-u"}, {"echo"}}},
+		{"substitution in quotes", `echo "now: $(date +%s)"`, [][]string{{"date", "+%s"}, {"echo", "now: "}}},
+		{"sh -c", `sh -c "rm -rf ~"`, [][]string{{"rm", "-rf", "~"}, {"sh", "-c", "rm -rf ~"}}},
+		{"bash -lc", `bash -o pipefail -lc 'git push -f'`, [][]string{{"git", "push", "-f"}, {"bash", "-o", "pipefail", "-lc", "git push -f"}}},
+		{"eval", `eval "rm -rf /"`, [][]string{{"rm", "-rf", "/"}}},
+		{"env prefixes", "FOO=1 env -i BAR=2 -u HOME rm -rf x", [][]string{{"rm", "-rf", "x"}}},
+		{"env -S", `env -S "rm -rf x"`, [][]string{{"rm", "-rf", "x"}}},
+		{"wrappers", "sudo -u root nohup timeout -s KILL 10 nice -n 5 rm -rf /", [][]string{{"rm", "-rf", "/"}}},
+		{"xargs", "find . -name '*.o' | xargs -0 -I{} rm -f {}", [][]string{{"find", ".", "-name", "*.o"}, {"rm", "-f", "{}"}}},
+		{"redirections", "make 2>&1 > build.log < /dev/null", [][]string{{"make"}}},
+		{"comment", "ls # rm -rf /", [][]string{{"ls"}}},
+		{"newlines", "ls\nrm -rf x \\\n  y", [][]string{{"ls"}, {"rm", "-rf", "x", "y"}}},
+		{"arithmetic", "echo $((1 + 2))", [][]string{{"echo", "(1 + 2)"}}},
+		{"unterminated quote", `echo "rm -rf`, [][]string{{"echo", "rm -rf"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, argsOf(tt.line))
+		})
+	}
+}
+
+func TestParse_PipedTo(t *testing.T) {
+	commands := Parse("curl -fsSL https://x.sh | sudo -E sh -s -- --yes; echo done")
+
+	assert.Len(t, commands, 3)
+	assert.Equal(t, "curl", commands[0].Name())
+	assert.Equal(t, "sh", commands[0].PipedTo)
+	assert.Equal(t, "", commands[1].PipedTo)
+	assert.Equal(t, "", commands[2].PipedTo)
+}
+
+func TestCommand_FlagsAndOperands(t *testing.T) {
+	c := Command{Args: []string{"/usr/bin/git", "-C", "repo", "push", "--force-with-lease=main", "-uf", "origin", "--", "-weird"}}
+
+	assert.Equal(t, "git", c.Name())
+	assert.Equal(t, []string{"-C", "--force-with-lease", "-u", "-f"}, c.Flags())
+	assert.Equal(t, []string{"repo", "push", "origin", "-weird"}, c.Operands())
+}
+
+func TestParse_DepthLimit(t *testing.T) {
+	commands := Parse(strings.Repeat("eval ", 20) + "rm -rf /")
+
+	assert.Len(t, commands, 1)
+	assert.Equal(t, "eval", commands[0].Name(), "too deep to follow, but still reported")
+}
@@ -94,14 +94,16 @@ CREATE TABLE tool_policies (
     team_id UUID REFERENCES teams(id) ON DELETE CASCADE,
     employee_id UUID REFERENCES employees(id) ON DELETE CASCADE,
 
-    -- What to match
-    tool_name VARCHAR(255) NOT NULL,  -- "Bash", "Read", "mcp__playwright__%", "*"
+    -- What to match: tool calls, the model of each request, or the files tools touch
+    policy_type VARCHAR(20) NOT NULL DEFAULT 'tool' CHECK (policy_type IN ('tool', 'model', 'path')),
+    tool_name VARCHAR(255) NOT NULL,  -- "Bash", "Read", "mcp__playwright__%", "*"; for model policies "claude-opus-*"; for path policies "**/.env"
 
-    -- Conditions (optional, for param-based blocking)
+    -- Conditions (optional, for param-based blocking): all/any/not over
+    -- {"param_path", "operator", "value"} leaves or {"<param>": "regex"}; see docs/architecture/tool-blocking.md
     conditions JSONB,  -- {"any": [{"param_path": "command", "operator": "contains", "value": "rm -rf"}]}
 
     -- Action
-    action VARCHAR(20) NOT NULL DEFAULT 'deny' CHECK (action IN ('deny', 'audit')),
+    action VARCHAR(20) NOT NULL DEFAULT 'deny' CHECK (action IN ('deny', 'audit', 'require_approval', 'rate_limit', 'allow', 'rewrite')),
     reason TEXT,  -- Human-readable explanation shown to agent
 
     -- Metadata
@@ -109,7 +111,54 @@ CREATE TABLE tool_policies (
     created_at TIMESTAMP NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
 
-    CONSTRAINT tool_policies_has_org CHECK (org_id IS NOT NULL)
+    CONSTRAINT tool_policies_has_org CHECK (org_id IS NOT NULL),
+    -- Model policies deny, rewrite or audit; rewrite only applies to models.
+    -- Path policies deny or audit. Allow only applies to tool policies.
+    CONSTRAINT tool_policies_model_actions CHECK (
+        (policy_type = 'model' AND action IN ('deny', 'audit', 'rewrite'))
+        OR (policy_type = 'path' AND action IN ('deny', 'audit'))
+        OR (policy_type = 'tool' AND action <> 'rewrite')
+    )
+);
+
+-- Append-only history of tool policy changes: one row per create, update,
+-- delete or rollback, kept after the policy itself is deleted
+CREATE TABLE tool_policy_revisions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    policy_id UUID NOT NULL,  -- Not a foreign key: history outlives the policy
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,  -- 1 for the create, counting up per policy
+    change_type VARCHAR(20) NOT NULL CHECK (change_type IN ('create', 'update', 'delete', 'rollback')),
+
+    -- The policy after the change (before it, for deletes), and the fields that changed:
+    -- {"action": {"from": "deny", "to": "audit"}}
+    snapshot JSONB NOT NULL,
+    diff JSONB NOT NULL DEFAULT '{}',
+    note TEXT,  -- Why the change was made
+    rolled_back_to INTEGER,  -- Revision restored by a rollback
+
+    -- Attribution
+    changed_by UUID REFERENCES employees(id) ON DELETE SET NULL,
+    session_id UUID,  -- Session the change was made in; not a foreign key, as sessions expire
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT unique_tool_policy_revision UNIQUE (policy_id, revision)
+);
+
+-- Built-in policy packs installed for an organization, or one of its teams.
+-- The pack's policies themselves live in tool_policies; the install tracks
+-- which of them it owns, so an upgrade to a newer version can be diffed
+-- against them and applied.
+CREATE TABLE policy_pack_installs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    team_id UUID REFERENCES teams(id) ON DELETE CASCADE,  -- NULL for the whole organization
+    pack_name VARCHAR(100) NOT NULL,  -- "secrets-protection"
+    version INTEGER NOT NULL,  -- Pack version installed
+    policy_ids UUID[] NOT NULL DEFAULT '{}',  -- Policies the install owns; not foreign keys, as admins may delete them
+    installed_by UUID REFERENCES employees(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
 );
 
 -- Team-level policy overrides
@@ -132,6 +181,40 @@ CREATE TABLE employee_policies (
     CONSTRAINT unique_employee_policy UNIQUE (employee_id, policy_id)
 );
 
+-- Token and cost budgets (org-wide, per team or per employee)
+CREATE TABLE budgets (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    team_id UUID REFERENCES teams(id) ON DELETE CASCADE,
+    employee_id UUID REFERENCES employees(id) ON DELETE CASCADE,
+
+    -- Limits apply per calendar day or month (UTC)
+    period VARCHAR(20) NOT NULL CHECK (period IN ('daily', 'monthly')),
+    token_limit BIGINT CHECK (token_limit > 0),              -- Input + output tokens
+    cost_limit_usd DOUBLE PRECISION CHECK (cost_limit_usd > 0),
+
+    -- Metadata
+    created_by UUID REFERENCES employees(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT budgets_has_limit CHECK (token_limit IS NOT NULL OR cost_limit_usd IS NOT NULL),
+    CONSTRAINT budgets_single_scope CHECK (team_id IS NULL OR employee_id IS NULL)
+);
+
+-- LLM usage reported by proxies, summed per employee per day (UTC)
+CREATE TABLE usage_daily (
+    employee_id UUID NOT NULL REFERENCES employees(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    team_id UUID REFERENCES teams(id) ON DELETE SET NULL, -- Employee's team when last reported
+    input_tokens BIGINT NOT NULL DEFAULT 0,
+    output_tokens BIGINT NOT NULL DEFAULT 0,
+    cost_usd DOUBLE PRECISION NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (employee_id, day)
+);
+
 -- ============================================================================
 -- ACTIVITY LOGS
 -- ============================================================================
@@ -264,6 +347,18 @@ CREATE INDEX idx_tool_policies_org_id ON tool_policies(org_id);
 CREATE INDEX idx_tool_policies_team_id ON tool_policies(team_id) WHERE team_id IS NOT NULL;
 CREATE INDEX idx_tool_policies_employee_id ON tool_policies(employee_id) WHERE employee_id IS NOT NULL;
 CREATE INDEX idx_tool_policies_lookup ON tool_policies(org_id, team_id, employee_id, tool_name);
+CREATE INDEX idx_tool_policies_scheduled ON tool_policies(org_id) WHERE conditions -> 'schedule' IS NOT NULL;
+
+CREATE INDEX idx_tool_policy_revisions_org_id ON tool_policy_revisions(org_id, created_at DESC);
+
+-- One install of a pack per organization, and per team
+CREATE UNIQUE INDEX idx_policy_pack_installs_org ON policy_pack_installs(org_id, pack_name) WHERE team_id IS NULL;
+CREATE UNIQUE INDEX idx_policy_pack_installs_team ON policy_pack_installs(org_id, team_id, pack_name) WHERE team_id IS NOT NULL;
+
+-- Budgets
+CREATE INDEX idx_budgets_org_id ON budgets(org_id);
+CREATE INDEX idx_usage_daily_org_day ON usage_daily(org_id, day);
+CREATE INDEX idx_usage_daily_team_day ON usage_daily(team_id, day) WHERE team_id IS NOT NULL;
 
 -- Activity Logs
 CREATE INDEX idx_activity_logs_org_id ON activity_logs(org_id);
@@ -333,6 +428,23 @@ CREATE TRIGGER update_policies_updated_at BEFORE UPDATE ON policies
 CREATE TRIGGER update_invitations_updated_at BEFORE UPDATE ON invitations
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+CREATE TRIGGER update_budgets_updated_at BEFORE UPDATE ON budgets
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_policy_pack_installs_updated_at BEFORE UPDATE ON policy_pack_installs
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Policy history is append-only
+CREATE OR REPLACE FUNCTION reject_revision_update()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'tool_policy_revisions is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER reject_tool_policy_revision_update BEFORE UPDATE ON tool_policy_revisions
+    FOR EACH ROW EXECUTE FUNCTION reject_revision_update();
+
 -- Generate invitation tokens automatically
 CREATE OR REPLACE FUNCTION generate_invitation_token()
 RETURNS TRIGGER AS $$
@@ -422,6 +534,7 @@ CREATE TRIGGER employee_revoke_trigger
 INSERT INTO roles (name, description, permissions) VALUES
     ('admin', 'Full administrative access', '["*"]'),
     ('member', 'Standard employee access', '["logs:read", "policies:read"]'),
+    ('approver', 'Decides tool calls paused for approval', '["approvals:decide"]'),
     ('viewer', 'Read-only access', '["logs:read"]');
 
 -- Default Policies
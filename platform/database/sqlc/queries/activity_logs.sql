@@ -157,3 +157,23 @@ WHERE org_id = $1
     AND client_name = $2
 ORDER BY created_at DESC
 LIMIT $3 OFFSET $4;
+
+-- name: ListToolCallEvents :many
+-- List an organization's tool calls in a time range, oldest first, with the
+-- employee's current team, for replaying against draft policies
+SELECT
+    al.id,
+    al.employee_id,
+    al.proxy_session_id,
+    e.team_id,
+    e.email AS employee_email,
+    al.payload,
+    al.created_at
+FROM activity_logs al
+LEFT JOIN employees e ON al.employee_id = e.id
+WHERE al.org_id = sqlc.arg(org_id)
+    AND al.event_type = 'tool_call'
+    AND al.created_at >= sqlc.arg(start_date)
+    AND al.created_at <= sqlc.arg(end_date)
+ORDER BY al.created_at ASC
+LIMIT sqlc.arg(query_limit);
@@ -0,0 +1,104 @@
+-- name: ListBudgets :many
+-- List all budgets for an organization
+SELECT
+    id,
+    org_id,
+    team_id,
+    employee_id,
+    period,
+    token_limit,
+    cost_limit_usd,
+    created_by,
+    created_at,
+    updated_at
+FROM budgets
+WHERE org_id = $1
+ORDER BY created_at DESC;
+
+-- name: GetBudgetsForEmployee :many
+-- Get all budgets that apply to an employee (org-level, team-level, and employee-level)
+SELECT
+    id,
+    org_id,
+    team_id,
+    employee_id,
+    period,
+    token_limit,
+    cost_limit_usd,
+    created_by,
+    created_at,
+    updated_at
+FROM budgets
+WHERE org_id = sqlc.arg(org_id)
+    AND (
+        (team_id IS NULL AND employee_id IS NULL)
+        OR (team_id = sqlc.narg(team_id) AND employee_id IS NULL)
+        OR employee_id = sqlc.arg(employee_id)
+    )
+ORDER BY created_at;
+
+-- name: GetBudgetByIdAndOrg :one
+-- Get a specific budget by ID with org_id check (for authorization)
+SELECT
+    id,
+    org_id,
+    team_id,
+    employee_id,
+    period,
+    token_limit,
+    cost_limit_usd,
+    created_by,
+    created_at,
+    updated_at
+FROM budgets
+WHERE id = $1 AND org_id = $2;
+
+-- name: CreateBudget :one
+-- Create a new budget
+INSERT INTO budgets (
+    org_id,
+    team_id,
+    employee_id,
+    period,
+    token_limit,
+    cost_limit_usd,
+    created_by
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: DeleteBudget :exec
+-- Delete a budget (with org_id check)
+DELETE FROM budgets
+WHERE id = $1 AND org_id = $2;
+
+-- name: RecordUsage :exec
+-- Add one response's usage to the employee's total for the day
+INSERT INTO usage_daily (
+    employee_id,
+    day,
+    org_id,
+    team_id,
+    input_tokens,
+    output_tokens,
+    cost_usd
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+)
+ON CONFLICT (employee_id, day) DO UPDATE SET
+    team_id = EXCLUDED.team_id,
+    input_tokens = usage_daily.input_tokens + EXCLUDED.input_tokens,
+    output_tokens = usage_daily.output_tokens + EXCLUDED.output_tokens,
+    cost_usd = usage_daily.cost_usd + EXCLUDED.cost_usd,
+    updated_at = NOW();
+
+-- name: GetUsageSince :one
+-- Sum usage from a day onward for an organization, optionally narrowed to a team or employee
+SELECT
+    COALESCE(SUM(input_tokens + output_tokens), 0)::BIGINT AS tokens,
+    COALESCE(SUM(cost_usd), 0)::DOUBLE PRECISION AS cost_usd
+FROM usage_daily
+WHERE org_id = sqlc.arg(org_id)
+    AND day >= sqlc.arg(since)::DATE
+    AND (sqlc.narg(team_id)::UUID IS NULL OR team_id = sqlc.narg(team_id))
+    AND (sqlc.narg(employee_id)::UUID IS NULL OR employee_id = sqlc.narg(employee_id));
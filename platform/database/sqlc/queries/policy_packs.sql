@@ -0,0 +1,40 @@
+-- name: GetPolicyPackInstall :one
+-- Get a pack's install for an organization, or one of its teams (NULL team)
+SELECT * FROM policy_pack_installs
+WHERE org_id = sqlc.arg(org_id)
+    AND team_id IS NOT DISTINCT FROM sqlc.narg(team_id)::uuid
+    AND pack_name = sqlc.arg(pack_name);
+
+-- name: ListPolicyPackInstalls :many
+-- List an organization's pack installs, organization-wide installs first
+SELECT * FROM policy_pack_installs
+WHERE org_id = $1
+ORDER BY pack_name, team_id NULLS FIRST;
+
+-- name: CreatePolicyPackInstall :one
+-- Record a pack install
+INSERT INTO policy_pack_installs (
+    org_id,
+    team_id,
+    pack_name,
+    version,
+    policy_ids,
+    installed_by
+) VALUES (
+    sqlc.arg(org_id),
+    sqlc.narg(team_id),
+    sqlc.arg(pack_name),
+    sqlc.arg(version),
+    sqlc.arg(policy_ids),
+    sqlc.narg(installed_by)
+) RETURNING *;
+
+-- name: UpdatePolicyPackInstall :one
+-- Record an upgrade of a pack install
+UPDATE policy_pack_installs
+SET
+    version = sqlc.arg(version),
+    policy_ids = sqlc.arg(policy_ids),
+    installed_by = sqlc.narg(installed_by)
+WHERE id = sqlc.arg(id)
+RETURNING *;
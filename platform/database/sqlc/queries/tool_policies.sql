@@ -1,11 +1,13 @@
 -- name: GetToolPoliciesForEmployee :many
 -- Get all tool policies that apply to an employee (org-level, team-level, and employee-level)
--- Policy resolution order: employee > team > org (but deny always wins regardless of level)
+-- Policy resolution order: employee > team > org; an allow policy exempts calls from
+-- the restrictions it outranks (see services/api/internal/service/policy_precedence.go)
 SELECT
     id,
     org_id,
     team_id,
     employee_id,
+    policy_type,
     tool_name,
     conditions,
     action,
@@ -42,9 +44,10 @@ INSERT INTO tool_policies (
     conditions,
     action,
     reason,
-    created_by
+    created_by,
+    policy_type
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
 ) RETURNING *;
 
 -- name: GetToolPolicy :one
@@ -54,6 +57,7 @@ SELECT
     org_id,
     team_id,
     employee_id,
+    policy_type,
     tool_name,
     conditions,
     action,
@@ -71,6 +75,7 @@ SELECT
     org_id,
     team_id,
     employee_id,
+    policy_type,
     tool_name,
     conditions,
     action,
@@ -81,6 +86,26 @@ SELECT
 FROM tool_policies
 WHERE id = $1 AND org_id = $2;
 
+-- name: LockToolPolicyByIdAndOrg :one
+-- Get a tool policy with org_id check and lock it until the transaction ends,
+-- so concurrent changes to it are made and numbered in its history in turn
+SELECT
+    id,
+    org_id,
+    team_id,
+    employee_id,
+    policy_type,
+    tool_name,
+    conditions,
+    action,
+    reason,
+    created_by,
+    created_at,
+    updated_at
+FROM tool_policies
+WHERE id = $1 AND org_id = $2
+FOR UPDATE;
+
 -- name: ListToolPoliciesByOrg :many
 -- List all tool policies for an organization (admin view)
 SELECT
@@ -88,6 +113,7 @@ SELECT
     org_id,
     team_id,
     employee_id,
+    policy_type,
     tool_name,
     conditions,
     action,
@@ -99,6 +125,45 @@ FROM tool_policies
 WHERE org_id = $1
 ORDER BY created_at DESC;
 
+-- name: ListToolPoliciesByIDs :many
+-- List an organization's tool policies among a set of IDs (for policy packs)
+SELECT
+    id,
+    org_id,
+    team_id,
+    employee_id,
+    policy_type,
+    tool_name,
+    conditions,
+    action,
+    reason,
+    created_by,
+    created_at,
+    updated_at
+FROM tool_policies
+WHERE org_id = sqlc.arg(org_id) AND id = ANY(sqlc.arg(ids)::uuid[])
+ORDER BY created_at;
+
+-- name: ListScheduledToolPolicies :many
+-- List the tool policies of every organization that have a schedule, for the
+-- policy scheduler to push activations and deactivations at their boundaries
+SELECT
+    id,
+    org_id,
+    team_id,
+    employee_id,
+    policy_type,
+    tool_name,
+    conditions,
+    action,
+    reason,
+    created_by,
+    created_at,
+    updated_at
+FROM tool_policies
+WHERE conditions -> 'schedule' IS NOT NULL
+ORDER BY org_id, created_at;
+
 -- name: ListToolPoliciesFiltered :many
 -- List tool policies with optional filters
 SELECT
@@ -106,6 +171,7 @@ SELECT
     org_id,
     team_id,
     employee_id,
+    policy_type,
     tool_name,
     conditions,
     action,
@@ -151,6 +217,36 @@ SET
 WHERE id = sqlc.arg(id) AND org_id = sqlc.arg(org_id)
 RETURNING *;
 
+-- name: ReplaceToolPolicyByOrg :one
+-- Set every field of a tool policy but its scope and type, with org_id check
+-- (for rollbacks, which may clear the reason or conditions)
+UPDATE tool_policies
+SET
+    tool_name = sqlc.arg(tool_name),
+    conditions = sqlc.narg(conditions),
+    action = sqlc.arg(action),
+    reason = sqlc.narg(reason),
+    updated_at = NOW()
+WHERE id = sqlc.arg(id) AND org_id = sqlc.arg(org_id)
+RETURNING *;
+
+-- name: RestoreToolPolicy :one
+-- Recreate a deleted tool policy under its old ID (for rollbacks)
+INSERT INTO tool_policies (
+    id,
+    org_id,
+    team_id,
+    employee_id,
+    policy_type,
+    tool_name,
+    conditions,
+    action,
+    reason,
+    created_by
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+) RETURNING *;
+
 -- name: DeleteToolPolicy :exec
 -- Delete a tool policy
 DELETE FROM tool_policies
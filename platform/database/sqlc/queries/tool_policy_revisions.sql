@@ -0,0 +1,53 @@
+-- name: CreateToolPolicyRevision :one
+-- Append a revision to a tool policy's history, numbered after the last one
+INSERT INTO tool_policy_revisions (
+    policy_id,
+    org_id,
+    revision,
+    change_type,
+    snapshot,
+    diff,
+    note,
+    rolled_back_to,
+    changed_by,
+    session_id
+) VALUES (
+    sqlc.arg(policy_id),
+    sqlc.arg(org_id),
+    (SELECT COALESCE(MAX(revision), 0) + 1 FROM tool_policy_revisions WHERE policy_id = sqlc.arg(policy_id)),
+    sqlc.arg(change_type),
+    sqlc.arg(snapshot),
+    sqlc.arg(diff),
+    sqlc.narg(note),
+    sqlc.narg(rolled_back_to),
+    sqlc.narg(changed_by),
+    sqlc.narg(session_id)
+) RETURNING *;
+
+-- name: ListToolPolicyRevisions :many
+-- List a tool policy's history, newest first, with the email of each author
+SELECT
+    r.id,
+    r.policy_id,
+    r.org_id,
+    r.revision,
+    r.change_type,
+    r.snapshot,
+    r.diff,
+    r.note,
+    r.rolled_back_to,
+    r.changed_by,
+    r.session_id,
+    r.created_at,
+    e.email AS changed_by_email
+FROM tool_policy_revisions r
+LEFT JOIN employees e ON r.changed_by = e.id
+WHERE r.policy_id = sqlc.arg(policy_id) AND r.org_id = sqlc.arg(org_id)
+ORDER BY r.revision DESC;
+
+-- name: GetToolPolicyRevision :one
+-- Get one revision of a tool policy with org_id check (for authorization)
+SELECT * FROM tool_policy_revisions
+WHERE policy_id = sqlc.arg(policy_id)
+    AND org_id = sqlc.arg(org_id)
+    AND revision = sqlc.arg(revision);
@@ -61,6 +61,10 @@ func main() {
 	policyListener := websocket.NewPolicyListener(dbPool, policyHub)
 	policyListener.Start(ctx)
 
+	// Push scheduled policies to proxies as they start and stop applying
+	policyScheduler := websocket.NewPolicyScheduler(queries, policyHub)
+	policyScheduler.Start(ctx)
+
 	// Create handlers
 	healthHandler := handlers.NewHealthHandler()
 	authHandler := handlers.NewAuthHandler(queries)
@@ -70,10 +74,14 @@ func main() {
 	teamsHandler := handlers.NewTeamsHandler(queries)
 	activityLogsHandler := handlers.NewActivityLogsHandler(queries)
 	logsHandler := handlers.NewLogsHandler(queries, wsHub)
+	logsHandler.SetPolicyHub(policyHub)
 	wsHandler := websocket.NewHandler(wsHub)
 	policyWSHandler := websocket.NewPolicyHandler(policyHub, queries)
 	toolPoliciesHandler := handlers.NewToolPoliciesHandler(queries)
+	toolPoliciesHandler.SetTxRunner(service.PoolTxRunner(dbPool))
 	webhooksHandler := handlers.NewWebhooksHandler(queries)
+	approvalsHandler := handlers.NewApprovalsHandler(policyHub)
+	budgetsHandler := handlers.NewBudgetsHandler(queries, policyHub)
 
 	// Email service (MockEmailService for development)
 	emailService := service.NewMockEmailService()
@@ -159,6 +167,19 @@ func main() {
 				})
 			})
 
+			// =================================================================
+			// Approver Routes (admin or approver)
+			// Deciding tool calls paused by require_approval policies
+			// =================================================================
+			r.Group(func(r chi.Router) {
+				r.Use(authmiddleware.RequireRole(queries, "admin", "approver"))
+
+				r.Route("/approvals", func(r chi.Router) {
+					r.Get("/", approvalsHandler.ListToolApprovals)
+					r.Post("/{approval_id}/decision", approvalsHandler.DecideToolApproval)
+				})
+			})
+
 			// =================================================================
 			// Manager Routes (admin or manager)
 			// These are management endpoints for team leads and admins
@@ -175,6 +196,13 @@ func main() {
 					r.Delete("/{employee_id}", employeesHandler.DeleteEmployee)
 				})
 
+				// Token and cost budgets
+				r.Route("/budgets", func(r chi.Router) {
+					r.Get("/", budgetsHandler.ListBudgets)
+					r.Post("/", budgetsHandler.CreateBudget)
+					r.Delete("/{budget_id}", budgetsHandler.DeleteBudget)
+				})
+
 				// Teams routes
 				r.Route("/teams", func(r chi.Router) {
 					r.Get("/", teamsHandler.ListTeams)
@@ -206,14 +234,26 @@ func main() {
 				r.Get("/", toolPoliciesHandler.GetEmployeeToolPolicies)
 			})
 
+			// Budgets covering the current user (read-only)
+			r.Route("/employees/me/budgets", func(r chi.Router) {
+				r.Get("/", budgetsHandler.GetEmployeeBudgets)
+			})
+
 			// Tool policies CRUD routes (admin/manager)
 			r.Route("/policies", func(r chi.Router) {
 				r.Get("/", toolPoliciesHandler.ListToolPolicies)
 				r.Post("/", toolPoliciesHandler.CreateToolPolicy)
+				r.Post("/simulate", toolPoliciesHandler.SimulateToolPolicies)
+				r.Get("/export", toolPoliciesHandler.ExportToolPolicies)
+				r.With(authmiddleware.RequireRole(queries, "admin", "manager")).Post("/apply", toolPoliciesHandler.ApplyToolPolicies)
+				r.Get("/packs", toolPoliciesHandler.ListPolicyPacks)
+				r.With(authmiddleware.RequireRole(queries, "admin", "manager")).Post("/packs/{pack_name}/install", toolPoliciesHandler.InstallPolicyPack)
 				r.Route("/{policy_id}", func(r chi.Router) {
 					r.Get("/", toolPoliciesHandler.GetToolPolicy)
-					r.Patch("/", toolPoliciesHandler.UpdateToolPolicy)
+					r.With(authmiddleware.RequireRole(queries, "admin", "manager")).Patch("/", toolPoliciesHandler.UpdateToolPolicy)
 					r.Delete("/", toolPoliciesHandler.DeleteToolPolicy)
+					r.Get("/history", toolPoliciesHandler.GetToolPolicyHistory)
+					r.With(authmiddleware.RequireRole(queries, "admin", "manager")).Post("/rollback", toolPoliciesHandler.RollbackToolPolicy)
 				})
 			})
 
@@ -305,8 +345,9 @@ func main() {
 
 	log.Println("🛑 Shutting down server...")
 
-	// Stop policy listener
+	// Stop policy listener and scheduler
 	policyListener.Stop()
+	policyScheduler.Stop()
 	policyHub.Stop()
 
 	// Stop webhook forwarder
@@ -14,6 +14,7 @@ require (
 	github.com/swaggo/http-swagger/v2 v2.0.2
 	github.com/testcontainers/testcontainers-go v0.33.0
 	github.com/rastrigin-systems/arfa/generated v0.0.0
+	github.com/rastrigin-systems/arfa/pkg v0.0.0
 	go.uber.org/mock v0.6.0
 	golang.org/x/crypto v0.41.0
 	golang.org/x/time v0.14.0
@@ -93,3 +94,4 @@ require (
 )
 
 replace github.com/rastrigin-systems/arfa/generated => ../../generated
+replace github.com/rastrigin-systems/arfa/pkg => ../../pkg
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/go-chi/chi/v5"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+	"github.com/rastrigin-systems/arfa/generated/api"
+	"github.com/rastrigin-systems/arfa/services/api/internal/middleware"
+	"github.com/rastrigin-systems/arfa/services/api/internal/websocket"
+)
+
+// ApprovalsHandler handles remote decisions on tool calls paused by
+// require_approval policies. Pending approvals live in the PolicyHub,
+// reported by the proxies holding the tool calls.
+type ApprovalsHandler struct {
+	hub *websocket.PolicyHub
+}
+
+// approvalRoles are the roles that can decide approvals
+var approvalRoles = []string{"admin", "approver"}
+
+// NewApprovalsHandler creates a new approvals handler
+func NewApprovalsHandler(hub *websocket.PolicyHub) *ApprovalsHandler {
+	return &ApprovalsHandler{
+		hub: hub,
+	}
+}
+
+// ListToolApprovals handles GET /approvals
+// Returns the organization's tool calls waiting for a decision
+func (h *ApprovalsHandler) ListToolApprovals(w http.ResponseWriter, r *http.Request) {
+	orgID, err := middleware.GetOrgID(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	pending := h.hub.PendingApprovals(orgID)
+	approvals := make([]api.ToolApproval, len(pending))
+	for i, req := range pending {
+		approvals[i] = approvalRequestToAPI(req)
+	}
+
+	response := api.ListToolApprovalsResponse{
+		Approvals: approvals,
+		Total:     len(approvals),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// DecideToolApproval handles POST /approvals/{approval_id}/decision
+// Sends the decision to the proxy holding the tool call. Only admins and
+// approvers can decide, and never on their own tool calls.
+func (h *ApprovalsHandler) DecideToolApproval(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	orgID, err := middleware.GetOrgID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	employeeID, err := middleware.GetEmployeeID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	roleName, err := middleware.GetRoleName(ctx)
+	if err != nil || !slices.Contains(approvalRoles, roleName) {
+		writeError(w, http.StatusForbidden, "Insufficient permissions")
+		return
+	}
+
+	var req api.ToolApprovalDecisionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	decision := websocket.ApprovalDecision{
+		ApprovalID: chi.URLParam(r, "approval_id"),
+		DecidedBy:  employeeID.String(),
+	}
+	switch req.Decision {
+	case api.ToolApprovalDecisionRequestDecisionApprove:
+		decision.Approved = true
+	case api.ToolApprovalDecisionRequestDecisionDeny:
+		decision.Approved = false
+	default:
+		writeError(w, http.StatusBadRequest, "decision must be approve or deny")
+		return
+	}
+	if req.Reason != nil {
+		decision.Reason = *req.Reason
+	}
+
+	if err := h.hub.DecideApproval(orgID, decision); err != nil {
+		if errors.Is(err, websocket.ErrApprovalNotFound) {
+			writeError(w, http.StatusNotFound, "Approval not found or already decided")
+			return
+		}
+		if errors.Is(err, websocket.ErrSelfApproval) {
+			writeError(w, http.StatusForbidden, "You can't decide your own tool call")
+			return
+		}
+		writeError(w, http.StatusServiceUnavailable, "Failed to deliver decision to proxy")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// approvalRequestToAPI converts a pending approval to an API ToolApproval
+func approvalRequestToAPI(req websocket.ApprovalRequest) api.ToolApproval {
+	approval := api.ToolApproval{
+		Id:          req.ID,
+		EmployeeId:  openapi_types.UUID(req.EmployeeID),
+		ToolName:    req.ToolName,
+		RequestedAt: req.RequestedAt,
+		ExpiresAt:   req.ExpiresAt,
+	}
+
+	if req.PolicyID != "" {
+		approval.PolicyId = &req.PolicyID
+	}
+	if req.ToolInput != nil {
+		approval.ToolInput = &req.ToolInput
+	}
+	if req.Reason != "" {
+		approval.Reason = &req.Reason
+	}
+	if req.SessionID != "" {
+		approval.SessionId = &req.SessionID
+	}
+
+	return approval
+}
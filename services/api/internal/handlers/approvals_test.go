@@ -0,0 +1,143 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rastrigin-systems/arfa/generated/api"
+	"github.com/rastrigin-systems/arfa/services/api/internal/handlers"
+	"github.com/rastrigin-systems/arfa/services/api/internal/websocket"
+)
+
+// ============================================================================
+// ListToolApprovals Tests
+// ============================================================================
+
+func TestListToolApprovals_Empty(t *testing.T) {
+	handler := handlers.NewApprovalsHandler(websocket.NewPolicyHub())
+
+	req := httptest.NewRequest(http.MethodGet, "/approvals", nil)
+	req = req.WithContext(handlers.SetOrgIDInContext(req.Context(), uuid.New()))
+	rec := httptest.NewRecorder()
+
+	handler.ListToolApprovals(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var response api.ListToolApprovalsResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	assert.Empty(t, response.Approvals)
+	assert.Equal(t, 0, response.Total)
+}
+
+func TestListToolApprovals_Unauthorized(t *testing.T) {
+	handler := handlers.NewApprovalsHandler(websocket.NewPolicyHub())
+
+	req := httptest.NewRequest(http.MethodGet, "/approvals", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ListToolApprovals(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+// ============================================================================
+// DecideToolApproval Tests
+// ============================================================================
+
+func decideApprovalRequest(t *testing.T, approvalID string, body interface{}) *http.Request {
+	t.Helper()
+	return decideApprovalRequestAs(t, uuid.New(), uuid.New(), "admin", approvalID, body)
+}
+
+// decideApprovalRequestAs builds a decision request made by an employee with a role
+func decideApprovalRequestAs(t *testing.T, orgID, employeeID uuid.UUID, roleName, approvalID string, body interface{}) *http.Request {
+	t.Helper()
+	payload, err := json.Marshal(body)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/approvals/"+approvalID+"/decision", bytes.NewReader(payload))
+	ctx := handlers.SetOrgIDInContext(req.Context(), orgID)
+	ctx = handlers.SetEmployeeIDInContext(ctx, employeeID)
+	if roleName != "" {
+		ctx = handlers.SetRoleNameInContext(ctx, roleName)
+	}
+
+	chiCtx := chi.NewRouteContext()
+	chiCtx.URLParams.Add("approval_id", approvalID)
+	return req.WithContext(handlers.WithChiContext(ctx, chiCtx))
+}
+
+func TestDecideToolApproval_NotFound(t *testing.T) {
+	handler := handlers.NewApprovalsHandler(websocket.NewPolicyHub())
+
+	req := decideApprovalRequest(t, "apr-unknown", map[string]string{"decision": "approve"})
+	rec := httptest.NewRecorder()
+
+	handler.DecideToolApproval(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestDecideToolApproval_InvalidDecision(t *testing.T) {
+	handler := handlers.NewApprovalsHandler(websocket.NewPolicyHub())
+
+	req := decideApprovalRequest(t, "apr-1", map[string]string{"decision": "maybe"})
+	rec := httptest.NewRecorder()
+
+	handler.DecideToolApproval(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestDecideToolApproval_RequiresApproverRole(t *testing.T) {
+	handler := handlers.NewApprovalsHandler(websocket.NewPolicyHub())
+
+	for _, roleName := range []string{"member", "manager", ""} {
+		req := decideApprovalRequestAs(t, uuid.New(), uuid.New(), roleName, "apr-1", map[string]string{"decision": "approve"})
+		rec := httptest.NewRecorder()
+
+		handler.DecideToolApproval(rec, req)
+
+		assert.Equal(t, http.StatusForbidden, rec.Code, "role %q", roleName)
+	}
+
+	// Approvers get through to the hub
+	req := decideApprovalRequestAs(t, uuid.New(), uuid.New(), "approver", "apr-1", map[string]string{"decision": "approve"})
+	rec := httptest.NewRecorder()
+
+	handler.DecideToolApproval(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestDecideToolApproval_SelfApproval(t *testing.T) {
+	hub := websocket.NewPolicyHub()
+	go hub.Run()
+	defer hub.Stop()
+	handler := handlers.NewApprovalsHandler(hub)
+
+	orgID := uuid.New()
+	conn := &websocket.PolicyConn{ID: uuid.New().String(), OrgID: orgID, EmployeeID: uuid.New()}
+	hub.Register(conn)
+	require.Eventually(t, func() bool { return hub.IsConnected(conn.ID) }, time.Second, 10*time.Millisecond)
+	hub.AddApproval(conn, websocket.ApprovalRequest{ID: "apr-1", ToolName: "Bash", ExpiresAt: time.Now().Add(time.Minute)})
+
+	// An admin can't approve their own tool call
+	req := decideApprovalRequestAs(t, orgID, conn.EmployeeID, "admin", "apr-1", map[string]string{"decision": "approve"})
+	rec := httptest.NewRecorder()
+
+	handler.DecideToolApproval(rec, req)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Len(t, hub.PendingApprovals(orgID), 1, "the approval is still pending")
+}
@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+	"github.com/rastrigin-systems/arfa/generated/api"
+	"github.com/rastrigin-systems/arfa/generated/db"
+	"github.com/rastrigin-systems/arfa/services/api/internal/middleware"
+	"github.com/rastrigin-systems/arfa/services/api/internal/service"
+	"github.com/rastrigin-systems/arfa/services/api/internal/websocket"
+)
+
+// BudgetsHandler handles token and cost budget requests.
+// Changes are pushed to the affected proxies through the PolicyHub.
+type BudgetsHandler struct {
+	db      db.Querier
+	budgets *service.BudgetService
+	hub     *websocket.PolicyHub
+}
+
+// NewBudgetsHandler creates a new budgets handler
+func NewBudgetsHandler(database db.Querier, hub *websocket.PolicyHub) *BudgetsHandler {
+	return &BudgetsHandler{
+		db:      database,
+		budgets: service.NewBudgetService(database),
+		hub:     hub,
+	}
+}
+
+// ListBudgets handles GET /budgets
+// Returns the organization's budgets with their usage in the current period
+func (h *BudgetsHandler) ListBudgets(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	orgID, err := middleware.GetOrgID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	budgets, err := h.db.ListBudgets(ctx, orgID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to fetch budgets")
+		return
+	}
+
+	apiBudgets := make([]api.Budget, 0, len(budgets))
+	for _, budget := range budgets {
+		status, err := h.budgets.Status(ctx, budget)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to fetch budget usage")
+			return
+		}
+		apiBudgets = append(apiBudgets, budgetStatusToAPI(status))
+	}
+
+	writeBudgets(w, apiBudgets)
+}
+
+// GetEmployeeBudgets handles GET /employees/me/budgets
+// Returns the budgets that cover the authenticated employee
+func (h *BudgetsHandler) GetEmployeeBudgets(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	employeeID, err := middleware.GetEmployeeID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	orgID, err := middleware.GetOrgID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	sessionData, err := middleware.GetSessionData(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to get session data")
+		return
+	}
+
+	statuses, err := h.budgets.StatusForEmployee(ctx, orgID, employeeID, sessionData.TeamID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to fetch budgets")
+		return
+	}
+
+	apiBudgets := make([]api.Budget, len(statuses))
+	for i, status := range statuses {
+		apiBudgets[i] = budgetStatusToAPI(status)
+	}
+
+	writeBudgets(w, apiBudgets)
+}
+
+// CreateBudget handles POST /budgets
+func (h *BudgetsHandler) CreateBudget(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	orgID, err := middleware.GetOrgID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	employeeID, err := middleware.GetEmployeeID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req api.CreateBudgetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Validate
+	switch req.Period {
+	case api.CreateBudgetRequestPeriodDaily, api.CreateBudgetRequestPeriodMonthly:
+	default:
+		writeError(w, http.StatusBadRequest, "period must be daily or monthly")
+		return
+	}
+	if req.TokenLimit == nil && req.CostLimitUsd == nil {
+		writeError(w, http.StatusBadRequest, "token_limit or cost_limit_usd is required")
+		return
+	}
+	if req.TokenLimit != nil && *req.TokenLimit <= 0 {
+		writeError(w, http.StatusBadRequest, "token_limit must be positive")
+		return
+	}
+	if req.CostLimitUsd != nil && *req.CostLimitUsd <= 0 {
+		writeError(w, http.StatusBadRequest, "cost_limit_usd must be positive")
+		return
+	}
+	if req.TeamId != nil && req.EmployeeId != nil {
+		writeError(w, http.StatusBadRequest, "A budget applies to a team or an employee, not both")
+		return
+	}
+
+	params := db.CreateBudgetParams{
+		OrgID:        orgID,
+		Period:       string(req.Period),
+		TokenLimit:   req.TokenLimit,
+		CostLimitUsd: req.CostLimitUsd,
+		CreatedBy:    pgtype.UUID{Bytes: employeeID, Valid: true},
+	}
+	if req.TeamId != nil {
+		params.TeamID = pgtype.UUID{Bytes: uuid.UUID(*req.TeamId), Valid: true}
+	}
+	if req.EmployeeId != nil {
+		params.EmployeeID = pgtype.UUID{Bytes: uuid.UUID(*req.EmployeeId), Valid: true}
+	}
+
+	budget, err := h.db.CreateBudget(ctx, params)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to create budget")
+		return
+	}
+
+	// Usage already spent this period counts against the new budget
+	status, err := h.budgets.Status(ctx, budget)
+	if err != nil {
+		status = service.BudgetStatus{Budget: budget}
+	}
+	if h.hub != nil {
+		h.hub.NotifyBudget(websocket.BudgetStatusToData(status))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(budgetStatusToAPI(status))
+}
+
+// DeleteBudget handles DELETE /budgets/{budget_id}
+func (h *BudgetsHandler) DeleteBudget(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	orgID, err := middleware.GetOrgID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	budgetID, err := uuid.Parse(chi.URLParam(r, "budget_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid budget ID")
+		return
+	}
+
+	budget, err := h.db.GetBudgetByIdAndOrg(ctx, db.GetBudgetByIdAndOrgParams{
+		ID:    budgetID,
+		OrgID: orgID,
+	})
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Budget not found")
+		return
+	}
+
+	if err := h.db.DeleteBudget(ctx, db.DeleteBudgetParams{ID: budgetID, OrgID: orgID}); err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to delete budget")
+		return
+	}
+
+	if h.hub != nil {
+		h.hub.NotifyBudgetDeleted(websocket.BudgetStatusToData(service.BudgetStatus{Budget: budget}))
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeBudgets writes a ListBudgetsResponse
+func writeBudgets(w http.ResponseWriter, budgets []api.Budget) {
+	response := api.ListBudgetsResponse{
+		Budgets: budgets,
+		Total:   len(budgets),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// budgetStatusToAPI converts a budget and its usage to an API Budget
+func budgetStatusToAPI(status service.BudgetStatus) api.Budget {
+	b := status.Budget
+	budget := api.Budget{
+		Id:           openapi_types.UUID(b.ID),
+		OrgId:        openapi_types.UUID(b.OrgID),
+		Period:       api.BudgetPeriod(b.Period),
+		TokenLimit:   b.TokenLimit,
+		CostLimitUsd: b.CostLimitUsd,
+		TokensUsed:   status.TokensUsed,
+		CostUsedUsd:  status.CostUsedUSD,
+		Exceeded:     status.Exceeded(),
+		ResetsAt:     status.ResetsAt,
+		CreatedAt:    b.CreatedAt.Time,
+	}
+
+	if b.TeamID.Valid {
+		teamID := openapi_types.UUID(b.TeamID.Bytes)
+		budget.TeamId = &teamID
+	}
+	if b.EmployeeID.Valid {
+		employeeID := openapi_types.UUID(b.EmployeeID.Bytes)
+		budget.EmployeeId = &employeeID
+	}
+
+	switch {
+	case b.EmployeeID.Valid:
+		budget.Scope = api.BudgetScopeEmployee
+	case b.TeamID.Valid:
+		budget.Scope = api.BudgetScopeTeam
+	default:
+		budget.Scope = api.BudgetScopeOrganization
+	}
+
+	return budget
+}
@@ -0,0 +1,131 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rastrigin-systems/arfa/generated/api"
+	"github.com/rastrigin-systems/arfa/generated/db"
+	"github.com/rastrigin-systems/arfa/generated/mocks"
+	"github.com/rastrigin-systems/arfa/services/api/internal/handlers"
+	"github.com/rastrigin-systems/arfa/services/api/internal/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func createBudgetRequest(t *testing.T, body interface{}) *http.Request {
+	t.Helper()
+	payload, err := json.Marshal(body)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/budgets", bytes.NewReader(payload))
+	ctx := handlers.SetOrgIDInContext(req.Context(), uuid.New())
+	ctx = handlers.SetEmployeeIDInContext(ctx, uuid.New())
+	return req.WithContext(ctx)
+}
+
+func TestCreateBudget_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mocks.NewMockQuerier(ctrl)
+	handler := handlers.NewBudgetsHandler(mockDB, websocket.NewPolicyHub())
+
+	teamID := uuid.New()
+	tokenLimit := int64(2000000)
+	req := createBudgetRequest(t, map[string]interface{}{
+		"period":      "monthly",
+		"token_limit": tokenLimit,
+		"team_id":     teamID,
+	})
+	orgID, _ := handlers.GetOrgID(req.Context())
+
+	mockDB.EXPECT().
+		CreateBudget(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ interface{}, params db.CreateBudgetParams) (db.Budget, error) {
+			assert.Equal(t, "monthly", params.Period)
+			assert.Equal(t, pgtype.UUID{Bytes: teamID, Valid: true}, params.TeamID)
+			assert.False(t, params.EmployeeID.Valid)
+			return db.Budget{
+				ID:         uuid.New(),
+				OrgID:      orgID,
+				TeamID:     params.TeamID,
+				Period:     params.Period,
+				TokenLimit: params.TokenLimit,
+			}, nil
+		})
+	mockDB.EXPECT().
+		GetUsageSince(gomock.Any(), gomock.Any()).
+		Return(db.GetUsageSinceRow{Tokens: 2500000, CostUsd: 12.5}, nil)
+
+	rec := httptest.NewRecorder()
+	handler.CreateBudget(rec, req)
+
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	var budget api.Budget
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&budget))
+	assert.Equal(t, api.BudgetScopeTeam, budget.Scope)
+	assert.Equal(t, int64(2500000), budget.TokensUsed)
+	assert.True(t, budget.Exceeded, "usage earlier in the period counts")
+}
+
+func TestCreateBudget_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		body map[string]interface{}
+	}{
+		{"unknown period", map[string]interface{}{"period": "weekly", "token_limit": 1000}},
+		{"no limit", map[string]interface{}{"period": "daily"}},
+		{"zero token limit", map[string]interface{}{"period": "daily", "token_limit": 0}},
+		{"negative cost limit", map[string]interface{}{"period": "daily", "cost_limit_usd": -5}},
+		{"team and employee", map[string]interface{}{"period": "daily", "token_limit": 1000, "team_id": uuid.New(), "employee_id": uuid.New()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// Invalid budgets are rejected before reaching the database
+			handler := handlers.NewBudgetsHandler(mocks.NewMockQuerier(ctrl), nil)
+
+			rec := httptest.NewRecorder()
+			handler.CreateBudget(rec, createBudgetRequest(t, tt.body))
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		})
+	}
+}
+
+func TestDeleteBudget_NotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mocks.NewMockQuerier(ctrl)
+	handler := handlers.NewBudgetsHandler(mockDB, nil)
+
+	mockDB.EXPECT().
+		GetBudgetByIdAndOrg(gomock.Any(), gomock.Any()).
+		Return(db.Budget{}, assert.AnError)
+
+	budgetID := uuid.New()
+	chiCtx := chi.NewRouteContext()
+	chiCtx.URLParams.Add("budget_id", budgetID.String())
+
+	req := httptest.NewRequest(http.MethodDelete, "/budgets/"+budgetID.String(), nil)
+	ctx := handlers.SetOrgIDInContext(req.Context(), uuid.New())
+	ctx = handlers.WithChiContext(ctx, chiCtx)
+	rec := httptest.NewRecorder()
+
+	handler.DeleteBudget(rec, req.WithContext(ctx))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
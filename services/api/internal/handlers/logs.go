@@ -3,6 +3,7 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 	"time"
 
@@ -12,6 +13,8 @@ import (
 
 	"github.com/rastrigin-systems/arfa/generated/api"
 	"github.com/rastrigin-systems/arfa/generated/db"
+	"github.com/rastrigin-systems/arfa/pkg/pricing"
+	"github.com/rastrigin-systems/arfa/services/api/internal/middleware"
 	"github.com/rastrigin-systems/arfa/services/api/internal/service"
 	"github.com/rastrigin-systems/arfa/services/api/internal/websocket"
 )
@@ -20,7 +23,9 @@ import (
 type LogsHandler struct {
 	db             db.Querier
 	loggingService *service.LoggingService
+	budgetService  *service.BudgetService
 	wsHub          *websocket.Hub
+	policyHub      *websocket.PolicyHub
 }
 
 // NewLogsHandler creates a new logs handler
@@ -28,10 +33,17 @@ func NewLogsHandler(database db.Querier, wsHub *websocket.Hub) *LogsHandler {
 	return &LogsHandler{
 		db:             database,
 		loggingService: service.NewLoggingService(database),
+		budgetService:  service.NewBudgetService(database),
 		wsHub:          wsHub,
 	}
 }
 
+// SetPolicyHub sets the hub that pushes budget usage to proxies after
+// llm_usage logs are recorded.
+func (h *LogsHandler) SetPolicyHub(hub *websocket.PolicyHub) {
+	h.policyHub = hub
+}
+
 // CreateLog implements POST /logs
 func (h *LogsHandler) CreateLog(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -82,6 +94,9 @@ func (h *LogsHandler) CreateLog(w http.ResponseWriter, r *http.Request) {
 		entry.Content = *req.Content
 	}
 	if req.Payload != nil {
+		if req.EventType == api.CreateLogRequestEventTypeLlmUsage {
+			priceUsage(*req.Payload)
+		}
 		entry.Payload = *req.Payload
 	}
 
@@ -92,6 +107,11 @@ func (h *LogsHandler) CreateLog(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Count reported LLM usage against the employee's budgets
+	if req.EventType == api.CreateLogRequestEventTypeLlmUsage && req.Payload != nil && employeeID != uuid.Nil {
+		h.recordUsage(r, orgID, employeeID, *req.Payload)
+	}
+
 	// Fetch the created log to return it
 	// For now, we'll construct a response from the input
 	// In a real implementation, the service would return the created log
@@ -312,3 +332,48 @@ func dbLogToAPI(log db.ActivityLog) api.ActivityLog {
 
 	return apiLog
 }
+
+// recordUsage adds the usage of an llm_usage log to the employee's budgets and
+// pushes the updated totals to the proxies they cover. The log is already
+// stored, so failures are logged rather than returned - a retried upload
+// would count the usage twice.
+func (h *LogsHandler) recordUsage(r *http.Request, orgID, employeeID uuid.UUID, payload map[string]interface{}) {
+	usage := service.UsageRecord{
+		OrgID:        orgID,
+		EmployeeID:   employeeID,
+		InputTokens:  int64(payloadNumber(payload, "input_tokens")),
+		OutputTokens: int64(payloadNumber(payload, "output_tokens")),
+		CostUSD:      payloadNumber(payload, "cost_usd"),
+	}
+	if sessionData, err := middleware.GetSessionData(r.Context()); err == nil {
+		usage.TeamID = sessionData.TeamID
+	}
+
+	statuses, err := h.budgetService.RecordUsage(r.Context(), usage)
+	if err != nil {
+		log.Printf("Failed to record usage for employee %s: %v", employeeID, err)
+		return
+	}
+
+	if h.policyHub != nil {
+		for _, status := range statuses {
+			h.policyHub.NotifyBudget(websocket.BudgetStatusToData(status))
+		}
+	}
+}
+
+// priceUsage sets the cost_usd of an llm_usage log payload from its model
+// and token counts, replacing whatever the client reported, so budgets and
+// logs don't depend on the client's price list
+func priceUsage(payload map[string]interface{}) {
+	model, _ := payload["model"].(string)
+	payload["cost_usd"] = pricing.EstimateCost(model,
+		int(payloadNumber(payload, "input_tokens")),
+		int(payloadNumber(payload, "output_tokens")))
+}
+
+// payloadNumber reads a JSON number from a log payload (0 if missing)
+func payloadNumber(payload map[string]interface{}, key string) float64 {
+	n, _ := payload[key].(float64)
+	return n
+}
@@ -95,6 +95,60 @@ func TestCreateLog_Success(t *testing.T) {
 	assert.Equal(t, "io", response.EventCategory)
 }
 
+func TestCreateLog_LLMUsageCountsAgainstBudgets(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mocks.NewMockQuerier(ctrl)
+
+	orgID := uuid.New()
+	employeeID := uuid.New()
+
+	requestBody := api.CreateLogRequest{
+		EventType:     "llm_usage",
+		EventCategory: "proxy",
+		Payload: &map[string]interface{}{
+			"model":         "claude-sonnet-4-5",
+			"input_tokens":  1200,
+			"output_tokens": 300,
+			"cost_usd":      0.0001, // The server prices usage itself
+		},
+	}
+
+	mockDB.EXPECT().
+		CreateActivityLog(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, params db.CreateActivityLogParams) (db.ActivityLog, error) {
+			var payload map[string]interface{}
+			require.NoError(t, json.Unmarshal(params.Payload, &payload))
+			assert.InDelta(t, 0.0081, payload["cost_usd"], 1e-9, "the logged cost is the server's")
+			return db.ActivityLog{ID: uuid.New(), OrgID: orgID}, nil
+		})
+	mockDB.EXPECT().
+		RecordUsage(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, params db.RecordUsageParams) error {
+			assert.Equal(t, employeeID, params.EmployeeID)
+			assert.Equal(t, int64(1200), params.InputTokens)
+			assert.Equal(t, int64(300), params.OutputTokens)
+			assert.InDelta(t, 0.0081, params.CostUsd, 1e-9)
+			return nil
+		})
+	mockDB.EXPECT().
+		GetBudgetsForEmployee(gomock.Any(), gomock.Any()).
+		Return([]db.Budget{}, nil)
+
+	handler := handlers.NewLogsHandler(mockDB, nil)
+
+	bodyBytes, _ := json.Marshal(requestBody)
+	req := httptest.NewRequest(http.MethodPost, "/logs", bytes.NewReader(bodyBytes))
+	req = req.WithContext(handlers.SetOrgIDInContext(req.Context(), orgID))
+	req = req.WithContext(handlers.SetEmployeeIDInContext(req.Context(), employeeID))
+
+	rec := httptest.NewRecorder()
+	handler.CreateLog(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+}
+
 func TestCreateLog_MissingRequiredFields(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -20,6 +20,12 @@ func SetEmployeeIDInContext(ctx context.Context, employeeID uuid.UUID) context.C
 	return middleware.SetEmployeeIDForTest(ctx, employeeID)
 }
 
+// SetRoleNameInContext is a test helper to set role_name in context
+// This simulates what the RequireRole middleware does in production
+func SetRoleNameInContext(ctx context.Context, roleName string) context.Context {
+	return middleware.SetRoleNameForTest(ctx, roleName)
+}
+
 // SetSessionDataInContext is a test helper to set session_data in context
 func SetSessionDataInContext(ctx context.Context, sessionData *db.GetSessionWithEmployeeRow) context.Context {
 	return middleware.SetSessionDataForTest(ctx, sessionData)
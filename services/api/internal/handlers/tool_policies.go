@@ -1,8 +1,13 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"path"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -11,18 +16,30 @@ import (
 	openapi_types "github.com/oapi-codegen/runtime/types"
 	"github.com/rastrigin-systems/arfa/generated/api"
 	"github.com/rastrigin-systems/arfa/generated/db"
+	"github.com/rastrigin-systems/arfa/pkg/policy"
 	"github.com/rastrigin-systems/arfa/services/api/internal/middleware"
+	"github.com/rastrigin-systems/arfa/services/api/internal/service"
 )
 
 // ToolPoliciesHandler handles tool policy-related requests
 type ToolPoliciesHandler struct {
-	db db.Querier
+	db         db.Querier
+	runTx      service.TxRunner
+	simulation *service.PolicySimulationService
+	revisions  *service.PolicyRevisionService
+	apply      *service.PolicyApplyService
+	packs      *service.PolicyPackService
 }
 
 // NewToolPoliciesHandler creates a new tool policies handler
 func NewToolPoliciesHandler(database db.Querier) *ToolPoliciesHandler {
 	return &ToolPoliciesHandler{
-		db: database,
+		db:         database,
+		runTx:      service.DirectTxRunner(database),
+		simulation: service.NewPolicySimulationService(database),
+		revisions:  service.NewPolicyRevisionService(database, nil),
+		apply:      service.NewPolicyApplyService(database, nil),
+		packs:      service.NewPolicyPackService(database, nil),
 	}
 }
 
@@ -65,6 +82,20 @@ func (h *ToolPoliciesHandler) GetEmployeeToolPolicies(w http.ResponseWriter, r *
 		return
 	}
 
+	// Leave out scheduled policies that aren't active now; their windows are
+	// in the organization's timezone
+	if service.HasScheduledPolicies(policies) {
+		org, err := h.db.GetOrganization(ctx, orgID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to fetch tool policies")
+			return
+		}
+		policies = service.ActiveToolPolicies(policies, time.Now(), service.OrgTimezone(org.Settings))
+	}
+
+	// Highest precedence first, the order the proxy resolves them in
+	service.SortToolPoliciesByPrecedence(policies)
+
 	// Convert to API response
 	apiPolicies := make([]api.ToolPolicy, len(policies))
 	for i, policy := range policies {
@@ -169,23 +200,20 @@ func (h *ToolPoliciesHandler) CreateToolPolicy(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	// Validate required fields
-	if req.ToolName == "" {
-		writeError(w, http.StatusBadRequest, "tool_name is required")
-		return
-	}
-	if req.Action == "" {
-		writeError(w, http.StatusBadRequest, "action is required")
+	policyType, err := validateCreateToolPolicy(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	// Build create params
 	params := db.CreateToolPolicyParams{
-		OrgID:     orgID,
-		ToolName:  req.ToolName,
-		Action:    string(req.Action),
-		Reason:    req.Reason,
-		CreatedBy: pgtype.UUID{Bytes: employeeID, Valid: true},
+		OrgID:      orgID,
+		PolicyType: string(policyType),
+		ToolName:   req.ToolName,
+		Action:     string(req.Action),
+		Reason:     req.Reason,
+		CreatedBy:  pgtype.UUID{Bytes: employeeID, Valid: true},
 	}
 
 	// Set optional team_id
@@ -208,7 +236,16 @@ func (h *ToolPoliciesHandler) CreateToolPolicy(w http.ResponseWriter, r *http.Re
 		params.Conditions = conditionsJSON
 	}
 
-	policy, err := h.db.CreateToolPolicy(ctx, params)
+	// The policy and its revision are saved together or not at all
+	var policy db.ToolPolicy
+	err = h.runTx(ctx, func(q db.Querier) error {
+		var err error
+		policy, err = q.CreateToolPolicy(ctx, params)
+		if err != nil {
+			return err
+		}
+		return recordChange(ctx, q, service.PolicyChangeCreate, nil, &policy, req.Note)
+	})
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "Failed to create policy")
 		return
@@ -221,6 +258,51 @@ func (h *ToolPoliciesHandler) CreateToolPolicy(w http.ResponseWriter, r *http.Re
 	_ = json.NewEncoder(w).Encode(response)
 }
 
+// validateCreateToolPolicy checks a policy to be created and returns its
+// policy type, which defaults to tool
+func validateCreateToolPolicy(req api.CreateToolPolicyRequest) (api.CreateToolPolicyRequestPolicyType, error) {
+	if req.ToolName == "" {
+		return "", fmt.Errorf("tool_name is required")
+	}
+	if req.Action == "" {
+		return "", fmt.Errorf("action is required")
+	}
+
+	policyType := api.CreateToolPolicyRequestPolicyTypeTool
+	if req.PolicyType != nil {
+		policyType = *req.PolicyType
+	}
+	if err := validateToolPolicy(string(policyType), string(req.Action), req.ToolName, req.Conditions); err != nil {
+		return "", err
+	}
+	return policyType, nil
+}
+
+// validateToolPolicy checks a policy's action, tool name and conditions
+// against its type. Updates check the policy as it will be after the update,
+// since the rules tie the fields together.
+func validateToolPolicy(policyType, action, toolName string, conditions *map[string]interface{}) error {
+	switch {
+	case policyType == string(api.CreateToolPolicyRequestPolicyTypeModel):
+		if err := validateModelPolicy(action, conditions); err != nil {
+			return err
+		}
+	case policyType == string(api.CreateToolPolicyRequestPolicyTypePath):
+		if err := validatePathPolicy(action, toolName, conditions); err != nil {
+			return err
+		}
+	case policyType != string(api.CreateToolPolicyRequestPolicyTypeTool):
+		return fmt.Errorf("policy_type must be tool, model or path")
+	case action == string(api.CreateToolPolicyRequestActionRewrite):
+		return fmt.Errorf("rewrite is only valid for model policies")
+	case action == string(api.CreateToolPolicyRequestActionRateLimit):
+		if err := validateRateLimit(conditions); err != nil {
+			return err
+		}
+	}
+	return validateConditions(conditions)
+}
+
 // GetToolPolicy handles GET /policies/{policy_id}
 func (h *ToolPoliciesHandler) GetToolPolicy(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -269,14 +351,28 @@ func (h *ToolPoliciesHandler) UpdateToolPolicy(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	// TODO: Check role (admin for org-level, manager for team/employee-level)
-
 	var req api.UpdateToolPolicyRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
+	before, err := h.db.GetToolPolicyByIdAndOrg(ctx, db.GetToolPolicyByIdAndOrgParams{
+		ID:    policyID,
+		OrgID: orgID,
+	})
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Policy not found")
+		return
+	}
+
+	// The route lets admins and managers through; organization-wide policies
+	// are for admins only
+	if roleName, _ := middleware.GetRoleName(ctx); roleName != "admin" && !before.TeamID.Valid && !before.EmployeeID.Valid {
+		writeError(w, http.StatusForbidden, "Only admins can change organization-wide policies")
+		return
+	}
+
 	// Build update params
 	params := db.UpdateToolPolicyByOrgParams{
 		ID:    policyID,
@@ -302,11 +398,36 @@ func (h *ToolPoliciesHandler) UpdateToolPolicy(w http.ResponseWriter, r *http.Re
 		params.Conditions = conditionsJSON
 	}
 
-	policy, err := h.db.UpdateToolPolicyByOrg(ctx, params)
-	if err != nil {
+	// The rules tie action, tool name and conditions together (a rate limit
+	// lives in conditions, a path policy's pattern in its tool name), so any
+	// change to them is checked against the policy as it will be
+	if req.ToolName != nil || req.Action != nil || req.Conditions != nil {
+		if err := validateToolPolicyUpdate(before, req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	var policy db.ToolPolicy
+	err = h.runTx(ctx, func(q db.Querier) error {
+		locked, err := lockPolicy(ctx, q, policyID, orgID)
+		if err != nil {
+			return err
+		}
+		policy, err = q.UpdateToolPolicyByOrg(ctx, params)
+		if err != nil {
+			return errPolicyNotFound
+		}
+		return recordChange(ctx, q, service.PolicyChangeUpdate, &locked, &policy, req.Note)
+	})
+	if errors.Is(err, errPolicyNotFound) {
 		writeError(w, http.StatusNotFound, "Policy not found")
 		return
 	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to update policy")
+		return
+	}
 
 	response := dbToolPolicyToAPI(policy)
 
@@ -333,16 +454,492 @@ func (h *ToolPoliciesHandler) DeleteToolPolicy(w http.ResponseWriter, r *http.Re
 
 	// TODO: Check role (admin for org-level, manager for team/employee-level)
 
-	err = h.db.DeleteToolPolicyByOrg(ctx, db.DeleteToolPolicyByOrgParams{
+	// Deletes have no body, so the note comes as a query parameter
+	var note *string
+	if n := r.URL.Query().Get("note"); n != "" {
+		note = &n
+	}
+
+	err = h.runTx(ctx, func(q db.Querier) error {
+		locked, err := lockPolicy(ctx, q, policyID, orgID)
+		if err != nil {
+			return err
+		}
+		err = q.DeleteToolPolicyByOrg(ctx, db.DeleteToolPolicyByOrgParams{
+			ID:    policyID,
+			OrgID: orgID,
+		})
+		if err != nil {
+			return errPolicyNotFound
+		}
+		return recordChange(ctx, q, service.PolicyChangeDelete, &locked, nil, note)
+	})
+	if errors.Is(err, errPolicyNotFound) {
+		writeError(w, http.StatusNotFound, "Policy not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to delete policy")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetToolPolicyHistory handles GET /policies/{policy_id}/history
+// Returns every recorded change to a policy, newest first, including
+// policies that have since been deleted
+func (h *ToolPoliciesHandler) GetToolPolicyHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	orgID, err := middleware.GetOrgID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	policyID, err := uuid.Parse(chi.URLParam(r, "policy_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid policy ID")
+		return
+	}
+
+	revisions, err := h.db.ListToolPolicyRevisions(ctx, db.ListToolPolicyRevisionsParams{
+		PolicyID: policyID,
+		OrgID:    orgID,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to fetch policy history")
+		return
+	}
+	if len(revisions) == 0 {
+		// Policies saved before history was kept have none yet
+		if _, err := h.db.GetToolPolicyByIdAndOrg(ctx, db.GetToolPolicyByIdAndOrgParams{ID: policyID, OrgID: orgID}); err != nil {
+			writeError(w, http.StatusNotFound, "Policy not found")
+			return
+		}
+	}
+
+	apiRevisions := make([]api.ToolPolicyRevision, len(revisions))
+	for i, revision := range revisions {
+		apiRevisions[i] = dbToolPolicyRevisionToAPI(revision)
+	}
+
+	response := api.ToolPolicyHistoryResponse{
+		Revisions: apiRevisions,
+		Total:     len(apiRevisions),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// RollbackToolPolicy handles POST /policies/{policy_id}/rollback
+// Restores a policy to the state recorded in one of its revisions
+func (h *ToolPoliciesHandler) RollbackToolPolicy(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	orgID, err := middleware.GetOrgID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	employeeID, err := middleware.GetEmployeeID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	policyID, err := uuid.Parse(chi.URLParam(r, "policy_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid policy ID")
+		return
+	}
+
+	// The route requires the admin or manager role
+
+	var req api.RollbackToolPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Revision < 1 {
+		writeError(w, http.StatusBadRequest, "revision must be at least 1")
+		return
+	}
+
+	policy, err := h.revisions.Rollback(ctx, service.PolicyRollback{
+		OrgID:     orgID,
+		PolicyID:  policyID,
+		Revision:  int32(req.Revision),
+		ChangedBy: employeeID,
+		SessionID: requestSessionID(ctx),
+		Note:      req.Note,
+	})
+	switch {
+	case errors.Is(err, service.ErrRevisionNotFound):
+		writeError(w, http.StatusNotFound, "Revision not found")
+		return
+	case errors.Is(err, service.ErrRevisionDeleted):
+		writeError(w, http.StatusBadRequest, "That revision deleted the policy; roll back to an earlier one")
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "Failed to roll back policy")
+		return
+	}
+
+	response := dbToolPolicyToAPI(policy)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// errPolicyNotFound aborts a policy change whose policy is gone
+var errPolicyNotFound = errors.New("policy not found")
+
+// validateToolPolicyUpdate checks a policy as it will be after an update
+func validateToolPolicyUpdate(before db.ToolPolicy, req api.UpdateToolPolicyRequest) error {
+	toolName := before.ToolName
+	if req.ToolName != nil {
+		toolName = *req.ToolName
+	}
+	action := before.Action
+	if req.Action != nil {
+		action = string(*req.Action)
+	}
+	conditions := req.Conditions
+	if conditions == nil && len(before.Conditions) > 0 {
+		var stored map[string]interface{}
+		if err := json.Unmarshal(before.Conditions, &stored); err != nil {
+			return fmt.Errorf("invalid stored conditions: %v", err)
+		}
+		if stored != nil {
+			conditions = &stored
+		}
+	}
+	return validateToolPolicy(before.PolicyType, action, toolName, conditions)
+}
+
+// lockPolicy gets a policy and locks it until q's transaction ends, so
+// concurrent changes to it are made, and numbered in its history, in turn
+func lockPolicy(ctx context.Context, q db.Querier, policyID, orgID uuid.UUID) (db.ToolPolicy, error) {
+	policy, err := q.LockToolPolicyByIdAndOrg(ctx, db.LockToolPolicyByIdAndOrgParams{
 		ID:    policyID,
 		OrgID: orgID,
 	})
 	if err != nil {
-		writeError(w, http.StatusNotFound, "Policy not found")
+		return db.ToolPolicy{}, errPolicyNotFound
+	}
+	return policy, nil
+}
+
+// recordChange appends a change to its policy's history against q,
+// attributed to the employee and session making the request
+func recordChange(ctx context.Context, q db.Querier, changeType string, before, after *db.ToolPolicy, note *string) error {
+	employeeID, _ := middleware.GetEmployeeID(ctx)
+	_, _, err := service.NewPolicyRevisionService(q, nil).Record(ctx, service.PolicyChange{
+		Type:      changeType,
+		Before:    before,
+		After:     after,
+		ChangedBy: employeeID,
+		SessionID: requestSessionID(ctx),
+		Note:      note,
+	})
+	return err
+}
+
+// requestSessionID returns the ID of the session making the request, if any
+func requestSessionID(ctx context.Context) pgtype.UUID {
+	session, err := middleware.GetSessionData(ctx)
+	if err != nil {
+		return pgtype.UUID{}
+	}
+	return pgtype.UUID{Bytes: session.ID, Valid: true}
+}
+
+// defaultSimulationRange is how far back a simulation replays tool calls
+// when no start date is given
+const defaultSimulationRange = 7 * 24 * time.Hour
+
+// SimulateToolPolicies handles POST /policies/simulate
+// Replays the organization's stored tool calls against draft policies
+func (h *ToolPoliciesHandler) SimulateToolPolicies(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	orgID, err := middleware.GetOrgID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	// TODO: Check role (admin/manager)
+
+	var req api.SimulateToolPoliciesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	end := time.Now().UTC()
+	if req.EndDate != nil {
+		end = *req.EndDate
+	}
+	start := end.Add(-defaultSimulationRange)
+	if req.StartDate != nil {
+		start = *req.StartDate
+	}
+	if !end.After(start) {
+		writeError(w, http.StatusBadRequest, "end_date must be after start_date")
+		return
+	}
+
+	var policies []db.ToolPolicy
+	if req.Policies != nil {
+		for i, draft := range *req.Policies {
+			policy, err := draftToolPolicy(orgID, draft)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("policies[%d]: %v", i, err))
+				return
+			}
+			policies = append(policies, policy)
+		}
+	}
+
+	if req.IncludeExisting == nil || *req.IncludeExisting {
+		replaced := make(map[uuid.UUID]bool)
+		if req.ReplacePolicyIds != nil {
+			for _, id := range *req.ReplacePolicyIds {
+				replaced[uuid.UUID(id)] = true
+			}
+		}
+
+		saved, err := h.db.ListToolPoliciesByOrg(ctx, orgID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to list policies")
+			return
+		}
+		for _, policy := range saved {
+			if !replaced[policy.ID] {
+				policies = append(policies, policy)
+			}
+		}
+	}
+
+	report, err := h.simulation.Simulate(ctx, service.PolicySimulation{
+		OrgID:    orgID,
+		Policies: policies,
+		Start:    start,
+		End:      end,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to simulate policies")
+		return
+	}
+
+	response := api.SimulateToolPoliciesResponse{
+		StartDate:       start,
+		EndDate:         end,
+		Total:           report.Total,
+		Allowed:         report.Allowed,
+		Denied:          report.Denied,
+		RequireApproval: report.RequireApproval,
+		RateLimited:     report.RateLimited,
+		NewlyDenied:     report.NewlyDenied,
+		NewlyAllowed:    report.NewlyAllowed,
+		Groups:          make([]api.PolicySimulationGroup, len(report.Groups)),
+		SkippedPolicies: report.SkippedPolicies,
+		Truncated:       report.Truncated,
+	}
+	for i, group := range report.Groups {
+		apiGroup := api.PolicySimulationGroup{
+			EmployeeEmail:   group.EmployeeEmail,
+			ToolName:        group.ToolName,
+			Total:           group.Total,
+			Allowed:         group.Allowed,
+			Denied:          group.Denied,
+			RequireApproval: group.RequireApproval,
+			RateLimited:     group.RateLimited,
+			NewlyDenied:     group.NewlyDenied,
+			NewlyAllowed:    group.NewlyAllowed,
+		}
+		if group.EmployeeID.Valid {
+			employeeID := openapi_types.UUID(group.EmployeeID.Bytes)
+			apiGroup.EmployeeId = &employeeID
+		}
+		response.Groups[i] = apiGroup
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// draftToolPolicy validates a draft policy and converts it to the policy it
+// would be saved as
+func draftToolPolicy(orgID uuid.UUID, req api.CreateToolPolicyRequest) (db.ToolPolicy, error) {
+	policyType, err := validateCreateToolPolicy(req)
+	if err != nil {
+		return db.ToolPolicy{}, err
+	}
+
+	policy := db.ToolPolicy{
+		OrgID:      orgID,
+		PolicyType: string(policyType),
+		ToolName:   req.ToolName,
+		Action:     string(req.Action),
+		Reason:     req.Reason,
+	}
+	if req.TeamId != nil {
+		policy.TeamID = pgtype.UUID{Bytes: uuid.UUID(*req.TeamId), Valid: true}
+	}
+	if req.EmployeeId != nil {
+		policy.EmployeeID = pgtype.UUID{Bytes: uuid.UUID(*req.EmployeeId), Valid: true}
+	}
+	if req.Conditions != nil {
+		conditionsJSON, err := json.Marshal(req.Conditions)
+		if err != nil {
+			return db.ToolPolicy{}, fmt.Errorf("invalid conditions format")
+		}
+		policy.Conditions = conditionsJSON
+	}
+	return policy, nil
+}
+
+// rateLimitConfig is the limit of a rate_limit policy, kept in its conditions
+// under "rate_limit".
+type rateLimitConfig struct {
+	MaxCalls      int    `json:"max_calls"`
+	WindowSeconds int    `json:"window_seconds"`
+	Per           string `json:"per,omitempty"`
+}
+
+// maxRateLimitWindow is the longest window a rate_limit policy can use (one day).
+const maxRateLimitWindow = 86400
+
+// validateConditions checks a policy's conditions with the compiler the
+// proxy evaluates them with, so a typo can't leave a policy that silently
+// never matches, and checks its schedule
+func validateConditions(conditions *map[string]interface{}) error {
+	if conditions == nil {
+		return nil
+	}
+	return policy.Validate(*conditions)
+}
+
+// validateRateLimit checks the limit of a rate_limit policy
+func validateRateLimit(conditions *map[string]interface{}) error {
+	if conditions == nil || (*conditions)["rate_limit"] == nil {
+		return fmt.Errorf("rate_limit policies require conditions.rate_limit")
+	}
+
+	data, err := json.Marshal((*conditions)["rate_limit"])
+	if err != nil {
+		return fmt.Errorf("invalid conditions.rate_limit")
+	}
+	var limit rateLimitConfig
+	if err := json.Unmarshal(data, &limit); err != nil {
+		return fmt.Errorf("invalid conditions.rate_limit: %v", err)
+	}
+
+	if limit.MaxCalls < 1 {
+		return fmt.Errorf("rate_limit.max_calls must be at least 1")
+	}
+	if limit.WindowSeconds < 1 || limit.WindowSeconds > maxRateLimitWindow {
+		return fmt.Errorf("rate_limit.window_seconds must be between 1 and %d", maxRateLimitWindow)
+	}
+	switch limit.Per {
+	case "", "session", "employee":
+	default:
+		return fmt.Errorf("rate_limit.per must be session or employee")
+	}
+	return nil
+}
+
+// validateModelPolicy checks the action and conditions of a model policy.
+// "allowed_models" lists models the policy doesn't apply to; rewrite
+// policies send matching requests to "fallback_model" instead.
+func validateModelPolicy(action string, conditions *map[string]interface{}) error {
+	switch api.ToolPolicyAction(action) {
+	case api.ToolPolicyActionDeny, api.ToolPolicyActionAudit, api.ToolPolicyActionRewrite:
+	default:
+		return fmt.Errorf("model policies support deny, audit and rewrite")
+	}
+
+	var c map[string]interface{}
+	if conditions != nil {
+		c = *conditions
+	}
+
+	if allowed, ok := c["allowed_models"]; ok {
+		models, ok := allowed.([]interface{})
+		if !ok {
+			return fmt.Errorf("allowed_models must be a list of model names")
+		}
+		for _, m := range models {
+			if name, ok := m.(string); !ok || name == "" {
+				return fmt.Errorf("allowed_models must be a list of model names")
+			}
+		}
+	}
+
+	if api.ToolPolicyAction(action) == api.ToolPolicyActionRewrite {
+		if fallback, ok := c["fallback_model"].(string); !ok || fallback == "" {
+			return fmt.Errorf("rewrite policies require conditions.fallback_model")
+		}
+	}
+	return nil
+}
+
+// validatePathPolicy checks the action, pattern and conditions of a path
+// policy. "allowed_paths" lists globs, relative to the project root, that the
+// policy doesn't apply to.
+func validatePathPolicy(action, pattern string, conditions *map[string]interface{}) error {
+	switch api.ToolPolicyAction(action) {
+	case api.ToolPolicyActionDeny, api.ToolPolicyActionAudit:
+	default:
+		return fmt.Errorf("path policies support deny and audit")
+	}
+	if err := validatePathGlob(pattern); err != nil {
+		return fmt.Errorf("invalid path pattern: %w", err)
+	}
+
+	if conditions == nil {
+		return nil
+	}
+	allowed, ok := (*conditions)["allowed_paths"]
+	if !ok {
+		return nil
+	}
+	paths, ok := allowed.([]interface{})
+	if !ok {
+		return fmt.Errorf("allowed_paths must be a list of paths")
+	}
+	for _, p := range paths {
+		glob, ok := p.(string)
+		if !ok || glob == "" || strings.HasPrefix(glob, "/") || strings.HasPrefix(glob, "~") {
+			return fmt.Errorf("allowed_paths must be paths relative to the project root")
+		}
+		if err := validatePathGlob(glob); err != nil {
+			return fmt.Errorf("invalid allowed path: %w", err)
+		}
+	}
+	return nil
+}
+
+// validatePathGlob checks that every segment of a path glob is a valid pattern.
+func validatePathGlob(glob string) error {
+	for _, segment := range strings.Split(glob, "/") {
+		if segment == "**" {
+			continue
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("%q: %w", glob, err)
+		}
+	}
+	return nil
 }
 
 // dbToolPolicyToAPI converts a database ToolPolicy to an API ToolPolicy
@@ -357,12 +954,13 @@ func dbToolPolicyToAPI(policy db.ToolPolicy) api.ToolPolicy {
 	}
 
 	apiPolicy := api.ToolPolicy{
-		Id:        &policyID,
-		OrgId:     orgID,
-		ToolName:  policy.ToolName,
-		Action:    api.ToolPolicyAction(policy.Action),
-		Reason:    policy.Reason,
-		CreatedAt: createdAt,
+		Id:         &policyID,
+		OrgId:      orgID,
+		PolicyType: api.ToolPolicyPolicyType(policy.PolicyType),
+		ToolName:   policy.ToolName,
+		Action:     api.ToolPolicyAction(policy.Action),
+		Reason:     policy.Reason,
+		CreatedAt:  createdAt,
 	}
 
 	// Set optional IDs
@@ -399,3 +997,31 @@ func dbToolPolicyToAPI(policy db.ToolPolicy) api.ToolPolicy {
 
 	return apiPolicy
 }
+
+// dbToolPolicyRevisionToAPI converts a recorded policy revision to its API form
+func dbToolPolicyRevisionToAPI(revision db.ListToolPolicyRevisionsRow) api.ToolPolicyRevision {
+	apiRevision := api.ToolPolicyRevision{
+		Id:             openapi_types.UUID(revision.ID),
+		PolicyId:       openapi_types.UUID(revision.PolicyID),
+		Revision:       int(revision.Revision),
+		ChangeType:     api.ToolPolicyRevisionChangeType(revision.ChangeType),
+		Note:           revision.Note,
+		ChangedByEmail: revision.ChangedByEmail,
+		CreatedAt:      revision.CreatedAt.Time,
+	}
+	if revision.RolledBackTo != nil {
+		rolledBackTo := int(*revision.RolledBackTo)
+		apiRevision.RolledBackTo = &rolledBackTo
+	}
+	if revision.ChangedBy.Valid {
+		changedBy := openapi_types.UUID(revision.ChangedBy.Bytes)
+		apiRevision.ChangedBy = &changedBy
+	}
+	if revision.SessionID.Valid {
+		sessionID := openapi_types.UUID(revision.SessionID.Bytes)
+		apiRevision.SessionId = &sessionID
+	}
+	_ = json.Unmarshal(revision.Snapshot, &apiRevision.Snapshot)
+	_ = json.Unmarshal(revision.Diff, &apiRevision.Diff)
+	return apiRevision
+}
@@ -1,17 +1,25 @@
 package handlers_test
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	openapi_types "github.com/oapi-codegen/runtime/types"
 	"github.com/rastrigin-systems/arfa/generated/api"
 	"github.com/rastrigin-systems/arfa/generated/db"
 	"github.com/rastrigin-systems/arfa/generated/mocks"
 	"github.com/rastrigin-systems/arfa/services/api/internal/handlers"
+	"github.com/rastrigin-systems/arfa/services/api/internal/service"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
@@ -135,6 +143,49 @@ func TestGetEmployeeToolPolicies_EmptyList(t *testing.T) {
 	assert.Empty(t, response.Policies)
 }
 
+func TestGetEmployeeToolPolicies_Schedules(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mocks.NewMockQuerier(ctrl)
+
+	employeeID := uuid.New()
+	orgID := uuid.New()
+	sessionData := &db.GetSessionWithEmployeeRow{EmployeeID: employeeID, OrgID: orgID}
+
+	always := db.ToolPolicy{ID: uuid.New(), OrgID: orgID, ToolName: "Bash", Action: "audit"}
+	started := db.ToolPolicy{ID: uuid.New(), OrgID: orgID, ToolName: "mcp__deploy__%", Action: "deny",
+		Conditions: []byte(`{"schedule": {"active_from": "2020-01-01T00:00:00Z"}}`)}
+	ended := db.ToolPolicy{ID: uuid.New(), OrgID: orgID, ToolName: "WebFetch", Action: "deny",
+		Conditions: []byte(`{"schedule": {"active_until": "2020-01-01T00:00:00Z"}}`)}
+
+	mockDB.EXPECT().
+		GetToolPoliciesForEmployee(gomock.Any(), gomock.Any()).
+		Return([]db.ToolPolicy{always, started, ended}, nil)
+	mockDB.EXPECT().
+		GetOrganization(gomock.Any(), orgID).
+		Return(db.Organization{ID: orgID, Settings: []byte(`{"timezone": "Europe/Berlin"}`)}, nil)
+
+	handler := handlers.NewToolPoliciesHandler(mockDB)
+
+	req := httptest.NewRequest(http.MethodGet, "/employees/me/tool-policies", nil)
+	ctx := handlers.SetEmployeeIDInContext(req.Context(), employeeID)
+	ctx = handlers.SetOrgIDInContext(ctx, orgID)
+	ctx = handlers.SetSessionDataInContext(ctx, sessionData)
+	rec := httptest.NewRecorder()
+
+	handler.GetEmployeeToolPolicies(rec, req.WithContext(ctx))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var response api.EmployeeToolPoliciesResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	require.Len(t, response.Policies, 2)
+	for _, policy := range response.Policies {
+		assert.NotEqual(t, "WebFetch", policy.ToolName, "a policy whose schedule has ended is left out")
+	}
+}
+
 func TestGetEmployeeToolPolicies_MultipleScopeLevels(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -222,6 +273,60 @@ func TestGetEmployeeToolPolicies_MultipleScopeLevels(t *testing.T) {
 	assert.Equal(t, api.ToolPolicyScopeEmployee, scopes["mcp__gcloud__%"])
 }
 
+func TestGetEmployeeToolPolicies_PrecedenceOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mocks.NewMockQuerier(ctrl)
+
+	employeeID := uuid.New()
+	orgID := uuid.New()
+	teamID := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+
+	sessionData := &db.GetSessionWithEmployeeRow{
+		EmployeeID: employeeID,
+		OrgID:      orgID,
+		TeamID:     teamID,
+	}
+
+	// As the query orders them: by scope, then tool name
+	policies := []db.ToolPolicy{
+		{ID: uuid.New(), OrgID: orgID, ToolName: "Bash", Action: "allow", TeamID: teamID},
+		{ID: uuid.New(), OrgID: orgID, ToolName: "Bash", Action: "deny"},
+		{ID: uuid.New(), OrgID: orgID, ToolName: "mcp__%", Action: "deny"},
+		{ID: uuid.New(), OrgID: orgID, ToolName: "mcp__github__%", Action: "deny"},
+	}
+
+	mockDB.EXPECT().
+		GetToolPoliciesForEmployee(gomock.Any(), gomock.Any()).
+		Return(policies, nil)
+
+	handler := handlers.NewToolPoliciesHandler(mockDB)
+
+	req := httptest.NewRequest(http.MethodGet, "/employees/me/tool-policies", nil)
+	ctx := req.Context()
+	ctx = handlers.SetEmployeeIDInContext(ctx, employeeID)
+	ctx = handlers.SetOrgIDInContext(ctx, orgID)
+	ctx = handlers.SetSessionDataInContext(ctx, sessionData)
+	req = req.WithContext(ctx)
+
+	rec := httptest.NewRecorder()
+
+	handler.GetEmployeeToolPolicies(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var response api.EmployeeToolPoliciesResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	require.Len(t, response.Policies, 4)
+
+	// Within the organization scope, longer prefixes come first
+	assert.Equal(t, api.ToolPolicyActionAllow, response.Policies[0].Action)
+	assert.Equal(t, "Bash", response.Policies[1].ToolName)
+	assert.Equal(t, "mcp__github__%", response.Policies[2].ToolName)
+	assert.Equal(t, "mcp__%", response.Policies[3].ToolName)
+}
+
 func TestGetEmployeeToolPolicies_Unauthorized(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -238,3 +343,940 @@ func TestGetEmployeeToolPolicies_Unauthorized(t *testing.T) {
 
 	assert.Equal(t, http.StatusUnauthorized, rec.Code)
 }
+
+func TestCreateToolPolicy_RateLimitValidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions interface{}
+	}{
+		{"missing limit", nil},
+		{"zero max_calls", map[string]interface{}{"rate_limit": map[string]interface{}{"max_calls": 0, "window_seconds": 60}}},
+		{"window too long", map[string]interface{}{"rate_limit": map[string]interface{}{"max_calls": 5, "window_seconds": 2 * 86400}}},
+		{"unknown per", map[string]interface{}{"rate_limit": map[string]interface{}{"max_calls": 5, "window_seconds": 60, "per": "team"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// Invalid limits are rejected before reaching the database
+			handler := handlers.NewToolPoliciesHandler(mocks.NewMockQuerier(ctrl))
+
+			body, err := json.Marshal(map[string]interface{}{
+				"tool_name":  "Bash",
+				"action":     "rate_limit",
+				"conditions": tt.conditions,
+			})
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/policies", bytes.NewReader(body))
+			ctx := handlers.SetOrgIDInContext(req.Context(), uuid.New())
+			ctx = handlers.SetEmployeeIDInContext(ctx, uuid.New())
+			rec := httptest.NewRecorder()
+
+			handler.CreateToolPolicy(rec, req.WithContext(ctx))
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		})
+	}
+}
+
+func TestCreateToolPolicy_ModelRewrite(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mocks.NewMockQuerier(ctrl)
+	handler := handlers.NewToolPoliciesHandler(mockDB)
+
+	mockDB.EXPECT().
+		CreateToolPolicy(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ interface{}, params db.CreateToolPolicyParams) (db.ToolPolicy, error) {
+			assert.Equal(t, "model", params.PolicyType)
+			assert.Equal(t, "claude-opus-*", params.ToolName)
+			assert.JSONEq(t, `{"fallback_model":"claude-sonnet-4-5"}`, string(params.Conditions))
+			return db.ToolPolicy{
+				ID:         uuid.New(),
+				OrgID:      params.OrgID,
+				PolicyType: params.PolicyType,
+				ToolName:   params.ToolName,
+				Action:     params.Action,
+				Conditions: params.Conditions,
+			}, nil
+		})
+	mockDB.EXPECT().
+		CreateToolPolicyRevision(gomock.Any(), gomock.Any()).
+		Return(db.ToolPolicyRevision{Revision: 1}, nil)
+
+	body, err := json.Marshal(map[string]interface{}{
+		"policy_type": "model",
+		"tool_name":   "claude-opus-*",
+		"action":      "rewrite",
+		"conditions":  map[string]interface{}{"fallback_model": "claude-sonnet-4-5"},
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/policies", bytes.NewReader(body))
+	ctx := handlers.SetOrgIDInContext(req.Context(), uuid.New())
+	ctx = handlers.SetEmployeeIDInContext(ctx, uuid.New())
+	rec := httptest.NewRecorder()
+
+	handler.CreateToolPolicy(rec, req.WithContext(ctx))
+
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	var policy api.ToolPolicy
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&policy))
+	assert.Equal(t, api.ToolPolicyPolicyTypeModel, policy.PolicyType)
+	assert.Equal(t, api.ToolPolicyActionRewrite, policy.Action)
+}
+
+func TestCreateToolPolicy_ModelPolicyValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		body map[string]interface{}
+	}{
+		{"rewrite without fallback", map[string]interface{}{"policy_type": "model", "tool_name": "*", "action": "rewrite"}},
+		{"rate limit on a model", map[string]interface{}{"policy_type": "model", "tool_name": "*", "action": "rate_limit"}},
+		{"allow on a model", map[string]interface{}{"policy_type": "model", "tool_name": "claude-opus-%", "action": "allow"}},
+		{"allowed_models not a list", map[string]interface{}{"policy_type": "model", "tool_name": "*", "action": "deny", "conditions": map[string]interface{}{"allowed_models": "claude-sonnet-4-5"}}},
+		{"rewrite on a tool", map[string]interface{}{"tool_name": "Bash", "action": "rewrite", "conditions": map[string]interface{}{"fallback_model": "claude-sonnet-4-5"}}},
+		{"unknown policy type", map[string]interface{}{"policy_type": "network", "tool_name": "*", "action": "deny"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			handler := handlers.NewToolPoliciesHandler(mocks.NewMockQuerier(ctrl))
+
+			body, err := json.Marshal(tt.body)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/policies", bytes.NewReader(body))
+			ctx := handlers.SetOrgIDInContext(req.Context(), uuid.New())
+			ctx = handlers.SetEmployeeIDInContext(ctx, uuid.New())
+			rec := httptest.NewRecorder()
+
+			handler.CreateToolPolicy(rec, req.WithContext(ctx))
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		})
+	}
+}
+
+func TestCreateToolPolicy_PathPolicyValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		body map[string]interface{}
+	}{
+		{"rate limit on a path", map[string]interface{}{"policy_type": "path", "tool_name": "**/.env", "action": "rate_limit"}},
+		{"allow on a path", map[string]interface{}{"policy_type": "path", "tool_name": "**/.env", "action": "allow"}},
+		{"invalid glob", map[string]interface{}{"policy_type": "path", "tool_name": "/etc/[", "action": "deny"}},
+		{"absolute allowed path", map[string]interface{}{"policy_type": "path", "tool_name": "/**", "action": "deny", "conditions": map[string]interface{}{"allowed_paths": []string{"/home/user/project/**"}}}},
+		{"allowed_paths not a list", map[string]interface{}{"policy_type": "path", "tool_name": "/**", "action": "deny", "conditions": map[string]interface{}{"allowed_paths": "src/**"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			handler := handlers.NewToolPoliciesHandler(mocks.NewMockQuerier(ctrl))
+
+			body, err := json.Marshal(tt.body)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/policies", bytes.NewReader(body))
+			ctx := handlers.SetOrgIDInContext(req.Context(), uuid.New())
+			ctx = handlers.SetEmployeeIDInContext(ctx, uuid.New())
+			rec := httptest.NewRecorder()
+
+			handler.CreateToolPolicy(rec, req.WithContext(ctx))
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		})
+	}
+}
+
+func TestCreateToolPolicy_ConditionValidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions map[string]interface{}
+		wantError  string
+	}{
+		{"unknown operator", map[string]interface{}{"command": map[string]interface{}{"regex": "rm"}}, `unknown operator "regex"`},
+		{"missing operator", map[string]interface{}{"any": []interface{}{map[string]interface{}{"param_path": "command", "value": "rm"}}}, "conditions.any[0]: operator is required"},
+		{"number for a string operator", map[string]interface{}{"command": map[string]interface{}{"contains": 5}}, "contains needs a string"},
+		{"string for a number operator", map[string]interface{}{"timeout": map[string]interface{}{"gt": "60000"}}, "gt needs a number"},
+		{"empty any", map[string]interface{}{"any": []interface{}{}}, "conditions.any must be a non-empty list"},
+		{"not a list", map[string]interface{}{"all": map[string]interface{}{"command": "rm"}}, "conditions.all must be a non-empty list"},
+		{"invalid path", map[string]interface{}{"edits[x].old_string": "TODO"}, "invalid param path"},
+		{"nested error", map[string]interface{}{"not": map[string]interface{}{"any": []interface{}{map[string]interface{}{"mode": map[string]interface{}{"in": "safe"}}}}}, "conditions.not.any[0].mode: in needs a non-empty list"},
+		{"invalid regex", map[string]interface{}{"command": `rm\s+(-rf`}, `conditions.command: invalid regex "rm\\s+(-rf": error parsing regexp: missing closing )`},
+		{"invalid regex in a leaf", map[string]interface{}{"any": []interface{}{map[string]interface{}{"param_path": "url", "operator": "matches", "value": "*.internal"}}}, "conditions.any[0]: invalid regex"},
+		{"shell without command", map[string]interface{}{"command": map[string]interface{}{"shell": map[string]interface{}{"flags": []string{"-f"}}}}, "shell needs a command"},
+		{"empty schedule", map[string]interface{}{"schedule": map[string]interface{}{}}, "schedule needs active_from, active_until or windows"},
+		{"invalid cron", map[string]interface{}{"schedule": map[string]interface{}{"windows": []interface{}{map[string]interface{}{"start": "0 16 * * fri", "end": "0 8 * * 1"}}}}, "schedule.windows[0].start: day of week"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			handler := handlers.NewToolPoliciesHandler(mocks.NewMockQuerier(ctrl))
+
+			body, err := json.Marshal(map[string]interface{}{"tool_name": "Bash", "action": "deny", "conditions": tt.conditions})
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/policies", bytes.NewReader(body))
+			ctx := handlers.SetOrgIDInContext(req.Context(), uuid.New())
+			ctx = handlers.SetEmployeeIDInContext(ctx, uuid.New())
+			rec := httptest.NewRecorder()
+
+			handler.CreateToolPolicy(rec, req.WithContext(ctx))
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			var apiErr api.Error
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&apiErr))
+			assert.Contains(t, apiErr.Error, tt.wantError)
+		})
+	}
+}
+
+func TestCreateToolPolicy_StructuredConditions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mocks.NewMockQuerier(ctrl)
+	handler := handlers.NewToolPoliciesHandler(mockDB)
+
+	conditions := `{
+		"all": [
+			{"param_path": "edits[*].new_string", "operator": "contains", "value": "TODO"},
+			{"not": {"file_path": {"ends_with": "_test.go"}}},
+			{"any": [{"timeout": {"gte": 60000}}, {"run_in_background": {"equals": true}}]}
+		],
+		"rate_limit": {"max_calls": 5, "window_seconds": 60}
+	}`
+
+	mockDB.EXPECT().
+		CreateToolPolicy(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ interface{}, params db.CreateToolPolicyParams) (db.ToolPolicy, error) {
+			assert.JSONEq(t, conditions, string(params.Conditions))
+			return db.ToolPolicy{ID: uuid.New(), OrgID: params.OrgID, PolicyType: "tool", ToolName: params.ToolName, Action: params.Action}, nil
+		})
+	mockDB.EXPECT().
+		CreateToolPolicyRevision(gomock.Any(), gomock.Any()).
+		Return(db.ToolPolicyRevision{Revision: 1}, nil)
+
+	body := `{"tool_name": "Edit", "action": "rate_limit", "conditions": ` + conditions + `}`
+	req := httptest.NewRequest(http.MethodPost, "/policies", bytes.NewReader([]byte(body)))
+	ctx := handlers.SetOrgIDInContext(req.Context(), uuid.New())
+	ctx = handlers.SetEmployeeIDInContext(ctx, uuid.New())
+	rec := httptest.NewRecorder()
+
+	handler.CreateToolPolicy(rec, req.WithContext(ctx))
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+}
+
+func TestCreateToolPolicy_RevisionFailureRollsBack(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mocks.NewMockQuerier(ctrl)
+	txDB := mocks.NewMockQuerier(ctrl)
+	handler := handlers.NewToolPoliciesHandler(mockDB)
+
+	var txErr error
+	handler.SetTxRunner(func(ctx context.Context, fn func(q db.Querier) error) error {
+		txErr = fn(txDB)
+		return txErr
+	})
+
+	txDB.EXPECT().
+		CreateToolPolicy(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ interface{}, params db.CreateToolPolicyParams) (db.ToolPolicy, error) {
+			return db.ToolPolicy{ID: uuid.New(), OrgID: params.OrgID, PolicyType: "tool", ToolName: params.ToolName, Action: params.Action}, nil
+		})
+	txDB.EXPECT().
+		CreateToolPolicyRevision(gomock.Any(), gomock.Any()).
+		Return(db.ToolPolicyRevision{}, errors.New("connection reset"))
+
+	req := httptest.NewRequest(http.MethodPost, "/policies", bytes.NewReader([]byte(`{"tool_name": "Bash", "action": "deny"}`)))
+	ctx := handlers.SetOrgIDInContext(req.Context(), uuid.New())
+	ctx = handlers.SetEmployeeIDInContext(ctx, uuid.New())
+	rec := httptest.NewRecorder()
+
+	handler.CreateToolPolicy(rec, req.WithContext(ctx))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Error(t, txErr, "the transaction sees the error, so the policy is rolled back")
+}
+
+func TestDeleteToolPolicy_RecordsRevisionInTransaction(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mocks.NewMockQuerier(ctrl)
+	txDB := mocks.NewMockQuerier(ctrl)
+	handler := handlers.NewToolPoliciesHandler(mockDB)
+
+	var inTx bool
+	handler.SetTxRunner(func(ctx context.Context, fn func(q db.Querier) error) error {
+		inTx = true
+		return fn(txDB)
+	})
+
+	orgID := uuid.New()
+	policyID := uuid.New()
+	policy := db.ToolPolicy{ID: policyID, OrgID: orgID, PolicyType: "tool", ToolName: "Bash", Action: "deny"}
+
+	txDB.EXPECT().
+		LockToolPolicyByIdAndOrg(gomock.Any(), db.LockToolPolicyByIdAndOrgParams{ID: policyID, OrgID: orgID}).
+		Return(policy, nil)
+	txDB.EXPECT().
+		DeleteToolPolicyByOrg(gomock.Any(), db.DeleteToolPolicyByOrgParams{ID: policyID, OrgID: orgID}).
+		Return(nil)
+	txDB.EXPECT().
+		CreateToolPolicyRevision(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ interface{}, params db.CreateToolPolicyRevisionParams) (db.ToolPolicyRevision, error) {
+			assert.Equal(t, "delete", params.ChangeType)
+			require.NotNil(t, params.Note)
+			assert.Equal(t, "Replaced by a pack", *params.Note)
+			return db.ToolPolicyRevision{Revision: 2}, nil
+		})
+
+	req := httptest.NewRequest(http.MethodDelete, "/policies/"+policyID.String()+"?note=Replaced+by+a+pack", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("policy_id", policyID.String())
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = handlers.SetOrgIDInContext(ctx, orgID)
+	ctx = handlers.SetEmployeeIDInContext(ctx, uuid.New())
+	rec := httptest.NewRecorder()
+
+	handler.DeleteToolPolicy(rec, req.WithContext(ctx))
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.True(t, inTx)
+}
+
+func TestSimulateToolPolicies(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mocks.NewMockQuerier(ctrl)
+	handler := handlers.NewToolPoliciesHandler(mockDB)
+
+	orgID := uuid.New()
+	employeeID := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	email := "alice@acme.com"
+	replacedID := uuid.New()
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+
+	// The saved Bash deny is replaced by the draft, which only denies rm -rf
+	mockDB.EXPECT().
+		ListToolPoliciesByOrg(gomock.Any(), orgID).
+		Return([]db.ToolPolicy{
+			{ID: replacedID, OrgID: orgID, PolicyType: "tool", ToolName: "Bash", Action: "deny"},
+			{ID: uuid.New(), OrgID: orgID, PolicyType: "tool", ToolName: "WebFetch", Action: "deny"},
+		}, nil)
+	mockDB.EXPECT().
+		ListToolCallEvents(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ interface{}, params db.ListToolCallEventsParams) ([]db.ListToolCallEventsRow, error) {
+			assert.Equal(t, orgID, params.OrgID)
+			assert.Equal(t, start, params.StartDate.Time)
+			assert.Equal(t, end, params.EndDate.Time)
+
+			at := pgtype.Timestamp{Time: start.Add(time.Hour), Valid: true}
+			return []db.ListToolCallEventsRow{
+				{ID: uuid.New(), EmployeeID: employeeID, EmployeeEmail: &email, CreatedAt: at,
+					Payload: []byte(`{"tool_name": "Bash", "tool_input": {"command": "ls"}, "blocked": true}`)},
+				{ID: uuid.New(), EmployeeID: employeeID, EmployeeEmail: &email, CreatedAt: at,
+					Payload: []byte(`{"tool_name": "Bash", "tool_input": {"command": "rm -rf /"}, "blocked": true}`)},
+				{ID: uuid.New(), EmployeeID: employeeID, EmployeeEmail: &email, CreatedAt: at,
+					Payload: []byte(`{"tool_name": "WebFetch", "tool_input": {"url": "https://example.com"}, "blocked": true}`)},
+			}, nil
+		})
+
+	body := `{
+		"policies": [{"tool_name": "Bash", "action": "deny", "conditions": {"command": "rm\\s+-rf"}}],
+		"replace_policy_ids": ["` + replacedID.String() + `"],
+		"start_date": "2026-10-01T00:00:00Z",
+		"end_date": "2026-10-02T00:00:00Z"
+	}`
+	req := httptest.NewRequest(http.MethodPost, "/policies/simulate", bytes.NewReader([]byte(body)))
+	ctx := handlers.SetOrgIDInContext(req.Context(), orgID)
+	rec := httptest.NewRecorder()
+
+	handler.SimulateToolPolicies(rec, req.WithContext(ctx))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp api.SimulateToolPoliciesResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, 3, resp.Total)
+	assert.Equal(t, 2, resp.Denied)
+	assert.Equal(t, 1, resp.NewlyAllowed)
+	require.Len(t, resp.Groups, 2)
+	assert.Equal(t, "Bash", resp.Groups[0].ToolName)
+	assert.Equal(t, 1, resp.Groups[0].Allowed)
+	assert.Equal(t, email, *resp.Groups[0].EmployeeEmail)
+	assert.Equal(t, "WebFetch", resp.Groups[1].ToolName)
+	assert.Equal(t, 1, resp.Groups[1].Denied)
+}
+
+func TestSimulateToolPolicies_Validation(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantError string
+	}{
+		{"invalid draft", `{"policies": [{"tool_name": "Bash", "action": "rewrite"}]}`, "policies[0]: rewrite is only valid for model policies"},
+		{"invalid conditions", `{"policies": [{"tool_name": "Bash", "action": "deny", "conditions": {"command": "(["}}]}`, "policies[0]"},
+		{"empty range", `{"start_date": "2026-10-02T00:00:00Z", "end_date": "2026-10-01T00:00:00Z"}`, "end_date must be after start_date"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// Invalid requests are rejected before reaching the database
+			handler := handlers.NewToolPoliciesHandler(mocks.NewMockQuerier(ctrl))
+
+			req := httptest.NewRequest(http.MethodPost, "/policies/simulate", bytes.NewReader([]byte(tt.body)))
+			ctx := handlers.SetOrgIDInContext(req.Context(), uuid.New())
+			rec := httptest.NewRecorder()
+
+			handler.SimulateToolPolicies(rec, req.WithContext(ctx))
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			var apiErr api.Error
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&apiErr))
+			assert.Contains(t, apiErr.Error, tt.wantError)
+		})
+	}
+}
+
+func TestUpdateToolPolicy_RecordsRevision(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mocks.NewMockQuerier(ctrl)
+	handler := handlers.NewToolPoliciesHandler(mockDB)
+
+	orgID := uuid.New()
+	employeeID := uuid.New()
+	policyID := uuid.New()
+	sessionData := &db.GetSessionWithEmployeeRow{ID: uuid.New(), EmployeeID: employeeID, OrgID: orgID}
+	before := db.ToolPolicy{ID: policyID, OrgID: orgID, PolicyType: "tool", ToolName: "Bash", Action: "deny"}
+	after := before
+	after.Action = "audit"
+
+	mockDB.EXPECT().
+		GetToolPolicyByIdAndOrg(gomock.Any(), db.GetToolPolicyByIdAndOrgParams{ID: policyID, OrgID: orgID}).
+		Return(before, nil)
+	mockDB.EXPECT().
+		LockToolPolicyByIdAndOrg(gomock.Any(), db.LockToolPolicyByIdAndOrgParams{ID: policyID, OrgID: orgID}).
+		Return(before, nil)
+	mockDB.EXPECT().
+		UpdateToolPolicyByOrg(gomock.Any(), gomock.Any()).
+		Return(after, nil)
+	mockDB.EXPECT().
+		CreateToolPolicyRevision(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ interface{}, params db.CreateToolPolicyRevisionParams) (db.ToolPolicyRevision, error) {
+			assert.Equal(t, policyID, params.PolicyID)
+			assert.Equal(t, "update", params.ChangeType)
+			assert.JSONEq(t, `{"action": {"from": "deny", "to": "audit"}}`, string(params.Diff))
+			require.NotNil(t, params.Note)
+			assert.Equal(t, "Too many false positives", *params.Note)
+			assert.Equal(t, pgtype.UUID{Bytes: employeeID, Valid: true}, params.ChangedBy)
+			assert.Equal(t, pgtype.UUID{Bytes: sessionData.ID, Valid: true}, params.SessionID)
+			return db.ToolPolicyRevision{Revision: 2}, nil
+		})
+
+	body := `{"action": "audit", "note": "Too many false positives"}`
+	req := httptest.NewRequest(http.MethodPatch, "/policies/"+policyID.String(), bytes.NewReader([]byte(body)))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("policy_id", policyID.String())
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = handlers.SetOrgIDInContext(ctx, orgID)
+	ctx = handlers.SetEmployeeIDInContext(ctx, employeeID)
+	ctx = handlers.SetSessionDataInContext(ctx, sessionData)
+	ctx = handlers.SetRoleNameInContext(ctx, "admin")
+	rec := httptest.NewRecorder()
+
+	handler.UpdateToolPolicy(rec, req.WithContext(ctx))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestUpdateToolPolicy_OrganizationPolicyNeedsAdmin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mocks.NewMockQuerier(ctrl)
+	handler := handlers.NewToolPoliciesHandler(mockDB)
+
+	orgID := uuid.New()
+	policyID := uuid.New()
+	mockDB.EXPECT().
+		GetToolPolicyByIdAndOrg(gomock.Any(), db.GetToolPolicyByIdAndOrgParams{ID: policyID, OrgID: orgID}).
+		Return(db.ToolPolicy{ID: policyID, OrgID: orgID, PolicyType: "tool", ToolName: "Bash", Action: "deny"}, nil)
+
+	req := httptest.NewRequest(http.MethodPatch, "/policies/"+policyID.String(), bytes.NewReader([]byte(`{"action": "audit"}`)))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("policy_id", policyID.String())
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = handlers.SetOrgIDInContext(ctx, orgID)
+	ctx = handlers.SetEmployeeIDInContext(ctx, uuid.New())
+	ctx = handlers.SetRoleNameInContext(ctx, "manager")
+	rec := httptest.NewRecorder()
+
+	handler.UpdateToolPolicy(rec, req.WithContext(ctx))
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestUpdateToolPolicy_ValidatesUpdatedPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		before db.ToolPolicy
+		body   string
+	}{
+		{
+			name:   "rate limit dropped from a rate_limit policy",
+			before: db.ToolPolicy{PolicyType: "tool", ToolName: "Bash", Action: "rate_limit", Conditions: []byte(`{"rate_limit": {"max_calls": 5, "window_seconds": 60}}`)},
+			body:   `{"conditions": {"command": "curl"}}`,
+		},
+		{
+			name:   "fallback model dropped from a rewrite policy",
+			before: db.ToolPolicy{PolicyType: "model", ToolName: "claude-opus-%", Action: "rewrite", Conditions: []byte(`{"fallback_model": "claude-sonnet-4"}`)},
+			body:   `{"conditions": {"allowed_models": ["claude-opus-4"]}}`,
+		},
+		{
+			name:   "invalid allowed_models",
+			before: db.ToolPolicy{PolicyType: "model", ToolName: "claude-opus-%", Action: "deny"},
+			body:   `{"conditions": {"allowed_models": "claude-opus-4"}}`,
+		},
+		{
+			name:   "absolute allowed_paths",
+			before: db.ToolPolicy{PolicyType: "path", ToolName: "**/.env", Action: "deny"},
+			body:   `{"conditions": {"allowed_paths": ["/etc/app/.env"]}}`,
+		},
+		{
+			name:   "rewrite on a tool policy",
+			before: db.ToolPolicy{PolicyType: "tool", ToolName: "Bash", Action: "deny"},
+			body:   `{"action": "rewrite"}`,
+		},
+		{
+			name:   "rate_limit on a path policy",
+			before: db.ToolPolicy{PolicyType: "path", ToolName: "**/.env", Action: "deny"},
+			body:   `{"action": "rate_limit", "conditions": {"rate_limit": {"max_calls": 5, "window_seconds": 60}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockDB := mocks.NewMockQuerier(ctrl)
+			handler := handlers.NewToolPoliciesHandler(mockDB)
+
+			orgID := uuid.New()
+			policyID := uuid.New()
+			before := tt.before
+			before.ID, before.OrgID = policyID, orgID
+			mockDB.EXPECT().
+				GetToolPolicyByIdAndOrg(gomock.Any(), db.GetToolPolicyByIdAndOrgParams{ID: policyID, OrgID: orgID}).
+				Return(before, nil)
+
+			req := httptest.NewRequest(http.MethodPatch, "/policies/"+policyID.String(), bytes.NewReader([]byte(tt.body)))
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("policy_id", policyID.String())
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			ctx = handlers.SetOrgIDInContext(ctx, orgID)
+			ctx = handlers.SetEmployeeIDInContext(ctx, uuid.New())
+			ctx = handlers.SetRoleNameInContext(ctx, "admin")
+			rec := httptest.NewRecorder()
+
+			handler.UpdateToolPolicy(rec, req.WithContext(ctx))
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		})
+	}
+}
+
+func TestGetToolPolicyHistory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mocks.NewMockQuerier(ctrl)
+	handler := handlers.NewToolPoliciesHandler(mockDB)
+
+	orgID := uuid.New()
+	policyID := uuid.New()
+	employeeID := uuid.New()
+	email := "admin@acme.com"
+	note := "Too many false positives"
+
+	mockDB.EXPECT().
+		ListToolPolicyRevisions(gomock.Any(), db.ListToolPolicyRevisionsParams{PolicyID: policyID, OrgID: orgID}).
+		Return([]db.ListToolPolicyRevisionsRow{
+			{
+				ID: uuid.New(), PolicyID: policyID, OrgID: orgID, Revision: 2, ChangeType: "update",
+				Snapshot:  []byte(`{"tool_name": "Bash", "action": "audit"}`),
+				Diff:      []byte(`{"action": {"from": "deny", "to": "audit"}}`),
+				Note:      &note,
+				ChangedBy: pgtype.UUID{Bytes: employeeID, Valid: true}, ChangedByEmail: &email,
+				CreatedAt: pgtype.Timestamp{Time: time.Now(), Valid: true},
+			},
+			{
+				ID: uuid.New(), PolicyID: policyID, OrgID: orgID, Revision: 1, ChangeType: "create",
+				Snapshot:  []byte(`{"tool_name": "Bash", "action": "deny"}`),
+				Diff:      []byte(`{"tool_name": {"from": null, "to": "Bash"}, "action": {"from": null, "to": "deny"}}`),
+				CreatedAt: pgtype.Timestamp{Time: time.Now(), Valid: true},
+			},
+		}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/policies/"+policyID.String()+"/history", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("policy_id", policyID.String())
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = handlers.SetOrgIDInContext(ctx, orgID)
+	rec := httptest.NewRecorder()
+
+	handler.GetToolPolicyHistory(rec, req.WithContext(ctx))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp api.ToolPolicyHistoryResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	require.Len(t, resp.Revisions, 2)
+	assert.Equal(t, 2, resp.Revisions[0].Revision)
+	assert.Equal(t, api.ToolPolicyRevisionChangeTypeUpdate, resp.Revisions[0].ChangeType)
+	assert.Equal(t, email, *resp.Revisions[0].ChangedByEmail)
+	assert.Equal(t, note, *resp.Revisions[0].Note)
+	assert.Equal(t, map[string]interface{}{"from": "deny", "to": "audit"}, resp.Revisions[0].Diff["action"])
+}
+
+func TestRollbackToolPolicy_DeleteRevision(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mocks.NewMockQuerier(ctrl)
+	handler := handlers.NewToolPoliciesHandler(mockDB)
+
+	orgID := uuid.New()
+	policyID := uuid.New()
+
+	mockDB.EXPECT().
+		GetToolPolicyRevision(gomock.Any(), db.GetToolPolicyRevisionParams{PolicyID: policyID, OrgID: orgID, Revision: 3}).
+		Return(db.ToolPolicyRevision{PolicyID: policyID, OrgID: orgID, Revision: 3, ChangeType: "delete"}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/policies/"+policyID.String()+"/rollback", bytes.NewReader([]byte(`{"revision": 3}`)))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("policy_id", policyID.String())
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = handlers.SetOrgIDInContext(ctx, orgID)
+	ctx = handlers.SetEmployeeIDInContext(ctx, uuid.New())
+	rec := httptest.NewRecorder()
+
+	handler.RollbackToolPolicy(rec, req.WithContext(ctx))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestExportToolPolicies(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mocks.NewMockQuerier(ctrl)
+	handler := handlers.NewToolPoliciesHandler(mockDB)
+
+	orgID := uuid.New()
+	teamID := uuid.New()
+	employeeID := uuid.New()
+
+	// Newest first, as listed
+	mockDB.EXPECT().ListToolPoliciesByOrg(gomock.Any(), orgID).Return([]db.ToolPolicy{
+		{ID: uuid.New(), OrgID: orgID, EmployeeID: pgtype.UUID{Bytes: employeeID, Valid: true}, PolicyType: "tool", ToolName: "Write", Action: "audit"},
+		{ID: uuid.New(), OrgID: orgID, TeamID: pgtype.UUID{Bytes: teamID, Valid: true}, PolicyType: "model", ToolName: "claude-opus-*", Action: "deny"},
+		{ID: uuid.New(), OrgID: orgID, PolicyType: "tool", ToolName: "Bash", Action: "deny", Conditions: []byte(`{"command": "rm"}`)},
+	}, nil)
+	mockDB.EXPECT().ListTeams(gomock.Any(), orgID).Return([]db.Team{{ID: teamID, OrgID: orgID, Name: "platform"}}, nil)
+	mockDB.EXPECT().GetEmployee(gomock.Any(), employeeID).Return(db.GetEmployeeRow{ID: employeeID, Email: "alice@acme.com"}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/policies/export", nil)
+	ctx := handlers.SetOrgIDInContext(req.Context(), orgID)
+	rec := httptest.NewRecorder()
+
+	handler.ExportToolPolicies(rec, req.WithContext(ctx))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp api.PolicyDefinitionList
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	require.Len(t, resp.Policies, 3)
+
+	assert.Equal(t, "Bash", resp.Policies[0].ToolName)
+	assert.Nil(t, resp.Policies[0].PolicyType, "tool is the default type")
+	assert.Nil(t, resp.Policies[0].Team)
+	assert.Equal(t, map[string]interface{}{"command": "rm"}, *resp.Policies[0].Conditions)
+
+	require.NotNil(t, resp.Policies[1].Team)
+	assert.Equal(t, "platform", *resp.Policies[1].Team)
+	assert.Equal(t, api.PolicyDefinitionPolicyTypeModel, *resp.Policies[1].PolicyType)
+
+	require.NotNil(t, resp.Policies[2].Employee)
+	assert.Equal(t, "alice@acme.com", *resp.Policies[2].Employee)
+}
+
+func TestApplyToolPolicies_DryRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mocks.NewMockQuerier(ctrl)
+	handler := handlers.NewToolPoliciesHandler(mockDB)
+
+	orgID := uuid.New()
+	teamID := uuid.New()
+	bash := db.ToolPolicy{ID: uuid.New(), OrgID: orgID, PolicyType: "tool", ToolName: "Bash", Action: "audit"}
+	teamRead := db.ToolPolicy{ID: uuid.New(), OrgID: orgID, TeamID: pgtype.UUID{Bytes: teamID, Valid: true}, PolicyType: "tool", ToolName: "Read", Action: "audit"}
+	webFetch := db.ToolPolicy{ID: uuid.New(), OrgID: orgID, PolicyType: "tool", ToolName: "WebFetch", Action: "deny"}
+
+	mockDB.EXPECT().ListTeams(gomock.Any(), orgID).Return([]db.Team{{ID: teamID, OrgID: orgID, Name: "platform"}}, nil)
+	mockDB.EXPECT().ListToolPoliciesByOrg(gomock.Any(), orgID).Return([]db.ToolPolicy{bash, teamRead, webFetch}, nil)
+
+	body := `{"dry_run": true, "policies": [
+		{"tool_name": "Bash", "action": "deny"},
+		{"tool_name": "Read", "action": "audit", "team": "platform"},
+		{"tool_name": "Write", "action": "deny"}
+	]}`
+	req := httptest.NewRequest(http.MethodPost, "/policies/apply", bytes.NewReader([]byte(body)))
+	ctx := handlers.SetOrgIDInContext(req.Context(), orgID)
+	ctx = handlers.SetEmployeeIDInContext(ctx, uuid.New())
+	rec := httptest.NewRecorder()
+
+	handler.ApplyToolPolicies(rec, req.WithContext(ctx))
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp api.ApplyToolPoliciesResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.False(t, resp.Applied)
+	assert.Equal(t, 1, resp.Created)
+	assert.Equal(t, 1, resp.Updated)
+	assert.Equal(t, 1, resp.Deleted)
+	assert.Equal(t, 1, resp.Unchanged)
+
+	require.Len(t, resp.Changes, 3)
+	assert.Equal(t, api.PolicyPlanChangeChangeTypeUpdate, resp.Changes[0].ChangeType)
+	assert.Equal(t, openapi_types.UUID(bash.ID), *resp.Changes[0].PolicyId)
+	assert.Equal(t, map[string]interface{}{"from": "audit", "to": "deny"}, resp.Changes[0].Diff["action"])
+	assert.Equal(t, api.PolicyPlanChangeChangeTypeCreate, resp.Changes[1].ChangeType)
+	assert.Nil(t, resp.Changes[1].PolicyId, "nothing was created")
+	assert.Equal(t, api.PolicyPlanChangeChangeTypeDelete, resp.Changes[2].ChangeType)
+	assert.Equal(t, "WebFetch", resp.Changes[2].Policy.ToolName)
+}
+
+func TestApplyToolPolicies_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		setup   func(mockDB *mocks.MockQuerier, orgID uuid.UUID)
+		wantErr string
+	}{
+		{
+			name:    "invalid policy",
+			body:    `{"policies": [{"tool_name": "Bash", "action": "deny"}, {"tool_name": "Bash", "action": "rewrite"}]}`,
+			wantErr: "policies[1]: rewrite is only valid for model policies",
+		},
+		{
+			name: "unknown team",
+			body: `{"policies": [{"tool_name": "Bash", "action": "deny", "team": "nobody"}]}`,
+			setup: func(mockDB *mocks.MockQuerier, orgID uuid.UUID) {
+				mockDB.EXPECT().ListTeams(gomock.Any(), orgID).Return([]db.Team{}, nil)
+			},
+			wantErr: `policies[0]: no team named "nobody"`,
+		},
+		{
+			name: "employee in another organization",
+			body: `{"policies": [{"tool_name": "Bash", "action": "deny", "employee": "eve@other.com"}]}`,
+			setup: func(mockDB *mocks.MockQuerier, orgID uuid.UUID) {
+				mockDB.EXPECT().GetEmployeeByEmail(gomock.Any(), "eve@other.com").Return(db.Employee{ID: uuid.New(), OrgID: uuid.New()}, nil)
+			},
+			wantErr: `policies[0]: no employee with email "eve@other.com"`,
+		},
+		{
+			name:    "team and employee",
+			body:    `{"policies": [{"tool_name": "Bash", "action": "deny", "team": "platform", "employee": "alice@acme.com"}]}`,
+			wantErr: "policies[0]: set team or employee, not both",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockDB := mocks.NewMockQuerier(ctrl)
+			handler := handlers.NewToolPoliciesHandler(mockDB)
+			orgID := uuid.New()
+			if tt.setup != nil {
+				tt.setup(mockDB, orgID)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/policies/apply", bytes.NewReader([]byte(tt.body)))
+			ctx := handlers.SetOrgIDInContext(req.Context(), orgID)
+			ctx = handlers.SetEmployeeIDInContext(ctx, uuid.New())
+			rec := httptest.NewRecorder()
+
+			handler.ApplyToolPolicies(rec, req.WithContext(ctx))
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			var apiErr api.Error
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&apiErr))
+			assert.Equal(t, tt.wantErr, apiErr.Error)
+		})
+	}
+}
+
+func TestListPolicyPacks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mocks.NewMockQuerier(ctrl)
+	handler := handlers.NewToolPoliciesHandler(mockDB)
+
+	orgID := uuid.New()
+	teamID := uuid.New()
+	mockDB.EXPECT().ListPolicyPackInstalls(gomock.Any(), orgID).Return([]db.PolicyPackInstall{
+		{ID: uuid.New(), OrgID: orgID, PackName: "destructive-commands", Version: 1, PolicyIds: []uuid.UUID{uuid.New(), uuid.New()}},
+		{ID: uuid.New(), OrgID: orgID, TeamID: pgtype.UUID{Bytes: teamID, Valid: true}, PackName: "network-audit", Version: 1},
+	}, nil)
+	mockDB.EXPECT().ListTeams(gomock.Any(), orgID).Return([]db.Team{{ID: teamID, OrgID: orgID, Name: "platform"}}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/policies/packs", nil)
+	ctx := handlers.SetOrgIDInContext(req.Context(), orgID)
+	rec := httptest.NewRecorder()
+
+	handler.ListPolicyPacks(rec, req.WithContext(ctx))
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp api.PolicyPackList
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	require.Len(t, resp.Packs, len(service.PolicyPacks()))
+
+	installs := make(map[string][]api.PolicyPackInstall)
+	for _, pack := range resp.Packs {
+		assert.NotEmpty(t, pack.Policies, pack.Name)
+		installs[pack.Name] = pack.Installs
+	}
+	require.Len(t, installs["destructive-commands"], 1)
+	assert.Equal(t, 2, installs["destructive-commands"][0].PolicyCount)
+	assert.Nil(t, installs["destructive-commands"][0].Team)
+	assert.False(t, installs["destructive-commands"][0].UpgradeAvailable)
+	require.Len(t, installs["network-audit"], 1)
+	assert.Equal(t, "platform", *installs["network-audit"][0].Team)
+	assert.Empty(t, installs["secrets-protection"])
+}
+
+func TestInstallPolicyPack_DryRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mocks.NewMockQuerier(ctrl)
+	handler := handlers.NewToolPoliciesHandler(mockDB)
+
+	orgID := uuid.New()
+	teamID := uuid.New()
+	pack, _ := service.FindPolicyPack("network-audit")
+
+	mockDB.EXPECT().ListTeams(gomock.Any(), orgID).Return([]db.Team{{ID: teamID, OrgID: orgID, Name: "platform"}}, nil)
+	mockDB.EXPECT().
+		GetPolicyPackInstall(gomock.Any(), db.GetPolicyPackInstallParams{
+			OrgID:    orgID,
+			TeamID:   pgtype.UUID{Bytes: teamID, Valid: true},
+			PackName: "network-audit",
+		}).
+		Return(db.PolicyPackInstall{}, pgx.ErrNoRows)
+
+	body := `{"team_id": "` + teamID.String() + `", "dry_run": true}`
+	req := httptest.NewRequest(http.MethodPost, "/policies/packs/network-audit/install", bytes.NewReader([]byte(body)))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("pack_name", "network-audit")
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = handlers.SetOrgIDInContext(ctx, orgID)
+	ctx = handlers.SetEmployeeIDInContext(ctx, uuid.New())
+	rec := httptest.NewRecorder()
+
+	handler.InstallPolicyPack(rec, req.WithContext(ctx))
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp api.InstallPolicyPackResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Nil(t, resp.FromVersion)
+	assert.Equal(t, int(pack.Version), resp.ToVersion)
+	assert.Nil(t, resp.Install, "nothing was installed")
+	assert.False(t, resp.Plan.Applied)
+	assert.Equal(t, len(pack.Policies), resp.Plan.Created)
+	require.Len(t, resp.Plan.Changes, len(pack.Policies))
+	assert.Equal(t, "platform", *resp.Plan.Changes[0].Policy.Team)
+}
+
+func TestInstallPolicyPack_NotFound(t *testing.T) {
+	tests := []struct {
+		name     string
+		packName string
+		body     string
+		setup    func(mockDB *mocks.MockQuerier, orgID uuid.UUID)
+		wantErr  string
+	}{
+		{
+			name:     "unknown pack",
+			packName: "nope",
+			wantErr:  "Policy pack not found",
+		},
+		{
+			name:     "team in another organization",
+			packName: "network-audit",
+			body:     `{"team_id": "` + uuid.New().String() + `"}`,
+			setup: func(mockDB *mocks.MockQuerier, orgID uuid.UUID) {
+				mockDB.EXPECT().ListTeams(gomock.Any(), orgID).Return([]db.Team{}, nil)
+			},
+			wantErr: "Team not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockDB := mocks.NewMockQuerier(ctrl)
+			handler := handlers.NewToolPoliciesHandler(mockDB)
+			orgID := uuid.New()
+			if tt.setup != nil {
+				tt.setup(mockDB, orgID)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/policies/packs/"+tt.packName+"/install", bytes.NewReader([]byte(tt.body)))
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("pack_name", tt.packName)
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			ctx = handlers.SetOrgIDInContext(ctx, orgID)
+			ctx = handlers.SetEmployeeIDInContext(ctx, uuid.New())
+			rec := httptest.NewRecorder()
+
+			handler.InstallPolicyPack(rec, req.WithContext(ctx))
+
+			assert.Equal(t, http.StatusNotFound, rec.Code)
+			var apiErr api.Error
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&apiErr))
+			assert.Equal(t, tt.wantErr, apiErr.Error)
+		})
+	}
+}
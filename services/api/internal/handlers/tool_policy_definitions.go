@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+	"github.com/rastrigin-systems/arfa/generated/api"
+	"github.com/rastrigin-systems/arfa/generated/db"
+	"github.com/rastrigin-systems/arfa/services/api/internal/middleware"
+	"github.com/rastrigin-systems/arfa/services/api/internal/service"
+)
+
+// Policy definitions are tool policies in the declarative form kept in
+// version control (arfa policies export/plan/apply): no IDs, and teams and
+// employees referenced by name and email rather than ID, so a file can be
+// reviewed and applied to any environment that has the same teams and people.
+
+// SetTxRunner sets how policy changes open transactions, so a change and
+// its revisions are saved together. Until it's set, changes are made one by one.
+func (h *ToolPoliciesHandler) SetTxRunner(runTx service.TxRunner) {
+	h.runTx = runTx
+	h.revisions = service.NewPolicyRevisionService(h.db, runTx)
+	h.apply = service.NewPolicyApplyService(h.db, runTx)
+	h.packs = service.NewPolicyPackService(h.db, runTx)
+}
+
+// ExportToolPolicies handles GET /policies/export
+// Returns the organization's tool policies as definitions
+func (h *ToolPoliciesHandler) ExportToolPolicies(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	orgID, err := middleware.GetOrgID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// TODO: Check role (admin/manager)
+
+	policies, err := h.db.ListToolPoliciesByOrg(ctx, orgID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to list policies")
+		return
+	}
+
+	// Oldest first, so new policies are appended to an exported file
+	definitions := make([]api.PolicyDefinition, 0, len(policies))
+	directory := newPolicyDirectory(h.db, orgID)
+	for i := len(policies) - 1; i >= 0; i-- {
+		definition, err := directory.definition(ctx, policies[i])
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to export policies")
+			return
+		}
+		definitions = append(definitions, definition)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(api.PolicyDefinitionList{Policies: definitions})
+}
+
+// ApplyToolPolicies handles POST /policies/apply
+// Brings the organization's tool policies to a declared set in one transaction
+func (h *ToolPoliciesHandler) ApplyToolPolicies(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	orgID, err := middleware.GetOrgID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	employeeID, err := middleware.GetEmployeeID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// The route requires the admin or manager role
+
+	var req api.ApplyToolPoliciesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if len(req.Policies) > maxAppliedPolicies {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("At most %d policies can be applied at once", maxAppliedPolicies))
+		return
+	}
+
+	directory := newPolicyDirectory(h.db, orgID)
+	desired := make([]db.ToolPolicy, 0, len(req.Policies))
+	for i, definition := range req.Policies {
+		policy, err := directory.draft(ctx, definition)
+		if errors.Is(err, errDirectoryLookup) {
+			writeError(w, http.StatusInternalServerError, "Failed to resolve teams and employees")
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("policies[%d]: %s", i, err))
+			return
+		}
+		desired = append(desired, policy)
+	}
+
+	dryRun := req.DryRun != nil && *req.DryRun
+	plan, err := h.apply.Apply(ctx, service.PolicyApply{
+		OrgID:     orgID,
+		Policies:  desired,
+		ChangedBy: employeeID,
+		SessionID: requestSessionID(ctx),
+		Note:      req.Note,
+		DryRun:    dryRun,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to apply policies; nothing was changed")
+		return
+	}
+
+	response, err := directory.planResponse(ctx, plan, len(desired), !dryRun)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to describe changes")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// maxAppliedPolicies is the most policies one apply can declare
+const maxAppliedPolicies = 1000
+
+// errDirectoryLookup is returned when teams or employees can't be looked up
+var errDirectoryLookup = errors.New("directory lookup failed")
+
+// policyDirectory translates between the team and employee IDs of saved
+// policies and the names and emails of definitions, looking each up once
+type policyDirectory struct {
+	db    db.Querier
+	orgID uuid.UUID
+
+	teams       []db.Team // nil until loaded
+	employeeIDs map[string]uuid.UUID
+	emails      map[uuid.UUID]string
+}
+
+func newPolicyDirectory(database db.Querier, orgID uuid.UUID) *policyDirectory {
+	return &policyDirectory{
+		db:          database,
+		orgID:       orgID,
+		employeeIDs: make(map[string]uuid.UUID),
+		emails:      make(map[uuid.UUID]string),
+	}
+}
+
+func (d *policyDirectory) loadTeams(ctx context.Context) error {
+	if d.teams != nil {
+		return nil
+	}
+	teams, err := d.db.ListTeams(ctx, d.orgID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errDirectoryLookup, err)
+	}
+	d.teams = append([]db.Team{}, teams...)
+	return nil
+}
+
+// draft validates a definition and converts it to the policy it would be
+// saved as
+func (d *policyDirectory) draft(ctx context.Context, definition api.PolicyDefinition) (db.ToolPolicy, error) {
+	req := api.CreateToolPolicyRequest{
+		ToolName:   definition.ToolName,
+		Action:     api.CreateToolPolicyRequestAction(definition.Action),
+		Reason:     definition.Reason,
+		Conditions: definition.Conditions,
+	}
+	if definition.PolicyType != nil {
+		policyType := api.CreateToolPolicyRequestPolicyType(*definition.PolicyType)
+		req.PolicyType = &policyType
+	}
+
+	team, employee := definition.Team, definition.Employee
+	if team != nil && employee != nil {
+		return db.ToolPolicy{}, fmt.Errorf("set team or employee, not both")
+	}
+	if team != nil {
+		if err := d.loadTeams(ctx); err != nil {
+			return db.ToolPolicy{}, err
+		}
+		for _, t := range d.teams {
+			if t.Name == *team {
+				teamID := openapi_types.UUID(t.ID)
+				req.TeamId = &teamID
+				break
+			}
+		}
+		if req.TeamId == nil {
+			return db.ToolPolicy{}, fmt.Errorf("no team named %q", *team)
+		}
+	}
+	if employee != nil {
+		employeeID, ok := d.employeeIDs[*employee]
+		if !ok {
+			found, err := d.db.GetEmployeeByEmail(ctx, *employee)
+			switch {
+			case errors.Is(err, pgx.ErrNoRows) || (err == nil && found.OrgID != d.orgID):
+				return db.ToolPolicy{}, fmt.Errorf("no employee with email %q", *employee)
+			case err != nil:
+				return db.ToolPolicy{}, fmt.Errorf("%w: %v", errDirectoryLookup, err)
+			}
+			employeeID = found.ID
+			d.employeeIDs[*employee] = employeeID
+			d.emails[employeeID] = *employee
+		}
+		id := openapi_types.UUID(employeeID)
+		req.EmployeeId = &id
+	}
+
+	return draftToolPolicy(d.orgID, req)
+}
+
+// definition converts a saved (or drafted) policy to its definition
+func (d *policyDirectory) definition(ctx context.Context, policy db.ToolPolicy) (api.PolicyDefinition, error) {
+	apiPolicy := dbToolPolicyToAPI(policy)
+	definition := api.PolicyDefinition{
+		ToolName:   apiPolicy.ToolName,
+		Action:     api.PolicyDefinitionAction(apiPolicy.Action),
+		Reason:     apiPolicy.Reason,
+		Conditions: apiPolicy.Conditions,
+	}
+	// Tool is the default type, left out to keep files short
+	if apiPolicy.PolicyType != api.ToolPolicyPolicyTypeTool {
+		policyType := api.PolicyDefinitionPolicyType(apiPolicy.PolicyType)
+		definition.PolicyType = &policyType
+	}
+
+	if policy.EmployeeID.Valid {
+		employeeID := uuid.UUID(policy.EmployeeID.Bytes)
+		email, ok := d.emails[employeeID]
+		if !ok {
+			employee, err := d.db.GetEmployee(ctx, employeeID)
+			if err != nil {
+				return api.PolicyDefinition{}, fmt.Errorf("%w: %v", errDirectoryLookup, err)
+			}
+			email = employee.Email
+			d.emails[employeeID] = email
+		}
+		definition.Employee = &email
+		return definition, nil
+	}
+	if policy.TeamID.Valid {
+		teamID := uuid.UUID(policy.TeamID.Bytes)
+		name, ok, err := d.teamName(ctx, teamID)
+		if err != nil {
+			return api.PolicyDefinition{}, err
+		}
+		if !ok {
+			return api.PolicyDefinition{}, fmt.Errorf("%w: team %s not found", errDirectoryLookup, teamID)
+		}
+		definition.Team = &name
+	}
+	return definition, nil
+}
+
+// teamName returns the name of one of the organization's teams
+func (d *policyDirectory) teamName(ctx context.Context, teamID uuid.UUID) (string, bool, error) {
+	if err := d.loadTeams(ctx); err != nil {
+		return "", false, err
+	}
+	for _, t := range d.teams {
+		if t.ID == teamID {
+			return t.Name, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// planResponse describes planned (or applied) changes as definitions, with
+// their totals. declared is the number of policies the changes were planned
+// to reach.
+func (d *policyDirectory) planResponse(ctx context.Context, plan []service.PolicyPlanChange, declared int, applied bool) (api.ApplyToolPoliciesResponse, error) {
+	response := api.ApplyToolPoliciesResponse{
+		Changes: make([]api.PolicyPlanChange, 0, len(plan)),
+		Applied: applied,
+	}
+	for _, change := range plan {
+		policy := change.After
+		switch change.Type {
+		case service.PolicyChangeCreate:
+			response.Created++
+		case service.PolicyChangeUpdate:
+			response.Updated++
+		case service.PolicyChangeDelete:
+			response.Deleted++
+			policy = change.Before
+		}
+
+		definition, err := d.definition(ctx, *policy)
+		if err != nil {
+			return api.ApplyToolPoliciesResponse{}, err
+		}
+		apiChange := api.PolicyPlanChange{
+			ChangeType: api.PolicyPlanChangeChangeType(change.Type),
+			Policy:     definition,
+		}
+		if policy.ID != uuid.Nil {
+			policyID := openapi_types.UUID(policy.ID)
+			apiChange.PolicyId = &policyID
+		}
+		diffJSON, _ := json.Marshal(change.Diff)
+		_ = json.Unmarshal(diffJSON, &apiChange.Diff)
+		response.Changes = append(response.Changes, apiChange)
+	}
+	response.Unchanged = declared - response.Created - response.Updated
+	return response, nil
+}
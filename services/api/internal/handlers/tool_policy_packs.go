@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+	"github.com/rastrigin-systems/arfa/generated/api"
+	"github.com/rastrigin-systems/arfa/generated/db"
+	"github.com/rastrigin-systems/arfa/services/api/internal/middleware"
+	"github.com/rastrigin-systems/arfa/services/api/internal/service"
+)
+
+// ListPolicyPacks handles GET /policies/packs
+// Returns the built-in policy packs with the organization's installs of each
+func (h *ToolPoliciesHandler) ListPolicyPacks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	orgID, err := middleware.GetOrgID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	installs, err := h.db.ListPolicyPackInstalls(ctx, orgID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to list policy packs")
+		return
+	}
+
+	directory := newPolicyDirectory(h.db, orgID)
+	packs := make([]api.PolicyPack, 0, len(service.PolicyPacks()))
+	for _, pack := range service.PolicyPacks() {
+		apiPack := api.PolicyPack{
+			Name:        pack.Name,
+			Version:     int(pack.Version),
+			Title:       pack.Title,
+			Description: pack.Description,
+			Policies:    make([]api.PolicyDefinition, 0, len(pack.Policies)),
+			Installs:    []api.PolicyPackInstall{},
+		}
+		for _, policy := range pack.ToolPolicies(orgID, pgtype.UUID{}) {
+			definition, err := directory.definition(ctx, policy)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, "Failed to list policy packs")
+				return
+			}
+			apiPack.Policies = append(apiPack.Policies, definition)
+		}
+		for _, install := range installs {
+			if install.PackName != pack.Name {
+				continue
+			}
+			apiInstall, err := directory.packInstall(ctx, install)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, "Failed to list policy packs")
+				return
+			}
+			apiPack.Installs = append(apiPack.Installs, apiInstall)
+		}
+		packs = append(packs, apiPack)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(api.PolicyPackList{Packs: packs})
+}
+
+// InstallPolicyPack handles POST /policies/packs/{pack_name}/install
+// Installs the latest version of a pack, or upgrades an earlier install to it
+func (h *ToolPoliciesHandler) InstallPolicyPack(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	orgID, err := middleware.GetOrgID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	employeeID, err := middleware.GetEmployeeID(ctx)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// The route requires the admin or manager role
+
+	pack, ok := service.FindPolicyPack(chi.URLParam(r, "pack_name"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Policy pack not found")
+		return
+	}
+
+	// The body is optional: no body installs for the whole organization
+	var req api.InstallPolicyPackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	directory := newPolicyDirectory(h.db, orgID)
+	var teamID pgtype.UUID
+	if req.TeamId != nil {
+		_, found, err := directory.teamName(ctx, uuid.UUID(*req.TeamId))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to get team")
+			return
+		}
+		if !found {
+			writeError(w, http.StatusNotFound, "Team not found")
+			return
+		}
+		teamID = pgtype.UUID{Bytes: *req.TeamId, Valid: true}
+	}
+
+	dryRun := req.DryRun != nil && *req.DryRun
+	result, err := h.packs.Install(ctx, service.PackInstall{
+		OrgID:     orgID,
+		TeamID:    teamID,
+		Pack:      pack.Name,
+		ChangedBy: employeeID,
+		SessionID: requestSessionID(ctx),
+		DryRun:    dryRun,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to install policy pack; nothing was changed")
+		return
+	}
+
+	plan, err := directory.planResponse(ctx, result.Plan, len(pack.Policies), !dryRun)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to describe changes")
+		return
+	}
+	response := api.InstallPolicyPackResponse{
+		PackName:  pack.Name,
+		ToVersion: int(pack.Version),
+		Plan:      plan,
+	}
+	if result.FromVersion > 0 {
+		fromVersion := int(result.FromVersion)
+		response.FromVersion = &fromVersion
+	}
+	if result.Install.ID != uuid.Nil {
+		install, err := directory.packInstall(ctx, result.Install)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to describe changes")
+			return
+		}
+		response.Install = &install
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// packInstall converts a pack install to its API form, with its team's name
+func (d *policyDirectory) packInstall(ctx context.Context, install db.PolicyPackInstall) (api.PolicyPackInstall, error) {
+	apiInstall := api.PolicyPackInstall{
+		Id:          openapi_types.UUID(install.ID),
+		PackName:    install.PackName,
+		Version:     int(install.Version),
+		PolicyCount: len(install.PolicyIds),
+		CreatedAt:   install.CreatedAt.Time,
+		UpdatedAt:   install.UpdatedAt.Time,
+	}
+	if pack, ok := service.FindPolicyPack(install.PackName); ok {
+		apiInstall.UpgradeAvailable = install.Version < pack.Version
+	}
+	if install.InstalledBy.Valid {
+		installedBy := openapi_types.UUID(install.InstalledBy.Bytes)
+		apiInstall.InstalledBy = &installedBy
+	}
+	if install.TeamID.Valid {
+		teamID := uuid.UUID(install.TeamID.Bytes)
+		name, ok, err := d.teamName(ctx, teamID)
+		if err != nil {
+			return api.PolicyPackInstall{}, err
+		}
+		if !ok {
+			return api.PolicyPackInstall{}, fmt.Errorf("%w: team %s not found", errDirectoryLookup, teamID)
+		}
+		apiTeamID := openapi_types.UUID(teamID)
+		apiInstall.TeamId = &apiTeamID
+		apiInstall.Team = &name
+	}
+	return apiInstall, nil
+}
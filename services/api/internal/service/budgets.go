@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/rastrigin-systems/arfa/generated/db"
+)
+
+// Budget periods. Periods follow UTC calendar days and months.
+const (
+	BudgetPeriodDaily   = "daily"
+	BudgetPeriodMonthly = "monthly"
+)
+
+// BudgetStatus is a budget with the usage counted against it in the current period
+type BudgetStatus struct {
+	Budget      db.Budget
+	TokensUsed  int64
+	CostUsedUSD float64
+	ResetsAt    time.Time // Start of the next period
+}
+
+// Exceeded reports whether the usage has reached either limit
+func (s BudgetStatus) Exceeded() bool {
+	if s.Budget.TokenLimit != nil && s.TokensUsed >= *s.Budget.TokenLimit {
+		return true
+	}
+	return s.Budget.CostLimitUsd != nil && s.CostUsedUSD >= *s.Budget.CostLimitUsd
+}
+
+// UsageRecord is the token usage of one LLM response, as reported by a proxy
+type UsageRecord struct {
+	OrgID        uuid.UUID
+	EmployeeID   uuid.UUID
+	TeamID       pgtype.UUID // Employee's team, if any
+	InputTokens  int64
+	OutputTokens int64
+	CostUSD      float64
+	At           time.Time
+}
+
+// BudgetService tracks LLM usage against token and cost budgets.
+// Usage is kept per employee per day, so budgets hold across every
+// machine the employee's proxies run on.
+type BudgetService struct {
+	db  db.Querier
+	now func() time.Time
+}
+
+// NewBudgetService creates a new budget service
+func NewBudgetService(db db.Querier) *BudgetService {
+	return &BudgetService{
+		db:  db,
+		now: time.Now,
+	}
+}
+
+// RecordUsage adds a response's usage to the employee's daily total and
+// returns the updated status of every budget that covers the employee.
+func (s *BudgetService) RecordUsage(ctx context.Context, usage UsageRecord) ([]BudgetStatus, error) {
+	if usage.OrgID == uuid.Nil || usage.EmployeeID == uuid.Nil {
+		return nil, fmt.Errorf("org_id and employee_id are required")
+	}
+	if usage.InputTokens < 0 || usage.OutputTokens < 0 || usage.CostUSD < 0 {
+		return nil, fmt.Errorf("usage cannot be negative")
+	}
+	if usage.At.IsZero() {
+		usage.At = s.now()
+	}
+
+	err := s.db.RecordUsage(ctx, db.RecordUsageParams{
+		EmployeeID:   usage.EmployeeID,
+		Day:          pgtype.Date{Time: truncateDay(usage.At), Valid: true},
+		OrgID:        usage.OrgID,
+		TeamID:       usage.TeamID,
+		InputTokens:  usage.InputTokens,
+		OutputTokens: usage.OutputTokens,
+		CostUsd:      usage.CostUSD,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record usage: %w", err)
+	}
+
+	return s.StatusForEmployee(ctx, usage.OrgID, usage.EmployeeID, usage.TeamID)
+}
+
+// StatusForEmployee returns the status of every budget that covers the
+// employee: organization-wide, their team's and their own.
+func (s *BudgetService) StatusForEmployee(ctx context.Context, orgID, employeeID uuid.UUID, teamID pgtype.UUID) ([]BudgetStatus, error) {
+	budgets, err := s.db.GetBudgetsForEmployee(ctx, db.GetBudgetsForEmployeeParams{
+		OrgID:      orgID,
+		TeamID:     teamID,
+		EmployeeID: pgtype.UUID{Bytes: employeeID, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch budgets: %w", err)
+	}
+
+	statuses := make([]BudgetStatus, 0, len(budgets))
+	for _, budget := range budgets {
+		status, err := s.Status(ctx, budget)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Status sums the usage counted against a budget in its current period.
+// Team budgets count every member's usage; organization budgets count everyone's.
+func (s *BudgetService) Status(ctx context.Context, budget db.Budget) (BudgetStatus, error) {
+	start, end := periodBounds(budget.Period, s.now())
+
+	usage, err := s.db.GetUsageSince(ctx, db.GetUsageSinceParams{
+		OrgID:      budget.OrgID,
+		Since:      pgtype.Date{Time: start, Valid: true},
+		TeamID:     budget.TeamID,
+		EmployeeID: budget.EmployeeID,
+	})
+	if err != nil {
+		return BudgetStatus{}, fmt.Errorf("failed to sum usage: %w", err)
+	}
+
+	return BudgetStatus{
+		Budget:      budget,
+		TokensUsed:  usage.Tokens,
+		CostUsedUSD: usage.CostUsd,
+		ResetsAt:    end,
+	}, nil
+}
+
+// periodBounds returns the start of the period containing now and the start
+// of the next one, in UTC.
+func periodBounds(period string, now time.Time) (time.Time, time.Time) {
+	day := truncateDay(now)
+	if period == BudgetPeriodMonthly {
+		start := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0)
+	}
+	return day, day.AddDate(0, 0, 1)
+}
+
+// truncateDay returns midnight UTC of t's day
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/rastrigin-systems/arfa/generated/db"
+	"github.com/rastrigin-systems/arfa/generated/mocks"
+)
+
+func TestPeriodBounds(t *testing.T) {
+	now := time.Date(2026, 2, 14, 18, 30, 0, 0, time.FixedZone("PST", -8*3600))
+
+	start, end := periodBounds(BudgetPeriodDaily, now)
+	assert.Equal(t, time.Date(2026, 2, 15, 0, 0, 0, 0, time.UTC), start, "days are UTC")
+	assert.Equal(t, time.Date(2026, 2, 16, 0, 0, 0, 0, time.UTC), end)
+
+	start, end = periodBounds(BudgetPeriodMonthly, now)
+	assert.Equal(t, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), end)
+}
+
+func TestBudgetStatus_Exceeded(t *testing.T) {
+	tokens := int64(1000)
+	cost := 5.0
+
+	assert.False(t, BudgetStatus{Budget: db.Budget{TokenLimit: &tokens}, TokensUsed: 999}.Exceeded())
+	assert.True(t, BudgetStatus{Budget: db.Budget{TokenLimit: &tokens}, TokensUsed: 1000}.Exceeded())
+	assert.True(t, BudgetStatus{Budget: db.Budget{TokenLimit: &tokens, CostLimitUsd: &cost}, CostUsedUSD: 5.01}.Exceeded())
+	assert.False(t, BudgetStatus{Budget: db.Budget{CostLimitUsd: &cost}, TokensUsed: 1 << 40}.Exceeded())
+}
+
+func TestBudgetService_RecordUsage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mocks.NewMockQuerier(ctrl)
+	svc := NewBudgetService(mockDB)
+	svc.now = func() time.Time { return time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC) }
+
+	orgID := uuid.New()
+	employeeID := uuid.New()
+	teamID := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	tokenLimit := int64(50000)
+
+	mockDB.EXPECT().
+		RecordUsage(gomock.Any(), db.RecordUsageParams{
+			EmployeeID:   employeeID,
+			Day:          pgtype.Date{Time: time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC), Valid: true},
+			OrgID:        orgID,
+			TeamID:       teamID,
+			InputTokens:  1200,
+			OutputTokens: 300,
+			CostUsd:      0.0081,
+		}).
+		Return(nil)
+
+	teamBudget := db.Budget{ID: uuid.New(), OrgID: orgID, TeamID: teamID, Period: BudgetPeriodMonthly, TokenLimit: &tokenLimit}
+	mockDB.EXPECT().
+		GetBudgetsForEmployee(gomock.Any(), db.GetBudgetsForEmployeeParams{
+			OrgID:      orgID,
+			TeamID:     teamID,
+			EmployeeID: pgtype.UUID{Bytes: employeeID, Valid: true},
+		}).
+		Return([]db.Budget{teamBudget}, nil)
+
+	// Team budgets count the whole team's usage since the start of the month
+	mockDB.EXPECT().
+		GetUsageSince(gomock.Any(), db.GetUsageSinceParams{
+			OrgID:  orgID,
+			Since:  pgtype.Date{Time: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), Valid: true},
+			TeamID: teamID,
+		}).
+		Return(db.GetUsageSinceRow{Tokens: 51500, CostUsd: 0.31}, nil)
+
+	statuses, err := svc.RecordUsage(context.Background(), UsageRecord{
+		OrgID:        orgID,
+		EmployeeID:   employeeID,
+		TeamID:       teamID,
+		InputTokens:  1200,
+		OutputTokens: 300,
+		CostUSD:      0.0081,
+	})
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	assert.Equal(t, int64(51500), statuses[0].TokensUsed)
+	assert.Equal(t, time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), statuses[0].ResetsAt)
+	assert.True(t, statuses[0].Exceeded())
+}
+
+func TestBudgetService_RecordUsage_RejectsNegativeUsage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc := NewBudgetService(mocks.NewMockQuerier(ctrl))
+	_, err := svc.RecordUsage(context.Background(), UsageRecord{
+		OrgID:       uuid.New(),
+		EmployeeID:  uuid.New(),
+		InputTokens: -5,
+	})
+	assert.Error(t, err)
+}
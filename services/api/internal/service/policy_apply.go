@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/rastrigin-systems/arfa/generated/db"
+)
+
+// TxRunner runs fn against queries in a database transaction, committing
+// when fn returns nil and rolling back otherwise
+type TxRunner func(ctx context.Context, fn func(q db.Querier) error) error
+
+// PoolTxRunner runs transactions on a connection pool
+func PoolTxRunner(pool *pgxpool.Pool) TxRunner {
+	return func(ctx context.Context, fn func(q db.Querier) error) error {
+		tx, err := pool.Begin(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		// A no-op once committed
+		defer func() { _ = tx.Rollback(ctx) }()
+
+		if err := fn(db.New(tx)); err != nil {
+			return err
+		}
+		return tx.Commit(ctx)
+	}
+}
+
+// DirectTxRunner runs fn against database itself, outside a transaction, so
+// each change is made on its own
+func DirectTxRunner(database db.Querier) TxRunner {
+	return func(ctx context.Context, fn func(q db.Querier) error) error {
+		return fn(database)
+	}
+}
+
+// PolicyPlanChange is one change needed to bring an organization's tool
+// policies to their declared state
+type PolicyPlanChange struct {
+	Type   string         // PolicyChangeCreate, PolicyChangeUpdate or PolicyChangeDelete
+	Before *db.ToolPolicy // nil for creates
+	After  *db.ToolPolicy // nil for deletes; no ID for creates until applied
+	Diff   map[string]FieldChange
+}
+
+// PolicyApply is a declared set of tool policies for an organization
+type PolicyApply struct {
+	OrgID     uuid.UUID
+	Policies  []db.ToolPolicy // Validated, with their scopes resolved
+	ChangedBy uuid.UUID
+	SessionID pgtype.UUID
+	Note      *string
+	DryRun    bool
+}
+
+// PolicyApplyService brings an organization's tool policies to a declared
+// state in a single transaction
+type PolicyApplyService struct {
+	db    db.Querier
+	runTx TxRunner
+}
+
+// NewPolicyApplyService creates a new policy apply service. Without runTx
+// (as in tests) changes are applied one by one, outside a transaction.
+func NewPolicyApplyService(database db.Querier, runTx TxRunner) *PolicyApplyService {
+	if runTx == nil {
+		runTx = DirectTxRunner(database)
+	}
+	return &PolicyApplyService{db: database, runTx: runTx}
+}
+
+// Apply plans the changes that bring the organization's policies to the
+// declared set and, unless it's a dry run, makes them, recording a revision
+// for each. Either every change is made or none is. Applying a set that is
+// already in place changes nothing.
+func (s *PolicyApplyService) Apply(ctx context.Context, req PolicyApply) ([]PolicyPlanChange, error) {
+	if req.DryRun {
+		current, err := s.db.ListToolPoliciesByOrg(ctx, req.OrgID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list policies: %w", err)
+		}
+		return PlanToolPolicies(current, req.Policies), nil
+	}
+
+	var plan []PolicyPlanChange
+	err := s.runTx(ctx, func(q db.Querier) error {
+		current, err := q.ListToolPoliciesByOrg(ctx, req.OrgID)
+		if err != nil {
+			return fmt.Errorf("failed to list policies: %w", err)
+		}
+		plan = PlanToolPolicies(current, req.Policies)
+
+		revisions := NewPolicyRevisionService(q, nil)
+		for i := range plan {
+			if err := applyChange(ctx, q, req.OrgID, req.ChangedBy, &plan[i]); err != nil {
+				return err
+			}
+			if _, _, err := revisions.Record(ctx, PolicyChange{
+				Type:      plan[i].Type,
+				Before:    plan[i].Before,
+				After:     plan[i].After,
+				ChangedBy: req.ChangedBy,
+				SessionID: req.SessionID,
+				Note:      req.Note,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// applyChange makes one planned change, updating its After to the saved policy
+func applyChange(ctx context.Context, q db.Querier, orgID, changedBy uuid.UUID, change *PolicyPlanChange) error {
+	switch change.Type {
+	case PolicyChangeCreate:
+		policy, err := q.CreateToolPolicy(ctx, db.CreateToolPolicyParams{
+			OrgID:      orgID,
+			TeamID:     change.After.TeamID,
+			EmployeeID: change.After.EmployeeID,
+			ToolName:   change.After.ToolName,
+			Conditions: change.After.Conditions,
+			Action:     change.After.Action,
+			Reason:     change.After.Reason,
+			CreatedBy:  pgtype.UUID{Bytes: changedBy, Valid: changedBy != uuid.Nil},
+			PolicyType: change.After.PolicyType,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create policy: %w", err)
+		}
+		change.After = &policy
+	case PolicyChangeUpdate:
+		policy, err := q.ReplaceToolPolicyByOrg(ctx, db.ReplaceToolPolicyByOrgParams{
+			ID:         change.Before.ID,
+			OrgID:      orgID,
+			ToolName:   change.After.ToolName,
+			Conditions: change.After.Conditions,
+			Action:     change.After.Action,
+			Reason:     change.After.Reason,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update policy: %w", err)
+		}
+		change.After = &policy
+	case PolicyChangeDelete:
+		if err := q.DeleteToolPolicyByOrg(ctx, db.DeleteToolPolicyByOrgParams{ID: change.Before.ID, OrgID: orgID}); err != nil {
+			return fmt.Errorf("failed to delete policy: %w", err)
+		}
+	}
+	return nil
+}
+
+// policyKey identifies a declared policy: policies have no names, so a
+// declared policy stands for a saved one with the same scope, type and tool
+type policyKey struct {
+	teamID     pgtype.UUID
+	employeeID pgtype.UUID
+	policyType string
+	toolName   string
+}
+
+func keyOf(p db.ToolPolicy) policyKey {
+	return policyKey{teamID: p.TeamID, employeeID: p.EmployeeID, policyType: p.PolicyType, toolName: p.ToolName}
+}
+
+// PlanToolPolicies returns the changes that turn the current policies into
+// the desired ones. Each desired policy is paired with a current policy of the
+// same scope, type and tool: first one identical to it, then the oldest left.
+// Pairs that differ become updates, unpaired desired policies creates and
+// unpaired current policies deletes. Creates and updates follow the order of
+// desired, then come the deletes, oldest first.
+func PlanToolPolicies(current, desired []db.ToolPolicy) []PolicyPlanChange {
+	oldestFirst := make([]db.ToolPolicy, len(current))
+	copy(oldestFirst, current)
+	sort.SliceStable(oldestFirst, func(i, j int) bool {
+		return oldestFirst[i].CreatedAt.Time.Before(oldestFirst[j].CreatedAt.Time)
+	})
+
+	candidates := make(map[policyKey][]int)
+	for i, p := range oldestFirst {
+		candidates[keyOf(p)] = append(candidates[keyOf(p)], i)
+	}
+	paired := make([]int, len(desired))
+	taken := make([]bool, len(oldestFirst))
+
+	// Identical pairs first, so a reordered file doesn't turn into updates
+	for i, want := range desired {
+		paired[i] = -1
+		for _, c := range candidates[keyOf(want)] {
+			if !taken[c] && len(diffPolicies(&oldestFirst[c], &want)) == 0 {
+				paired[i], taken[c] = c, true
+				break
+			}
+		}
+	}
+	for i, want := range desired {
+		if paired[i] >= 0 {
+			continue
+		}
+		for _, c := range candidates[keyOf(want)] {
+			if !taken[c] {
+				paired[i], taken[c] = c, true
+				break
+			}
+		}
+	}
+
+	var plan []PolicyPlanChange
+	for i := range desired {
+		after := desired[i]
+		if paired[i] < 0 {
+			plan = append(plan, PolicyPlanChange{Type: PolicyChangeCreate, After: &after, Diff: diffPolicies(nil, &after)})
+			continue
+		}
+		before := oldestFirst[paired[i]]
+		if diff := diffPolicies(&before, &after); len(diff) > 0 {
+			plan = append(plan, PolicyPlanChange{Type: PolicyChangeUpdate, Before: &before, After: &after, Diff: diff})
+		}
+	}
+	for c := range oldestFirst {
+		if !taken[c] {
+			before := oldestFirst[c]
+			plan = append(plan, PolicyPlanChange{Type: PolicyChangeDelete, Before: &before, Diff: diffPolicies(&before, nil)})
+		}
+	}
+	return plan
+}
+
+// diffPolicies diffs two policies, either of which may be nil
+func diffPolicies(before, after *db.ToolPolicy) map[string]FieldChange {
+	var from, to *PolicySnapshot
+	if before != nil {
+		snapshot := NewPolicySnapshot(*before)
+		from = &snapshot
+	}
+	if after != nil {
+		snapshot := NewPolicySnapshot(*after)
+		to = &snapshot
+	}
+	return DiffPolicySnapshots(from, to)
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/rastrigin-systems/arfa/generated/db"
+	"github.com/rastrigin-systems/arfa/generated/mocks"
+)
+
+func savedPolicy(orgID uuid.UUID, toolName, action string, age time.Duration) db.ToolPolicy {
+	return db.ToolPolicy{
+		ID:         uuid.New(),
+		OrgID:      orgID,
+		PolicyType: "tool",
+		ToolName:   toolName,
+		Action:     action,
+		CreatedAt:  pgtype.Timestamp{Time: time.Now().Add(-age), Valid: true},
+	}
+}
+
+func TestPlanToolPolicies(t *testing.T) {
+	orgID := uuid.New()
+	teamID := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+
+	bash := savedPolicy(orgID, "Bash", "deny", 3*time.Hour)
+	webFetch := savedPolicy(orgID, "WebFetch", "audit", 2*time.Hour)
+	teamBash := savedPolicy(orgID, "Bash", "audit", time.Hour)
+	teamBash.TeamID = teamID
+	current := []db.ToolPolicy{teamBash, webFetch, bash}
+
+	t.Run("declared state in place", func(t *testing.T) {
+		desired := []db.ToolPolicy{
+			{OrgID: orgID, PolicyType: "tool", ToolName: "WebFetch", Action: "audit"},
+			{OrgID: orgID, TeamID: teamID, PolicyType: "tool", ToolName: "Bash", Action: "audit"},
+			{OrgID: orgID, PolicyType: "tool", ToolName: "Bash", Action: "deny"},
+		}
+		assert.Empty(t, PlanToolPolicies(current, desired))
+	})
+
+	t.Run("create, update and delete", func(t *testing.T) {
+		reason := "No shell"
+		desired := []db.ToolPolicy{
+			{OrgID: orgID, PolicyType: "tool", ToolName: "Bash", Action: "deny", Reason: &reason},
+			{OrgID: orgID, TeamID: teamID, PolicyType: "tool", ToolName: "Bash", Action: "audit"},
+			{OrgID: orgID, PolicyType: "tool", ToolName: "Write", Action: "deny"},
+		}
+		plan := PlanToolPolicies(current, desired)
+		require.Len(t, plan, 3)
+
+		assert.Equal(t, PolicyChangeUpdate, plan[0].Type)
+		assert.Equal(t, bash.ID, plan[0].Before.ID)
+		assert.Equal(t, map[string]FieldChange{"reason": {To: reason}}, plan[0].Diff)
+
+		assert.Equal(t, PolicyChangeCreate, plan[1].Type)
+		assert.Equal(t, "Write", plan[1].After.ToolName)
+
+		assert.Equal(t, PolicyChangeDelete, plan[2].Type)
+		assert.Equal(t, webFetch.ID, plan[2].Before.ID)
+	})
+
+	t.Run("policies sharing a tool pair by content", func(t *testing.T) {
+		first := savedPolicy(orgID, "Bash", "deny", 2*time.Hour)
+		first.Conditions = []byte(`{"command": "rm"}`)
+		second := savedPolicy(orgID, "Bash", "audit", time.Hour)
+
+		// Declared in the other order, and with conditions reformatted
+		desired := []db.ToolPolicy{
+			{OrgID: orgID, PolicyType: "tool", ToolName: "Bash", Action: "audit"},
+			{OrgID: orgID, PolicyType: "tool", ToolName: "Bash", Action: "deny", Conditions: []byte(`{"command":"rm"}`)},
+		}
+		assert.Empty(t, PlanToolPolicies([]db.ToolPolicy{second, first}, desired))
+
+		// A third is a create; dropping one deletes the one that differs
+		plan := PlanToolPolicies([]db.ToolPolicy{second, first}, append(desired, desired[0]))
+		require.Len(t, plan, 1)
+		assert.Equal(t, PolicyChangeCreate, plan[0].Type)
+
+		plan = PlanToolPolicies([]db.ToolPolicy{second, first}, desired[:1])
+		require.Len(t, plan, 1)
+		assert.Equal(t, PolicyChangeDelete, plan[0].Type)
+		assert.Equal(t, first.ID, plan[0].Before.ID)
+	})
+}
+
+func TestPolicyApplyService_Apply(t *testing.T) {
+	orgID := uuid.New()
+	employeeID := uuid.New()
+	note := "Sync from git"
+	bash := savedPolicy(orgID, "Bash", "audit", time.Hour)
+	webFetch := savedPolicy(orgID, "WebFetch", "audit", time.Hour)
+	desired := []db.ToolPolicy{
+		{OrgID: orgID, PolicyType: "tool", ToolName: "Bash", Action: "deny"},
+		{OrgID: orgID, PolicyType: "tool", ToolName: "Write", Action: "deny"},
+	}
+
+	t.Run("applies in a transaction", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockDB := mocks.NewMockQuerier(ctrl)
+		txDB := mocks.NewMockQuerier(ctrl)
+
+		var inTx bool
+		runTx := func(ctx context.Context, fn func(q db.Querier) error) error {
+			inTx = true
+			return fn(txDB)
+		}
+
+		txDB.EXPECT().ListToolPoliciesByOrg(gomock.Any(), orgID).Return([]db.ToolPolicy{bash, webFetch}, nil)
+		updated := bash
+		updated.Action = "deny"
+		txDB.EXPECT().
+			ReplaceToolPolicyByOrg(gomock.Any(), db.ReplaceToolPolicyByOrgParams{ID: bash.ID, OrgID: orgID, ToolName: "Bash", Action: "deny"}).
+			Return(updated, nil)
+		txDB.EXPECT().
+			CreateToolPolicy(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, params db.CreateToolPolicyParams) (db.ToolPolicy, error) {
+				assert.Equal(t, "Write", params.ToolName)
+				assert.Equal(t, pgtype.UUID{Bytes: employeeID, Valid: true}, params.CreatedBy)
+				return db.ToolPolicy{ID: uuid.New(), OrgID: orgID, PolicyType: params.PolicyType, ToolName: params.ToolName, Action: params.Action}, nil
+			})
+		txDB.EXPECT().DeleteToolPolicyByOrg(gomock.Any(), db.DeleteToolPolicyByOrgParams{ID: webFetch.ID, OrgID: orgID}).Return(nil)
+		txDB.EXPECT().
+			CreateToolPolicyRevision(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, params db.CreateToolPolicyRevisionParams) (db.ToolPolicyRevision, error) {
+				assert.Equal(t, &note, params.Note)
+				return db.ToolPolicyRevision{Revision: 1}, nil
+			}).
+			Times(3)
+
+		plan, err := NewPolicyApplyService(mockDB, runTx).Apply(context.Background(), PolicyApply{
+			OrgID:     orgID,
+			Policies:  desired,
+			ChangedBy: employeeID,
+			Note:      &note,
+		})
+		require.NoError(t, err)
+		assert.True(t, inTx)
+		require.Len(t, plan, 3)
+		assert.NotEqual(t, uuid.Nil, plan[1].After.ID, "creates carry the saved policy")
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockDB := mocks.NewMockQuerier(ctrl)
+
+		mockDB.EXPECT().ListToolPoliciesByOrg(gomock.Any(), orgID).Return([]db.ToolPolicy{bash, webFetch}, nil)
+
+		runTx := func(ctx context.Context, fn func(q db.Querier) error) error {
+			t.Fatal("a dry run doesn't open a transaction")
+			return nil
+		}
+		plan, err := NewPolicyApplyService(mockDB, runTx).Apply(context.Background(), PolicyApply{OrgID: orgID, Policies: desired, DryRun: true})
+		require.NoError(t, err)
+		assert.Len(t, plan, 3)
+	})
+
+	t.Run("failure rolls back", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockDB := mocks.NewMockQuerier(ctrl)
+
+		mockDB.EXPECT().ListToolPoliciesByOrg(gomock.Any(), orgID).Return([]db.ToolPolicy{bash}, nil)
+		mockDB.EXPECT().ReplaceToolPolicyByOrg(gomock.Any(), gomock.Any()).Return(db.ToolPolicy{}, errors.New("connection reset"))
+
+		var txErr error
+		runTx := func(ctx context.Context, fn func(q db.Querier) error) error {
+			txErr = fn(mockDB)
+			return txErr
+		}
+		_, err := NewPolicyApplyService(mockDB, runTx).Apply(context.Background(), PolicyApply{OrgID: orgID, Policies: desired})
+		assert.ErrorContains(t, err, "failed to update policy")
+		assert.Equal(t, txErr, err, "the transaction sees the error, so it rolls back")
+	})
+}
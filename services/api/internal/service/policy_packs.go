@@ -0,0 +1,326 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/rastrigin-systems/arfa/generated/db"
+)
+
+// ErrUnknownPack is returned for a pack name no built-in pack has
+var ErrUnknownPack = errors.New("unknown policy pack")
+
+// PackPolicy is one policy of a policy pack
+type PackPolicy struct {
+	PolicyType string // Default tool
+	ToolName   string
+	Action     string
+	Reason     string
+	Conditions json.RawMessage
+}
+
+// PolicyPack is a curated, versioned set of tool policies that can be
+// installed for an organization or a team. A pack's version goes up whenever
+// its policies change, so installs can be upgraded.
+type PolicyPack struct {
+	Name        string
+	Version     int32
+	Title       string
+	Description string
+	Policies    []PackPolicy
+}
+
+// builtinPacks are the packs every organization can install. Shell
+// conditions match the commands a command line runs, however they're
+// chained, quoted or flagged (see policy_conditions.go).
+var builtinPacks = []PolicyPack{
+	{
+		Name:        "secrets-protection",
+		Version:     1,
+		Title:       "Secrets protection",
+		Description: "Keeps agents away from credential files: environment files, SSH and cloud credentials, keys and certificates.",
+		Policies: []PackPolicy{
+			{PolicyType: "path", ToolName: "**/.env", Action: "deny", Reason: "Environment files hold secrets"},
+			{PolicyType: "path", ToolName: "**/.env.*", Action: "deny", Reason: "Environment files hold secrets",
+				Conditions: json.RawMessage(`{"allowed_paths": ["**/.env.example", "**/.env.sample", "**/.env.template"]}`)},
+			{PolicyType: "path", ToolName: "**/*.pem", Action: "deny", Reason: "Keys and certificates are secrets"},
+			{PolicyType: "path", ToolName: "**/*.key", Action: "deny", Reason: "Keys and certificates are secrets"},
+			{PolicyType: "path", ToolName: "~/.ssh/**", Action: "deny", Reason: "SSH keys are secrets"},
+			{PolicyType: "path", ToolName: "~/.aws/**", Action: "deny", Reason: "Cloud credentials are secrets"},
+			{PolicyType: "path", ToolName: "~/.config/gcloud/**", Action: "deny", Reason: "Cloud credentials are secrets"},
+			{PolicyType: "path", ToolName: "~/.azure/**", Action: "deny", Reason: "Cloud credentials are secrets"},
+			{PolicyType: "path", ToolName: "~/.kube/config", Action: "deny", Reason: "Cluster credentials are secrets"},
+			{PolicyType: "path", ToolName: "~/.docker/config.json", Action: "deny", Reason: "Registry credentials are secrets"},
+			{PolicyType: "path", ToolName: "~/.netrc", Action: "deny", Reason: "Login credentials are secrets"},
+			{ToolName: "Bash", Action: "deny", Reason: "Printing credential files exposes secrets",
+				Conditions: json.RawMessage(`{"command": "\\b(cat|less|more|head|tail|strings|xxd|base64)\\s+[^;&|]*(\\.env\\b|id_(rsa|ed25519|ecdsa)|\\.pem\\b|\\.aws/credentials|\\.netrc)"}`)},
+		},
+	},
+	{
+		Name:        "destructive-commands",
+		Version:     1,
+		Title:       "Destructive commands",
+		Description: "Blocks shell commands that destroy data beyond the project: recursive deletes of / or home, disk wipes, force pushes and fork bombs.",
+		Policies: []PackPolicy{
+			{ToolName: "Bash", Action: "deny", Reason: "Recursive deletes of / or the home directory are blocked",
+				Conditions: json.RawMessage(`{"any": [
+					{"param_path": "command", "operator": "shell", "value": {"command": "rm", "args": ["/"], "flags": ["-r|-R|--recursive"]}},
+					{"param_path": "command", "operator": "shell", "value": {"command": "rm", "args": ["/*"], "flags": ["-r|-R|--recursive"]}},
+					{"param_path": "command", "operator": "shell", "value": {"command": "rm", "args": ["~"], "flags": ["-r|-R|--recursive"]}},
+					{"param_path": "command", "operator": "shell", "value": {"command": "rm", "args": ["~/"], "flags": ["-r|-R|--recursive"]}},
+					{"param_path": "command", "operator": "shell", "value": {"command": "rm", "args": ["$HOME"], "flags": ["-r|-R|--recursive"]}},
+					{"param_path": "command", "operator": "shell", "value": {"command": "rm", "flags": ["--no-preserve-root"]}}
+				]}`)},
+			{ToolName: "Bash", Action: "deny", Reason: "Formatting and overwriting disks is blocked",
+				Conditions: json.RawMessage(`{"any": [
+					{"param_path": "command", "operator": "shell", "value": {"command": "mkfs*"}},
+					{"param_path": "command", "operator": "matches", "value": "\\bdd\\b[^;&|]*\\bof=/dev/"}
+				]}`)},
+			{ToolName: "Bash", Action: "deny", Reason: "Force pushes rewrite shared history",
+				Conditions: json.RawMessage(`{"command": {"shell": {"command": "git", "args": ["push"], "flags": ["--force|-f"]}}}`)},
+			{ToolName: "Bash", Action: "deny", Reason: "Recursive permission changes on / are blocked",
+				Conditions: json.RawMessage(`{"command": {"shell": {"command": "chmod", "args": ["/"], "flags": ["-R|--recursive"]}}}`)},
+			{ToolName: "Bash", Action: "deny", Reason: "Fork bombs are blocked",
+				Conditions: json.RawMessage(`{"command": ":\\(\\)\\s*\\{\\s*:\\s*\\|\\s*:\\s*&\\s*\\}"}`)},
+		},
+	},
+	{
+		Name:        "cloud-clis",
+		Version:     1,
+		Title:       "Cloud CLIs",
+		Description: "Blocks cloud MCP servers, holds infrastructure changes for approval and audits cloud CLI use.",
+		Policies: []PackPolicy{
+			{ToolName: "mcp__gcloud__%", Action: "deny", Reason: "Cloud changes go through reviewed pipelines"},
+			{ToolName: "mcp__aws__%", Action: "deny", Reason: "Cloud changes go through reviewed pipelines"},
+			{ToolName: "mcp__azure__%", Action: "deny", Reason: "Cloud changes go through reviewed pipelines"},
+			{ToolName: "mcp__kubernetes__%", Action: "deny", Reason: "Cloud changes go through reviewed pipelines"},
+			{ToolName: "Bash", Action: "require_approval", Reason: "Infrastructure changes need approval",
+				Conditions: json.RawMessage(`{"any": [
+					{"param_path": "command", "operator": "shell", "value": {"command": "terraform", "args": ["apply"]}},
+					{"param_path": "command", "operator": "shell", "value": {"command": "terraform", "args": ["destroy"]}},
+					{"param_path": "command", "operator": "shell", "value": {"command": "kubectl", "args": ["apply"]}},
+					{"param_path": "command", "operator": "shell", "value": {"command": "kubectl", "args": ["delete"]}},
+					{"param_path": "command", "operator": "shell", "value": {"command": "helm", "args": ["upgrade"]}},
+					{"param_path": "command", "operator": "shell", "value": {"command": "helm", "args": ["uninstall"]}}
+				]}`)},
+			{ToolName: "Bash", Action: "audit", Reason: "Cloud CLI use is audited",
+				Conditions: json.RawMessage(`{"any": [
+					{"param_path": "command", "operator": "shell", "value": {"command": "gcloud"}},
+					{"param_path": "command", "operator": "shell", "value": {"command": "aws"}},
+					{"param_path": "command", "operator": "shell", "value": {"command": "az"}}
+				]}`)},
+		},
+	},
+	{
+		Name:        "network-audit",
+		Version:     1,
+		Title:       "Network audit",
+		Description: "Audits web fetches and shell network tools, without blocking them.",
+		Policies: []PackPolicy{
+			{ToolName: "WebFetch", Action: "audit", Reason: "Network access is audited"},
+			{ToolName: "WebSearch", Action: "audit", Reason: "Network access is audited"},
+			{ToolName: "mcp__fetch__%", Action: "audit", Reason: "Network access is audited"},
+			{ToolName: "Bash", Action: "audit", Reason: "Network access is audited",
+				Conditions: json.RawMessage(`{"any": [
+					{"param_path": "command", "operator": "shell", "value": {"command": "curl"}},
+					{"param_path": "command", "operator": "shell", "value": {"command": "wget"}},
+					{"param_path": "command", "operator": "shell", "value": {"command": "ssh"}},
+					{"param_path": "command", "operator": "shell", "value": {"command": "scp"}},
+					{"param_path": "command", "operator": "shell", "value": {"command": "rsync"}},
+					{"param_path": "command", "operator": "shell", "value": {"command": "nc"}},
+					{"param_path": "command", "operator": "shell", "value": {"command": "ncat"}},
+					{"param_path": "command", "operator": "shell", "value": {"command": "telnet"}}
+				]}`)},
+		},
+	},
+}
+
+// PolicyPacks returns the built-in policy packs
+func PolicyPacks() []PolicyPack {
+	return builtinPacks
+}
+
+// FindPolicyPack returns the built-in pack with a name
+func FindPolicyPack(name string) (PolicyPack, bool) {
+	for _, pack := range builtinPacks {
+		if pack.Name == name {
+			return pack, true
+		}
+	}
+	return PolicyPack{}, false
+}
+
+// ToolPolicies returns the pack's policies as they would be saved for an
+// organization, or one of its teams
+func (p PolicyPack) ToolPolicies(orgID uuid.UUID, teamID pgtype.UUID) []db.ToolPolicy {
+	policies := make([]db.ToolPolicy, 0, len(p.Policies))
+	for _, pp := range p.Policies {
+		policy := db.ToolPolicy{
+			OrgID:      orgID,
+			TeamID:     teamID,
+			PolicyType: pp.PolicyType,
+			ToolName:   pp.ToolName,
+			Action:     pp.Action,
+			Conditions: pp.Conditions,
+		}
+		if policy.PolicyType == "" {
+			policy.PolicyType = "tool"
+		}
+		if pp.Reason != "" {
+			reason := pp.Reason
+			policy.Reason = &reason
+		}
+		policies = append(policies, policy)
+	}
+	return policies
+}
+
+// PackInstall installs a pack for an organization, or one of its teams
+type PackInstall struct {
+	OrgID     uuid.UUID
+	TeamID    pgtype.UUID // Unset for the whole organization
+	Pack      string
+	ChangedBy uuid.UUID
+	SessionID pgtype.UUID
+	DryRun    bool
+}
+
+// PackInstallResult is what installing a pack did, or would do
+type PackInstallResult struct {
+	Pack        PolicyPack
+	Install     db.PolicyPackInstall // Unset for a dry run of a new install
+	FromVersion int32                // Version installed before; 0 for a new install
+	Plan        []PolicyPlanChange
+}
+
+// PolicyPackService installs and upgrades built-in policy packs
+type PolicyPackService struct {
+	db    db.Querier
+	runTx TxRunner
+}
+
+// NewPolicyPackService creates a new policy pack service. Without runTx
+// (as in tests) changes are made one by one, outside a transaction.
+func NewPolicyPackService(database db.Querier, runTx TxRunner) *PolicyPackService {
+	if runTx == nil {
+		runTx = DirectTxRunner(database)
+	}
+	return &PolicyPackService{db: database, runTx: runTx}
+}
+
+// Install installs the latest version of a pack, or upgrades an earlier
+// install to it. An install owns the policies it created: an upgrade plans
+// the latest pack against them, as they are now, so it also reverts edits
+// to them and recreates any that were deleted. Other policies are never
+// touched. Each change is recorded in its policy's history.
+func (s *PolicyPackService) Install(ctx context.Context, req PackInstall) (PackInstallResult, error) {
+	pack, ok := FindPolicyPack(req.Pack)
+	if !ok {
+		return PackInstallResult{}, ErrUnknownPack
+	}
+	result := PackInstallResult{Pack: pack}
+
+	install := func(q db.Querier) error {
+		existing, err := q.GetPolicyPackInstall(ctx, db.GetPolicyPackInstallParams{
+			OrgID:    req.OrgID,
+			TeamID:   req.TeamID,
+			PackName: pack.Name,
+		})
+		installed := err == nil
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("failed to get pack install: %w", err)
+		}
+
+		var owned []db.ToolPolicy
+		if installed {
+			result.Install = existing
+			result.FromVersion = existing.Version
+			owned, err = q.ListToolPoliciesByIDs(ctx, db.ListToolPoliciesByIDsParams{OrgID: req.OrgID, Ids: existing.PolicyIds})
+			if err != nil {
+				return fmt.Errorf("failed to list pack policies: %w", err)
+			}
+		}
+		result.Plan = PlanToolPolicies(owned, pack.ToolPolicies(req.OrgID, req.TeamID))
+		if req.DryRun {
+			return nil
+		}
+
+		note := fmt.Sprintf("Installed policy pack %s v%d", pack.Name, pack.Version)
+		if installed {
+			note = fmt.Sprintf("Upgraded policy pack %s from v%d to v%d", pack.Name, existing.Version, pack.Version)
+		}
+		deleted := make(map[uuid.UUID]bool)
+		var created []uuid.UUID
+		revisions := NewPolicyRevisionService(q, nil)
+		for i := range result.Plan {
+			change := &result.Plan[i]
+			if err := applyChange(ctx, q, req.OrgID, req.ChangedBy, change); err != nil {
+				return err
+			}
+			switch change.Type {
+			case PolicyChangeCreate:
+				created = append(created, change.After.ID)
+			case PolicyChangeDelete:
+				deleted[change.Before.ID] = true
+			}
+			if _, _, err := revisions.Record(ctx, PolicyChange{
+				Type:      change.Type,
+				Before:    change.Before,
+				After:     change.After,
+				ChangedBy: req.ChangedBy,
+				SessionID: req.SessionID,
+				Note:      &note,
+			}); err != nil {
+				return err
+			}
+		}
+
+		policyIDs := make([]uuid.UUID, 0, len(pack.Policies))
+		for _, p := range owned {
+			if !deleted[p.ID] {
+				policyIDs = append(policyIDs, p.ID)
+			}
+		}
+		policyIDs = append(policyIDs, created...)
+
+		installedBy := pgtype.UUID{Bytes: req.ChangedBy, Valid: req.ChangedBy != uuid.Nil}
+		if installed {
+			result.Install, err = q.UpdatePolicyPackInstall(ctx, db.UpdatePolicyPackInstallParams{
+				ID:          existing.ID,
+				Version:     pack.Version,
+				PolicyIds:   policyIDs,
+				InstalledBy: installedBy,
+			})
+		} else {
+			result.Install, err = q.CreatePolicyPackInstall(ctx, db.CreatePolicyPackInstallParams{
+				OrgID:       req.OrgID,
+				TeamID:      req.TeamID,
+				PackName:    pack.Name,
+				Version:     pack.Version,
+				PolicyIds:   policyIDs,
+				InstalledBy: installedBy,
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("failed to record pack install: %w", err)
+		}
+		return nil
+	}
+
+	var err error
+	if req.DryRun {
+		err = install(s.db)
+	} else {
+		err = s.runTx(ctx, install)
+	}
+	if err != nil {
+		return PackInstallResult{}, err
+	}
+	return result, nil
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/rastrigin-systems/arfa/generated/db"
+	"github.com/rastrigin-systems/arfa/pkg/policy"
+)
+
+// The proxy lets an allow policy exempt a tool call from the deny,
+// require_approval and rate_limit policies it outranks, ranking policies as
+// policy.Rank orders them. Policies are sent to it highest ranked first.
+
+// ToolPolicyScope returns the scope a policy is set for
+func ToolPolicyScope(p db.ToolPolicy) string {
+	switch {
+	case p.EmployeeID.Valid:
+		return policy.ScopeEmployee
+	case p.TeamID.Valid:
+		return policy.ScopeTeam
+	default:
+		return policy.ScopeOrganization
+	}
+}
+
+// ToolPolicyRank returns what a policy is ranked by
+func ToolPolicyRank(p db.ToolPolicy) policy.Rank {
+	return policy.Rank{
+		Scope:       ToolPolicyScope(p),
+		ToolName:    p.ToolName,
+		Conditional: hasMatchConditions(p.Conditions),
+	}
+}
+
+// ToolPolicyOutranks reports whether policy a takes precedence over policy b
+func ToolPolicyOutranks(a, b db.ToolPolicy) bool {
+	return ToolPolicyRank(a).Outranks(ToolPolicyRank(b))
+}
+
+// SortToolPoliciesByPrecedence orders policies from the highest ranked down,
+// keeping the order of policies that tie
+func SortToolPoliciesByPrecedence(policies []db.ToolPolicy) {
+	sort.SliceStable(policies, func(i, j int) bool {
+		return ToolPolicyOutranks(policies[i], policies[j])
+	})
+}
+
+// hasMatchConditions reports whether a policy's conditions match tool input,
+// rather than only configuring the policy
+func hasMatchConditions(raw []byte) bool {
+	var conditions map[string]interface{}
+	if err := json.Unmarshal(raw, &conditions); err != nil {
+		return false
+	}
+	for key := range conditions {
+		if !policy.IsReserved(key) {
+			return true
+		}
+	}
+	return false
+}
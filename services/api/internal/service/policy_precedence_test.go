@@ -0,0 +1,62 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rastrigin-systems/arfa/generated/db"
+)
+
+func TestToolPolicyOutranks(t *testing.T) {
+	team := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	employee := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+
+	org := db.ToolPolicy{ToolName: "Bash"}
+	teamPolicy := db.ToolPolicy{ToolName: "%", TeamID: team}
+	employeePolicy := db.ToolPolicy{ToolName: "B%", TeamID: team, EmployeeID: employee}
+
+	assert.True(t, ToolPolicyOutranks(employeePolicy, teamPolicy))
+	assert.True(t, ToolPolicyOutranks(teamPolicy, org), "scope comes before the tool name")
+	assert.False(t, ToolPolicyOutranks(org, teamPolicy))
+
+	assert.True(t, ToolPolicyOutranks(db.ToolPolicy{ToolName: "Bash"}, db.ToolPolicy{ToolName: "Ba%"}))
+	assert.True(t, ToolPolicyOutranks(db.ToolPolicy{ToolName: "mcp__github__%"}, db.ToolPolicy{ToolName: "mcp__%"}))
+
+	conditional := db.ToolPolicy{ToolName: "Bash", Conditions: []byte(`{"command": "^git"}`)}
+	limited := db.ToolPolicy{ToolName: "Bash", Conditions: []byte(`{"rate_limit": {"max_calls": 5, "window_seconds": 60}}`)}
+	assert.True(t, ToolPolicyOutranks(conditional, org))
+	assert.True(t, ToolPolicyOutranks(conditional, limited), "a rate limit isn't a condition")
+	assert.False(t, ToolPolicyOutranks(limited, org), "ties")
+	assert.False(t, ToolPolicyOutranks(org, limited), "ties")
+}
+
+func TestSortToolPoliciesByPrecedence(t *testing.T) {
+	team := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	employee := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+
+	policies := []db.ToolPolicy{
+		{ToolName: "mcp__%", Action: "deny"},
+		{ToolName: "Bash", Action: "deny"},
+		{ToolName: "Bash", Action: "allow", TeamID: team},
+		{ToolName: "mcp__github__%", Action: "deny"},
+		{ToolName: "%", Action: "audit", EmployeeID: employee},
+		{ToolName: "Bash", Action: "audit"},
+	}
+	SortToolPoliciesByPrecedence(policies)
+
+	var order []string
+	for _, p := range policies {
+		order = append(order, p.ToolName+":"+p.Action)
+	}
+	assert.Equal(t, []string{
+		"%:audit",
+		"Bash:allow",
+		"Bash:deny",
+		"Bash:audit",
+		"mcp__github__%:deny",
+		"mcp__%:deny",
+	}, order)
+}
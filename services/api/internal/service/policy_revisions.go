@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/rastrigin-systems/arfa/generated/db"
+)
+
+// Tool policy change types, as recorded in a policy's history
+const (
+	PolicyChangeCreate   = "create"
+	PolicyChangeUpdate   = "update"
+	PolicyChangeDelete   = "delete"
+	PolicyChangeRollback = "rollback"
+)
+
+var (
+	// ErrRevisionNotFound is returned when a policy has no such revision
+	ErrRevisionNotFound = errors.New("revision not found")
+	// ErrRevisionDeleted is returned when rolling back to a delete
+	ErrRevisionDeleted = errors.New("revision deleted the policy")
+)
+
+// PolicySnapshot is the state of a tool policy kept in its history
+type PolicySnapshot struct {
+	PolicyType string          `json:"policy_type"`
+	ToolName   string          `json:"tool_name"`
+	Action     string          `json:"action"`
+	Reason     *string         `json:"reason"`
+	Conditions json.RawMessage `json:"conditions"`
+	TeamID     *uuid.UUID      `json:"team_id"`
+	EmployeeID *uuid.UUID      `json:"employee_id"`
+}
+
+// NewPolicySnapshot captures the state of a tool policy
+func NewPolicySnapshot(p db.ToolPolicy) PolicySnapshot {
+	snapshot := PolicySnapshot{
+		PolicyType: p.PolicyType,
+		ToolName:   p.ToolName,
+		Action:     p.Action,
+		Reason:     p.Reason,
+	}
+	if len(p.Conditions) > 0 {
+		snapshot.Conditions = json.RawMessage(p.Conditions)
+	}
+	if p.TeamID.Valid {
+		teamID := uuid.UUID(p.TeamID.Bytes)
+		snapshot.TeamID = &teamID
+	}
+	if p.EmployeeID.Valid {
+		employeeID := uuid.UUID(p.EmployeeID.Bytes)
+		snapshot.EmployeeID = &employeeID
+	}
+	return snapshot
+}
+
+// FieldChange is the old and new value of a changed policy field. From is
+// nil for creates and To is nil for deletes.
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// DiffPolicySnapshots returns the fields that differ between two states of
+// a policy, by their JSON names. Either state may be nil.
+func DiffPolicySnapshots(before, after *PolicySnapshot) map[string]FieldChange {
+	from, to := snapshotFields(before), snapshotFields(after)
+	diff := make(map[string]FieldChange)
+	for key := range from {
+		if !reflect.DeepEqual(from[key], to[key]) {
+			diff[key] = FieldChange{From: from[key], To: to[key]}
+		}
+	}
+	for key := range to {
+		if _, seen := from[key]; !seen && to[key] != nil {
+			diff[key] = FieldChange{To: to[key]}
+		}
+	}
+	return diff
+}
+
+// snapshotFields decodes a snapshot into its JSON fields, so conditions
+// compare by value rather than by formatting
+func snapshotFields(s *PolicySnapshot) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if s == nil {
+		return fields
+	}
+	data, _ := json.Marshal(s)
+	_ = json.Unmarshal(data, &fields)
+	return fields
+}
+
+// PolicyChange is one change to a tool policy, to be recorded in its history
+type PolicyChange struct {
+	Type         string
+	Before       *db.ToolPolicy // nil for creates, and rollbacks that restore a deleted policy
+	After        *db.ToolPolicy // nil for deletes
+	ChangedBy    uuid.UUID
+	SessionID    pgtype.UUID // Session the change was made in, if known
+	Note         *string     // Why the change was made
+	RolledBackTo *int32
+}
+
+// PolicyRollback restores a tool policy to an earlier revision
+type PolicyRollback struct {
+	OrgID     uuid.UUID
+	PolicyID  uuid.UUID
+	Revision  int32
+	ChangedBy uuid.UUID
+	SessionID pgtype.UUID
+	Note      *string
+}
+
+// PolicyRevisionService keeps the append-only history of tool policy changes
+type PolicyRevisionService struct {
+	db    db.Querier
+	runTx TxRunner
+}
+
+// NewPolicyRevisionService creates a new policy revision service. Without
+// runTx (as in tests, or when already in a transaction) a rollback's changes
+// are made one by one.
+func NewPolicyRevisionService(database db.Querier, runTx TxRunner) *PolicyRevisionService {
+	if runTx == nil {
+		runTx = DirectTxRunner(database)
+	}
+	return &PolicyRevisionService{db: database, runTx: runTx}
+}
+
+// Record appends a change to its policy's history, with a snapshot of the
+// policy after it (before it, for deletes) and the fields it changed.
+// Updates that change nothing aren't recorded, and return ok false.
+func (s *PolicyRevisionService) Record(ctx context.Context, change PolicyChange) (revision db.ToolPolicyRevision, ok bool, err error) {
+	policy := change.After
+	if policy == nil {
+		policy = change.Before
+	}
+	if policy == nil {
+		return db.ToolPolicyRevision{}, false, fmt.Errorf("policy change has no policy")
+	}
+
+	diff := diffPolicies(change.Before, change.After)
+	if change.Type == PolicyChangeUpdate && len(diff) == 0 {
+		return db.ToolPolicyRevision{}, false, nil
+	}
+
+	snapshotJSON, err := json.Marshal(NewPolicySnapshot(*policy))
+	if err != nil {
+		return db.ToolPolicyRevision{}, false, fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	diffJSON, err := json.Marshal(diff)
+	if err != nil {
+		return db.ToolPolicyRevision{}, false, fmt.Errorf("failed to encode diff: %w", err)
+	}
+
+	revision, err = s.db.CreateToolPolicyRevision(ctx, db.CreateToolPolicyRevisionParams{
+		PolicyID:     policy.ID,
+		OrgID:        policy.OrgID,
+		ChangeType:   change.Type,
+		Snapshot:     snapshotJSON,
+		Diff:         diffJSON,
+		Note:         change.Note,
+		RolledBackTo: change.RolledBackTo,
+		ChangedBy:    pgtype.UUID{Bytes: change.ChangedBy, Valid: change.ChangedBy != uuid.Nil},
+		SessionID:    change.SessionID,
+	})
+	if err != nil {
+		return db.ToolPolicyRevision{}, false, fmt.Errorf("failed to record policy revision: %w", err)
+	}
+	return revision, true, nil
+}
+
+// Rollback restores a policy to the state recorded in one of its revisions,
+// recreating it under its old ID if it has been deleted since, and records
+// the rollback as a new revision in the same transaction
+func (s *PolicyRevisionService) Rollback(ctx context.Context, rb PolicyRollback) (db.ToolPolicy, error) {
+	var restored db.ToolPolicy
+	err := s.runTx(ctx, func(q db.Querier) error {
+		var err error
+		restored, err = rollbackPolicy(ctx, q, rb)
+		return err
+	})
+	if err != nil {
+		return db.ToolPolicy{}, err
+	}
+	return restored, nil
+}
+
+// rollbackPolicy makes a rollback against q
+func rollbackPolicy(ctx context.Context, q db.Querier, rb PolicyRollback) (db.ToolPolicy, error) {
+	target, err := q.GetToolPolicyRevision(ctx, db.GetToolPolicyRevisionParams{
+		PolicyID: rb.PolicyID,
+		OrgID:    rb.OrgID,
+		Revision: rb.Revision,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.ToolPolicy{}, ErrRevisionNotFound
+	}
+	if err != nil {
+		return db.ToolPolicy{}, fmt.Errorf("failed to get revision: %w", err)
+	}
+	if target.ChangeType == PolicyChangeDelete {
+		return db.ToolPolicy{}, ErrRevisionDeleted
+	}
+
+	var snapshot PolicySnapshot
+	if err := json.Unmarshal(target.Snapshot, &snapshot); err != nil {
+		return db.ToolPolicy{}, fmt.Errorf("failed to decode revision: %w", err)
+	}
+	var conditions []byte
+	if len(snapshot.Conditions) > 0 && string(snapshot.Conditions) != "null" {
+		conditions = snapshot.Conditions
+	}
+
+	var before *db.ToolPolicy
+	var restored db.ToolPolicy
+	// Lock the policy so a concurrent change can't take the next revision number
+	current, err := q.LockToolPolicyByIdAndOrg(ctx, db.LockToolPolicyByIdAndOrgParams{ID: rb.PolicyID, OrgID: rb.OrgID})
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		params := db.RestoreToolPolicyParams{
+			ID:         rb.PolicyID,
+			OrgID:      rb.OrgID,
+			PolicyType: snapshot.PolicyType,
+			ToolName:   snapshot.ToolName,
+			Conditions: conditions,
+			Action:     snapshot.Action,
+			Reason:     snapshot.Reason,
+			CreatedBy:  pgtype.UUID{Bytes: rb.ChangedBy, Valid: rb.ChangedBy != uuid.Nil},
+		}
+		if snapshot.TeamID != nil {
+			params.TeamID = pgtype.UUID{Bytes: *snapshot.TeamID, Valid: true}
+		}
+		if snapshot.EmployeeID != nil {
+			params.EmployeeID = pgtype.UUID{Bytes: *snapshot.EmployeeID, Valid: true}
+		}
+		restored, err = q.RestoreToolPolicy(ctx, params)
+		if err != nil {
+			return db.ToolPolicy{}, fmt.Errorf("failed to restore policy: %w", err)
+		}
+	case err != nil:
+		return db.ToolPolicy{}, fmt.Errorf("failed to get policy: %w", err)
+	default:
+		// Scope and type can't change, so only the rest is restored
+		before = &current
+		restored, err = q.ReplaceToolPolicyByOrg(ctx, db.ReplaceToolPolicyByOrgParams{
+			ID:         rb.PolicyID,
+			OrgID:      rb.OrgID,
+			ToolName:   snapshot.ToolName,
+			Conditions: conditions,
+			Action:     snapshot.Action,
+			Reason:     snapshot.Reason,
+		})
+		if err != nil {
+			return db.ToolPolicy{}, fmt.Errorf("failed to restore policy: %w", err)
+		}
+	}
+
+	revision := rb.Revision
+	if _, _, err := NewPolicyRevisionService(q, nil).Record(ctx, PolicyChange{
+		Type:         PolicyChangeRollback,
+		Before:       before,
+		After:        &restored,
+		ChangedBy:    rb.ChangedBy,
+		SessionID:    rb.SessionID,
+		Note:         rb.Note,
+		RolledBackTo: &revision,
+	}); err != nil {
+		return db.ToolPolicy{}, err
+	}
+	return restored, nil
+}
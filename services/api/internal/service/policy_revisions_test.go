@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/rastrigin-systems/arfa/generated/db"
+	"github.com/rastrigin-systems/arfa/generated/mocks"
+)
+
+func TestDiffPolicySnapshots(t *testing.T) {
+	reason := "Shell blocked"
+	before := NewPolicySnapshot(db.ToolPolicy{
+		PolicyType: "tool",
+		ToolName:   "Bash",
+		Action:     "deny",
+		Reason:     &reason,
+		Conditions: []byte(`{"command": "rm\\s+-rf", "timeout": 5}`),
+	})
+
+	// Reformatted conditions aren't a change
+	same := before
+	same.Conditions = json.RawMessage(`{"timeout":5,"command":"rm\\s+-rf"}`)
+	assert.Empty(t, DiffPolicySnapshots(&before, &same))
+
+	after := before
+	after.Action = "audit"
+	after.Reason = nil
+	diff := DiffPolicySnapshots(&before, &after)
+	assert.Equal(t, map[string]FieldChange{
+		"action": {From: "deny", To: "audit"},
+		"reason": {From: reason, To: nil},
+	}, diff)
+
+	created := DiffPolicySnapshots(nil, &after)
+	assert.Equal(t, FieldChange{To: "Bash"}, created["tool_name"])
+	assert.NotContains(t, created, "reason", "unset fields aren't part of a create")
+
+	deleted := DiffPolicySnapshots(&before, nil)
+	assert.Equal(t, FieldChange{From: "deny"}, deleted["action"])
+	assert.NotContains(t, deleted, "team_id")
+}
+
+func TestPolicyRevisionService_Record(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mocks.NewMockQuerier(ctrl)
+	svc := NewPolicyRevisionService(mockDB, nil)
+
+	employeeID := uuid.New()
+	session := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	note := "Too noisy"
+	before := db.ToolPolicy{ID: uuid.New(), OrgID: uuid.New(), PolicyType: "tool", ToolName: "Bash", Action: "deny"}
+	after := before
+	after.Action = "audit"
+
+	mockDB.EXPECT().
+		CreateToolPolicyRevision(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, params db.CreateToolPolicyRevisionParams) (db.ToolPolicyRevision, error) {
+			assert.Equal(t, before.ID, params.PolicyID)
+			assert.Equal(t, before.OrgID, params.OrgID)
+			assert.Equal(t, PolicyChangeUpdate, params.ChangeType)
+			assert.JSONEq(t, `{"action": {"from": "deny", "to": "audit"}}`, string(params.Diff))
+			assert.Contains(t, string(params.Snapshot), `"action":"audit"`)
+			assert.Equal(t, &note, params.Note)
+			assert.Equal(t, pgtype.UUID{Bytes: employeeID, Valid: true}, params.ChangedBy)
+			assert.Equal(t, session, params.SessionID)
+			return db.ToolPolicyRevision{PolicyID: params.PolicyID, Revision: 2}, nil
+		})
+
+	revision, ok, err := svc.Record(context.Background(), PolicyChange{
+		Type:      PolicyChangeUpdate,
+		Before:    &before,
+		After:     &after,
+		ChangedBy: employeeID,
+		SessionID: session,
+		Note:      &note,
+	})
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int32(2), revision.Revision)
+
+	// Updates that change nothing aren't recorded
+	_, ok, err = svc.Record(context.Background(), PolicyChange{Type: PolicyChangeUpdate, Before: &after, After: &after})
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestPolicyRevisionService_Rollback(t *testing.T) {
+	orgID := uuid.New()
+	policyID := uuid.New()
+	employeeID := uuid.New()
+	teamID := uuid.New()
+	reason := "Shell blocked"
+
+	snapshot, err := json.Marshal(PolicySnapshot{
+		PolicyType: "tool",
+		ToolName:   "Bash",
+		Action:     "deny",
+		Reason:     &reason,
+		TeamID:     &teamID,
+	})
+	require.NoError(t, err)
+	target := db.ToolPolicyRevision{PolicyID: policyID, OrgID: orgID, Revision: 1, ChangeType: PolicyChangeCreate, Snapshot: snapshot}
+	rollback := PolicyRollback{OrgID: orgID, PolicyID: policyID, Revision: 1, ChangedBy: employeeID}
+	getRevision := db.GetToolPolicyRevisionParams{PolicyID: policyID, OrgID: orgID, Revision: 1}
+	lockPolicy := db.LockToolPolicyByIdAndOrgParams{ID: policyID, OrgID: orgID}
+
+	t.Run("live policy", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockDB := mocks.NewMockQuerier(ctrl)
+
+		current := db.ToolPolicy{ID: policyID, OrgID: orgID, TeamID: pgtype.UUID{Bytes: teamID, Valid: true},
+			PolicyType: "tool", ToolName: "Bash", Action: "audit", Conditions: []byte(`{"command": "rm"}`)}
+		restored := current
+		restored.Action, restored.Reason, restored.Conditions = "deny", &reason, nil
+
+		mockDB.EXPECT().GetToolPolicyRevision(gomock.Any(), getRevision).Return(target, nil)
+		mockDB.EXPECT().LockToolPolicyByIdAndOrg(gomock.Any(), lockPolicy).Return(current, nil)
+		mockDB.EXPECT().
+			ReplaceToolPolicyByOrg(gomock.Any(), db.ReplaceToolPolicyByOrgParams{
+				ID: policyID, OrgID: orgID, ToolName: "Bash", Action: "deny", Reason: &reason,
+			}).
+			Return(restored, nil)
+		mockDB.EXPECT().
+			CreateToolPolicyRevision(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, params db.CreateToolPolicyRevisionParams) (db.ToolPolicyRevision, error) {
+				assert.Equal(t, PolicyChangeRollback, params.ChangeType)
+				require.NotNil(t, params.RolledBackTo)
+				assert.Equal(t, int32(1), *params.RolledBackTo)
+				assert.JSONEq(t, `{
+					"action": {"from": "audit", "to": "deny"},
+					"reason": {"from": null, "to": "Shell blocked"},
+					"conditions": {"from": {"command": "rm"}, "to": null}
+				}`, string(params.Diff))
+				return db.ToolPolicyRevision{Revision: 3}, nil
+			})
+
+		policy, err := NewPolicyRevisionService(mockDB, nil).Rollback(context.Background(), rollback)
+		require.NoError(t, err)
+		assert.Equal(t, "deny", policy.Action)
+	})
+
+	t.Run("deleted policy", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockDB := mocks.NewMockQuerier(ctrl)
+
+		mockDB.EXPECT().GetToolPolicyRevision(gomock.Any(), getRevision).Return(target, nil)
+		mockDB.EXPECT().LockToolPolicyByIdAndOrg(gomock.Any(), lockPolicy).Return(db.ToolPolicy{}, pgx.ErrNoRows)
+		mockDB.EXPECT().
+			RestoreToolPolicy(gomock.Any(), db.RestoreToolPolicyParams{
+				ID:         policyID,
+				OrgID:      orgID,
+				TeamID:     pgtype.UUID{Bytes: teamID, Valid: true},
+				PolicyType: "tool",
+				ToolName:   "Bash",
+				Action:     "deny",
+				Reason:     &reason,
+				CreatedBy:  pgtype.UUID{Bytes: employeeID, Valid: true},
+			}).
+			Return(db.ToolPolicy{ID: policyID, OrgID: orgID, PolicyType: "tool", ToolName: "Bash", Action: "deny"}, nil)
+		mockDB.EXPECT().
+			CreateToolPolicyRevision(gomock.Any(), gomock.Any()).
+			Return(db.ToolPolicyRevision{Revision: 4}, nil)
+
+		policy, err := NewPolicyRevisionService(mockDB, nil).Rollback(context.Background(), rollback)
+		require.NoError(t, err)
+		assert.Equal(t, policyID, policy.ID, "the policy keeps its ID")
+	})
+
+	t.Run("failure rolls back", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockDB := mocks.NewMockQuerier(ctrl)
+		txDB := mocks.NewMockQuerier(ctrl)
+
+		txDB.EXPECT().GetToolPolicyRevision(gomock.Any(), getRevision).Return(target, nil)
+		txDB.EXPECT().LockToolPolicyByIdAndOrg(gomock.Any(), lockPolicy).Return(db.ToolPolicy{}, pgx.ErrNoRows)
+		txDB.EXPECT().
+			RestoreToolPolicy(gomock.Any(), gomock.Any()).
+			Return(db.ToolPolicy{ID: policyID, OrgID: orgID, PolicyType: "tool", ToolName: "Bash", Action: "deny"}, nil)
+		txDB.EXPECT().
+			CreateToolPolicyRevision(gomock.Any(), gomock.Any()).
+			Return(db.ToolPolicyRevision{}, errors.New("connection reset"))
+
+		var txErr error
+		runTx := func(ctx context.Context, fn func(q db.Querier) error) error {
+			txErr = fn(txDB)
+			return txErr
+		}
+		_, err := NewPolicyRevisionService(mockDB, runTx).Rollback(context.Background(), rollback)
+		assert.ErrorContains(t, err, "failed to record policy revision")
+		assert.Equal(t, txErr, err, "the transaction sees the error, so the restore is rolled back")
+	})
+
+	t.Run("delete revision", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockDB := mocks.NewMockQuerier(ctrl)
+
+		deleted := target
+		deleted.ChangeType = PolicyChangeDelete
+		mockDB.EXPECT().GetToolPolicyRevision(gomock.Any(), getRevision).Return(deleted, nil)
+
+		_, err := NewPolicyRevisionService(mockDB, nil).Rollback(context.Background(), rollback)
+		assert.ErrorIs(t, err, ErrRevisionDeleted)
+	})
+
+	t.Run("unknown revision", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockDB := mocks.NewMockQuerier(ctrl)
+
+		mockDB.EXPECT().GetToolPolicyRevision(gomock.Any(), getRevision).Return(db.ToolPolicyRevision{}, pgx.ErrNoRows)
+
+		_, err := NewPolicyRevisionService(mockDB, nil).Rollback(context.Background(), rollback)
+		assert.ErrorIs(t, err, ErrRevisionNotFound)
+	})
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/rastrigin-systems/arfa/generated/db"
+	"github.com/rastrigin-systems/arfa/pkg/policy"
+)
+
+// A tool policy can be limited to certain times by a "schedule" in its
+// conditions (see policy.Schedule), with windows read in the organization's
+// timezone. The proxy only ever holds the policies that are active: the API
+// sends those, and the PolicyScheduler pushes policies to it as their
+// schedules start and stop.
+
+// ToolPolicySchedule returns a saved policy's schedule, or nil if it has none.
+// Schedules are validated when saved; one that doesn't parse is ignored, so
+// the policy always applies rather than never.
+func ToolPolicySchedule(p db.ToolPolicy) *policy.Schedule {
+	if !bytes.Contains(p.Conditions, []byte(`"schedule"`)) {
+		return nil
+	}
+	var conditions map[string]interface{}
+	if err := json.Unmarshal(p.Conditions, &conditions); err != nil {
+		return nil
+	}
+	schedule, err := policy.ParseSchedule(conditions)
+	if err != nil {
+		return nil
+	}
+	return schedule
+}
+
+// HasScheduledPolicies reports whether any of the policies has a schedule
+func HasScheduledPolicies(policies []db.ToolPolicy) bool {
+	for _, p := range policies {
+		if ToolPolicySchedule(p) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ActiveToolPolicies returns the policies that apply at t, in order: those
+// without a schedule and those whose schedule is active
+func ActiveToolPolicies(policies []db.ToolPolicy, t time.Time, loc *time.Location) []db.ToolPolicy {
+	active := make([]db.ToolPolicy, 0, len(policies))
+	for _, p := range policies {
+		if schedule := ToolPolicySchedule(p); schedule == nil || schedule.ActiveAt(t, loc) {
+			active = append(active, p)
+		}
+	}
+	return active
+}
+
+// OrgTimezone reads the "timezone" of organization settings JSON, an IANA
+// name such as "Europe/Berlin". Schedule windows are read in it; it defaults
+// to UTC when unset or unknown.
+func OrgTimezone(settings []byte) *time.Location {
+	var parsed struct {
+		Timezone string `json:"timezone"`
+	}
+	if len(settings) == 0 || json.Unmarshal(settings, &parsed) != nil || parsed.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(parsed.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
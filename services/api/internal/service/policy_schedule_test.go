@@ -0,0 +1,31 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rastrigin-systems/arfa/generated/db"
+)
+
+func TestActiveToolPolicies(t *testing.T) {
+	always := db.ToolPolicy{ID: uuid.New(), ToolName: "Bash", Conditions: []byte(`{"command": "deploy"}`)}
+	weekends := db.ToolPolicy{ID: uuid.New(), ToolName: "Bash", Conditions: []byte(`{"schedule": {"windows": [{"start": "0 0 * * 6", "end": "0 0 * * 1"}]}}`)}
+	policies := []db.ToolPolicy{always, weekends}
+
+	assert.True(t, HasScheduledPolicies(policies))
+	assert.False(t, HasScheduledPolicies([]db.ToolPolicy{always}))
+
+	saturday := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, policies, ActiveToolPolicies(policies, saturday, time.UTC))
+	assert.Equal(t, []db.ToolPolicy{always}, ActiveToolPolicies(policies, saturday.AddDate(0, 0, 3), time.UTC))
+}
+
+func TestOrgTimezone(t *testing.T) {
+	assert.Equal(t, "Europe/Berlin", OrgTimezone([]byte(`{"timezone": "Europe/Berlin"}`)).String())
+	assert.Equal(t, time.UTC, OrgTimezone([]byte(`{"timezone": "Mars/Olympus"}`)))
+	assert.Equal(t, time.UTC, OrgTimezone([]byte(`{}`)))
+	assert.Equal(t, time.UTC, OrgTimezone(nil))
+}
@@ -0,0 +1,435 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/rastrigin-systems/arfa/generated/db"
+	"github.com/rastrigin-systems/arfa/pkg/policy"
+)
+
+// A policy simulation replays the tool_call events stored in activity_logs
+// against a draft policy set, oldest first, and resolves each call the way
+// the proxy's PolicyHandler would have (services/cli/internal/control):
+//
+//  1. deny policies, highest ranked first, unless an allow policy outranks them
+//  2. rate_limit policies, counting the calls each lets through per session or employee
+//  3. require_approval policies
+//
+// Policies apply to a call as they would have to its employee: organization
+// policies, their team's and their own. Teams are the employees' current
+// ones. Model and path policies are skipped: model policies don't apply to
+// tool calls, and path policies need the employee's file system to resolve
+// paths against. Scheduled policies apply to the calls made while their
+// schedule was active, read in the organization's timezone.
+
+// Simulated outcomes of a tool call
+const (
+	SimulatedAllowed         = "allowed"
+	SimulatedDenied          = "denied"
+	SimulatedRequireApproval = "require_approval"
+	SimulatedRateLimited     = "rate_limited"
+)
+
+// MaxSimulatedCalls bounds how many tool calls one simulation replays
+const MaxSimulatedCalls = 50000
+
+// PolicySimulation is a policy set to replay stored tool calls against
+type PolicySimulation struct {
+	OrgID    uuid.UUID
+	Policies []db.ToolPolicy // Every policy in effect, saved or draft
+	Start    time.Time
+	End      time.Time
+}
+
+// SimulationCounts counts replayed tool calls by outcome
+type SimulationCounts struct {
+	Total           int
+	Allowed         int
+	Denied          int
+	RequireApproval int
+	RateLimited     int
+	NewlyDenied     int // Let through when logged, denied or rate limited now
+	NewlyAllowed    int // Blocked when logged, let through now
+}
+
+// SimulationGroup counts the replayed tool calls of one employee and tool
+type SimulationGroup struct {
+	EmployeeID    pgtype.UUID
+	EmployeeEmail *string
+	ToolName      string
+	SimulationCounts
+}
+
+// SimulationReport is the result of a policy simulation
+type SimulationReport struct {
+	SimulationCounts
+	Groups          []SimulationGroup // By employee, then tool
+	SkippedPolicies int               // Model and path policies, which aren't replayed
+	Truncated       bool              // Only the oldest MaxSimulatedCalls calls were replayed
+}
+
+// PolicySimulationService replays stored tool calls against draft policies
+type PolicySimulationService struct {
+	db db.Querier
+}
+
+// NewPolicySimulationService creates a new policy simulation service
+func NewPolicySimulationService(db db.Querier) *PolicySimulationService {
+	return &PolicySimulationService{db: db}
+}
+
+// Simulate replays the organization's tool calls between Start and End
+// against the simulation's policies
+func (s *PolicySimulationService) Simulate(ctx context.Context, sim PolicySimulation) (SimulationReport, error) {
+	if !sim.End.After(sim.Start) {
+		return SimulationReport{}, fmt.Errorf("end must be after start")
+	}
+
+	events, err := s.db.ListToolCallEvents(ctx, db.ListToolCallEventsParams{
+		OrgID:      sim.OrgID,
+		StartDate:  pgtype.Timestamp{Time: sim.Start, Valid: true},
+		EndDate:    pgtype.Timestamp{Time: sim.End, Valid: true},
+		QueryLimit: MaxSimulatedCalls + 1,
+	})
+	if err != nil {
+		return SimulationReport{}, fmt.Errorf("failed to fetch tool calls: %w", err)
+	}
+
+	var report SimulationReport
+	if len(events) > MaxSimulatedCalls {
+		events = events[:MaxSimulatedCalls]
+		report.Truncated = true
+	}
+
+	replay := newPolicyReplay(sim.Policies)
+	report.SkippedPolicies = replay.skipped
+
+	// Calls are replayed at their time in the organization's timezone, which
+	// schedules are read in
+	timezone := time.UTC
+	if HasScheduledPolicies(sim.Policies) {
+		org, err := s.db.GetOrganization(ctx, sim.OrgID)
+		if err != nil {
+			return SimulationReport{}, fmt.Errorf("failed to fetch organization: %w", err)
+		}
+		timezone = OrgTimezone(org.Settings)
+	}
+
+	groups := make(map[string]*SimulationGroup)
+	for _, event := range events {
+		var payload struct {
+			ToolName  string                 `json:"tool_name"`
+			ToolInput map[string]interface{} `json:"tool_input"`
+			Blocked   bool                   `json:"blocked"`
+			SessionID string                 `json:"session_id"`
+		}
+		if err := json.Unmarshal(event.Payload, &payload); err != nil || payload.ToolName == "" {
+			continue
+		}
+
+		session := payload.SessionID
+		if session == "" && event.ProxySessionID.Valid {
+			session = uuid.UUID(event.ProxySessionID.Bytes).String()
+		}
+		outcome := replay.decide(replayCall{
+			employeeID: event.EmployeeID,
+			teamID:     event.TeamID,
+			session:    session,
+			toolName:   payload.ToolName,
+			input:      replayInput(payload.ToolInput),
+			at:         event.CreatedAt.Time.In(timezone),
+		})
+
+		key := uuid.UUID(event.EmployeeID.Bytes).String() + "/" + payload.ToolName
+		group, ok := groups[key]
+		if !ok {
+			group = &SimulationGroup{EmployeeID: event.EmployeeID, EmployeeEmail: event.EmployeeEmail, ToolName: payload.ToolName}
+			groups[key] = group
+		}
+		group.add(outcome, payload.Blocked)
+		report.add(outcome, payload.Blocked)
+	}
+
+	report.Groups = make([]SimulationGroup, 0, len(groups))
+	for _, group := range groups {
+		report.Groups = append(report.Groups, *group)
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		a, b := report.Groups[i], report.Groups[j]
+		if ea, eb := groupEmployee(a), groupEmployee(b); ea != eb {
+			return ea < eb
+		}
+		return a.ToolName < b.ToolName
+	})
+	return report, nil
+}
+
+// add counts one replayed call, and whether the outcome changed from the
+// logged one
+func (c *SimulationCounts) add(outcome string, wasBlocked bool) {
+	c.Total++
+	switch outcome {
+	case SimulatedAllowed:
+		c.Allowed++
+	case SimulatedDenied:
+		c.Denied++
+	case SimulatedRateLimited:
+		c.RateLimited++
+	case SimulatedRequireApproval:
+		c.RequireApproval++
+	}
+
+	stopped := outcome == SimulatedDenied || outcome == SimulatedRateLimited
+	if stopped && !wasBlocked {
+		c.NewlyDenied++
+	}
+	if outcome == SimulatedAllowed && wasBlocked {
+		c.NewlyAllowed++
+	}
+}
+
+// groupEmployee sorts groups by email, then employee ID
+func groupEmployee(g SimulationGroup) string {
+	if g.EmployeeEmail != nil {
+		return *g.EmployeeEmail
+	}
+	return "~" + uuid.UUID(g.EmployeeID.Bytes).String()
+}
+
+// replayInput returns the tool input the proxy would have parsed. Input that
+// wasn't JSON is logged as {"_raw": "..."}, and matches no conditions.
+func replayInput(input map[string]interface{}) map[string]interface{} {
+	if _, raw := input["_raw"]; raw && len(input) == 1 {
+		return nil
+	}
+	return input
+}
+
+// replayCall is one stored tool call
+type replayCall struct {
+	employeeID pgtype.UUID
+	teamID     pgtype.UUID
+	session    string
+	toolName   string
+	input      map[string]interface{}
+	at         time.Time
+}
+
+// replayRule is a tool policy compiled for replay
+type replayRule struct {
+	key      string // Identifies the policy's rate limit windows
+	policy   db.ToolPolicy
+	cond     policy.Condition // nil without conditions to match
+	schedule *policy.Schedule // nil when the policy always applies
+	rank     policy.Rank
+	limit    replayLimit
+}
+
+// replayLimit is the limit of a rate_limit policy
+type replayLimit struct {
+	maxCalls    int
+	window      time.Duration
+	perEmployee bool
+}
+
+// policyReplay resolves tool calls against a policy set, keeping rate limit
+// windows across calls
+type policyReplay struct {
+	deny, allow, approval, limits []replayRule // Highest ranked first
+	skipped                       int
+	windows                       map[string][]time.Time
+}
+
+// newPolicyReplay compiles a policy set. Like the proxy, it ignores audit
+// policies, which don't change outcomes, and rate_limit policies without a
+// valid limit.
+func newPolicyReplay(policies []db.ToolPolicy) *policyReplay {
+	r := &policyReplay{windows: make(map[string][]time.Time)}
+	for i, p := range policies {
+		if p.PolicyType != "" && p.PolicyType != "tool" {
+			r.skipped++
+			continue
+		}
+
+		rule := replayRule{
+			key:      fmt.Sprintf("%d", i),
+			policy:   p,
+			cond:     compileStoredConditions(p.Conditions),
+			schedule: ToolPolicySchedule(p),
+			rank:     ToolPolicyRank(p),
+		}
+		switch p.Action {
+		case "deny":
+			r.deny = append(r.deny, rule)
+		case "allow":
+			r.allow = append(r.allow, rule)
+		case "require_approval":
+			r.approval = append(r.approval, rule)
+		case "rate_limit":
+			limit, ok := parseReplayLimit(p.Conditions)
+			if !ok {
+				continue
+			}
+			rule.limit = limit
+			r.limits = append(r.limits, rule)
+		}
+	}
+	for _, rules := range [][]replayRule{r.deny, r.allow, r.approval, r.limits} {
+		sort.SliceStable(rules, func(i, j int) bool { return rules[i].outranks(rules[j]) })
+	}
+	return r
+}
+
+// compileStoredConditions compiles a policy's stored conditions; conditions
+// that don't compile never match
+func compileStoredConditions(raw []byte) policy.Condition {
+	var conditions map[string]interface{}
+	if len(raw) > 0 {
+		_ = json.Unmarshal(raw, &conditions)
+	}
+	return policy.CompileOrNever(conditions)
+}
+
+// parseReplayLimit reads the limit of a rate_limit policy from its conditions
+func parseReplayLimit(raw []byte) (replayLimit, bool) {
+	var conditions struct {
+		RateLimit *struct {
+			MaxCalls      int    `json:"max_calls"`
+			WindowSeconds int    `json:"window_seconds"`
+			Per           string `json:"per"`
+		} `json:"rate_limit"`
+	}
+	if err := json.Unmarshal(raw, &conditions); err != nil || conditions.RateLimit == nil {
+		return replayLimit{}, false
+	}
+	config := conditions.RateLimit
+	if config.MaxCalls < 1 || config.WindowSeconds < 1 || config.WindowSeconds > 86400 {
+		return replayLimit{}, false
+	}
+	switch config.Per {
+	case "", "session", "employee":
+	default:
+		return replayLimit{}, false
+	}
+	return replayLimit{
+		maxCalls:    config.MaxCalls,
+		window:      time.Duration(config.WindowSeconds) * time.Second,
+		perEmployee: config.Per == "employee",
+	}, true
+}
+
+// outranks orders rules as ToolPolicyOutranks orders their policies
+func (r replayRule) outranks(other replayRule) bool {
+	return r.rank.Outranks(other.rank)
+}
+
+// appliesTo reports whether the policy covers the call's employee, and was
+// active when it was made
+func (r replayRule) appliesTo(call replayCall) bool {
+	if r.schedule != nil && !r.schedule.ActiveAt(call.at, call.at.Location()) {
+		return false
+	}
+	switch {
+	case r.policy.EmployeeID.Valid:
+		return call.employeeID.Valid && call.employeeID.Bytes == r.policy.EmployeeID.Bytes
+	case r.policy.TeamID.Valid:
+		return call.teamID.Valid && call.teamID.Bytes == r.policy.TeamID.Bytes
+	}
+	return true
+}
+
+// namesTool reports whether the policy covers the call's employee and tool
+func (r replayRule) namesTool(call replayCall) bool {
+	return r.appliesTo(call) && policy.MatchesToolName(r.policy.ToolName, call.toolName)
+}
+
+// matches reports whether the policy matches the call. Conditional policies
+// never match input that isn't a JSON object.
+func (r replayRule) matches(call replayCall) bool {
+	if !r.namesTool(call) {
+		return false
+	}
+	return r.cond == nil || (call.input != nil && r.cond.Match(call.input))
+}
+
+// allowed reports whether an allow policy exempts the call from restriction
+func (r *policyReplay) allowed(restriction replayRule, call replayCall) bool {
+	for _, allow := range r.allow {
+		if allow.outranks(restriction) && allow.matches(call) {
+			return true
+		}
+	}
+	return false
+}
+
+// decide resolves one call, counting it against the rate limits it passes
+func (r *policyReplay) decide(call replayCall) string {
+	// The proxy blocks a tool an unconditional deny names before its input
+	// arrives, unless an allow policy names it too
+	early := false
+	for _, rule := range r.deny {
+		if rule.cond == nil && rule.namesTool(call) {
+			early = true
+			break
+		}
+	}
+	if early {
+		for _, allow := range r.allow {
+			if allow.namesTool(call) {
+				early = false
+				break
+			}
+		}
+	}
+	for _, rule := range r.deny {
+		if early && rule.cond != nil {
+			continue
+		}
+		if rule.matches(call) && !r.allowed(rule, call) {
+			return SimulatedDenied
+		}
+	}
+
+	var keys []string
+	for _, rule := range r.limits {
+		if !rule.matches(call) || r.allowed(rule, call) {
+			continue
+		}
+		key := "session:" + call.session + ":" + rule.key
+		if rule.limit.perEmployee {
+			key = "employee:" + uuid.UUID(call.employeeID.Bytes).String() + ":" + rule.key
+		}
+		calls := pruneReplayWindow(r.windows[key], call.at.Add(-rule.limit.window))
+		r.windows[key] = calls
+		if len(calls) >= rule.limit.maxCalls {
+			return SimulatedRateLimited
+		}
+		keys = append(keys, key)
+	}
+	for _, key := range keys {
+		r.windows[key] = append(r.windows[key], call.at)
+	}
+
+	for _, rule := range r.approval {
+		if rule.matches(call) && !r.allowed(rule, call) {
+			return SimulatedRequireApproval
+		}
+	}
+	return SimulatedAllowed
+}
+
+// pruneReplayWindow drops call times at or before since
+func pruneReplayWindow(calls []time.Time, since time.Time) []time.Time {
+	i := 0
+	for i < len(calls) && !calls[i].After(since) {
+		i++
+	}
+	return calls[i:]
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/rastrigin-systems/arfa/generated/db"
+	"github.com/rastrigin-systems/arfa/generated/mocks"
+)
+
+func TestPolicyReplay_Decide(t *testing.T) {
+	team := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	otherTeam := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	employee := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	at := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	replay := newPolicyReplay([]db.ToolPolicy{
+		{PolicyType: "tool", ToolName: "Bash", Action: "deny", Conditions: []byte(`{"command": {"shell": {"command": "rm", "flags": ["-r"]}}}`)},
+		{PolicyType: "tool", ToolName: "WebFetch", Action: "deny"},
+		{PolicyType: "tool", ToolName: "WebFetch", Action: "allow", TeamID: team},
+		{PolicyType: "tool", ToolName: "mcp__github__%", Action: "require_approval"},
+		{PolicyType: "tool", ToolName: "Bash", Action: "rate_limit", Conditions: []byte(`{"rate_limit": {"max_calls": 2, "window_seconds": 60}}`)},
+		{PolicyType: "path", ToolName: "**/.env", Action: "deny"},
+		{PolicyType: "model", ToolName: "claude-opus-%", Action: "deny"},
+	})
+	assert.Equal(t, 2, replay.skipped)
+
+	call := func(toolName, input string, teamID pgtype.UUID, offset time.Duration) replayCall {
+		var parsed map[string]interface{}
+		_ = json.Unmarshal([]byte(input), &parsed)
+		return replayCall{employeeID: employee, teamID: teamID, session: "s1", toolName: toolName, input: parsed, at: at.Add(offset)}
+	}
+
+	assert.Equal(t, SimulatedDenied, replay.decide(call("Bash", `{"command": "sudo rm -rf /"}`, team, 0)))
+	assert.Equal(t, SimulatedDenied, replay.decide(call("WebFetch", `{}`, otherTeam, 0)))
+	assert.Equal(t, SimulatedAllowed, replay.decide(call("WebFetch", `{}`, team, 0)), "the team allow outranks the org deny")
+	assert.Equal(t, SimulatedRequireApproval, replay.decide(call("mcp__github__create_issue", `{}`, team, 0)))
+
+	// Denied calls don't count against the limit; the third allowed call in a minute does
+	assert.Equal(t, SimulatedAllowed, replay.decide(call("Bash", `{"command": "ls"}`, team, time.Second)))
+	assert.Equal(t, SimulatedAllowed, replay.decide(call("bash", `{"command": "ls"}`, team, 2*time.Second)))
+	assert.Equal(t, SimulatedRateLimited, replay.decide(call("Bash", `{"command": "ls"}`, team, 3*time.Second)))
+	assert.Equal(t, SimulatedAllowed, replay.decide(call("Bash", `{"command": "ls"}`, team, 62*time.Second)), "the window moved on")
+}
+
+func TestPolicyReplay_EarlyDeny(t *testing.T) {
+	employee := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	replay := newPolicyReplay([]db.ToolPolicy{
+		{ToolName: "Bash", Action: "deny"},
+		{ToolName: "Bash", Action: "allow", EmployeeID: employee, Conditions: []byte(`{"command": "^git"}`)},
+		{ToolName: "Read", Action: "deny"},
+		{ToolName: "Read", Action: "allow", EmployeeID: pgtype.UUID{Bytes: uuid.New(), Valid: true}},
+	})
+
+	git := replayCall{employeeID: employee, toolName: "Bash", input: map[string]interface{}{"command": "git status"}}
+	assert.Equal(t, SimulatedAllowed, replay.decide(git))
+	git.input = nil
+	assert.Equal(t, SimulatedDenied, replay.decide(git), "input that wasn't JSON matches no allow conditions")
+
+	read := replayCall{employeeID: employee, toolName: "Read", input: map[string]interface{}{}}
+	assert.Equal(t, SimulatedDenied, replay.decide(read), "another employee's allow doesn't apply")
+}
+
+func TestPolicyReplay_ConditionalDenyToolName(t *testing.T) {
+	replay := newPolicyReplay([]db.ToolPolicy{
+		{ToolName: "mcp__github__%", Action: "deny", Conditions: []byte(`{"repo": "^acme/"}`)},
+		{ToolName: "MCP__Jira__Create", Action: "deny", Conditions: []byte(`{"project": "^OPS$"}`)},
+	})
+
+	github := replayCall{toolName: "mcp__github__create_issue", input: map[string]interface{}{"repo": "acme/api"}}
+	assert.Equal(t, SimulatedDenied, replay.decide(github), "a % in a conditional deny matches a prefix")
+	github.input = map[string]interface{}{"repo": "other/api"}
+	assert.Equal(t, SimulatedAllowed, replay.decide(github))
+	jira := replayCall{toolName: "mcp__jira__create", input: map[string]interface{}{"project": "OPS"}}
+	assert.Equal(t, SimulatedDenied, replay.decide(jira), "names match ignoring case")
+}
+
+func TestPolicyReplay_Schedules(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	replay := newPolicyReplay([]db.ToolPolicy{
+		{ToolName: "mcp__deploy__%", Action: "deny", Conditions: []byte(`{"schedule": {"windows": [{"start": "0 16 * * 5", "end": "0 8 * * 1"}]}}`)},
+	})
+
+	// Calls are replayed at their time in the organization's timezone
+	deploy := replayCall{toolName: "mcp__deploy__release", at: time.Date(2026, 10, 16, 17, 0, 0, 0, berlin)}
+	assert.Equal(t, SimulatedDenied, replay.decide(deploy), "Friday evening is frozen")
+	deploy.at = time.Date(2026, 10, 21, 11, 0, 0, 0, berlin)
+	assert.Equal(t, SimulatedAllowed, replay.decide(deploy), "Wednesday isn't")
+}
+
+func TestPolicySimulationService_Simulate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := mocks.NewMockQuerier(ctrl)
+	svc := NewPolicySimulationService(mockDB)
+
+	orgID := uuid.New()
+	alice := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	bob := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	aliceEmail, bobEmail := "alice@acme.com", "bob@acme.com"
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+
+	event := func(employee pgtype.UUID, email *string, payload string) db.ListToolCallEventsRow {
+		return db.ListToolCallEventsRow{
+			ID:            uuid.New(),
+			EmployeeID:    employee,
+			EmployeeEmail: email,
+			Payload:       json.RawMessage(payload),
+			CreatedAt:     pgtype.Timestamp{Time: start.Add(time.Hour), Valid: true},
+		}
+	}
+
+	mockDB.EXPECT().
+		ListToolCallEvents(gomock.Any(), db.ListToolCallEventsParams{
+			OrgID:      orgID,
+			StartDate:  pgtype.Timestamp{Time: start, Valid: true},
+			EndDate:    pgtype.Timestamp{Time: end, Valid: true},
+			QueryLimit: MaxSimulatedCalls + 1,
+		}).
+		Return([]db.ListToolCallEventsRow{
+			event(bob, &bobEmail, `{"tool_name": "Bash", "tool_input": {"command": "rm -rf build"}, "blocked": false}`),
+			event(alice, &aliceEmail, `{"tool_name": "Bash", "tool_input": {"command": "rm -rf /"}, "blocked": true}`),
+			event(alice, &aliceEmail, `{"tool_name": "Bash", "tool_input": {"command": "ls"}, "blocked": true}`),
+			event(alice, &aliceEmail, `{"tool_name": "Read", "tool_input": {"file_path": "a.go"}, "blocked": false}`),
+			event(alice, &aliceEmail, `{"tool_input": {}}`),
+		}, nil)
+
+	report, err := svc.Simulate(context.Background(), PolicySimulation{
+		OrgID:    orgID,
+		Policies: []db.ToolPolicy{{ToolName: "Bash", Action: "deny", Conditions: []byte(`{"command": "rm\\s+-rf"}`)}},
+		Start:    start,
+		End:      end,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, report.Total, "events without a tool name are skipped")
+	assert.Equal(t, 2, report.Denied)
+	assert.Equal(t, 2, report.Allowed)
+	assert.Equal(t, 1, report.NewlyDenied)
+	assert.Equal(t, 1, report.NewlyAllowed)
+	assert.False(t, report.Truncated)
+
+	require.Len(t, report.Groups, 3)
+	assert.Equal(t, aliceEmail, *report.Groups[0].EmployeeEmail)
+	assert.Equal(t, "Bash", report.Groups[0].ToolName)
+	assert.Equal(t, SimulationCounts{Total: 2, Allowed: 1, Denied: 1, NewlyAllowed: 1}, report.Groups[0].SimulationCounts)
+	assert.Equal(t, "Read", report.Groups[1].ToolName)
+	assert.Equal(t, bobEmail, *report.Groups[2].EmployeeEmail)
+	assert.Equal(t, SimulationCounts{Total: 1, Denied: 1, NewlyDenied: 1}, report.Groups[2].SimulationCounts)
+
+	_, err = svc.Simulate(context.Background(), PolicySimulation{OrgID: orgID, Start: end, End: start})
+	assert.Error(t, err)
+}
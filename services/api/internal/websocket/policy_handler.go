@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,6 +15,7 @@ import (
 
 	"github.com/rastrigin-systems/arfa/generated/db"
 	"github.com/rastrigin-systems/arfa/services/api/internal/auth"
+	"github.com/rastrigin-systems/arfa/services/api/internal/service"
 )
 
 // PolicyHandler handles WebSocket connections for policy streaming to proxies
@@ -138,14 +141,49 @@ func (h *PolicyHandler) sendInitialPolicies(ctx context.Context, conn *PolicyCon
 		return
 	}
 
-	// Convert to PolicyData format
+	// Load the organization's enforcement settings (defaults if unavailable),
+	// any extra hosts to intercept and the timezone schedules are read in
+	enforcement := EnforcementSettings{
+		FailMode:           DefaultFailMode,
+		GracePeriodSeconds: DefaultGracePeriodSeconds,
+	}
+	var interceptHosts []InterceptHost
+	timezone := time.UTC
+	if org, err := h.queries.GetOrganization(ctx, conn.OrgID); err == nil {
+		enforcement = parseEnforcementSettings(org.Settings)
+		interceptHosts = parseInterceptHosts(org.Settings)
+		timezone = service.OrgTimezone(org.Settings)
+	} else {
+		log.Printf("Failed to fetch organization settings for connection %s: %v", conn.ID, err)
+	}
+
+	// Scheduled policies are sent whether they're active or not, with the
+	// timezone: the proxy applies schedules itself, and the PolicyScheduler
+	// pushes them again as they start and stop applying
+
+	// Convert to PolicyData format, highest precedence first
+	service.SortToolPoliciesByPrecedence(dbPolicies)
 	policies := make([]PolicyData, len(dbPolicies))
 	for i, p := range dbPolicies {
 		policies[i] = dbPolicyToPolicyData(p)
 	}
 
+	// Include the budgets covering the employee with their current usage
+	var budgets []BudgetData
+	teamID := pgtype.UUID{}
+	if conn.TeamID != nil {
+		teamID = pgtype.UUID{Bytes: *conn.TeamID, Valid: true}
+	}
+	statuses, err := service.NewBudgetService(h.queries).StatusForEmployee(ctx, conn.OrgID, conn.EmployeeID, teamID)
+	if err != nil {
+		log.Printf("Failed to fetch budgets for connection %s: %v", conn.ID, err)
+	}
+	for _, status := range statuses {
+		budgets = append(budgets, BudgetStatusToData(status))
+	}
+
 	// Send init message
-	if err := h.hub.SendInitMessage(conn, policies); err != nil {
+	if err := h.hub.SendInitMessage(conn, policies, timezone.String(), enforcement, interceptHosts, budgets); err != nil {
 		log.Printf("Failed to send init message to connection %s: %v", conn.ID, err)
 	}
 }
@@ -180,15 +218,24 @@ func (h *PolicyHandler) readPump(conn *PolicyConn) {
 			break
 		}
 
-		// Handle pong messages from proxy
-		var msg map[string]interface{}
+		var msg ProxyMessage
 		if err := json.Unmarshal(message, &msg); err != nil {
 			continue
 		}
 
-		// Handle pong messages - reset read deadline
-		if msgType, ok := msg["type"].(string); ok && msgType == "pong" {
+		switch msg.Type {
+		case ProxyMessageTypePong:
+			// Reset read deadline
 			_ = wsConn.SetReadDeadline(time.Now().Add(pongWait))
+
+		case ProxyMessageTypeApprovalRequest:
+			// A tool call is paused waiting for approval - make it visible to admins
+			if msg.Approval != nil && msg.Approval.ID != "" {
+				h.hub.AddApproval(conn, *msg.Approval)
+			}
+
+		case ProxyMessageTypeApprovalResolved:
+			h.hub.ResolveApproval(conn, msg.ApprovalID)
 		}
 	}
 }
@@ -234,13 +281,74 @@ func (h *PolicyHandler) writePump(conn *PolicyConn) {
 	}
 }
 
+// parseEnforcementSettings reads the "policy_enforcement" object from organization
+// settings JSON, falling back to defaults for missing or invalid values.
+func parseEnforcementSettings(settings []byte) EnforcementSettings {
+	result := EnforcementSettings{
+		FailMode:           DefaultFailMode,
+		GracePeriodSeconds: DefaultGracePeriodSeconds,
+	}
+
+	var parsed struct {
+		PolicyEnforcement *struct {
+			FailMode           string `json:"fail_mode"`
+			GracePeriodSeconds *int   `json:"grace_period_seconds"`
+		} `json:"policy_enforcement"`
+	}
+	if len(settings) == 0 || json.Unmarshal(settings, &parsed) != nil || parsed.PolicyEnforcement == nil {
+		return result
+	}
+
+	switch parsed.PolicyEnforcement.FailMode {
+	case FailModeClosed, FailModeOpen:
+		result.FailMode = parsed.PolicyEnforcement.FailMode
+	}
+	if p := parsed.PolicyEnforcement.GracePeriodSeconds; p != nil && *p >= 0 {
+		result.GracePeriodSeconds = *p
+	}
+
+	return result
+}
+
+// hostLabel matches one label of a hostname
+const hostLabel = `[a-z0-9]([a-z0-9-]*[a-z0-9])?`
+
+// interceptHostRegex matches a hostname, or a "*." wildcard over a domain of at
+// least two labels, so an entry like "*.com" can't take in a whole top-level domain
+var interceptHostRegex = regexp.MustCompile(`^(\*\.` + hostLabel + `\.` + hostLabel + `|` + hostLabel + `)(\.` + hostLabel + `)*$`)
+
+// parseInterceptHosts reads the "intercept_hosts" list from organization settings
+// JSON. Entries with an invalid host or an unknown protocol are skipped.
+func parseInterceptHosts(settings []byte) []InterceptHost {
+	var parsed struct {
+		InterceptHosts []InterceptHost `json:"intercept_hosts"`
+	}
+	if len(settings) == 0 || json.Unmarshal(settings, &parsed) != nil {
+		return nil
+	}
+
+	var hosts []InterceptHost
+	for _, h := range parsed.InterceptHosts {
+		host := strings.ToLower(strings.TrimSpace(h.Host))
+		if !interceptHostRegex.MatchString(host) {
+			continue
+		}
+		switch h.Protocol {
+		case ProtocolAnthropic, ProtocolOpenAI:
+			hosts = append(hosts, InterceptHost{Host: host, Protocol: h.Protocol})
+		}
+	}
+	return hosts
+}
+
 // dbPolicyToPolicyData converts a database policy to WebSocket PolicyData
 func dbPolicyToPolicyData(p db.ToolPolicy) PolicyData {
 	pd := PolicyData{
-		ID:       p.ID,
-		OrgID:    p.OrgID,
-		ToolName: p.ToolName,
-		Action:   p.Action,
+		ID:         p.ID,
+		OrgID:      p.OrgID,
+		PolicyType: p.PolicyType,
+		ToolName:   p.ToolName,
+		Action:     p.Action,
 	}
 
 	// Handle nullable Reason
@@ -284,3 +392,38 @@ func dbPolicyToPolicyData(p db.ToolPolicy) PolicyData {
 
 	return pd
 }
+
+// BudgetStatusToData converts a budget and its usage to WebSocket BudgetData
+func BudgetStatusToData(status service.BudgetStatus) BudgetData {
+	b := status.Budget
+	bd := BudgetData{
+		ID:           b.ID,
+		OrgID:        b.OrgID,
+		Period:       b.Period,
+		TokenLimit:   b.TokenLimit,
+		CostLimitUSD: b.CostLimitUsd,
+		TokensUsed:   status.TokensUsed,
+		CostUsedUSD:  status.CostUsedUSD,
+		ResetsAt:     status.ResetsAt,
+	}
+
+	if b.TeamID.Valid {
+		tid := uuid.UUID(b.TeamID.Bytes)
+		bd.TeamID = &tid
+	}
+	if b.EmployeeID.Valid {
+		eid := uuid.UUID(b.EmployeeID.Bytes)
+		bd.EmployeeID = &eid
+	}
+
+	switch {
+	case b.EmployeeID.Valid:
+		bd.Scope = "employee"
+	case b.TeamID.Valid:
+		bd.Scope = "team"
+	default:
+		bd.Scope = "organization"
+	}
+
+	return bd
+}
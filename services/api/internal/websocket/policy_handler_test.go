@@ -0,0 +1,95 @@
+package websocket
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEnforcementSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings string
+		want     EnforcementSettings
+	}{
+		{
+			name:     "empty settings use defaults",
+			settings: "",
+			want:     EnforcementSettings{FailMode: FailModeClosed, GracePeriodSeconds: 300},
+		},
+		{
+			name:     "no policy_enforcement key",
+			settings: `{"theme":"dark"}`,
+			want:     EnforcementSettings{FailMode: FailModeClosed, GracePeriodSeconds: 300},
+		},
+		{
+			name:     "fail open with custom grace period",
+			settings: `{"policy_enforcement":{"fail_mode":"open","grace_period_seconds":60}}`,
+			want:     EnforcementSettings{FailMode: FailModeOpen, GracePeriodSeconds: 60},
+		},
+		{
+			name:     "zero grace period is allowed",
+			settings: `{"policy_enforcement":{"grace_period_seconds":0}}`,
+			want:     EnforcementSettings{FailMode: FailModeClosed, GracePeriodSeconds: 0},
+		},
+		{
+			name:     "invalid values fall back to defaults",
+			settings: `{"policy_enforcement":{"fail_mode":"sometimes","grace_period_seconds":-5}}`,
+			want:     EnforcementSettings{FailMode: FailModeClosed, GracePeriodSeconds: 300},
+		},
+		{
+			name:     "malformed JSON",
+			settings: `{not json`,
+			want:     EnforcementSettings{FailMode: FailModeClosed, GracePeriodSeconds: 300},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseEnforcementSettings([]byte(tt.settings)))
+		})
+	}
+}
+
+func TestParseInterceptHosts(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings string
+		want     []InterceptHost
+	}{
+		{
+			name:     "no intercept_hosts key",
+			settings: `{"policy_enforcement":{"fail_mode":"open"}}`,
+			want:     nil,
+		},
+		{
+			name:     "valid hosts are normalized",
+			settings: `{"intercept_hosts":[{"host":" LLM.Internal.example.com ","protocol":"openai"},{"host":"*.gateway.corp","protocol":"anthropic"}]}`,
+			want: []InterceptHost{
+				{Host: "llm.internal.example.com", Protocol: ProtocolOpenAI},
+				{Host: "*.gateway.corp", Protocol: ProtocolAnthropic},
+			},
+		},
+		{
+			name:     "invalid entries are skipped",
+			settings: `{"intercept_hosts":[{"host":"https://x.com/v1","protocol":"openai"},{"host":"ok.corp","protocol":"grpc"},{"host":"","protocol":"anthropic"},{"host":"good.corp","protocol":"anthropic"}]}`,
+			want:     []InterceptHost{{Host: "good.corp", Protocol: ProtocolAnthropic}},
+		},
+		{
+			name:     "wildcards need a domain of two labels or more",
+			settings: `{"intercept_hosts":[{"host":"*.com","protocol":"openai"},{"host":"*.io","protocol":"anthropic"},{"host":"*","protocol":"anthropic"},{"host":"*.llm.io","protocol":"anthropic"}]}`,
+			want:     []InterceptHost{{Host: "*.llm.io", Protocol: ProtocolAnthropic}},
+		},
+		{
+			name:     "malformed JSON",
+			settings: `{not json`,
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseInterceptHosts([]byte(tt.settings)))
+		})
+	}
+}
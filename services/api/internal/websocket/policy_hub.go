@@ -2,7 +2,10 @@ package websocket
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"sort"
 	"sync"
 	"time"
 
@@ -16,16 +19,114 @@ const (
 	PolicyMessageTypeDelete = "delete"
 	PolicyMessageTypeRevoke = "revoke"
 	PolicyMessageTypePing   = "ping"
+
+	PolicyMessageTypeApprovalDecision = "approval_decision"
+	PolicyMessageTypeBudget           = "budget"
+	PolicyMessageTypeBudgetDelete     = "budget_delete"
+)
+
+// ProxyMessage types for proxy-to-server communication
+const (
+	ProxyMessageTypePong             = "pong"
+	ProxyMessageTypeApprovalRequest  = "approval_request"
+	ProxyMessageTypeApprovalResolved = "approval_resolved"
 )
 
+// ErrApprovalNotFound is returned when deciding an approval that isn't pending.
+var ErrApprovalNotFound = errors.New("approval not found")
+
+// ErrSelfApproval is returned when the employee whose tool call is paused
+// tries to decide it.
+var ErrSelfApproval = errors.New("approval can't be decided by its requester")
+
 // PolicyMessage represents a message sent from server to proxy
 type PolicyMessage struct {
 	Type     string       `json:"type"`
 	Policies []PolicyData `json:"policies,omitempty"`  // For init
 	Policy   *PolicyData  `json:"policy,omitempty"`    // For upsert
 	PolicyID *uuid.UUID   `json:"policy_id,omitempty"` // For delete
-	Reason   string       `json:"reason,omitempty"`    // For revoke
+	Reason   string       `json:"reason,omitempty"`    // For revoke, and upserts and deletes by a schedule
 	Version  int64        `json:"version,omitempty"`   // For init
+	Timezone string       `json:"timezone,omitempty"`  // For init: the organization's, for schedules
+
+	Enforcement    *EnforcementSettings `json:"enforcement,omitempty"`     // For init
+	InterceptHosts []InterceptHost      `json:"intercept_hosts,omitempty"` // For init
+
+	Decision *ApprovalDecision `json:"decision,omitempty"` // For approval_decision
+
+	Budgets  []BudgetData `json:"budgets,omitempty"`   // For init
+	Budget   *BudgetData  `json:"budget,omitempty"`    // For budget
+	BudgetID *uuid.UUID   `json:"budget_id,omitempty"` // For budget_delete
+}
+
+// ProxyMessage represents a message sent from proxy to server
+type ProxyMessage struct {
+	Type       string           `json:"type"`
+	Approval   *ApprovalRequest `json:"approval,omitempty"`    // For approval_request
+	ApprovalID string           `json:"approval_id,omitempty"` // For approval_resolved
+}
+
+// ApprovalRequest is a tool call paused by a require_approval policy.
+// The proxy that paused it reports it so admins can decide remotely.
+type ApprovalRequest struct {
+	ID          string                 `json:"id"`
+	PolicyID    string                 `json:"policy_id,omitempty"`
+	ToolName    string                 `json:"tool_name"`
+	ToolInput   map[string]interface{} `json:"tool_input,omitempty"`
+	Reason      string                 `json:"reason,omitempty"`
+	SessionID   string                 `json:"session_id,omitempty"`
+	RequestedAt time.Time              `json:"requested_at"`
+	ExpiresAt   time.Time              `json:"expires_at"`
+
+	// Set by the server from the reporting connection
+	EmployeeID uuid.UUID `json:"employee_id"`
+	OrgID      uuid.UUID `json:"-"`
+}
+
+// ApprovalDecision is an admin's decision on a pending approval
+type ApprovalDecision struct {
+	ApprovalID string `json:"approval_id"`
+	Approved   bool   `json:"approved"`
+	DecidedBy  string `json:"decided_by,omitempty"` // Employee ID of the admin
+	Reason     string `json:"reason,omitempty"`
+}
+
+// pendingApproval is an approval waiting on the connection that reported it
+type pendingApproval struct {
+	request ApprovalRequest
+	conn    *PolicyConn
+}
+
+// Policy enforcement fail modes
+const (
+	FailModeClosed = "closed" // Block all requests when policies can't be enforced
+	FailModeOpen   = "open"   // Allow requests without policy enforcement
+)
+
+// Default enforcement settings when the organization hasn't configured any
+const (
+	DefaultFailMode           = FailModeClosed
+	DefaultGracePeriodSeconds = 300
+)
+
+// EnforcementSettings tells the proxy how to behave when it cannot enforce policies.
+// Sourced from the "policy_enforcement" key of organization settings.
+type EnforcementSettings struct {
+	FailMode           string `json:"fail_mode"`            // closed or open
+	GracePeriodSeconds int    `json:"grace_period_seconds"` // How long cached policies stay valid after disconnect
+}
+
+// Wire protocols an intercepted host can speak
+const (
+	ProtocolAnthropic = "anthropic"
+	ProtocolOpenAI    = "openai"
+)
+
+// InterceptHost is an extra host the proxy should intercept, such as an internal
+// LLM gateway. Sourced from the "intercept_hosts" key of organization settings.
+type InterceptHost struct {
+	Host     string `json:"host"`     // Hostname, or *.domain for any subdomain
+	Protocol string `json:"protocol"` // anthropic or openai
 }
 
 // PolicyData represents a policy in WebSocket messages
@@ -34,6 +135,7 @@ type PolicyData struct {
 	OrgID      uuid.UUID              `json:"org_id"`
 	TeamID     *uuid.UUID             `json:"team_id,omitempty"`
 	EmployeeID *uuid.UUID             `json:"employee_id,omitempty"`
+	PolicyType string                 `json:"policy_type,omitempty"` // tool or model
 	ToolName   string                 `json:"tool_name"`
 	Action     string                 `json:"action"`
 	Reason     string                 `json:"reason,omitempty"`
@@ -43,9 +145,26 @@ type PolicyData struct {
 	UpdatedAt  *time.Time             `json:"updated_at,omitempty"`
 }
 
-// PolicyChangeNotification represents a notification from PostgreSQL NOTIFY
+// BudgetData is a token or cost budget with the usage counted against it in
+// the current period. Proxies reject new requests once it is exceeded.
+type BudgetData struct {
+	ID           uuid.UUID  `json:"id"`
+	OrgID        uuid.UUID  `json:"org_id"`
+	TeamID       *uuid.UUID `json:"team_id,omitempty"`
+	EmployeeID   *uuid.UUID `json:"employee_id,omitempty"`
+	Scope        string     `json:"scope"`
+	Period       string     `json:"period"`                   // daily or monthly
+	TokenLimit   *int64     `json:"token_limit,omitempty"`    // Input + output tokens
+	CostLimitUSD *float64   `json:"cost_limit_usd,omitempty"` // Estimated USD
+	TokensUsed   int64      `json:"tokens_used"`
+	CostUsedUSD  float64    `json:"cost_used_usd"`
+	ResetsAt     time.Time  `json:"resets_at"`
+}
+
+// PolicyChangeNotification represents a notification from PostgreSQL NOTIFY,
+// or from the PolicyScheduler when a scheduled policy starts or stops applying
 type PolicyChangeNotification struct {
-	Action     string      `json:"action"` // create, update, delete, revoke
+	Action     string      `json:"action"` // insert, update, delete, revoke, activate, deactivate
 	Policy     *PolicyData `json:"policy,omitempty"`
 	PolicyID   *uuid.UUID  `json:"policy_id,omitempty"`
 	OrgID      uuid.UUID   `json:"org_id"`
@@ -81,6 +200,9 @@ type PolicyHub struct {
 	// Channel for policy change notifications
 	policyChange chan PolicyChangeNotification
 
+	// Approvals waiting for a decision, by approval ID
+	approvals map[string]*pendingApproval
+
 	// Stop signal
 	stop chan struct{}
 
@@ -97,6 +219,7 @@ func NewPolicyHub() *PolicyHub {
 		register:     make(chan *PolicyConn),
 		unregister:   make(chan *PolicyConn),
 		policyChange: make(chan PolicyChangeNotification, 256),
+		approvals:    make(map[string]*pendingApproval),
 		stop:         make(chan struct{}),
 	}
 }
@@ -211,6 +334,13 @@ func (h *PolicyHub) unregisterConnection(conn *PolicyConn) {
 		}
 	}
 
+	// Approvals can't be delivered once the proxy is gone
+	for id, pending := range h.approvals {
+		if pending.conn == conn {
+			delete(h.approvals, id)
+		}
+	}
+
 	// Close send channel
 	close(conn.send)
 }
@@ -229,18 +359,9 @@ func (h *PolicyHub) handlePolicyChange(notification PolicyChangeNotification) {
 			affectedConns = h.byEmployee[*notification.EmployeeID]
 		}
 
-	case "insert", "update", "delete":
+	case "insert", "update", "delete", "activate", "deactivate":
 		// Determine affected connections based on policy scope
-		if notification.EmployeeID != nil {
-			// Employee-scoped policy: only that employee
-			affectedConns = h.byEmployee[*notification.EmployeeID]
-		} else if notification.TeamID != nil {
-			// Team-scoped policy: all team members
-			affectedConns = h.byTeam[*notification.TeamID]
-		} else {
-			// Org-scoped policy: all org members
-			affectedConns = h.byOrg[notification.OrgID]
-		}
+		affectedConns = h.scopeConnections(notification.OrgID, notification.TeamID, notification.EmployeeID)
 	}
 
 	if len(affectedConns) == 0 {
@@ -260,6 +381,18 @@ func (h *PolicyHub) handlePolicyChange(notification PolicyChangeNotification) {
 			Type:     PolicyMessageTypeDelete,
 			PolicyID: notification.PolicyID,
 		}
+	case "activate", "deactivate":
+		// Proxies keep scheduled policies and apply their schedules
+		// themselves; the upsert has them check it now
+		reason := "Policy schedule started"
+		if notification.Action == "deactivate" {
+			reason = "Policy schedule ended"
+		}
+		msg = PolicyMessage{
+			Type:   PolicyMessageTypeUpsert,
+			Policy: notification.Policy,
+			Reason: reason,
+		}
 	case "revoke":
 		msg = PolicyMessage{
 			Type:   PolicyMessageTypeRevoke,
@@ -285,11 +418,15 @@ func (h *PolicyHub) handlePolicyChange(notification PolicyChangeNotification) {
 }
 
 // SendInitMessage sends the initial policy sync message to a connection
-func (h *PolicyHub) SendInitMessage(conn *PolicyConn, policies []PolicyData) error {
+func (h *PolicyHub) SendInitMessage(conn *PolicyConn, policies []PolicyData, timezone string, enforcement EnforcementSettings, interceptHosts []InterceptHost, budgets []BudgetData) error {
 	msg := PolicyMessage{
-		Type:     PolicyMessageTypeInit,
-		Policies: policies,
-		Version:  time.Now().Unix(),
+		Type:           PolicyMessageTypeInit,
+		Policies:       policies,
+		Version:        time.Now().Unix(),
+		Timezone:       timezone,
+		Enforcement:    &enforcement,
+		InterceptHosts: interceptHosts,
+		Budgets:        budgets,
 	}
 
 	msgBytes, err := json.Marshal(msg)
@@ -305,6 +442,128 @@ func (h *PolicyHub) SendInitMessage(conn *PolicyConn, policies []PolicyData) err
 	}
 }
 
+// NotifyBudget sends a budget's current usage to every connection it covers,
+// so limits reached on one machine apply on all of them.
+func (h *PolicyHub) NotifyBudget(budget BudgetData) {
+	h.broadcastBudget(budget, PolicyMessage{Type: PolicyMessageTypeBudget, Budget: &budget})
+}
+
+// NotifyBudgetDeleted tells the connections a budget covered that it's gone.
+func (h *PolicyHub) NotifyBudgetDeleted(budget BudgetData) {
+	h.broadcastBudget(budget, PolicyMessage{Type: PolicyMessageTypeBudgetDelete, BudgetID: &budget.ID})
+}
+
+// broadcastBudget sends a budget message to the connections in the budget's scope
+func (h *PolicyHub) broadcastBudget(budget BudgetData, msg PolicyMessage) {
+	msgBytes, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("Failed to marshal message: %v", err)
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, conn := range h.scopeConnections(budget.OrgID, budget.TeamID, budget.EmployeeID) {
+		select {
+		case conn.send <- msgBytes:
+		default:
+			// Channel full, connection gets current usage on next sync
+		}
+	}
+}
+
+// scopeConnections returns the connections of an employee, a team's members or
+// a whole organization, whichever is the narrowest scope set.
+// Must be called with h.mu held.
+func (h *PolicyHub) scopeConnections(orgID uuid.UUID, teamID, employeeID *uuid.UUID) map[string]*PolicyConn {
+	switch {
+	case employeeID != nil:
+		return h.byEmployee[*employeeID]
+	case teamID != nil:
+		return h.byTeam[*teamID]
+	default:
+		return h.byOrg[orgID]
+	}
+}
+
+// AddApproval records an approval reported by a proxy connection.
+// The request is attributed to the connection's employee and organization.
+func (h *PolicyHub) AddApproval(conn *PolicyConn, req ApprovalRequest) {
+	req.EmployeeID = conn.EmployeeID
+	req.OrgID = conn.OrgID
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.connections[conn.ID]; !ok {
+		return
+	}
+	if existing, ok := h.approvals[req.ID]; ok && existing.conn != conn {
+		return // IDs are chosen by proxies; never let one replace another's
+	}
+	h.approvals[req.ID] = &pendingApproval{request: req, conn: conn}
+}
+
+// ResolveApproval removes an approval the proxy decided itself
+// (locally or by timeout). Only the reporting connection can resolve it.
+func (h *PolicyHub) ResolveApproval(conn *PolicyConn, approvalID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if pending, ok := h.approvals[approvalID]; ok && pending.conn == conn {
+		delete(h.approvals, approvalID)
+	}
+}
+
+// PendingApprovals returns the organization's approvals that haven't expired,
+// oldest first.
+func (h *PolicyHub) PendingApprovals(orgID uuid.UUID) []ApprovalRequest {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	now := time.Now()
+	var approvals []ApprovalRequest
+	for _, pending := range h.approvals {
+		if pending.request.OrgID == orgID && now.Before(pending.request.ExpiresAt) {
+			approvals = append(approvals, pending.request)
+		}
+	}
+	sort.Slice(approvals, func(i, j int) bool {
+		return approvals[i].RequestedAt.Before(approvals[j].RequestedAt)
+	})
+	return approvals
+}
+
+// DecideApproval sends a decision to the proxy waiting on the approval.
+// Returns ErrApprovalNotFound if no approval with that ID is pending in the organization,
+// and ErrSelfApproval if it's decided by the employee who made the tool call.
+func (h *PolicyHub) DecideApproval(orgID uuid.UUID, decision ApprovalDecision) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	pending, ok := h.approvals[decision.ApprovalID]
+	if !ok || pending.request.OrgID != orgID || !time.Now().Before(pending.request.ExpiresAt) {
+		return ErrApprovalNotFound
+	}
+	if decision.DecidedBy == pending.request.EmployeeID.String() {
+		return ErrSelfApproval
+	}
+
+	msgBytes, err := json.Marshal(PolicyMessage{
+		Type:     PolicyMessageTypeApprovalDecision,
+		Decision: &decision,
+	})
+	if err != nil {
+		return err
+	}
+
+	select {
+	case pending.conn.send <- msgBytes:
+	default:
+		return fmt.Errorf("proxy connection is not accepting messages")
+	}
+	delete(h.approvals, decision.ApprovalID)
+	return nil
+}
+
 // GetConnectionCount returns the total number of connections (for monitoring)
 func (h *PolicyHub) GetConnectionCount() int {
 	h.mu.RLock()
@@ -0,0 +1,116 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// registeredPolicyConn registers a connection directly, bypassing the Run loop.
+func registeredPolicyConn(h *PolicyHub, orgID uuid.UUID) *PolicyConn {
+	conn := &PolicyConn{
+		ID:         uuid.New().String(),
+		OrgID:      orgID,
+		EmployeeID: uuid.New(),
+		send:       make(chan []byte, 16),
+	}
+	h.registerConnection(conn)
+	return conn
+}
+
+func TestPolicyHub_DecideApproval(t *testing.T) {
+	hub := NewPolicyHub()
+	orgID := uuid.New()
+	conn := registeredPolicyConn(hub, orgID)
+
+	hub.AddApproval(conn, ApprovalRequest{
+		ID:          "apr-1",
+		ToolName:    "Bash",
+		RequestedAt: time.Now(),
+		ExpiresAt:   time.Now().Add(time.Minute),
+	})
+
+	pending := hub.PendingApprovals(orgID)
+	require.Len(t, pending, 1)
+	assert.Equal(t, conn.EmployeeID, pending[0].EmployeeID)
+	assert.Empty(t, hub.PendingApprovals(uuid.New()), "other organizations can't see the approval")
+
+	// Another organization can't decide it
+	err := hub.DecideApproval(uuid.New(), ApprovalDecision{ApprovalID: "apr-1", Approved: true})
+	assert.ErrorIs(t, err, ErrApprovalNotFound)
+
+	// Nor can the employee who made the tool call
+	err = hub.DecideApproval(orgID, ApprovalDecision{ApprovalID: "apr-1", Approved: true, DecidedBy: conn.EmployeeID.String()})
+	assert.ErrorIs(t, err, ErrSelfApproval)
+	require.Len(t, hub.PendingApprovals(orgID), 1, "a rejected decision leaves the approval pending")
+
+	require.NoError(t, hub.DecideApproval(orgID, ApprovalDecision{ApprovalID: "apr-1", Approved: true, DecidedBy: "admin-1"}))
+
+	var msg PolicyMessage
+	require.NoError(t, json.Unmarshal(<-conn.send, &msg))
+	assert.Equal(t, PolicyMessageTypeApprovalDecision, msg.Type)
+	require.NotNil(t, msg.Decision)
+	assert.Equal(t, "apr-1", msg.Decision.ApprovalID)
+	assert.True(t, msg.Decision.Approved)
+
+	// Decided approvals are no longer pending
+	assert.Empty(t, hub.PendingApprovals(orgID))
+	assert.ErrorIs(t, hub.DecideApproval(orgID, ApprovalDecision{ApprovalID: "apr-1"}), ErrApprovalNotFound)
+}
+
+func TestPolicyHub_ApprovalLifecycle(t *testing.T) {
+	hub := NewPolicyHub()
+	orgID := uuid.New()
+	conn := registeredPolicyConn(hub, orgID)
+	other := registeredPolicyConn(hub, orgID)
+
+	hub.AddApproval(conn, ApprovalRequest{ID: "apr-1", ExpiresAt: time.Now().Add(time.Minute)})
+	hub.AddApproval(conn, ApprovalRequest{ID: "apr-expired", ExpiresAt: time.Now().Add(-time.Second)})
+
+	// Another connection can neither replace nor resolve the approval
+	hub.AddApproval(other, ApprovalRequest{ID: "apr-1", ToolName: "Other", ExpiresAt: time.Now().Add(time.Minute)})
+	hub.ResolveApproval(other, "apr-1")
+	pending := hub.PendingApprovals(orgID)
+	require.Len(t, pending, 1, "expired approvals are not listed")
+	assert.Equal(t, conn.EmployeeID, pending[0].EmployeeID)
+
+	// Resolved locally by the proxy
+	hub.ResolveApproval(conn, "apr-1")
+	assert.Empty(t, hub.PendingApprovals(orgID))
+
+	// Approvals are dropped with their connection
+	hub.AddApproval(conn, ApprovalRequest{ID: "apr-2", ExpiresAt: time.Now().Add(time.Minute)})
+	hub.unregisterConnection(conn)
+	assert.Empty(t, hub.PendingApprovals(orgID))
+}
+
+func TestPolicyHub_NotifyBudget(t *testing.T) {
+	hub := NewPolicyHub()
+	orgID := uuid.New()
+	teamID := uuid.New()
+
+	member := &PolicyConn{ID: uuid.New().String(), OrgID: orgID, EmployeeID: uuid.New(), TeamID: &teamID, send: make(chan []byte, 16)}
+	hub.registerConnection(member)
+	outsider := registeredPolicyConn(hub, orgID)
+
+	limit := int64(1000)
+	budget := BudgetData{ID: uuid.New(), OrgID: orgID, TeamID: &teamID, Scope: "team", Period: "daily", TokenLimit: &limit, TokensUsed: 1200}
+	hub.NotifyBudget(budget)
+
+	var msg PolicyMessage
+	require.NoError(t, json.Unmarshal(<-member.send, &msg))
+	assert.Equal(t, PolicyMessageTypeBudget, msg.Type)
+	require.NotNil(t, msg.Budget)
+	assert.Equal(t, int64(1200), msg.Budget.TokensUsed)
+	assert.Empty(t, outsider.send, "team budgets only reach team members")
+
+	hub.NotifyBudgetDeleted(budget)
+	require.NoError(t, json.Unmarshal(<-member.send, &msg))
+	assert.Equal(t, PolicyMessageTypeBudgetDelete, msg.Type)
+	require.NotNil(t, msg.BudgetID)
+	assert.Equal(t, budget.ID, *msg.BudgetID)
+}
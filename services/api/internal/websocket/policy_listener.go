@@ -165,6 +165,7 @@ func (l *PolicyListener) processNotification(payload string) {
 			OrgID      string          `json:"org_id"`
 			TeamID     *string         `json:"team_id"`
 			EmployeeID *string         `json:"employee_id"`
+			PolicyType string          `json:"policy_type"`
 			ToolName   string          `json:"tool_name"`
 			Action     string          `json:"action"`
 			Reason     string          `json:"reason"`
@@ -177,9 +178,10 @@ func (l *PolicyListener) processNotification(payload string) {
 			log.Printf("Failed to parse policy data: %v", err)
 		} else {
 			pd := PolicyData{
-				ToolName: policyData.ToolName,
-				Action:   policyData.Action,
-				Reason:   policyData.Reason,
+				PolicyType: policyData.PolicyType,
+				ToolName:   policyData.ToolName,
+				Action:     policyData.Action,
+				Reason:     policyData.Reason,
 			}
 
 			// Parse UUIDs
@@ -0,0 +1,118 @@
+package websocket
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/rastrigin-systems/arfa/generated/db"
+	"github.com/rastrigin-systems/arfa/services/api/internal/service"
+)
+
+// schedulerTickDelay is how long after each minute starts the scheduler
+// checks: schedules have minute resolution, and the delay keeps a check from
+// landing just before the minute it's meant for
+const schedulerTickDelay = time.Second
+
+// PolicyScheduler watches scheduled policies and has the PolicyHub push each
+// one to its proxies again when it becomes active and when it stops being
+// active. Proxies apply schedules on their own timers; the pushes only make
+// them check sooner
+type PolicyScheduler struct {
+	queries db.Querier
+	hub     *PolicyHub
+	stop    chan struct{}
+}
+
+// NewPolicyScheduler creates a new policy scheduler
+func NewPolicyScheduler(queries db.Querier, hub *PolicyHub) *PolicyScheduler {
+	return &PolicyScheduler{
+		queries: queries,
+		hub:     hub,
+		stop:    make(chan struct{}),
+	}
+}
+
+// Start begins checking schedules every minute
+func (s *PolicyScheduler) Start(ctx context.Context) {
+	go s.run(ctx)
+}
+
+// Stop signals the scheduler to stop
+func (s *PolicyScheduler) Stop() {
+	close(s.stop)
+}
+
+// run checks schedules just after each minute starts. A failed check is
+// retried the next minute over the whole time since the last good one, so
+// boundaries aren't missed while the database is unavailable.
+func (s *PolicyScheduler) run(ctx context.Context) {
+	last := time.Now()
+	for {
+		wait := time.Until(time.Now().Truncate(time.Minute).Add(time.Minute + schedulerTickDelay))
+		select {
+		case <-time.After(wait):
+		case <-s.stop:
+			return
+		case <-ctx.Done():
+			return
+		}
+
+		now := time.Now()
+		if err := s.check(ctx, last, now); err != nil {
+			log.Printf("Policy scheduler error: %v", err)
+			continue
+		}
+		last = now
+	}
+}
+
+// check notifies the hub of each scheduled policy that was active at from and
+// isn't at to, or the other way around
+func (s *PolicyScheduler) check(ctx context.Context, from, to time.Time) error {
+	policies, err := s.queries.ListScheduledToolPolicies(ctx)
+	if err != nil {
+		return err
+	}
+
+	timezones := make(map[uuid.UUID]*time.Location)
+	for _, p := range policies {
+		schedule := service.ToolPolicySchedule(p)
+		if schedule == nil {
+			continue
+		}
+
+		timezone, ok := timezones[p.OrgID]
+		if !ok {
+			org, err := s.queries.GetOrganization(ctx, p.OrgID)
+			if err != nil {
+				return err
+			}
+			timezone = service.OrgTimezone(org.Settings)
+			timezones[p.OrgID] = timezone
+		}
+
+		was, is := schedule.ActiveAt(from, timezone), schedule.ActiveAt(to, timezone)
+		if was == is {
+			continue
+		}
+
+		policy := dbPolicyToPolicyData(p)
+		notification := PolicyChangeNotification{
+			Action:     "activate",
+			Policy:     &policy,
+			PolicyID:   &policy.ID,
+			OrgID:      p.OrgID,
+			TeamID:     policy.TeamID,
+			EmployeeID: policy.EmployeeID,
+		}
+		if !is {
+			notification.Action = "deactivate"
+		}
+		log.Printf("Policy schedule: %s %s %s", notification.Action, p.ToolName, p.ID)
+		s.hub.NotifyPolicyChange(notification)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/rastrigin-systems/arfa/generated/db"
+	"github.com/rastrigin-systems/arfa/generated/mocks"
+)
+
+func TestPolicyScheduler_Check(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockDB := mocks.NewMockQuerier(ctrl)
+
+	hub := NewPolicyHub()
+	orgID := uuid.New()
+	conn := registeredPolicyConn(hub, orgID)
+
+	// Deploy tools are frozen Friday 16:00 to Monday 08:00, Berlin time
+	freeze := db.ToolPolicy{ID: uuid.New(), OrgID: orgID, ToolName: "mcp__deploy__%", Action: "deny",
+		Conditions: []byte(`{"schedule": {"windows": [{"start": "0 16 * * 5", "end": "0 8 * * 1"}]}}`)}
+	mockDB.EXPECT().ListScheduledToolPolicies(gomock.Any()).Return([]db.ToolPolicy{freeze}, nil).Times(3)
+	mockDB.EXPECT().
+		GetOrganization(gomock.Any(), orgID).
+		Return(db.Organization{ID: orgID, Settings: []byte(`{"timezone": "Europe/Berlin"}`)}, nil).
+		Times(3)
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	scheduler := NewPolicyScheduler(mockDB, hub)
+	check := func(from, to time.Time) {
+		require.NoError(t, scheduler.check(context.Background(), from, to))
+		for len(hub.policyChange) > 0 {
+			hub.handlePolicyChange(<-hub.policyChange)
+		}
+	}
+
+	// 2026-10-16 is a Friday: the freeze starts
+	check(time.Date(2026, 10, 16, 15, 59, 1, 0, berlin), time.Date(2026, 10, 16, 16, 0, 1, 0, berlin))
+	var msg PolicyMessage
+	require.NoError(t, json.Unmarshal(<-conn.send, &msg))
+	assert.Equal(t, PolicyMessageTypeUpsert, msg.Type)
+	require.NotNil(t, msg.Policy)
+	assert.Equal(t, freeze.ID, msg.Policy.ID)
+	assert.Equal(t, "Policy schedule started", msg.Reason)
+
+	// Nothing changes over the weekend
+	check(time.Date(2026, 10, 17, 12, 0, 1, 0, berlin), time.Date(2026, 10, 17, 12, 1, 1, 0, berlin))
+	assert.Empty(t, conn.send)
+
+	// Monday 08:00 it ends
+	check(time.Date(2026, 10, 19, 7, 59, 1, 0, berlin), time.Date(2026, 10, 19, 8, 0, 1, 0, berlin))
+	require.NoError(t, json.Unmarshal(<-conn.send, &msg))
+	assert.Equal(t, PolicyMessageTypeUpsert, msg.Type, "the proxy keeps the policy for the next window")
+	require.NotNil(t, msg.Policy)
+	assert.Equal(t, freeze.ID, msg.Policy.ID)
+	assert.Equal(t, "Policy schedule ended", msg.Reason)
+}
@@ -302,9 +302,83 @@ func (c *Client) UpdatePolicy(ctx context.Context, id string, req UpdateToolPoli
 	return &resp, nil
 }
 
-// DeletePolicy deletes a tool policy by ID.
-func (c *Client) DeletePolicy(ctx context.Context, id string) error {
+// GetPolicyHistory fetches every recorded change to a tool policy, newest first.
+func (c *Client) GetPolicyHistory(ctx context.Context, id string) (*ToolPolicyHistoryResponse, error) {
+	var resp ToolPolicyHistoryResponse
+	endpoint := fmt.Sprintf("/policies/%s/history", id)
+	if err := c.DoRequest(ctx, "GET", endpoint, nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get policy history: %w", err)
+	}
+	return &resp, nil
+}
+
+// RollbackPolicy restores a tool policy to the state of an earlier revision.
+func (c *Client) RollbackPolicy(ctx context.Context, id string, req RollbackToolPolicyRequest) (*ToolPolicy, error) {
+	var resp ToolPolicy
+	endpoint := fmt.Sprintf("/policies/%s/rollback", id)
+	if err := c.DoRequest(ctx, "POST", endpoint, req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to roll back policy: %w", err)
+	}
+	return &resp, nil
+}
+
+// ExportPolicies fetches the organization's tool policies as definitions.
+func (c *Client) ExportPolicies(ctx context.Context) (*PolicyDefinitionList, error) {
+	var resp PolicyDefinitionList
+	if err := c.DoRequest(ctx, "GET", "/policies/export", nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to export policies: %w", err)
+	}
+	return &resp, nil
+}
+
+// ApplyPolicies brings the organization's tool policies to the declared set
+// in one transaction, or with DryRun reports the changes that would make.
+func (c *Client) ApplyPolicies(ctx context.Context, req ApplyToolPoliciesRequest) (*ApplyToolPoliciesResponse, error) {
+	var resp ApplyToolPoliciesResponse
+	if err := c.DoRequest(ctx, "POST", "/policies/apply", req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to apply policies: %w", err)
+	}
+	return &resp, nil
+}
+
+// ListPolicyPacks lists the built-in policy packs with the organization's
+// installs of each.
+func (c *Client) ListPolicyPacks(ctx context.Context) (*PolicyPackList, error) {
+	var resp PolicyPackList
+	if err := c.DoRequest(ctx, "GET", "/policies/packs", nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to list policy packs: %w", err)
+	}
+	return &resp, nil
+}
+
+// InstallPolicyPack installs the latest version of a policy pack, or
+// upgrades an earlier install to it. With DryRun the changes are only reported.
+func (c *Client) InstallPolicyPack(ctx context.Context, name string, req InstallPolicyPackRequest) (*InstallPolicyPackResponse, error) {
+	var resp InstallPolicyPackResponse
+	endpoint := fmt.Sprintf("/policies/packs/%s/install", url.PathEscape(name))
+	if err := c.DoRequest(ctx, "POST", endpoint, req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to install policy pack: %w", err)
+	}
+	return &resp, nil
+}
+
+// SimulatePolicies replays the organization's stored tool calls against
+// draft policies without saving them.
+func (c *Client) SimulatePolicies(ctx context.Context, req SimulateToolPoliciesRequest) (*SimulateToolPoliciesResponse, error) {
+	var resp SimulateToolPoliciesResponse
+	if err := c.DoRequest(ctx, "POST", "/policies/simulate", req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to simulate policies: %w", err)
+	}
+	return &resp, nil
+}
+
+// DeletePolicy deletes a tool policy by ID. The note, if any, is kept in
+// the policy's history.
+func (c *Client) DeletePolicy(ctx context.Context, id, note string) error {
 	endpoint := fmt.Sprintf("/policies/%s", id)
+	if note != "" {
+		endpoint += "?note=" + url.QueryEscape(note)
+	}
 	if err := c.DoRequest(ctx, "DELETE", endpoint, nil, nil); err != nil {
 		return fmt.Errorf("failed to delete policy: %w", err)
 	}
@@ -293,8 +293,21 @@ const (
 type ToolPolicyAction string
 
 const (
-	ToolPolicyActionDeny  ToolPolicyAction = "deny"
-	ToolPolicyActionAudit ToolPolicyAction = "audit"
+	ToolPolicyActionDeny            ToolPolicyAction = "deny"
+	ToolPolicyActionAudit           ToolPolicyAction = "audit"
+	ToolPolicyActionRequireApproval ToolPolicyAction = "require_approval"
+	ToolPolicyActionRateLimit       ToolPolicyAction = "rate_limit"
+	ToolPolicyActionRewrite         ToolPolicyAction = "rewrite" // Model policies only
+	ToolPolicyActionAllow           ToolPolicyAction = "allow"   // Exempts calls from restrictions it outranks
+)
+
+// ToolPolicyType indicates what a policy matches.
+type ToolPolicyType string
+
+const (
+	ToolPolicyTypeTool  ToolPolicyType = "tool"  // Tool calls in responses
+	ToolPolicyTypeModel ToolPolicyType = "model" // The model requested in conversation requests
+	ToolPolicyTypePath  ToolPolicyType = "path"  // Files read or written by file tools
 )
 
 // ToolPolicy represents a policy that controls tool access for an employee.
@@ -303,6 +316,7 @@ type ToolPolicy struct {
 	OrgID      string                 `json:"org_id,omitempty"`
 	TeamID     *string                `json:"team_id,omitempty"`
 	EmployeeID *string                `json:"employee_id,omitempty"`
+	PolicyType ToolPolicyType         `json:"policy_type,omitempty"`
 	ToolName   string                 `json:"tool_name"`
 	Action     ToolPolicyAction       `json:"action"`
 	Reason     *string                `json:"reason,omitempty"`
@@ -327,12 +341,14 @@ type ListToolPoliciesResponse struct {
 
 // CreateToolPolicyRequest represents the request to create a tool policy.
 type CreateToolPolicyRequest struct {
+	PolicyType ToolPolicyType         `json:"policy_type,omitempty"`
 	ToolName   string                 `json:"tool_name"`
 	Action     ToolPolicyAction       `json:"action"`
 	Reason     *string                `json:"reason,omitempty"`
 	TeamID     *string                `json:"team_id,omitempty"`
 	EmployeeID *string                `json:"employee_id,omitempty"`
 	Conditions map[string]interface{} `json:"conditions,omitempty"`
+	Note       *string                `json:"note,omitempty"` // Why, kept in the policy's history
 }
 
 // UpdateToolPolicyRequest represents the request to update a tool policy.
@@ -341,6 +357,167 @@ type UpdateToolPolicyRequest struct {
 	Action     *ToolPolicyAction      `json:"action,omitempty"`
 	Reason     *string                `json:"reason,omitempty"`
 	Conditions map[string]interface{} `json:"conditions,omitempty"`
+	Note       *string                `json:"note,omitempty"` // Why, kept in the policy's history
+}
+
+// ToolPolicyRevision is one recorded change to a tool policy.
+type ToolPolicyRevision struct {
+	ID             string                 `json:"id"`
+	PolicyID       string                 `json:"policy_id"`
+	Revision       int                    `json:"revision"`
+	ChangeType     string                 `json:"change_type"` // create, update, delete or rollback
+	Snapshot       map[string]interface{} `json:"snapshot"`    // The policy after the change (before it, for deletes)
+	Diff           map[string]FieldChange `json:"diff"`
+	Note           *string                `json:"note,omitempty"`
+	RolledBackTo   *int                   `json:"rolled_back_to,omitempty"`
+	ChangedBy      *string                `json:"changed_by,omitempty"`
+	ChangedByEmail *string                `json:"changed_by_email,omitempty"`
+	SessionID      *string                `json:"session_id,omitempty"`
+	CreatedAt      string                 `json:"created_at"`
+}
+
+// FieldChange is the old and new value of a changed policy field.
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// ToolPolicyHistoryResponse represents the response from GET /policies/{id}/history.
+type ToolPolicyHistoryResponse struct {
+	Revisions []ToolPolicyRevision `json:"revisions"` // Newest first
+	Total     int                  `json:"total"`
+}
+
+// RollbackToolPolicyRequest represents the request to POST /policies/{id}/rollback.
+type RollbackToolPolicyRequest struct {
+	Revision int     `json:"revision"`
+	Note     *string `json:"note,omitempty"`
+}
+
+// PolicyDefinition is a tool policy in the declarative form kept in version
+// control: no ID, and its team or employee referenced by name or email. The
+// yaml tags keep exported files in this field order.
+type PolicyDefinition struct {
+	ToolName   string                 `json:"tool_name" yaml:"tool_name"`
+	PolicyType ToolPolicyType         `json:"policy_type,omitempty" yaml:"policy_type,omitempty"` // Default tool
+	Action     ToolPolicyAction       `json:"action" yaml:"action"`
+	Team       *string                `json:"team,omitempty" yaml:"team,omitempty"`         // Team name
+	Employee   *string                `json:"employee,omitempty" yaml:"employee,omitempty"` // Employee email
+	Reason     *string                `json:"reason,omitempty" yaml:"reason,omitempty"`
+	Conditions map[string]interface{} `json:"conditions,omitempty" yaml:"conditions,omitempty"`
+}
+
+// PolicyDefinitionList is a policies file, and the response from GET /policies/export.
+type PolicyDefinitionList struct {
+	Policies []PolicyDefinition `json:"policies" yaml:"policies"`
+}
+
+// ApplyToolPoliciesRequest represents the request to POST /policies/apply.
+type ApplyToolPoliciesRequest struct {
+	Policies []PolicyDefinition `json:"policies"` // Every policy the organization should have
+	DryRun   bool               `json:"dry_run,omitempty"`
+	Note     *string            `json:"note,omitempty"`
+}
+
+// PolicyPlanChange is one change made, or to be made, by an apply.
+type PolicyPlanChange struct {
+	ChangeType string                 `json:"change_type"` // create, update or delete
+	PolicyID   *string                `json:"policy_id,omitempty"`
+	Policy     PolicyDefinition       `json:"policy"` // After the change (before it, for deletes)
+	Diff       map[string]FieldChange `json:"diff"`
+}
+
+// ApplyToolPoliciesResponse represents the response from POST /policies/apply.
+type ApplyToolPoliciesResponse struct {
+	Changes   []PolicyPlanChange `json:"changes"`
+	Created   int                `json:"created"`
+	Updated   int                `json:"updated"`
+	Deleted   int                `json:"deleted"`
+	Unchanged int                `json:"unchanged"`
+	Applied   bool               `json:"applied"` // False for dry runs
+}
+
+// PolicyPack is a built-in, curated set of tool policies.
+type PolicyPack struct {
+	Name        string              `json:"name"`
+	Version     int                 `json:"version"` // Latest version
+	Title       string              `json:"title"`
+	Description string              `json:"description"`
+	Policies    []PolicyDefinition  `json:"policies"` // As an organization-wide install creates them
+	Installs    []PolicyPackInstall `json:"installs"`
+}
+
+// PolicyPackInstall is a pack installed for an organization, or one of its teams.
+type PolicyPackInstall struct {
+	ID               string    `json:"id"`
+	PackName         string    `json:"pack_name"`
+	Version          int       `json:"version"`
+	UpgradeAvailable bool      `json:"upgrade_available"`
+	TeamID           *string   `json:"team_id,omitempty"` // Unset for organization-wide installs
+	Team             *string   `json:"team,omitempty"`
+	PolicyCount      int       `json:"policy_count"`
+	InstalledBy      *string   `json:"installed_by,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// PolicyPackList represents the response from GET /policies/packs.
+type PolicyPackList struct {
+	Packs []PolicyPack `json:"packs"`
+}
+
+// InstallPolicyPackRequest represents the request to POST /policies/packs/{pack_name}/install.
+type InstallPolicyPackRequest struct {
+	TeamID *string `json:"team_id,omitempty"` // Unset for the whole organization
+	DryRun bool    `json:"dry_run,omitempty"`
+}
+
+// InstallPolicyPackResponse represents the response from POST /policies/packs/{pack_name}/install.
+type InstallPolicyPackResponse struct {
+	PackName    string                    `json:"pack_name"`
+	FromVersion *int                      `json:"from_version,omitempty"` // Unset for new installs
+	ToVersion   int                       `json:"to_version"`
+	Install     *PolicyPackInstall        `json:"install,omitempty"` // Unset for dry runs of new installs
+	Plan        ApplyToolPoliciesResponse `json:"plan"`
+}
+
+// SimulateToolPoliciesRequest represents the request to POST /policies/simulate.
+// Drafts are evaluated with the saved policies, less those in ReplacePolicyIDs.
+type SimulateToolPoliciesRequest struct {
+	Policies         []CreateToolPolicyRequest `json:"policies,omitempty"`
+	ReplacePolicyIDs []string                  `json:"replace_policy_ids,omitempty"`
+	IncludeExisting  *bool                     `json:"include_existing,omitempty"`
+	StartDate        *string                   `json:"start_date,omitempty"`
+	EndDate          *string                   `json:"end_date,omitempty"`
+}
+
+// PolicySimulationCounts counts replayed tool calls by simulated outcome.
+type PolicySimulationCounts struct {
+	Total           int `json:"total"`
+	Allowed         int `json:"allowed"`
+	Denied          int `json:"denied"`
+	RequireApproval int `json:"require_approval"`
+	RateLimited     int `json:"rate_limited"`
+	NewlyDenied     int `json:"newly_denied"`  // Let through when logged
+	NewlyAllowed    int `json:"newly_allowed"` // Blocked when logged
+}
+
+// PolicySimulationGroup counts the replayed tool calls of one employee and tool.
+type PolicySimulationGroup struct {
+	EmployeeID    *string `json:"employee_id,omitempty"`
+	EmployeeEmail *string `json:"employee_email,omitempty"`
+	ToolName      string  `json:"tool_name"`
+	PolicySimulationCounts
+}
+
+// SimulateToolPoliciesResponse represents the response from POST /policies/simulate.
+type SimulateToolPoliciesResponse struct {
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+	PolicySimulationCounts
+	Groups          []PolicySimulationGroup `json:"groups"`
+	SkippedPolicies int                     `json:"skipped_policies"`
+	Truncated       bool                    `json:"truncated"`
 }
 
 // ============================================================================
@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rastrigin-systems/arfa/services/cli/internal/container"
+	"github.com/rastrigin-systems/arfa/services/cli/internal/control"
+	"github.com/spf13/cobra"
+)
+
+// NewApproveCommand creates the approve command.
+func NewApproveCommand(c *container.Container) *cobra.Command {
+	var deny bool
+	var reason string
+
+	cmd := &cobra.Command{
+		Use:   "approve [approval-id]",
+		Short: "Approve or deny paused tool calls",
+		Long: `Approve or deny tool calls paused by require_approval policies.
+
+Without an ID, lists the tool calls waiting for a decision. Calls that are
+not decided before they expire are denied.
+
+Examples:
+  arfa approve                                # List pending approvals
+  arfa approve apr_1a2b3c4d                   # Approve a tool call
+  arfa approve apr_1a2b3c4d --deny            # Deny it
+  arfa approve apr_1a2b3c4d --deny --reason "Not on prod"`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
+			pidFile, err := control.NewPIDFile()
+			if err != nil {
+				return fmt.Errorf("failed to create PID file manager: %w", err)
+			}
+
+			status, err := pidFile.GetStatus()
+			if err != nil {
+				return fmt.Errorf("failed to check proxy status: %w", err)
+			}
+			if !status.Running {
+				return fmt.Errorf("proxy is not running. Start it with 'arfa start'")
+			}
+
+			client := control.NewApprovalClient(status.Info)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			if len(args) == 0 {
+				pending, err := client.List(ctx)
+				if err != nil {
+					return fmt.Errorf("failed to list approvals: %w", err)
+				}
+				printApprovals(cmd, pending)
+				return nil
+			}
+
+			approvalID := args[0]
+			if err := client.Decide(ctx, approvalID, !deny, reason); err != nil {
+				if errors.Is(err, control.ErrApprovalNotFound) {
+					return fmt.Errorf("approval %s not found (already decided or expired)", approvalID)
+				}
+				return fmt.Errorf("failed to decide approval: %w", err)
+			}
+
+			if deny {
+				_, _ = fmt.Fprintf(out, "✗ Denied %s\n", approvalID)
+			} else {
+				_, _ = fmt.Fprintf(out, "✓ Approved %s\n", approvalID)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&deny, "deny", false, "Deny the tool call instead of approving it")
+	cmd.Flags().StringVar(&reason, "reason", "", "Reason for the decision, shown to the agent when denied")
+
+	return cmd
+}
+
+// printApprovals lists pending approvals as a table.
+func printApprovals(cmd *cobra.Command, pending []control.ApprovalRequest) {
+	out := cmd.OutOrStdout()
+
+	if len(pending) == 0 {
+		_, _ = fmt.Fprintln(out, "No tool calls are waiting for approval.")
+		return
+	}
+
+	_, _ = fmt.Fprintf(out, "\nPending Approvals (%d):\n\n", len(pending))
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tTOOL\tEXPIRES\tINPUT")
+	_, _ = fmt.Fprintln(w, "──\t────\t───────\t─────")
+
+	for _, req := range pending {
+		input := "-"
+		if req.ToolInput != nil {
+			if data, err := json.Marshal(req.ToolInput); err == nil {
+				input = truncate(string(data), 60)
+			}
+		}
+		expires := time.Until(req.ExpiresAt).Round(time.Second)
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", req.ID, req.ToolName, expires, input)
+	}
+
+	_ = w.Flush()
+	_, _ = fmt.Fprintln(out)
+}
+
+// truncate shortens a string to maxLen characters
+func truncate(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen-3] + "..."
+}
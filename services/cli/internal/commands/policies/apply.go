@@ -0,0 +1,341 @@
+package policies
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rastrigin-systems/arfa/pkg/policy"
+	"github.com/rastrigin-systems/arfa/services/cli/internal/api"
+	"github.com/rastrigin-systems/arfa/services/cli/internal/container"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// NewExportCommand creates the policies export command.
+func NewExportCommand(c *container.Container) *cobra.Command {
+	var output, format string
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the organization's policies as a policies file",
+		Long: `Write the organization's tool policies as a declarative policies file, to
+keep in version control and apply with 'arfa policies apply'.
+
+Teams are referenced by name and employees by email, so the file carries no
+IDs. Policies are listed oldest first.
+
+Examples:
+  arfa policies export > policies.yaml
+  arfa policies export --output policies.json --format json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "yaml" && format != "json" {
+				return fmt.Errorf("--format must be 'yaml' or 'json'")
+			}
+
+			client, err := c.APIClient()
+			if err != nil {
+				return fmt.Errorf("not logged in. Run 'arfa login' first: %w", err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			list, err := client.ExportPolicies(ctx)
+			if err != nil {
+				return err
+			}
+			data, err := encodeDefinitions(list, format)
+			if err != nil {
+				return err
+			}
+
+			if output == "" {
+				_, _ = cmd.OutOrStdout().Write(data)
+				return nil
+			}
+			if err := os.WriteFile(output, data, 0644); err != nil {
+				return fmt.Errorf("failed to write policies: %w", err)
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Exported %d policies to %s\n", len(list.Policies), output)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "File to write instead of stdout")
+	cmd.Flags().StringVar(&format, "format", "yaml", "File format: yaml or json")
+
+	return cmd
+}
+
+// NewPlanCommand creates the policies plan command.
+func NewPlanCommand(c *container.Container) *cobra.Command {
+	var file string
+	var showJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Show what applying a policies file would change",
+		Long: `Compare a policies file with the organization's saved policies and show the
+policies 'arfa policies apply' would create, update and delete. Nothing is
+changed.
+
+The file declares every policy the organization should have. Saved policies
+are matched to declared ones by scope, type and tool name (then by content,
+when several share those), so a file that is already applied plans no changes.
+
+Examples:
+  arfa policies plan -f policies.yaml
+  arfa policies plan -f policies.yaml --json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
+			definitions, err := loadDefinitions(file)
+			if err != nil {
+				return err
+			}
+
+			client, err := c.APIClient()
+			if err != nil {
+				return fmt.Errorf("not logged in. Run 'arfa login' first: %w", err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			plan, err := client.ApplyPolicies(ctx, api.ApplyToolPoliciesRequest{Policies: definitions, DryRun: true})
+			if err != nil {
+				return err
+			}
+
+			if showJSON {
+				data, _ := json.MarshalIndent(plan, "", "  ")
+				_, _ = fmt.Fprintln(out, string(data))
+				return nil
+			}
+			printPlan(out, plan)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", "", "Policies file (YAML or JSON)")
+	cmd.Flags().BoolVar(&showJSON, "json", false, "Output as JSON")
+	_ = cmd.MarkFlagRequired("file")
+
+	return cmd
+}
+
+// NewApplyCommand creates the policies apply command.
+func NewApplyCommand(c *container.Container) *cobra.Command {
+	var file, note string
+	var yes, showJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Make the organization's policies match a policies file",
+		Long: `Create, update and delete tool policies so the organization has exactly the
+policies in a policies file. The changes are made in one transaction: if any
+fails, none is made. Each change is recorded in its policy's history along
+with --note.
+
+The plan is shown and confirmed first unless --yes is given, as in a CI
+pipeline. Applying a file that is already applied changes nothing.
+
+Examples:
+  arfa policies apply -f policies.yaml
+  arfa policies apply -f policies.yaml --yes --note "Merged $CI_COMMIT_SHA"`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
+			definitions, err := loadDefinitions(file)
+			if err != nil {
+				return err
+			}
+
+			client, err := c.APIClient()
+			if err != nil {
+				return fmt.Errorf("not logged in. Run 'arfa login' first: %w", err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+			defer cancel()
+
+			req := api.ApplyToolPoliciesRequest{Policies: definitions}
+			if note != "" {
+				req.Note = &note
+			}
+
+			if !yes {
+				req.DryRun = true
+				plan, err := client.ApplyPolicies(ctx, req)
+				if err != nil {
+					return err
+				}
+				printPlan(out, plan)
+				if len(plan.Changes) == 0 {
+					return nil
+				}
+
+				reader := bufio.NewReader(cmd.InOrStdin())
+				_, _ = fmt.Fprint(out, "Apply these changes? [y/N]: ")
+				answer, _ := reader.ReadString('\n')
+				answer = strings.TrimSpace(strings.ToLower(answer))
+				if answer != "y" && answer != "yes" {
+					_, _ = fmt.Fprintln(out, "Apply cancelled.")
+					return nil
+				}
+				req.DryRun = false
+			}
+
+			// The server plans again inside its transaction, so changes made
+			// since the plan above are accounted for
+			result, err := client.ApplyPolicies(ctx, req)
+			if err != nil {
+				return err
+			}
+
+			if showJSON {
+				data, _ := json.MarshalIndent(result, "", "  ")
+				_, _ = fmt.Fprintln(out, string(data))
+				return nil
+			}
+			if yes {
+				printPlan(out, result)
+				return nil
+			}
+			_, _ = fmt.Fprintf(out, "%s.\n", planSummary(result))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", "", "Policies file (YAML or JSON)")
+	cmd.Flags().StringVar(&note, "note", "", "Why the policies are changing, kept in their history")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Apply without showing the plan and asking for confirmation")
+	cmd.Flags().BoolVar(&showJSON, "json", false, "Output as JSON")
+	_ = cmd.MarkFlagRequired("file")
+
+	return cmd
+}
+
+// loadDefinitions reads a policies file: YAML or JSON with a "policies" list,
+// as written by 'arfa policies export'. Unknown fields are rejected, so a
+// misspelt field can't be silently dropped from what is applied.
+func loadDefinitions(path string) ([]api.PolicyDefinition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policies: %w", err)
+	}
+
+	// JSON is YAML, so one decoder reads both
+	var list api.PolicyDefinitionList
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&list); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse policies: %w", err)
+	}
+	if list.Policies == nil {
+		return nil, fmt.Errorf("failed to parse policies: expected a \"policies\" list (use \"policies: []\" to delete every policy)")
+	}
+
+	for i := range list.Policies {
+		p := &list.Policies[i]
+		if p.ToolName == "" {
+			return nil, fmt.Errorf("policy %d: tool_name is required", i+1)
+		}
+		if !validAction(string(p.Action)) {
+			return nil, fmt.Errorf("policy %d (%s): unknown action %q", i+1, p.ToolName, p.Action)
+		}
+		if p.Team != nil && p.Employee != nil {
+			return nil, fmt.Errorf("policy %d (%s): set team or employee, not both", i+1, p.ToolName)
+		}
+		if err := policy.Validate(p.Conditions); err != nil {
+			return nil, fmt.Errorf("policy %d (%s): invalid conditions: %w", i+1, p.ToolName, err)
+		}
+	}
+	return list.Policies, nil
+}
+
+// encodeDefinitions writes definitions as a policies file
+func encodeDefinitions(list *api.PolicyDefinitionList, format string) ([]byte, error) {
+	if list.Policies == nil {
+		list.Policies = []api.PolicyDefinition{}
+	}
+	if format == "json" {
+		data, err := json.MarshalIndent(list, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode policies: %w", err)
+		}
+		return append(data, '\n'), nil
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(list); err != nil {
+		return nil, fmt.Errorf("failed to encode policies: %w", err)
+	}
+	_ = encoder.Close()
+	return buf.Bytes(), nil
+}
+
+// printPlan writes a line per change, with the fields each update changes,
+// then the totals.
+func printPlan(out io.Writer, plan *api.ApplyToolPoliciesResponse) {
+	if len(plan.Changes) == 0 {
+		_, _ = fmt.Fprintf(out, "No changes. %d policies are up to date.\n", plan.Unchanged)
+		return
+	}
+
+	_, _ = fmt.Fprintln(out)
+	for _, change := range plan.Changes {
+		symbol := map[string]string{"create": "+", "update": "~", "delete": "-"}[change.ChangeType]
+		_, _ = fmt.Fprintf(out, "  %s %-6s  %s  %s  %s\n", symbol, change.ChangeType,
+			definitionTool(change.Policy), definitionScope(change.Policy), strings.ToUpper(string(change.Policy.Action)))
+		if change.ChangeType == "update" {
+			for _, line := range formatDiff(change.Diff) {
+				_, _ = fmt.Fprintf(out, "        %s\n", line)
+			}
+		}
+	}
+	_, _ = fmt.Fprintln(out)
+	_, _ = fmt.Fprintf(out, "%s.\n", planSummary(plan))
+}
+
+// planSummary counts the changes of a plan, or of an apply
+func planSummary(plan *api.ApplyToolPoliciesResponse) string {
+	if plan.Applied {
+		return fmt.Sprintf("Applied: %d created, %d updated, %d deleted, %d unchanged",
+			plan.Created, plan.Updated, plan.Deleted, plan.Unchanged)
+	}
+	return fmt.Sprintf("Plan: %d to create, %d to update, %d to delete, %d unchanged",
+		plan.Created, plan.Updated, plan.Deleted, plan.Unchanged)
+}
+
+// definitionTool returns a definition's tool, prefixed for model and path policies
+func definitionTool(d api.PolicyDefinition) string {
+	switch d.PolicyType {
+	case api.ToolPolicyTypeModel:
+		return "model:" + d.ToolName
+	case api.ToolPolicyTypePath:
+		return "path:" + d.ToolName
+	}
+	return d.ToolName
+}
+
+// definitionScope returns who a definition applies to
+func definitionScope(d api.PolicyDefinition) string {
+	switch {
+	case d.Employee != nil:
+		return "employee " + *d.Employee
+	case d.Team != nil:
+		return "team " + *d.Team
+	}
+	return "organization"
+}
@@ -4,8 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/rastrigin-systems/arfa/pkg/policy"
+	"github.com/rastrigin-systems/arfa/pkg/shell"
 	"github.com/rastrigin-systems/arfa/services/cli/internal/api"
 	"github.com/rastrigin-systems/arfa/services/cli/internal/container"
 	"github.com/spf13/cobra"
@@ -13,9 +17,21 @@ import (
 
 // NewCreateCommand creates the policies create command.
 func NewCreateCommand(c *container.Container) *cobra.Command {
-	var toolName, action, reason string
+	var toolName, model, pathGlob, action, reason, note string
+	var fallback string
+	var allowedModels, allowedPaths []string
+	var shellCommand string
+	var pipedTo []string
 	var teamID, employeeID string
 	var conditions []string
+	var matchAny bool
+	var maxCalls int
+	var window time.Duration
+	var per string
+	var activeFrom, activeUntil string
+	var activeWindows []string
+	var simulate bool
+	var since time.Duration
 	var showJSON bool
 
 	cmd := &cobra.Command{
@@ -23,18 +39,59 @@ func NewCreateCommand(c *container.Container) *cobra.Command {
 		Short: "Create a tool policy",
 		Long: `Create a new tool policy to control LLM tool access.
 
-Policies can block or audit specific tools or patterns. Use glob patterns
-to match multiple tools (e.g., "mcp__*" matches all MCP tools).
+Policies can block, audit, rate-limit, or require approval for specific tools
+or patterns. Use glob patterns to match multiple tools (e.g., "mcp__*" matches
+all MCP tools).
+
+Model policies (--model instead of --tool) apply to the model requested in each
+conversation. They can deny the model, audit it, or rewrite it to --fallback.
+Models listed with --allowed-model are exempt, so --model "%" with allowed
+models only lets those through.
+
+Path policies (--path instead of --tool) apply to the files that file tools
+(Read, Write, Edit, Glob, Grep and MCP filesystem tools) touch. Paths are
+normalized before matching, so ../ segments, ~ and symlinks can't get around
+them. Globs relative to the project root listed with --allowed-path are exempt.
+
+Shell conditions (--shell) match Bash commands on what they actually run: the
+command line is split into every command in its pipelines, lists, subshells,
+$(...) substitutions and sh -c scripts, with env, sudo and xargs prefixes
+removed. The policy applies when one of them is the given command with the
+given arguments and flags, in any order or spelling (-rf, -r -f). Use
+--piped-to to only match the command when its output is piped into another.
+
+Scheduled policies only apply part of the time: from --active-from, until
+--active-until, and if --active-window is given, only inside one of its
+recurring windows. A window is two five-field cron expressions (minute hour
+day-of-month month day-of-week) joined by " to ", and is open from each match
+of the first to the next match of the second, in the organization's timezone.
 
 Actions:
-  deny   - Block the tool (default)
-  audit  - Allow but log usage
+  deny             - Block the tool (default)
+  audit            - Allow but log usage
+  require_approval - Pause the call until it is approved (arfa approve)
+  rate_limit       - Block calls over --max-calls per --window
+  allow            - Exempt calls from deny, require_approval and rate_limit
+                     policies it outranks (tool policies only)
+  rewrite          - Replace the model with --fallback (model policies only)
+
+Path policies support deny and audit.
+
+With --simulate the policy isn't created. Instead, the organization's tool
+calls from the last --since are replayed against it and the saved policies,
+as the proxy would have decided them, and the calls it would block or let
+through are reported by employee and tool.
 
 Scopes:
   Organization - No team or employee flags (default)
   Team         - Use --team flag
   Employee     - Use --employee flag
 
+An allow policy outranks a restriction with a narrower scope (employee over
+team over organization), or with the same scope, a more specific tool name
+(an exact name over a pattern, a longer pattern over a shorter one) or
+conditions the restriction lacks. Ties go to the restriction.
+
 Examples:
   # Block Bash for entire organization
   arfa policies create --tool Bash --action deny --reason "Shell blocked"
@@ -45,20 +102,139 @@ Examples:
   # Audit dangerous commands (conditional policy)
   arfa policies create --tool Bash --action deny \
     --condition 'command=~rm\s+-rf' \
-    --reason "Destructive commands blocked"`,
+    --reason "Destructive commands blocked"
+
+  # Audit edits that leave TODOs in Go files
+  arfa policies create --tool Edit --action audit \
+    --condition 'file_path$=.go' --condition 'new_string*=TODO'
+
+  # Block long-running or background commands
+  arfa policies create --tool Bash --any \
+    --condition 'timeout>600000' --condition 'run_in_background=true'
+
+  # At most 5 pull requests per hour per employee
+  arfa policies create --tool mcp__github__create_pull_request --action rate_limit \
+    --max-calls 5 --window 1h --per employee
+
+  # Only allow Sonnet and Haiku models
+  arfa policies create --model "%" --action deny \
+    --allowed-model "claude-sonnet-%" --allowed-model "claude-haiku-%"
+
+  # Send Opus requests from a team to Sonnet instead
+  arfa policies create --model "claude-opus-%" --action rewrite \
+    --fallback claude-sonnet-4-5 --team 123e4567-e89b-12d3-a456-426614174000
+
+  # Let one team use Bash despite an organization-wide block
+  arfa policies create --tool Bash --action allow --team 123e4567-e89b-12d3-a456-426614174000
+
+  # Block force pushes, however they are written
+  arfa policies create --shell "git push --force" --reason "No force pushes"
+
+  # Block piping downloads into a shell
+  arfa policies create --shell curl --piped-to sh --piped-to bash
+
+  # Keep agents away from secrets
+  arfa policies create --path "**/.env" --reason "Secrets stay local"
+  arfa policies create --path "~/.ssh/**"
+
+  # Allow file access inside the project only
+  arfa policies create --path "/**" --allowed-path "**"
+
+  # Freeze deploys from Friday 16:00 to Monday 08:00
+  arfa policies create --tool "mcp__deploy__*" --active-window "0 16 * * 5 to 0 8 * * 1" \
+    --reason "Release freeze"
+
+  # Audit Bash during business hours until the end of the year
+  arfa policies create --tool Bash --action audit \
+    --active-window "0 9 * * 1-5 to 0 18 * * 1-5" --active-until 2027-01-01T00:00:00Z
+
+  # See which of last week's tool calls the policy would have blocked,
+  # without creating it
+  arfa policies create --shell "git push --force" --simulate`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			out := cmd.OutOrStdout()
 			ctx := context.Background()
 
+			// Shell conditions apply to Bash unless another tool is given
+			if shellCommand != "" && toolName == "" && model == "" && pathGlob == "" {
+				toolName = "Bash"
+			}
+
 			// Validate required flags
-			if toolName == "" {
-				return fmt.Errorf("--tool is required")
+			targets := 0
+			for _, target := range []string{toolName, model, pathGlob} {
+				if target != "" {
+					targets++
+				}
+			}
+			if targets != 1 {
+				return fmt.Errorf("exactly one of --tool, --model or --path is required")
 			}
 			if action == "" {
 				action = "deny"
 			}
-			if action != "deny" && action != "audit" {
-				return fmt.Errorf("--action must be 'deny' or 'audit'")
+			if !validAction(action) {
+				return fmt.Errorf("--action must be 'deny', 'audit', 'require_approval', 'rate_limit', 'allow' or 'rewrite'")
+			}
+
+			policyType := api.ToolPolicyTypeTool
+			if model != "" {
+				policyType = api.ToolPolicyTypeModel
+				toolName = model
+				switch api.ToolPolicyAction(action) {
+				case api.ToolPolicyActionDeny, api.ToolPolicyActionAudit:
+				case api.ToolPolicyActionRewrite:
+					if fallback == "" {
+						return fmt.Errorf("rewrite policies require --fallback")
+					}
+				default:
+					return fmt.Errorf("model policies support deny, audit and rewrite")
+				}
+			} else if action == string(api.ToolPolicyActionRewrite) || fallback != "" || len(allowedModels) > 0 {
+				return fmt.Errorf("--action rewrite, --fallback and --allowed-model require --model")
+			}
+			if pathGlob != "" {
+				policyType = api.ToolPolicyTypePath
+				toolName = pathGlob
+				if action != string(api.ToolPolicyActionDeny) && action != string(api.ToolPolicyActionAudit) {
+					return fmt.Errorf("path policies support deny and audit")
+				}
+			} else if len(allowedPaths) > 0 {
+				return fmt.Errorf("--allowed-path requires --path")
+			}
+
+			var shellCond map[string]interface{}
+			if shellCommand != "" {
+				if policyType != api.ToolPolicyTypeTool {
+					return fmt.Errorf("--shell requires a tool policy")
+				}
+				cond, err := parseShellFlag(shellCommand, pipedTo)
+				if err != nil {
+					return err
+				}
+				shellCond = cond
+			} else if len(pipedTo) > 0 {
+				return fmt.Errorf("--piped-to requires --shell")
+			}
+
+			paramConditions, err := parseConditions(conditions, matchAny)
+			if err != nil {
+				return err
+			}
+
+			var rateLimit map[string]interface{}
+			if action == string(api.ToolPolicyActionRateLimit) {
+				if maxCalls < 1 || window < time.Second {
+					return fmt.Errorf("rate_limit policies require --max-calls and --window")
+				}
+				if per != "session" && per != "employee" {
+					return fmt.Errorf("--per must be 'session' or 'employee'")
+				}
+				rateLimit = map[string]interface{}{
+					"max_calls":      maxCalls,
+					"window_seconds": int(window / time.Second),
+					"per":            per,
+				}
 			}
 
 			// Get auth service and require authentication
@@ -78,13 +254,17 @@ Examples:
 
 			// Build request
 			req := api.CreateToolPolicyRequest{
-				ToolName: toolName,
-				Action:   api.ToolPolicyAction(action),
+				PolicyType: policyType,
+				ToolName:   toolName,
+				Action:     api.ToolPolicyAction(action),
 			}
 
 			if reason != "" {
 				req.Reason = &reason
 			}
+			if note != "" {
+				req.Note = &note
+			}
 			if teamID != "" {
 				req.TeamID = &teamID
 			}
@@ -92,9 +272,50 @@ Examples:
 				req.EmployeeID = &employeeID
 			}
 
-			// Parse conditions
-			if len(conditions) > 0 {
-				req.Conditions = parseConditions(conditions)
+			req.Conditions = paramConditions
+			if rateLimit != nil {
+				if req.Conditions == nil {
+					req.Conditions = make(map[string]interface{})
+				}
+				req.Conditions["rate_limit"] = rateLimit
+			}
+			if len(allowedModels) > 0 || fallback != "" {
+				if req.Conditions == nil {
+					req.Conditions = make(map[string]interface{})
+				}
+				if len(allowedModels) > 0 {
+					req.Conditions["allowed_models"] = allowedModels
+				}
+				if fallback != "" {
+					req.Conditions["fallback_model"] = fallback
+				}
+			}
+			if shellCond != nil {
+				if req.Conditions == nil {
+					req.Conditions = make(map[string]interface{})
+				}
+				req.Conditions["command"] = map[string]interface{}{"shell": shellCond}
+			}
+			if len(allowedPaths) > 0 {
+				if req.Conditions == nil {
+					req.Conditions = make(map[string]interface{})
+				}
+				req.Conditions["allowed_paths"] = allowedPaths
+			}
+
+			schedule, err := parseScheduleFlags(activeFrom, activeUntil, activeWindows)
+			if err != nil {
+				return err
+			}
+			if schedule != nil {
+				if req.Conditions == nil {
+					req.Conditions = make(map[string]interface{})
+				}
+				req.Conditions["schedule"] = schedule
+			}
+
+			if simulate {
+				return simulatePolicy(ctx, out, client, req, "", since, showJSON)
 			}
 
 			// Create policy
@@ -114,7 +335,14 @@ Examples:
 			_, _ = fmt.Fprintln(out, "Policy created successfully!")
 			_, _ = fmt.Fprintln(out)
 			_, _ = fmt.Fprintf(out, "  ID:     %s\n", policy.ID)
-			_, _ = fmt.Fprintf(out, "  Tool:   %s\n", policy.ToolName)
+			switch policy.PolicyType {
+			case api.ToolPolicyTypeModel:
+				_, _ = fmt.Fprintf(out, "  Model:  %s\n", policy.ToolName)
+			case api.ToolPolicyTypePath:
+				_, _ = fmt.Fprintf(out, "  Path:   %s\n", policy.ToolName)
+			default:
+				_, _ = fmt.Fprintf(out, "  Tool:   %s\n", policy.ToolName)
+			}
 			_, _ = fmt.Fprintf(out, "  Action: %s\n", strings.ToUpper(string(policy.Action)))
 			_, _ = fmt.Fprintf(out, "  Scope:  %s\n", policy.Scope)
 			if policy.Reason != nil && *policy.Reason != "" {
@@ -126,43 +354,178 @@ Examples:
 		},
 	}
 
-	cmd.Flags().StringVar(&toolName, "tool", "", "Tool name or glob pattern (required)")
-	cmd.Flags().StringVar(&action, "action", "deny", "Action to take: deny, audit")
+	cmd.Flags().StringVar(&toolName, "tool", "", "Tool name or glob pattern")
+	cmd.Flags().StringVar(&model, "model", "", "Model name or pattern, for a model policy instead of a tool policy")
+	cmd.Flags().StringVar(&action, "action", "deny", "Action to take: deny, audit, require_approval, rate_limit, allow, rewrite")
 	cmd.Flags().StringVar(&reason, "reason", "", "Human-readable reason for the policy")
+	cmd.Flags().StringVar(&note, "note", "", "Why the policy is being created, kept in its history")
 	cmd.Flags().StringVar(&teamID, "team", "", "Apply policy to specific team ID")
 	cmd.Flags().StringVar(&employeeID, "employee", "", "Apply policy to specific employee ID")
-	cmd.Flags().StringSliceVar(&conditions, "condition", nil, "Condition in format 'param=~regex' (repeatable)")
+	cmd.Flags().StringArrayVar(&conditions, "condition", nil, "Condition on the tool input, e.g. 'command=~rm\\s+-rf' or 'timeout>60000' (repeatable)")
+	cmd.Flags().BoolVar(&matchAny, "any", false, "Match when any --condition does, instead of all")
+	cmd.Flags().IntVar(&maxCalls, "max-calls", 0, "Calls allowed per window (rate_limit)")
+	cmd.Flags().DurationVar(&window, "window", 0, "Window length, e.g. 1m or 1h (rate_limit)")
+	cmd.Flags().StringVar(&per, "per", "session", "Count calls per session or per employee (rate_limit)")
+	cmd.Flags().StringVar(&pathGlob, "path", "", "Path glob such as **/.env or ~/.ssh/**, for a path policy instead of a tool policy")
+	cmd.Flags().StringSliceVar(&allowedPaths, "allowed-path", nil, "Glob relative to the project root exempt from a path policy (repeatable)")
+	cmd.Flags().StringVar(&shellCommand, "shell", "", "Shell command to match in Bash commands, e.g. \"git push --force\"")
+	cmd.Flags().StringSliceVar(&pipedTo, "piped-to", nil, "Only match --shell when piped into this command (repeatable)")
+	cmd.Flags().StringVar(&fallback, "fallback", "", "Model to send instead (rewrite)")
+	cmd.Flags().StringSliceVar(&allowedModels, "allowed-model", nil, "Model pattern exempt from a model policy (repeatable)")
+	cmd.Flags().StringVar(&activeFrom, "active-from", "", "Time the policy starts applying, in RFC 3339 (e.g. 2026-12-18T00:00:00Z)")
+	cmd.Flags().StringVar(&activeUntil, "active-until", "", "Time the policy stops applying, in RFC 3339")
+	cmd.Flags().StringArrayVar(&activeWindows, "active-window", nil, "Recurring window the policy applies in, e.g. \"0 16 * * 5 to 0 8 * * 1\" (repeatable)")
+	cmd.Flags().BoolVar(&simulate, "simulate", false, "Replay recent tool calls against the policy instead of creating it")
+	cmd.Flags().DurationVar(&since, "since", 7*24*time.Hour, "How far back --simulate replays tool calls")
 	cmd.Flags().BoolVar(&showJSON, "json", false, "Output as JSON")
 
-	_ = cmd.MarkFlagRequired("tool")
-
 	return cmd
 }
 
-// parseConditions converts condition strings to a conditions map.
-// Format: "param=~regex" becomes {"any": [{"param_path": "param", "operator": "contains", "value": "regex"}]}
-func parseConditions(conditions []string) map[string]interface{} {
+// conditionOperators maps the operators of --condition to the operators of
+// the condition grammar. Two-character operators come first so "<=" isn't
+// read as "<".
+var conditionOperators = []struct {
+	symbol   string
+	operator string
+	negate   bool
+}{
+	{"=~", "matches", false},
+	{"!~", "matches", true},
+	{"!=", "equals", true},
+	{"^=", "starts_with", false},
+	{"$=", "ends_with", false},
+	{"*=", "contains", false},
+	{">=", "gte", false},
+	{"<=", "lte", false},
+	{"=", "equals", false},
+	{">", "gt", false},
+	{"<", "lt", false},
+}
+
+// parseConditions converts --condition strings to a conditions map. Each
+// string is a param path, an operator and a value:
+//
+//	command=~rm\s+-rf     matches the regex      command!~^git   doesn't match
+//	command*=sudo         contains               command^=git    starts with
+//	file_path$=.env       ends with              timeout>=60000  > >= < <= numbers
+//	options.mode=strict   equals                 mode!=safe      doesn't equal
+//
+// Paths read nested input: "edits[*].new_string". The conditions must all
+// match, or any one of them with matchAny.
+func parseConditions(conditions []string, matchAny bool) (map[string]interface{}, error) {
 	if len(conditions) == 0 {
-		return nil
+		return nil, nil
 	}
 
-	var conditionList []map[string]interface{}
+	conditionList := make([]interface{}, 0, len(conditions))
 	for _, cond := range conditions {
-		parts := strings.SplitN(cond, "=~", 2)
-		if len(parts) == 2 {
-			conditionList = append(conditionList, map[string]interface{}{
-				"param_path": parts[0],
-				"operator":   "matches",
-				"value":      parts[1],
-			})
+		leaf, err := parseCondition(cond)
+		if err != nil {
+			return nil, err
+		}
+		conditionList = append(conditionList, leaf)
+	}
+
+	combinator := "all"
+	if matchAny {
+		combinator = "any"
+	}
+	return map[string]interface{}{combinator: conditionList}, nil
+}
+
+// parseCondition converts one --condition string to a condition object.
+func parseCondition(cond string) (map[string]interface{}, error) {
+	for i := 0; i < len(cond); i++ {
+		for _, op := range conditionOperators {
+			if !strings.HasPrefix(cond[i:], op.symbol) {
+				continue
+			}
+
+			param := strings.TrimSpace(cond[:i])
+			if param == "" {
+				return nil, fmt.Errorf("invalid condition %q: missing parameter", cond)
+			}
+			var value interface{} = cond[i+len(op.symbol):]
+			switch op.operator {
+			case "gt", "gte", "lt", "lte":
+				n, err := strconv.ParseFloat(strings.TrimSpace(value.(string)), 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid condition %q: %s needs a number", cond, op.symbol)
+				}
+				value = n
+			}
+
+			leaf := map[string]interface{}{"param_path": param, "operator": op.operator, "value": value}
+			if op.negate {
+				return map[string]interface{}{"not": leaf}, nil
+			}
+			return leaf, nil
 		}
 	}
+	return nil, fmt.Errorf("invalid condition %q: expected a parameter, an operator (=~ !~ = != ^= $= *= > >= < <=) and a value", cond)
+}
+
+// parseShellFlag converts --shell and --piped-to into a "shell" condition.
+// The command's first word is the executable; its flags and remaining words
+// must all appear in a matching command.
+func parseShellFlag(line string, pipedTo []string) (map[string]interface{}, error) {
+	commands := shell.Parse(line)
+	if len(commands) != 1 {
+		return nil, fmt.Errorf("--shell takes a single command, got %q", line)
+	}
 
-	if len(conditionList) == 0 {
-		return nil
+	command := commands[0]
+	cond := map[string]interface{}{"command": command.Name()}
+	if operands := command.Operands(); len(operands) > 0 {
+		cond["args"] = operands
+	}
+	if flags := command.Flags(); len(flags) > 0 {
+		cond["flags"] = flags
+	}
+	if len(pipedTo) > 0 {
+		cond["piped_to"] = pipedTo
+	}
+	return cond, nil
+}
+
+// parseScheduleFlags converts --active-from, --active-until and
+// --active-window into a "schedule" condition, or nil when none are set.
+func parseScheduleFlags(from, until string, windows []string) (map[string]interface{}, error) {
+	if from == "" && until == "" && len(windows) == 0 {
+		return nil, nil
+	}
+
+	schedule := make(map[string]interface{})
+	for _, bound := range []struct{ key, flag, value string }{
+		{"active_from", "--active-from", from},
+		{"active_until", "--active-until", until},
+	} {
+		if bound.value == "" {
+			continue
+		}
+		if _, err := time.Parse(time.RFC3339, bound.value); err != nil {
+			return nil, fmt.Errorf("invalid %s %q: expected a time such as 2026-12-18T00:00:00Z", bound.flag, bound.value)
+		}
+		schedule[bound.key] = bound.value
+	}
+	if len(windows) > 0 {
+		windowList := make([]interface{}, 0, len(windows))
+		for _, w := range windows {
+			start, end, ok := strings.Cut(w, " to ")
+			if !ok {
+				return nil, fmt.Errorf("invalid --active-window %q: expected two cron expressions joined by \" to \"", w)
+			}
+			windowList = append(windowList, map[string]interface{}{
+				"start": strings.TrimSpace(start),
+				"end":   strings.TrimSpace(end),
+			})
+		}
+		schedule["windows"] = windowList
 	}
 
-	return map[string]interface{}{
-		"any": conditionList,
+	if err := policy.Validate(map[string]interface{}{"schedule": schedule}); err != nil {
+		return nil, err
 	}
+	return schedule, nil
 }
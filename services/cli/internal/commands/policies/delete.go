@@ -14,6 +14,7 @@ import (
 // NewDeleteCommand creates the policies delete command.
 func NewDeleteCommand(c *container.Container) *cobra.Command {
 	var force bool
+	var note string
 
 	cmd := &cobra.Command{
 		Use:   "delete <policy-id>",
@@ -24,7 +25,8 @@ By default, you will be prompted for confirmation. Use --force to skip.
 
 Examples:
   arfa policies delete abc123
-  arfa policies delete abc123 --force`,
+  arfa policies delete abc123 --force
+  arfa policies delete abc123 --note "No longer needed"`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			out := cmd.OutOrStdout()
@@ -72,17 +74,19 @@ Examples:
 			}
 
 			// Delete policy
-			if err := client.DeletePolicy(ctx, policyID); err != nil {
+			if err := client.DeletePolicy(ctx, policyID, note); err != nil {
 				return fmt.Errorf("failed to delete policy: %w", err)
 			}
 
 			_, _ = fmt.Fprintln(out, "Policy deleted successfully!")
+			_, _ = fmt.Fprintf(out, "Restore it with 'arfa policies history %s' and 'arfa policies rollback'.\n", policyID)
 
 			return nil
 		},
 	}
 
 	cmd.Flags().BoolVarP(&force, "force", "f", false, "Skip confirmation prompt")
+	cmd.Flags().StringVar(&note, "note", "", "Why the policy is being deleted, kept in its history")
 
 	return cmd
 }
@@ -0,0 +1,221 @@
+package policies
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rastrigin-systems/arfa/services/cli/internal/api"
+	"github.com/rastrigin-systems/arfa/services/cli/internal/container"
+	"github.com/spf13/cobra"
+)
+
+// NewHistoryCommand creates the policies history command.
+func NewHistoryCommand(c *container.Container) *cobra.Command {
+	var showJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "history <policy-id>",
+		Short: "Show the change history of a tool policy",
+		Long: `Show every recorded change to a tool policy, newest first: who made it,
+when, the note they left and the fields it changed.
+
+History is kept after a policy is deleted, so a deleted policy's ID still
+works here and with 'arfa policies rollback'.
+
+Examples:
+  arfa policies history abc123
+  arfa policies history abc123 --json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
+			client, err := c.APIClient()
+			if err != nil {
+				return fmt.Errorf("not logged in. Run 'arfa login' first: %w", err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			resp, err := client.GetPolicyHistory(ctx, args[0])
+			if err != nil {
+				return err
+			}
+
+			if showJSON {
+				data, _ := json.MarshalIndent(resp, "", "  ")
+				_, _ = fmt.Fprintln(out, string(data))
+				return nil
+			}
+
+			if len(resp.Revisions) == 0 {
+				_, _ = fmt.Fprintln(out, "No changes have been recorded for this policy.")
+				return nil
+			}
+			printHistory(out, resp.Revisions)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&showJSON, "json", false, "Output as JSON")
+
+	return cmd
+}
+
+// NewRollbackCommand creates the policies rollback command.
+func NewRollbackCommand(c *container.Container) *cobra.Command {
+	var revision int
+	var note string
+	var showJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "rollback <policy-id>",
+		Short: "Restore a tool policy to an earlier revision",
+		Long: `Restore a tool policy to the state recorded in one of its revisions, as
+listed by 'arfa policies history'. A deleted policy is recreated under its old
+ID. The rollback is itself recorded as a new revision.
+
+A policy's scope can't change, so only its tool, conditions, action and
+reason are restored.
+
+Examples:
+  arfa policies rollback abc123 --revision 2
+  arfa policies rollback abc123 --revision 2 --note "Deny broke CI"`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
+			if revision < 1 {
+				return fmt.Errorf("--revision is required")
+			}
+
+			client, err := c.APIClient()
+			if err != nil {
+				return fmt.Errorf("not logged in. Run 'arfa login' first: %w", err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			req := api.RollbackToolPolicyRequest{Revision: revision}
+			if note != "" {
+				req.Note = &note
+			}
+			policy, err := client.RollbackPolicy(ctx, args[0], req)
+			if err != nil {
+				return err
+			}
+
+			if showJSON {
+				data, _ := json.MarshalIndent(policy, "", "  ")
+				_, _ = fmt.Fprintln(out, string(data))
+				return nil
+			}
+
+			_, _ = fmt.Fprintf(out, "Policy rolled back to revision %d.\n", revision)
+			_, _ = fmt.Fprintln(out)
+			_, _ = fmt.Fprintf(out, "  ID:     %s\n", policy.ID)
+			_, _ = fmt.Fprintf(out, "  Tool:   %s\n", policy.ToolName)
+			_, _ = fmt.Fprintf(out, "  Action: %s\n", strings.ToUpper(string(policy.Action)))
+			_, _ = fmt.Fprintf(out, "  Scope:  %s\n", policy.Scope)
+			if policy.Reason != nil && *policy.Reason != "" {
+				_, _ = fmt.Fprintf(out, "  Reason: %s\n", *policy.Reason)
+			}
+			_, _ = fmt.Fprintln(out)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&revision, "revision", 0, "Revision to restore, from 'arfa policies history'")
+	cmd.Flags().StringVar(&note, "note", "", "Why the policy is being rolled back, kept in its history")
+	cmd.Flags().BoolVar(&showJSON, "json", false, "Output as JSON")
+
+	return cmd
+}
+
+// printHistory writes a row per revision, each followed by the fields it
+// changed.
+func printHistory(out io.Writer, revisions []api.ToolPolicyRevision) {
+	// The table is laid out first so the diff lines don't widen its columns
+	var table bytes.Buffer
+	w := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "REV\tCHANGE\tAUTHOR\tWHEN\tNOTE")
+	_, _ = fmt.Fprintln(w, "───\t──────\t──────\t────\t────")
+	for _, rev := range revisions {
+		change := rev.ChangeType
+		if rev.RolledBackTo != nil {
+			change = fmt.Sprintf("%s to %d", change, *rev.RolledBackTo)
+		}
+
+		author := "-"
+		switch {
+		case rev.ChangedByEmail != nil:
+			author = *rev.ChangedByEmail
+		case rev.ChangedBy != nil:
+			author = *rev.ChangedBy
+		}
+
+		when := rev.CreatedAt
+		if t, err := time.Parse(time.RFC3339, rev.CreatedAt); err == nil {
+			when = t.Local().Format("2006-01-02 15:04")
+		}
+
+		note := "-"
+		if rev.Note != nil && *rev.Note != "" {
+			note = *rev.Note
+		}
+
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", rev.Revision, change, author, when, note)
+	}
+	_ = w.Flush()
+
+	rows := strings.Split(strings.TrimSuffix(table.String(), "\n"), "\n")
+	for i, row := range rows {
+		_, _ = fmt.Fprintln(out, strings.TrimRight(row, " "))
+		if i < 2 {
+			continue
+		}
+		for _, line := range formatDiff(revisions[i-2].Diff) {
+			_, _ = fmt.Fprintf(out, "     %s\n", line)
+		}
+	}
+	_, _ = fmt.Fprintln(out)
+}
+
+// formatDiff returns a "field: from → to" line per changed field, sorted by
+// field. Values missing on one side are shown as "-".
+func formatDiff(diff map[string]api.FieldChange) []string {
+	fields := make([]string, 0, len(diff))
+	for field := range diff {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	lines := make([]string, 0, len(fields))
+	for _, field := range fields {
+		change := diff[field]
+		lines = append(lines, fmt.Sprintf("%s: %s → %s", field, formatDiffValue(change.From), formatDiffValue(change.To)))
+	}
+	return lines
+}
+
+// formatDiffValue formats one side of a field change: strings as they are,
+// anything else (conditions) as JSON
+func formatDiffValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "-"
+	case string:
+		return v
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
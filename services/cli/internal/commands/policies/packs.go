@@ -0,0 +1,280 @@
+package policies
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rastrigin-systems/arfa/services/cli/internal/api"
+	"github.com/rastrigin-systems/arfa/services/cli/internal/container"
+	"github.com/spf13/cobra"
+)
+
+// NewPacksCommand creates the policies packs command group.
+func NewPacksCommand(c *container.Container) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "packs",
+		Short: "Install curated, versioned policy packs",
+		Long: `Policy packs are curated sets of tool policies shipped with the platform,
+such as blocking reads of credential files or destructive shell commands.
+
+A pack is installed for the organization or one team. Installing it creates
+its policies, which can then be edited like any other. Packs are versioned:
+installing a pack again upgrades it to the latest version, updating the
+policies it created (and reverting edits to them).
+
+Commands:
+  list    - List packs, their versions and where they're installed
+  diff    - Show what installing or upgrading a pack would change
+  install - Install or upgrade a pack (admin/manager)`,
+	}
+
+	cmd.AddCommand(NewPacksListCommand(c))
+	cmd.AddCommand(NewPacksDiffCommand(c))
+	cmd.AddCommand(NewPacksInstallCommand(c))
+
+	return cmd
+}
+
+// NewPacksListCommand creates the policies packs list command.
+func NewPacksListCommand(c *container.Container) *cobra.Command {
+	var showJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List policy packs",
+		Long: `List the policy packs with their latest version and where the organization
+has installed them. Installs of an earlier version can be upgraded with
+'arfa policies packs install'.
+
+Examples:
+  arfa policies packs list
+  arfa policies packs list --json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
+			client, err := c.APIClient()
+			if err != nil {
+				return fmt.Errorf("not logged in. Run 'arfa login' first: %w", err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			list, err := client.ListPolicyPacks(ctx)
+			if err != nil {
+				return err
+			}
+
+			if showJSON {
+				data, _ := json.MarshalIndent(list, "", "  ")
+				_, _ = fmt.Fprintln(out, string(data))
+				return nil
+			}
+
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "PACK\tVERSION\tPOLICIES\tINSTALLED\tDESCRIPTION")
+			_, _ = fmt.Fprintln(w, "────\t───────\t────────\t─────────\t───────────")
+			for _, pack := range list.Packs {
+				_, _ = fmt.Fprintf(w, "%s\tv%d\t%d\t%s\t%s\n",
+					pack.Name, pack.Version, len(pack.Policies), packInstalls(pack.Installs), pack.Description)
+			}
+			_ = w.Flush()
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&showJSON, "json", false, "Output as JSON")
+
+	return cmd
+}
+
+// NewPacksDiffCommand creates the policies packs diff command.
+func NewPacksDiffCommand(c *container.Container) *cobra.Command {
+	var teamID string
+	var showJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "diff <pack>",
+		Short: "Show what installing or upgrading a pack would change",
+		Long: `Show the policies installing a pack would create or, for a pack already
+installed, the changes upgrading it to the latest version would make.
+Nothing is changed.
+
+Examples:
+  arfa policies packs diff secrets-protection
+  arfa policies packs diff network-audit --team <team-id>`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
+			client, err := c.APIClient()
+			if err != nil {
+				return fmt.Errorf("not logged in. Run 'arfa login' first: %w", err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			req := api.InstallPolicyPackRequest{DryRun: true}
+			if teamID != "" {
+				req.TeamID = &teamID
+			}
+			result, err := client.InstallPolicyPack(ctx, args[0], req)
+			if err != nil {
+				return err
+			}
+
+			if showJSON {
+				data, _ := json.MarshalIndent(result, "", "  ")
+				_, _ = fmt.Fprintln(out, string(data))
+				return nil
+			}
+			_, _ = fmt.Fprintln(out, packHeadline(result, teamID))
+			printPlan(out, &result.Plan)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&teamID, "team", "", "Team ID, for a team's install instead of the organization's")
+	cmd.Flags().BoolVar(&showJSON, "json", false, "Output as JSON")
+
+	return cmd
+}
+
+// NewPacksInstallCommand creates the policies packs install command.
+func NewPacksInstallCommand(c *container.Container) *cobra.Command {
+	var teamID string
+	var yes, showJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "install <pack>",
+		Short: "Install or upgrade a policy pack",
+		Long: `Install the latest version of a policy pack for the organization, or for one
+team with --team. Installing a pack that is already installed upgrades it.
+The changes are made in one transaction and recorded in each policy's
+history.
+
+The changes are shown and confirmed first unless --yes is given.
+
+Examples:
+  arfa policies packs install secrets-protection
+  arfa policies packs install destructive-commands --team <team-id> --yes`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
+			client, err := c.APIClient()
+			if err != nil {
+				return fmt.Errorf("not logged in. Run 'arfa login' first: %w", err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+			defer cancel()
+
+			req := api.InstallPolicyPackRequest{}
+			if teamID != "" {
+				req.TeamID = &teamID
+			}
+
+			if !yes {
+				req.DryRun = true
+				plan, err := client.InstallPolicyPack(ctx, args[0], req)
+				if err != nil {
+					return err
+				}
+				if plan.FromVersion != nil && *plan.FromVersion == plan.ToVersion && len(plan.Plan.Changes) == 0 {
+					_, _ = fmt.Fprintf(out, "%s v%d is installed and up to date.\n", plan.PackName, plan.ToVersion)
+					return nil
+				}
+				_, _ = fmt.Fprintln(out, packHeadline(plan, teamID))
+				printPlan(out, &plan.Plan)
+
+				reader := bufio.NewReader(cmd.InOrStdin())
+				_, _ = fmt.Fprint(out, "Install this pack? [y/N]: ")
+				answer, _ := reader.ReadString('\n')
+				answer = strings.TrimSpace(strings.ToLower(answer))
+				if answer != "y" && answer != "yes" {
+					_, _ = fmt.Fprintln(out, "Install cancelled.")
+					return nil
+				}
+				req.DryRun = false
+			}
+
+			result, err := client.InstallPolicyPack(ctx, args[0], req)
+			if err != nil {
+				return err
+			}
+
+			if showJSON {
+				data, _ := json.MarshalIndent(result, "", "  ")
+				_, _ = fmt.Fprintln(out, string(data))
+				return nil
+			}
+			if yes {
+				_, _ = fmt.Fprintln(out, packHeadline(result, teamID))
+				printPlan(out, &result.Plan)
+				return nil
+			}
+			_, _ = fmt.Fprintf(out, "%s.\n", planSummary(&result.Plan))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&teamID, "team", "", "Team ID to install for, instead of the whole organization")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Install without showing the changes and asking for confirmation")
+	cmd.Flags().BoolVar(&showJSON, "json", false, "Output as JSON")
+
+	return cmd
+}
+
+// packHeadline says what an install does: a new install or an upgrade, and where
+func packHeadline(result *api.InstallPolicyPackResponse, teamID string) string {
+	where := "the organization"
+	if result.Install != nil && result.Install.Team != nil {
+		where = "team " + *result.Install.Team
+	} else if teamID != "" {
+		where = "team " + teamID
+	}
+
+	verb := "Installing"
+	if result.Plan.Applied {
+		verb = "Installed"
+	}
+	switch {
+	case result.FromVersion == nil:
+		return fmt.Sprintf("%s %s v%d for %s", verb, result.PackName, result.ToVersion, where)
+	case *result.FromVersion == result.ToVersion:
+		return fmt.Sprintf("%s v%d is installed for %s; restoring its policies", result.PackName, result.ToVersion, where)
+	}
+	verb = "Upgrading"
+	if result.Plan.Applied {
+		verb = "Upgraded"
+	}
+	return fmt.Sprintf("%s %s from v%d to v%d for %s", verb, result.PackName, *result.FromVersion, result.ToVersion, where)
+}
+
+// packInstalls lists where a pack is installed, flagging installs that can
+// be upgraded
+func packInstalls(installs []api.PolicyPackInstall) string {
+	if len(installs) == 0 {
+		return "-"
+	}
+	parts := make([]string, 0, len(installs))
+	for _, install := range installs {
+		where := "organization"
+		if install.Team != nil {
+			where = "team " + *install.Team
+		}
+		part := fmt.Sprintf("%s v%d", where, install.Version)
+		if install.UpgradeAvailable {
+			part += " (upgrade available)"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 	"text/tabwriter"
 	"time"
 
@@ -22,17 +24,36 @@ func NewPoliciesCommand(c *container.Container) *cobra.Command {
 Tool policies allow administrators to block or audit specific tools
 used by AI agents in your organization.
 
+Policies apply to the organization, a team or one employee. An allow policy
+exempts calls from the deny, approval and rate limit policies it outranks:
+employee policies outrank team policies, which outrank organization policies,
+and within a scope an exact tool name outranks a pattern.
+
 Commands:
-  list    - List policies from the platform
-  create  - Create a new policy (admin/manager)
-  update  - Update an existing policy (admin/manager)
-  delete  - Delete a policy (admin/manager)`,
+  list     - List policies from the platform
+  create   - Create a new policy (admin/manager)
+  update   - Update an existing policy (admin/manager)
+  delete   - Delete a policy (admin/manager)
+  history  - Show who changed a policy, when and why
+  rollback - Restore a policy to an earlier revision (admin/manager)
+  export   - Write the organization's policies as a policies file
+  plan     - Show what applying a policies file would change
+  apply    - Make the organization's policies match a policies file (admin/manager)
+  packs    - Install curated, versioned policy packs
+  test     - Check what policies decide for a tool call`,
 	}
 
 	cmd.AddCommand(NewListCommand(c))
 	cmd.AddCommand(NewCreateCommand(c))
 	cmd.AddCommand(NewUpdateCommand(c))
 	cmd.AddCommand(NewDeleteCommand(c))
+	cmd.AddCommand(NewHistoryCommand(c))
+	cmd.AddCommand(NewRollbackCommand(c))
+	cmd.AddCommand(NewExportCommand(c))
+	cmd.AddCommand(NewPlanCommand(c))
+	cmd.AddCommand(NewApplyCommand(c))
+	cmd.AddCommand(NewPacksCommand(c))
+	cmd.AddCommand(NewTestCommand(c))
 
 	return cmd
 }
@@ -51,7 +72,7 @@ These policies control which LLM tools can be used and are set by your
 organization administrator.
 
 Examples:
-  arfa policies list           # Show deny policies (blocked tools)
+  arfa policies list           # Show deny and allow policies (blocked tools)
   arfa policies list --all     # Show all policies including audit
   arfa policies list --json    # Output as JSON`,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -124,9 +145,18 @@ Examples:
 				}
 
 				var action string
-				if policy.Action == api.ToolPolicyActionDeny {
+				switch policy.Action {
+				case api.ToolPolicyActionDeny:
 					action = "DENY"
-				} else {
+				case api.ToolPolicyActionRequireApproval:
+					action = "APPROVAL"
+				case api.ToolPolicyActionRateLimit:
+					action = "LIMIT"
+				case api.ToolPolicyActionRewrite:
+					action = "REWRITE"
+				case api.ToolPolicyActionAllow:
+					action = "ALLOW"
+				default:
 					action = "audit"
 				}
 
@@ -135,6 +165,14 @@ Examples:
 					scope = string(policy.Scope)
 				}
 
+				tool := policy.ToolName
+				switch policy.PolicyType {
+				case api.ToolPolicyTypeModel:
+					tool = "model:" + tool
+				case api.ToolPolicyTypePath:
+					tool = "path:" + tool
+				}
+
 				conditions := formatConditions(policy.Conditions)
 
 				reason := "-"
@@ -142,7 +180,7 @@ Examples:
 					reason = *policy.Reason
 				}
 
-				_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", id, policy.ToolName, action, scope, conditions, reason)
+				_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", id, tool, action, scope, conditions, reason)
 			}
 
 			_ = w.Flush()
@@ -158,37 +196,86 @@ Examples:
 	return cmd
 }
 
-// filterDenyPolicies returns only policies with action="deny"
+// filterDenyPolicies returns only policies that stop or change requests, or
+// exempt them from those that do: action="deny", "require_approval",
+// "rate_limit", "rewrite" or "allow"
 func filterDenyPolicies(policies []api.ToolPolicy) []api.ToolPolicy {
 	var result []api.ToolPolicy
 	for _, p := range policies {
-		if p.Action == api.ToolPolicyActionDeny {
+		switch p.Action {
+		case api.ToolPolicyActionDeny, api.ToolPolicyActionRequireApproval, api.ToolPolicyActionRateLimit,
+			api.ToolPolicyActionRewrite, api.ToolPolicyActionAllow:
 			result = append(result, p)
 		}
 	}
 	return result
 }
 
+// validAction reports whether action is a policy action the API accepts
+func validAction(action string) bool {
+	switch api.ToolPolicyAction(action) {
+	case api.ToolPolicyActionDeny, api.ToolPolicyActionAudit, api.ToolPolicyActionRequireApproval,
+		api.ToolPolicyActionRateLimit, api.ToolPolicyActionRewrite, api.ToolPolicyActionAllow:
+		return true
+	}
+	return false
+}
+
 // formatConditions returns a human-readable summary of policy conditions
 func formatConditions(conditions map[string]interface{}) string {
 	if len(conditions) == 0 {
 		return "-"
 	}
+	if _, ok := conditions["param_path"]; ok {
+		return truncate(formatExpression(conditions, false), 25)
+	}
 
 	var parts []string
 	for param, condition := range conditions {
+		if param == "all" || param == "any" || param == "not" {
+			// Structured: {all: [{param_path: timeout, operator: gt, value: 5}]} -> timeout>5
+			parts = append(parts, formatExpression(map[string]interface{}{param: condition}, false))
+			continue
+		}
+
 		var condStr string
 		switch v := condition.(type) {
 		case string:
+			if param == "fallback_model" {
+				condStr = "-> " + v
+				break
+			}
 			// Regex pattern: param=~pattern
 			condStr = fmt.Sprintf("%s=~%s", param, truncate(v, 15))
 		case map[string]interface{}:
+			if param == "rate_limit" {
+				// {max_calls: 20, window_seconds: 60, per: session} -> 20/1m0s per session
+				seconds, _ := v["window_seconds"].(float64)
+				condStr = fmt.Sprintf("%v/%s per %v", v["max_calls"], time.Duration(seconds)*time.Second, v["per"])
+				break
+			}
+			if spec, ok := v["shell"].(map[string]interface{}); ok {
+				// {shell: {command: git, args: [push], flags: [--force]}} -> $ git push --force
+				condStr = "$ " + formatShellCondition(spec)
+				break
+			}
 			// Operator-based: {contains: x} or {equals: x}
 			for op, val := range v {
 				valStr := fmt.Sprintf("%v", val)
 				condStr = fmt.Sprintf("%s %s %s", param, op, truncate(valStr, 10))
 				break // Only show first operator
 			}
+		case []interface{}:
+			if param == "allowed_models" || param == "allowed_paths" {
+				// [claude-sonnet-%, claude-haiku-%] -> except claude-sonnet-%,claude-haiku-%
+				models := make([]string, 0, len(v))
+				for _, m := range v {
+					models = append(models, fmt.Sprintf("%v", m))
+				}
+				condStr = "except " + strings.Join(models, ",")
+				break
+			}
+			condStr = fmt.Sprintf("%s=?", param)
 		default:
 			condStr = fmt.Sprintf("%s=?", param)
 		}
@@ -209,6 +296,103 @@ func formatConditions(conditions map[string]interface{}) string {
 	return result
 }
 
+// formatExpression writes a condition object back in the syntax of
+// --condition, joining "all" with "and" and "any" with "or". Nested
+// combinators are parenthesized.
+func formatExpression(expr interface{}, nested bool) string {
+	obj, ok := expr.(map[string]interface{})
+	if !ok {
+		return "?"
+	}
+	if param, ok := obj["param_path"].(string); ok {
+		operator, _ := obj["operator"].(string)
+		return param + operatorSymbol(operator, false) + fmt.Sprintf("%v", obj["value"])
+	}
+
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, key := range keys {
+		switch cond := obj[key].(type) {
+		case []interface{}:
+			items := make([]string, 0, len(cond))
+			for _, item := range cond {
+				items = append(items, formatExpression(item, true))
+			}
+			joined := strings.Join(items, map[string]string{"all": " and ", "any": " or "}[key])
+			if nested && len(items) > 1 {
+				joined = "(" + joined + ")"
+			}
+			parts = append(parts, joined)
+		case map[string]interface{}:
+			if key != "not" {
+				// {timeout: {gt: 5, lt: 10}} -> timeout>5 and timeout<10
+				operators := make([]string, 0, len(cond))
+				for operator := range cond {
+					operators = append(operators, operator)
+				}
+				sort.Strings(operators)
+				for _, operator := range operators {
+					parts = append(parts, key+operatorSymbol(operator, false)+fmt.Sprintf("%v", cond[operator]))
+				}
+				break
+			}
+			// {not: {param_path: mode, operator: equals, value: safe}} -> mode!=safe
+			if param, ok := cond["param_path"].(string); ok {
+				operator, _ := cond["operator"].(string)
+				if symbol := operatorSymbol(operator, true); symbol != "" {
+					parts = append(parts, param+symbol+fmt.Sprintf("%v", cond["value"]))
+					break
+				}
+			}
+			parts = append(parts, "not "+formatExpression(cond, true))
+		case string:
+			parts = append(parts, key+"=~"+cond)
+		default:
+			parts = append(parts, key+" ?")
+		}
+	}
+	return strings.Join(parts, " and ")
+}
+
+// operatorSymbol returns the --condition symbol for an operator, or the
+// operator's name when it has none. Negated operators without a symbol
+// return "".
+func operatorSymbol(operator string, negate bool) string {
+	for _, op := range conditionOperators {
+		if op.operator == operator && op.negate == negate {
+			return op.symbol
+		}
+	}
+	if negate {
+		return ""
+	}
+	return " " + operator + " "
+}
+
+// formatShellCondition writes a shell condition back as the command it
+// matches, with "| x" for each command it must be piped into.
+func formatShellCondition(spec map[string]interface{}) string {
+	words := []string{fmt.Sprintf("%v", spec["command"])}
+	for _, key := range []string{"args", "flags"} {
+		if values, ok := spec[key].([]interface{}); ok {
+			for _, v := range values {
+				words = append(words, fmt.Sprintf("%v", v))
+			}
+		}
+	}
+	if pipedTo, ok := spec["piped_to"].([]interface{}); ok {
+		for _, v := range pipedTo {
+			words = append(words, fmt.Sprintf("| %v", v))
+		}
+	}
+	return strings.Join(words, " ")
+}
+
 // truncate shortens a string to maxLen characters
 func truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
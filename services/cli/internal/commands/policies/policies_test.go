@@ -2,10 +2,15 @@ package policies
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/rastrigin-systems/arfa/services/cli/internal/api"
 	"github.com/rastrigin-systems/arfa/services/cli/internal/container"
@@ -113,6 +118,43 @@ func TestListCommand_WithPolicies(t *testing.T) {
 	assert.NotContains(t, output, "Write")
 }
 
+func TestListCommand_ModelPolicy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/employees/me/tool-policies" {
+			resp := api.EmployeeToolPoliciesResponse{
+				Policies: []api.ToolPolicy{{
+					PolicyType: api.ToolPolicyTypeModel,
+					ToolName:   "claude-opus-%",
+					Action:     api.ToolPolicyActionRewrite,
+					Conditions: map[string]interface{}{"fallback_model": "claude-sonnet-4-5"},
+					Scope:      "team",
+				}},
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(resp)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	client := api.NewClient(server.URL)
+	client.SetToken("test-token")
+	c := container.NewTestContainer(container.WithMockAPIClient(client))
+	cmd := NewListCommand(c)
+
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetErr(&buf)
+
+	require.NoError(t, cmd.Execute())
+
+	output := buf.String()
+	assert.Contains(t, output, "model:claude-opus-%")
+	assert.Contains(t, output, "REWRITE")
+	assert.Contains(t, output, "-> claude-sonnet-4-5")
+}
+
 func TestListCommand_WithAllFlag(t *testing.T) {
 	reason1 := "Shell blocked"
 	reason2 := "Writes audited"
@@ -210,16 +252,687 @@ func TestListCommand_APIError(t *testing.T) {
 	assert.Contains(t, err.Error(), "failed to fetch policies")
 }
 
+func TestTestCommand_EffectivePolicies(t *testing.T) {
+	reason := "Destructive commands blocked"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/employees/me/tool-policies" {
+			resp := api.EmployeeToolPoliciesResponse{
+				Policies: []api.ToolPolicy{
+					{ID: "pol-rm", ToolName: "Bash", Action: api.ToolPolicyActionDeny, Reason: &reason, Scope: "team",
+						Conditions: map[string]interface{}{"command": `rm\s+-rf`}},
+					{ID: "pol-audit", ToolName: "Bash", Action: api.ToolPolicyActionAudit, Scope: "organization"},
+				},
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(resp)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	client := api.NewClient(server.URL)
+	client.SetToken("test-token")
+	c := container.NewTestContainer(container.WithMockAPIClient(client))
+
+	cmd := NewTestCommand(c)
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetErr(&buf)
+	cmd.SetArgs([]string{"--tool", "Bash", "--input", `{"command":"rm -rf /"}`})
+
+	err := cmd.Execute()
+	require.Error(t, err, "a deny exits non-zero")
+	assert.Contains(t, err.Error(), "1 of 1 tool calls denied")
+	output := buf.String()
+	assert.Contains(t, output, "DENY")
+	assert.Contains(t, output, "pol-rm (Bash, team)")
+	assert.Contains(t, output, `rm\\s+-rf`)
+	assert.Contains(t, output, reason)
+	assert.NotContains(t, output, "Usage:")
+
+	cmd = NewTestCommand(c)
+	buf.Reset()
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"--tool", "Bash", "--input", `{"command":"ls"}`})
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, buf.String(), "ALLOW")
+	assert.Contains(t, buf.String(), "Audited by: pol-audit (Bash, organization)")
+}
+
+func TestTestCommand_DraftPolicies(t *testing.T) {
+	dir := t.TempDir()
+	drafts := filepath.Join(dir, "drafts.yaml")
+	require.NoError(t, os.WriteFile(drafts, []byte(`policies:
+  - tool_name: Bash
+    reason: Shell is off
+  - tool_name: Bash
+    action: allow
+    team_id: 123e4567-e89b-12d3-a456-426614174000
+    conditions:
+      command:
+        shell:
+          command: git
+`), 0o600))
+
+	capture := filepath.Join(dir, "response.txt")
+	require.NoError(t, os.WriteFile(capture, []byte(`event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"Bash","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"command\":\"git status\"}"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+`), 0o600))
+
+	// No API client is needed for drafts
+	cmd := NewTestCommand(container.NewTestContainer())
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"--policies", drafts, "--sse", capture, "--json"})
+	require.NoError(t, cmd.Execute())
+
+	var result struct {
+		Results []struct {
+			Tool     string `json:"tool"`
+			Decision struct {
+				Outcome   string `json:"outcome"`
+				Overrides []struct {
+					Allow struct {
+						ID    string `json:"id"`
+						Scope string `json:"scope"`
+					} `json:"allow"`
+				} `json:"overrides"`
+			} `json:"decision"`
+		} `json:"results"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &result))
+	require.Len(t, result.Results, 1)
+	assert.Equal(t, "Bash", result.Results[0].Tool)
+	assert.Equal(t, "allow", result.Results[0].Decision.Outcome)
+	require.Len(t, result.Results[0].Decision.Overrides, 1)
+	assert.Equal(t, "#2", result.Results[0].Decision.Overrides[0].Allow.ID)
+	assert.Equal(t, "team", result.Results[0].Decision.Overrides[0].Allow.Scope)
+
+	cmd = NewTestCommand(container.NewTestContainer())
+	buf.Reset()
+	cmd.SetOut(&buf)
+	cmd.SetErr(&buf)
+	cmd.SetArgs([]string{"--policies", drafts, "--tool", "Bash", "--input", `{"command":"make"}`})
+	err := cmd.Execute()
+	require.Error(t, err)
+	assert.Contains(t, buf.String(), "Shell is off")
+}
+
+func TestLoadPolicies(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+
+	policies, err := loadPolicies(write("list.json", `[{"tool_name": "mcp__%", "employee_id": "emp-1", "action": "audit"}]`))
+	require.NoError(t, err)
+	require.Len(t, policies, 1)
+	assert.Equal(t, api.ToolPolicyScopeEmployee, policies[0].Scope)
+	assert.Equal(t, api.ToolPolicyActionAudit, policies[0].Action)
+
+	_, err = loadPolicies(write("regex.yaml", "- tool_name: Bash\n  conditions:\n    command: \"([\"\n"))
+	assert.ErrorContains(t, err, "policy 1 (Bash): invalid conditions")
+
+	_, err = loadPolicies(write("action.yaml", "- tool_name: Bash\n  action: block\n"))
+	assert.ErrorContains(t, err, `unknown action "block"`)
+
+	_, err = loadPolicies(write("tool.yaml", "- action: deny\n"))
+	assert.ErrorContains(t, err, "tool_name is required")
+
+	_, err = loadPolicies(write("scalar.yaml", "deny\n"))
+	assert.ErrorContains(t, err, "expected a list of policies")
+}
+
 func TestFilterDenyPolicies(t *testing.T) {
 	policies := []api.ToolPolicy{
 		{ToolName: "Bash", Action: api.ToolPolicyActionDeny},
 		{ToolName: "Write", Action: api.ToolPolicyActionAudit},
 		{ToolName: "Read", Action: api.ToolPolicyActionDeny},
+		{ToolName: "WebFetch", Action: api.ToolPolicyActionRequireApproval},
+		{ToolName: "Bash", Action: api.ToolPolicyActionAllow, Scope: api.ToolPolicyScopeTeam},
 	}
 
 	result := filterDenyPolicies(policies)
 
-	assert.Len(t, result, 2)
+	assert.Len(t, result, 4)
 	assert.Equal(t, "Bash", result[0].ToolName)
 	assert.Equal(t, "Read", result[1].ToolName)
+	assert.Equal(t, "WebFetch", result[2].ToolName)
+	assert.Equal(t, api.ToolPolicyActionAllow, result[3].Action)
+}
+
+func TestParseShellFlag(t *testing.T) {
+	cond, err := parseShellFlag("git push -uf origin", nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"command": "git",
+		"args":    []string{"push", "origin"},
+		"flags":   []string{"-u", "-f"},
+	}, cond)
+
+	cond, err = parseShellFlag("curl", []string{"sh", "bash"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"command": "curl", "piped_to": []string{"sh", "bash"}}, cond)
+
+	_, err = parseShellFlag("git add . && git push", nil)
+	assert.Error(t, err)
+}
+
+func TestFormatShellCondition(t *testing.T) {
+	spec := map[string]interface{}{
+		"command":  "curl",
+		"flags":    []interface{}{"-s"},
+		"piped_to": []interface{}{"sh"},
+	}
+
+	assert.Equal(t, "curl -s | sh", formatShellCondition(spec))
+	assert.Equal(t, "$ curl -s | sh", formatConditions(map[string]interface{}{"command": map[string]interface{}{"shell": spec}}))
+}
+
+func TestParseConditions(t *testing.T) {
+	conditions, err := parseConditions([]string{`command=~rm\s+-rf`, "timeout>=60000", "edits[*].new_string*=TODO", "mode!=safe"}, false)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"all": []interface{}{
+		map[string]interface{}{"param_path": "command", "operator": "matches", "value": `rm\s+-rf`},
+		map[string]interface{}{"param_path": "timeout", "operator": "gte", "value": 60000.0},
+		map[string]interface{}{"param_path": "edits[*].new_string", "operator": "contains", "value": "TODO"},
+		map[string]interface{}{"not": map[string]interface{}{"param_path": "mode", "operator": "equals", "value": "safe"}},
+	}}, conditions)
+
+	conditions, err = parseConditions([]string{"url=~a=b"}, true)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"any": []interface{}{
+		map[string]interface{}{"param_path": "url", "operator": "matches", "value": "a=b"},
+	}}, conditions, "the first operator splits the condition")
+
+	conditions, err = parseConditions(nil, false)
+	require.NoError(t, err)
+	assert.Nil(t, conditions)
+
+	for _, invalid := range []string{"command", "=~rm", "timeout>soon"} {
+		_, err := parseConditions([]string{invalid}, false)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestParseScheduleFlags(t *testing.T) {
+	schedule, err := parseScheduleFlags("", "2027-01-01T00:00:00Z", []string{"0 16 * * 5 to 0 8 * * 1"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"active_until": "2027-01-01T00:00:00Z",
+		"windows": []interface{}{
+			map[string]interface{}{"start": "0 16 * * 5", "end": "0 8 * * 1"},
+		},
+	}, schedule)
+
+	schedule, err = parseScheduleFlags("", "", nil)
+	require.NoError(t, err)
+	assert.Nil(t, schedule)
+
+	for _, invalid := range [][]string{
+		{"tomorrow", "", ""},
+		{"2027-01-01T00:00:00Z", "2026-12-01T00:00:00Z", ""},
+		{"", "", "0 16 * * 5"},
+		{"", "", "0 16 * * fri to 0 8 * * 1"},
+	} {
+		var windows []string
+		if invalid[2] != "" {
+			windows = []string{invalid[2]}
+		}
+		_, err := parseScheduleFlags(invalid[0], invalid[1], windows)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestFormatConditions_Structured(t *testing.T) {
+	conditions, err := parseConditions([]string{"mode!=safe", "timeout>5"}, true)
+	require.NoError(t, err)
+	assert.Equal(t, "mode!=safe or timeout>5", formatConditions(conditions))
+
+	nested := map[string]interface{}{"all": []interface{}{
+		map[string]interface{}{"param_path": "a", "operator": "in", "value": []interface{}{"x"}},
+		map[string]interface{}{"any": []interface{}{
+			map[string]interface{}{"b": "y"},
+			map[string]interface{}{"not": map[string]interface{}{"c": map[string]interface{}{"exists": true}}},
+		}},
+	}}
+	assert.Equal(t, "a in [x] and (b=~y or not c exists true)", formatExpression(nested, false))
+	assert.Equal(t, "a>=3", formatConditions(map[string]interface{}{"param_path": "a", "operator": "gte", "value": 3}))
+}
+
+func TestSimulatePolicy(t *testing.T) {
+	email := "alice@acme.com"
+	var got api.SimulateToolPoliciesRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/policies/simulate" || r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		resp := api.SimulateToolPoliciesResponse{
+			StartDate:              "2026-10-01T00:00:00Z",
+			EndDate:                "2026-10-08T00:00:00Z",
+			PolicySimulationCounts: api.PolicySimulationCounts{Total: 12, Allowed: 9, Denied: 3, NewlyDenied: 3},
+			Groups: []api.PolicySimulationGroup{
+				{EmployeeEmail: &email, ToolName: "Bash", PolicySimulationCounts: api.PolicySimulationCounts{Total: 5, Allowed: 2, Denied: 3, NewlyDenied: 3}},
+				{EmployeeEmail: &email, ToolName: "Read", PolicySimulationCounts: api.PolicySimulationCounts{Total: 7, Allowed: 7}},
+			},
+			SkippedPolicies: 1,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := api.NewClient(server.URL)
+	client.SetToken("test-token")
+
+	draft := api.CreateToolPolicyRequest{ToolName: "Bash", Action: api.ToolPolicyActionDeny}
+	var buf bytes.Buffer
+	require.NoError(t, simulatePolicy(context.Background(), &buf, client, draft, "pol-1", 24*time.Hour, false))
+
+	require.Len(t, got.Policies, 1)
+	assert.Equal(t, "Bash", got.Policies[0].ToolName)
+	assert.Equal(t, []string{"pol-1"}, got.ReplacePolicyIDs)
+	require.NotNil(t, got.StartDate)
+
+	output := buf.String()
+	assert.Contains(t, output, "Simulated 12 tool calls")
+	assert.Contains(t, output, "Newly blocked:     3")
+	assert.Contains(t, output, "1 model or path policies weren't replayed")
+	assert.Contains(t, output, "alice@acme.com")
+	assert.NotContains(t, output, "Read", "groups whose calls are all still allowed are left out")
+
+	assert.Error(t, simulatePolicy(context.Background(), &buf, client, draft, "", 0, false))
+}
+
+func TestMergeUpdate(t *testing.T) {
+	team := "team-1"
+	reason := "old"
+	saved := api.ToolPolicy{
+		ID:         "pol-1",
+		TeamID:     &team,
+		PolicyType: api.ToolPolicyTypeTool,
+		ToolName:   "Bash",
+		Action:     api.ToolPolicyActionAudit,
+		Reason:     &reason,
+		Conditions: map[string]interface{}{"command": "rm"},
+	}
+	action := api.ToolPolicyActionDeny
+
+	draft := mergeUpdate(saved, api.UpdateToolPolicyRequest{Action: &action})
+	assert.Equal(t, api.ToolPolicyActionDeny, draft.Action)
+	assert.Equal(t, "Bash", draft.ToolName)
+	assert.Equal(t, &team, draft.TeamID)
+	assert.Equal(t, &reason, draft.Reason)
+	assert.Equal(t, saved.Conditions, draft.Conditions)
+}
+
+func TestHistoryCommand(t *testing.T) {
+	email := "alice@acme.com"
+	note := "Too noisy"
+	rolledBackTo := 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/policies/pol-1/history" {
+			http.NotFound(w, r)
+			return
+		}
+		resp := api.ToolPolicyHistoryResponse{
+			Revisions: []api.ToolPolicyRevision{
+				{Revision: 3, ChangeType: "rollback", RolledBackTo: &rolledBackTo, ChangedByEmail: &email,
+					CreatedAt: "2026-10-02T09:00:00Z", Diff: map[string]api.FieldChange{"action": {From: "audit", To: "deny"}}},
+				{Revision: 2, ChangeType: "update", ChangedByEmail: &email, Note: &note, CreatedAt: "2026-10-01T09:00:00Z",
+					Diff: map[string]api.FieldChange{
+						"action":     {From: "deny", To: "audit"},
+						"conditions": {From: map[string]interface{}{"command": "rm"}, To: nil},
+					}},
+				{Revision: 1, ChangeType: "create", CreatedAt: "2026-09-30T09:00:00Z",
+					Diff: map[string]api.FieldChange{"tool_name": {To: "Bash"}}},
+			},
+			Total: 3,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := api.NewClient(server.URL)
+	client.SetToken("test-token")
+	cmd := NewHistoryCommand(container.NewTestContainer(container.WithMockAPIClient(client)))
+
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"pol-1"})
+	require.NoError(t, cmd.Execute())
+
+	output := buf.String()
+	assert.Contains(t, output, "rollback to 1")
+	assert.Contains(t, output, "alice@acme.com")
+	assert.Contains(t, output, "Too noisy")
+	assert.Contains(t, output, "action: deny → audit")
+	assert.Contains(t, output, `conditions: {"command":"rm"} → -`)
+	assert.Contains(t, output, "tool_name: - → Bash")
+	assert.Less(t, strings.Index(output, "action: audit → deny"), strings.Index(output, "Too noisy"),
+		"each revision's diff follows its row")
+}
+
+func TestRollbackCommand(t *testing.T) {
+	var got api.RollbackToolPolicyRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/policies/pol-1/rollback" || r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(api.ToolPolicy{ID: "pol-1", ToolName: "Bash", Action: api.ToolPolicyActionDeny, Scope: "organization"})
+	}))
+	defer server.Close()
+
+	client := api.NewClient(server.URL)
+	client.SetToken("test-token")
+	c := container.NewTestContainer(container.WithMockAPIClient(client))
+
+	cmd := NewRollbackCommand(c)
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"pol-1", "--revision", "2", "--note", "Deny broke CI"})
+	require.NoError(t, cmd.Execute())
+
+	assert.Equal(t, 2, got.Revision)
+	require.NotNil(t, got.Note)
+	assert.Equal(t, "Deny broke CI", *got.Note)
+	assert.Contains(t, buf.String(), "Policy rolled back to revision 2.")
+	assert.Contains(t, buf.String(), "DENY")
+
+	cmd = NewRollbackCommand(c)
+	cmd.SetOut(&buf)
+	cmd.SetErr(&buf)
+	cmd.SetArgs([]string{"pol-1"})
+	assert.Error(t, cmd.Execute(), "--revision is required")
+}
+
+func TestLoadDefinitions(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+
+	definitions, err := loadDefinitions(write("policies.yaml", `policies:
+  - tool_name: Bash
+    action: deny
+    conditions:
+      timeout: {gt: 60000}
+  - tool_name: claude-opus-*
+    policy_type: model
+    action: deny
+    team: platform
+`))
+	require.NoError(t, err)
+	require.Len(t, definitions, 2)
+	assert.Equal(t, map[string]interface{}{"timeout": map[string]interface{}{"gt": 60000}}, definitions[0].Conditions)
+	assert.Equal(t, api.ToolPolicyTypeModel, definitions[1].PolicyType)
+	assert.Equal(t, "platform", *definitions[1].Team)
+
+	definitions, err = loadDefinitions(write("policies.json", `{"policies": [{"tool_name": "Write", "action": "audit", "employee": "alice@acme.com"}]}`))
+	require.NoError(t, err)
+	require.Len(t, definitions, 1)
+	assert.Equal(t, "alice@acme.com", *definitions[0].Employee)
+
+	definitions, err = loadDefinitions(write("empty.yaml", "policies: []\n"))
+	require.NoError(t, err)
+	assert.Empty(t, definitions)
+
+	_, err = loadDefinitions(write("typo.yaml", "policies:\n  - tool_name: Bash\n    action: deny\n    tema: platform\n"))
+	assert.ErrorContains(t, err, "field tema not found")
+
+	_, err = loadDefinitions(write("blank.yaml", ""))
+	assert.ErrorContains(t, err, `expected a "policies" list`)
+
+	_, err = loadDefinitions(write("scope.yaml", "policies:\n  - tool_name: Bash\n    action: deny\n    team: platform\n    employee: alice@acme.com\n"))
+	assert.ErrorContains(t, err, "set team or employee, not both")
+
+	_, err = loadDefinitions(write("action.yaml", "policies:\n  - tool_name: Bash\n"))
+	assert.ErrorContains(t, err, `unknown action ""`)
+}
+
+func TestEncodeDefinitions(t *testing.T) {
+	team := "platform"
+	reason := "No Opus"
+	list := &api.PolicyDefinitionList{Policies: []api.PolicyDefinition{
+		{ToolName: "Bash", Action: api.ToolPolicyActionDeny, Conditions: map[string]interface{}{"command": "rm"}},
+		{ToolName: "claude-opus-*", PolicyType: api.ToolPolicyTypeModel, Action: api.ToolPolicyActionDeny, Team: &team, Reason: &reason},
+	}}
+
+	data, err := encodeDefinitions(list, "yaml")
+	require.NoError(t, err)
+	assert.Equal(t, `policies:
+  - tool_name: Bash
+    action: deny
+    conditions:
+      command: rm
+  - tool_name: claude-opus-*
+    policy_type: model
+    action: deny
+    team: platform
+    reason: No Opus
+`, string(data))
+
+	// Exported files load back as they were
+	for _, format := range []string{"yaml", "json"} {
+		data, err := encodeDefinitions(list, format)
+		require.NoError(t, err)
+		path := filepath.Join(t.TempDir(), "policies."+format)
+		require.NoError(t, os.WriteFile(path, data, 0o600))
+		definitions, err := loadDefinitions(path)
+		require.NoError(t, err)
+		assert.Equal(t, list.Policies, definitions, format)
+	}
+}
+
+func TestApplyCommand(t *testing.T) {
+	var requests []api.ApplyToolPoliciesRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/policies/apply" || r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		var req api.ApplyToolPoliciesRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, req)
+
+		policyID := "pol-1"
+		resp := api.ApplyToolPoliciesResponse{
+			Changes: []api.PolicyPlanChange{
+				{ChangeType: "update", PolicyID: &policyID, Policy: req.Policies[0],
+					Diff: map[string]api.FieldChange{"action": {From: "audit", To: "deny"}}},
+				{ChangeType: "delete", Policy: api.PolicyDefinition{ToolName: "WebFetch", Action: api.ToolPolicyActionAudit}},
+			},
+			Updated: 1, Deleted: 1,
+			Applied: !req.DryRun,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := api.NewClient(server.URL)
+	client.SetToken("test-token")
+	c := container.NewTestContainer(container.WithMockAPIClient(client))
+
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("policies:\n  - tool_name: Bash\n    action: deny\n"), 0o600))
+
+	t.Run("plan", func(t *testing.T) {
+		requests = nil
+		cmd := NewPlanCommand(c)
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetArgs([]string{"-f", path})
+		require.NoError(t, cmd.Execute())
+
+		require.Len(t, requests, 1)
+		assert.True(t, requests[0].DryRun)
+		output := buf.String()
+		assert.Contains(t, output, "~ update  Bash  organization  DENY")
+		assert.Contains(t, output, "action: audit → deny")
+		assert.Contains(t, output, "- delete  WebFetch  organization  AUDIT")
+		assert.Contains(t, output, "Plan: 0 to create, 1 to update, 1 to delete, 0 unchanged.")
+	})
+
+	t.Run("confirmed", func(t *testing.T) {
+		requests = nil
+		cmd := NewApplyCommand(c)
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetIn(strings.NewReader("y\n"))
+		cmd.SetArgs([]string{"-f", path, "--note", "Sync"})
+		require.NoError(t, cmd.Execute())
+
+		require.Len(t, requests, 2, "a dry run, then the apply")
+		assert.True(t, requests[0].DryRun)
+		assert.False(t, requests[1].DryRun)
+		require.NotNil(t, requests[1].Note)
+		assert.Equal(t, "Sync", *requests[1].Note)
+		assert.Contains(t, buf.String(), "Applied: 0 created, 1 updated, 1 deleted, 0 unchanged.")
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		requests = nil
+		cmd := NewApplyCommand(c)
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetIn(strings.NewReader("n\n"))
+		cmd.SetArgs([]string{"-f", path})
+		require.NoError(t, cmd.Execute())
+
+		assert.Len(t, requests, 1)
+		assert.Contains(t, buf.String(), "Apply cancelled.")
+	})
+
+	t.Run("yes", func(t *testing.T) {
+		requests = nil
+		cmd := NewApplyCommand(c)
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetArgs([]string{"-f", path, "--yes"})
+		require.NoError(t, cmd.Execute())
+
+		require.Len(t, requests, 1)
+		assert.False(t, requests[0].DryRun)
+		assert.Contains(t, buf.String(), "~ update  Bash")
+		assert.Contains(t, buf.String(), "Applied: 0 created, 1 updated, 1 deleted, 0 unchanged.")
+	})
+}
+
+func TestPacksCommands(t *testing.T) {
+	var requests []api.InstallPolicyPackRequest
+	fromVersion := 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/v1/policies/packs" && r.Method == http.MethodGet:
+			team := "platform"
+			_ = json.NewEncoder(w).Encode(api.PolicyPackList{Packs: []api.PolicyPack{
+				{Name: "destructive-commands", Version: 2, Description: "Blocks destructive shell commands.",
+					Policies: make([]api.PolicyDefinition, 5),
+					Installs: []api.PolicyPackInstall{{PackName: "destructive-commands", Version: 1, UpgradeAvailable: true, Team: &team}}},
+				{Name: "network-audit", Version: 1, Description: "Audits network access.", Policies: make([]api.PolicyDefinition, 4)},
+			}})
+		case r.URL.Path == "/api/v1/policies/packs/destructive-commands/install" && r.Method == http.MethodPost:
+			var req api.InstallPolicyPackRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			requests = append(requests, req)
+			_ = json.NewEncoder(w).Encode(api.InstallPolicyPackResponse{
+				PackName:    "destructive-commands",
+				FromVersion: &fromVersion,
+				ToVersion:   2,
+				Plan: api.ApplyToolPoliciesResponse{
+					Changes: []api.PolicyPlanChange{
+						{ChangeType: "create", Policy: api.PolicyDefinition{ToolName: "Bash", Action: api.ToolPolicyActionDeny}},
+					},
+					Created: 1, Unchanged: 4,
+					Applied: !req.DryRun,
+				},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := api.NewClient(server.URL)
+	client.SetToken("test-token")
+	c := container.NewTestContainer(container.WithMockAPIClient(client))
+
+	t.Run("list", func(t *testing.T) {
+		cmd := NewPacksListCommand(c)
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetArgs([]string{})
+		require.NoError(t, cmd.Execute())
+
+		output := buf.String()
+		assert.Contains(t, output, "team platform v1 (upgrade available)")
+		assert.Regexp(t, `network-audit\s+v1\s+4\s+-`, output)
+	})
+
+	t.Run("diff", func(t *testing.T) {
+		requests = nil
+		cmd := NewPacksDiffCommand(c)
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetArgs([]string{"destructive-commands", "--team", "team-1"})
+		require.NoError(t, cmd.Execute())
+
+		require.Len(t, requests, 1)
+		assert.True(t, requests[0].DryRun)
+		require.NotNil(t, requests[0].TeamID)
+		assert.Equal(t, "team-1", *requests[0].TeamID)
+		output := buf.String()
+		assert.Contains(t, output, "Upgrading destructive-commands from v1 to v2 for team team-1")
+		assert.Contains(t, output, "+ create  Bash  organization  DENY")
+		assert.Contains(t, output, "Plan: 1 to create, 0 to update, 0 to delete, 4 unchanged.")
+	})
+
+	t.Run("install confirmed", func(t *testing.T) {
+		requests = nil
+		cmd := NewPacksInstallCommand(c)
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetIn(strings.NewReader("y\n"))
+		cmd.SetArgs([]string{"destructive-commands"})
+		require.NoError(t, cmd.Execute())
+
+		require.Len(t, requests, 2, "a dry run, then the install")
+		assert.True(t, requests[0].DryRun)
+		assert.False(t, requests[1].DryRun)
+		assert.Nil(t, requests[1].TeamID)
+		assert.Contains(t, buf.String(), "Applied: 1 created, 0 updated, 0 deleted, 4 unchanged.")
+	})
+
+	t.Run("install cancelled", func(t *testing.T) {
+		requests = nil
+		cmd := NewPacksInstallCommand(c)
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetIn(strings.NewReader("\n"))
+		cmd.SetArgs([]string{"destructive-commands"})
+		require.NoError(t, cmd.Execute())
+
+		assert.Len(t, requests, 1)
+		assert.Contains(t, buf.String(), "Install cancelled.")
+	})
 }
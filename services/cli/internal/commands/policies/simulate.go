@@ -0,0 +1,90 @@
+package policies
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rastrigin-systems/arfa/services/cli/internal/api"
+)
+
+// simulatePolicy replays the last since of stored tool calls against a draft
+// policy, in place of the saved policy replaced (if any), and prints the
+// report instead of saving the draft.
+func simulatePolicy(ctx context.Context, out io.Writer, client *api.Client, draft api.CreateToolPolicyRequest, replaced string, since time.Duration, showJSON bool) error {
+	if since <= 0 {
+		return fmt.Errorf("--since must be positive")
+	}
+
+	start := time.Now().UTC().Add(-since).Format(time.RFC3339)
+	req := api.SimulateToolPoliciesRequest{
+		Policies:  []api.CreateToolPolicyRequest{draft},
+		StartDate: &start,
+	}
+	if replaced != "" {
+		req.ReplacePolicyIDs = []string{replaced}
+	}
+
+	resp, err := client.SimulatePolicies(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	if showJSON {
+		data, _ := json.MarshalIndent(resp, "", "  ")
+		_, _ = fmt.Fprintln(out, string(data))
+		return nil
+	}
+	printSimulation(out, resp)
+	return nil
+}
+
+// printSimulation writes a simulation report: the totals, then a row per
+// employee and tool whose calls weren't all allowed or whose outcome changed.
+func printSimulation(out io.Writer, resp *api.SimulateToolPoliciesResponse) {
+	_, _ = fmt.Fprintf(out, "Simulated %d tool calls from %s to %s (nothing was saved)\n\n", resp.Total, resp.StartDate, resp.EndDate)
+	_, _ = fmt.Fprintf(out, "  Allowed:           %d\n", resp.Allowed)
+	_, _ = fmt.Fprintf(out, "  Denied:            %d\n", resp.Denied)
+	_, _ = fmt.Fprintf(out, "  Rate limited:      %d\n", resp.RateLimited)
+	_, _ = fmt.Fprintf(out, "  Approval required: %d\n", resp.RequireApproval)
+	_, _ = fmt.Fprintf(out, "  Newly blocked:     %d\n", resp.NewlyDenied)
+	_, _ = fmt.Fprintf(out, "  Newly allowed:     %d\n", resp.NewlyAllowed)
+
+	if resp.Truncated {
+		_, _ = fmt.Fprintln(out, "\nOnly the oldest tool calls in the range were replayed; try a shorter --since.")
+	}
+	if resp.SkippedPolicies > 0 {
+		_, _ = fmt.Fprintf(out, "\n%d model or path policies weren't replayed.\n", resp.SkippedPolicies)
+	}
+
+	var rows []api.PolicySimulationGroup
+	for _, group := range resp.Groups {
+		if group.Allowed < group.Total || group.NewlyAllowed > 0 {
+			rows = append(rows, group)
+		}
+	}
+	if len(rows) == 0 {
+		return
+	}
+
+	_, _ = fmt.Fprintln(out)
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "EMPLOYEE\tTOOL\tCALLS\tDENIED\tLIMITED\tAPPROVAL\tNEWLY BLOCKED\tNEWLY ALLOWED")
+	_, _ = fmt.Fprintln(w, "────────\t────\t─────\t──────\t───────\t────────\t─────────────\t─────────────")
+	for _, group := range rows {
+		employee := "-"
+		switch {
+		case group.EmployeeEmail != nil:
+			employee = *group.EmployeeEmail
+		case group.EmployeeID != nil:
+			employee = *group.EmployeeID
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\n", employee, group.ToolName,
+			group.Total, group.Denied, group.RateLimited, group.RequireApproval, group.NewlyDenied, group.NewlyAllowed)
+	}
+	_ = w.Flush()
+	_, _ = fmt.Fprintln(out)
+}
@@ -0,0 +1,298 @@
+package policies
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rastrigin-systems/arfa/pkg/policy"
+	"github.com/rastrigin-systems/arfa/services/cli/internal/api"
+	"github.com/rastrigin-systems/arfa/services/cli/internal/container"
+	"github.com/rastrigin-systems/arfa/services/cli/internal/control"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// testResult is one tool call and the decision reached for it
+type testResult struct {
+	Tool     string           `json:"tool"`
+	Input    json.RawMessage  `json:"input,omitempty"`
+	Decision control.Decision `json:"decision"`
+}
+
+// NewTestCommand creates the policies test command.
+func NewTestCommand(c *container.Container) *cobra.Command {
+	var toolName, input, capture, provider, policiesFile, timezone string
+	var showJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "test",
+		Short: "Check what policies decide for a tool call",
+		Long: `Evaluate tool calls against your effective policies, exactly as the proxy
+would, without running them. For each call it prints the decision, the policy
+that decided it, its scope and the conditions that matched, along with any
+allow policy overrides, rate limits and audit policies that apply.
+
+Give the call with --tool and --input, or feed a captured response with --sse
+(an SSE stream or a JSON message body) to test every tool call in it.
+
+Policies are fetched from the platform unless --policies names a YAML or JSON
+file holding draft policies: a list, or an object with a "policies" list as
+printed by 'arfa policies list --json'. Drafts need no IDs; their scope comes
+from team_id and employee_id when not set. Drafts with a schedule are only
+tested when it's active now, reading windows in --timezone (the organization's
+timezone, UTC by default).
+
+The command exits non-zero when any call is denied, so it can run in CI.
+Rate limits are reported but not counted, and calls that require approval
+are reported without waiting for one.
+
+Examples:
+  # Would this command be blocked?
+  arfa policies test --tool Bash --input '{"command":"rm -rf /"}'
+
+  # Check every tool call in a captured response
+  arfa policies test --sse response.txt
+
+  # Try draft policies before creating them
+  arfa policies test --policies drafts.yaml --tool Bash --input '{"command":"git push --force"}'`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
+			if (toolName == "") == (capture == "") {
+				return fmt.Errorf("exactly one of --tool or --sse is required")
+			}
+			if capture != "" && cmd.Flags().Changed("input") {
+				return fmt.Errorf("--input can't be combined with --sse")
+			}
+
+			var calls []control.ToolCall
+			if capture != "" {
+				data, err := os.ReadFile(capture)
+				if err != nil {
+					return fmt.Errorf("failed to read capture: %w", err)
+				}
+				calls, err = control.ParseCapture(data, provider)
+				if err != nil {
+					return fmt.Errorf("failed to parse capture: %w", err)
+				}
+				if len(calls) == 0 {
+					_, _ = fmt.Fprintln(out, "No tool calls found in the capture.")
+					return nil
+				}
+			} else {
+				if !json.Valid([]byte(input)) {
+					return fmt.Errorf("--input must be JSON")
+				}
+				calls = []control.ToolCall{{ToolName: toolName, Input: input}}
+			}
+
+			var policies []api.ToolPolicy
+			if policiesFile != "" {
+				var err error
+				policies, err = loadPolicies(policiesFile)
+				if err != nil {
+					return err
+				}
+				loc, err := time.LoadLocation(timezone)
+				if err != nil {
+					return fmt.Errorf("invalid --timezone: %w", err)
+				}
+				// The platform only returns active policies; drafts are
+				// filtered the same way
+				policies, _ = control.ActivePolicies(policies, time.Now(), loc)
+			} else {
+				client, err := c.APIClient()
+				if err != nil {
+					return fmt.Errorf("not logged in. Run 'arfa login' first: %w", err)
+				}
+
+				ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+				defer cancel()
+
+				resp, err := client.GetMyToolPolicies(ctx)
+				if err != nil {
+					return fmt.Errorf("failed to fetch policies: %w", err)
+				}
+				policies = resp.Policies
+			}
+
+			handler := control.NewPolicyHandlerWithPolicies(policies)
+			results := make([]testResult, 0, len(calls))
+			denied := 0
+			for _, call := range calls {
+				result := testResult{Tool: call.ToolName, Decision: handler.Explain(call.ToolName, call.Input)}
+				if json.Valid([]byte(call.Input)) {
+					result.Input = json.RawMessage(call.Input)
+				}
+				if result.Decision.Outcome == api.ToolPolicyActionDeny {
+					denied++
+				}
+				results = append(results, result)
+			}
+
+			if showJSON {
+				data, _ := json.MarshalIndent(struct {
+					Results []testResult `json:"results"`
+				}{results}, "", "  ")
+				_, _ = fmt.Fprintln(out, string(data))
+			} else {
+				for _, result := range results {
+					printTestResult(out, result)
+				}
+			}
+
+			if denied > 0 {
+				// The decision was printed; a deny isn't a usage mistake
+				cmd.SilenceUsage = true
+				return fmt.Errorf("%d of %d tool calls denied", denied, len(results))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&toolName, "tool", "", "Tool name to test (e.g., Bash)")
+	cmd.Flags().StringVar(&input, "input", "{}", "Tool input as JSON")
+	cmd.Flags().StringVar(&capture, "sse", "", "Captured response (SSE stream or JSON message) whose tool calls to test")
+	cmd.Flags().StringVar(&provider, "provider", "anthropic", "Format of the --sse capture: "+strings.Join(control.CaptureProviders(), ", "))
+	cmd.Flags().StringVar(&policiesFile, "policies", "", "YAML or JSON file of draft policies to test instead of your effective policies")
+	cmd.Flags().StringVar(&timezone, "timezone", "UTC", "Timezone the schedules of --policies drafts are read in (e.g., Europe/Berlin)")
+	cmd.Flags().BoolVar(&showJSON, "json", false, "Output as JSON")
+
+	return cmd
+}
+
+// loadPolicies reads draft policies from a YAML or JSON file
+func loadPolicies(path string) ([]api.ToolPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policies: %w", err)
+	}
+
+	// JSON is YAML, so one decoder reads both
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse policies: %w", err)
+	}
+	if obj, ok := doc.(map[string]interface{}); ok {
+		doc = obj["policies"]
+	}
+	if _, ok := doc.([]interface{}); !ok {
+		return nil, fmt.Errorf("failed to parse policies: expected a list of policies or a \"policies\" list")
+	}
+
+	data, err = json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse policies: %w", err)
+	}
+	var policies []api.ToolPolicy
+	if err := json.Unmarshal(data, &policies); err != nil {
+		return nil, fmt.Errorf("failed to parse policies: %w", err)
+	}
+
+	for i := range policies {
+		p := &policies[i]
+		if p.ToolName == "" {
+			return nil, fmt.Errorf("policy %d: tool_name is required", i+1)
+		}
+		if p.Action == "" {
+			p.Action = api.ToolPolicyActionDeny
+		}
+		if !validAction(string(p.Action)) {
+			return nil, fmt.Errorf("policy %d (%s): unknown action %q", i+1, p.ToolName, p.Action)
+		}
+		if err := policy.Validate(p.Conditions); err != nil {
+			return nil, fmt.Errorf("policy %d (%s): invalid conditions: %w", i+1, p.ToolName, err)
+		}
+		if p.Scope == "" {
+			switch {
+			case p.EmployeeID != nil:
+				p.Scope = api.ToolPolicyScopeEmployee
+			case p.TeamID != nil:
+				p.Scope = api.ToolPolicyScopeTeam
+			default:
+				p.Scope = api.ToolPolicyScopeOrganization
+			}
+		}
+		if p.ID == "" {
+			// Name drafts by their place in the file
+			p.ID = fmt.Sprintf("#%d", i+1)
+		}
+	}
+	return policies, nil
+}
+
+// printTestResult writes the decision for one tool call
+func printTestResult(out io.Writer, result testResult) {
+	d := result.Decision
+
+	_, _ = fmt.Fprintf(out, "\nTool:       %s\n", result.Tool)
+	if len(result.Input) > 0 {
+		_, _ = fmt.Fprintf(out, "Input:      %s\n", result.Input)
+	}
+
+	var decision string
+	switch d.Outcome {
+	case api.ToolPolicyActionDeny:
+		decision = "DENY"
+	case api.ToolPolicyActionRequireApproval:
+		decision = "APPROVAL REQUIRED"
+	default:
+		decision = "ALLOW"
+	}
+	_, _ = fmt.Fprintf(out, "Decision:   %s\n", decision)
+
+	if d.Policy != nil {
+		_, _ = fmt.Fprintf(out, "Policy:     %s\n", formatPolicyMatch(*d.Policy))
+		if conditions := formatMatchConditions(d.Policy.Conditions); conditions != "" {
+			_, _ = fmt.Fprintf(out, "Condition:  %s\n", conditions)
+		}
+	}
+	if d.Reason != "" {
+		_, _ = fmt.Fprintf(out, "Reason:     %s\n", d.Reason)
+	}
+	for _, o := range d.Overrides {
+		_, _ = fmt.Fprintf(out, "Overridden: %s %s by allow %s\n", o.Restriction.Action, formatPolicyMatch(o.Restriction), formatPolicyMatch(o.Allow))
+	}
+	for _, limit := range d.RateLimits {
+		_, _ = fmt.Fprintf(out, "Rate limit: %s\n", formatPolicyMatch(limit))
+	}
+	for _, audit := range d.Audits {
+		_, _ = fmt.Fprintf(out, "Audited by: %s\n", formatPolicyMatch(audit))
+	}
+}
+
+// formatPolicyMatch names a policy with its tool and scope, such as
+// "3f2a1b4c (Bash, team)"
+func formatPolicyMatch(p control.PolicyMatch) string {
+	id := p.ID
+	if id == "" {
+		id = "-"
+	}
+	scope := p.Scope
+	if scope == "" {
+		scope = api.ToolPolicyScopeOrganization
+	}
+	return fmt.Sprintf("%s (%s, %s)", id, p.ToolName, scope)
+}
+
+// formatMatchConditions writes the conditions a policy matched on in full, as
+// JSON, leaving out the keys that configure the policy instead. It returns ""
+// when there are none.
+func formatMatchConditions(conditions map[string]interface{}) string {
+	matched := make(map[string]interface{}, len(conditions))
+	for key, condition := range conditions {
+		if !policy.IsReserved(key) {
+			matched[key] = condition
+		}
+	}
+	if len(matched) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(matched)
+	return string(data)
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/rastrigin-systems/arfa/services/cli/internal/api"
 	"github.com/rastrigin-systems/arfa/services/cli/internal/container"
@@ -13,8 +14,11 @@ import (
 
 // NewUpdateCommand creates the policies update command.
 func NewUpdateCommand(c *container.Container) *cobra.Command {
-	var toolName, action, reason string
+	var toolName, action, reason, note string
 	var conditions []string
+	var matchAny bool
+	var simulate bool
+	var since time.Duration
 	var showJSON bool
 
 	cmd := &cobra.Command{
@@ -25,15 +29,22 @@ func NewUpdateCommand(c *container.Container) *cobra.Command {
 Only the specified fields will be updated. The scope (org/team/employee)
 cannot be changed after creation.
 
+With --simulate the policy isn't changed. Instead, the organization's tool
+calls from the last --since are replayed against the updated policy, in place
+of the saved one, and the calls it would block or let through are reported.
+
 Examples:
-  # Change action to audit
-  arfa policies update abc123 --action audit
+  # Change action to audit, noting why in the policy's history
+  arfa policies update abc123 --action audit --note "Too many false positives"
 
   # Update reason
   arfa policies update abc123 --reason "Updated policy reason"
 
   # Change tool pattern
-  arfa policies update abc123 --tool "mcp__gcloud__*"`,
+  arfa policies update abc123 --tool "mcp__gcloud__*"
+
+  # Preview what switching to deny would have blocked last month
+  arfa policies update abc123 --action deny --simulate --since 720h`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			out := cmd.OutOrStdout()
@@ -45,6 +56,11 @@ Examples:
 				return fmt.Errorf("at least one field must be specified for update")
 			}
 
+			paramConditions, err := parseConditions(conditions, matchAny)
+			if err != nil {
+				return err
+			}
+
 			// Get auth service and require authentication
 			authService, err := c.AuthService()
 			if err != nil {
@@ -67,8 +83,8 @@ Examples:
 				req.ToolName = &toolName
 			}
 			if action != "" {
-				if action != "deny" && action != "audit" {
-					return fmt.Errorf("--action must be 'deny' or 'audit'")
+				if !validAction(action) {
+					return fmt.Errorf("--action must be 'deny', 'audit', 'require_approval', 'rate_limit', 'allow' or 'rewrite'")
 				}
 				a := api.ToolPolicyAction(action)
 				req.Action = &a
@@ -76,8 +92,19 @@ Examples:
 			if reason != "" {
 				req.Reason = &reason
 			}
-			if len(conditions) > 0 {
-				req.Conditions = parseConditions(conditions)
+			if paramConditions != nil {
+				req.Conditions = paramConditions
+			}
+			if note != "" {
+				req.Note = &note
+			}
+
+			if simulate {
+				saved, err := client.GetPolicy(ctx, policyID)
+				if err != nil {
+					return err
+				}
+				return simulatePolicy(ctx, out, client, mergeUpdate(*saved, req), saved.ID, since, showJSON)
 			}
 
 			// Update policy
@@ -110,10 +137,40 @@ Examples:
 	}
 
 	cmd.Flags().StringVar(&toolName, "tool", "", "New tool name or glob pattern")
-	cmd.Flags().StringVar(&action, "action", "", "New action: deny, audit")
+	cmd.Flags().StringVar(&action, "action", "", "New action: deny, audit, require_approval, rate_limit, allow, rewrite")
 	cmd.Flags().StringVar(&reason, "reason", "", "New reason for the policy")
-	cmd.Flags().StringSliceVar(&conditions, "condition", nil, "New conditions (replaces existing)")
+	cmd.Flags().StringArrayVar(&conditions, "condition", nil, "New conditions, as in create (replaces existing)")
+	cmd.Flags().BoolVar(&matchAny, "any", false, "Match when any --condition does, instead of all")
+	cmd.Flags().StringVar(&note, "note", "", "Why the policy is changing, kept in its history")
+	cmd.Flags().BoolVar(&simulate, "simulate", false, "Replay recent tool calls against the updated policy instead of saving it")
+	cmd.Flags().DurationVar(&since, "since", 7*24*time.Hour, "How far back --simulate replays tool calls")
 	cmd.Flags().BoolVar(&showJSON, "json", false, "Output as JSON")
 
 	return cmd
 }
+
+// mergeUpdate returns the policy an update would save, as a draft
+func mergeUpdate(saved api.ToolPolicy, req api.UpdateToolPolicyRequest) api.CreateToolPolicyRequest {
+	draft := api.CreateToolPolicyRequest{
+		PolicyType: saved.PolicyType,
+		ToolName:   saved.ToolName,
+		Action:     saved.Action,
+		Reason:     saved.Reason,
+		TeamID:     saved.TeamID,
+		EmployeeID: saved.EmployeeID,
+		Conditions: saved.Conditions,
+	}
+	if req.ToolName != nil {
+		draft.ToolName = *req.ToolName
+	}
+	if req.Action != nil {
+		draft.Action = *req.Action
+	}
+	if req.Reason != nil {
+		draft.Reason = req.Reason
+	}
+	if req.Conditions != nil {
+		draft.Conditions = req.Conditions
+	}
+	return draft
+}
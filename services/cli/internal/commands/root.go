@@ -21,17 +21,19 @@ func NewRootCommand(version string, c *container.Container) *cobra.Command {
 		Long: `arfa CLI provides security monitoring and control for AI coding agents.
 It runs as an HTTPS proxy that intercepts LLM API traffic, enabling:
 - Real-time visibility into AI agent tool usage
-- Policy enforcement (block/audit dangerous operations)
+- Policy enforcement (block/audit/approve dangerous operations)
 - Activity logging for compliance and debugging
 - Webhook integration with SIEM systems
 
 Commands:
   arfa start             Start the security proxy
   arfa stop              Stop the security proxy
+  arfa approve           Approve or deny paused tool calls
   arfa status            Show status of all components
   arfa login             Authenticate with the platform
   arfa logs stream       Monitor AI agent activity
-  arfa policies list     View active security policies`,
+  arfa policies list     View active security policies
+  arfa policies test     Check what policies decide for a tool call`,
 		Version: version,
 		// No default action - just print help
 	}
@@ -40,6 +42,7 @@ Commands:
 	rootCmd.AddCommand(NewStartCommand(c))
 	rootCmd.AddCommand(NewStopCommand(c))
 	rootCmd.AddCommand(NewEnvCommand(c))
+	rootCmd.AddCommand(NewApproveCommand(c))
 
 	// Register auth commands
 	rootCmd.AddCommand(auth.NewLoginCommand(c))
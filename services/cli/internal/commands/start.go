@@ -1,11 +1,14 @@
 package commands
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
@@ -29,6 +32,7 @@ The proxy will:
 - Intercept HTTPS traffic from AI agents (Claude Code, Cursor, etc.)
 - Log all tool usage to the platform
 - Enforce security policies (block dangerous operations)
+- Prompt for approval of tool calls that require it
 
 To use the proxy with AI agents:
   export HTTPS_PROXY=http://localhost:8082
@@ -103,6 +107,7 @@ func runStart(cmd *cobra.Command, args []string) error {
 		FlushInterval: 5 * time.Second,
 		MaxBatchSize:  10,
 		Uploader:      uploader,
+		RateLimitFile: filepath.Join(home, ".arfa", "rate_limits.json"),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to initialize control service: %w", err)
@@ -147,10 +152,11 @@ func runStart(cmd *cobra.Command, args []string) error {
 
 	// Write PID file
 	if err := pidFile.Write(control.ProxyInfo{
-		PID:       os.Getpid(),
-		Port:      port,
-		StartedAt: time.Now(),
-		CertPath:  certPath,
+		PID:          os.Getpid(),
+		Port:         port,
+		StartedAt:    time.Now(),
+		CertPath:     certPath,
+		ControlToken: controlProxy.GetControlToken(),
 	}); err != nil {
 		fmt.Printf("Warning: Failed to write PID file: %v\n", err)
 	}
@@ -170,6 +176,9 @@ func runStart(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 	fmt.Println("Press Ctrl+C to stop")
 
+	// Tool calls that require approval are decided here or with 'arfa approve'
+	watchApprovals(controlSvc.Approvals())
+
 	// Wait for interrupt signal
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
@@ -184,3 +193,53 @@ func runStart(cmd *cobra.Command, args []string) error {
 	fmt.Println("✓ Proxy stopped")
 	return nil
 }
+
+// watchApprovals prints a prompt for every tool call waiting for approval and
+// reads y/n answers from stdin. An answer decides the oldest pending call.
+func watchApprovals(approvals *control.Approvals) {
+	approvals.OnRequest(func(req control.ApprovalRequest) {
+		fmt.Println()
+		fmt.Printf("⏸  Approval required: %s (%s)\n", req.ToolName, req.ID)
+		if input, err := json.Marshal(req.ToolInput); err == nil && req.ToolInput != nil {
+			fmt.Printf("   Input:  %s\n", input)
+		}
+		if req.Reason != "" {
+			fmt.Printf("   Reason: %s\n", req.Reason)
+		}
+		fmt.Printf("   Approve? [y/n] (expires at %s)\n", req.ExpiresAt.Format("15:04:05"))
+	})
+
+	approvals.OnResolved(func(req control.ApprovalRequest, decision control.ApprovalDecision) {
+		if decision.Approved {
+			fmt.Printf("✓ Approved %s (%s, %s)\n", req.ToolName, req.ID, decision.Source)
+		} else {
+			fmt.Printf("✗ Denied %s (%s, %s)\n", req.ToolName, req.ID, decision.Source)
+		}
+	})
+
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			var approved bool
+			switch strings.ToLower(strings.TrimSpace(scanner.Text())) {
+			case "y", "yes":
+				approved = true
+			case "n", "no":
+				approved = false
+			default:
+				continue
+			}
+
+			pending := approvals.Pending()
+			if len(pending) == 0 {
+				fmt.Println("No tool calls are waiting for approval")
+				continue
+			}
+			_ = approvals.Decide(control.ApprovalDecision{
+				ApprovalID: pending[0].ID,
+				Approved:   approved,
+				Source:     control.ApprovalSourceLocal,
+			})
+		}
+	}()
+}
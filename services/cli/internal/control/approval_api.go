@@ -0,0 +1,147 @@
+package control
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// approvalsPath is where the proxy serves its local approvals API. Requests
+// sent to the proxy port directly (not proxied) are routed here.
+const approvalsPath = "/approvals"
+
+// approvalDecisionBody is the body of POST /approvals/{id}.
+type approvalDecisionBody struct {
+	Approved bool   `json:"approved"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// newApprovalsHandler serves the local approvals API used by arfa approve:
+//
+//	GET  /approvals       - pending approvals, oldest first
+//	POST /approvals/{id}  - approve or deny one
+//
+// Every request must carry the proxy's control token as a bearer token.
+func newApprovalsHandler(approvals *Approvals, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(auth), []byte(token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, approvalsPath), "/")
+		switch {
+		case r.URL.Path != approvalsPath && !strings.HasPrefix(r.URL.Path, approvalsPath+"/"):
+			http.NotFound(w, r)
+
+		case id == "" && r.Method == http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(approvals.Pending())
+
+		case id != "" && r.Method == http.MethodPost:
+			var body approvalDecisionBody
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				http.Error(w, "invalid request body", http.StatusBadRequest)
+				return
+			}
+			err := approvals.Decide(ApprovalDecision{
+				ApprovalID: id,
+				Approved:   body.Approved,
+				Reason:     body.Reason,
+				Source:     ApprovalSourceLocal,
+			})
+			if errors.Is(err, ErrApprovalNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// ApprovalClient talks to a running proxy's local approvals API.
+type ApprovalClient struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewApprovalClient creates a client for the proxy described by info.
+func NewApprovalClient(info *ProxyInfo) *ApprovalClient {
+	return &ApprovalClient{
+		baseURL:    fmt.Sprintf("http://127.0.0.1:%d", info.Port),
+		token:      info.ControlToken,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// List returns the tool calls waiting for a decision, oldest first.
+func (c *ApprovalClient) List(ctx context.Context) ([]ApprovalRequest, error) {
+	resp, err := c.do(ctx, http.MethodGet, approvalsPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("proxy returned %s", resp.Status)
+	}
+
+	var requests []ApprovalRequest
+	if err := json.NewDecoder(resp.Body).Decode(&requests); err != nil {
+		return nil, fmt.Errorf("failed to decode approvals: %w", err)
+	}
+	return requests, nil
+}
+
+// Decide approves or denies a pending tool call. Returns ErrApprovalNotFound
+// if it was already decided or has expired.
+func (c *ApprovalClient) Decide(ctx context.Context, approvalID string, approved bool, reason string) error {
+	body, err := json.Marshal(approvalDecisionBody{Approved: approved, Reason: reason})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(ctx, http.MethodPost, approvalsPath+"/"+approvalID, body)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		return nil
+	case http.StatusNotFound:
+		return ErrApprovalNotFound
+	default:
+		return fmt.Errorf("proxy returned %s", resp.Status)
+	}
+}
+
+// do sends an authenticated request to the proxy.
+func (c *ApprovalClient) do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach proxy: %w", err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,178 @@
+package control
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultApprovalTimeout is how long a tool call waits for a decision before
+// it is denied.
+const DefaultApprovalTimeout = 5 * time.Minute
+
+// Where an approval decision came from
+const (
+	ApprovalSourceLocal   = "local"   // The developer, in the arfa start terminal or with arfa approve
+	ApprovalSourceRemote  = "remote"  // An admin, through the API
+	ApprovalSourceTimeout = "timeout" // Nobody decided in time
+)
+
+// ErrApprovalNotFound is returned when deciding an approval that isn't pending.
+var ErrApprovalNotFound = errors.New("approval not found")
+
+// ApprovalRequest is a tool call paused by a require_approval policy.
+type ApprovalRequest struct {
+	ID          string                 `json:"id"`
+	PolicyID    string                 `json:"policy_id,omitempty"`
+	ToolName    string                 `json:"tool_name"`
+	ToolInput   map[string]interface{} `json:"tool_input,omitempty"`
+	Reason      string                 `json:"reason,omitempty"`
+	SessionID   string                 `json:"session_id,omitempty"`
+	RequestedAt time.Time              `json:"requested_at"`
+	ExpiresAt   time.Time              `json:"expires_at"`
+}
+
+// ApprovalDecision approves or rejects a pending tool call.
+type ApprovalDecision struct {
+	ApprovalID string `json:"approval_id"`
+	Approved   bool   `json:"approved"`
+	DecidedBy  string `json:"decided_by,omitempty"` // Employee ID, for remote decisions
+	Reason     string `json:"reason,omitempty"`
+	Source     string `json:"source,omitempty"` // local, remote or timeout
+}
+
+// pendingApproval is a request and the channel its decision is delivered on.
+type pendingApproval struct {
+	request  ApprovalRequest
+	decision chan ApprovalDecision
+}
+
+// Approvals holds tool calls waiting for a decision. The handler that paused
+// the call blocks in Request until Decide is called for it or it times out.
+type Approvals struct {
+	timeout time.Duration
+
+	mu         sync.Mutex
+	pending    map[string]*pendingApproval
+	onRequest  []func(ApprovalRequest)
+	onResolved []func(ApprovalRequest, ApprovalDecision)
+}
+
+// NewApprovals creates an empty approval queue. A zero timeout uses
+// DefaultApprovalTimeout.
+func NewApprovals(timeout time.Duration) *Approvals {
+	if timeout <= 0 {
+		timeout = DefaultApprovalTimeout
+	}
+	return &Approvals{
+		timeout: timeout,
+		pending: make(map[string]*pendingApproval),
+	}
+}
+
+// OnRequest registers a callback for every new pending approval.
+func (a *Approvals) OnRequest(fn func(ApprovalRequest)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.onRequest = append(a.onRequest, fn)
+}
+
+// OnResolved registers a callback for every decision, including timeouts.
+func (a *Approvals) OnResolved(fn func(ApprovalRequest, ApprovalDecision)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.onResolved = append(a.onResolved, fn)
+}
+
+// Request adds a pending approval and blocks until it is decided. A request
+// without a decision before it expires is denied.
+func (a *Approvals) Request(req ApprovalRequest) ApprovalDecision {
+	if req.ID == "" {
+		req.ID = newApprovalID()
+	}
+	req.RequestedAt = time.Now()
+	req.ExpiresAt = req.RequestedAt.Add(a.timeout)
+
+	pending := &pendingApproval{request: req, decision: make(chan ApprovalDecision, 1)}
+	a.mu.Lock()
+	a.pending[req.ID] = pending
+	onRequest := a.onRequest
+	a.mu.Unlock()
+
+	for _, fn := range onRequest {
+		fn(req)
+	}
+
+	timer := time.NewTimer(a.timeout)
+	defer timer.Stop()
+
+	var decision ApprovalDecision
+	select {
+	case decision = <-pending.decision:
+	case <-timer.C:
+		a.mu.Lock()
+		if _, ok := a.pending[req.ID]; ok {
+			delete(a.pending, req.ID)
+			decision = ApprovalDecision{
+				ApprovalID: req.ID,
+				Reason:     "no decision within " + a.timeout.String(),
+				Source:     ApprovalSourceTimeout,
+			}
+		}
+		a.mu.Unlock()
+		if decision.ApprovalID == "" {
+			// Decided just as the timer fired
+			decision = <-pending.decision
+		}
+	}
+
+	a.mu.Lock()
+	onResolved := a.onResolved
+	a.mu.Unlock()
+	for _, fn := range onResolved {
+		fn(req, decision)
+	}
+	return decision
+}
+
+// Decide delivers a decision for a pending approval. Returns
+// ErrApprovalNotFound if it was already decided or has expired.
+func (a *Approvals) Decide(decision ApprovalDecision) error {
+	a.mu.Lock()
+	pending, ok := a.pending[decision.ApprovalID]
+	if ok {
+		delete(a.pending, decision.ApprovalID)
+	}
+	a.mu.Unlock()
+
+	if !ok {
+		return ErrApprovalNotFound
+	}
+	pending.decision <- decision
+	return nil
+}
+
+// Pending returns the approvals waiting for a decision, oldest first.
+func (a *Approvals) Pending() []ApprovalRequest {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	requests := make([]ApprovalRequest, 0, len(a.pending))
+	for _, p := range a.pending {
+		requests = append(requests, p.request)
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].RequestedAt.Before(requests[j].RequestedAt)
+	})
+	return requests
+}
+
+// newApprovalID returns a short random ID that is easy to type.
+func newApprovalID() string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return "apr_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,78 @@
+package control
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApprovals_Decide(t *testing.T) {
+	approvals := NewApprovals(time.Minute)
+
+	var resolved []ApprovalDecision
+	approvals.OnResolved(func(_ ApprovalRequest, d ApprovalDecision) { resolved = append(resolved, d) })
+
+	done := make(chan ApprovalDecision)
+	go func() { done <- approvals.Request(ApprovalRequest{ToolName: "Bash"}) }()
+
+	require.Eventually(t, func() bool { return len(approvals.Pending()) == 1 }, time.Second, time.Millisecond)
+	pending := approvals.Pending()[0]
+	assert.True(t, strings.HasPrefix(pending.ID, "apr_"))
+	assert.Equal(t, time.Minute, pending.ExpiresAt.Sub(pending.RequestedAt))
+
+	require.NoError(t, approvals.Decide(ApprovalDecision{ApprovalID: pending.ID, Approved: true, Source: ApprovalSourceLocal}))
+	decision := <-done
+
+	assert.True(t, decision.Approved)
+	assert.Empty(t, approvals.Pending())
+	assert.Equal(t, []ApprovalDecision{decision}, resolved)
+
+	// Already decided
+	assert.ErrorIs(t, approvals.Decide(ApprovalDecision{ApprovalID: pending.ID}), ErrApprovalNotFound)
+}
+
+func TestApprovals_TimeoutDenies(t *testing.T) {
+	approvals := NewApprovals(10 * time.Millisecond)
+
+	decision := approvals.Request(ApprovalRequest{ID: "apr_1", ToolName: "Bash"})
+
+	assert.False(t, decision.Approved)
+	assert.Equal(t, "apr_1", decision.ApprovalID)
+	assert.Equal(t, ApprovalSourceTimeout, decision.Source)
+	assert.Empty(t, approvals.Pending())
+	assert.ErrorIs(t, approvals.Decide(ApprovalDecision{ApprovalID: "apr_1", Approved: true}), ErrApprovalNotFound)
+}
+
+func TestApprovalClient(t *testing.T) {
+	approvals := NewApprovals(time.Minute)
+	server := httptest.NewServer(newApprovalsHandler(approvals, "secret"))
+	defer server.Close()
+
+	client := &ApprovalClient{baseURL: server.URL, token: "secret", httpClient: server.Client()}
+	ctx := context.Background()
+
+	done := make(chan ApprovalDecision)
+	go func() { done <- approvals.Request(ApprovalRequest{ID: "apr_1", ToolName: "Bash"}) }()
+	require.Eventually(t, func() bool { return len(approvals.Pending()) == 1 }, time.Second, time.Millisecond)
+
+	pending, err := client.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "Bash", pending[0].ToolName)
+
+	require.NoError(t, client.Decide(ctx, "apr_1", false, "Not now"))
+	decision := <-done
+	assert.Equal(t, ApprovalDecision{ApprovalID: "apr_1", Reason: "Not now", Source: ApprovalSourceLocal}, decision)
+
+	assert.ErrorIs(t, client.Decide(ctx, "apr_1", true, ""), ErrApprovalNotFound)
+
+	// The token is required
+	client.token = "wrong"
+	_, err = client.List(ctx)
+	assert.Error(t, err)
+}
@@ -0,0 +1,60 @@
+package control
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/elazarl/goproxy"
+)
+
+// Each provider answers a blocked request the way its API reports an error,
+// so the agent surfaces the message: Provider.BlockedResponse. Streaming
+// requests get the error as a stream event where the API has one, since
+// clients that asked for a stream may not read an error body.
+
+// eventStreamResponse returns a 200 response streaming body as server-sent
+// events.
+func eventStreamResponse(r *http.Request, body string) *http.Response {
+	resp := goproxy.NewResponse(r, "text/event-stream", http.StatusOK, body)
+	resp.Header.Set("Cache-Control", "no-cache")
+	return resp
+}
+
+// jsonErrorResponse returns a 403 response with a JSON error body.
+func jsonErrorResponse(r *http.Request, payload any) *http.Response {
+	data, _ := json.Marshal(payload)
+	return goproxy.NewResponse(r, "application/json", http.StatusForbidden, string(data))
+}
+
+// formatRequestBlockError creates the user-friendly message for a blocked request.
+func formatRequestBlockError(reason string) string {
+	return "[REQUEST BLOCKED BY ORGANIZATION POLICY]\n\n" +
+		"Reason: " + reason + "\n\n" +
+		policyHint
+}
+
+// isStreamingRequest reports whether the request asks for a streamed response
+// with "stream": true in its body, as Anthropic and OpenAI requests do. The
+// body is restored after inspection.
+func isStreamingRequest(r *http.Request) bool {
+	if r.Body == nil {
+		return false
+	}
+
+	bodyBytes, err := io.ReadAll(r.Body)
+	_ = r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+	if err != nil {
+		return false
+	}
+
+	var body struct {
+		Stream bool `json:"stream"`
+	}
+	if json.Unmarshal(bodyBytes, &body) != nil {
+		return false
+	}
+	return body.Stream
+}
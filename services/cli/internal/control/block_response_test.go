@@ -0,0 +1,177 @@
+package control
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnthropicBlockedResponse_JSON(t *testing.T) {
+	req, _ := http.NewRequest("POST", "https://api.anthropic.com/v1/messages", bytes.NewBufferString(`{"model":"claude-sonnet-4","stream":false}`))
+
+	resp := anthropicProvider{}.BlockedResponse(req, "Employee access has been revoked")
+
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var body map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "error", body["type"])
+
+	errObj := body["error"].(map[string]any)
+	assert.Equal(t, "permission_error", errObj["type"])
+	assert.Contains(t, errObj["message"], "REQUEST BLOCKED BY ORGANIZATION POLICY")
+	assert.Contains(t, errObj["message"], "Employee access has been revoked")
+	assert.Contains(t, errObj["message"], "arfa policies list")
+}
+
+func TestAnthropicBlockedResponse_Streaming(t *testing.T) {
+	req, _ := http.NewRequest("POST", "https://api.anthropic.com/v1/messages", bytes.NewBufferString(`{"model":"claude-sonnet-4","stream":true}`))
+
+	resp := anthropicProvider{}.BlockedResponse(req, "Connection to policy server lost. All requests blocked.")
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(body), "event: error\ndata: "))
+
+	data := strings.TrimSuffix(strings.TrimPrefix(string(body), "event: error\ndata: "), "\n\n")
+	var event map[string]any
+	require.NoError(t, json.Unmarshal([]byte(data), &event))
+	errObj := event["error"].(map[string]any)
+	assert.Equal(t, "permission_error", errObj["type"])
+	assert.Contains(t, errObj["message"], "Connection to policy server lost")
+}
+
+func TestOpenAIBlockedResponse(t *testing.T) {
+	req, _ := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewBufferString(`{"model":"gpt-4o"}`))
+	resp := openAIProvider{}.BlockedResponse(req, "Employee access has been revoked")
+
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	var body struct {
+		Error struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+			Code    string `json:"code"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "permission_error", body.Error.Type)
+	assert.Equal(t, "blocked_by_policy", body.Error.Code)
+	assert.Contains(t, body.Error.Message, "Employee access has been revoked")
+
+	// Chat Completions streams the error as a data chunk
+	req, _ = http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewBufferString(`{"model":"gpt-4o","stream":true}`))
+	resp = openAIProvider{}.BlockedResponse(req, "Employee access has been revoked")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	events := readSSEEvents(t, resp.Body)
+	require.Len(t, events, 2)
+	assert.Empty(t, events[0].Event)
+	assert.Contains(t, events[0].Data, `"code":"blocked_by_policy"`)
+	assert.Equal(t, "[DONE]", events[1].Data)
+
+	// The Responses API has an error event
+	req, _ = http.NewRequest("POST", "https://api.openai.com/v1/responses", bytes.NewBufferString(`{"model":"gpt-5","stream":true}`))
+	resp = openAIProvider{}.BlockedResponse(req, "Employee access has been revoked")
+	events = readSSEEvents(t, resp.Body)
+	require.Len(t, events, 1)
+	assert.Equal(t, "error", events[0].Event)
+	var event map[string]any
+	require.NoError(t, json.Unmarshal([]byte(events[0].Data), &event))
+	assert.Equal(t, "error", event["type"])
+	assert.Contains(t, event["message"], "Employee access has been revoked")
+}
+
+func TestGeminiBlockedResponse(t *testing.T) {
+	// Gemini asks for a stream in the URL, not the body
+	req, _ := http.NewRequest("POST", "https://generativelanguage.googleapis.com/v1beta/models/gemini-2.5-pro:streamGenerateContent?alt=sse", bytes.NewBufferString(`{"contents":[]}`))
+	resp := geminiProvider{}.BlockedResponse(req, "Employee access has been revoked")
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	events := readSSEEvents(t, resp.Body)
+	require.Len(t, events, 1)
+	var chunk struct {
+		Error struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+			Status  string `json:"status"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(events[0].Data), &chunk))
+	assert.Equal(t, http.StatusForbidden, chunk.Error.Code)
+	assert.Equal(t, "PERMISSION_DENIED", chunk.Error.Status)
+	assert.Contains(t, chunk.Error.Message, "Employee access has been revoked")
+
+	req, _ = http.NewRequest("POST", "https://generativelanguage.googleapis.com/v1beta/models/gemini-2.5-pro:generateContent", bytes.NewBufferString(`{"contents":[]}`))
+	resp = geminiProvider{}.BlockedResponse(req, "Employee access has been revoked")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&chunk))
+	assert.Equal(t, "PERMISSION_DENIED", chunk.Error.Status)
+}
+
+func TestBedrockBlockedResponse(t *testing.T) {
+	req, _ := http.NewRequest("POST", "https://bedrock-runtime.us-east-1.amazonaws.com/model/anthropic.claude-sonnet-4-5-20250929-v1:0/invoke-with-response-stream", bytes.NewBufferString(`{"messages":[]}`))
+	resp := bedrockProvider{}.BlockedResponse(req, "Employee access has been revoked")
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, awsEventStreamContentType, resp.Header.Get("Content-Type"))
+	ev, err := newAWSEventStreamCodec(resp.Body).readEvent()
+	require.NoError(t, err)
+	assert.Equal(t, "validationException", ev.Event)
+	assert.Contains(t, ev.Data, "Employee access has been revoked")
+
+	req, _ = http.NewRequest("POST", "https://bedrock-runtime.us-east-1.amazonaws.com/model/anthropic.claude-sonnet-4-5-20250929-v1:0/invoke", bytes.NewBufferString(`{"messages":[]}`))
+	resp = bedrockProvider{}.BlockedResponse(req, "Employee access has been revoked")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, "AccessDeniedException", resp.Header.Get("X-Amzn-ErrorType"))
+	var body struct {
+		Message string `json:"message"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Contains(t, body.Message, "REQUEST BLOCKED BY ORGANIZATION POLICY")
+}
+
+// readSSEEvents reads every event of a server-sent event stream.
+func readSSEEvents(t *testing.T, r io.Reader) []SSEEvent {
+	t.Helper()
+	codec := newSSECodec(r)
+	var events []SSEEvent
+	for {
+		ev, err := codec.readEvent()
+		if err == io.EOF {
+			return events
+		}
+		require.NoError(t, err)
+		events = append(events, ev)
+	}
+}
+
+func TestIsStreamingRequest_RestoresBody(t *testing.T) {
+	payload := `{"stream":true,"messages":[]}`
+	req, _ := http.NewRequest("POST", "https://api.anthropic.com/v1/messages", bytes.NewBufferString(payload))
+
+	assert.True(t, isStreamingRequest(req))
+
+	body, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.Equal(t, payload, string(body))
+}
+
+func TestIsStreamingRequest_NoBodyOrInvalidJSON(t *testing.T) {
+	req, _ := http.NewRequest("GET", "https://api.anthropic.com/v1/models", nil)
+	assert.False(t, isStreamingRequest(req))
+
+	req, _ = http.NewRequest("POST", "https://api.anthropic.com/v1/messages", bytes.NewBufferString("not json"))
+	assert.False(t, isStreamingRequest(req))
+}
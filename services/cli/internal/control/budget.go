@@ -0,0 +1,263 @@
+package control
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// BudgetData is a token or cost budget with the usage counted against it in
+// the current period. The server tracks usage centrally and pushes budgets
+// with init and whenever usage or the budget changes.
+type BudgetData struct {
+	ID           string    `json:"id"`
+	OrgID        string    `json:"org_id"`
+	TeamID       *string   `json:"team_id,omitempty"`
+	EmployeeID   *string   `json:"employee_id,omitempty"`
+	Scope        string    `json:"scope"`                    // organization, team or employee
+	Period       string    `json:"period"`                   // daily or monthly
+	TokenLimit   *int64    `json:"token_limit,omitempty"`    // Input + output tokens
+	CostLimitUSD *float64  `json:"cost_limit_usd,omitempty"` // Estimated USD
+	TokensUsed   int64     `json:"tokens_used"`
+	CostUsedUSD  float64   `json:"cost_used_usd"`
+	ResetsAt     time.Time `json:"resets_at"`
+}
+
+// exceeded reports whether the budget's usage has reached either limit in a
+// period that hasn't ended yet.
+func (b BudgetData) exceeded(now time.Time) bool {
+	if !b.ResetsAt.IsZero() && !now.Before(b.ResetsAt) {
+		return false // New period; the server sends fresh usage with the next update
+	}
+	if b.TokenLimit != nil && b.TokensUsed >= *b.TokenLimit {
+		return true
+	}
+	return b.CostLimitUSD != nil && b.CostUsedUSD >= *b.CostLimitUSD
+}
+
+// reason describes an exceeded budget for the blocked request's error message.
+func (b BudgetData) reason() string {
+	limit := ""
+	switch {
+	case b.TokenLimit != nil && b.TokensUsed >= *b.TokenLimit:
+		limit = fmt.Sprintf("%d tokens", *b.TokenLimit)
+	case b.CostLimitUSD != nil:
+		limit = fmt.Sprintf("$%.2f", *b.CostLimitUSD)
+	}
+
+	reason := fmt.Sprintf("Budget exceeded: %s %s budget of %s used", b.Period, b.Scope, limit)
+	if !b.ResetsAt.IsZero() {
+		reason += fmt.Sprintf(" (resets %s)", b.ResetsAt.UTC().Format("2006-01-02 15:04 UTC"))
+	}
+	return reason
+}
+
+// Budgets holds the budgets that cover this proxy's employee.
+type Budgets struct {
+	mu      sync.RWMutex
+	budgets map[string]BudgetData // id -> budget
+	now     func() time.Time
+}
+
+// NewBudgets creates an empty budget set.
+func NewBudgets() *Budgets {
+	return &Budgets{
+		budgets: make(map[string]BudgetData),
+		now:     time.Now,
+	}
+}
+
+// Set replaces all budgets, as sent with init.
+func (b *Budgets) Set(budgets []BudgetData) {
+	m := make(map[string]BudgetData, len(budgets))
+	for _, budget := range budgets {
+		m[budget.ID] = budget
+	}
+
+	b.mu.Lock()
+	b.budgets = m
+	b.mu.Unlock()
+}
+
+// Upsert adds a budget or replaces it with the server's latest usage.
+func (b *Budgets) Upsert(budget BudgetData) {
+	b.mu.Lock()
+	b.budgets[budget.ID] = budget
+	b.mu.Unlock()
+}
+
+// Delete removes a budget.
+func (b *Budgets) Delete(id string) {
+	b.mu.Lock()
+	delete(b.budgets, id)
+	b.mu.Unlock()
+}
+
+// Add counts usage against every budget until the server's totals arrive,
+// so a limit reached by this proxy applies to its next request.
+func (b *Budgets) Add(usage Usage) {
+	cost := usage.Cost()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for id, budget := range b.budgets {
+		budget.TokensUsed += int64(usage.Tokens())
+		budget.CostUsedUSD += cost
+		b.budgets[id] = budget
+	}
+}
+
+// Exceeded returns an exceeded budget, if any. Budgets are checked in ID
+// order so the same one is reported each time.
+func (b *Budgets) Exceeded() (BudgetData, bool) {
+	now := b.now()
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	ids := make([]string, 0, len(b.budgets))
+	for id := range b.budgets {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		if budget := b.budgets[id]; budget.exceeded(now) {
+			return budget, true
+		}
+	}
+	return BudgetData{}, false
+}
+
+// BudgetHandler rejects conversation requests once a token or cost budget is
+// exceeded, and reports the usage of every response so the server can count
+// it against the organization's budgets.
+type BudgetHandler struct {
+	budgets *Budgets
+	queue   LoggerQueue
+}
+
+// NewBudgetHandler creates a budget handler. Usage is reported through queue.
+func NewBudgetHandler(budgets *Budgets, queue LoggerQueue) *BudgetHandler {
+	return &BudgetHandler{
+		budgets: budgets,
+		queue:   queue,
+	}
+}
+
+// Name returns the handler name.
+func (h *BudgetHandler) Name() string {
+	return "BudgetHandler"
+}
+
+// Priority returns 120 (before PolicyHandler at 110), so a request over
+// budget is rejected before anything else looks at it.
+func (h *BudgetHandler) Priority() int {
+	return 120
+}
+
+// HandleRequest blocks conversation requests while a budget is exceeded.
+func (h *BudgetHandler) HandleRequest(ctx *HandlerContext, req *http.Request) Result {
+	if req == nil || req.URL == nil || !contextProvider(ctx, req).MatchEndpoint(req.URL.Path) {
+		return ContinueResult()
+	}
+
+	if budget, exceeded := h.budgets.Exceeded(); exceeded {
+		return BlockResult(budget.reason())
+	}
+	return ContinueResult()
+}
+
+// HandleResponse reads the usage of a conversation response. JSON bodies are
+// read here; streams report usage once the client has read the last event.
+func (h *BudgetHandler) HandleResponse(ctx *HandlerContext, res *http.Response) Result {
+	if res == nil || res.Body == nil || res.Request == nil {
+		return ContinueResult()
+	}
+
+	provider := contextProvider(ctx, res.Request)
+	if !provider.MatchEndpoint(res.Request.URL.Path) {
+		return ContinueResult()
+	}
+
+	if isJSONResponse(res) {
+		body, err := io.ReadAll(res.Body)
+		_ = res.Body.Close()
+		res.Body = io.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			return ContinueResult()
+		}
+		h.recordUsage(ctx, provider, provider.Usage(body))
+		return ContinueResult()
+	}
+
+	if !isEventStream(res) {
+		return ContinueResult()
+	}
+
+	AttachSSEProcessor(res, &usageStreamProcessor{h: h, ctx: ctx, provider: provider})
+
+	return Result{
+		Action:           ActionContinue,
+		ModifiedResponse: res,
+	}
+}
+
+// recordUsage counts usage locally and queues an llm_usage entry for the server.
+func (h *BudgetHandler) recordUsage(ctx *HandlerContext, provider Provider, usage Usage) {
+	if usage.IsZero() {
+		return
+	}
+
+	h.budgets.Add(usage)
+
+	if h.queue == nil {
+		return
+	}
+	_ = h.queue.Enqueue(LogEntry{
+		EmployeeID:    ctx.EmployeeID,
+		OrgID:         ctx.OrgID,
+		ClientName:    ctx.ClientName,
+		ClientVersion: ctx.ClientVersion,
+		EventType:     "llm_usage",
+		EventCategory: "proxy",
+		Timestamp:     time.Now(),
+		Payload: map[string]interface{}{
+			"provider":      string(provider.Name()),
+			"model":         usage.Model,
+			"input_tokens":  usage.InputTokens,
+			"output_tokens": usage.OutputTokens,
+			"cost_usd":      usage.Cost(),
+			"session_id":    ctx.SessionID,
+			"request_id":    ctx.RequestID,
+		},
+	})
+}
+
+// usageStreamProcessor totals the usage reported across one streaming
+// response without altering it.
+type usageStreamProcessor struct {
+	h        *BudgetHandler
+	ctx      *HandlerContext
+	provider Provider
+	usage    Usage
+}
+
+// ProcessEvent folds the event's usage into the total and forwards it unchanged.
+func (p *usageStreamProcessor) ProcessEvent(ev SSEEvent) []SSEEvent {
+	if ev.Data != "" && ev.Data != "[DONE]" {
+		p.usage.merge(p.provider.Usage([]byte(ev.Data)))
+	}
+	return []SSEEvent{ev}
+}
+
+// Finish records the total, including that of a stream cut short: the
+// tokens were used either way.
+func (p *usageStreamProcessor) Finish(err error) []SSEEvent {
+	p.h.recordUsage(p.ctx, p.provider, p.usage)
+	return nil
+}
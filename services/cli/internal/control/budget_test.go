@@ -0,0 +1,229 @@
+package control
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBudgets_Exceeded(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	tokenLimit := int64(1000)
+	costLimit := 5.0
+
+	b := NewBudgets()
+	b.now = func() time.Time { return now }
+	b.Set([]BudgetData{
+		{ID: "b-1", Period: "daily", Scope: "employee", TokenLimit: &tokenLimit, TokensUsed: 999, ResetsAt: now.Add(time.Hour)},
+		{ID: "b-2", Period: "monthly", Scope: "team", CostLimitUSD: &costLimit, CostUsedUSD: 1, ResetsAt: now.Add(time.Hour)},
+	})
+
+	_, exceeded := b.Exceeded()
+	assert.False(t, exceeded)
+
+	// Usage reported by this proxy counts before the server's totals arrive
+	b.Add(Usage{Model: "claude-sonnet-4-5", InputTokens: 1, OutputTokens: 1})
+	budget, exceeded := b.Exceeded()
+	require.True(t, exceeded)
+	assert.Equal(t, "b-1", budget.ID)
+	assert.Equal(t, "Budget exceeded: daily employee budget of 1000 tokens used (resets 2026-03-10 13:00 UTC)", budget.reason())
+
+	// The server's update replaces the local estimate
+	b.Upsert(BudgetData{ID: "b-1", Period: "daily", Scope: "employee", TokenLimit: &tokenLimit, TokensUsed: 10, ResetsAt: now.Add(time.Hour)})
+	_, exceeded = b.Exceeded()
+	assert.False(t, exceeded)
+
+	b.Upsert(BudgetData{ID: "b-2", Period: "monthly", Scope: "team", CostLimitUSD: &costLimit, CostUsedUSD: 5, ResetsAt: now.Add(time.Hour)})
+	budget, exceeded = b.Exceeded()
+	require.True(t, exceeded)
+	assert.Contains(t, budget.reason(), "monthly team budget of $5.00 used")
+
+	b.Delete("b-2")
+	_, exceeded = b.Exceeded()
+	assert.False(t, exceeded)
+}
+
+func TestBudgets_ExceededEndsWithPeriod(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	tokenLimit := int64(1000)
+
+	b := NewBudgets()
+	b.now = func() time.Time { return now }
+	b.Set([]BudgetData{{ID: "b-1", Period: "daily", TokenLimit: &tokenLimit, TokensUsed: 5000, ResetsAt: now}})
+
+	_, exceeded := b.Exceeded()
+	assert.False(t, exceeded, "usage from a period that has ended doesn't count")
+}
+
+func TestBudgetHandler_BlocksConversationRequests(t *testing.T) {
+	tokenLimit := int64(1000)
+	budgets := NewBudgets()
+	budgets.Set([]BudgetData{{ID: "b-1", Period: "monthly", Scope: "organization", TokenLimit: &tokenLimit, TokensUsed: 1000}})
+	h := NewBudgetHandler(budgets, nil)
+	ctx := NewHandlerContext("emp-1", "org-1", "sess-1")
+
+	assert.Equal(t, "BudgetHandler", h.Name())
+	assert.Equal(t, 120, h.Priority())
+
+	req, _ := http.NewRequest("POST", "https://api.anthropic.com/v1/messages", nil)
+	result := h.HandleRequest(ctx, req)
+	assert.True(t, result.ShouldBlock())
+	assert.Contains(t, result.Reason, "Budget exceeded: monthly organization budget of 1000 tokens used")
+
+	// Other endpoints are left alone
+	req, _ = http.NewRequest("GET", "https://api.anthropic.com/v1/models", nil)
+	assert.True(t, h.HandleRequest(ctx, req).ShouldContinue())
+}
+
+func TestBudgetHandler_RecordsStreamUsage(t *testing.T) {
+	queue := &mockToolLoggerQueue{}
+	budgets := NewBudgets()
+	h := NewBudgetHandler(budgets, queue)
+	ctx := &HandlerContext{EmployeeID: "emp-1", OrgID: "org-1", SessionID: "sess-1", RequestID: "req-1"}
+
+	stream := `event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","model":"claude-sonnet-4-5","usage":{"input_tokens":100,"cache_read_input_tokens":900,"output_tokens":1}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":250}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`
+	req, _ := http.NewRequest("POST", "https://api.anthropic.com/v1/messages", nil)
+	res := &http.Response{
+		StatusCode: 200,
+		Request:    req,
+		Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+		Body:       io.NopCloser(bytes.NewReader([]byte(stream))),
+	}
+
+	result := h.HandleResponse(ctx, res)
+	require.NotNil(t, result.ModifiedResponse)
+	assert.Empty(t, queue.Entries(), "usage is reported once the stream ends")
+
+	assert.Equal(t, stream, string(drainBody(t, res)))
+
+	entries := queue.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "llm_usage", entries[0].EventType)
+	assert.Equal(t, "emp-1", entries[0].EmployeeID)
+	assert.Equal(t, "claude-sonnet-4-5", entries[0].Payload["model"])
+	assert.Equal(t, 1000, entries[0].Payload["input_tokens"])
+	assert.Equal(t, 250, entries[0].Payload["output_tokens"])
+	assert.Equal(t, "req-1", entries[0].Payload["request_id"])
+	assert.InDelta(t, 0.00675, entries[0].Payload["cost_usd"], 1e-9)
+}
+
+func TestBudgetHandler_RecordsUsageOfStreamClosedEarly(t *testing.T) {
+	queue := &mockToolLoggerQueue{}
+	h := NewBudgetHandler(NewBudgets(), queue)
+	ctx := &HandlerContext{EmployeeID: "emp-1", OrgID: "org-1", SessionID: "sess-1", RequestID: "req-1"}
+
+	stream := `event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","model":"claude-sonnet-4-5","usage":{"input_tokens":100,"output_tokens":1}}}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":250}}
+
+`
+	req, _ := http.NewRequest("POST", "https://api.anthropic.com/v1/messages", nil)
+	res := &http.Response{
+		StatusCode: 200,
+		Request:    req,
+		Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+		Body:       io.NopCloser(bytes.NewReader([]byte(stream))),
+	}
+	h.HandleResponse(ctx, res)
+
+	// The client disconnects after the first event
+	_, err := res.Body.Read(make([]byte, 16))
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+
+	entries := queue.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "llm_usage", entries[0].EventType)
+	assert.Equal(t, 100, entries[0].Payload["input_tokens"])
+	assert.Equal(t, 1, entries[0].Payload["output_tokens"])
+}
+
+func TestBudgetHandler_RecordsJSONUsage(t *testing.T) {
+	queue := &mockToolLoggerQueue{}
+	tokenLimit := int64(100)
+	budgets := NewBudgets()
+	budgets.Set([]BudgetData{{ID: "b-1", Period: "daily", Scope: "employee", TokenLimit: &tokenLimit}})
+	h := NewBudgetHandler(budgets, queue)
+	ctx := NewHandlerContext("emp-1", "org-1", "sess-1")
+
+	body := `{"id":"chatcmpl-1","model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"hi"}}],"usage":{"prompt_tokens":80,"completion_tokens":40}}`
+	res := jsonResponse("https://api.openai.com/v1/chat/completions", body)
+
+	result := h.HandleResponse(ctx, res)
+	assert.True(t, result.ShouldContinue())
+	assert.Equal(t, body, string(drainBody(t, res)), "body is left for the client")
+
+	entries := queue.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "openai", entries[0].Payload["provider"])
+	assert.Equal(t, 80, entries[0].Payload["input_tokens"])
+
+	// The next request is rejected without waiting for the server
+	req, _ := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", nil)
+	assert.True(t, h.HandleRequest(ctx, req).ShouldBlock())
+}
+
+func TestProviderUsage(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider Provider
+		data     string
+		want     Usage
+	}{
+		{
+			name:     "anthropic message",
+			provider: anthropicProvider{},
+			data:     `{"type":"message","model":"claude-opus-4-1","usage":{"input_tokens":10,"cache_creation_input_tokens":5,"output_tokens":7}}`,
+			want:     Usage{Model: "claude-opus-4-1", InputTokens: 15, OutputTokens: 7},
+		},
+		{
+			name:     "anthropic content event",
+			provider: anthropicProvider{},
+			data:     `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"hi"}}`,
+			want:     Usage{},
+		},
+		{
+			name:     "openai responses completed",
+			provider: openAIProvider{},
+			data:     `{"type":"response.completed","response":{"model":"gpt-5","usage":{"input_tokens":30,"output_tokens":12}}}`,
+			want:     Usage{Model: "gpt-5", InputTokens: 30, OutputTokens: 12},
+		},
+		{
+			name:     "gemini",
+			provider: geminiProvider{},
+			data:     `{"candidates":[],"usageMetadata":{"promptTokenCount":40,"candidatesTokenCount":9,"thoughtsTokenCount":3},"modelVersion":"gemini-2.5-pro"}`,
+			want:     Usage{Model: "gemini-2.5-pro", InputTokens: 40, OutputTokens: 12},
+		},
+		{
+			name:     "gemini code assist",
+			provider: geminiProvider{},
+			data:     `{"response":{"usageMetadata":{"promptTokenCount":4,"candidatesTokenCount":2},"modelVersion":"gemini-2.5-flash"}}`,
+			want:     Usage{Model: "gemini-2.5-flash", InputTokens: 4, OutputTokens: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.provider.Usage([]byte(tt.data)))
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package control
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/rastrigin-systems/arfa/services/cli/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func conditionInput(t *testing.T, s string) map[string]interface{} {
+	t.Helper()
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(s), &m))
+	return m
+}
+
+func TestPolicyHandler_StructuredConditions(t *testing.T) {
+	// The conditions arfa policies create --condition sends
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{{
+		ToolName: "Bash",
+		Action:   api.ToolPolicyActionDeny,
+		Conditions: map[string]interface{}{
+			"any": []interface{}{
+				map[string]interface{}{"param_path": "command", "operator": "matches", "value": `rm\s+-rf`},
+				map[string]interface{}{"param_path": "command", "operator": "contains", "value": "sudo"},
+			},
+		},
+	}})
+
+	_, blocked := h.evaluateConditions("Bash", `{"command":"sudo apt install jq"}`)
+	assert.True(t, blocked)
+	_, blocked = h.evaluateConditions("Bash", `{"command":"rm -rf build"}`)
+	assert.True(t, blocked)
+	_, blocked = h.evaluateConditions("Bash", `{"command":"ls -la"}`)
+	assert.False(t, blocked)
+}
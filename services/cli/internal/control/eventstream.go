@@ -0,0 +1,198 @@
+package control
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// awsEventStreamContentType is the content type of Bedrock streaming responses.
+const awsEventStreamContentType = "application/vnd.amazon.eventstream"
+
+// AWS event-stream framing: a 12-byte prelude (total length, headers length,
+// prelude CRC), the headers, the payload, and a CRC of the whole message.
+const (
+	eventStreamPreludeLen = 12
+	eventStreamCRCLen     = 4
+	eventStreamMaxMessage = 16 << 20
+
+	// eventStreamStringHeader is the header value type for UTF-8 strings.
+	eventStreamStringHeader = 7
+)
+
+// isAWSEventStream reports whether the response uses AWS event-stream framing.
+func isAWSEventStream(res *http.Response) bool {
+	return strings.Contains(res.Header.Get("Content-Type"), awsEventStreamContentType)
+}
+
+// awsEventStreamCodec decodes Bedrock InvokeModelWithResponseStream frames.
+// Each "chunk" event carries a base64-encoded Anthropic streaming event, which is
+// exposed as an SSEEvent named after its "type" so the Anthropic parser applies
+// unchanged. Other frames (exceptions) pass through byte for byte.
+type awsEventStreamCodec struct {
+	r io.Reader
+}
+
+// newAWSEventStreamCodec creates a codec reading frames from body.
+func newAWSEventStreamCodec(body io.Reader) *awsEventStreamCodec {
+	return &awsEventStreamCodec{r: body}
+}
+
+// eventStreamChunk is the payload of a Bedrock chunk event.
+type eventStreamChunk struct {
+	Bytes string `json:"bytes"`
+}
+
+// readEvent reads and decodes the next frame.
+func (c *awsEventStreamCodec) readEvent() (SSEEvent, error) {
+	prelude := make([]byte, eventStreamPreludeLen)
+	if _, err := io.ReadFull(c.r, prelude); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return SSEEvent{}, fmt.Errorf("truncated event-stream prelude: %w", err)
+		}
+		return SSEEvent{}, err
+	}
+
+	totalLen := binary.BigEndian.Uint32(prelude[0:4])
+	headersLen := binary.BigEndian.Uint32(prelude[4:8])
+	if crc32.ChecksumIEEE(prelude[0:8]) != binary.BigEndian.Uint32(prelude[8:12]) {
+		return SSEEvent{}, fmt.Errorf("event-stream prelude checksum mismatch")
+	}
+	if totalLen > eventStreamMaxMessage || totalLen < eventStreamPreludeLen+eventStreamCRCLen {
+		return SSEEvent{}, fmt.Errorf("invalid event-stream message length %d", totalLen)
+	}
+	// Compared without adding to headersLen, which could overflow
+	if headersLen > totalLen-eventStreamPreludeLen-eventStreamCRCLen {
+		return SSEEvent{}, fmt.Errorf("invalid event-stream headers length %d", headersLen)
+	}
+
+	frame := make([]byte, totalLen)
+	copy(frame, prelude)
+	if _, err := io.ReadFull(c.r, frame[eventStreamPreludeLen:]); err != nil {
+		return SSEEvent{}, fmt.Errorf("truncated event-stream message: %w", err)
+	}
+	crcPos := totalLen - eventStreamCRCLen
+	if crc32.ChecksumIEEE(frame[:crcPos]) != binary.BigEndian.Uint32(frame[crcPos:]) {
+		return SSEEvent{}, fmt.Errorf("event-stream message checksum mismatch")
+	}
+
+	headersEnd := eventStreamPreludeLen + headersLen
+	headers := parseEventStreamHeaders(frame[eventStreamPreludeLen:headersEnd])
+	payload := frame[headersEnd:crcPos]
+
+	if headers[":message-type"] == "event" && headers[":event-type"] == "chunk" {
+		var chunk eventStreamChunk
+		if err := json.Unmarshal(payload, &chunk); err == nil {
+			if data, err := base64.StdEncoding.DecodeString(chunk.Bytes); err == nil {
+				var event struct {
+					Type string `json:"type"`
+				}
+				_ = json.Unmarshal(data, &event)
+				return SSEEvent{Event: event.Type, Data: string(data)}, nil
+			}
+		}
+	}
+
+	// Pass anything else through untouched, labelled for logging
+	name := headers[":exception-type"]
+	if name == "" {
+		name = headers[":event-type"]
+	}
+	return SSEEvent{Event: name, Data: string(payload), raw: frame}, nil
+}
+
+// writeEvent encodes the event as a chunk frame, or writes a passed-through
+// frame as it was received.
+func (c *awsEventStreamCodec) writeEvent(w *bytes.Buffer, ev SSEEvent) {
+	if ev.raw != nil {
+		w.Write(ev.raw)
+		return
+	}
+
+	payload, _ := json.Marshal(eventStreamChunk{
+		Bytes: base64.StdEncoding.EncodeToString([]byte(ev.Data)),
+	})
+	writeEventStreamFrame(w, [][2]string{
+		{":event-type", "chunk"},
+		{":content-type", "application/json"},
+		{":message-type", "event"},
+	}, payload)
+}
+
+// parseEventStreamHeaders returns the string-valued headers of a frame.
+// Headers of other types are skipped.
+func parseEventStreamHeaders(b []byte) map[string]string {
+	headers := make(map[string]string)
+	for len(b) > 0 {
+		nameLen := int(b[0])
+		if len(b) < 1+nameLen+1 {
+			break
+		}
+		name := string(b[1 : 1+nameLen])
+		valueType := b[1+nameLen]
+		b = b[2+nameLen:]
+
+		size, ok := eventStreamHeaderValueSize(valueType, b)
+		if !ok || len(b) < size {
+			break
+		}
+		if valueType == eventStreamStringHeader {
+			headers[name] = string(b[2:size])
+		}
+		b = b[size:]
+	}
+	return headers
+}
+
+// eventStreamHeaderValueSize returns the encoded size of a header value.
+func eventStreamHeaderValueSize(valueType byte, b []byte) (int, bool) {
+	switch valueType {
+	case 0, 1: // bool true/false
+		return 0, true
+	case 2: // byte
+		return 1, true
+	case 3: // int16
+		return 2, true
+	case 4: // int32
+		return 4, true
+	case 5, 8: // int64, timestamp
+		return 8, true
+	case 6, 7: // byte array, string
+		if len(b) < 2 {
+			return 0, false
+		}
+		return 2 + int(binary.BigEndian.Uint16(b)), true
+	case 9: // uuid
+		return 16, true
+	}
+	return 0, false
+}
+
+// writeEventStreamFrame encodes a message with string headers.
+func writeEventStreamFrame(w *bytes.Buffer, headers [][2]string, payload []byte) {
+	var hdr bytes.Buffer
+	for _, h := range headers {
+		hdr.WriteByte(byte(len(h[0])))
+		hdr.WriteString(h[0])
+		hdr.WriteByte(eventStreamStringHeader)
+		_ = binary.Write(&hdr, binary.BigEndian, uint16(len(h[1])))
+		hdr.WriteString(h[1])
+	}
+
+	totalLen := eventStreamPreludeLen + hdr.Len() + len(payload) + eventStreamCRCLen
+	frame := make([]byte, 0, totalLen)
+	frame = binary.BigEndian.AppendUint32(frame, uint32(totalLen))
+	frame = binary.BigEndian.AppendUint32(frame, uint32(hdr.Len()))
+	frame = binary.BigEndian.AppendUint32(frame, crc32.ChecksumIEEE(frame))
+	frame = append(frame, hdr.Bytes()...)
+	frame = append(frame, payload...)
+	frame = binary.BigEndian.AppendUint32(frame, crc32.ChecksumIEEE(frame))
+
+	w.Write(frame)
+}
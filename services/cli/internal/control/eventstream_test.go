@@ -0,0 +1,142 @@
+package control
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bedrockFrames encodes Anthropic events as Bedrock chunk frames.
+func bedrockFrames(events ...SSEEvent) []byte {
+	var buf bytes.Buffer
+	codec := newAWSEventStreamCodec(nil)
+	for _, ev := range events {
+		codec.writeEvent(&buf, ev)
+	}
+	return buf.Bytes()
+}
+
+// decodeBedrockFrames decodes every frame in data.
+func decodeBedrockFrames(t *testing.T, data []byte) []SSEEvent {
+	t.Helper()
+	codec := newAWSEventStreamCodec(bytes.NewReader(data))
+	var events []SSEEvent
+	for {
+		ev, err := codec.readEvent()
+		if err == io.EOF {
+			return events
+		}
+		require.NoError(t, err)
+		events = append(events, ev)
+	}
+}
+
+func TestAWSEventStreamCodec_RoundTrip(t *testing.T) {
+	data := bedrockFrames(
+		SSEEvent{Data: `{"type":"message_start","message":{"id":"msg_1"}}`},
+		SSEEvent{Data: `{"type":"message_stop"}`},
+	)
+
+	events := decodeBedrockFrames(t, data)
+	require.Len(t, events, 2)
+	assert.Equal(t, "message_start", events[0].Event)
+	assert.Equal(t, `{"type":"message_start","message":{"id":"msg_1"}}`, events[0].Data)
+	assert.Equal(t, "message_stop", events[1].Event)
+}
+
+func TestAWSEventStreamCodec_ChecksumMismatch(t *testing.T) {
+	data := bedrockFrames(SSEEvent{Data: `{"type":"ping"}`})
+	data[len(data)-1] ^= 0xff
+
+	_, err := newAWSEventStreamCodec(bytes.NewReader(data)).readEvent()
+	assert.ErrorContains(t, err, "checksum")
+}
+
+func TestAWSEventStreamCodec_ForgedHeadersLength(t *testing.T) {
+	// A headers length that wraps around when added to the prelude and
+	// checksum lengths, in a frame whose checksums are otherwise valid
+	frame := make([]byte, 20)
+	binary.BigEndian.PutUint32(frame[0:4], uint32(len(frame)))
+	binary.BigEndian.PutUint32(frame[4:8], 0xfffffff8)
+	binary.BigEndian.PutUint32(frame[8:12], crc32.ChecksumIEEE(frame[0:8]))
+	binary.BigEndian.PutUint32(frame[16:20], crc32.ChecksumIEEE(frame[0:16]))
+
+	_, err := newAWSEventStreamCodec(bytes.NewReader(frame)).readEvent()
+	assert.ErrorContains(t, err, "headers length")
+}
+
+func TestAWSEventStreamCodec_ExceptionPassesThrough(t *testing.T) {
+	var frame bytes.Buffer
+	writeEventStreamFrame(&frame, [][2]string{
+		{":exception-type", "throttlingException"},
+		{":content-type", "application/json"},
+		{":message-type", "exception"},
+	}, []byte(`{"message":"Too many requests"}`))
+
+	codec := newAWSEventStreamCodec(bytes.NewReader(frame.Bytes()))
+	ev, err := codec.readEvent()
+	require.NoError(t, err)
+	assert.Equal(t, "throttlingException", ev.Event)
+	assert.Equal(t, `{"message":"Too many requests"}`, ev.Data)
+
+	var out bytes.Buffer
+	codec.writeEvent(&out, ev)
+	assert.Equal(t, frame.Bytes(), out.Bytes())
+}
+
+func TestBedrockProvider_MatchEndpoint(t *testing.T) {
+	assert.Equal(t, bedrockProvider{}, providerForHost("bedrock-runtime.us-east-1.amazonaws.com"))
+	assert.Equal(t, vertexProvider{}, providerForHost("us-east5-aiplatform.googleapis.com"))
+
+	assert.True(t, bedrockProvider{}.MatchEndpoint("/model/anthropic.claude-sonnet-4-20250514-v1:0/invoke-with-response-stream"))
+	assert.True(t, bedrockProvider{}.MatchEndpoint("/model/arn:aws:bedrock:us-east-1:123:inference-profile/us.anthropic.claude-sonnet-4/invoke"))
+	assert.False(t, bedrockProvider{}.MatchEndpoint("/model/anthropic.claude/converse"))
+
+	assert.True(t, vertexProvider{}.MatchEndpoint("/v1/projects/p/locations/us-east5/publishers/anthropic/models/claude-sonnet-4@20250514:streamRawPredict"))
+	assert.False(t, vertexProvider{}.MatchEndpoint("/v1/projects/p/locations/us-east5/publishers/google/models/gemini-2.5-pro:streamGenerateContent"))
+}
+
+func TestPolicyHandler_Bedrock_BlockedTool(t *testing.T) {
+	queue := &mockToolLoggerQueue{}
+	h := NewPolicyHandlerWithDenyList(map[string]string{"Bash": "Shell commands are blocked"})
+	h.SetQueue(queue)
+
+	upstream := bedrockFrames(
+		SSEEvent{Data: `{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"Bash","input":{}}}`},
+		SSEEvent{Data: `{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"command\":\"ls\"}"}}`},
+		SSEEvent{Data: `{"type":"content_block_stop","index":0}`},
+		SSEEvent{Data: `{"type":"message_delta","delta":{"stop_reason":"tool_use"}}`},
+	)
+
+	req, _ := http.NewRequest("POST", "https://bedrock-runtime.us-east-1.amazonaws.com/model/anthropic.claude-sonnet-4/invoke-with-response-stream", nil)
+	res := &http.Response{
+		StatusCode:    200,
+		Request:       req,
+		Header:        http.Header{"Content-Type": []string{awsEventStreamContentType}},
+		ContentLength: int64(len(upstream)),
+		Body:          io.NopCloser(bytes.NewReader(upstream)),
+	}
+
+	result := h.HandleResponse(NewHandlerContext("emp-1", "org-1", "sess-1"), res)
+	require.NotNil(t, result.ModifiedResponse)
+	assert.Equal(t, int64(-1), result.ModifiedResponse.ContentLength)
+
+	events := decodeBedrockFrames(t, drainBody(t, result.ModifiedResponse))
+	require.Len(t, events, 4)
+	assert.Equal(t, "content_block_start", events[0].Event)
+	assert.Contains(t, events[0].Data, `"type":"text"`)
+	assert.Contains(t, events[1].Data, "Shell commands are blocked")
+	assert.Equal(t, "content_block_stop", events[2].Event)
+	assert.Contains(t, events[3].Data, `"stop_reason":"end_turn"`)
+
+	entries := queue.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "Bash", entries[0].Payload["tool_name"])
+	assert.Equal(t, map[string]interface{}{"command": "ls"}, entries[0].Payload["tool_input"])
+}
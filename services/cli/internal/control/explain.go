@@ -0,0 +1,149 @@
+package control
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/rastrigin-systems/arfa/services/cli/internal/api"
+)
+
+// Decision is what the handler would do with one tool call, and which
+// policies decided it.
+type Decision struct {
+	// Outcome is deny, require_approval or allow.
+	Outcome api.ToolPolicyAction `json:"outcome"`
+	// Reason is shown to the agent in place of a denied call.
+	Reason string `json:"reason,omitempty"`
+	// Policy is the deny or require_approval policy that decided the call;
+	// nil when the call is allowed.
+	Policy *PolicyMatch `json:"policy,omitempty"`
+	// RateLimits are the rate_limit policies the call counts against.
+	RateLimits []PolicyMatch `json:"rate_limits,omitempty"`
+	// Overrides are the restrictions allow policies lifted for the call.
+	Overrides []Override `json:"overrides,omitempty"`
+	// Audits are the audit policies that log the call once let through.
+	Audits []PolicyMatch `json:"audits,omitempty"`
+}
+
+// PolicyMatch identifies a policy that applied to a tool call.
+type PolicyMatch struct {
+	ID         string                 `json:"id,omitempty"`
+	ToolName   string                 `json:"tool_name"`
+	Action     api.ToolPolicyAction   `json:"action"`
+	Scope      api.ToolPolicyScope    `json:"scope,omitempty"`
+	Conditions map[string]interface{} `json:"conditions,omitempty"`
+}
+
+// Override is a restriction an allow policy lifted.
+type Override struct {
+	Allow       PolicyMatch `json:"allow"`
+	Restriction PolicyMatch `json:"restriction"`
+}
+
+// Explain evaluates a tool call the way the stream processor does once its
+// input is complete, without counting it against rate limits, waiting for
+// approval or logging it.
+func (h *PolicyHandler) Explain(toolName, input string) Decision {
+	// Blocked tools no allow policy names are denied before their input
+	// streams in, so conditions can't pick a different deny policy
+	denyInput := input
+	if _, blocked := h.isBlocked(toolName); blocked && !h.hasAllowPolicies(toolName) {
+		denyInput = ""
+	}
+	if policy, blocked := h.matchDenyPolicy(toolName, denyInput, true); blocked {
+		match := newPolicyMatch(policy)
+		return Decision{Outcome: api.ToolPolicyActionDeny, Reason: policy.Reason, Policy: &match}
+	}
+
+	inputMap := parseToolInput(input)
+	d := Decision{Outcome: api.ToolPolicyActionAllow}
+	for _, rule := range h.matchRateLimits(toolName, inputMap) {
+		d.RateLimits = append(d.RateLimits, newPolicyMatch(rule.policyRule))
+	}
+	if policy, ok := h.matchApprovalPolicy(toolName, input); ok {
+		match := newPolicyMatch(policy)
+		d.Outcome, d.Reason, d.Policy = api.ToolPolicyActionRequireApproval, policy.Reason, &match
+	}
+	for _, o := range h.matchOverrides(toolName, inputMap) {
+		d.Overrides = append(d.Overrides, Override{Allow: newPolicyMatch(o.allow), Restriction: newPolicyMatch(o.restriction)})
+	}
+	for _, rule := range h.matchAuditPolicies(toolName, input) {
+		d.Audits = append(d.Audits, newPolicyMatch(rule))
+	}
+	return d
+}
+
+// newPolicyMatch describes a loaded policy.
+func newPolicyMatch(rule policyRule) PolicyMatch {
+	return PolicyMatch{
+		ID:         rule.ID,
+		ToolName:   rule.ToolName,
+		Action:     rule.Action,
+		Scope:      rule.Scope,
+		Conditions: rule.Conditions,
+	}
+}
+
+// captureProviders are the providers whose captured responses ParseCapture
+// reads, by name.
+var captureProviders = map[string]Provider{
+	"anthropic": anthropicProvider{},
+	"openai":    openAIProvider{},
+	"gemini":    geminiProvider{},
+}
+
+// CaptureProviders lists the provider names ParseCapture accepts.
+func CaptureProviders() []string {
+	names := make([]string, 0, len(captureProviders))
+	for name := range captureProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseCapture returns the complete tool calls in a captured provider
+// response: an SSE stream or a JSON message. Calls whose stream was cut off
+// are left out, as the handler never releases them.
+func ParseCapture(data []byte, provider string) ([]ToolCall, error) {
+	p, ok := captureProviders[provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q (want one of %s)", provider, strings.Join(CaptureProviders(), ", "))
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		return p.MessageToolCalls(trimmed)
+	}
+
+	codec := newSSECodec(bytes.NewReader(data))
+	parser := p.NewStreamParser()
+	started := make(map[int]*ToolCall)
+	var calls []ToolCall
+	for {
+		ev, err := codec.readEvent()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, te := range parser.Parse(ev) {
+			switch te.Type {
+			case ToolStart:
+				started[te.Index] = &ToolCall{Index: te.Index, ToolID: te.ToolID, ToolName: te.ToolName}
+			case ToolInput:
+				if call, ok := started[te.Index]; ok {
+					call.Input += te.Input
+				}
+			case ToolStop:
+				if call, ok := started[te.Index]; ok {
+					delete(started, te.Index)
+					calls = append(calls, *call)
+				}
+			}
+		}
+	}
+	return calls, nil
+}
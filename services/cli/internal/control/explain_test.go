@@ -0,0 +1,94 @@
+package control
+
+import (
+	"testing"
+
+	"github.com/rastrigin-systems/arfa/services/cli/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyHandler_Explain(t *testing.T) {
+	reason := "Destructive commands blocked"
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{
+		{ID: "no-rm", ToolName: "Bash", Action: api.ToolPolicyActionDeny, Scope: api.ToolPolicyScopeOrganization, Reason: &reason,
+			Conditions: map[string]interface{}{"command": `rm\s+-rf`}},
+		{ID: "approve-push", ToolName: "Bash", Action: api.ToolPolicyActionRequireApproval, Scope: api.ToolPolicyScopeTeam,
+			Conditions: map[string]interface{}{"command": `^git push`}},
+		{ID: "limit", ToolName: "Bash", Action: api.ToolPolicyActionRateLimit, Scope: api.ToolPolicyScopeOrganization,
+			Conditions: map[string]interface{}{"rate_limit": map[string]interface{}{"max_calls": 1, "window_seconds": 60}}},
+		{ID: "audit", ToolName: "%", Action: api.ToolPolicyActionAudit, Scope: api.ToolPolicyScopeOrganization},
+		{ID: "no-web", ToolName: "WebFetch", Action: api.ToolPolicyActionDeny, Scope: api.ToolPolicyScopeOrganization},
+		{ID: "allow-web", ToolName: "WebFetch", Action: api.ToolPolicyActionAllow, Scope: api.ToolPolicyScopeEmployee},
+	})
+
+	d := h.Explain("Bash", `{"command":"rm -rf /"}`)
+	assert.Equal(t, api.ToolPolicyActionDeny, d.Outcome)
+	assert.Equal(t, reason, d.Reason)
+	require.NotNil(t, d.Policy)
+	assert.Equal(t, "no-rm", d.Policy.ID)
+	assert.Equal(t, `rm\s+-rf`, d.Policy.Conditions["command"])
+
+	d = h.Explain("Bash", `{"command":"git push origin main"}`)
+	assert.Equal(t, api.ToolPolicyActionRequireApproval, d.Outcome)
+	require.NotNil(t, d.Policy)
+	assert.Equal(t, "approve-push", d.Policy.ID)
+
+	// Explaining a call doesn't count it, so the limit of one is never hit
+	for i := 0; i < 3; i++ {
+		d = h.Explain("Bash", `{"command":"ls"}`)
+		assert.Equal(t, api.ToolPolicyActionAllow, d.Outcome)
+		assert.Nil(t, d.Policy)
+		require.Len(t, d.RateLimits, 1)
+		assert.Equal(t, "limit", d.RateLimits[0].ID)
+		require.Len(t, d.Audits, 1)
+		assert.Equal(t, "audit", d.Audits[0].ID)
+	}
+
+	d = h.Explain("WebFetch", `{"url":"https://example.com"}`)
+	assert.Equal(t, api.ToolPolicyActionAllow, d.Outcome)
+	require.Len(t, d.Overrides, 1)
+	assert.Equal(t, "allow-web", d.Overrides[0].Allow.ID)
+	assert.Equal(t, "no-web", d.Overrides[0].Restriction.ID)
+}
+
+func TestPolicyHandler_Explain_MatchesStream(t *testing.T) {
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{
+		{ID: "no-bash", ToolName: "Bash", Action: api.ToolPolicyActionDeny, Scope: api.ToolPolicyScopeOrganization},
+		{ID: "no-rm", ToolName: "Bash", Action: api.ToolPolicyActionDeny, Scope: api.ToolPolicyScopeEmployee,
+			Conditions: map[string]interface{}{"command": `^rm`}},
+	})
+
+	// The stream blocks Bash before its input arrives, so the unconditional
+	// policy decides even though the conditional one outranks it
+	d := h.Explain("Bash", `{"command":"rm x"}`)
+	require.NotNil(t, d.Policy)
+	assert.Equal(t, "no-bash", d.Policy.ID)
+}
+
+func TestParseCapture(t *testing.T) {
+	calls, err := ParseCapture([]byte(auditedBashStream("git status")), "anthropic")
+	require.NoError(t, err)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "Bash", calls[0].ToolName)
+	assert.Equal(t, "toolu_1", calls[0].ToolID)
+	assert.JSONEq(t, `{"command":"git status"}`, calls[0].Input)
+
+	body := `{"type":"message","content":[{"type":"tool_use","id":"toolu_2","name":"Read","input":{"file_path":"a.go"}}]}`
+	calls, err = ParseCapture([]byte(body), "anthropic")
+	require.NoError(t, err)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "Read", calls[0].ToolName)
+
+	// A call cut off before content_block_stop is left out
+	cut := `event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"Bash","input":{}}}
+
+`
+	calls, err = ParseCapture([]byte(cut), "anthropic")
+	require.NoError(t, err)
+	assert.Empty(t, calls)
+
+	_, err = ParseCapture([]byte(body), "cohere")
+	assert.ErrorContains(t, err, "unknown provider")
+}
@@ -3,7 +3,11 @@
 package control
 
 import (
+	"context"
 	"net/http"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 // Action represents what should happen after a handler processes a request/response.
@@ -64,6 +68,8 @@ func ErrorResult(err error) Result {
 
 // HandlerContext provides context to handlers about the current request/response.
 // This includes ownership fields for log attribution.
+// Each request/response pair gets its own context (see ForRequest), so handlers
+// may store per-request state without synchronization.
 type HandlerContext struct {
 	// EmployeeID identifies the employee making the request.
 	EmployeeID string
@@ -82,6 +88,19 @@ type HandlerContext struct {
 	// Detected from User-Agent headers.
 	ClientVersion string
 
+	// RequestID links a request to its response. Empty for the session-level context.
+	RequestID string
+
+	// RequestStart is when the request entered the pipeline.
+	RequestStart time.Time
+
+	// ResponseStart is when the response headers arrived (time to first byte).
+	ResponseStart time.Time
+
+	// Provider is the LLM API the request was sent to, resolved from its host.
+	// Nil when the service didn't resolve one; handlers then look it up themselves.
+	Provider Provider
+
 	// Metadata allows handlers to pass data to downstream handlers.
 	Metadata map[string]interface{}
 }
@@ -96,6 +115,36 @@ func NewHandlerContext(employeeID, orgID, sessionID string) *HandlerContext {
 	}
 }
 
+// ForRequest returns a fresh context for a single request/response pair.
+// Ownership fields are copied; the request gets a new ID, start time and metadata.
+func (ctx *HandlerContext) ForRequest() *HandlerContext {
+	return &HandlerContext{
+		EmployeeID:    ctx.EmployeeID,
+		OrgID:         ctx.OrgID,
+		SessionID:     ctx.SessionID,
+		ClientName:    ctx.ClientName,
+		ClientVersion: ctx.ClientVersion,
+		RequestID:     uuid.New().String(),
+		RequestStart:  time.Now(),
+		Metadata:      make(map[string]interface{}),
+	}
+}
+
+// handlerContextKey is the context key under which a request's HandlerContext is stored.
+type handlerContextKey struct{}
+
+// WithHandlerContext attaches a HandlerContext to a request context so the
+// matching response can be processed with the same context.
+func WithHandlerContext(parent context.Context, hctx *HandlerContext) context.Context {
+	return context.WithValue(parent, handlerContextKey{}, hctx)
+}
+
+// HandlerContextFrom returns the HandlerContext attached by WithHandlerContext.
+func HandlerContextFrom(c context.Context) (*HandlerContext, bool) {
+	hctx, ok := c.Value(handlerContextKey{}).(*HandlerContext)
+	return hctx, ok
+}
+
 // SetClient updates the client detection fields from a ClientInfo.
 func (ctx *HandlerContext) SetClient(info ClientInfo) {
 	ctx.ClientName = info.Name
@@ -1,6 +1,7 @@
 package control
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -213,3 +214,36 @@ func TestErrorResult(t *testing.T) {
 	assert.Equal(t, ActionContinue, result.Action)
 	assert.Error(t, result.Error)
 }
+
+func TestHandlerContext_ForRequest(t *testing.T) {
+	base := NewHandlerContext("emp-123", "org-456", "sess-789")
+	base.SetClient(ClientInfo{Name: "claude-code", Version: "1.0.25"})
+
+	a := base.ForRequest()
+	b := base.ForRequest()
+
+	assert.Equal(t, "emp-123", a.EmployeeID)
+	assert.Equal(t, "org-456", a.OrgID)
+	assert.Equal(t, "sess-789", a.SessionID)
+	assert.Equal(t, "claude-code", a.ClientName)
+	assert.NotEmpty(t, a.RequestID)
+	assert.NotEqual(t, a.RequestID, b.RequestID)
+	assert.False(t, a.RequestStart.IsZero())
+
+	// Per-request state doesn't leak into the base or sibling contexts
+	a.Metadata["key"] = "value"
+	a.SetClient(ClientInfo{Name: "cursor"})
+	assert.Empty(t, b.Metadata)
+	assert.Equal(t, "claude-code", base.ClientName)
+}
+
+func TestHandlerContextFrom(t *testing.T) {
+	hctx := NewHandlerContext("emp-123", "org-456", "sess-789").ForRequest()
+
+	got, ok := HandlerContextFrom(WithHandlerContext(context.Background(), hctx))
+	require.True(t, ok)
+	assert.Same(t, hctx, got)
+
+	_, ok = HandlerContextFrom(context.Background())
+	assert.False(t, ok)
+}
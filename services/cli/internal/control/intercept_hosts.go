@@ -0,0 +1,119 @@
+package control
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/elazarl/goproxy"
+)
+
+// Wire protocols an organization-configured host can speak
+const (
+	ProtocolAnthropic = "anthropic"
+	ProtocolOpenAI    = "openai"
+)
+
+// InterceptHost is an extra host to intercept, such as an internal LLM gateway.
+// Sent by the server with init, from the organization's settings.
+type InterceptHost struct {
+	Host     string `json:"host"`     // Hostname, or *.domain for any subdomain
+	Protocol string `json:"protocol"` // anthropic or openai
+}
+
+// InterceptHosts decides which hosts the proxy intercepts and which provider
+// parses their traffic: the built-in LLM APIs (llmHostRegex) plus any hosts
+// the organization configured. A nil *InterceptHosts matches built-in hosts only.
+type InterceptHosts struct {
+	mu     sync.RWMutex
+	custom []customHost
+}
+
+// customHost is a configured host with its provider.
+type customHost struct {
+	pattern  string // Lowercase hostname, or ".domain" for a wildcard
+	provider Provider
+}
+
+// NewInterceptHosts creates a host set with only the built-in LLM APIs.
+func NewInterceptHosts() *InterceptHosts {
+	return &InterceptHosts{}
+}
+
+// Set replaces the configured hosts. Entries with an unknown protocol are ignored.
+func (h *InterceptHosts) Set(hosts []InterceptHost) {
+	custom := make([]customHost, 0, len(hosts))
+	for _, host := range hosts {
+		provider := providerForProtocol(host.Protocol)
+		pattern := strings.ToLower(strings.TrimSpace(host.Host))
+		if provider == nil || pattern == "" {
+			continue
+		}
+		custom = append(custom, customHost{
+			pattern:  strings.TrimPrefix(pattern, "*"),
+			provider: provider,
+		})
+	}
+
+	h.mu.Lock()
+	h.custom = custom
+	h.mu.Unlock()
+}
+
+// Match reports whether traffic to host (with or without a port) is intercepted.
+func (h *InterceptHosts) Match(host string) bool {
+	return llmHostRegex.MatchString(host) || h.lookup(host) != nil
+}
+
+// ProviderFor returns the provider for a request's host, defaulting to
+// Anthropic for hosts that aren't known.
+func (h *InterceptHosts) ProviderFor(req *http.Request) Provider {
+	if req != nil {
+		if p := h.lookup(req.URL.Host); p != nil {
+			return p
+		}
+		if p := h.lookup(req.Host); p != nil {
+			return p
+		}
+	}
+	return providerForRequest(req)
+}
+
+// reqCondition matches requests to intercepted hosts, for goproxy rules.
+func (h *InterceptHosts) reqCondition() goproxy.ReqConditionFunc {
+	return func(req *http.Request, ctx *goproxy.ProxyCtx) bool {
+		return h.Match(req.URL.Host) || h.Match(req.Host)
+	}
+}
+
+// lookup returns the provider of a configured host, or nil.
+func (h *InterceptHosts) lookup(host string) Provider {
+	if h == nil || host == "" {
+		return nil
+	}
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		host = name
+	}
+	host = strings.ToLower(host)
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, c := range h.custom {
+		if host == c.pattern || (strings.HasPrefix(c.pattern, ".") && strings.HasSuffix(host, c.pattern)) {
+			return c.provider
+		}
+	}
+	return nil
+}
+
+// providerForProtocol returns the provider for a configured wire protocol.
+func providerForProtocol(protocol string) Provider {
+	switch protocol {
+	case ProtocolAnthropic:
+		return anthropicProvider{}
+	case ProtocolOpenAI:
+		return openAIProvider{}
+	}
+	return nil
+}
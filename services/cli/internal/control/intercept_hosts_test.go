@@ -0,0 +1,77 @@
+package control
+
+import (
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterceptHosts_Match(t *testing.T) {
+	hosts := NewInterceptHosts()
+	hosts.Set([]InterceptHost{
+		{Host: "LiteLLM.internal", Protocol: ProtocolOpenAI},
+		{Host: "*.claude-gw.corp", Protocol: ProtocolAnthropic},
+		{Host: "grpc.corp", Protocol: "grpc"},
+	})
+
+	tests := []struct {
+		host    string
+		matches bool
+	}{
+		{"api.anthropic.com:443", true},
+		{"litellm.internal", true},
+		{"litellm.internal:4000", true},
+		{"eu.claude-gw.corp:443", true},
+		{"claude-gw.corp", false},
+		{"grpc.corp", false},
+		{"example.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			assert.Equal(t, tt.matches, hosts.Match(tt.host))
+		})
+	}
+}
+
+func TestInterceptHosts_ProviderFor(t *testing.T) {
+	hosts := NewInterceptHosts()
+	hosts.Set([]InterceptHost{{Host: "litellm.internal", Protocol: ProtocolOpenAI}})
+
+	assert.Equal(t, openAIProvider{}, hosts.ProviderFor(httptest.NewRequest("POST", "http://litellm.internal:4000/chat/completions", nil)))
+	assert.Equal(t, openAIProvider{}, hosts.ProviderFor(httptest.NewRequest("POST", "https://api.openai.com/v1/chat/completions", nil)))
+	assert.Equal(t, anthropicProvider{}, hosts.ProviderFor(httptest.NewRequest("POST", "https://unknown.corp/v1/messages", nil)))
+
+	// Replacing the list drops earlier hosts
+	hosts.Set(nil)
+	assert.False(t, hosts.Match("litellm.internal"))
+}
+
+func TestInterceptHosts_NilMatchesBuiltInHosts(t *testing.T) {
+	var hosts *InterceptHosts
+	assert.True(t, hosts.Match("api.openai.com"))
+	assert.False(t, hosts.Match("litellm.internal"))
+}
+
+func TestService_LogsConfiguredGatewayTraffic(t *testing.T) {
+	dir := t.TempDir()
+	svc, err := NewService(ServiceConfig{EmployeeID: "emp-123", OrgID: "org-456", QueueDir: dir})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("POST", "http://litellm.internal:4000/chat/completions", nil)
+
+	// Not a known provider endpoint until the org configures the host
+	svc.HandleRequest(req)
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	svc.InterceptHosts().Set([]InterceptHost{{Host: "litellm.internal", Protocol: ProtocolOpenAI}})
+	svc.HandleRequest(req)
+	files, err = filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+}
@@ -4,14 +4,10 @@ import (
 	"bytes"
 	"io"
 	"net/http"
-	"regexp"
 	"strings"
 	"time"
 )
 
-// aiEndpointRegex matches Anthropic's conversation endpoint
-var aiEndpointRegex = regexp.MustCompile(`/v1/messages`)
-
 // sensitiveHeaders are headers that should be redacted in logs.
 var sensitiveHeaders = map[string]bool{
 	"authorization":       true,
@@ -53,8 +49,8 @@ func (h *LoggerHandler) Priority() int {
 
 // HandleRequest logs an outgoing API request.
 func (h *LoggerHandler) HandleRequest(ctx *HandlerContext, req *http.Request) Result {
-	// Only log Anthropic /v1/messages requests
-	if !aiEndpointRegex.MatchString(req.URL.Path) {
+	// Only log conversation endpoints (e.g. /v1/messages, /v1/chat/completions)
+	if !contextProvider(ctx, req).MatchEndpoint(req.URL.Path) {
 		return ContinueResult()
 	}
 
@@ -89,6 +85,11 @@ func (h *LoggerHandler) HandleRequest(ctx *HandlerContext, req *http.Request) Re
 		entry.Payload["body"] = bodyStr
 	}
 
+	// Link to the matching api_response
+	if ctx.RequestID != "" {
+		entry.Payload["request_id"] = ctx.RequestID
+	}
+
 	// Enqueue is non-blocking (writes to disk)
 	_ = h.queue.Enqueue(entry)
 
@@ -97,11 +98,20 @@ func (h *LoggerHandler) HandleRequest(ctx *HandlerContext, req *http.Request) Re
 
 // HandleResponse logs an incoming API response.
 func (h *LoggerHandler) HandleResponse(ctx *HandlerContext, res *http.Response) Result {
-	// Only log Anthropic /v1/messages responses
-	if res.Request == nil || !aiEndpointRegex.MatchString(res.Request.URL.Path) {
+	// Only log conversation endpoint responses
+	if res.Request == nil || !contextProvider(ctx, res.Request).MatchEndpoint(res.Request.URL.Path) {
 		return ContinueResult()
 	}
 
+	// Streaming responses are logged once the stream has been delivered
+	if res.Body != nil && isEventStream(res) {
+		AttachSSEProcessor(res, &responseLogStreamProcessor{h: h, ctx: ctx, res: res})
+		return Result{
+			Action:           ActionContinue,
+			ModifiedResponse: res,
+		}
+	}
+
 	// Read and restore body
 	var bodyStr string
 	if res.Body != nil {
@@ -113,6 +123,17 @@ func (h *LoggerHandler) HandleResponse(ctx *HandlerContext, res *http.Response)
 		}
 	}
 
+	h.logResponse(ctx, res, bodyStr, false)
+
+	return ContinueResult()
+}
+
+// logResponse enqueues an api_response entry.
+// Called once the body has been fully read, or the stream cut short, so latency
+// covers the whole response.
+func (h *LoggerHandler) logResponse(ctx *HandlerContext, res *http.Response, bodyStr string, truncated bool) {
+	end := time.Now()
+
 	entry := LogEntry{
 		EmployeeID:    ctx.EmployeeID,
 		OrgID:         ctx.OrgID,
@@ -130,16 +151,52 @@ func (h *LoggerHandler) HandleResponse(ctx *HandlerContext, res *http.Response)
 	if bodyStr != "" {
 		entry.Payload["body"] = bodyStr
 	}
+	if truncated {
+		entry.Payload["truncated"] = true
+	}
 
 	// Include request URL for correlation
 	if res.Request != nil {
 		entry.Payload["url"] = res.Request.URL.String()
 	}
 
+	if ctx.RequestID != "" {
+		entry.Payload["request_id"] = ctx.RequestID
+	}
+
+	// Timing is only known when the request passed through this pipeline
+	if !ctx.RequestStart.IsZero() {
+		if !ctx.ResponseStart.IsZero() {
+			entry.Payload["ttfb_ms"] = ctx.ResponseStart.Sub(ctx.RequestStart).Milliseconds()
+		}
+		entry.Payload["latency_ms"] = end.Sub(ctx.RequestStart).Milliseconds()
+	}
+
 	// Enqueue is non-blocking (writes to disk)
 	_ = h.queue.Enqueue(entry)
+}
 
-	return ContinueResult()
+// responseLogStreamProcessor records the events delivered to the client and
+// logs the full stream as a single api_response when it ends.
+type responseLogStreamProcessor struct {
+	h    *LoggerHandler
+	ctx  *HandlerContext
+	res  *http.Response
+	body bytes.Buffer
+}
+
+// ProcessEvent records the event and forwards it unchanged.
+func (p *responseLogStreamProcessor) ProcessEvent(ev SSEEvent) []SSEEvent {
+	ev.writeTo(&p.body)
+	return []SSEEvent{ev}
+}
+
+// Finish logs the response with the recorded stream. A stream that was cut
+// short, by an upstream error or the client closing it, is logged as far as
+// it got and marked truncated.
+func (p *responseLogStreamProcessor) Finish(err error) []SSEEvent {
+	p.h.logResponse(p.ctx, p.res, p.body.String(), err != nil)
+	return nil
 }
 
 // redactHeaders returns a copy of headers with sensitive values redacted.
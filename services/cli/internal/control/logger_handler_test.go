@@ -345,3 +345,103 @@ func TestLoggerHandler_AlwaysContinues(t *testing.T) {
 	assert.Equal(t, ActionContinue, reqResult.Action)
 	assert.Equal(t, ActionContinue, resResult.Action)
 }
+
+func TestLoggerHandler_LinksRequestAndResponse(t *testing.T) {
+	queue := &mockLoggerQueue{}
+	handler := NewLoggerHandler(queue)
+
+	ctx := NewHandlerContext("emp-123", "org-456", "sess-789").ForRequest()
+	ctx.RequestStart = time.Now().Add(-300 * time.Millisecond)
+
+	req := httptest.NewRequest("POST", "https://api.anthropic.com/v1/messages", nil)
+	handler.HandleRequest(ctx, req)
+
+	ctx.ResponseStart = ctx.RequestStart.Add(120 * time.Millisecond)
+	handler.HandleResponse(ctx, &http.Response{
+		StatusCode: 200,
+		Request:    req,
+		Header:     make(http.Header),
+		Body:       io.NopCloser(bytes.NewBufferString(`{}`)),
+	})
+
+	entries := queue.Entries()
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, ctx.RequestID, entries[0].Payload["request_id"])
+	assert.Equal(t, ctx.RequestID, entries[1].Payload["request_id"])
+	assert.Equal(t, int64(120), entries[1].Payload["ttfb_ms"])
+	assert.GreaterOrEqual(t, entries[1].Payload["latency_ms"].(int64), int64(300))
+}
+
+func TestLoggerHandler_StreamingLatencyMeasuredAtEnd(t *testing.T) {
+	queue := &mockLoggerQueue{}
+	handler := NewLoggerHandler(queue)
+
+	ctx := NewHandlerContext("emp-123", "org-456", "sess-789").ForRequest()
+	ctx.ResponseStart = time.Now()
+
+	res := &http.Response{
+		StatusCode: 200,
+		Request:    httptest.NewRequest("POST", "https://api.anthropic.com/v1/messages", nil),
+		Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+		Body:       io.NopCloser(bytes.NewBufferString("event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n")),
+	}
+	handler.HandleResponse(ctx, res)
+
+	// Nothing is logged until the stream has been delivered
+	assert.Empty(t, queue.Entries())
+
+	drainBody(t, res)
+
+	entries := queue.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, ctx.RequestID, entries[0].Payload["request_id"])
+	assert.Contains(t, entries[0].Payload, "ttfb_ms")
+	assert.Contains(t, entries[0].Payload, "latency_ms")
+	assert.Contains(t, entries[0].Payload["body"], "message_stop")
+}
+
+func TestLoggerHandler_StreamClosedEarlyLoggedTruncated(t *testing.T) {
+	queue := &mockLoggerQueue{}
+	handler := NewLoggerHandler(queue)
+	ctx := NewHandlerContext("emp-123", "org-456", "sess-789").ForRequest()
+
+	res := &http.Response{
+		StatusCode: 200,
+		Request:    httptest.NewRequest("POST", "https://api.anthropic.com/v1/messages", nil),
+		Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+		Body: io.NopCloser(bytes.NewBufferString("event: message_start\ndata: {\"type\":\"message_start\"}\n\n" +
+			"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n")),
+	}
+	handler.HandleResponse(ctx, res)
+
+	// The client reads the first event, then disconnects
+	_, err := res.Body.Read(make([]byte, 16))
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+
+	entries := queue.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, true, entries[0].Payload["truncated"])
+	assert.Contains(t, entries[0].Payload["body"], "message_start")
+	assert.NotContains(t, entries[0].Payload["body"], "message_stop")
+	assert.Contains(t, entries[0].Payload, "latency_ms")
+}
+
+func TestLoggerHandler_NoTimingWithoutRequestStart(t *testing.T) {
+	queue := &mockLoggerQueue{}
+	handler := NewLoggerHandler(queue)
+
+	handler.HandleResponse(NewHandlerContext("emp-123", "org-456", "sess-789"), &http.Response{
+		StatusCode: 200,
+		Request:    httptest.NewRequest("POST", "https://api.anthropic.com/v1/messages", nil),
+		Header:     make(http.Header),
+		Body:       io.NopCloser(bytes.NewBufferString(`{}`)),
+	})
+
+	entries := queue.Entries()
+	require.Len(t, entries, 1)
+	assert.NotContains(t, entries[0].Payload, "request_id")
+	assert.NotContains(t, entries[0].Payload, "ttfb_ms")
+	assert.NotContains(t, entries[0].Payload, "latency_ms")
+}
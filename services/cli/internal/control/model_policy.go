@@ -0,0 +1,263 @@
+package control
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rastrigin-systems/arfa/pkg/policy"
+	"github.com/rastrigin-systems/arfa/services/cli/internal/api"
+)
+
+// modelRule is a policy with policy_type "model". Its tool name is a model
+// pattern matched against the model of conversation requests, named in the
+// URL path or the "model" field of the body. Models
+// listed in the "allowed_models" condition are exempt, so a "%" policy with
+// allowed models acts as an allow list. Rewrite policies replace the model
+// with the "fallback_model" condition.
+type modelRule struct {
+	policyRule
+	Allowed  []string
+	Fallback string
+}
+
+// newModelRule converts a model policy from the API. Rewrite policies without
+// a fallback are skipped.
+func newModelRule(policy api.ToolPolicy) (modelRule, bool) {
+	rule := modelRule{policyRule: newPolicyRule(policy)}
+	if allowed, ok := policy.Conditions["allowed_models"].([]interface{}); ok {
+		for _, m := range allowed {
+			if name, ok := m.(string); ok && name != "" {
+				rule.Allowed = append(rule.Allowed, name)
+			}
+		}
+	}
+	rule.Fallback, _ = policy.Conditions["fallback_model"].(string)
+
+	switch rule.Action {
+	case api.ToolPolicyActionDeny, api.ToolPolicyActionAudit:
+	case api.ToolPolicyActionRewrite:
+		if rule.Fallback == "" {
+			return modelRule{}, false
+		}
+	default:
+		return modelRule{}, false
+	}
+	return rule, true
+}
+
+// matches reports whether the rule applies to the model.
+func (r modelRule) matches(model string) bool {
+	if !matchesModel(r.ToolName, model) {
+		return false
+	}
+	for _, allowed := range r.Allowed {
+		if matchesModel(allowed, model) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesModel reports whether a model pattern matches, ignoring case. A * or
+// % in the pattern matches any run of characters, so "claude-opus-%" matches
+// every Opus model.
+func matchesModel(pattern, model string) bool {
+	pattern = strings.ToLower(strings.ReplaceAll(pattern, "%", "*"))
+	model = strings.ToLower(model)
+
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == model
+	}
+	if !strings.HasPrefix(model, parts[0]) {
+		return false
+	}
+	model = model[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(model, part)
+		if i < 0 {
+			return false
+		}
+		model = model[i+len(part):]
+	}
+	return strings.HasSuffix(model, parts[len(parts)-1])
+}
+
+// enforceModelPolicies applies model policies to a conversation request.
+// Deny wins over rewrite; of several rewrites the most specific one applies.
+// A fallback model is itself checked against deny policies. Audit policies
+// are logged and never change the request.
+func (h *PolicyHandler) enforceModelPolicies(ctx *HandlerContext, req *http.Request) Result {
+	h.mu.RLock()
+	rules := h.modelPolicies
+	h.mu.RUnlock()
+	if len(rules) == 0 || req == nil || req.URL == nil {
+		return ContinueResult()
+	}
+
+	provider := contextProvider(ctx, req)
+	if !provider.MatchEndpoint(req.URL.Path) {
+		return ContinueResult()
+	}
+
+	// Bedrock, Vertex and Gemini name the model in the path, the rest in
+	// the body
+	model, _ := provider.PathModel(req.URL.Path)
+	var fields map[string]json.RawMessage
+	if model == "" {
+		if req.Body == nil {
+			return ContinueResult()
+		}
+		body, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			return ContinueResult()
+		}
+		if json.Unmarshal(body, &fields) != nil || json.Unmarshal(fields["model"], &model) != nil || model == "" {
+			return ContinueResult()
+		}
+	}
+
+	var rewrite *modelRule
+	for i, rule := range rules {
+		if !rule.matches(model) {
+			continue
+		}
+		switch rule.Action {
+		case api.ToolPolicyActionDeny:
+			h.logModelPolicy(ctx, provider, rule, model, "", true)
+			return BlockResult(modelDenialReason(rule, model))
+		case api.ToolPolicyActionRewrite:
+			if rewrite == nil {
+				rewrite = &rules[i] // Rules are sorted most specific first
+			}
+		case api.ToolPolicyActionAudit:
+			h.logModelPolicy(ctx, provider, rule, model, "", false)
+		}
+	}
+	if rewrite == nil {
+		return ContinueResult()
+	}
+
+	for _, rule := range rules {
+		if rule.Action == api.ToolPolicyActionDeny && rule.matches(rewrite.Fallback) {
+			h.logModelPolicy(ctx, provider, rule, rewrite.Fallback, "", true)
+			return BlockResult(modelDenialReason(rule, rewrite.Fallback))
+		}
+	}
+
+	var modified *http.Request
+	if fields == nil {
+		modified = rewriteModelPath(req, provider, rewrite.Fallback)
+	} else {
+		modified = rewriteModelBody(req, fields, rewrite.Fallback)
+	}
+	if modified == nil {
+		return ContinueResult()
+	}
+	h.logModelPolicy(ctx, provider, *rewrite, model, rewrite.Fallback, false)
+
+	return Result{
+		Action:          ActionContinue,
+		ModifiedRequest: modified,
+	}
+}
+
+// rewriteModelBody returns a copy of req whose body names model instead.
+func rewriteModelBody(req *http.Request, fields map[string]json.RawMessage, model string) *http.Request {
+	fields["model"], _ = json.Marshal(model)
+	rewritten, err := json.Marshal(fields)
+	if err != nil {
+		return nil
+	}
+
+	modified := req.Clone(req.Context())
+	modified.Body = io.NopCloser(bytes.NewReader(rewritten))
+	modified.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(rewritten)), nil
+	}
+	modified.ContentLength = int64(len(rewritten))
+	modified.Header.Del("Content-Length")
+	return modified
+}
+
+// rewriteModelPath returns a copy of req whose URL path names model instead.
+// The model is replaced in the escaped path, so a Bedrock ARN keeps its
+// slashes within the one segment.
+func rewriteModelPath(req *http.Request, provider Provider, model string) *http.Request {
+	_, withModel := provider.PathModel(req.URL.EscapedPath())
+	if withModel == nil {
+		return nil
+	}
+	rawPath := withModel(url.PathEscape(model))
+	path, err := url.PathUnescape(rawPath)
+	if err != nil {
+		return nil
+	}
+
+	modified := req.Clone(req.Context())
+	modified.URL.Path = path
+	modified.URL.RawPath = rawPath
+	return modified
+}
+
+// modelDenialReason explains a denied model to the agent.
+func modelDenialReason(rule modelRule, model string) string {
+	if rule.Reason != "" {
+		return rule.Reason
+	}
+	return "Model " + model + " is not allowed by organization policy"
+}
+
+// logModelPolicy enqueues a policy_violation event for a model policy that
+// matched a request.
+func (h *PolicyHandler) logModelPolicy(ctx *HandlerContext, provider Provider, rule modelRule, model, rewrittenTo string, blocked bool) {
+	if h.queue == nil {
+		return
+	}
+
+	payload := map[string]interface{}{
+		"session_id": ctx.SessionID,
+		"provider":   string(provider.Name()),
+		"policy_id":  rule.ID,
+		"action":     string(rule.Action),
+		"model":      model,
+		"blocked":    blocked,
+	}
+	if rewrittenTo != "" {
+		payload["rewritten_to"] = rewrittenTo
+	}
+	if rule.Reason != "" {
+		payload["reason"] = rule.Reason
+	}
+
+	_ = h.queue.Enqueue(LogEntry{
+		EmployeeID:    ctx.EmployeeID,
+		OrgID:         ctx.OrgID,
+		ClientName:    ctx.ClientName,
+		ClientVersion: ctx.ClientVersion,
+		EventType:     "policy_violation",
+		EventCategory: "classified",
+		Timestamp:     time.Now(),
+		Payload:       payload,
+	})
+}
+
+// sortModelRules orders rules by scope, employee policies first, then ID, so
+// the same rewrite wins regardless of the order policies arrived in.
+func sortModelRules(rules []modelRule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		a, b := policy.ScopeRank(string(rules[i].Scope)), policy.ScopeRank(string(rules[j].Scope))
+		if a != b {
+			return a > b
+		}
+		return rules[i].ID < rules[j].ID
+	})
+}
@@ -0,0 +1,223 @@
+package control
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rastrigin-systems/arfa/services/cli/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func modelRequest(t *testing.T, body string) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest("POST", "https://api.anthropic.com/v1/messages", strings.NewReader(body))
+	require.NoError(t, err)
+	return req
+}
+
+func TestMatchesModel(t *testing.T) {
+	assert.True(t, matchesModel("claude-opus-4-1", "Claude-Opus-4-1"))
+	assert.True(t, matchesModel("claude-opus-%", "claude-opus-4-1-20250805"))
+	assert.True(t, matchesModel("claude-*-4-5", "claude-sonnet-4-5"))
+	assert.True(t, matchesModel("%", "gpt-4o"))
+	assert.False(t, matchesModel("claude-opus-%", "claude-sonnet-4-5"))
+	assert.False(t, matchesModel("claude-*-4-5", "claude-sonnet-4-1"))
+}
+
+func TestPolicyHandler_ModelPolicy_Deny(t *testing.T) {
+	queue := &mockToolLoggerQueue{}
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{
+		{ID: "p-1", PolicyType: api.ToolPolicyTypeModel, ToolName: "claude-opus-%", Action: api.ToolPolicyActionDeny},
+	})
+	h.SetQueue(queue)
+	ctx := NewHandlerContext("emp-1", "org-1", "sess-1")
+
+	result := h.HandleRequest(ctx, modelRequest(t, `{"model":"claude-opus-4-1","max_tokens":1024,"messages":[]}`))
+	assert.True(t, result.ShouldBlock())
+	assert.Equal(t, "Model claude-opus-4-1 is not allowed by organization policy", result.Reason)
+
+	entries := queue.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "policy_violation", entries[0].EventType)
+	assert.Equal(t, "deny", entries[0].Payload["action"])
+	assert.Equal(t, "claude-opus-4-1", entries[0].Payload["model"])
+	assert.Equal(t, true, entries[0].Payload["blocked"])
+
+	result = h.HandleRequest(ctx, modelRequest(t, `{"model":"claude-sonnet-4-5","messages":[]}`))
+	assert.True(t, result.ShouldContinue())
+	assert.Nil(t, result.ModifiedRequest)
+}
+
+func TestPolicyHandler_ModelPolicy_AllowedModels(t *testing.T) {
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{{
+		ID:         "p-1",
+		PolicyType: api.ToolPolicyTypeModel,
+		ToolName:   "%",
+		Action:     api.ToolPolicyActionDeny,
+		Conditions: map[string]interface{}{"allowed_models": []interface{}{"claude-sonnet-%", "claude-haiku-%"}},
+	}})
+	ctx := NewHandlerContext("emp-1", "org-1", "sess-1")
+
+	assert.True(t, h.HandleRequest(ctx, modelRequest(t, `{"model":"claude-haiku-4-5"}`)).ShouldContinue())
+	assert.True(t, h.HandleRequest(ctx, modelRequest(t, `{"model":"claude-opus-4-1"}`)).ShouldBlock())
+}
+
+func TestPolicyHandler_ModelPolicy_Rewrite(t *testing.T) {
+	queue := &mockToolLoggerQueue{}
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{
+		{
+			ID: "p-org", PolicyType: api.ToolPolicyTypeModel, ToolName: "claude-opus-%", Action: api.ToolPolicyActionRewrite,
+			Scope: api.ToolPolicyScopeOrganization, Conditions: map[string]interface{}{"fallback_model": "claude-haiku-4-5"},
+		},
+		{
+			ID: "p-team", PolicyType: api.ToolPolicyTypeModel, ToolName: "claude-opus-%", Action: api.ToolPolicyActionRewrite,
+			Scope: api.ToolPolicyScopeTeam, Conditions: map[string]interface{}{"fallback_model": "claude-sonnet-4-5"},
+		},
+	})
+	h.SetQueue(queue)
+	ctx := NewHandlerContext("emp-1", "org-1", "sess-1")
+
+	req := modelRequest(t, `{"model":"claude-opus-4-1","max_tokens":1024,"stream":true}`)
+	result := h.HandleRequest(ctx, req)
+	require.True(t, result.ShouldContinue())
+	require.NotNil(t, result.ModifiedRequest)
+
+	body, err := io.ReadAll(result.ModifiedRequest.Body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"model":"claude-sonnet-4-5","max_tokens":1024,"stream":true}`, string(body), "the team policy is more specific")
+	assert.Equal(t, int64(len(body)), result.ModifiedRequest.ContentLength)
+
+	original, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(original), "claude-opus-4-1", "the original request is left intact")
+
+	entries := queue.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "p-team", entries[0].Payload["policy_id"])
+	assert.Equal(t, "claude-sonnet-4-5", entries[0].Payload["rewritten_to"])
+	assert.Equal(t, false, entries[0].Payload["blocked"])
+}
+
+func TestPolicyHandler_ModelPolicy_DenyWinsOverRewrite(t *testing.T) {
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{
+		{
+			ID: "p-1", PolicyType: api.ToolPolicyTypeModel, ToolName: "claude-opus-%", Action: api.ToolPolicyActionRewrite,
+			Scope: api.ToolPolicyScopeEmployee, Conditions: map[string]interface{}{"fallback_model": "gpt-4o"},
+		},
+		{ID: "p-2", PolicyType: api.ToolPolicyTypeModel, ToolName: "gpt-%", Action: api.ToolPolicyActionDeny},
+	})
+	ctx := NewHandlerContext("emp-1", "org-1", "sess-1")
+
+	result := h.HandleRequest(ctx, modelRequest(t, `{"model":"claude-opus-4-1"}`))
+	assert.True(t, result.ShouldBlock(), "a fallback the organization denies is not used")
+	assert.Contains(t, result.Reason, "gpt-4o")
+}
+
+func TestPolicyHandler_ModelPolicy_Audit(t *testing.T) {
+	queue := &mockToolLoggerQueue{}
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{
+		{ID: "p-1", PolicyType: api.ToolPolicyTypeModel, ToolName: "claude-opus-%", Action: api.ToolPolicyActionAudit},
+	})
+	h.SetQueue(queue)
+
+	result := h.HandleRequest(NewHandlerContext("emp-1", "org-1", "sess-1"), modelRequest(t, `{"model":"claude-opus-4-1"}`))
+	assert.True(t, result.ShouldContinue())
+	assert.Nil(t, result.ModifiedRequest)
+	require.Len(t, queue.Entries(), 1)
+	assert.Equal(t, "audit", queue.Entries()[0].Payload["action"])
+}
+
+func TestPolicyHandler_ModelPolicy_IgnoredForTools(t *testing.T) {
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{
+		{ID: "p-1", PolicyType: api.ToolPolicyTypeModel, ToolName: "Bash", Action: api.ToolPolicyActionDeny},
+	})
+
+	_, blocked := h.isBlocked("Bash")
+	assert.False(t, blocked, "model policies don't block tools")
+
+	// Other endpoints are left alone
+	req, _ := http.NewRequest("POST", "https://api.anthropic.com/v1/models", strings.NewReader(`{"model":"Bash"}`))
+	assert.True(t, h.HandleRequest(NewHandlerContext("emp-1", "org-1", "sess-1"), req).ShouldContinue())
+}
+
+func TestPolicyHandler_ModelPolicy_ModelInPath(t *testing.T) {
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{
+		{ID: "p-1", PolicyType: api.ToolPolicyTypeModel, ToolName: "%claude-opus-%", Action: api.ToolPolicyActionDeny},
+		{
+			ID: "p-2", PolicyType: api.ToolPolicyTypeModel, ToolName: "gemini-2.5-pro", Action: api.ToolPolicyActionRewrite,
+			Conditions: map[string]interface{}{"fallback_model": "gemini-2.5-flash"},
+		},
+		{
+			ID: "p-3", PolicyType: api.ToolPolicyTypeModel, ToolName: "%claude-sonnet-%", Action: api.ToolPolicyActionRewrite,
+			Conditions: map[string]interface{}{"fallback_model": "arn:aws:bedrock:us-east-1:123456789012:inference-profile/us.anthropic.claude-haiku-4-5-v1:0"},
+		},
+	})
+	ctx := NewHandlerContext("emp-1", "org-1", "sess-1")
+	request := func(rawURL, body string) *http.Request {
+		req, err := http.NewRequest("POST", rawURL, strings.NewReader(body))
+		require.NoError(t, err)
+		return req
+	}
+
+	// Bedrock and Gemini requests carry no "model" in the body
+	result := h.HandleRequest(ctx, request("https://bedrock-runtime.us-east-1.amazonaws.com/model/anthropic.claude-opus-4-1-20250805-v1:0/invoke", `{"anthropic_version":"bedrock-2023-05-31","messages":[]}`))
+	assert.True(t, result.ShouldBlock())
+	assert.Contains(t, result.Reason, "anthropic.claude-opus-4-1-20250805-v1:0")
+
+	result = h.HandleRequest(ctx, request("https://generativelanguage.googleapis.com/v1beta/models/gemini-2.5-pro:streamGenerateContent?alt=sse", `{"contents":[]}`))
+	require.True(t, result.ShouldContinue())
+	require.NotNil(t, result.ModifiedRequest)
+	assert.Equal(t, "/v1beta/models/gemini-2.5-flash:streamGenerateContent", result.ModifiedRequest.URL.Path)
+	assert.Equal(t, "alt=sse", result.ModifiedRequest.URL.RawQuery)
+	body, err := io.ReadAll(result.ModifiedRequest.Body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"contents":[]}`, string(body), "the body is left alone")
+
+	// An ARN keeps its slash within the model segment
+	result = h.HandleRequest(ctx, request("https://bedrock-runtime.us-east-1.amazonaws.com/model/anthropic.claude-sonnet-4-5-20250929-v1:0/invoke-with-response-stream", `{}`))
+	require.NotNil(t, result.ModifiedRequest)
+	assert.Equal(t, "/model/arn:aws:bedrock:us-east-1:123456789012:inference-profile%2Fus.anthropic.claude-haiku-4-5-v1:0/invoke-with-response-stream",
+		result.ModifiedRequest.URL.EscapedPath())
+}
+
+func TestProvider_PathModel(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider Provider
+		path     string
+		want     string
+	}{
+		{"anthropic", anthropicProvider{}, "/v1/messages", ""},
+		{"openai", openAIProvider{}, "/v1/chat/completions", ""},
+		{"gemini", geminiProvider{}, "/v1beta/models/gemini-2.5-pro:generateContent", "gemini-2.5-pro"},
+		{"gemini code assist", geminiProvider{}, "/v1internal:streamGenerateContent", ""},
+		{"bedrock", bedrockProvider{}, "/model/anthropic.claude-sonnet-4-5-20250929-v1:0/invoke", "anthropic.claude-sonnet-4-5-20250929-v1:0"},
+		{"vertex", vertexProvider{}, "/v1/projects/p/locations/us-east5/publishers/anthropic/models/claude-sonnet-4-5@20250929:streamRawPredict", "claude-sonnet-4-5@20250929"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model, withModel := tt.provider.PathModel(tt.path)
+			assert.Equal(t, tt.want, model)
+			if tt.want == "" {
+				assert.Nil(t, withModel)
+				return
+			}
+			assert.Equal(t, tt.path, withModel(model))
+		})
+	}
+}
+
+func TestPolicyHandler_ModelPolicy_RemovedWithPolicy(t *testing.T) {
+	client := NewPolicyClient(PolicyClientConfig{APIURL: "http://localhost:0"})
+	h := NewPolicyHandler()
+	h.SetPolicyClient(client)
+
+	client.handleMessage([]byte(`{"type":"init","policies":[{"id":"p-1","policy_type":"model","tool_name":"claude-opus-%","action":"deny","scope":"organization"}],"version":1}`))
+	assert.Len(t, h.modelPolicies, 1)
+
+	client.handleMessage([]byte(`{"type":"delete","policy_id":"p-1"}`))
+	assert.Empty(t, h.modelPolicies)
+}
@@ -0,0 +1,277 @@
+package control
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/rastrigin-systems/arfa/services/cli/internal/api"
+)
+
+// fileToolParams lists the input parameters holding paths for each built-in
+// file tool, keyed by lowercase tool name.
+var fileToolParams = map[string][]string{
+	"read":         {"file_path"},
+	"write":        {"file_path"},
+	"edit":         {"file_path"},
+	"multiedit":    {"file_path"},
+	"notebookread": {"notebook_path"},
+	"notebookedit": {"notebook_path"},
+	"glob":         {"path"},
+	"grep":         {"path"},
+	"ls":           {"path"},
+}
+
+// mcpFileParams lists the parameters holding paths in MCP filesystem server
+// tools (read_file, write_file, move_file, read_multiple_files, ...).
+var mcpFileParams = []string{"path", "paths", "source", "destination"}
+
+// pathParams returns the input parameters holding paths for a file tool.
+// Tools that don't touch files return nil.
+func pathParams(toolName string) []string {
+	name := strings.ToLower(toolName)
+	if params, ok := fileToolParams[name]; ok {
+		return params
+	}
+	// mcp__<server>__<tool>, for any server named like "filesystem"
+	if rest, ok := strings.CutPrefix(name, "mcp__"); ok {
+		if server, _, ok := strings.Cut(rest, "__"); ok && strings.Contains(server, "filesystem") {
+			return mcpFileParams
+		}
+	}
+	return nil
+}
+
+// isFileTool reports whether path policies apply to a tool.
+func isFileTool(toolName string) bool {
+	return pathParams(toolName) != nil
+}
+
+// toolPaths returns the paths a file tool call touches, as given in its
+// input. Glob's pattern is joined to its directory, so searching for
+// "**/.env" is caught like reading one.
+func toolPaths(toolName string, input map[string]interface{}) []string {
+	var paths []string
+	for _, param := range pathParams(toolName) {
+		switch v := input[param].(type) {
+		case string:
+			paths = append(paths, v)
+		case []interface{}:
+			for _, p := range v {
+				if s, ok := p.(string); ok {
+					paths = append(paths, s)
+				}
+			}
+		}
+	}
+
+	switch strings.ToLower(toolName) {
+	case "glob":
+		if pattern, ok := input["pattern"].(string); ok && pattern != "" {
+			dir, _ := input["path"].(string)
+			paths = append(paths, path.Join(dir, pattern))
+		}
+	case "grep", "ls":
+		if len(paths) == 0 {
+			paths = append(paths, ".") // Searches default to the working directory
+		}
+	}
+	return paths
+}
+
+// pathScope resolves relative paths and patterns: against root, the project
+// the proxy was started in, and ~ against home.
+type pathScope struct {
+	root string
+	home string
+}
+
+// newPathScope creates a scope for the project root. The home directory is
+// looked up once here.
+func newPathScope(root string) pathScope {
+	home, _ := os.UserHomeDir()
+	return pathScope{root: root, home: home}
+}
+
+// workingDir returns the current directory, the default project root.
+func workingDir() string {
+	dir, _ := os.Getwd()
+	return dir
+}
+
+// normalize turns a path from tool input into a clean absolute path: ~ is
+// expanded, relative paths are joined to the project root, and . and ..
+// segments are removed. Symlinks are left alone; see resolve.
+func (s pathScope) normalize(p string) string {
+	p = filepath.ToSlash(p)
+	switch {
+	case p == "~":
+		p = s.home
+	case strings.HasPrefix(p, "~/"):
+		p = path.Join(s.home, p[2:])
+	case !path.IsAbs(p):
+		p = path.Join(s.root, p)
+	}
+	return path.Clean("/" + strings.TrimPrefix(p, "/"))
+}
+
+// pattern turns a policy's path glob into an absolute one. Globs starting
+// with ** match anywhere and are left as they are.
+func (s pathScope) pattern(glob string) string {
+	if strings.HasPrefix(glob, "**") {
+		return glob
+	}
+	return s.normalize(glob)
+}
+
+// resolve returns the path with symlinks followed. Parts of the path that
+// don't exist yet (a file about to be written) are kept as they are.
+func resolve(p string) string {
+	if resolved, err := filepath.EvalSymlinks(p); err == nil {
+		return filepath.ToSlash(resolved)
+	}
+	dir, base := path.Split(p)
+	dir = path.Clean(dir)
+	if dir == p || dir == "/" || dir == "." {
+		return p
+	}
+	return path.Join(resolve(dir), base)
+}
+
+// matchPath reports whether a path glob matches a clean absolute path. A **
+// segment matches any number of directories, including none; other segments
+// use path.Match, so * and ? don't cross a /.
+func matchPath(glob, p string) bool {
+	return matchSegments(strings.Split(strings.TrimPrefix(glob, "/"), "/"), strings.Split(strings.TrimPrefix(p, "/"), "/"))
+}
+
+// matchSegments matches the segments of a glob against those of a path.
+func matchSegments(glob, segments []string) bool {
+	for len(glob) > 0 {
+		if glob[0] == "**" {
+			glob = glob[1:]
+			if len(glob) == 0 {
+				return true
+			}
+			for i := range segments {
+				if matchSegments(glob, segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(glob[0], segments[0]); !ok {
+			return false
+		}
+		glob, segments = glob[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// pathRule is a policy with policy_type "path". Its tool name is a path glob
+// such as "**/.env", "~/.ssh/**" or "/etc/**". Paths matching one of the
+// "allowed_paths" globs, relative to the project root, are exempt.
+type pathRule struct {
+	policyRule
+	Allowed []string
+}
+
+// newPathRule converts a path policy from the API. Only deny and audit
+// policies are kept.
+func newPathRule(policy api.ToolPolicy) (pathRule, bool) {
+	switch policy.Action {
+	case api.ToolPolicyActionDeny, api.ToolPolicyActionAudit:
+	default:
+		return pathRule{}, false
+	}
+
+	rule := pathRule{policyRule: newPolicyRule(policy)}
+	if allowed, ok := policy.Conditions["allowed_paths"].([]interface{}); ok {
+		for _, p := range allowed {
+			if glob, ok := p.(string); ok && glob != "" {
+				rule.Allowed = append(rule.Allowed, glob)
+			}
+		}
+	}
+	return rule, true
+}
+
+// matches reports whether the rule applies to a path from tool input. The
+// path is checked both as written and with symlinks resolved, so a link
+// can't hide a protected file; an allowed path must be allowed once resolved.
+func (r pathRule) matches(scope pathScope, p string) bool {
+	normalized := scope.normalize(p)
+	resolved := resolve(normalized)
+
+	glob := scope.pattern(r.ToolName)
+	if !matchPath(glob, normalized) && !matchPath(glob, resolved) {
+		return false
+	}
+
+	root := pathScope{root: resolve(scope.root), home: scope.home}
+	for _, allowed := range r.Allowed {
+		if matchPath(root.normalize(allowed), resolved) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchPathPolicies returns the path policies with the given action that
+// match any path the tool call touches, with the first path each matched.
+func (h *PolicyHandler) matchPathPolicies(action api.ToolPolicyAction, toolName string, input map[string]interface{}) ([]pathRule, []string) {
+	h.mu.RLock()
+	rules := h.pathPolicies
+	scope := h.paths
+	h.mu.RUnlock()
+
+	if len(rules) == 0 || input == nil || !isFileTool(toolName) {
+		return nil, nil
+	}
+
+	paths := toolPaths(toolName, input)
+	var matched []pathRule
+	var matchedPaths []string
+	for _, rule := range rules {
+		if rule.Action != action {
+			continue
+		}
+		for _, p := range paths {
+			if rule.matches(scope, p) {
+				matched = append(matched, rule)
+				matchedPaths = append(matchedPaths, p)
+				break
+			}
+		}
+	}
+	return matched, matchedPaths
+}
+
+// hasPathPolicies reports whether any path policy with the given action
+// could apply to the tool, before its input is known.
+func (h *PolicyHandler) hasPathPolicies(action api.ToolPolicyAction, toolName string) bool {
+	if !isFileTool(toolName) {
+		return false
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, rule := range h.pathPolicies {
+		if rule.Action == action {
+			return true
+		}
+	}
+	return false
+}
+
+// pathDenialReason explains a denied path to the agent.
+func pathDenialReason(rule pathRule, p string) string {
+	if rule.Reason != "" {
+		return rule.Reason
+	}
+	return "Access to " + p + " is blocked by organization policy"
+}
@@ -0,0 +1,231 @@
+package control
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rastrigin-systems/arfa/services/cli/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathScope_Normalize(t *testing.T) {
+	scope := pathScope{root: "/home/dev/project", home: "/home/dev"}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"src/main.go", "/home/dev/project/src/main.go"},
+		{"./src/../.env", "/home/dev/project/.env"},
+		{"../../../etc/passwd", "/etc/passwd"},
+		{"/etc/../etc//hosts", "/etc/hosts"},
+		{"~/.ssh/id_rsa", "/home/dev/.ssh/id_rsa"},
+		{"~", "/home/dev"},
+		{"/../../root", "/root"},
+		{".", "/home/dev/project"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.want, scope.normalize(tt.path))
+		})
+	}
+
+	assert.Equal(t, "**/.env", scope.pattern("**/.env"))
+	assert.Equal(t, "/home/dev/.ssh/**", scope.pattern("~/.ssh/**"))
+	assert.Equal(t, "/home/dev/project/secrets/*", scope.pattern("secrets/*"))
+}
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		glob string
+		path string
+		want bool
+	}{
+		{"**/.env", "/home/dev/project/.env", true},
+		{"**/.env", "/home/dev/project/config/.env", true},
+		{"**/.env", "/home/dev/project/.env.example", false},
+		{"**/.env*", "/home/dev/project/.env.local", true},
+		{"/etc/**", "/etc", true},
+		{"/etc/**", "/etc/ssh/sshd_config", true},
+		{"/etc/**", "/home/etc/file", false},
+		{"/home/dev/.ssh/**", "/home/dev/.ssh/id_rsa", true},
+		{"/home/*/secrets", "/home/dev/secrets", true},
+		{"/home/*/secrets", "/home/dev/x/secrets", false},
+		{"/home/dev/**/*.pem", "/home/dev/certs/prod/server.pem", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.glob+" "+tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchPath(tt.glob, tt.path))
+		})
+	}
+}
+
+func TestToolPaths(t *testing.T) {
+	tests := []struct {
+		name  string
+		tool  string
+		input map[string]interface{}
+		want  []string
+	}{
+		{"read", "Read", map[string]interface{}{"file_path": "/etc/passwd"}, []string{"/etc/passwd"}},
+		{"notebook", "NotebookEdit", map[string]interface{}{"notebook_path": "a.ipynb"}, []string{"a.ipynb"}},
+		{"glob joins pattern", "Glob", map[string]interface{}{"path": "/home/dev", "pattern": "**/.env"}, []string{"/home/dev", "/home/dev/**/.env"}},
+		{"grep defaults to working directory", "Grep", map[string]interface{}{"pattern": "password"}, []string{"."}},
+		{"mcp filesystem", "mcp__filesystem__move_file", map[string]interface{}{"source": "a", "destination": "b"}, []string{"a", "b"}},
+		{"mcp filesystem list", "mcp__filesystem__read_multiple_files", map[string]interface{}{"paths": []interface{}{"a", "b"}}, []string{"a", "b"}},
+		{"not a file tool", "Bash", map[string]interface{}{"command": "cat /etc/passwd"}, nil},
+		{"other mcp server", "mcp__github__get_file", map[string]interface{}{"path": "README.md"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, toolPaths(tt.tool, tt.input))
+		})
+	}
+}
+
+func TestResolve_FollowsSymlinks(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	secrets := filepath.Join(dir, "secrets")
+	require.NoError(t, os.Mkdir(secrets, 0o755))
+	require.NoError(t, os.Symlink(secrets, filepath.Join(dir, "innocent")))
+
+	assert.Equal(t, secrets+"/key.pem", resolve(dir+"/innocent/key.pem"), "files that don't exist yet resolve through their directory")
+	assert.Equal(t, dir+"/missing/key.pem", resolve(dir+"/missing/key.pem"))
+}
+
+func TestPolicyHandler_PathPolicy_Deny(t *testing.T) {
+	reason := "System files are off limits"
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{
+		{ID: "p-1", PolicyType: api.ToolPolicyTypePath, ToolName: "**/.env", Action: api.ToolPolicyActionDeny},
+		{ID: "p-2", PolicyType: api.ToolPolicyTypePath, ToolName: "/etc/**", Action: api.ToolPolicyActionDeny, Reason: &reason},
+	})
+	h.SetProjectRoot("/home/dev/project")
+
+	assert.True(t, h.hasConditionalPolicies("Read"))
+	assert.False(t, h.hasConditionalPolicies("Bash"))
+
+	got, blocked := h.evaluateConditions("Read", `{"file_path":"config/../.env"}`)
+	assert.True(t, blocked)
+	assert.Equal(t, "Access to config/../.env is blocked by organization policy", got)
+
+	got, blocked = h.evaluateConditions("Edit", `{"file_path":"../../../etc/hosts"}`)
+	assert.True(t, blocked)
+	assert.Equal(t, reason, got)
+
+	_, blocked = h.evaluateConditions("Glob", `{"pattern":"**/.env"}`)
+	assert.True(t, blocked, "searching for the file is blocked too")
+
+	_, blocked = h.evaluateConditions("mcp__filesystem__read_file", `{"path":"/etc/shadow"}`)
+	assert.True(t, blocked)
+
+	_, blocked = h.evaluateConditions("Read", `{"file_path":"src/main.go"}`)
+	assert.False(t, blocked)
+}
+
+func TestPolicyHandler_PathPolicy_Precedence(t *testing.T) {
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{
+		{ID: "org-env", PolicyType: api.ToolPolicyTypePath, ToolName: "**/.env", Action: api.ToolPolicyActionDeny, Scope: api.ToolPolicyScopeOrganization},
+		{ID: "team-keys", PolicyType: api.ToolPolicyTypePath, ToolName: "**/*.pem", Action: api.ToolPolicyActionDeny, Scope: api.ToolPolicyScopeTeam},
+		{ID: "team-read", ToolName: "Read", Action: api.ToolPolicyActionAllow, Scope: api.ToolPolicyScopeTeam},
+	})
+	h.SetProjectRoot("/home/dev/project")
+
+	_, blocked := h.matchDenyPolicy("Read", `{"file_path":".env"}`, true)
+	assert.False(t, blocked, "a team allow lifts an organization path deny")
+	assert.Len(t, h.matchOverrides("Read", map[string]interface{}{"file_path": ".env"}), 1)
+
+	policy, blocked := h.matchDenyPolicy("Read", `{"file_path":"certs/key.pem"}`, true)
+	assert.True(t, blocked, "ties go to the restriction")
+	assert.Equal(t, "team-keys", policy.ID)
+
+	policy, blocked = h.matchDenyPolicy("Edit", `{"file_path":".env"}`, true)
+	assert.True(t, blocked)
+	assert.Equal(t, "org-env", policy.ID)
+}
+
+func TestPolicyHandler_PathPolicy_AllowedPaths(t *testing.T) {
+	// Outside the project, only the allowed paths may be touched
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{{
+		ID:         "p-1",
+		PolicyType: api.ToolPolicyTypePath,
+		ToolName:   "/**",
+		Action:     api.ToolPolicyActionDeny,
+		Conditions: map[string]interface{}{"allowed_paths": []interface{}{"**"}},
+	}})
+	h.SetProjectRoot("/home/dev/project")
+
+	_, blocked := h.evaluateConditions("Write", `{"file_path":"src/new.go"}`)
+	assert.False(t, blocked)
+	_, blocked = h.evaluateConditions("Grep", `{"pattern":"TODO"}`)
+	assert.False(t, blocked)
+	_, blocked = h.evaluateConditions("Read", `{"file_path":"~/.aws/credentials"}`)
+	assert.True(t, blocked)
+	_, blocked = h.evaluateConditions("Read", `{"file_path":"src/../../other/main.go"}`)
+	assert.True(t, blocked)
+}
+
+func TestPolicyHandler_PathPolicy_Symlink(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	project := filepath.Join(dir, "project")
+	secrets := filepath.Join(dir, "secrets")
+	require.NoError(t, os.Mkdir(project, 0o755))
+	require.NoError(t, os.Mkdir(secrets, 0o755))
+	require.NoError(t, os.Symlink(secrets, filepath.Join(project, "docs")))
+
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{{
+		ID:         "p-1",
+		PolicyType: api.ToolPolicyTypePath,
+		ToolName:   "/**",
+		Action:     api.ToolPolicyActionDeny,
+		Conditions: map[string]interface{}{"allowed_paths": []interface{}{"**"}},
+	}})
+	h.SetProjectRoot(project)
+
+	_, blocked := h.evaluateConditions("Read", `{"file_path":"docs/key.pem"}`)
+	assert.True(t, blocked, "a link out of the project is followed")
+}
+
+func TestPolicyHandler_PathPolicy_JSONResponse(t *testing.T) {
+	queue := &mockToolLoggerQueue{}
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{
+		{ID: "p-1", PolicyType: api.ToolPolicyTypePath, ToolName: "~/.ssh/**", Action: api.ToolPolicyActionDeny},
+		{ID: "p-2", PolicyType: api.ToolPolicyTypePath, ToolName: "**/*.pem", Action: api.ToolPolicyActionAudit},
+	})
+	h.SetQueue(queue)
+	h.SetProjectRoot("/home/dev/project")
+	h.paths.home = "/home/dev"
+
+	body := `{"id":"msg_1","type":"message","role":"assistant","content":[` +
+		`{"type":"tool_use","id":"toolu_1","name":"Read","input":{"file_path":"/home/dev/.ssh/id_rsa"}},` +
+		`{"type":"tool_use","id":"toolu_2","name":"Read","input":{"file_path":"certs/dev.pem"}}],` +
+		`"stop_reason":"tool_use"}`
+	res := jsonResponse("https://api.anthropic.com/v1/messages", body)
+
+	result := h.HandleResponse(NewHandlerContext("emp-1", "org-1", "sess-1"), res)
+	require.NotNil(t, result.ModifiedResponse)
+	output := string(drainBody(t, res))
+	assert.Contains(t, output, "Access to /home/dev/.ssh/id_rsa is blocked by organization policy")
+	assert.Contains(t, output, "toolu_2")
+
+	var events []string
+	for _, entry := range queue.Entries() {
+		events = append(events, entry.EventType)
+	}
+	assert.ElementsMatch(t, []string{"tool_call", "policy_violation"}, events)
+}
+
+func TestPolicyHandler_PathPolicy_IgnoredForTools(t *testing.T) {
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{
+		{ID: "p-1", PolicyType: api.ToolPolicyTypePath, ToolName: "Read", Action: api.ToolPolicyActionDeny},
+	})
+
+	_, blocked := h.isBlocked("Read")
+	assert.False(t, blocked, "path policies don't block tools by name")
+}
@@ -15,6 +15,10 @@ type ProxyInfo struct {
 	Port      int       `json:"port"`
 	StartedAt time.Time `json:"started_at"`
 	CertPath  string    `json:"cert_path"`
+
+	// ControlToken authenticates arfa approve with the proxy. The PID file is
+	// only readable by its owner.
+	ControlToken string `json:"control_token,omitempty"`
 }
 
 // ProxyStatus represents the current status of the proxy.
@@ -24,11 +24,20 @@ const (
 	StateRevoked      ProxyState = "revoked"      // Access revoked (block all)
 )
 
+// FailMode controls whether requests are blocked when policies can't be enforced
+type FailMode string
+
+const (
+	FailModeClosed FailMode = "closed" // Block all requests (default)
+	FailModeOpen   FailMode = "open"   // Allow requests; revocation still blocks
+)
+
 // PolicyClientConfig holds configuration for PolicyClient
 type PolicyClientConfig struct {
 	APIURL           string        // Base API URL (e.g., http://localhost:3001)
 	Token            string        // JWT token for authentication
-	GracePeriod      time.Duration // Time to allow cached policies after disconnect (default: 5m)
+	GracePeriod      time.Duration // Time to allow cached policies after disconnect (default: 5m, server may override)
+	FailMode         FailMode      // Behavior when policies can't be enforced (default: closed, server may override)
 	ReconnectBackoff time.Duration // Initial backoff for reconnection (default: 1s)
 	MaxReconnectWait time.Duration // Max backoff for reconnection (default: 30s)
 }
@@ -41,6 +50,29 @@ type PolicyMessage struct {
 	PolicyID *string      `json:"policy_id,omitempty"`
 	Reason   string       `json:"reason,omitempty"`
 	Version  int64        `json:"version,omitempty"`
+	Timezone string       `json:"timezone,omitempty"` // For init: the organization's, for schedules
+
+	Enforcement    *EnforcementSettings `json:"enforcement,omitempty"`
+	InterceptHosts []InterceptHost      `json:"intercept_hosts,omitempty"`
+
+	Decision *ApprovalDecision `json:"decision,omitempty"` // For approval_decision
+
+	Budgets  []BudgetData `json:"budgets,omitempty"`   // For init
+	Budget   *BudgetData  `json:"budget,omitempty"`    // For budget
+	BudgetID *string      `json:"budget_id,omitempty"` // For budget_delete
+}
+
+// ProxyMessage represents a message sent to the server
+type ProxyMessage struct {
+	Type       string           `json:"type"`
+	Approval   *ApprovalRequest `json:"approval,omitempty"`    // For approval_request
+	ApprovalID string           `json:"approval_id,omitempty"` // For approval_resolved
+}
+
+// EnforcementSettings are the organization's fail-mode settings, sent with init
+type EnforcementSettings struct {
+	FailMode           FailMode `json:"fail_mode"`
+	GracePeriodSeconds int      `json:"grace_period_seconds"`
 }
 
 // PolicyData represents a policy in WebSocket messages
@@ -49,6 +81,7 @@ type PolicyData struct {
 	OrgID      string                 `json:"org_id"`
 	TeamID     *string                `json:"team_id,omitempty"`
 	EmployeeID *string                `json:"employee_id,omitempty"`
+	PolicyType string                 `json:"policy_type,omitempty"` // tool or model
 	ToolName   string                 `json:"tool_name"`
 	Action     string                 `json:"action"`
 	Reason     string                 `json:"reason,omitempty"`
@@ -58,22 +91,31 @@ type PolicyData struct {
 
 // PolicyClient manages WebSocket connection for real-time policy updates
 type PolicyClient struct {
-	config PolicyClientConfig
-	conn   *websocket.Conn
+	config  PolicyClientConfig
+	conn    *websocket.Conn
+	writeMu sync.Mutex // Serializes writes; gorilla/websocket allows one writer at a time
 
 	// Policy storage
 	policies map[string]PolicyData // id -> policy
+	timezone *time.Location        // Policy schedules are read in it
 	mu       sync.RWMutex
 
 	// State management
 	state          ProxyState
 	stateMu        sync.RWMutex
+	failMode       FailMode
+	gracePeriod    time.Duration
 	lastContact    time.Time
 	disconnectedAt time.Time
 
 	// Callbacks
-	onStateChange     func(ProxyState)
-	onPoliciesChanged func()
+	onStateChange           func(ProxyState)
+	onPoliciesChanged       func()
+	onInterceptHostsChanged func([]InterceptHost)
+	onApprovalDecision      func(ApprovalDecision)
+
+	// Budgets kept up to date by init and budget messages (optional)
+	budgets *Budgets
 
 	// Control channels
 	done   chan struct{}
@@ -91,13 +133,18 @@ func NewPolicyClient(config PolicyClientConfig) *PolicyClient {
 	if config.MaxReconnectWait == 0 {
 		config.MaxReconnectWait = 30 * time.Second
 	}
+	if config.FailMode == "" {
+		config.FailMode = FailModeClosed
+	}
 
 	return &PolicyClient{
-		config:   config,
-		policies: make(map[string]PolicyData),
-		state:    StateConnecting,
-		done:     make(chan struct{}),
-		initCh:   make(chan struct{}),
+		config:      config,
+		policies:    make(map[string]PolicyData),
+		state:       StateConnecting,
+		failMode:    config.FailMode,
+		gracePeriod: config.GracePeriod,
+		done:        make(chan struct{}),
+		initCh:      make(chan struct{}),
 	}
 }
 
@@ -111,6 +158,22 @@ func (c *PolicyClient) SetOnPoliciesChanged(fn func()) {
 	c.onPoliciesChanged = fn
 }
 
+// SetOnInterceptHostsChanged sets callback for the organization's intercept hosts,
+// called with every init message
+func (c *PolicyClient) SetOnInterceptHostsChanged(fn func([]InterceptHost)) {
+	c.onInterceptHostsChanged = fn
+}
+
+// SetOnApprovalDecision sets callback for remote decisions on pending approvals
+func (c *PolicyClient) SetOnApprovalDecision(fn func(ApprovalDecision)) {
+	c.onApprovalDecision = fn
+}
+
+// SetBudgets sets the budgets to update from init and budget messages
+func (c *PolicyClient) SetBudgets(budgets *Budgets) {
+	c.budgets = budgets
+}
+
 // Connect establishes WebSocket connection and starts receiving policies
 func (c *PolicyClient) Connect(ctx context.Context) error {
 	// Build WebSocket URL
@@ -276,6 +339,12 @@ func (c *PolicyClient) handleMessage(data []byte) {
 		c.handleRevoke(msg)
 	case "ping":
 		c.handlePing()
+	case "approval_decision":
+		c.handleApprovalDecision(msg)
+	case "budget":
+		c.handleBudget(msg)
+	case "budget_delete":
+		c.handleBudgetDelete(msg)
 	}
 }
 
@@ -286,10 +355,26 @@ func (c *PolicyClient) handleInit(msg PolicyMessage) {
 	for _, p := range msg.Policies {
 		c.policies[p.ID] = p
 	}
+	c.timezone = time.UTC // Servers that predate schedules send none
+	if loc, err := time.LoadLocation(msg.Timezone); err == nil {
+		c.timezone = loc
+	}
 	c.mu.Unlock()
 
 	log.Printf("Received %d policies (version %d)", len(msg.Policies), msg.Version)
 
+	if msg.Enforcement != nil {
+		c.applyEnforcement(*msg.Enforcement)
+	}
+
+	if c.onInterceptHostsChanged != nil {
+		c.onInterceptHostsChanged(msg.InterceptHosts)
+	}
+
+	if c.budgets != nil {
+		c.budgets.Set(msg.Budgets)
+	}
+
 	// Signal that init is complete
 	select {
 	case <-c.initCh:
@@ -303,6 +388,20 @@ func (c *PolicyClient) handleInit(msg PolicyMessage) {
 	}
 }
 
+// applyEnforcement updates fail mode and grace period from server settings
+func (c *PolicyClient) applyEnforcement(settings EnforcementSettings) {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+
+	switch settings.FailMode {
+	case FailModeClosed, FailModeOpen:
+		c.failMode = settings.FailMode
+	}
+	if settings.GracePeriodSeconds >= 0 {
+		c.gracePeriod = time.Duration(settings.GracePeriodSeconds) * time.Second
+	}
+}
+
 // handleUpsert processes policy create/update
 func (c *PolicyClient) handleUpsert(msg PolicyMessage) {
 	if msg.Policy == nil {
@@ -313,7 +412,11 @@ func (c *PolicyClient) handleUpsert(msg PolicyMessage) {
 	c.policies[msg.Policy.ID] = *msg.Policy
 	c.mu.Unlock()
 
-	log.Printf("Policy upserted: %s (%s)", msg.Policy.ToolName, msg.Policy.Action)
+	if msg.Reason != "" {
+		log.Printf("Policy upserted: %s (%s): %s", msg.Policy.ToolName, msg.Policy.Action, msg.Reason)
+	} else {
+		log.Printf("Policy upserted: %s (%s)", msg.Policy.ToolName, msg.Policy.Action)
+	}
 
 	if c.onPoliciesChanged != nil {
 		c.onPoliciesChanged()
@@ -330,7 +433,11 @@ func (c *PolicyClient) handleDelete(msg PolicyMessage) {
 	delete(c.policies, *msg.PolicyID)
 	c.mu.Unlock()
 
-	log.Printf("Policy deleted: %s", *msg.PolicyID)
+	if msg.Reason != "" {
+		log.Printf("Policy deleted: %s: %s", *msg.PolicyID, msg.Reason)
+	} else {
+		log.Printf("Policy deleted: %s", *msg.PolicyID)
+	}
 
 	if c.onPoliciesChanged != nil {
 		c.onPoliciesChanged()
@@ -348,15 +455,67 @@ func (c *PolicyClient) handleRevoke(msg PolicyMessage) {
 	}
 }
 
+// handleApprovalDecision delivers an admin's decision on a pending approval
+func (c *PolicyClient) handleApprovalDecision(msg PolicyMessage) {
+	if msg.Decision == nil || msg.Decision.ApprovalID == "" {
+		return
+	}
+
+	decision := *msg.Decision
+	decision.Source = ApprovalSourceRemote
+	log.Printf("Approval %s decided remotely (approved: %v)", decision.ApprovalID, decision.Approved)
+
+	if c.onApprovalDecision != nil {
+		c.onApprovalDecision(decision)
+	}
+}
+
+// handleBudget applies a budget's latest usage from the server
+func (c *PolicyClient) handleBudget(msg PolicyMessage) {
+	if msg.Budget == nil || c.budgets == nil {
+		return
+	}
+	c.budgets.Upsert(*msg.Budget)
+}
+
+// handleBudgetDelete removes a deleted budget
+func (c *PolicyClient) handleBudgetDelete(msg PolicyMessage) {
+	if msg.BudgetID == nil || c.budgets == nil {
+		return
+	}
+	c.budgets.Delete(*msg.BudgetID)
+	log.Printf("Budget deleted: %s", *msg.BudgetID)
+}
+
 // handlePing responds to server ping
 func (c *PolicyClient) handlePing() {
+	_ = c.send(ProxyMessage{Type: "pong"})
+}
+
+// SendApprovalRequest reports a paused tool call so admins can decide remotely
+func (c *PolicyClient) SendApprovalRequest(req ApprovalRequest) error {
+	return c.send(ProxyMessage{Type: "approval_request", Approval: &req})
+}
+
+// SendApprovalResolved tells the server a pending approval no longer needs a decision
+func (c *PolicyClient) SendApprovalResolved(approvalID string) error {
+	return c.send(ProxyMessage{Type: "approval_resolved", ApprovalID: approvalID})
+}
+
+// send writes a message to the server
+func (c *PolicyClient) send(msg ProxyMessage) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
 	if c.conn == nil {
-		return
+		return fmt.Errorf("not connected")
 	}
 
-	pong := map[string]string{"type": "pong"}
-	data, _ := json.Marshal(pong)
-	_ = c.conn.WriteMessage(websocket.TextMessage, data)
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return c.conn.WriteMessage(websocket.TextMessage, data)
 }
 
 // setState updates the state and triggers callback
@@ -377,22 +536,25 @@ func (c *PolicyClient) GetState() ProxyState {
 	return c.state
 }
 
-// ShouldBlockAll returns true if all requests should be blocked
+// ShouldBlockAll returns true if all requests should be blocked.
+// Revocation always blocks; loss of policy enforcement blocks only in fail-closed mode.
 func (c *PolicyClient) ShouldBlockAll() bool {
 	c.stateMu.RLock()
 	state := c.state
 	disconnectedAt := c.disconnectedAt
+	failMode := c.failMode
+	gracePeriod := c.gracePeriod
 	c.stateMu.RUnlock()
 
 	switch state {
 	case StateConnecting:
-		return true // Block until ready
+		return failMode == FailModeClosed // Block until ready
 	case StateRevoked:
 		return true // Block all after revocation
 	case StateDisconnected:
 		// Check if grace period expired
-		if time.Since(disconnectedAt) > c.config.GracePeriod {
-			return true
+		if time.Since(disconnectedAt) > gracePeriod {
+			return failMode == FailModeClosed
 		}
 		return false // Still within grace period
 	default:
@@ -400,6 +562,20 @@ func (c *PolicyClient) ShouldBlockAll() bool {
 	}
 }
 
+// GetFailMode returns the active fail mode
+func (c *PolicyClient) GetFailMode() FailMode {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
+	return c.failMode
+}
+
+// GetGracePeriod returns the active disconnect grace period
+func (c *PolicyClient) GetGracePeriod() time.Duration {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
+	return c.gracePeriod
+}
+
 // GetPolicies returns a copy of all current policies as api.ToolPolicy slice
 func (c *PolicyClient) GetPolicies() []api.ToolPolicy {
 	c.mu.RLock()
@@ -412,12 +588,26 @@ func (c *PolicyClient) GetPolicies() []api.ToolPolicy {
 	return result
 }
 
+// Timezone returns the organization's timezone, which policy schedules are
+// read in. It is UTC until init arrives.
+func (c *PolicyClient) Timezone() *time.Location {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.timezone == nil {
+		return time.UTC
+	}
+	return c.timezone
+}
+
 // toPolicyAPI converts PolicyData to api.ToolPolicy
 func (c *PolicyClient) toPolicyAPI(p PolicyData) api.ToolPolicy {
 	policy := api.ToolPolicy{
+		ID:         p.ID,
+		PolicyType: api.ToolPolicyType(p.PolicyType),
 		ToolName:   p.ToolName,
 		Action:     api.ToolPolicyAction(p.Action),
 		Conditions: p.Conditions,
+		Scope:      api.ToolPolicyScope(p.Scope),
 	}
 
 	if p.Reason != "" {
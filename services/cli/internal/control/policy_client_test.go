@@ -0,0 +1,120 @@
+package control
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyClient_Defaults(t *testing.T) {
+	c := NewPolicyClient(PolicyClientConfig{APIURL: "http://localhost:0"})
+
+	assert.Equal(t, StateConnecting, c.GetState())
+	assert.Equal(t, FailModeClosed, c.GetFailMode())
+	assert.Equal(t, 5*time.Minute, c.GetGracePeriod())
+}
+
+func TestPolicyClient_InitAppliesEnforcementSettings(t *testing.T) {
+	c := NewPolicyClient(PolicyClientConfig{APIURL: "http://localhost:0"})
+
+	c.handleMessage([]byte(`{"type":"init","policies":[],"version":1,"enforcement":{"fail_mode":"open","grace_period_seconds":30}}`))
+
+	assert.Equal(t, FailModeOpen, c.GetFailMode())
+	assert.Equal(t, 30*time.Second, c.GetGracePeriod())
+}
+
+func TestPolicyClient_InitWithoutEnforcementKeepsDefaults(t *testing.T) {
+	c := NewPolicyClient(PolicyClientConfig{APIURL: "http://localhost:0", GracePeriod: time.Minute})
+
+	c.handleMessage([]byte(`{"type":"init","policies":[],"version":1}`))
+
+	assert.Equal(t, FailModeClosed, c.GetFailMode())
+	assert.Equal(t, time.Minute, c.GetGracePeriod())
+}
+
+func TestPolicyClient_ShouldBlockAll(t *testing.T) {
+	tests := []struct {
+		name         string
+		failMode     FailMode
+		state        ProxyState
+		disconnected time.Duration // how long ago the connection was lost
+		want         bool
+	}{
+		{"closed: connecting blocks", FailModeClosed, StateConnecting, 0, true},
+		{"closed: ready allows", FailModeClosed, StateReady, 0, false},
+		{"closed: within grace period allows", FailModeClosed, StateDisconnected, 30 * time.Second, false},
+		{"closed: past grace period blocks", FailModeClosed, StateDisconnected, 2 * time.Minute, true},
+		{"closed: revoked blocks", FailModeClosed, StateRevoked, 0, true},
+		{"open: connecting allows", FailModeOpen, StateConnecting, 0, false},
+		{"open: past grace period allows", FailModeOpen, StateDisconnected, 2 * time.Minute, false},
+		{"open: revoked still blocks", FailModeOpen, StateRevoked, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewPolicyClient(PolicyClientConfig{
+				APIURL:      "http://localhost:0",
+				GracePeriod: time.Minute,
+				FailMode:    tt.failMode,
+			})
+			c.state = tt.state
+			c.disconnectedAt = time.Now().Add(-tt.disconnected)
+
+			assert.Equal(t, tt.want, c.ShouldBlockAll())
+		})
+	}
+}
+
+func TestPolicyClient_InitDeliversInterceptHosts(t *testing.T) {
+	c := NewPolicyClient(PolicyClientConfig{APIURL: "http://localhost:0"})
+
+	var got []InterceptHost
+	c.SetOnInterceptHostsChanged(func(hosts []InterceptHost) { got = hosts })
+
+	c.handleMessage([]byte(`{"type":"init","policies":[],"version":1,"intercept_hosts":[{"host":"llm.corp","protocol":"openai"}]}`))
+
+	assert.Equal(t, []InterceptHost{{Host: "llm.corp", Protocol: ProtocolOpenAI}}, got)
+}
+
+func TestPolicyClient_ApprovalDecision(t *testing.T) {
+	c := NewPolicyClient(PolicyClientConfig{APIURL: "http://localhost:0"})
+
+	var got []ApprovalDecision
+	c.SetOnApprovalDecision(func(d ApprovalDecision) { got = append(got, d) })
+
+	c.handleMessage([]byte(`{"type":"approval_decision","decision":{"approval_id":"apr_1","approved":true,"decided_by":"admin-1"}}`))
+	c.handleMessage([]byte(`{"type":"approval_decision"}`))
+
+	assert.Equal(t, []ApprovalDecision{{ApprovalID: "apr_1", Approved: true, DecidedBy: "admin-1", Source: ApprovalSourceRemote}}, got)
+}
+
+func TestPolicyClient_BudgetMessages(t *testing.T) {
+	c := NewPolicyClient(PolicyClientConfig{APIURL: "http://localhost:0"})
+	budgets := NewBudgets()
+	c.SetBudgets(budgets)
+
+	c.handleMessage([]byte(`{"type":"init","policies":[],"version":1,"budgets":[{"id":"b-1","scope":"team","period":"daily","token_limit":1000,"tokens_used":10,"resets_at":"2999-01-01T00:00:00Z"}]}`))
+	_, exceeded := budgets.Exceeded()
+	assert.False(t, exceeded)
+
+	// Usage reported from another machine pushes the team over its limit
+	c.handleMessage([]byte(`{"type":"budget","budget":{"id":"b-1","scope":"team","period":"daily","token_limit":1000,"tokens_used":1200,"resets_at":"2999-01-01T00:00:00Z"}}`))
+	budget, exceeded := budgets.Exceeded()
+	assert.True(t, exceeded)
+	assert.Equal(t, int64(1200), budget.TokensUsed)
+
+	c.handleMessage([]byte(`{"type":"budget_delete","budget_id":"b-1"}`))
+	_, exceeded = budgets.Exceeded()
+	assert.False(t, exceeded)
+}
+
+func TestPolicyClient_GetPoliciesKeepsID(t *testing.T) {
+	c := NewPolicyClient(PolicyClientConfig{APIURL: "http://localhost:0"})
+
+	c.handleMessage([]byte(`{"type":"init","policies":[{"id":"pol-1","tool_name":"Bash","action":"require_approval"}],"version":1}`))
+
+	policies := c.GetPolicies()
+	assert.Len(t, policies, 1)
+	assert.Equal(t, "pol-1", policies[0].ID)
+}
@@ -3,17 +3,17 @@
 package control
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/rastrigin-systems/arfa/pkg/policy"
 	"github.com/rastrigin-systems/arfa/services/cli/internal/api"
 )
 
@@ -23,13 +23,48 @@ type PolicyHandler struct {
 	// denyList contains tool names that should be blocked unconditionally.
 	denyList map[string]string // tool name -> reason
 
-	// globPatterns contains tool name patterns that end with %
-	// These match tools that start with the pattern prefix
-	globPatterns map[string]string // pattern prefix -> reason
+	// globPatterns contains tool name patterns that end with %, indexed by
+	// prefix. These match tools that start with the pattern prefix.
+	globPatterns *prefixTrie
 
-	// conditionalPolicies contains policies with conditions that need parameter evaluation.
-	// Key is tool name (lowercase), value is list of policies with conditions.
-	conditionalPolicies map[string][]conditionalPolicy
+	// conditionalPolicies contains deny policies with conditions that need
+	// parameter evaluation, highest precedence first. They name tools like
+	// other rules: exactly, ignoring case, or by a prefix ending in %.
+	conditionalPolicies []policyRule
+
+	// denyRules contains the unconditional deny policies behind denyList and
+	// globPatterns, highest precedence first, to name the policy blocking a call.
+	denyRules []policyRule
+
+	// allowPolicies contains action="allow" policies. Tool calls they match are
+	// exempt from the restrictions they outrank; see precedence.go.
+	allowPolicies []policyRule
+
+	// auditPolicies contains action="audit" policies. Tool calls they match are
+	// let through and logged as policy_violation events.
+	auditPolicies []policyRule
+
+	// approvalPolicies contains action="require_approval" policies. Tool calls
+	// they match are held until approved, denied, or timed out.
+	approvalPolicies []policyRule
+
+	// approvals receives tool calls waiting for a decision. Without it, calls
+	// that require approval are denied.
+	approvals *Approvals
+
+	// rateLimits contains action="rate_limit" policies. Tool calls over a
+	// limit are blocked; limiter counts the calls in each window.
+	rateLimits []rateLimitRule
+	limiter    *rateLimiter
+
+	// modelPolicies contains policy_type="model" policies, checked against
+	// the model of each conversation request. Sorted most specific first.
+	modelPolicies []modelRule
+
+	// pathPolicies contains policy_type="path" policies, checked against the
+	// paths file tools touch. paths resolves relative paths and patterns.
+	pathPolicies []pathRule
+	paths        pathScope
 
 	// queue is optional - if set, blocked tools are logged as tool_call events
 	queue LoggerQueue
@@ -37,24 +72,34 @@ type PolicyHandler struct {
 	// policyClient provides real-time policy updates via WebSocket
 	policyClient *PolicyClient
 
+	// scheduleTimer rebuilds the lists when a scheduled policy from
+	// policyClient may next start or stop applying
+	scheduleTimer *time.Timer
+
 	// mu protects concurrent access to policy lists during updates
 	mu sync.RWMutex
 }
 
-// conditionalPolicy represents a policy with conditions to evaluate against tool input.
-type conditionalPolicy struct {
+// policyRule is a tool policy as the handler evaluates it. Every action matches
+// tools the same way: exact name, a prefix ending in %, and optional conditions.
+type policyRule struct {
+	ID         string
 	ToolName   string
+	Action     api.ToolPolicyAction
+	Scope      api.ToolPolicyScope
 	Reason     string
 	Conditions map[string]interface{}
+	cond       policy.Condition // Conditions, compiled; nil when there are none
 }
 
 // NewPolicyHandler creates a new PolicyHandler.
 // Policies are loaded via WebSocket when SetPolicyClient is called.
 func NewPolicyHandler() *PolicyHandler {
 	return &PolicyHandler{
-		denyList:            make(map[string]string),
-		globPatterns:        make(map[string]string),
-		conditionalPolicies: make(map[string][]conditionalPolicy),
+		denyList:     make(map[string]string),
+		globPatterns: newPrefixTrie(),
+		limiter:      newRateLimiter(),
+		paths:        newPathScope(workingDir()),
 	}
 }
 
@@ -62,26 +107,29 @@ func NewPolicyHandler() *PolicyHandler {
 // Used for testing.
 func NewPolicyHandlerWithDenyList(denyList map[string]string) *PolicyHandler {
 	return &PolicyHandler{
-		denyList:            denyList,
-		globPatterns:        make(map[string]string),
-		conditionalPolicies: make(map[string][]conditionalPolicy),
+		denyList:     denyList,
+		globPatterns: newPrefixTrie(),
+		limiter:      newRateLimiter(),
+		paths:        newPathScope(workingDir()),
 	}
 }
 
 // NewPolicyHandlerWithPolicies creates a PolicyHandler with a list of policies.
-// Used for testing with full policy objects.
+// Used for testing with full policy objects, and by arfa policies test.
 func NewPolicyHandlerWithPolicies(policies []api.ToolPolicy) *PolicyHandler {
 	h := &PolicyHandler{
-		denyList:            make(map[string]string),
-		globPatterns:        make(map[string]string),
-		conditionalPolicies: make(map[string][]conditionalPolicy),
+		denyList:     make(map[string]string),
+		globPatterns: newPrefixTrie(),
+		limiter:      newRateLimiter(),
+		paths:        newPathScope(workingDir()),
 	}
 	h.buildDenyList(policies)
 	return h
 }
 
 // buildDenyList converts policies to the internal deny list format.
-// Only policies with action="deny" are added.
+// Model policies and policies with action="audit", "require_approval", "rate_limit" or "allow"
+// are kept separately; other non-deny actions are ignored.
 // Policies with conditions are stored separately for parameter evaluation.
 func (h *PolicyHandler) buildDenyList(policies []api.ToolPolicy) {
 	h.mu.Lock()
@@ -92,41 +140,87 @@ func (h *PolicyHandler) buildDenyList(policies []api.ToolPolicy) {
 // buildDenyListLocked converts policies to the internal deny list format.
 // Caller must hold h.mu.
 func (h *PolicyHandler) buildDenyListLocked(policies []api.ToolPolicy) {
-	for _, policy := range policies {
-		if policy.Action != api.ToolPolicyActionDeny {
-			continue // Skip audit-only policies
+	for _, p := range policies {
+		if p.PolicyType == api.ToolPolicyTypeModel {
+			if rule, ok := newModelRule(p); ok {
+				h.modelPolicies = append(h.modelPolicies, rule)
+			}
+			continue
+		}
+		if p.PolicyType == api.ToolPolicyTypePath {
+			if rule, ok := newPathRule(p); ok {
+				h.pathPolicies = append(h.pathPolicies, rule)
+			}
+			continue
 		}
 
-		reason := "Tool blocked by organization policy"
-		if policy.Reason != nil && *policy.Reason != "" {
-			reason = *policy.Reason
+		switch p.Action {
+		case api.ToolPolicyActionAudit:
+			h.auditPolicies = append(h.auditPolicies, newPolicyRule(p))
+			continue
+		case api.ToolPolicyActionRequireApproval:
+			h.approvalPolicies = append(h.approvalPolicies, newPolicyRule(p))
+			continue
+		case api.ToolPolicyActionRateLimit:
+			if rule, ok := newRateLimitRule(p); ok {
+				h.rateLimits = append(h.rateLimits, rule)
+			}
+			continue
+		case api.ToolPolicyActionAllow:
+			h.allowPolicies = append(h.allowPolicies, newPolicyRule(p))
+			continue
+		case api.ToolPolicyActionDeny:
+		default:
+			continue
 		}
 
-		toolName := policy.ToolName
+		// Conditions are compiled here, once per policy update
+		rule := newPolicyRule(p)
+		if rule.Reason == "" {
+			rule.Reason = "Tool blocked by organization policy"
+		}
+		reason := rule.Reason
+		toolName := p.ToolName
 
-		// Handle policies with conditions - these need parameter evaluation
-		if len(policy.Conditions) > 0 {
-			cp := conditionalPolicy{
-				ToolName:   toolName,
-				Reason:     reason,
-				Conditions: policy.Conditions,
-			}
-			// Store by lowercase tool name for case-insensitive matching
-			key := strings.ToLower(toolName)
-			h.conditionalPolicies[key] = append(h.conditionalPolicies[key], cp)
+		// Handle policies with conditions - these need parameter evaluation.
+		if rule.cond != nil {
+			h.conditionalPolicies = append(h.conditionalPolicies, rule)
 			continue
 		}
+		h.denyRules = append(h.denyRules, rule)
 
 		// Handle glob patterns (e.g., "mcp__gcloud__%")
 		if strings.HasSuffix(toolName, "%") {
 			prefix := strings.TrimSuffix(toolName, "%")
-			h.globPatterns[prefix] = reason
+			h.globPatterns.insert(prefix, reason)
 		} else {
 			// Exact match - store in both original case and lowercase
 			h.denyList[toolName] = reason
 			h.denyList[strings.ToLower(toolName)] = reason
 		}
 	}
+
+	sortModelRules(h.modelPolicies)
+	sortByPrecedence(h.denyRules)
+	sortByPrecedence(h.allowPolicies)
+	sortByPrecedence(h.approvalPolicies)
+	sortByPrecedence(h.conditionalPolicies)
+}
+
+// newPolicyRule converts a policy from the API.
+func newPolicyRule(p api.ToolPolicy) policyRule {
+	ap := policyRule{
+		ID:         p.ID,
+		ToolName:   p.ToolName,
+		Action:     p.Action,
+		Scope:      p.Scope,
+		Conditions: p.Conditions,
+		cond:       policy.CompileOrNever(p.Conditions),
+	}
+	if p.Reason != nil {
+		ap.Reason = *p.Reason
+	}
+	return ap
 }
 
 // isBlocked checks if a tool should be blocked, returning the reason if so.
@@ -144,131 +238,182 @@ func (h *PolicyHandler) isBlocked(toolName string) (string, bool) {
 		return reason, true
 	}
 
-	// Check glob patterns
-	for prefix, reason := range h.globPatterns {
-		if strings.HasPrefix(toolName, prefix) || strings.HasPrefix(strings.ToLower(toolName), strings.ToLower(prefix)) {
-			return reason, true
-		}
-	}
-
-	return "", false
+	// Check glob patterns; the most specific prefix decides
+	return h.globPatterns.longestMatch(toolName)
 }
 
-// pendingBlock tracks tool_use blocks that need condition evaluation.
-// We buffer events until we have enough input to evaluate conditions.
+// pendingBlock tracks a tool_use block while its input streams in.
+// Blocks that need condition evaluation hold their events until content_block_stop.
 type pendingBlock struct {
-	index       int
-	toolName    string
-	toolID      string // Tool use ID for logging
-	startEvent  string // The original content_block_start event
-	startData   string // The original data for content_block_start
-	deltaEvents []string
-	inputJSON   strings.Builder // Accumulated JSON input from deltas
+	index     int
+	toolName  string
+	toolID    string     // Tool use ID for logging
+	policy    policyRule // Deny policy, set for unconditionally blocked tools
+	held      []SSEEvent
+	inputJSON strings.Builder // Accumulated JSON input from deltas
 }
 
-// hasConditionalPolicies checks if a tool has policies with conditions.
+// hasConditionalPolicies checks if a tool has policies with conditions,
+// including path deny policies for file tools.
 func (h *PolicyHandler) hasConditionalPolicies(toolName string) bool {
+	if h.hasPathPolicies(api.ToolPolicyActionDeny, toolName) {
+		return true
+	}
+
 	h.mu.RLock()
 	defer h.mu.RUnlock()
-
-	key := strings.ToLower(toolName)
-	policies, exists := h.conditionalPolicies[key]
-	return exists && len(policies) > 0
+	return rulesNameTool(h.conditionalPolicies, toolName)
 }
 
-// evaluateConditions checks if tool input matches any conditional policy.
+// evaluateConditions checks if tool input matches any conditional policy or,
+// for file tools, any path deny policy.
 // Returns (reason, blocked) - if blocked is true, the tool should be denied.
 func (h *PolicyHandler) evaluateConditions(toolName string, input string) (string, bool) {
+	policy, blocked := h.matchDenyPolicy(toolName, input, false)
+	return policy.Reason, blocked
+}
+
+// matchDenyPolicy returns the highest ranked deny policy blocking a tool
+// call: a path deny policy for file tools, a conditional policy the input
+// matches, or with unconditional set, an unconditional one. Policies an
+// allow policy outranks are skipped; see precedence.go.
+func (h *PolicyHandler) matchDenyPolicy(toolName, input string, unconditional bool) (policyRule, bool) {
+	inputMap := parseToolInput(input)
+	pathRules, paths := h.matchPathPolicies(api.ToolPolicyActionDeny, toolName, inputMap)
+
+	var candidates []policyRule
+	deniedBy, denied := "", false
+	if unconditional {
+		deniedBy, denied = h.isBlocked(toolName)
+	}
+
 	h.mu.RLock()
-	key := strings.ToLower(toolName)
-	policies, exists := h.conditionalPolicies[key]
-	if !exists {
-		h.mu.RUnlock()
-		return "", false
+	if denied {
+		for _, rule := range h.denyRules {
+			if policy.MatchesToolName(rule.ToolName, toolName) {
+				candidates = append(candidates, rule)
+			}
+		}
+	}
+	named := len(candidates) > 0
+	// Input that isn't a JSON object matches no conditions (fail open)
+	for _, rule := range h.conditionalPolicies {
+		if h.ruleMatches(rule, toolName, inputMap) {
+			candidates = append(candidates, rule)
+		}
 	}
-	// Copy policies to avoid holding lock while processing
-	policiesCopy := make([]conditionalPolicy, len(policies))
-	copy(policiesCopy, policies)
 	h.mu.RUnlock()
 
-	// Parse the input JSON to extract parameter values
-	var inputMap map[string]interface{}
-	if err := json.Unmarshal([]byte(input), &inputMap); err != nil {
-		// If we can't parse, don't block (fail open)
-		return "", false
+	if denied && !named {
+		// A deny list set without its policies
+		return policyRule{ToolName: toolName, Action: api.ToolPolicyActionDeny, Reason: deniedBy}, true
 	}
-
-	for _, policy := range policiesCopy {
-		if h.matchesConditions(inputMap, policy.Conditions) {
-			return policy.Reason, true
+	for i, rule := range pathRules {
+		candidate := rule.policyRule
+		candidate.Reason = pathDenialReason(rule, paths[i])
+		candidates = append(candidates, candidate)
+	}
+	sortByPrecedence(candidates)
+	for _, rule := range candidates {
+		if _, allowed := h.allowedBy(rule, toolName, inputMap); !allowed {
+			return rule, true
 		}
 	}
+	return policyRule{}, false
+}
 
-	return "", false
+// hasAuditPolicies reports whether any audit policy names the tool, before
+// its input is known.
+func (h *PolicyHandler) hasAuditPolicies(toolName string) bool {
+	h.mu.RLock()
+	rules := h.auditPolicies
+	h.mu.RUnlock()
+	return rulesNameTool(rules, toolName) || h.hasPathPolicies(api.ToolPolicyActionAudit, toolName)
+}
+
+// matchAuditPolicies returns every audit policy matching the tool call,
+// including path audit policies.
+func (h *PolicyHandler) matchAuditPolicies(toolName, input string) []policyRule {
+	h.mu.RLock()
+	rules := h.auditPolicies
+	h.mu.RUnlock()
+
+	matched := h.matchRules(rules, toolName, input)
+	pathRules, _ := h.matchPathPolicies(api.ToolPolicyActionAudit, toolName, parseToolInput(input))
+	for _, rule := range pathRules {
+		matched = append(matched, rule.policyRule)
+	}
+	return matched
+}
+
+// hasApprovalPolicies reports whether any require_approval policy names the
+// tool, before its input is known.
+func (h *PolicyHandler) hasApprovalPolicies(toolName string) bool {
+	h.mu.RLock()
+	rules := h.approvalPolicies
+	h.mu.RUnlock()
+	return rulesNameTool(rules, toolName)
 }
 
-// matchesConditions checks if the input matches all conditions in a policy.
-// Conditions use regex patterns that must match parameter values.
-func (h *PolicyHandler) matchesConditions(input map[string]interface{}, conditions map[string]interface{}) bool {
-	for paramName, condition := range conditions {
-		// Get the parameter value from input
-		inputValue, exists := input[paramName]
-		if !exists {
-			return false // Parameter doesn't exist, condition not met
+// matchApprovalPolicy returns the highest ranked require_approval policy
+// matching the tool call that no allow policy outranks.
+func (h *PolicyHandler) matchApprovalPolicy(toolName, input string) (policyRule, bool) {
+	h.mu.RLock()
+	rules := h.approvalPolicies
+	h.mu.RUnlock()
+	inputMap := parseToolInput(input)
+	for _, rule := range h.matchRules(rules, toolName, input) {
+		if _, allowed := h.allowedBy(rule, toolName, inputMap); !allowed {
+			return rule, true
 		}
+	}
+	return policyRule{}, false
+}
 
-		// Convert input value to string for pattern matching
-		var inputStr string
-		switch v := inputValue.(type) {
-		case string:
-			inputStr = v
-		case float64:
-			inputStr = fmt.Sprintf("%v", v)
-		case bool:
-			inputStr = fmt.Sprintf("%v", v)
-		default:
-			// For complex types (arrays, objects), marshal to JSON
-			jsonBytes, err := json.Marshal(v)
-			if err != nil {
-				return false
-			}
-			inputStr = string(jsonBytes)
+// rulesNameTool reports whether any of the rules matches the tool name.
+func rulesNameTool(rules []policyRule, toolName string) bool {
+	for _, rule := range rules {
+		if policy.MatchesToolName(rule.ToolName, toolName) {
+			return true
 		}
+	}
+	return false
+}
 
-		// Check condition type
-		switch c := condition.(type) {
-		case string:
-			// Direct string - treat as regex pattern
-			if !h.matchesPattern(inputStr, c) {
-				return false
-			}
-		case map[string]interface{}:
-			// Object with operator
-			if pattern, ok := c["matches"].(string); ok {
-				if !h.matchesPattern(inputStr, pattern) {
-					return false
-				}
-			} else if pattern, ok := c["contains"].(string); ok {
-				if !strings.Contains(inputStr, pattern) {
-					return false
-				}
-			} else if pattern, ok := c["equals"].(string); ok {
-				if inputStr != pattern {
-					return false
-				}
-			}
+// matchRules returns the rules matching the tool call.
+func (h *PolicyHandler) matchRules(rules []policyRule, toolName, input string) []policyRule {
+	inputMap := parseToolInput(input)
+
+	var matched []policyRule
+	for _, rule := range rules {
+		if h.ruleMatches(rule, toolName, inputMap) {
+			matched = append(matched, rule)
 		}
 	}
-	return true
+	return matched
 }
 
-// matchesPattern checks if a string matches a regex pattern.
-func (h *PolicyHandler) matchesPattern(s, pattern string) bool {
-	re, err := regexp.Compile(pattern)
-	if err != nil {
-		return false // Invalid regex, don't match
+// ruleMatches reports whether a rule matches the tool call. Conditional rules
+// never match input that isn't a JSON object.
+func (h *PolicyHandler) ruleMatches(rule policyRule, toolName string, input map[string]interface{}) bool {
+	if !policy.MatchesToolName(rule.ToolName, toolName) {
+		return false
 	}
-	return re.MatchString(s)
+	return rule.cond == nil || (input != nil && rule.cond.Match(input))
+}
+
+// hasRateLimits reports whether any rate_limit policy names the tool, before
+// its input is known.
+func (h *PolicyHandler) hasRateLimits(toolName string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, rule := range h.rateLimits {
+		if policy.MatchesToolName(rule.ToolName, toolName) {
+			return true
+		}
+	}
+	return false
 }
 
 // SetQueue sets the logger queue for logging blocked tool calls.
@@ -276,6 +421,26 @@ func (h *PolicyHandler) SetQueue(queue LoggerQueue) {
 	h.queue = queue
 }
 
+// SetRateLimitFile keeps per-employee rate limit windows in a file, so they
+// aren't reset when the proxy restarts.
+func (h *PolicyHandler) SetRateLimitFile(path string) {
+	h.limiter.setFile(path)
+}
+
+// SetProjectRoot sets the directory that relative paths in tool input and
+// allowed_paths in path policies are resolved against. It defaults to the
+// working directory.
+func (h *PolicyHandler) SetProjectRoot(root string) {
+	h.mu.Lock()
+	h.paths = newPathScope(root)
+	h.mu.Unlock()
+}
+
+// SetApprovals sets the queue where tool calls requiring approval wait for a decision.
+func (h *PolicyHandler) SetApprovals(approvals *Approvals) {
+	h.approvals = approvals
+}
+
 // SetPolicyClient sets the PolicyClient for real-time policy updates.
 // When set, policies are sourced from the client instead of file cache.
 // Note: We don't clear disk-cached policies until WebSocket delivers policies.
@@ -292,21 +457,38 @@ func (h *PolicyHandler) SetPolicyClient(client *PolicyClient) {
 	// No policies are enforced until handleInit triggers onPoliciesChanged.
 }
 
-// rebuildFromClient rebuilds the deny lists from PolicyClient's policies.
+// rebuildFromClient rebuilds the deny lists from PolicyClient's policies,
+// leaving out scheduled policies that aren't active now. It runs again when
+// one may next start or stop applying.
 func (h *PolicyHandler) rebuildFromClient() {
 	if h.policyClient == nil {
 		return
 	}
 
-	policies := h.policyClient.GetPolicies()
+	policies, next := ActivePolicies(h.policyClient.GetPolicies(), time.Now(), h.policyClient.Timezone())
 
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
+	if h.scheduleTimer != nil {
+		h.scheduleTimer.Stop()
+		h.scheduleTimer = nil
+	}
+	if !next.IsZero() {
+		h.scheduleTimer = time.AfterFunc(time.Until(next), h.rebuildFromClient)
+	}
+
 	// Clear existing lists
 	h.denyList = make(map[string]string)
-	h.globPatterns = make(map[string]string)
-	h.conditionalPolicies = make(map[string][]conditionalPolicy)
+	h.globPatterns = newPrefixTrie()
+	h.conditionalPolicies = nil
+	h.denyRules = nil
+	h.allowPolicies = nil
+	h.auditPolicies = nil
+	h.approvalPolicies = nil
+	h.rateLimits = nil
+	h.modelPolicies = nil
+	h.pathPolicies = nil
 
 	// Rebuild from client policies
 	h.buildDenyListLocked(policies)
@@ -335,13 +517,29 @@ func (h *PolicyHandler) ShouldBlockAll() (string, bool) {
 	return "", false
 }
 
-// logBlockedTool logs a blocked tool call if a queue is configured.
-func (h *PolicyHandler) logBlockedTool(ctx *HandlerContext, toolName, toolID, reason string, toolInput map[string]interface{}) {
+// logBlockedTool logs a blocked tool call if a queue is configured. Calls
+// blocked by a deny policy name it; rate limits and approvals log their own
+// events.
+func (h *PolicyHandler) logBlockedTool(ctx *HandlerContext, provider Provider, toolName, toolID, reason string, policy policyRule, toolInput map[string]interface{}) {
 	if h.queue == nil {
 		return
 	}
 
-	entry := LogEntry{
+	payload := map[string]interface{}{
+		"session_id":   ctx.SessionID,
+		"provider":     string(provider.Name()),
+		"tool_name":    toolName,
+		"tool_id":      toolID,
+		"tool_input":   toolInput,
+		"blocked":      true,
+		"block_reason": reason,
+	}
+	if policy.ID != "" {
+		payload["policy_id"] = policy.ID
+		payload["scope"] = string(policy.Scope)
+	}
+
+	_ = h.queue.Enqueue(LogEntry{
 		EmployeeID:    ctx.EmployeeID,
 		OrgID:         ctx.OrgID,
 		ClientName:    ctx.ClientName,
@@ -349,17 +547,222 @@ func (h *PolicyHandler) logBlockedTool(ctx *HandlerContext, toolName, toolID, re
 		EventType:     "tool_call",
 		EventCategory: "classified",
 		Timestamp:     time.Now(),
-		Payload: map[string]interface{}{
-			"session_id":   ctx.SessionID,
-			"tool_name":    toolName,
-			"tool_id":      toolID,
-			"tool_input":   toolInput,
-			"blocked":      true,
-			"block_reason": reason,
-		},
+		Payload:       payload,
+	})
+}
+
+// auditToolCall logs a policy_violation for every audit policy the tool call
+// matches. The call itself is not affected.
+func (h *PolicyHandler) auditToolCall(ctx *HandlerContext, provider Provider, toolName, toolID, input string) {
+	if h.queue == nil {
+		return
 	}
+	for _, policy := range h.matchAuditPolicies(toolName, input) {
+		h.logPolicyViolation(ctx, provider, policy, toolName, toolID, parseToolInput(input))
+	}
+}
 
-	_ = h.queue.Enqueue(entry)
+// logPolicyViolation enqueues a policy_violation event for an audited tool call.
+func (h *PolicyHandler) logPolicyViolation(ctx *HandlerContext, provider Provider, policy policyRule, toolName, toolID string, toolInput map[string]interface{}) {
+	payload := map[string]interface{}{
+		"session_id": ctx.SessionID,
+		"provider":   string(provider.Name()),
+		"policy_id":  policy.ID,
+		"action":     string(api.ToolPolicyActionAudit),
+		"tool_name":  toolName,
+		"tool_id":    toolID,
+		"tool_input": toolInput,
+		"blocked":    false,
+	}
+	if policy.Reason != "" {
+		payload["reason"] = policy.Reason
+	}
+	if len(policy.Conditions) > 0 {
+		payload["matched_condition"] = policy.Conditions
+	}
+
+	_ = h.queue.Enqueue(LogEntry{
+		EmployeeID:    ctx.EmployeeID,
+		OrgID:         ctx.OrgID,
+		ClientName:    ctx.ClientName,
+		ClientVersion: ctx.ClientVersion,
+		EventType:     "policy_violation",
+		EventCategory: "classified",
+		Timestamp:     time.Now(),
+		Payload:       payload,
+	})
+}
+
+// checkRateLimits counts a tool call against every rate_limit policy it
+// matches, except those an allow policy outranks. Returns (reason, blocked)
+// like evaluateConditions; a call over any limit is blocked and not counted.
+func (h *PolicyHandler) checkRateLimits(ctx *HandlerContext, provider Provider, toolName, toolID, input string) (string, bool) {
+	inputMap := parseToolInput(input)
+	matched := h.matchRateLimits(toolName, inputMap)
+	if len(matched) == 0 {
+		return "", false
+	}
+	checks := make([]rateLimitCheck, len(matched))
+	for i, rule := range matched {
+		checks[i] = rateLimitCheck{key: rule.key(ctx), maxCalls: rule.MaxCalls, window: rule.Window}
+	}
+
+	i, retryAfter, ok := h.limiter.take(checks)
+	if ok {
+		return "", false
+	}
+
+	rule := matched[i]
+	h.logRateLimited(ctx, provider, rule, toolName, toolID, inputMap, retryAfter)
+	return rateLimitReason(rule, retryAfter), true
+}
+
+// matchRateLimits returns the rate_limit policies a tool call counts against:
+// those it matches that no allow policy outranks.
+func (h *PolicyHandler) matchRateLimits(toolName string, input map[string]interface{}) []rateLimitRule {
+	h.mu.RLock()
+	rules := h.rateLimits
+	h.mu.RUnlock()
+
+	var matched []rateLimitRule
+	for _, rule := range rules {
+		if !h.ruleMatches(rule.policyRule, toolName, input) {
+			continue
+		}
+		if _, allowed := h.allowedBy(rule.policyRule, toolName, input); !allowed {
+			matched = append(matched, rule)
+		}
+	}
+	return matched
+}
+
+// rateLimitReason explains a rate-limited call to the agent.
+func rateLimitReason(rule rateLimitRule, retryAfter time.Duration) string {
+	reason := fmt.Sprintf("Rate limit exceeded: at most %d %s calls per %s per %s. Try again in %s.",
+		rule.MaxCalls, rule.ToolName, rule.Window, rule.Per, retryAfter.Round(time.Second))
+	if rule.Reason != "" {
+		reason += " " + rule.Reason
+	}
+	return reason
+}
+
+// logRateLimited enqueues a policy_violation event for a call over a limit.
+func (h *PolicyHandler) logRateLimited(ctx *HandlerContext, provider Provider, rule rateLimitRule, toolName, toolID string, toolInput map[string]interface{}, retryAfter time.Duration) {
+	if h.queue == nil {
+		return
+	}
+
+	payload := map[string]interface{}{
+		"session_id":          ctx.SessionID,
+		"provider":            string(provider.Name()),
+		"policy_id":           rule.ID,
+		"action":              string(api.ToolPolicyActionRateLimit),
+		"tool_name":           toolName,
+		"tool_id":             toolID,
+		"tool_input":          toolInput,
+		"blocked":             true,
+		"max_calls":           rule.MaxCalls,
+		"window_seconds":      int(rule.Window / time.Second),
+		"per":                 rule.Per,
+		"retry_after_seconds": int(retryAfter.Round(time.Second) / time.Second),
+	}
+	if rule.Reason != "" {
+		payload["reason"] = rule.Reason
+	}
+
+	_ = h.queue.Enqueue(LogEntry{
+		EmployeeID:    ctx.EmployeeID,
+		OrgID:         ctx.OrgID,
+		ClientName:    ctx.ClientName,
+		ClientVersion: ctx.ClientVersion,
+		EventType:     "policy_violation",
+		EventCategory: "classified",
+		Timestamp:     time.Now(),
+		Payload:       payload,
+	})
+}
+
+// awaitApproval holds a tool call matching a require_approval policy until it
+// is approved, denied, or times out. Returns (reason, blocked) like
+// evaluateConditions; calls without a matching policy are never blocked.
+func (h *PolicyHandler) awaitApproval(ctx *HandlerContext, provider Provider, toolName, toolID, input string) (string, bool) {
+	policy, ok := h.matchApprovalPolicy(toolName, input)
+	if !ok {
+		return "", false
+	}
+	if h.approvals == nil {
+		return "Tool requires approval, but approvals are not available", true
+	}
+
+	req := ApprovalRequest{
+		PolicyID:  policy.ID,
+		ToolName:  toolName,
+		ToolInput: parseToolInput(input),
+		Reason:    policy.Reason,
+		SessionID: ctx.SessionID,
+	}
+	decision := h.approvals.Request(req)
+	h.logApprovalDecision(ctx, provider, policy, req, toolID, decision)
+
+	if decision.Approved {
+		return "", false
+	}
+	return approvalDenialReason(decision), true
+}
+
+// approvalDenialReason explains a denied or expired approval to the agent.
+func approvalDenialReason(decision ApprovalDecision) string {
+	var reason string
+	switch decision.Source {
+	case ApprovalSourceTimeout:
+		reason = "Approval request timed out"
+	case ApprovalSourceRemote:
+		reason = "Approval denied by an administrator"
+	default:
+		reason = "Approval denied"
+	}
+	if decision.Reason != "" {
+		reason += ": " + decision.Reason
+	}
+	return reason
+}
+
+// logApprovalDecision enqueues a tool_approval event for every decision,
+// including timeouts.
+func (h *PolicyHandler) logApprovalDecision(ctx *HandlerContext, provider Provider, policy policyRule, req ApprovalRequest, toolID string, decision ApprovalDecision) {
+	if h.queue == nil {
+		return
+	}
+
+	payload := map[string]interface{}{
+		"session_id":      ctx.SessionID,
+		"provider":        string(provider.Name()),
+		"approval_id":     decision.ApprovalID,
+		"policy_id":       policy.ID,
+		"action":          string(api.ToolPolicyActionRequireApproval),
+		"tool_name":       req.ToolName,
+		"tool_id":         toolID,
+		"tool_input":      req.ToolInput,
+		"approved":        decision.Approved,
+		"decision_source": decision.Source,
+	}
+	if decision.DecidedBy != "" {
+		payload["decided_by"] = decision.DecidedBy
+	}
+	if decision.Reason != "" {
+		payload["reason"] = decision.Reason
+	}
+
+	_ = h.queue.Enqueue(LogEntry{
+		EmployeeID:    ctx.EmployeeID,
+		OrgID:         ctx.OrgID,
+		ClientName:    ctx.ClientName,
+		ClientVersion: ctx.ClientVersion,
+		EventType:     "tool_approval",
+		EventCategory: "classified",
+		Timestamp:     time.Now(),
+		Payload:       payload,
+	})
 }
 
 // Name returns the handler name.
@@ -372,42 +775,100 @@ func (h *PolicyHandler) Priority() int {
 	return 110
 }
 
-// HandleRequest is a no-op for policy handler (we block in response).
+// HandleRequest blocks every request while policies can't be enforced
+// (connecting, revoked, or disconnected past the grace period in fail-closed mode).
+// Model policies are applied to conversation requests here: a denied model
+// blocks the request and a rewritten one is forwarded as ModifiedRequest.
+// Tool blocking happens in the response.
 func (h *PolicyHandler) HandleRequest(ctx *HandlerContext, req *http.Request) Result {
-	return ContinueResult()
+	if reason, blocked := h.ShouldBlockAll(); blocked {
+		return BlockResult(reason)
+	}
+	return h.enforceModelPolicies(ctx, req)
 }
 
-// HandleResponse parses SSE stream and blocks denied tools.
+// HandleResponse attaches a stream processor that blocks denied tools as SSE
+// events flow to the client. Text and allowed tool calls pass through as they
+// arrive; only tool calls with conditional, rate limit or approval policies are
+// held until complete.
+// Non-streaming JSON responses are checked and rewritten as a whole.
 func (h *PolicyHandler) HandleResponse(ctx *HandlerContext, res *http.Response) Result {
 	if res == nil || res.Body == nil {
 		return ContinueResult()
 	}
 
-	// Only process SSE streams from Anthropic
-	contentType := res.Header.Get("Content-Type")
-	if !strings.Contains(contentType, "text/event-stream") {
+	if isJSONResponse(res) {
+		return h.handleJSONResponse(ctx, res)
+	}
+
+	// Otherwise only process SSE streams
+	if !isEventStream(res) {
 		return ContinueResult()
 	}
 
-	// Read entire body
-	bodyBytes, err := io.ReadAll(res.Body)
+	AttachSSEProcessor(res, h.newStreamProcessor(ctx, contextProvider(ctx, res.Request)))
+
+	return Result{
+		Action:           ActionContinue,
+		ModifiedResponse: res,
+	}
+}
+
+// isJSONResponse reports whether the response is a single JSON document.
+func isJSONResponse(res *http.Response) bool {
+	return strings.Contains(res.Header.Get("Content-Type"), "application/json")
+}
+
+// handleJSONResponse applies the same rules as the stream processor to a
+// complete response (a request sent with stream: false). Blocked tool calls
+// are replaced with text and logged; the body is left untouched otherwise.
+func (h *PolicyHandler) handleJSONResponse(ctx *HandlerContext, res *http.Response) Result {
+	provider := contextProvider(ctx, res.Request)
+	if res.Request == nil || !provider.MatchEndpoint(res.Request.URL.Path) || !h.hasPolicies() {
+		return ContinueResult()
+	}
+
+	body, err := io.ReadAll(res.Body)
 	_ = res.Body.Close()
+	res.Body = io.NopCloser(bytes.NewReader(body))
 	if err != nil {
-		return ErrorResult(err)
+		return ContinueResult()
+	}
+
+	calls, err := provider.MessageToolCalls(body)
+	if err != nil || len(calls) == 0 {
+		return ContinueResult()
 	}
 
-	// Parse and potentially modify the SSE stream
-	modified, wasModified := h.processSSEStream(ctx, bodyBytes)
+	blocked := make(map[int]string)
+	for _, call := range calls {
+		policy, isBlocked := h.matchDenyPolicy(call.ToolName, call.Input, true)
+		reason := policy.Reason
+		if !isBlocked {
+			reason, isBlocked = h.checkRateLimits(ctx, provider, call.ToolName, call.ToolID, call.Input)
+		}
+		if !isBlocked {
+			reason, isBlocked = h.awaitApproval(ctx, provider, call.ToolName, call.ToolID, call.Input)
+		}
+		if !isBlocked {
+			h.logOverrides(ctx, provider, call.ToolName, call.ToolID, call.Input)
+			h.auditToolCall(ctx, provider, call.ToolName, call.ToolID, call.Input)
+			continue
+		}
+		h.logBlockedTool(ctx, provider, call.ToolName, call.ToolID, reason, policy, parseToolInput(call.Input))
+		blocked[call.Index] = h.formatBlockError(call.ToolName, reason)
+	}
+	if len(blocked) == 0 {
+		return ContinueResult()
+	}
 
-	// Always restore body (we consumed it by reading)
-	// Return ModifiedResponse so pipeline passes restored body to next handler
-	if wasModified {
-		res.Body = io.NopCloser(bytes.NewReader(modified))
-		res.ContentLength = int64(len(modified))
-	} else {
-		res.Body = io.NopCloser(bytes.NewReader(bodyBytes))
-		res.ContentLength = int64(len(bodyBytes))
+	rewritten, err := provider.RewriteMessage(body, blocked)
+	if err != nil {
+		return ContinueResult()
 	}
+	res.Body = io.NopCloser(bytes.NewReader(rewritten))
+	res.ContentLength = int64(len(rewritten))
+	res.Header.Set("Content-Length", strconv.Itoa(len(rewritten)))
 
 	return Result{
 		Action:           ActionContinue,
@@ -415,227 +876,192 @@ func (h *PolicyHandler) HandleResponse(ctx *HandlerContext, res *http.Response)
 	}
 }
 
-// SSE event types we care about
-type sseContentBlockStart struct {
-	Type         string `json:"type"`
-	Index        int    `json:"index"`
-	ContentBlock struct {
-		Type  string `json:"type"`
-		ID    string `json:"id"`
-		Name  string `json:"name"`
-		Input any    `json:"input"`
-	} `json:"content_block"`
-}
-
-// processSSEStream parses SSE events and replaces blocked tool_use blocks with error text.
-// For tools with conditional policies, we buffer events until we have the full input.
-func (h *PolicyHandler) processSSEStream(ctx *HandlerContext, data []byte) ([]byte, bool) {
-	if len(h.denyList) == 0 && len(h.globPatterns) == 0 && len(h.conditionalPolicies) == 0 {
-		return data, false
-	}
-
-	var output bytes.Buffer
-	scanner := bufio.NewScanner(bytes.NewReader(data))
-	scanner.Buffer(make([]byte, 1024*1024), 1024*1024) // 1MB buffer for large events
-
-	var currentEvent string
-	var currentData string
-	var blockedIndices = make(map[int]string)       // index -> reason (unconditionally blocked)
-	var blockedBlocks = make(map[int]*pendingBlock) // index -> blocked block (for capturing input for logging)
-	var pendingBlocks = make(map[int]*pendingBlock) // index -> pending block (needs condition evaluation)
-	wasModified := false
-
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		if strings.HasPrefix(line, "event: ") {
-			currentEvent = strings.TrimPrefix(line, "event: ")
-			currentData = ""
-		} else if strings.HasPrefix(line, "data: ") {
-			currentData = strings.TrimPrefix(line, "data: ")
-		} else if line == "" && currentEvent != "" {
-			// End of SSE event - process it
-			shouldWrite := true
-
-			switch currentEvent {
-			case "content_block_start":
-				var block sseContentBlockStart
-				if err := json.Unmarshal([]byte(currentData), &block); err == nil {
-					if block.ContentBlock.Type == "tool_use" {
-						toolName := block.ContentBlock.Name
-						toolID := block.ContentBlock.ID
-
-						// Check unconditional block first
-						if reason, blocked := h.isBlocked(toolName); blocked {
-							blockedIndices[block.Index] = reason
-							wasModified = true
-							h.writeBlockedEvent(&output, block.Index, toolName, reason)
-							// Buffer for logging with full input at content_block_stop
-							blockedBlocks[block.Index] = &pendingBlock{
-								index:    block.Index,
-								toolName: toolName,
-								toolID:   toolID,
-							}
-							shouldWrite = false
-						} else if h.hasConditionalPolicies(toolName) {
-							// Tool has conditional policies - buffer for evaluation
-							pendingBlocks[block.Index] = &pendingBlock{
-								index:      block.Index,
-								toolName:   toolName,
-								toolID:     toolID,
-								startEvent: currentEvent,
-								startData:  currentData,
-							}
-							shouldWrite = false // Buffer, don't write yet
-						}
-					}
-				}
+// hasPolicies reports whether any deny, audit, require_approval, rate_limit or path rules are loaded.
+func (h *PolicyHandler) hasPolicies() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.denyList) > 0 || h.globPatterns.len() > 0 || len(h.conditionalPolicies) > 0 ||
+		len(h.auditPolicies) > 0 || len(h.approvalPolicies) > 0 || len(h.rateLimits) > 0 ||
+		len(h.pathPolicies) > 0
+}
 
-			case "content_block_delta":
-				var delta struct {
-					Index int `json:"index"`
-					Delta struct {
-						Type        string `json:"type"`
-						PartialJSON string `json:"partial_json"`
-					} `json:"delta"`
-				}
-				if err := json.Unmarshal([]byte(currentData), &delta); err == nil {
-					if blocked, ok := blockedBlocks[delta.Index]; ok {
-						// Capture input for blocked tools (for logging)
-						if delta.Delta.Type == "input_json_delta" {
-							blocked.inputJSON.WriteString(delta.Delta.PartialJSON)
-						}
-						shouldWrite = false // Skip deltas for blocked tools
-					} else if pending, ok := pendingBlocks[delta.Index]; ok {
-						// Buffer this delta for later evaluation
-						pending.deltaEvents = append(pending.deltaEvents,
-							"event: "+currentEvent+"\ndata: "+currentData+"\n\n")
-						// Accumulate input JSON from input_json_delta events
-						if delta.Delta.Type == "input_json_delta" {
-							pending.inputJSON.WriteString(delta.Delta.PartialJSON)
-						}
-						shouldWrite = false
-					}
-				}
+// policyStreamProcessor applies a PolicyHandler's rules to one SSE response.
+type policyStreamProcessor struct {
+	h             *PolicyHandler
+	ctx           *HandlerContext
+	provider      Provider
+	parser        StreamParser
+	blockedBlocks map[int]*pendingBlock // index -> blocked block (for capturing input for logging)
+	pendingBlocks map[int]*pendingBlock // index -> pending block (needs condition evaluation)
+	auditBlocks   map[int]*pendingBlock // index -> allowed block with audit policies (input captured, events not held)
+	blocked       map[int]string        // index -> message shown in place of every blocked tool call
+	allowed       int                   // tool calls let through
+	modified      bool
+}
 
-			case "content_block_stop":
-				var stop struct {
-					Index int `json:"index"`
+// newStreamProcessor creates a processor for a single response stream.
+func (h *PolicyHandler) newStreamProcessor(ctx *HandlerContext, provider Provider) *policyStreamProcessor {
+	return &policyStreamProcessor{
+		h:             h,
+		ctx:           ctx,
+		provider:      provider,
+		parser:        provider.NewStreamParser(),
+		blockedBlocks: make(map[int]*pendingBlock),
+		pendingBlocks: make(map[int]*pendingBlock),
+		auditBlocks:   make(map[int]*pendingBlock),
+		blocked:       make(map[int]string),
+	}
+}
+
+// ProcessEvent replaces blocked tool calls with error text.
+// Parts of the event that belong to blocked or held tool calls are taken out;
+// whatever remains is forwarded after any replacement or released events.
+func (p *policyStreamProcessor) ProcessEvent(ev SSEEvent) []SSEEvent {
+	var out []SSEEvent
+	current := &ev
+
+	for _, te := range p.parser.Parse(ev) {
+		switch te.Type {
+		case ToolStart:
+			// Check unconditional block first. Calls an allow policy may exempt
+			// are held instead: their input decides
+			_, blocked := p.h.isBlocked(te.ToolName)
+			if blocked && !p.h.hasAllowPolicies(te.ToolName) {
+				policy, _ := p.h.matchDenyPolicy(te.ToolName, "", true)
+				// Keep the block around to log it with full input once complete
+				p.blockedBlocks[te.Index] = &pendingBlock{
+					index:    te.Index,
+					toolName: te.ToolName,
+					toolID:   te.ToolID,
+					policy:   policy,
+				}
+				out = append(out, p.block(te.Index, te.ToolName, policy.Reason)...)
+				current = p.take(current, te.Index, nil)
+			} else if blocked || p.h.hasConditionalPolicies(te.ToolName) || p.h.hasRateLimits(te.ToolName) || p.h.hasApprovalPolicies(te.ToolName) {
+				// Tool has deny, conditional, rate limit or approval policies - hold until the input is complete
+				pending := &pendingBlock{
+					index:    te.Index,
+					toolName: te.ToolName,
+					toolID:   te.ToolID,
 				}
-				if err := json.Unmarshal([]byte(currentData), &stop); err == nil {
-					if blocked, ok := blockedBlocks[stop.Index]; ok {
-						// Log blocked tool with captured input
-						reason := blockedIndices[stop.Index]
-						input := blocked.inputJSON.String()
-						var toolInput map[string]interface{}
-						_ = json.Unmarshal([]byte(input), &toolInput)
-						h.logBlockedTool(ctx, blocked.toolName, blocked.toolID, reason, toolInput)
-						delete(blockedBlocks, stop.Index)
-						shouldWrite = false // Skip stop for blocked tools (we already wrote it)
-					} else if pending, ok := pendingBlocks[stop.Index]; ok {
-						// Evaluate conditions against accumulated input
-						input := pending.inputJSON.String()
-						if reason, blocked := h.evaluateConditions(pending.toolName, input); blocked {
-							// Condition matched - block this tool
-							wasModified = true
-							h.writeBlockedEvent(&output, pending.index, pending.toolName, reason)
-							// Log blocked tool with parsed input
-							var toolInput map[string]interface{}
-							_ = json.Unmarshal([]byte(input), &toolInput)
-							h.logBlockedTool(ctx, pending.toolName, pending.toolID, reason, toolInput)
-						} else {
-							// No conditions matched - flush buffered events
-							_, _ = output.WriteString("event: ")
-							_, _ = output.WriteString(pending.startEvent)
-							_, _ = output.WriteString("\n")
-							_, _ = output.WriteString("data: ")
-							_, _ = output.WriteString(pending.startData)
-							output.WriteString("\n\n")
-							for _, event := range pending.deltaEvents {
-								output.WriteString(event)
-							}
-							// Write the stop event
-							output.WriteString("event: ")
-							output.WriteString(currentEvent)
-							output.WriteString("\n")
-							output.WriteString("data: ")
-							output.WriteString(currentData)
-							output.WriteString("\n\n")
-						}
-						delete(pendingBlocks, stop.Index)
-						shouldWrite = false
-					}
+				p.pendingBlocks[te.Index] = pending
+				current = p.take(current, te.Index, &pending.held)
+			} else if p.h.hasAuditPolicies(te.ToolName) {
+				// Allowed, but the complete input is needed to audit it
+				p.auditBlocks[te.Index] = &pendingBlock{
+					index:    te.Index,
+					toolName: te.ToolName,
+					toolID:   te.ToolID,
 				}
 			}
 
-			if shouldWrite {
-				output.WriteString("event: ")
-				output.WriteString(currentEvent)
-				output.WriteString("\n")
-				output.WriteString("data: ")
-				output.WriteString(currentData)
-				output.WriteString("\n\n")
+		case ToolInput:
+			if blocked, ok := p.blockedBlocks[te.Index]; ok {
+				// Capture input for blocked tools (for logging)
+				blocked.inputJSON.WriteString(te.Input)
+				current = p.take(current, te.Index, nil)
+			} else if pending, ok := p.pendingBlocks[te.Index]; ok {
+				pending.inputJSON.WriteString(te.Input)
+				current = p.take(current, te.Index, &pending.held)
+			} else if audited, ok := p.auditBlocks[te.Index]; ok {
+				audited.inputJSON.WriteString(te.Input)
 			}
 
-			currentEvent = ""
-			currentData = ""
+		case ToolStop:
+			if blocked, ok := p.blockedBlocks[te.Index]; ok {
+				// Replacement was already emitted
+				delete(p.blockedBlocks, te.Index)
+				p.h.logBlockedTool(p.ctx, p.provider, blocked.toolName, blocked.toolID, blocked.policy.Reason, blocked.policy, parseToolInput(blocked.inputJSON.String()))
+				current = p.take(current, te.Index, nil)
+			} else if pending, ok := p.pendingBlocks[te.Index]; ok {
+				delete(p.pendingBlocks, te.Index)
+				input := pending.inputJSON.String()
+				policy, blocked := p.h.matchDenyPolicy(pending.toolName, input, true)
+				reason := policy.Reason
+				if !blocked {
+					reason, blocked = p.h.checkRateLimits(p.ctx, p.provider, pending.toolName, pending.toolID, input)
+				}
+				if !blocked {
+					// Waits here for a decision when approval is required
+					reason, blocked = p.h.awaitApproval(p.ctx, p.provider, pending.toolName, pending.toolID, input)
+				}
+				if blocked {
+					p.h.logBlockedTool(p.ctx, p.provider, pending.toolName, pending.toolID, reason, policy, parseToolInput(input))
+					out = append(out, p.block(pending.index, pending.toolName, reason)...)
+					current = p.take(current, te.Index, nil)
+				} else {
+					// No conditions matched and approved if needed - release the held events
+					p.allowed++
+					p.h.logOverrides(p.ctx, p.provider, pending.toolName, pending.toolID, input)
+					p.h.auditToolCall(p.ctx, p.provider, pending.toolName, pending.toolID, input)
+					out = append(out, pending.held...)
+					current = p.take(current, te.Index, &out)
+				}
+			} else {
+				p.allowed++
+				if audited, ok := p.auditBlocks[te.Index]; ok {
+					delete(p.auditBlocks, te.Index)
+					p.h.auditToolCall(p.ctx, p.provider, audited.toolName, audited.toolID, audited.inputJSON.String())
+				}
+			}
 		}
 	}
 
-	return output.Bytes(), wasModified
+	if current != nil {
+		if len(p.blocked) > 0 {
+			rewritten := p.parser.Rewrite(*current, p.blocked, p.allowed)
+			current = &rewritten
+		}
+		out = append(out, *current)
+	}
+	return out
 }
 
-// writeBlockedEvent writes SSE events for a blocked tool (text block with error message).
-func (h *PolicyHandler) writeBlockedEvent(w *bytes.Buffer, index int, toolName, reason string) {
-	errorText := h.formatBlockError(toolName, reason)
+// block records a blocked tool call and returns its replacement events.
+func (p *policyStreamProcessor) block(index int, toolName, reason string) []SSEEvent {
+	message := p.h.formatBlockError(toolName, reason)
+	p.blocked[index] = message
+	p.modified = true
+	return p.parser.BlockedEvents(index, message)
+}
 
-	// Write content_block_start for text
-	startData := map[string]any{
-		"type":  "content_block_start",
-		"index": index,
-		"content_block": map[string]any{
-			"type": "text",
-			"text": "",
-		},
+// take removes tool call index's part from current, appending it to into
+// (or dropping it when into is nil), and returns what is left of current.
+func (p *policyStreamProcessor) take(current *SSEEvent, index int, into *[]SSEEvent) *SSEEvent {
+	if current == nil {
+		return nil
 	}
-	startJSON, _ := json.Marshal(startData)
-	w.WriteString("event: content_block_start\n")
-	w.WriteString("data: ")
-	w.Write(startJSON)
-	w.WriteString("\n\n")
-
-	// Write content_block_delta with error text
-	deltaData := map[string]any{
-		"type":  "content_block_delta",
-		"index": index,
-		"delta": map[string]any{
-			"type": "text_delta",
-			"text": errorText,
-		},
+	tool, rest := p.parser.Extract(*current, index)
+	if tool != nil && into != nil {
+		*into = append(*into, *tool)
 	}
-	deltaJSON, _ := json.Marshal(deltaData)
-	w.WriteString("event: content_block_delta\n")
-	w.WriteString("data: ")
-	w.Write(deltaJSON)
-	w.WriteString("\n\n")
+	return rest
+}
 
-	// Write content_block_stop
-	stopData := map[string]any{
-		"type":  "content_block_stop",
-		"index": index,
-	}
-	stopJSON, _ := json.Marshal(stopData)
-	w.WriteString("event: content_block_stop\n")
-	w.WriteString("data: ")
-	w.Write(stopJSON)
-	w.WriteString("\n\n")
+// Finish drops tool blocks that never completed. Their input was not fully
+// evaluated, so they are not released to the client.
+func (p *policyStreamProcessor) Finish(err error) []SSEEvent {
+	p.pendingBlocks = make(map[int]*pendingBlock)
+	p.blockedBlocks = make(map[int]*pendingBlock)
+	p.auditBlocks = make(map[int]*pendingBlock)
+	return nil
 }
 
+// parseToolInput parses accumulated tool input JSON, returning nil if invalid.
+func parseToolInput(input string) map[string]interface{} {
+	var toolInput map[string]interface{}
+	_ = json.Unmarshal([]byte(input), &toolInput)
+	return toolInput
+}
+
+// policyHint is appended to every policy block message shown to the agent.
+const policyHint = "This restriction is set by your company administrator.\n" +
+	"To see all tool restrictions, run: arfa policies list"
+
 // formatBlockError creates the user-friendly error message.
 func (h *PolicyHandler) formatBlockError(toolName, reason string) string {
 	return "\n\n[TOOL BLOCKED BY ORGANIZATION POLICY]\n\n" +
 		"Tool: " + toolName + "\n" +
 		"Reason: " + reason + "\n\n" +
-		"This restriction is set by your company administrator.\n" +
-		"To see all tool restrictions, run: arfa policies list\n\n"
+		policyHint + "\n\n"
 }
@@ -0,0 +1,82 @@
+package control
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rastrigin-systems/arfa/pkg/policy"
+	"github.com/rastrigin-systems/arfa/services/cli/internal/api"
+)
+
+// benchmarkPolicies returns n deny policies of each kind: exact tool names,
+// prefixes ending in %, and regex conditions on Bash.
+func benchmarkPolicies(n int) []api.ToolPolicy {
+	policies := make([]api.ToolPolicy, 0, 3*n)
+	for i := 0; i < n; i++ {
+		policies = append(policies,
+			api.ToolPolicy{ToolName: fmt.Sprintf("Tool%d", i), Action: api.ToolPolicyActionDeny},
+			api.ToolPolicy{ToolName: fmt.Sprintf("mcp__server%d__%%", i), Action: api.ToolPolicyActionDeny},
+			api.ToolPolicy{
+				ToolName:   "Bash",
+				Action:     api.ToolPolicyActionDeny,
+				Conditions: map[string]interface{}{"command": fmt.Sprintf(`^deploy-%d\s+--prod`, i)},
+			},
+		)
+	}
+	return policies
+}
+
+func BenchmarkIsBlocked(b *testing.B) {
+	for _, n := range []int{10, 1000, 10000} {
+		h := NewPolicyHandlerWithPolicies(benchmarkPolicies(n))
+
+		b.Run(fmt.Sprintf("prefix/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				h.isBlocked("mcp__server7__query")
+			}
+		})
+		b.Run(fmt.Sprintf("miss/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				h.isBlocked("mcp__github__create_pull_request")
+			}
+		})
+	}
+}
+
+func BenchmarkEvaluateConditions(b *testing.B) {
+	input := `{"command":"git status && go test ./...","description":"Run tests"}`
+
+	for _, n := range []int{10, 100, 1000} {
+		h := NewPolicyHandlerWithPolicies(benchmarkPolicies(n))
+
+		b.Run(fmt.Sprintf("regex/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				h.evaluateConditions("Bash", input)
+			}
+		})
+	}
+}
+
+func BenchmarkConditions(b *testing.B) {
+	conditions := map[string]interface{}{
+		"any": []interface{}{
+			map[string]interface{}{"param_path": "command", "operator": "matches", "value": `rm\s+-rf\s+/`},
+			map[string]interface{}{"param_path": "command", "operator": "shell", "value": map[string]interface{}{
+				"command": "git", "args": []interface{}{"push"}, "flags": []interface{}{"--force|-f"},
+			}},
+		},
+	}
+	input := map[string]interface{}{"command": "cd repo && git push origin main"}
+
+	b.Run("compiled", func(b *testing.B) {
+		cond := policy.CompileOrNever(conditions)
+		for i := 0; i < b.N; i++ {
+			cond.Match(input)
+		}
+	})
+	b.Run("uncompiled", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			policy.Matches(input, conditions)
+		}
+	})
+}
@@ -2,11 +2,15 @@ package control
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/rastrigin-systems/arfa/pkg/policy"
 	"github.com/rastrigin-systems/arfa/services/cli/internal/api"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -50,6 +54,101 @@ func TestPolicyHandler_HandleResponse_NonSSE(t *testing.T) {
 	// Non-SSE responses are not modified
 }
 
+// jsonResponse builds a non-streaming response to a request for url.
+func jsonResponse(url, body string) *http.Response {
+	req, _ := http.NewRequest("POST", url, nil)
+	return &http.Response{
+		StatusCode:    200,
+		Request:       req,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		ContentLength: int64(len(body)),
+		Body:          io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestPolicyHandler_HandleResponse_JSONBlockedTool(t *testing.T) {
+	queue := &mockToolLoggerQueue{}
+	h := NewPolicyHandlerWithDenyList(map[string]string{"Bash": "Shell commands are blocked"})
+	h.SetQueue(queue)
+
+	body := `{"id":"msg_1","type":"message","role":"assistant","content":[` +
+		`{"type":"text","text":"Let me check."},` +
+		`{"type":"tool_use","id":"toolu_1","name":"Bash","input":{"command":"ls"}}],` +
+		`"stop_reason":"tool_use"}`
+	res := jsonResponse("https://api.anthropic.com/v1/messages", body)
+
+	result := h.HandleResponse(NewHandlerContext("emp-1", "org-1", "sess-1"), res)
+	require.NotNil(t, result.ModifiedResponse)
+
+	output := drainBody(t, result.ModifiedResponse)
+	assert.Equal(t, int64(len(output)), result.ModifiedResponse.ContentLength)
+	assert.Equal(t, strconv.Itoa(len(output)), result.ModifiedResponse.Header.Get("Content-Length"))
+
+	var msg struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		StopReason string `json:"stop_reason"`
+	}
+	require.NoError(t, json.Unmarshal(output, &msg))
+	require.Len(t, msg.Content, 2)
+	assert.Equal(t, "Let me check.", msg.Content[0].Text)
+	assert.Equal(t, "text", msg.Content[1].Type)
+	assert.Contains(t, msg.Content[1].Text, "Shell commands are blocked")
+	assert.Equal(t, "end_turn", msg.StopReason)
+
+	entries := queue.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "Bash", entries[0].Payload["tool_name"])
+	assert.Equal(t, "toolu_1", entries[0].Payload["tool_id"])
+	assert.Equal(t, map[string]interface{}{"command": "ls"}, entries[0].Payload["tool_input"])
+}
+
+func TestPolicyHandler_HandleResponse_JSONConditionalPolicy(t *testing.T) {
+	reason := "Dangerous command"
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{{
+		ToolName:   "Bash",
+		Action:     api.ToolPolicyActionDeny,
+		Reason:     &reason,
+		Conditions: map[string]interface{}{"command": `rm\s+-rf`},
+	}})
+	ctx := NewHandlerContext("emp-1", "org-1", "sess-1")
+
+	// Two calls: only the one matching the condition is blocked, so the
+	// message still ends with tool_use
+	body := `{"type":"message","content":[` +
+		`{"type":"tool_use","id":"toolu_1","name":"Bash","input":{"command":"rm -rf /"}},` +
+		`{"type":"tool_use","id":"toolu_2","name":"Bash","input":{"command":"ls"}}],` +
+		`"stop_reason":"tool_use"}`
+	result := h.HandleResponse(ctx, jsonResponse("https://api.anthropic.com/v1/messages", body))
+	require.NotNil(t, result.ModifiedResponse)
+
+	output := string(drainBody(t, result.ModifiedResponse))
+	assert.Contains(t, output, "Dangerous command")
+	assert.Contains(t, output, `"id":"toolu_2"`)
+	assert.NotContains(t, output, `"id":"toolu_1"`)
+	assert.Contains(t, output, `"stop_reason":"tool_use"`)
+
+	// A safe command passes through untouched
+	safe := `{"type":"message","content":[{"type":"tool_use","id":"toolu_3","name":"Bash","input":{"command":"ls"}}],"stop_reason":"tool_use"}`
+	res := jsonResponse("https://api.anthropic.com/v1/messages", safe)
+	result = h.HandleResponse(ctx, res)
+	assert.Nil(t, result.ModifiedResponse)
+	assert.Equal(t, safe, string(drainBody(t, res)))
+}
+
+func TestPolicyHandler_HandleResponse_JSONOtherEndpoint(t *testing.T) {
+	h := NewPolicyHandlerWithDenyList(map[string]string{"Bash": "blocked"})
+
+	body := `{"input_tokens":42}`
+	res := jsonResponse("https://api.anthropic.com/v1/models", body)
+	result := h.HandleResponse(NewHandlerContext("emp-1", "org-1", "sess-1"), res)
+
+	assert.Nil(t, result.ModifiedResponse)
+	assert.Equal(t, body, string(drainBody(t, res)))
+}
+
 func TestPolicyHandler_HandleResponse_AllowedTool(t *testing.T) {
 	// Block Bash, but allow Read
 	h := NewPolicyHandlerWithDenyList(map[string]string{"Bash": "blocked"})
@@ -234,8 +333,25 @@ data: {"type":"content_block_stop","index":0}
 	assert.Contains(t, string(modifiedBody), `"type":"tool_use"`)
 }
 
-func TestProcessSSEStream_NoBlocking(t *testing.T) {
-	h := &PolicyHandler{denyList: map[string]string{}, globPatterns: map[string]string{}}
+// streamThrough runs an Anthropic SSE body through the handler's stream
+// processor, attached to the response as the proxy attaches it, and returns
+// the body the agent receives and whether the processor rewrote it.
+func streamThrough(t *testing.T, h *PolicyHandler, ctx *HandlerContext, data []byte) ([]byte, bool) {
+	t.Helper()
+	res := &http.Response{
+		Header: http.Header{"Content-Type": []string{"text/event-stream"}},
+		Body:   io.NopCloser(bytes.NewReader(data)),
+	}
+	processor := h.newStreamProcessor(ctx, anthropicProvider{})
+	AttachSSEProcessor(res, processor)
+
+	output, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return output, processor.modified
+}
+
+func TestPolicyStream_NoBlocking(t *testing.T) {
+	h := &PolicyHandler{denyList: map[string]string{}}
 
 	input := []byte(`event: test
 data: {"foo":"bar"}
@@ -243,14 +359,14 @@ data: {"foo":"bar"}
 `)
 
 	ctx := &HandlerContext{EmployeeID: "emp-1", OrgID: "org-1", SessionID: "sess-1", ClientName: "claude-code", ClientVersion: "1.0.25"}
-	output, modified := h.processSSEStream(ctx, input)
+	output, modified := streamThrough(t, h, ctx, input)
 
 	assert.False(t, modified)
 	assert.Equal(t, input, output)
 }
 
-func TestProcessSSEStream_BlockBash(t *testing.T) {
-	h := &PolicyHandler{denyList: map[string]string{"Bash": "no shell"}, globPatterns: map[string]string{}}
+func TestPolicyStream_BlockBash(t *testing.T) {
+	h := &PolicyHandler{denyList: map[string]string{"Bash": "no shell"}}
 
 	input := []byte(`event: content_block_start
 data: {"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"t1","name":"Bash","input":{}}}
@@ -264,7 +380,7 @@ data: {"type":"content_block_stop","index":0}
 `)
 
 	ctx := &HandlerContext{EmployeeID: "emp-1", OrgID: "org-1", SessionID: "sess-1", ClientName: "claude-code", ClientVersion: "1.0.25"}
-	output, modified := h.processSSEStream(ctx, input)
+	output, modified := streamThrough(t, h, ctx, input)
 
 	assert.True(t, modified)
 
@@ -286,11 +402,14 @@ func TestFormatBlockError(t *testing.T) {
 	assert.Contains(t, msg, "arfa policies list")
 }
 
-func TestWriteBlockedEvent(t *testing.T) {
+func TestAnthropicStreamParser_BlockedEvents(t *testing.T) {
 	h := &PolicyHandler{}
 	var buf bytes.Buffer
 
-	h.writeBlockedEvent(&buf, 5, "Bash", "blocked")
+	parser := anthropicProvider{}.NewStreamParser()
+	for _, ev := range parser.BlockedEvents(5, h.formatBlockError("Bash", "blocked")) {
+		ev.writeTo(&buf)
+	}
 
 	output := buf.String()
 
@@ -371,6 +490,319 @@ func TestPolicyHandler_SkipsAuditPolicies(t *testing.T) {
 	assert.False(t, blocked)
 }
 
+// auditedBashStream is a response with a single Bash call running command.
+func auditedBashStream(command string) string {
+	input, _ := json.Marshal(map[string]string{"command": command})
+	delta, _ := json.Marshal(string(input))
+	return `event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"Bash","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":` + string(delta) + `}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"tool_use"}}
+
+`
+}
+
+func TestPolicyHandler_AuditPolicy_LogsViolation(t *testing.T) {
+	reason := "Trialling a terraform rule"
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{{
+		ID:         "pol-1",
+		ToolName:   "Bash",
+		Action:     api.ToolPolicyActionAudit,
+		Reason:     &reason,
+		Conditions: map[string]interface{}{"command": `terraform\s+apply`},
+	}})
+	queue := &mockToolLoggerQueue{}
+	h.SetQueue(queue)
+	ctx := NewHandlerContext("emp-1", "org-1", "sess-1")
+
+	stream := auditedBashStream("terraform apply -auto-approve")
+	output, modified := streamThrough(t, h, ctx, []byte(stream))
+
+	// The call goes through untouched
+	assert.False(t, modified)
+	assert.Equal(t, stream, string(output))
+
+	entries := queue.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "policy_violation", entries[0].EventType)
+	assert.Equal(t, "pol-1", entries[0].Payload["policy_id"])
+	assert.Equal(t, "audit", entries[0].Payload["action"])
+	assert.Equal(t, "Bash", entries[0].Payload["tool_name"])
+	assert.Equal(t, "toolu_1", entries[0].Payload["tool_id"])
+	assert.Equal(t, false, entries[0].Payload["blocked"])
+	assert.Equal(t, reason, entries[0].Payload["reason"])
+	assert.Equal(t, map[string]interface{}{"command": "terraform apply -auto-approve"}, entries[0].Payload["tool_input"])
+	assert.Equal(t, map[string]interface{}{"command": `terraform\s+apply`}, entries[0].Payload["matched_condition"])
+
+	// A command that doesn't match the condition isn't logged
+	_, _ = streamThrough(t, h, ctx, []byte(auditedBashStream("terraform plan")))
+	assert.Len(t, queue.Entries(), 1)
+}
+
+func TestPolicyHandler_AuditPolicy_GlobAndDeny(t *testing.T) {
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{
+		{ID: "pol-audit", ToolName: "ba%", Action: api.ToolPolicyActionAudit},
+		{ID: "pol-deny", ToolName: "Bash", Action: api.ToolPolicyActionDeny, Conditions: map[string]interface{}{"command": "^rm"}},
+	})
+	queue := &mockToolLoggerQueue{}
+	h.SetQueue(queue)
+	ctx := NewHandlerContext("emp-1", "org-1", "sess-1")
+
+	// Allowed by the conditional deny policy, so audited
+	_, modified := streamThrough(t, h, ctx, []byte(auditedBashStream("ls")))
+	assert.False(t, modified)
+	entries := queue.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "policy_violation", entries[0].EventType)
+	assert.Equal(t, "pol-audit", entries[0].Payload["policy_id"])
+	assert.NotContains(t, entries[0].Payload, "matched_condition")
+
+	// Denied calls are logged as blocked, not as audit violations
+	_, modified = streamThrough(t, h, ctx, []byte(auditedBashStream("rm -rf /")))
+	assert.True(t, modified)
+	entries = queue.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "tool_call", entries[1].EventType)
+	assert.Equal(t, true, entries[1].Payload["blocked"])
+}
+
+func TestPolicyHandler_AuditPolicy_JSONResponse(t *testing.T) {
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{{ID: "pol-1", ToolName: "Write", Action: api.ToolPolicyActionAudit}})
+	queue := &mockToolLoggerQueue{}
+	h.SetQueue(queue)
+
+	body := `{"type":"message","content":[{"type":"tool_use","id":"toolu_1","name":"Write","input":{"file_path":"a.txt"}}],"stop_reason":"tool_use"}`
+	res := jsonResponse("https://api.anthropic.com/v1/messages", body)
+	result := h.HandleResponse(NewHandlerContext("emp-1", "org-1", "sess-1"), res)
+
+	assert.Nil(t, result.ModifiedResponse)
+	assert.Equal(t, body, string(drainBody(t, res)))
+
+	entries := queue.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "policy_violation", entries[0].EventType)
+	assert.Equal(t, map[string]interface{}{"file_path": "a.txt"}, entries[0].Payload["tool_input"])
+}
+
+// approvingHandler returns a handler with a require_approval policy on Bash
+// whose approvals are answered with decide as soon as they are requested.
+func approvingHandler(t *testing.T, timeout time.Duration, decide func(ApprovalRequest) *ApprovalDecision) (*PolicyHandler, *mockToolLoggerQueue) {
+	t.Helper()
+	reason := "Shell commands need a second pair of eyes"
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{{
+		ID:         "pol-1",
+		ToolName:   "Bash",
+		Action:     api.ToolPolicyActionRequireApproval,
+		Reason:     &reason,
+		Conditions: map[string]interface{}{"command": "^kubectl"},
+	}})
+	queue := &mockToolLoggerQueue{}
+	h.SetQueue(queue)
+
+	approvals := NewApprovals(timeout)
+	approvals.OnRequest(func(req ApprovalRequest) {
+		if decision := decide(req); decision != nil {
+			decision.ApprovalID = req.ID
+			require.NoError(t, approvals.Decide(*decision))
+		}
+	})
+	h.SetApprovals(approvals)
+	return h, queue
+}
+
+func TestPolicyHandler_RequireApproval_Approved(t *testing.T) {
+	var requested []ApprovalRequest
+	h, queue := approvingHandler(t, time.Minute, func(req ApprovalRequest) *ApprovalDecision {
+		requested = append(requested, req)
+		return &ApprovalDecision{Approved: true, Source: ApprovalSourceLocal}
+	})
+	ctx := NewHandlerContext("emp-1", "org-1", "sess-1")
+
+	stream := auditedBashStream("kubectl get pods")
+	output, modified := streamThrough(t, h, ctx, []byte(stream))
+
+	// Held until approved, then released unchanged
+	assert.False(t, modified)
+	assert.Equal(t, stream, string(output))
+
+	require.Len(t, requested, 1)
+	assert.Equal(t, "pol-1", requested[0].PolicyID)
+	assert.Equal(t, "sess-1", requested[0].SessionID)
+	assert.Equal(t, map[string]interface{}{"command": "kubectl get pods"}, requested[0].ToolInput)
+
+	entries := queue.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "tool_approval", entries[0].EventType)
+	assert.Equal(t, requested[0].ID, entries[0].Payload["approval_id"])
+	assert.Equal(t, "pol-1", entries[0].Payload["policy_id"])
+	assert.Equal(t, "toolu_1", entries[0].Payload["tool_id"])
+	assert.Equal(t, true, entries[0].Payload["approved"])
+	assert.Equal(t, ApprovalSourceLocal, entries[0].Payload["decision_source"])
+
+	// Calls the policy doesn't match don't wait for approval
+	_, _ = streamThrough(t, h, ctx, []byte(auditedBashStream("ls")))
+	assert.Len(t, requested, 1)
+}
+
+func TestPolicyHandler_RequireApproval_Denied(t *testing.T) {
+	h, queue := approvingHandler(t, time.Minute, func(ApprovalRequest) *ApprovalDecision {
+		return &ApprovalDecision{Approved: false, DecidedBy: "admin-1", Reason: "Not on prod", Source: ApprovalSourceRemote}
+	})
+
+	output, modified := streamThrough(t, h, NewHandlerContext("emp-1", "org-1", "sess-1"), []byte(auditedBashStream("kubectl delete ns prod")))
+
+	assert.True(t, modified)
+	assert.Contains(t, string(output), "Approval denied by an administrator: Not on prod")
+	assert.NotContains(t, string(output), "kubectl delete")
+
+	entries := queue.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "tool_approval", entries[0].EventType)
+	assert.Equal(t, false, entries[0].Payload["approved"])
+	assert.Equal(t, "admin-1", entries[0].Payload["decided_by"])
+	assert.Equal(t, "Not on prod", entries[0].Payload["reason"])
+	assert.Equal(t, "tool_call", entries[1].EventType)
+	assert.Equal(t, true, entries[1].Payload["blocked"])
+}
+
+func TestPolicyHandler_RequireApproval_TimeoutDenies(t *testing.T) {
+	h, queue := approvingHandler(t, 10*time.Millisecond, func(ApprovalRequest) *ApprovalDecision {
+		return nil // Nobody answers
+	})
+
+	output, modified := streamThrough(t, h, NewHandlerContext("emp-1", "org-1", "sess-1"), []byte(auditedBashStream("kubectl apply -f x.yaml")))
+
+	assert.True(t, modified)
+	assert.Contains(t, string(output), "Approval request timed out")
+
+	entries := queue.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "tool_approval", entries[0].EventType)
+	assert.Equal(t, false, entries[0].Payload["approved"])
+	assert.Equal(t, ApprovalSourceTimeout, entries[0].Payload["decision_source"])
+}
+
+func TestPolicyHandler_RequireApproval_WithoutApprovalsDenies(t *testing.T) {
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{{ID: "pol-1", ToolName: "Bash", Action: api.ToolPolicyActionRequireApproval}})
+
+	output, modified := streamThrough(t, h, NewHandlerContext("emp-1", "org-1", "sess-1"), []byte(auditedBashStream("ls")))
+
+	assert.True(t, modified)
+	assert.Contains(t, string(output), "approvals are not available")
+}
+
+func TestPolicyHandler_RequireApproval_JSONResponse(t *testing.T) {
+	h, queue := approvingHandler(t, time.Minute, func(ApprovalRequest) *ApprovalDecision {
+		return &ApprovalDecision{Approved: false, Source: ApprovalSourceLocal}
+	})
+
+	body := `{"type":"message","content":[{"type":"tool_use","id":"toolu_1","name":"Bash","input":{"command":"kubectl drain node-1"}}],"stop_reason":"tool_use"}`
+	res := jsonResponse("https://api.anthropic.com/v1/messages", body)
+	result := h.HandleResponse(NewHandlerContext("emp-1", "org-1", "sess-1"), res)
+
+	require.NotNil(t, result.ModifiedResponse)
+	assert.Contains(t, string(drainBody(t, res)), "Approval denied")
+
+	entries := queue.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "tool_approval", entries[0].EventType)
+	assert.Equal(t, "tool_call", entries[1].EventType)
+}
+
+func TestPolicyHandler_RateLimit(t *testing.T) {
+	reason := "Slow down."
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{{
+		ID:       "pol-1",
+		ToolName: "Bash",
+		Action:   api.ToolPolicyActionRateLimit,
+		Reason:   &reason,
+		Conditions: map[string]interface{}{
+			"rate_limit": map[string]interface{}{"max_calls": 2, "window_seconds": 60},
+		},
+	}})
+	queue := &mockToolLoggerQueue{}
+	h.SetQueue(queue)
+	ctx := NewHandlerContext("emp-1", "org-1", "sess-1")
+
+	for i := 0; i < 2; i++ {
+		stream := auditedBashStream("ls")
+		output, modified := streamThrough(t, h, ctx, []byte(stream))
+		assert.False(t, modified)
+		assert.Equal(t, stream, string(output))
+	}
+
+	output, modified := streamThrough(t, h, ctx, []byte(auditedBashStream("ls")))
+	assert.True(t, modified)
+	assert.Contains(t, string(output), "Rate limit exceeded: at most 2 Bash calls per 1m0s per session")
+	assert.Contains(t, string(output), "Slow down.")
+
+	entries := queue.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "policy_violation", entries[0].EventType)
+	assert.Equal(t, "rate_limit", entries[0].Payload["action"])
+	assert.Equal(t, "pol-1", entries[0].Payload["policy_id"])
+	assert.Equal(t, true, entries[0].Payload["blocked"])
+	assert.Equal(t, 2, entries[0].Payload["max_calls"])
+	assert.Equal(t, "tool_call", entries[1].EventType)
+
+	// Each session has its own window
+	_, modified = streamThrough(t, h, NewHandlerContext("emp-1", "org-1", "sess-2"), []byte(auditedBashStream("ls")))
+	assert.False(t, modified)
+}
+
+func TestPolicyHandler_RateLimit_PerEmployeeWithConditions(t *testing.T) {
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{{
+		ID:       "pol-1",
+		ToolName: "Bash",
+		Action:   api.ToolPolicyActionRateLimit,
+		Conditions: map[string]interface{}{
+			"rate_limit": map[string]interface{}{"max_calls": 1, "window_seconds": 3600, "per": "employee"},
+			"command":    "^kubectl",
+		},
+	}})
+
+	_, modified := streamThrough(t, h, NewHandlerContext("emp-1", "org-1", "sess-1"), []byte(auditedBashStream("kubectl get pods")))
+	assert.False(t, modified)
+
+	// Calls the conditions don't match aren't counted
+	_, modified = streamThrough(t, h, NewHandlerContext("emp-1", "org-1", "sess-1"), []byte(auditedBashStream("ls")))
+	assert.False(t, modified)
+
+	// The window is shared by the employee's sessions
+	output, modified := streamThrough(t, h, NewHandlerContext("emp-1", "org-1", "sess-2"), []byte(auditedBashStream("kubectl get ns")))
+	assert.True(t, modified)
+	assert.Contains(t, string(output), "per employee")
+
+	_, modified = streamThrough(t, h, NewHandlerContext("emp-2", "org-1", "sess-3"), []byte(auditedBashStream("kubectl get ns")))
+	assert.False(t, modified)
+}
+
+func TestPolicyHandler_RateLimit_JSONResponse(t *testing.T) {
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{{
+		ID:         "pol-1",
+		ToolName:   "Write",
+		Action:     api.ToolPolicyActionRateLimit,
+		Conditions: map[string]interface{}{"rate_limit": map[string]interface{}{"max_calls": 1, "window_seconds": 60}},
+	}})
+	ctx := NewHandlerContext("emp-1", "org-1", "sess-1")
+	body := `{"type":"message","content":[{"type":"tool_use","id":"toolu_1","name":"Write","input":{"file_path":"a.txt"}}],"stop_reason":"tool_use"}`
+
+	result := h.HandleResponse(ctx, jsonResponse("https://api.anthropic.com/v1/messages", body))
+	assert.Nil(t, result.ModifiedResponse)
+
+	res := jsonResponse("https://api.anthropic.com/v1/messages", body)
+	result = h.HandleResponse(ctx, res)
+	require.NotNil(t, result.ModifiedResponse)
+	assert.Contains(t, string(drainBody(t, res)), "Rate limit exceeded")
+}
+
 func TestPolicyHandler_CaseInsensitive(t *testing.T) {
 	// Create handler with a policy
 	reason := "Shell blocked"
@@ -541,7 +973,7 @@ data: {"type":"message_stop"}
 `
 
 	ctx := &HandlerContext{EmployeeID: "emp-1", OrgID: "org-1", SessionID: "sess-1", ClientName: "claude-code", ClientVersion: "1.0.25"}
-	output, modified := h.processSSEStream(ctx, []byte(sseStream))
+	output, modified := streamThrough(t, h, ctx, []byte(sseStream))
 
 	assert.True(t, modified, "Stream should be modified when condition matches")
 	outputStr := string(output)
@@ -588,7 +1020,7 @@ data: {"type":"message_stop"}
 `
 
 	ctx := &HandlerContext{EmployeeID: "emp-1", OrgID: "org-1", SessionID: "sess-1", ClientName: "claude-code", ClientVersion: "1.0.25"}
-	output, modified := h.processSSEStream(ctx, []byte(sseStream))
+	output, modified := streamThrough(t, h, ctx, []byte(sseStream))
 
 	assert.False(t, modified, "Stream should not be modified when condition doesn't match")
 	outputStr := string(output)
@@ -630,27 +1062,63 @@ func TestPolicyHandler_MixedUnconditionalAndConditional(t *testing.T) {
 	assert.False(t, h.hasConditionalPolicies("Write"))
 }
 
+func TestPolicyHandler_ConditionalPolicy_GlobPattern(t *testing.T) {
+	reason := "No changes to acme repositories"
+	policies := []api.ToolPolicy{
+		{
+			ToolName:   "mcp__github__%",
+			Action:     api.ToolPolicyActionDeny,
+			Reason:     &reason,
+			Conditions: map[string]interface{}{"repo": "^acme/"},
+		},
+	}
+	h := NewPolicyHandlerWithPolicies(policies)
+
+	assert.True(t, h.hasConditionalPolicies("MCP__GitHub__create_issue"))
+	assert.False(t, h.hasConditionalPolicies("mcp__gitlab__create_issue"))
+
+	actualReason, blocked := h.evaluateConditions("mcp__github__create_issue", `{"repo": "acme/api"}`)
+	assert.True(t, blocked)
+	assert.Equal(t, reason, actualReason)
+
+	_, blocked = h.evaluateConditions("mcp__github__create_issue", `{"repo": "other/api"}`)
+	assert.False(t, blocked)
+	_, blocked = h.evaluateConditions("mcp__gitlab__create_issue", `{"repo": "acme/api"}`)
+	assert.False(t, blocked)
+}
+
 func TestPolicyHandler_MatchesPattern_ValidRegex(t *testing.T) {
-	h := &PolicyHandler{}
+	matches := func(s, pattern string) bool {
+		cond, err := policy.Compile(map[string]interface{}{"command": pattern})
+		require.NoError(t, err)
+		return cond.Match(map[string]interface{}{"command": s})
+	}
 
 	// Simple patterns
-	assert.True(t, h.matchesPattern("rm -rf /", "rm\\s+-rf"))
-	assert.False(t, h.matchesPattern("rm-rf /", "rm\\s+-rf"))
+	assert.True(t, matches("rm -rf /", "rm\\s+-rf"))
+	assert.False(t, matches("rm-rf /", "rm\\s+-rf"))
 
 	// Complex patterns
-	assert.True(t, h.matchesPattern("/etc/passwd", ".*/passwd$"))
-	assert.False(t, h.matchesPattern("/etc/shadow", ".*/passwd$"))
+	assert.True(t, matches("/etc/passwd", ".*/passwd$"))
+	assert.False(t, matches("/etc/shadow", ".*/passwd$"))
 
 	// Case sensitivity
-	assert.True(t, h.matchesPattern("DELETE FROM", "DELETE"))
-	assert.False(t, h.matchesPattern("delete from", "DELETE"))
+	assert.True(t, matches("DELETE FROM", "DELETE"))
+	assert.False(t, matches("delete from", "DELETE"))
 }
 
 func TestPolicyHandler_MatchesPattern_InvalidRegex(t *testing.T) {
-	h := &PolicyHandler{}
-
-	// Invalid regex should not match (fail open)
-	assert.False(t, h.matchesPattern("anything", "[invalid(regex"))
+	_, err := policy.Compile(map[string]interface{}{"command": "[invalid(regex"})
+	assert.Error(t, err)
+
+	// A policy with an invalid regex should not match (fail open)
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{{
+		ToolName:   "Bash",
+		Action:     api.ToolPolicyActionDeny,
+		Conditions: map[string]interface{}{"command": "[invalid(regex"},
+	}})
+	_, blocked := h.evaluateConditions("Bash", `{"command":"anything"}`)
+	assert.False(t, blocked)
 }
 
 func TestPolicyHandler_EvaluateConditions_InvalidJSON(t *testing.T) {
@@ -723,7 +1191,7 @@ data: {"type":"message_stop"}
 `
 
 	ctx := &HandlerContext{EmployeeID: "emp-1", OrgID: "org-1", SessionID: "sess-1", ClientName: "claude-code", ClientVersion: "1.0.25"}
-	output, modified := h.processSSEStream(ctx, []byte(sseStream))
+	output, modified := streamThrough(t, h, ctx, []byte(sseStream))
 
 	assert.True(t, modified, "Stream should be modified when tool is blocked")
 
@@ -775,3 +1243,58 @@ func (q *mockLogQueue) Len() int {
 func (q *mockLogQueue) Close() error {
 	return nil
 }
+
+func TestPolicyHandler_HandleRequest_BlocksWhenRevoked(t *testing.T) {
+	client := NewPolicyClient(PolicyClientConfig{APIURL: "http://localhost:0"})
+	h := NewPolicyHandler()
+	h.SetPolicyClient(client)
+	ctx := NewHandlerContext("emp-1", "org-1", "sess-1")
+	req, _ := http.NewRequest("POST", "https://api.anthropic.com/v1/messages", nil)
+
+	client.handleMessage([]byte(`{"type":"init","policies":[]}`))
+	client.setState(StateReady)
+	assert.True(t, h.HandleRequest(ctx, req).ShouldContinue())
+
+	client.handleMessage([]byte(`{"type":"revoke","reason":"Employee account deactivated"}`))
+	result := h.HandleRequest(ctx, req)
+	assert.True(t, result.ShouldBlock())
+	assert.Equal(t, "Employee access has been revoked", result.Reason)
+}
+
+func TestPolicyHandler_ScheduledPolicies(t *testing.T) {
+	client := NewPolicyClient(PolicyClientConfig{APIURL: "http://localhost:0"})
+	h := NewPolicyHandler()
+	h.SetPolicyClient(client)
+
+	// The Bash deny stops applying shortly and the WebFetch deny starts
+	// shortly; the mcp deploy freeze only starts next year
+	until := time.Now().Add(200 * time.Millisecond).UTC().Format(time.RFC3339Nano)
+	from := time.Now().Add(200 * time.Millisecond).UTC().Format(time.RFC3339Nano)
+	nextYear := time.Now().AddDate(1, 0, 0).UTC().Format(time.RFC3339)
+	client.handleMessage([]byte(`{"type":"init","version":1,"timezone":"Europe/Berlin","policies":[
+		{"id":"pol-1","tool_name":"Bash","action":"deny","conditions":{"schedule":{"active_until":"` + until + `"}}},
+		{"id":"pol-2","tool_name":"WebFetch","action":"deny","conditions":{"schedule":{"active_from":"` + from + `"}}},
+		{"id":"pol-3","tool_name":"mcp__deploy__%","action":"deny","conditions":{"schedule":{"active_from":"` + nextYear + `"}}}
+	]}`))
+	assert.Equal(t, "Europe/Berlin", client.Timezone().String())
+
+	_, blocked := h.isBlocked("Bash")
+	assert.True(t, blocked)
+	_, blocked = h.isBlocked("WebFetch")
+	assert.False(t, blocked, "not active yet")
+	_, blocked = h.isBlocked("mcp__deploy__release")
+	assert.False(t, blocked, "not active yet")
+
+	// Without any message from the server, as while disconnected
+	assert.Eventually(t, func() bool {
+		_, bash := h.isBlocked("Bash")
+		_, webFetch := h.isBlocked("WebFetch")
+		return !bash && webFetch
+	}, 2*time.Second, 20*time.Millisecond, "schedules start and stop on the handler's timer")
+
+	// A push from the server keeps the policy, to be applied when it's active
+	client.handleMessage([]byte(`{"type":"upsert","reason":"Policy schedule ended","policy":
+		{"id":"pol-2","tool_name":"WebFetch","action":"deny","conditions":{"schedule":{"active_until":"` + from + `"}}}}`))
+	_, blocked = h.isBlocked("WebFetch")
+	assert.False(t, blocked)
+}
@@ -0,0 +1,147 @@
+package control
+
+import (
+	"sort"
+	"time"
+
+	"github.com/rastrigin-systems/arfa/pkg/policy"
+	"github.com/rastrigin-systems/arfa/services/cli/internal/api"
+)
+
+// An allow policy exempts the tool calls it matches from the deny,
+// require_approval and rate_limit policies it outranks; policies are ranked as
+// policy.Rank orders them. Audit policies log matching calls whatever the
+// outcome, and allow policies don't exempt calls from model policies.
+
+// rank returns what the rule's policy is ranked by.
+func (r policyRule) rank() policy.Rank {
+	return policy.Rank{Scope: string(r.Scope), ToolName: r.ToolName, Conditional: r.cond != nil}
+}
+
+// outranks reports whether r takes precedence over other.
+func (r policyRule) outranks(other policyRule) bool {
+	return r.rank().Outranks(other.rank())
+}
+
+// sortByPrecedence orders rules from the highest ranked down, keeping the
+// order of rules that tie.
+func sortByPrecedence(rules []policyRule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].outranks(rules[j])
+	})
+}
+
+// hasAllowPolicies reports whether any allow policy names the tool, before
+// its input is known.
+func (h *PolicyHandler) hasAllowPolicies(toolName string) bool {
+	h.mu.RLock()
+	rules := h.allowPolicies
+	h.mu.RUnlock()
+	return rulesNameTool(rules, toolName)
+}
+
+// allowedBy returns the allow policy that exempts a tool call from a
+// restriction it matches, if any.
+func (h *PolicyHandler) allowedBy(restriction policyRule, toolName string, input map[string]interface{}) (policyRule, bool) {
+	h.mu.RLock()
+	rules := h.allowPolicies
+	h.mu.RUnlock()
+
+	for _, allow := range rules {
+		if allow.outranks(restriction) && h.ruleMatches(allow, toolName, input) {
+			return allow, true
+		}
+	}
+	return policyRule{}, false
+}
+
+// override is a restriction an allow policy lifted for a tool call.
+type override struct {
+	allow       policyRule
+	restriction policyRule
+}
+
+// matchOverrides returns every restriction an allow policy lifts for a tool
+// call.
+func (h *PolicyHandler) matchOverrides(toolName string, input map[string]interface{}) []override {
+	if !h.hasAllowPolicies(toolName) {
+		return nil
+	}
+
+	h.mu.RLock()
+	var restrictions []policyRule
+	restrictions = append(restrictions, h.denyRules...)
+	restrictions = append(restrictions, h.conditionalPolicies...)
+	restrictions = append(restrictions, h.approvalPolicies...)
+	for _, rule := range h.rateLimits {
+		restrictions = append(restrictions, rule.policyRule)
+	}
+	h.mu.RUnlock()
+
+	var overrides []override
+	for _, restriction := range restrictions {
+		if !h.ruleMatches(restriction, toolName, input) {
+			continue
+		}
+		if allow, ok := h.allowedBy(restriction, toolName, input); ok {
+			overrides = append(overrides, override{allow: allow, restriction: restriction})
+		}
+	}
+	// Path deny policies name paths rather than tools
+	pathRules, _ := h.matchPathPolicies(api.ToolPolicyActionDeny, toolName, input)
+	for _, rule := range pathRules {
+		if allow, ok := h.allowedBy(rule.policyRule, toolName, input); ok {
+			overrides = append(overrides, override{allow: allow, restriction: rule.policyRule})
+		}
+	}
+	return overrides
+}
+
+// logOverrides logs every restriction an allow policy lifted for a tool call
+// that was let through.
+func (h *PolicyHandler) logOverrides(ctx *HandlerContext, provider Provider, toolName, toolID, input string) {
+	if h.queue == nil {
+		return
+	}
+	inputMap := parseToolInput(input)
+	for _, o := range h.matchOverrides(toolName, inputMap) {
+		h.logAllowed(ctx, provider, o.allow, o.restriction, toolName, toolID, inputMap)
+	}
+}
+
+// logAllowed enqueues a policy_violation event for a tool call an allow
+// policy let through a restriction, naming both policies.
+func (h *PolicyHandler) logAllowed(ctx *HandlerContext, provider Provider, allow, restriction policyRule, toolName, toolID string, toolInput map[string]interface{}) {
+	if h.queue == nil {
+		return
+	}
+
+	payload := map[string]interface{}{
+		"session_id":        ctx.SessionID,
+		"provider":          string(provider.Name()),
+		"policy_id":         allow.ID,
+		"scope":             string(allow.Scope),
+		"action":            string(api.ToolPolicyActionAllow),
+		"overridden_policy": restriction.ID,
+		"overridden_scope":  string(restriction.Scope),
+		"overridden_action": string(restriction.Action),
+		"tool_name":         toolName,
+		"tool_id":           toolID,
+		"tool_input":        toolInput,
+		"blocked":           false,
+	}
+	if allow.Reason != "" {
+		payload["reason"] = allow.Reason
+	}
+
+	_ = h.queue.Enqueue(LogEntry{
+		EmployeeID:    ctx.EmployeeID,
+		OrgID:         ctx.OrgID,
+		ClientName:    ctx.ClientName,
+		ClientVersion: ctx.ClientVersion,
+		EventType:     "policy_violation",
+		EventCategory: "classified",
+		Timestamp:     time.Now(),
+		Payload:       payload,
+	})
+}
@@ -0,0 +1,146 @@
+package control
+
+import (
+	"testing"
+
+	"github.com/rastrigin-systems/arfa/services/cli/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyRule_Outranks(t *testing.T) {
+	rule := func(scope api.ToolPolicyScope, toolName string, conditions map[string]interface{}) policyRule {
+		return newPolicyRule(api.ToolPolicy{ToolName: toolName, Scope: scope, Conditions: conditions})
+	}
+	command := map[string]interface{}{"command": "^git"}
+
+	tests := []struct {
+		name string
+		a, b policyRule
+		want bool
+	}{
+		{"employee over team", rule("employee", "B%", nil), rule("team", "Bash", nil), true},
+		{"team over organization", rule("team", "%", nil), rule("organization", "Bash", command), true},
+		{"organization under team", rule("organization", "Bash", nil), rule("team", "Bash", nil), false},
+		{"exact name over prefix", rule("team", "Bash", nil), rule("team", "Ba%", nil), true},
+		{"longer prefix", rule("team", "mcp__github__%", nil), rule("team", "mcp__%", nil), true},
+		{"conditions over none", rule("team", "Bash", command), rule("team", "Bash", nil), true},
+		{"tie", rule("team", "Bash", nil), rule("team", "bash", nil), false},
+		{"missing scope is organization", rule("", "Bash", nil), rule("organization", "Bash", nil), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.a.outranks(tt.b))
+		})
+	}
+}
+
+func TestPolicyHandler_AllowOverridesOrgDeny(t *testing.T) {
+	reason := "Shell is off for everyone"
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{
+		{ID: "org-deny", ToolName: "Bash", Action: api.ToolPolicyActionDeny, Scope: api.ToolPolicyScopeOrganization, Reason: &reason},
+		{ID: "team-allow", ToolName: "Bash", Action: api.ToolPolicyActionAllow, Scope: api.ToolPolicyScopeTeam},
+	})
+	queue := &mockToolLoggerQueue{}
+	h.SetQueue(queue)
+	ctx := NewHandlerContext("emp-1", "org-1", "sess-1")
+
+	stream := auditedBashStream("make test")
+	output, modified := streamThrough(t, h, ctx, []byte(stream))
+	assert.False(t, modified)
+	assert.Equal(t, stream, string(output))
+
+	entries := queue.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "policy_violation", entries[0].EventType)
+	assert.Equal(t, "allow", entries[0].Payload["action"])
+	assert.Equal(t, "team-allow", entries[0].Payload["policy_id"])
+	assert.Equal(t, "team", entries[0].Payload["scope"])
+	assert.Equal(t, "org-deny", entries[0].Payload["overridden_policy"])
+	assert.Equal(t, "organization", entries[0].Payload["overridden_scope"])
+	assert.Equal(t, "deny", entries[0].Payload["overridden_action"])
+	assert.Equal(t, false, entries[0].Payload["blocked"])
+}
+
+func TestPolicyHandler_AllowDoesNotOverrideEqualRank(t *testing.T) {
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{
+		{ID: "deny", ToolName: "Bash", Action: api.ToolPolicyActionDeny, Scope: api.ToolPolicyScopeTeam},
+		{ID: "allow", ToolName: "Bash", Action: api.ToolPolicyActionAllow, Scope: api.ToolPolicyScopeTeam},
+		{ID: "org-allow", ToolName: "Read", Action: api.ToolPolicyActionAllow, Scope: api.ToolPolicyScopeOrganization},
+		{ID: "employee-deny", ToolName: "Read", Action: api.ToolPolicyActionDeny, Scope: api.ToolPolicyScopeEmployee},
+	})
+
+	_, blocked := h.matchDenyPolicy("Bash", `{"command":"ls"}`, true)
+	assert.True(t, blocked, "ties go to the restriction")
+	policy, blocked := h.matchDenyPolicy("Read", `{"file_path":"a.go"}`, true)
+	assert.True(t, blocked, "a broader allow doesn't lift a narrower deny")
+	assert.Equal(t, "employee-deny", policy.ID)
+}
+
+func TestPolicyHandler_ConditionalAllow(t *testing.T) {
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{
+		{ID: "deny", ToolName: "Bash", Action: api.ToolPolicyActionDeny, Scope: api.ToolPolicyScopeTeam},
+		{ID: "allow-git", ToolName: "Bash", Action: api.ToolPolicyActionAllow, Scope: api.ToolPolicyScopeTeam,
+			Conditions: map[string]interface{}{"command": map[string]interface{}{"shell": map[string]interface{}{"command": "git"}}}},
+	})
+	ctx := NewHandlerContext("emp-1", "org-1", "sess-1")
+
+	_, modified := streamThrough(t, h, ctx, []byte(auditedBashStream("git status")))
+	assert.False(t, modified)
+
+	output, modified := streamThrough(t, h, ctx, []byte(auditedBashStream("rm -rf build")))
+	assert.True(t, modified)
+	assert.Contains(t, string(output), "Tool blocked by organization policy")
+
+	// Input that can't be parsed matches no allow conditions
+	_, blocked := h.evaluateConditions("Bash", "not json")
+	assert.False(t, blocked)
+	_, blocked = h.matchDenyPolicy("Bash", "not json", true)
+	assert.True(t, blocked)
+}
+
+func TestPolicyHandler_MostSpecificDenyDecides(t *testing.T) {
+	orgReason, employeeReason := "No MCP servers", "No GitHub writes for you"
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{
+		{ID: "org-deny", ToolName: "mcp__%", Action: api.ToolPolicyActionDeny, Scope: api.ToolPolicyScopeOrganization, Reason: &orgReason},
+		{ID: "employee-deny", ToolName: "mcp__github__%", Action: api.ToolPolicyActionDeny, Scope: api.ToolPolicyScopeEmployee, Reason: &employeeReason},
+	})
+	queue := &mockToolLoggerQueue{}
+	h.SetQueue(queue)
+
+	body := `{"type":"message","content":[{"type":"tool_use","id":"toolu_1","name":"mcp__github__create_issue","input":{}}],"stop_reason":"tool_use"}`
+	res := jsonResponse("https://api.anthropic.com/v1/messages", body)
+	result := h.HandleResponse(NewHandlerContext("emp-1", "org-1", "sess-1"), res)
+	require.NotNil(t, result.ModifiedResponse)
+	assert.Contains(t, string(drainBody(t, res)), employeeReason)
+
+	entries := queue.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "tool_call", entries[0].EventType)
+	assert.Equal(t, "employee-deny", entries[0].Payload["policy_id"])
+	assert.Equal(t, "employee", entries[0].Payload["scope"])
+}
+
+func TestPolicyHandler_AllowOverridesApprovalAndRateLimit(t *testing.T) {
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{
+		{ID: "approval", ToolName: "Bash", Action: api.ToolPolicyActionRequireApproval, Scope: api.ToolPolicyScopeOrganization},
+		{ID: "limit", ToolName: "%", Action: api.ToolPolicyActionRateLimit, Scope: api.ToolPolicyScopeTeam,
+			Conditions: map[string]interface{}{"rate_limit": map[string]interface{}{"max_calls": 1, "window_seconds": 60}}},
+		{ID: "allow", ToolName: "Bash", Action: api.ToolPolicyActionAllow, Scope: api.ToolPolicyScopeTeam},
+	})
+	queue := &mockToolLoggerQueue{}
+	h.SetQueue(queue)
+	ctx := NewHandlerContext("emp-1", "org-1", "sess-1")
+
+	// Without approvals set up, a call requiring approval would be denied
+	for i := 0; i < 3; i++ {
+		_, modified := streamThrough(t, h, ctx, []byte(auditedBashStream("ls")))
+		assert.False(t, modified)
+	}
+
+	overridden := map[interface{}]bool{}
+	for _, entry := range queue.Entries() {
+		overridden[entry.Payload["overridden_policy"]] = true
+	}
+	assert.Equal(t, map[interface{}]bool{"approval": true, "limit": true}, overridden)
+}
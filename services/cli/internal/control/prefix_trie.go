@@ -0,0 +1,82 @@
+package control
+
+// prefixTrie indexes the prefixes of tool name patterns ending in %, so a
+// tool name is checked against all of them in one walk over its characters
+// rather than one comparison per pattern. Matching is case-insensitive.
+type prefixTrie struct {
+	root trieNode
+	size int
+}
+
+// trieNode is one character of a prefix. Nodes ending a prefix carry the
+// reason of its policy.
+type trieNode struct {
+	children map[byte]*trieNode
+	reason   string
+	terminal bool
+}
+
+// newPrefixTrie creates an empty trie.
+func newPrefixTrie() *prefixTrie {
+	return &prefixTrie{}
+}
+
+// insert adds a prefix, replacing the reason of the same prefix added before.
+func (t *prefixTrie) insert(prefix, reason string) {
+	node := &t.root
+	for i := 0; i < len(prefix); i++ {
+		c := lower(prefix[i])
+		child, ok := node.children[c]
+		if !ok {
+			if node.children == nil {
+				node.children = make(map[byte]*trieNode)
+			}
+			child = &trieNode{}
+			node.children[c] = child
+		}
+		node = child
+	}
+	if !node.terminal {
+		node.terminal = true
+		t.size++
+	}
+	node.reason = reason
+}
+
+// longestMatch returns the reason of the longest prefix of name, the most
+// specific pattern matching it.
+func (t *prefixTrie) longestMatch(name string) (string, bool) {
+	if t == nil || t.size == 0 {
+		return "", false
+	}
+
+	node := &t.root
+	reason, found := node.reason, node.terminal
+	for i := 0; i < len(name); i++ {
+		node = node.children[lower(name[i])]
+		if node == nil {
+			break
+		}
+		if node.terminal {
+			reason, found = node.reason, true
+		}
+	}
+	return reason, found
+}
+
+// len returns the number of prefixes in the trie.
+func (t *prefixTrie) len() int {
+	if t == nil {
+		return 0
+	}
+	return t.size
+}
+
+// lower lowercases an ASCII letter; tool names are ASCII, so other bytes are
+// compared as they are.
+func lower(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
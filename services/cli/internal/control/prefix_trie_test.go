@@ -0,0 +1,57 @@
+package control
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrefixTrie(t *testing.T) {
+	trie := newPrefixTrie()
+	trie.insert("mcp__", "No MCP tools")
+	trie.insert("mcp__GitHub__", "No GitHub")
+	trie.insert("mcp__github__create_", "No creating")
+
+	tests := []struct {
+		name   string
+		want   string
+		wantOK bool
+	}{
+		{"mcp__playwright__click", "No MCP tools", true},
+		{"mcp__github__get_issue", "No GitHub", true},
+		{"MCP__GITHUB__CREATE_ISSUE", "No creating", true},
+		{"mcp__github", "No MCP tools", true},
+		{"mcp_", "", false},
+		{"Bash", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, ok := trie.longestMatch(tt.name)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, reason)
+		})
+	}
+
+	assert.Equal(t, 3, trie.len())
+	trie.insert("MCP__", "Replaced")
+	assert.Equal(t, 3, trie.len())
+	reason, _ := trie.longestMatch("mcp__x")
+	assert.Equal(t, "Replaced", reason)
+}
+
+func TestPrefixTrie_EmptyPrefix(t *testing.T) {
+	trie := newPrefixTrie()
+	_, ok := trie.longestMatch("Bash")
+	assert.False(t, ok)
+
+	// "%" blocks every tool
+	trie.insert("", "Everything")
+	reason, ok := trie.longestMatch("Bash")
+	assert.True(t, ok)
+	assert.Equal(t, "Everything", reason)
+
+	var missing *prefixTrie
+	_, ok = missing.longestMatch("Bash")
+	assert.False(t, ok)
+	assert.Equal(t, 0, missing.len())
+}
@@ -0,0 +1,159 @@
+package control
+
+import (
+	"net"
+	"net/http"
+	"regexp"
+
+	"github.com/rastrigin-systems/arfa/services/cli/internal/types"
+)
+
+// Provider describes the wire protocol of an LLM API so handlers can find
+// tool calls in its traffic without knowing the format.
+type Provider interface {
+	// Name identifies the provider in log entries.
+	Name() types.LogProvider
+
+	// MatchEndpoint reports whether a request path is a conversation endpoint
+	// (the traffic worth logging and enforcing policies on).
+	MatchEndpoint(path string) bool
+
+	// PathModel returns the model a conversation request names in its URL
+	// path, and a function returning the path with another model in its
+	// place. Providers that name the model in the request body return "".
+	PathModel(path string) (model string, withModel func(model string) string)
+
+	// BlockedResponse answers a request a policy blocked with an error in the
+	// API's own format, as a stream event if the request asked for a stream.
+	BlockedResponse(r *http.Request, reason string) *http.Response
+
+	// NewStreamParser returns a parser for a single streaming response.
+	NewStreamParser() StreamParser
+
+	// MessageToolCalls returns the tool calls in a complete (non-streaming)
+	// JSON response body.
+	MessageToolCalls(body []byte) ([]ToolCall, error)
+
+	// RewriteMessage replaces the blocked tool calls of a complete response
+	// with text carrying their message, keyed by ToolCall.Index, and adjusts
+	// the stop reason when no tool call is left.
+	RewriteMessage(body []byte, blocked map[int]string) ([]byte, error)
+
+	// Usage returns the token usage reported in a complete JSON response or
+	// in the data of one streamed event. Events that carry no usage return a
+	// zero Usage.
+	Usage(data []byte) Usage
+}
+
+// ToolCall is a complete tool call found in a non-streaming response.
+type ToolCall struct {
+	Index    int // Identifies the tool call within the response
+	ToolID   string
+	ToolName string
+	Input    string // JSON input
+}
+
+// ToolEventType classifies a ToolEvent.
+type ToolEventType int
+
+const (
+	// ToolStart marks the beginning of a tool call; ToolName and ToolID are set.
+	ToolStart ToolEventType = iota
+	// ToolInput carries a fragment of the tool's JSON input.
+	ToolInput
+	// ToolStop marks the tool call as complete.
+	ToolStop
+)
+
+// ToolEvent is a provider-neutral view of part of a streamed tool call.
+type ToolEvent struct {
+	Type     ToolEventType
+	Index    int // Identifies the tool call within the response
+	ToolID   string
+	ToolName string
+	Input    string // Partial JSON input (ToolInput only)
+}
+
+// StreamParser understands one provider's streaming events.
+// A parser keeps per-stream state, so each response needs its own.
+type StreamParser interface {
+	// Parse returns the tool events carried by an SSE event, in order.
+	Parse(ev SSEEvent) []ToolEvent
+
+	// Extract splits an event into the part belonging to tool call index and
+	// the rest. Either may be nil. Providers that give every tool call its own
+	// events return (ev, nil).
+	Extract(ev SSEEvent, index int) (tool, rest *SSEEvent)
+
+	// BlockedEvents returns events for a text block that takes the place of a
+	// blocked tool call, carrying message.
+	BlockedEvents(index int, message string) []SSEEvent
+
+	// Rewrite adjusts events that summarize the response (stop reasons,
+	// completed output) after tool calls were blocked. blocked maps tool call
+	// index to the message shown instead; allowed counts tool calls let through.
+	Rewrite(ev SSEEvent, blocked map[int]string, allowed int) SSEEvent
+}
+
+// pathModel reads a model from the first submatch of re in a request path.
+func pathModel(re *regexp.Regexp, path string) (string, func(string) string) {
+	m := re.FindStringSubmatchIndex(path)
+	if m == nil || m[2] < 0 {
+		return "", nil
+	}
+	return path[m[2]:m[3]], func(model string) string {
+		return path[:m[2]] + model + path[m[3]:]
+	}
+}
+
+// providerRoute maps LLM API hosts to the provider that serves them.
+type providerRoute struct {
+	hosts    *regexp.Regexp
+	provider Provider
+}
+
+// providerRoutes lists the built-in providers, checked in order.
+var providerRoutes = []providerRoute{
+	{regexp.MustCompile(`^api\.anthropic\.com$`), anthropicProvider{}},
+	{regexp.MustCompile(`^api\.openai\.com$`), openAIProvider{}},
+	{regexp.MustCompile(`^(generativelanguage|cloudcode-pa)\.googleapis\.com$`), geminiProvider{}},
+	{regexp.MustCompile(`^bedrock-runtime(-fips)?\.[a-z0-9-]+\.amazonaws\.com$`), bedrockProvider{}},
+	{regexp.MustCompile(`^([a-z0-9-]+-)?aiplatform\.googleapis\.com$`), vertexProvider{}},
+}
+
+// providerForHost returns the provider for an API host, or nil if the host
+// isn't a known LLM API.
+func providerForHost(host string) Provider {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	for _, route := range providerRoutes {
+		if route.hosts.MatchString(host) {
+			return route.provider
+		}
+	}
+	return nil
+}
+
+// providerForRequest returns the provider for a request, defaulting to
+// Anthropic when the request is unknown.
+func providerForRequest(req *http.Request) Provider {
+	if req != nil && req.URL != nil {
+		if p := providerForHost(req.URL.Host); p != nil {
+			return p
+		}
+		if p := providerForHost(req.Host); p != nil {
+			return p
+		}
+	}
+	return anthropicProvider{}
+}
+
+// contextProvider returns the provider the service resolved for the request,
+// falling back to a lookup by the request's host.
+func contextProvider(ctx *HandlerContext, req *http.Request) Provider {
+	if ctx != nil && ctx.Provider != nil {
+		return ctx.Provider
+	}
+	return providerForRequest(req)
+}
@@ -0,0 +1,349 @@
+package control
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/elazarl/goproxy"
+
+	"github.com/rastrigin-systems/arfa/services/cli/internal/types"
+)
+
+// anthropicEndpointRegex matches Anthropic's conversation endpoint
+var anthropicEndpointRegex = regexp.MustCompile(`/v1/messages`)
+
+// bedrockEndpointRegex matches Bedrock model invocation. The model ID may be an
+// ARN, which contains slashes once the path is unescaped.
+var bedrockEndpointRegex = regexp.MustCompile(`^/model/(.+)/invoke(-with-response-stream)?$`)
+
+// vertexEndpointRegex matches Claude models on Vertex AI.
+var vertexEndpointRegex = regexp.MustCompile(`/publishers/anthropic/models/([^/]+):(stream)?[rR]awPredict$`)
+
+// anthropicProvider handles the Anthropic Messages API.
+type anthropicProvider struct{}
+
+// Name returns the provider name.
+func (anthropicProvider) Name() types.LogProvider {
+	return types.LogProviderAnthropic
+}
+
+// MatchEndpoint matches /v1/messages.
+func (anthropicProvider) MatchEndpoint(path string) bool {
+	return anthropicEndpointRegex.MatchString(path)
+}
+
+// PathModel returns "": the Messages API names the model in the body.
+func (anthropicProvider) PathModel(string) (string, func(string) string) {
+	return "", nil
+}
+
+// BlockedResponse returns a permission_error, as an "error" event when the
+// request streams.
+func (anthropicProvider) BlockedResponse(r *http.Request, reason string) *http.Response {
+	payload := map[string]any{
+		"type": "error",
+		"error": map[string]any{
+			"type":    "permission_error",
+			"message": formatRequestBlockError(reason),
+		},
+	}
+	if isStreamingRequest(r) {
+		data, _ := json.Marshal(payload)
+		return eventStreamResponse(r, "event: error\ndata: "+string(data)+"\n\n")
+	}
+	return jsonErrorResponse(r, payload)
+}
+
+// NewStreamParser returns a parser for a Messages API SSE stream.
+func (anthropicProvider) NewStreamParser() StreamParser {
+	return &anthropicStreamParser{toolBlocks: make(map[int]bool)}
+}
+
+// anthropicMessage is a non-streaming Messages API response.
+type anthropicMessage struct {
+	Content []struct {
+		Type  string          `json:"type"`
+		ID    string          `json:"id"`
+		Name  string          `json:"name"`
+		Input json.RawMessage `json:"input"`
+	} `json:"content"`
+}
+
+// MessageToolCalls returns the tool_use blocks of a message, indexed by
+// their position in content.
+func (anthropicProvider) MessageToolCalls(body []byte) ([]ToolCall, error) {
+	var msg anthropicMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, err
+	}
+
+	var calls []ToolCall
+	for i, block := range msg.Content {
+		if block.Type != "tool_use" {
+			continue
+		}
+		calls = append(calls, ToolCall{
+			Index:    i,
+			ToolID:   block.ID,
+			ToolName: block.Name,
+			Input:    string(block.Input),
+		})
+	}
+	return calls, nil
+}
+
+// RewriteMessage turns blocked tool_use blocks into text blocks. stop_reason
+// "tool_use" becomes "end_turn" once no tool_use block is left.
+func (anthropicProvider) RewriteMessage(body []byte, blocked map[int]string) ([]byte, error) {
+	var msg map[string]any
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, err
+	}
+
+	content, _ := msg["content"].([]any)
+	for index, message := range blocked {
+		if index >= 0 && index < len(content) {
+			content[index] = map[string]any{"type": "text", "text": message}
+		}
+	}
+
+	remaining := 0
+	for _, b := range content {
+		if block, ok := b.(map[string]any); ok && block["type"] == "tool_use" {
+			remaining++
+		}
+	}
+	if remaining == 0 && msg["stop_reason"] == "tool_use" {
+		msg["stop_reason"] = "end_turn"
+	}
+
+	return json.Marshal(msg)
+}
+
+// anthropicUsage is the usage object of a message or message_delta event.
+// Cached prompt tokens are reported apart from input_tokens.
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+// anthropicUsageEnvelope holds usage wherever the Messages API reports it:
+// at the top of a message or message_delta event, or in message_start's message.
+type anthropicUsageEnvelope struct {
+	Model   string          `json:"model"`
+	Usage   *anthropicUsage `json:"usage"`
+	Message *struct {
+		Model string          `json:"model"`
+		Usage *anthropicUsage `json:"usage"`
+	} `json:"message"`
+}
+
+// Usage reads the usage of a message, or of a message_start or message_delta event.
+func (anthropicProvider) Usage(data []byte) Usage {
+	var env anthropicUsageEnvelope
+	if err := json.Unmarshal([]byte(cleanSSEData(string(data))), &env); err != nil {
+		return Usage{}
+	}
+
+	model, usage := env.Model, env.Usage
+	if env.Message != nil {
+		model, usage = env.Message.Model, env.Message.Usage
+	}
+	if usage == nil {
+		return Usage{Model: model}
+	}
+	return Usage{
+		Model:        model,
+		InputTokens:  usage.InputTokens + usage.CacheCreationInputTokens + usage.CacheReadInputTokens,
+		OutputTokens: usage.OutputTokens,
+	}
+}
+
+// bedrockProvider handles Claude on AWS Bedrock. Responses carry Messages API
+// events inside AWS event-stream frames, which the stream codec decodes.
+type bedrockProvider struct {
+	anthropicProvider
+}
+
+// MatchEndpoint matches /model/{id}/invoke and /model/{id}/invoke-with-response-stream.
+func (bedrockProvider) MatchEndpoint(path string) bool {
+	return bedrockEndpointRegex.MatchString(path)
+}
+
+// BlockedResponse returns an AccessDeniedException, or for
+// invoke-with-response-stream a 200 event stream holding a
+// validationException frame, the exception type the SDKs surface mid-stream.
+func (bedrockProvider) BlockedResponse(r *http.Request, reason string) *http.Response {
+	payload, _ := json.Marshal(map[string]any{"message": formatRequestBlockError(reason)})
+	if strings.HasSuffix(r.URL.Path, "/invoke-with-response-stream") {
+		var body bytes.Buffer
+		writeEventStreamFrame(&body, [][2]string{
+			{":exception-type", "validationException"},
+			{":content-type", "application/json"},
+			{":message-type", "exception"},
+		}, payload)
+		return goproxy.NewResponse(r, awsEventStreamContentType, http.StatusOK, body.String())
+	}
+
+	resp := goproxy.NewResponse(r, "application/json", http.StatusForbidden, string(payload))
+	resp.Header.Set("X-Amzn-ErrorType", "AccessDeniedException")
+	return resp
+}
+
+// PathModel returns the model ID of /model/{id}/invoke.
+func (bedrockProvider) PathModel(path string) (string, func(string) string) {
+	return pathModel(bedrockEndpointRegex, path)
+}
+
+// vertexProvider handles Claude on Google Vertex AI, which streams Messages API
+// events as SSE.
+type vertexProvider struct {
+	anthropicProvider
+}
+
+// MatchEndpoint matches :rawPredict and :streamRawPredict on Anthropic models.
+func (vertexProvider) MatchEndpoint(path string) bool {
+	return vertexEndpointRegex.MatchString(path)
+}
+
+// PathModel returns the model of /publishers/anthropic/models/{model}:rawPredict.
+func (vertexProvider) PathModel(path string) (string, func(string) string) {
+	return pathModel(vertexEndpointRegex, path)
+}
+
+// SSE event types we care about
+type sseContentBlockStart struct {
+	Type         string `json:"type"`
+	Index        int    `json:"index"`
+	ContentBlock struct {
+		Type  string `json:"type"`
+		ID    string `json:"id"`
+		Name  string `json:"name"`
+		Input any    `json:"input"`
+	} `json:"content_block"`
+}
+
+// sseContentBlockDelta is the payload of a content_block_delta event.
+type sseContentBlockDelta struct {
+	Index int `json:"index"`
+	Delta struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+	} `json:"delta"`
+}
+
+// sseContentBlockStop is the payload of a content_block_stop event.
+type sseContentBlockStop struct {
+	Index int `json:"index"`
+}
+
+// anthropicStreamParser tracks tool_use content blocks in a Messages API stream.
+// Every event belongs to exactly one content block, so no splitting is needed.
+type anthropicStreamParser struct {
+	toolBlocks map[int]bool // content block index -> is an open tool_use block
+}
+
+// Parse maps content_block_* events of tool_use blocks to tool events.
+func (p *anthropicStreamParser) Parse(ev SSEEvent) []ToolEvent {
+	// Anthropic's SSE stream sometimes has trailing whitespace and extra braces
+	data := []byte(cleanSSEData(ev.Data))
+
+	switch ev.Event {
+	case "content_block_start":
+		var block sseContentBlockStart
+		if err := json.Unmarshal(data, &block); err == nil && block.ContentBlock.Type == "tool_use" {
+			p.toolBlocks[block.Index] = true
+			return []ToolEvent{{
+				Type:     ToolStart,
+				Index:    block.Index,
+				ToolID:   block.ContentBlock.ID,
+				ToolName: block.ContentBlock.Name,
+			}}
+		}
+
+	case "content_block_delta":
+		var delta sseContentBlockDelta
+		if err := json.Unmarshal(data, &delta); err == nil && p.toolBlocks[delta.Index] {
+			event := ToolEvent{Type: ToolInput, Index: delta.Index}
+			if delta.Delta.Type == "input_json_delta" {
+				event.Input = delta.Delta.PartialJSON
+			}
+			return []ToolEvent{event}
+		}
+
+	case "content_block_stop":
+		var stop sseContentBlockStop
+		if err := json.Unmarshal(data, &stop); err == nil && p.toolBlocks[stop.Index] {
+			delete(p.toolBlocks, stop.Index)
+			return []ToolEvent{{Type: ToolStop, Index: stop.Index}}
+		}
+	}
+
+	return nil
+}
+
+// Extract returns the whole event as belonging to the tool block.
+func (p *anthropicStreamParser) Extract(ev SSEEvent, index int) (*SSEEvent, *SSEEvent) {
+	return &ev, nil
+}
+
+// BlockedEvents returns a complete text content block (start, delta, stop).
+func (p *anthropicStreamParser) BlockedEvents(index int, message string) []SSEEvent {
+	startJSON, _ := json.Marshal(map[string]any{
+		"type":  "content_block_start",
+		"index": index,
+		"content_block": map[string]any{
+			"type": "text",
+			"text": "",
+		},
+	})
+	deltaJSON, _ := json.Marshal(map[string]any{
+		"type":  "content_block_delta",
+		"index": index,
+		"delta": map[string]any{
+			"type": "text_delta",
+			"text": message,
+		},
+	})
+	stopJSON, _ := json.Marshal(map[string]any{
+		"type":  "content_block_stop",
+		"index": index,
+	})
+
+	return []SSEEvent{
+		{Event: "content_block_start", Data: string(startJSON)},
+		{Event: "content_block_delta", Data: string(deltaJSON)},
+		{Event: "content_block_stop", Data: string(stopJSON)},
+	}
+}
+
+// Rewrite changes stop_reason "tool_use" to "end_turn" when every tool call
+// was blocked, since the message no longer contains any tool_use blocks.
+func (p *anthropicStreamParser) Rewrite(ev SSEEvent, blocked map[int]string, allowed int) SSEEvent {
+	if ev.Event != "message_delta" || len(blocked) == 0 || allowed > 0 {
+		return ev
+	}
+
+	var msg map[string]any
+	if err := json.Unmarshal([]byte(ev.Data), &msg); err != nil {
+		return ev
+	}
+	delta, ok := msg["delta"].(map[string]any)
+	if !ok || delta["stop_reason"] != "tool_use" {
+		return ev
+	}
+	delta["stop_reason"] = "end_turn"
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return ev
+	}
+	ev.Data = string(data)
+	return ev
+}
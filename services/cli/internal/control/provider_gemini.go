@@ -0,0 +1,374 @@
+package control
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/rastrigin-systems/arfa/services/cli/internal/types"
+)
+
+// geminiEndpointRegex matches generateContent and streamGenerateContent,
+// on both the public API (/v1beta/models/{model}:...) and Code Assist (/v1internal:...).
+var geminiEndpointRegex = regexp.MustCompile(`:(stream)?[gG]enerateContent$`)
+
+// geminiModelRegex finds the model of a public API request. Code Assist
+// requests name it in the body instead.
+var geminiModelRegex = regexp.MustCompile(`/models/([^/:]+):(stream)?[gG]enerateContent$`)
+
+// geminiProvider handles the Gemini API and the Code Assist API used by Gemini CLI.
+type geminiProvider struct{}
+
+// Name returns the provider name.
+func (geminiProvider) Name() types.LogProvider {
+	return types.LogProviderGoogle
+}
+
+// MatchEndpoint matches :generateContent and :streamGenerateContent.
+func (geminiProvider) MatchEndpoint(path string) bool {
+	return geminiEndpointRegex.MatchString(path)
+}
+
+// PathModel returns the model of /models/{model}:generateContent.
+func (geminiProvider) PathModel(path string) (string, func(string) string) {
+	return pathModel(geminiModelRegex, path)
+}
+
+// BlockedResponse returns a PERMISSION_DENIED error, as a data chunk for
+// :streamGenerateContent, where streaming is asked for in the URL.
+func (geminiProvider) BlockedResponse(r *http.Request, reason string) *http.Response {
+	payload := map[string]any{
+		"error": map[string]any{
+			"code":    http.StatusForbidden,
+			"message": formatRequestBlockError(reason),
+			"status":  "PERMISSION_DENIED",
+		},
+	}
+	if strings.Contains(r.URL.Path, ":stream") {
+		data, _ := json.Marshal(payload)
+		return eventStreamResponse(r, "data: "+string(data)+"\n\n")
+	}
+	return jsonErrorResponse(r, payload)
+}
+
+// NewStreamParser returns a parser for a streamGenerateContent SSE stream.
+func (geminiProvider) NewStreamParser() StreamParser {
+	return &geminiStreamParser{calls: make(map[int]*geminiCallRef)}
+}
+
+// MessageToolCalls returns the functionCall parts of a generateContent
+// response, numbered in the order they appear.
+func (geminiProvider) MessageToolCalls(body []byte) ([]ToolCall, error) {
+	var resp geminiStreamChunk
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	candidates := resp.Candidates
+	if resp.Response != nil {
+		candidates = resp.Response.Candidates
+	}
+
+	var calls []ToolCall
+	for _, candidate := range candidates {
+		for _, part := range candidate.Content.Parts {
+			if fc := part.FunctionCall; fc != nil {
+				input := string(fc.Args)
+				if len(fc.Args) == 0 || input == "null" {
+					input = "{}"
+				}
+				calls = append(calls, ToolCall{Index: len(calls), ToolID: fc.ID, ToolName: fc.Name, Input: input})
+			}
+		}
+	}
+	return calls, nil
+}
+
+// RewriteMessage replaces blocked functionCall parts with text parts.
+func (geminiProvider) RewriteMessage(body []byte, blocked map[int]string) ([]byte, error) {
+	var msg map[string]any
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, err
+	}
+	resp := msg
+	if wrapped, ok := msg["response"].(map[string]any); ok {
+		resp = wrapped
+	}
+
+	index := 0
+	candidates, _ := resp["candidates"].([]any)
+	for _, c := range candidates {
+		candidate, _ := c.(map[string]any)
+		content, _ := candidate["content"].(map[string]any)
+		parts, _ := content["parts"].([]any)
+		for i, pt := range parts {
+			part, _ := pt.(map[string]any)
+			if part["functionCall"] == nil {
+				continue
+			}
+			if message, ok := blocked[index]; ok {
+				parts[i] = map[string]any{"text": message}
+			}
+			index++
+		}
+	}
+	return json.Marshal(msg)
+}
+
+// geminiUsageMetadata is the usageMetadata of a GenerateContentResponse.
+// Thinking tokens are billed as output.
+type geminiUsageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	ThoughtsTokenCount   int `json:"thoughtsTokenCount"`
+}
+
+// geminiUsageEnvelope holds the usage of a response, unwrapped or wrapped by Code Assist.
+type geminiUsageEnvelope struct {
+	ModelVersion  string               `json:"modelVersion"`
+	UsageMetadata *geminiUsageMetadata `json:"usageMetadata"`
+	Response      *struct {
+		ModelVersion  string               `json:"modelVersion"`
+		UsageMetadata *geminiUsageMetadata `json:"usageMetadata"`
+	} `json:"response"`
+}
+
+// Usage reads usageMetadata. Every streamed chunk carries the running totals.
+func (geminiProvider) Usage(data []byte) Usage {
+	var env geminiUsageEnvelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return Usage{}
+	}
+
+	model, usage := env.ModelVersion, env.UsageMetadata
+	if env.Response != nil {
+		model, usage = env.Response.ModelVersion, env.Response.UsageMetadata
+	}
+	if usage == nil {
+		return Usage{Model: model}
+	}
+	return Usage{
+		Model:        model,
+		InputTokens:  usage.PromptTokenCount,
+		OutputTokens: usage.CandidatesTokenCount + usage.ThoughtsTokenCount,
+	}
+}
+
+// geminiStreamChunk is one streamed GenerateContentResponse. Code Assist wraps
+// it in {"response": ...}.
+type geminiStreamChunk struct {
+	Candidates []geminiStreamCandidate `json:"candidates"`
+	Response   *struct {
+		Candidates []geminiStreamCandidate `json:"candidates"`
+	} `json:"response"`
+}
+
+// geminiStreamCandidate is a candidate within a stream chunk.
+type geminiStreamCandidate struct {
+	Index   int `json:"index"`
+	Content struct {
+		Parts []struct {
+			FunctionCall *struct {
+				ID   string          `json:"id"`
+				Name string          `json:"name"`
+				Args json.RawMessage `json:"args"`
+			} `json:"functionCall"`
+		} `json:"parts"`
+	} `json:"content"`
+}
+
+// geminiCallRef locates a function call part within the chunk it arrived in.
+type geminiCallRef struct {
+	candidate int
+	name      string
+	args      string // Canonical JSON, for matching the part
+	taken     bool
+}
+
+// geminiStreamParser finds functionCall parts in a Gemini stream. Gemini sends
+// each call whole in a single chunk, so every call starts and stops in the same
+// event. Calls are numbered in the order they appear.
+type geminiStreamParser struct {
+	next    int
+	calls   map[int]*geminiCallRef // tool index -> call in the current chunk
+	wrapped bool                   // stream uses the Code Assist {"response": ...} wrapper
+}
+
+// Parse returns start, input and stop events for each functionCall part.
+func (p *geminiStreamParser) Parse(ev SSEEvent) []ToolEvent {
+	var chunk geminiStreamChunk
+	if err := json.Unmarshal([]byte(ev.Data), &chunk); err != nil {
+		return nil
+	}
+	candidates := chunk.Candidates
+	if chunk.Response != nil {
+		p.wrapped = true
+		candidates = chunk.Response.Candidates
+	}
+
+	clear(p.calls)
+	var events []ToolEvent
+	for _, candidate := range candidates {
+		for _, part := range candidate.Content.Parts {
+			fc := part.FunctionCall
+			if fc == nil {
+				continue
+			}
+
+			index := p.next
+			p.next++
+			p.calls[index] = &geminiCallRef{
+				candidate: candidate.Index,
+				name:      fc.Name,
+				args:      canonicalJSON(fc.Args),
+			}
+
+			input := string(fc.Args)
+			if len(fc.Args) == 0 || input == "null" {
+				input = "{}"
+			}
+			events = append(events,
+				ToolEvent{Type: ToolStart, Index: index, ToolID: fc.ID, ToolName: fc.Name},
+				ToolEvent{Type: ToolInput, Index: index, Input: input},
+				ToolEvent{Type: ToolStop, Index: index},
+			)
+		}
+	}
+	return events
+}
+
+// Extract splits the functionCall part of tool call index out of the chunk.
+// A chunk left with no parts, finish reason or usage metadata is dropped.
+func (p *geminiStreamParser) Extract(ev SSEEvent, index int) (*SSEEvent, *SSEEvent) {
+	ref, ok := p.calls[index]
+	if !ok || ref.taken {
+		return nil, &ev
+	}
+
+	var root map[string]any
+	if err := json.Unmarshal([]byte(ev.Data), &root); err != nil {
+		return nil, &ev
+	}
+	chunk := root
+	if p.wrapped {
+		chunk, _ = root["response"].(map[string]any)
+	}
+
+	candidates, _ := chunk["candidates"].([]any)
+	var toolPart any
+	for _, c := range candidates {
+		candidate, _ := c.(map[string]any)
+		if candidate == nil || geminiCandidateIndex(candidate) != ref.candidate {
+			continue
+		}
+		content, _ := candidate["content"].(map[string]any)
+		parts, _ := content["parts"].([]any)
+		for i, part := range parts {
+			fc, _ := part.(map[string]any)["functionCall"].(map[string]any)
+			if fc == nil || fc["name"] != ref.name || canonicalValue(fc["args"]) != ref.args {
+				continue
+			}
+			toolPart = part
+			parts = append(parts[:i:i], parts[i+1:]...)
+			if len(parts) > 0 {
+				content["parts"] = parts
+			} else {
+				delete(candidate, "content")
+			}
+			break
+		}
+		break
+	}
+	if toolPart == nil {
+		return nil, &ev
+	}
+	ref.taken = true
+
+	tool := &SSEEvent{Event: ev.Event, Data: p.chunkData(map[string]any{
+		"candidates": []any{map[string]any{
+			"index":   ref.candidate,
+			"content": map[string]any{"role": "model", "parts": []any{toolPart}},
+		}},
+	})}
+
+	if geminiChunkIsEmpty(chunk) {
+		return tool, nil
+	}
+	data, _ := json.Marshal(root)
+	return tool, &SSEEvent{Event: ev.Event, Data: string(data)}
+}
+
+// geminiChunkIsEmpty reports whether a chunk has nothing left for the client.
+func geminiChunkIsEmpty(chunk map[string]any) bool {
+	if chunk["usageMetadata"] != nil || chunk["promptFeedback"] != nil {
+		return false
+	}
+	candidates, _ := chunk["candidates"].([]any)
+	for _, c := range candidates {
+		candidate, _ := c.(map[string]any)
+		if candidate["content"] != nil || candidate["finishReason"] != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// geminiCandidateIndex returns a candidate's index; it is omitted when zero.
+func geminiCandidateIndex(candidate map[string]any) int {
+	if _, ok := candidate["index"]; !ok {
+		return 0
+	}
+	return jsonInt(candidate["index"])
+}
+
+// BlockedEvents returns a text part in place of the blocked function call.
+func (p *geminiStreamParser) BlockedEvents(index int, message string) []SSEEvent {
+	candidate := 0
+	if ref, ok := p.calls[index]; ok {
+		candidate = ref.candidate
+	}
+
+	data := p.chunkData(map[string]any{
+		"candidates": []any{map[string]any{
+			"index": candidate,
+			"content": map[string]any{
+				"role":  "model",
+				"parts": []any{map[string]any{"text": message}},
+			},
+		}},
+	})
+	return []SSEEvent{{Data: data}}
+}
+
+// Rewrite leaves events unchanged: Gemini reports finishReason STOP whether or
+// not the response contains function calls.
+func (p *geminiStreamParser) Rewrite(ev SSEEvent, blocked map[int]string, allowed int) SSEEvent {
+	return ev
+}
+
+// chunkData serializes a chunk, adding the Code Assist wrapper if the stream uses it.
+func (p *geminiStreamParser) chunkData(chunk map[string]any) string {
+	var v any = chunk
+	if p.wrapped {
+		v = map[string]any{"response": chunk}
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// canonicalJSON re-encodes raw JSON with sorted keys so equal values compare equal.
+func canonicalJSON(raw json.RawMessage) string {
+	var v any
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return string(raw)
+		}
+	}
+	return canonicalValue(v)
+}
+
+// canonicalValue encodes a decoded JSON value with sorted keys.
+func canonicalValue(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -0,0 +1,138 @@
+package control
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rastrigin-systems/arfa/services/cli/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// geminiSSEResponse builds a streaming response for a Gemini endpoint.
+func geminiSSEResponse(host, path, stream string) *http.Response {
+	req, _ := http.NewRequest("POST", "https://"+host+path+"?alt=sse", nil)
+	return &http.Response{
+		StatusCode: 200,
+		Request:    req,
+		Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+		Body:       io.NopCloser(strings.NewReader(stream)),
+	}
+}
+
+const geminiToolStream = "data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"Let me clean up.\"}]}}]}\r\n\r\n" +
+	"data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"functionCall\":{\"name\":\"run_shell_command\",\"args\":{\"command\":\"rm -rf /tmp/x\"}}},{\"functionCall\":{\"name\":\"read_file\",\"args\":{\"absolute_path\":\"/a\"}}}]},\"finishReason\":\"STOP\"}],\"usageMetadata\":{\"promptTokenCount\":10,\"candidatesTokenCount\":5}}\r\n\r\n"
+
+func TestGeminiProvider_MatchEndpoint(t *testing.T) {
+	p := geminiProvider{}
+	assert.True(t, p.MatchEndpoint("/v1beta/models/gemini-2.5-pro:streamGenerateContent"))
+	assert.True(t, p.MatchEndpoint("/v1beta/models/gemini-2.5-pro:generateContent"))
+	assert.True(t, p.MatchEndpoint("/v1internal:streamGenerateContent"))
+	assert.False(t, p.MatchEndpoint("/v1beta/models/gemini-2.5-pro:countTokens"))
+
+	assert.Equal(t, geminiProvider{}, providerForHost("generativelanguage.googleapis.com"))
+	assert.Equal(t, geminiProvider{}, providerForHost("cloudcode-pa.googleapis.com:443"))
+}
+
+func TestGeminiStreamParser_FunctionCalls(t *testing.T) {
+	parser := geminiProvider{}.NewStreamParser()
+
+	assert.Empty(t, parser.Parse(SSEEvent{Data: `{"candidates":[{"content":{"parts":[{"text":"hi"}]}}]}`}))
+
+	events := parser.Parse(SSEEvent{Data: `{"candidates":[{"content":{"parts":[{"functionCall":{"id":"c1","name":"read_file","args":{"absolute_path":"/a"}}},{"functionCall":{"name":"list_directory"}}]}}]}`})
+	require.Len(t, events, 6)
+	assert.Equal(t, ToolEvent{Type: ToolStart, Index: 0, ToolID: "c1", ToolName: "read_file"}, events[0])
+	assert.Equal(t, ToolEvent{Type: ToolInput, Index: 0, Input: `{"absolute_path":"/a"}`}, events[1])
+	assert.Equal(t, ToolEvent{Type: ToolStop, Index: 0}, events[2])
+	assert.Equal(t, ToolEvent{Type: ToolStart, Index: 1, ToolName: "list_directory"}, events[3])
+	assert.Equal(t, ToolEvent{Type: ToolInput, Index: 1, Input: "{}"}, events[4])
+}
+
+func TestPolicyHandler_Gemini_BlocksOneOfTwoCalls(t *testing.T) {
+	queue := &mockToolLoggerQueue{}
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{
+		{
+			ToolName:   "run_shell_command",
+			Action:     api.ToolPolicyActionDeny,
+			Conditions: map[string]interface{}{"command": `rm\s+-rf`},
+		},
+	})
+	h.SetQueue(queue)
+
+	res := geminiSSEResponse("generativelanguage.googleapis.com", "/v1beta/models/gemini-2.5-pro:streamGenerateContent", geminiToolStream)
+	result := h.HandleResponse(NewHandlerContext("emp-1", "org-1", "sess-1"), res)
+	require.NotNil(t, result.ModifiedResponse)
+
+	body := string(drainBody(t, result.ModifiedResponse))
+	assert.Contains(t, body, "Let me clean up.")
+	assert.NotContains(t, body, "run_shell_command\",\"args")
+	assert.Contains(t, body, "TOOL BLOCKED BY ORGANIZATION POLICY")
+	assert.Contains(t, body, `"name":"read_file"`)
+	assert.Contains(t, body, `"finishReason":"STOP"`)
+	assert.Contains(t, body, `"usageMetadata"`)
+
+	entries := queue.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "run_shell_command", entries[0].Payload["tool_name"])
+	assert.Equal(t, "google", entries[0].Payload["provider"])
+	assert.Equal(t, map[string]interface{}{"command": "rm -rf /tmp/x"}, entries[0].Payload["tool_input"])
+}
+
+func TestPolicyHandler_Gemini_CodeAssistWrapper(t *testing.T) {
+	h := NewPolicyHandlerWithDenyList(map[string]string{"run_shell_command": "No shell"})
+
+	stream := "data: {\"response\":{\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"functionCall\":{\"name\":\"run_shell_command\",\"args\":{\"command\":\"ls\"}}}]},\"finishReason\":\"STOP\"}]},\"traceId\":\"t1\"}\r\n\r\n"
+	res := geminiSSEResponse("cloudcode-pa.googleapis.com", "/v1internal:streamGenerateContent", stream)
+	result := h.HandleResponse(NewHandlerContext("emp-1", "org-1", "sess-1"), res)
+	require.NotNil(t, result.ModifiedResponse)
+
+	chunks := sseDataLines(t, string(drainBody(t, result.ModifiedResponse)))
+	require.Len(t, chunks, 2)
+
+	// Replacement text keeps the wrapper, and the finish reason is still delivered
+	replacement := chunks[0]["response"].(map[string]any)["candidates"].([]any)[0].(map[string]any)
+	parts := replacement["content"].(map[string]any)["parts"].([]any)
+	assert.Contains(t, parts[0].(map[string]any)["text"], "No shell")
+
+	rest := chunks[1]["response"].(map[string]any)["candidates"].([]any)[0].(map[string]any)
+	assert.Equal(t, "STOP", rest["finishReason"])
+	assert.Nil(t, rest["content"])
+}
+
+func TestToolCallLoggerHandler_Gemini(t *testing.T) {
+	queue := &mockToolLoggerQueue{}
+	h := NewToolCallLoggerHandler(queue)
+
+	res := geminiSSEResponse("generativelanguage.googleapis.com", "/v1beta/models/gemini-2.5-pro:streamGenerateContent", geminiToolStream)
+	h.HandleResponse(&HandlerContext{EmployeeID: "emp-1", ClientName: "gemini-cli"}, res)
+	drainBody(t, res)
+
+	entries := queue.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "run_shell_command", entries[0].Payload["tool_name"])
+	assert.Equal(t, "read_file", entries[1].Payload["tool_name"])
+	assert.Equal(t, "google", entries[1].Payload["provider"])
+	assert.Equal(t, map[string]interface{}{"absolute_path": "/a"}, entries[1].Payload["tool_input"])
+}
+
+func TestPolicyHandler_Gemini_JSONBlockedTool(t *testing.T) {
+	h := NewPolicyHandlerWithDenyList(map[string]string{"run_shell_command": "No shell"})
+
+	body := `{"response":{"candidates":[{"content":{"role":"model","parts":[` +
+		`{"functionCall":{"name":"run_shell_command","args":{"command":"ls"}}},` +
+		`{"functionCall":{"name":"read_file","args":{"absolute_path":"/a"}}}]},"finishReason":"STOP"}]}}`
+	res := jsonResponse("https://cloudcode-pa.googleapis.com/v1internal:generateContent", body)
+	result := h.HandleResponse(NewHandlerContext("emp-1", "org-1", "sess-1"), res)
+	require.NotNil(t, result.ModifiedResponse)
+
+	var msg map[string]any
+	require.NoError(t, json.Unmarshal(drainBody(t, result.ModifiedResponse), &msg))
+	candidate := msg["response"].(map[string]any)["candidates"].([]any)[0].(map[string]any)
+	parts := candidate["content"].(map[string]any)["parts"].([]any)
+	require.Len(t, parts, 2)
+	assert.Contains(t, parts[0].(map[string]any)["text"], "No shell")
+	assert.NotNil(t, parts[1].(map[string]any)["functionCall"])
+}
@@ -0,0 +1,639 @@
+package control
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/rastrigin-systems/arfa/services/cli/internal/types"
+)
+
+// openAIEndpointRegex matches the Chat Completions and Responses endpoints.
+// Gateways such as LiteLLM also serve them without the /v1 prefix.
+var openAIEndpointRegex = regexp.MustCompile(`/(chat/completions|responses)$`)
+
+// openAIFunctionCallSlot is the tool index used for legacy function_call deltas,
+// which carry no index of their own.
+const openAIFunctionCallSlot = 999
+
+// openAIChoiceStride separates tool call indices of different choices.
+const openAIChoiceStride = 1000
+
+// openAIProvider handles the OpenAI Chat Completions and Responses APIs.
+type openAIProvider struct{}
+
+// Name returns the provider name.
+func (openAIProvider) Name() types.LogProvider {
+	return types.LogProviderOpenAI
+}
+
+// MatchEndpoint matches /chat/completions and /responses, with or without /v1.
+func (openAIProvider) MatchEndpoint(path string) bool {
+	return openAIEndpointRegex.MatchString(path)
+}
+
+// PathModel returns "": OpenAI names the model in the body.
+func (openAIProvider) PathModel(string) (string, func(string) string) {
+	return "", nil
+}
+
+// BlockedResponse returns an OpenAI error object. A streamed Chat
+// Completions request gets it as a data chunk, a streamed Responses request
+// as an "error" event.
+func (openAIProvider) BlockedResponse(r *http.Request, reason string) *http.Response {
+	message := formatRequestBlockError(reason)
+	if !isStreamingRequest(r) {
+		return jsonErrorResponse(r, map[string]any{"error": openAIError(message)})
+	}
+
+	if strings.HasSuffix(r.URL.Path, "/responses") {
+		event := openAIError(message)
+		event["type"] = "error"
+		data, _ := json.Marshal(event)
+		return eventStreamResponse(r, "event: error\ndata: "+string(data)+"\n\n")
+	}
+	data, _ := json.Marshal(map[string]any{"error": openAIError(message)})
+	return eventStreamResponse(r, "data: "+string(data)+"\n\ndata: [DONE]\n\n")
+}
+
+// openAIError is the error object of OpenAI's error responses.
+func openAIError(message string) map[string]any {
+	return map[string]any{
+		"message": message,
+		"type":    "permission_error",
+		"param":   nil,
+		"code":    "blocked_by_policy",
+	}
+}
+
+// NewStreamParser returns a parser for either OpenAI streaming format.
+// The format is recognized from the events themselves.
+func (openAIProvider) NewStreamParser() StreamParser {
+	return &openAIStreamParser{open: make(map[int]bool)}
+}
+
+// openAIChatChunk is a Chat Completions stream chunk.
+type openAIChatChunk struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Created int64  `json:"created"`
+	Choices []struct {
+		Index int `json:"index"`
+		Delta struct {
+			ToolCalls []struct {
+				Index    int    `json:"index"`
+				ID       string `json:"id"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+			FunctionCall *struct {
+				Name      string `json:"name"`
+				Arguments string `json:"arguments"`
+			} `json:"function_call"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+// openAIResponsesEvent is a Responses API stream event.
+type openAIResponsesEvent struct {
+	Type        string `json:"type"`
+	OutputIndex int    `json:"output_index"`
+	Delta       string `json:"delta"`
+	Item        struct {
+		Type      string `json:"type"`
+		CallID    string `json:"call_id"`
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"item"`
+}
+
+// openAIStreamParser tracks tool calls in a Chat Completions or Responses stream.
+type openAIStreamParser struct {
+	open      map[int]bool // tool index -> call in progress
+	responses bool         // stream uses the Responses API
+
+	// Chat chunk identity, reused for synthesized chunks
+	chunkID string
+	model   string
+	created int64
+}
+
+// Parse maps tool call deltas to tool events.
+func (p *openAIStreamParser) Parse(ev SSEEvent) []ToolEvent {
+	if ev.Data == "" || ev.Data == "[DONE]" {
+		return nil
+	}
+
+	if strings.HasPrefix(ev.Event, "response.") || strings.Contains(ev.Data, `"type":"response.`) {
+		p.responses = true
+		return p.parseResponsesEvent(ev)
+	}
+	return p.parseChatChunk(ev)
+}
+
+// parseChatChunk handles a Chat Completions chunk.
+// A tool call starts when its id or name appears and ends with the choice's finish_reason.
+func (p *openAIStreamParser) parseChatChunk(ev SSEEvent) []ToolEvent {
+	var chunk openAIChatChunk
+	if err := json.Unmarshal([]byte(ev.Data), &chunk); err != nil {
+		return nil
+	}
+	if chunk.ID != "" {
+		p.chunkID, p.model, p.created = chunk.ID, chunk.Model, chunk.Created
+	}
+
+	var events []ToolEvent
+	for _, choice := range chunk.Choices {
+		base := choice.Index * openAIChoiceStride
+
+		for _, call := range choice.Delta.ToolCalls {
+			index := base + call.Index
+			if !p.open[index] && (call.ID != "" || call.Function.Name != "") {
+				p.open[index] = true
+				events = append(events, ToolEvent{Type: ToolStart, Index: index, ToolID: call.ID, ToolName: call.Function.Name})
+			}
+			if p.open[index] {
+				events = append(events, ToolEvent{Type: ToolInput, Index: index, Input: call.Function.Arguments})
+			}
+		}
+
+		if fc := choice.Delta.FunctionCall; fc != nil {
+			index := base + openAIFunctionCallSlot
+			if !p.open[index] && fc.Name != "" {
+				p.open[index] = true
+				events = append(events, ToolEvent{Type: ToolStart, Index: index, ToolName: fc.Name})
+			}
+			if p.open[index] {
+				events = append(events, ToolEvent{Type: ToolInput, Index: index, Input: fc.Arguments})
+			}
+		}
+
+		if choice.FinishReason != nil && *choice.FinishReason != "" {
+			var done []int
+			for index := range p.open {
+				if index/openAIChoiceStride == choice.Index {
+					done = append(done, index)
+				}
+			}
+			sort.Ints(done)
+			for _, index := range done {
+				delete(p.open, index)
+				events = append(events, ToolEvent{Type: ToolStop, Index: index})
+			}
+		}
+	}
+	return events
+}
+
+// parseResponsesEvent handles a Responses API event. Function calls are output
+// items; every event refers to a single item by output_index.
+func (p *openAIStreamParser) parseResponsesEvent(ev SSEEvent) []ToolEvent {
+	var event openAIResponsesEvent
+	if err := json.Unmarshal([]byte(ev.Data), &event); err != nil {
+		return nil
+	}
+	index := event.OutputIndex
+
+	switch event.Type {
+	case "response.output_item.added":
+		if event.Item.Type != "function_call" {
+			return nil
+		}
+		p.open[index] = true
+		events := []ToolEvent{{Type: ToolStart, Index: index, ToolID: event.Item.CallID, ToolName: event.Item.Name}}
+		if event.Item.Arguments != "" {
+			events = append(events, ToolEvent{Type: ToolInput, Index: index, Input: event.Item.Arguments})
+		}
+		return events
+
+	case "response.function_call_arguments.delta":
+		if p.open[index] {
+			return []ToolEvent{{Type: ToolInput, Index: index, Input: event.Delta}}
+		}
+
+	case "response.function_call_arguments.done":
+		// Arguments were already accumulated from deltas; claim the event only
+		if p.open[index] {
+			return []ToolEvent{{Type: ToolInput, Index: index}}
+		}
+
+	case "response.output_item.done":
+		if p.open[index] {
+			delete(p.open, index)
+			return []ToolEvent{{Type: ToolStop, Index: index}}
+		}
+	}
+	return nil
+}
+
+// Extract splits a Chat Completions chunk into the given tool call's delta and
+// everything else. Responses API events always belong to a single item.
+func (p *openAIStreamParser) Extract(ev SSEEvent, index int) (*SSEEvent, *SSEEvent) {
+	if p.responses {
+		return &ev, nil
+	}
+
+	var chunk map[string]any
+	if err := json.Unmarshal([]byte(ev.Data), &chunk); err != nil {
+		return nil, &ev
+	}
+	choices, _ := chunk["choices"].([]any)
+
+	choiceIndex, callIndex := index/openAIChoiceStride, index%openAIChoiceStride
+	var toolDelta map[string]any
+	for _, c := range choices {
+		choice, _ := c.(map[string]any)
+		if choice == nil || jsonInt(choice["index"]) != choiceIndex {
+			continue
+		}
+		delta, _ := choice["delta"].(map[string]any)
+		if delta == nil {
+			continue
+		}
+
+		if callIndex == openAIFunctionCallSlot {
+			if fc, ok := delta["function_call"]; ok && fc != nil {
+				toolDelta = map[string]any{"function_call": fc}
+				delete(delta, "function_call")
+			}
+			continue
+		}
+
+		calls, _ := delta["tool_calls"].([]any)
+		var kept, taken []any
+		for _, call := range calls {
+			if m, ok := call.(map[string]any); ok && jsonInt(m["index"]) == callIndex {
+				taken = append(taken, call)
+			} else {
+				kept = append(kept, call)
+			}
+		}
+		if len(taken) > 0 {
+			toolDelta = map[string]any{"tool_calls": taken}
+			if len(kept) > 0 {
+				delta["tool_calls"] = kept
+			} else {
+				delete(delta, "tool_calls")
+			}
+		}
+	}
+
+	if toolDelta == nil {
+		return nil, &ev
+	}
+
+	// Tool part: same chunk identity with only this call's delta
+	toolChunk := make(map[string]any, len(chunk))
+	for k, v := range chunk {
+		if k != "choices" && k != "usage" {
+			toolChunk[k] = v
+		}
+	}
+	toolChunk["choices"] = []any{map[string]any{
+		"index":         choiceIndex,
+		"delta":         toolDelta,
+		"finish_reason": nil,
+	}}
+	toolData, _ := json.Marshal(toolChunk)
+	tool := &SSEEvent{Event: ev.Event, Data: string(toolData)}
+
+	if openAIChunkIsEmpty(chunk) {
+		return tool, nil
+	}
+	restData, _ := json.Marshal(chunk)
+	return tool, &SSEEvent{Event: ev.Event, Data: string(restData)}
+}
+
+// openAIChunkIsEmpty reports whether a chat chunk carries nothing a client needs:
+// no usage, no finish reason and no delta content.
+func openAIChunkIsEmpty(chunk map[string]any) bool {
+	if chunk["usage"] != nil {
+		return false
+	}
+	choices, _ := chunk["choices"].([]any)
+	for _, c := range choices {
+		choice, _ := c.(map[string]any)
+		if choice == nil {
+			continue
+		}
+		if choice["finish_reason"] != nil {
+			return false
+		}
+		delta, _ := choice["delta"].(map[string]any)
+		for _, v := range delta {
+			if v != nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// BlockedEvents returns a text message in place of the blocked tool call.
+func (p *openAIStreamParser) BlockedEvents(index int, message string) []SSEEvent {
+	if p.responses {
+		return p.blockedResponsesEvents(index, message)
+	}
+
+	data, _ := json.Marshal(map[string]any{
+		"id":      p.chunkID,
+		"object":  "chat.completion.chunk",
+		"created": p.created,
+		"model":   p.model,
+		"choices": []any{map[string]any{
+			"index":         index / openAIChoiceStride,
+			"delta":         map[string]any{"content": message},
+			"finish_reason": nil,
+		}},
+	})
+	return []SSEEvent{{Data: string(data)}}
+}
+
+// blockedResponsesEvents returns a complete assistant message output item.
+func (p *openAIStreamParser) blockedResponsesEvents(index int, message string) []SSEEvent {
+	itemID := blockedMessageItemID(index)
+
+	added, _ := json.Marshal(map[string]any{
+		"type":         "response.output_item.added",
+		"output_index": index,
+		"item": map[string]any{
+			"id":      itemID,
+			"type":    "message",
+			"role":    "assistant",
+			"status":  "in_progress",
+			"content": []any{},
+		},
+	})
+	delta, _ := json.Marshal(map[string]any{
+		"type":          "response.output_text.delta",
+		"item_id":       itemID,
+		"output_index":  index,
+		"content_index": 0,
+		"delta":         message,
+	})
+	done, _ := json.Marshal(map[string]any{
+		"type":         "response.output_item.done",
+		"output_index": index,
+		"item":         blockedMessageItem(index, message),
+	})
+
+	return []SSEEvent{
+		{Event: "response.output_item.added", Data: string(added)},
+		{Event: "response.output_text.delta", Data: string(delta)},
+		{Event: "response.output_item.done", Data: string(done)},
+	}
+}
+
+// blockedMessageItemID returns the ID of the message item replacing a blocked call.
+func blockedMessageItemID(index int) string {
+	return fmt.Sprintf("msg_arfa_blocked_%d", index)
+}
+
+// blockedMessageItem returns a completed Responses API message item.
+func blockedMessageItem(index int, message string) map[string]any {
+	return map[string]any{
+		"id":     blockedMessageItemID(index),
+		"type":   "message",
+		"role":   "assistant",
+		"status": "completed",
+		"content": []any{map[string]any{
+			"type":        "output_text",
+			"text":        message,
+			"annotations": []any{},
+		}},
+	}
+}
+
+// Rewrite fixes up summaries of the response once tool calls were blocked.
+// Chat: finish_reason "tool_calls" becomes "stop" when no call was allowed.
+// Responses: blocked function calls in response.completed become messages.
+func (p *openAIStreamParser) Rewrite(ev SSEEvent, blocked map[int]string, allowed int) SSEEvent {
+	if len(blocked) == 0 {
+		return ev
+	}
+
+	var msg map[string]any
+	if err := json.Unmarshal([]byte(ev.Data), &msg); err != nil {
+		return ev
+	}
+	changed := false
+
+	if p.responses {
+		if msg["type"] != "response.completed" {
+			return ev
+		}
+		response, _ := msg["response"].(map[string]any)
+		output, _ := response["output"].([]any)
+		for index, message := range blocked {
+			if index < len(output) {
+				output[index] = blockedMessageItem(index, message)
+				changed = true
+			}
+		}
+	} else if allowed == 0 {
+		choices, _ := msg["choices"].([]any)
+		for _, c := range choices {
+			choice, _ := c.(map[string]any)
+			if choice == nil {
+				continue
+			}
+			if reason := choice["finish_reason"]; reason == "tool_calls" || reason == "function_call" {
+				choice["finish_reason"] = "stop"
+				changed = true
+			}
+		}
+	}
+
+	if !changed {
+		return ev
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return ev
+	}
+	ev.Data = string(data)
+	return ev
+}
+
+// openAIMessage is a non-streaming Chat Completions or Responses API response.
+type openAIMessage struct {
+	Object  string `json:"object"`
+	Choices []struct {
+		Message struct {
+			ToolCalls []struct {
+				ID       string `json:"id"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+			FunctionCall *struct {
+				Name      string `json:"name"`
+				Arguments string `json:"arguments"`
+			} `json:"function_call"`
+		} `json:"message"`
+	} `json:"choices"`
+	Output []struct {
+		Type      string `json:"type"`
+		CallID    string `json:"call_id"`
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"output"`
+}
+
+// MessageToolCalls returns the function calls of a completion or response.
+// Indices match the stream parser's: choice*openAIChoiceStride+position for
+// Chat Completions, and the output index for the Responses API.
+func (openAIProvider) MessageToolCalls(body []byte) ([]ToolCall, error) {
+	var msg openAIMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, err
+	}
+
+	var calls []ToolCall
+	if msg.Object == "response" {
+		for i, item := range msg.Output {
+			if item.Type == "function_call" {
+				calls = append(calls, ToolCall{Index: i, ToolID: item.CallID, ToolName: item.Name, Input: item.Arguments})
+			}
+		}
+		return calls, nil
+	}
+
+	for c, choice := range msg.Choices {
+		for i, tc := range choice.Message.ToolCalls {
+			calls = append(calls, ToolCall{
+				Index:    c*openAIChoiceStride + i,
+				ToolID:   tc.ID,
+				ToolName: tc.Function.Name,
+				Input:    tc.Function.Arguments,
+			})
+		}
+		if fc := choice.Message.FunctionCall; fc != nil {
+			calls = append(calls, ToolCall{
+				Index:    c*openAIChoiceStride + openAIFunctionCallSlot,
+				ToolName: fc.Name,
+				Input:    fc.Arguments,
+			})
+		}
+	}
+	return calls, nil
+}
+
+// RewriteMessage removes blocked function calls. Chat: the message is appended
+// to the choice's content, and finish_reason becomes "stop" once the choice has
+// no calls left. Responses: blocked output items become assistant messages.
+func (openAIProvider) RewriteMessage(body []byte, blocked map[int]string) ([]byte, error) {
+	var msg map[string]any
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, err
+	}
+
+	if msg["object"] == "response" {
+		output, _ := msg["output"].([]any)
+		for index, message := range blocked {
+			if index >= 0 && index < len(output) {
+				output[index] = blockedMessageItem(index, message)
+			}
+		}
+		return json.Marshal(msg)
+	}
+
+	choices, _ := msg["choices"].([]any)
+	for c, ch := range choices {
+		choice, _ := ch.(map[string]any)
+		message, _ := choice["message"].(map[string]any)
+		if message == nil {
+			continue
+		}
+
+		var texts []string
+		toolCalls, _ := message["tool_calls"].([]any)
+		kept := make([]any, 0, len(toolCalls))
+		for i, tc := range toolCalls {
+			if text, ok := blocked[c*openAIChoiceStride+i]; ok {
+				texts = append(texts, text)
+			} else {
+				kept = append(kept, tc)
+			}
+		}
+		if text, ok := blocked[c*openAIChoiceStride+openAIFunctionCallSlot]; ok {
+			texts = append(texts, text)
+			delete(message, "function_call")
+		}
+		if len(texts) == 0 {
+			continue
+		}
+
+		if len(kept) > 0 {
+			message["tool_calls"] = kept
+		} else {
+			delete(message, "tool_calls")
+		}
+		content, _ := message["content"].(string)
+		message["content"] = content + strings.Join(texts, "")
+
+		if len(kept) == 0 && message["function_call"] == nil {
+			if reason := choice["finish_reason"]; reason == "tool_calls" || reason == "function_call" {
+				choice["finish_reason"] = "stop"
+			}
+		}
+	}
+	return json.Marshal(msg)
+}
+
+// openAIUsage is a usage object in either API's naming.
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	InputTokens      int `json:"input_tokens"`
+	OutputTokens     int `json:"output_tokens"`
+}
+
+// openAIUsageEnvelope holds usage at the top of a response or chat chunk, or
+// in the response of a response.completed event.
+type openAIUsageEnvelope struct {
+	Model    string       `json:"model"`
+	Usage    *openAIUsage `json:"usage"`
+	Response *struct {
+		Model string       `json:"model"`
+		Usage *openAIUsage `json:"usage"`
+	} `json:"response"`
+}
+
+// Usage reads the usage of a response. Chat streams report it in a final
+// chunk only when the request asked for it (stream_options.include_usage);
+// Responses streams report it in response.completed.
+func (openAIProvider) Usage(data []byte) Usage {
+	var env openAIUsageEnvelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return Usage{}
+	}
+
+	model, usage := env.Model, env.Usage
+	if env.Response != nil {
+		model, usage = env.Response.Model, env.Response.Usage
+	}
+	if usage == nil {
+		return Usage{Model: model}
+	}
+	return Usage{
+		Model:        model,
+		InputTokens:  usage.PromptTokens + usage.InputTokens,
+		OutputTokens: usage.CompletionTokens + usage.OutputTokens,
+	}
+}
+
+// jsonInt converts a decoded JSON number to int (-1 if not a number).
+func jsonInt(v any) int {
+	if f, ok := v.(float64); ok {
+		return int(f)
+	}
+	return -1
+}
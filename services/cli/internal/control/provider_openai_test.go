@@ -0,0 +1,286 @@
+package control
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rastrigin-systems/arfa/services/cli/internal/api"
+	"github.com/rastrigin-systems/arfa/services/cli/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openAISSEResponse builds a streaming response for an OpenAI endpoint.
+func openAISSEResponse(path, stream string) *http.Response {
+	req, _ := http.NewRequest("POST", "https://api.openai.com"+path, nil)
+	return &http.Response{
+		StatusCode: 200,
+		Request:    req,
+		Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+		Body:       io.NopCloser(strings.NewReader(stream)),
+	}
+}
+
+// sseDataLines returns the decoded JSON payload of every data line in body.
+func sseDataLines(t *testing.T, body string) []map[string]any {
+	t.Helper()
+	var chunks []map[string]any
+	for _, line := range strings.Split(body, "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok || data == "[DONE]" {
+			continue
+		}
+		var chunk map[string]any
+		require.NoError(t, json.Unmarshal([]byte(data), &chunk), data)
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+const openAIChatToolStream = `data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"role":"assistant","content":null,"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"shell","arguments":""}}]},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"command\":"}}]},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"rm -rf /\"}"}}]},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}
+
+data: [DONE]
+
+`
+
+func TestProviderForHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want Provider
+	}{
+		{"api.anthropic.com", anthropicProvider{}},
+		{"api.anthropic.com:443", anthropicProvider{}},
+		{"api.openai.com", openAIProvider{}},
+		{"api.openai.com:443", openAIProvider{}},
+		{"example.com", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			assert.Equal(t, tt.want, providerForHost(tt.host))
+		})
+	}
+}
+
+func TestOpenAIProvider_MatchEndpoint(t *testing.T) {
+	p := openAIProvider{}
+	assert.Equal(t, types.LogProviderOpenAI, p.Name())
+	assert.True(t, p.MatchEndpoint("/v1/chat/completions"))
+	assert.True(t, p.MatchEndpoint("/v1/responses"))
+	assert.False(t, p.MatchEndpoint("/v1/models"))
+}
+
+func TestOpenAIStreamParser_ChatToolCalls(t *testing.T) {
+	parser := openAIProvider{}.NewStreamParser()
+
+	var events []ToolEvent
+	for _, data := range sseDataLines(t, openAIChatToolStream) {
+		raw, _ := json.Marshal(data)
+		events = append(events, parser.Parse(SSEEvent{Data: string(raw)})...)
+	}
+
+	require.Len(t, events, 5)
+	assert.Equal(t, ToolEvent{Type: ToolStart, Index: 0, ToolID: "call_1", ToolName: "shell"}, events[0])
+
+	var input strings.Builder
+	for _, ev := range events[1:4] {
+		assert.Equal(t, ToolInput, ev.Type)
+		input.WriteString(ev.Input)
+	}
+	assert.Equal(t, `{"command":"rm -rf /"}`, input.String())
+	assert.Equal(t, ToolEvent{Type: ToolStop, Index: 0}, events[4])
+}
+
+func TestOpenAIStreamParser_ExtractSplitsParallelCalls(t *testing.T) {
+	parser := openAIProvider{}.NewStreamParser()
+	ev := SSEEvent{Data: `{"id":"chatcmpl-1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"shell","arguments":""}},{"index":1,"id":"call_2","function":{"name":"read_file","arguments":""}}]},"finish_reason":null}]}`}
+	require.Len(t, parser.Parse(ev), 4)
+
+	tool, rest := parser.Extract(ev, 1)
+	require.NotNil(t, tool)
+	require.NotNil(t, rest)
+	assert.Contains(t, tool.Data, "call_2")
+	assert.NotContains(t, tool.Data, "call_1")
+	assert.Contains(t, rest.Data, "call_1")
+	assert.NotContains(t, rest.Data, "call_2")
+
+	// Nothing left once the last call is taken
+	tool, rest = parser.Extract(*rest, 0)
+	require.NotNil(t, tool)
+	assert.Nil(t, rest)
+}
+
+func TestPolicyHandler_OpenAIChat_BlockedTool(t *testing.T) {
+	queue := &mockToolLoggerQueue{}
+	reason := "No destructive commands"
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{
+		{
+			ToolName:   "shell",
+			Action:     api.ToolPolicyActionDeny,
+			Reason:     &reason,
+			Conditions: map[string]interface{}{"command": `rm\s+-rf`},
+		},
+	})
+	h.SetQueue(queue)
+
+	res := openAISSEResponse("/v1/chat/completions", openAIChatToolStream)
+	result := h.HandleResponse(NewHandlerContext("emp-1", "org-1", "sess-1"), res)
+	require.NotNil(t, result.ModifiedResponse)
+
+	body := string(drainBody(t, result.ModifiedResponse))
+	assert.NotContains(t, body, `"tool_calls":[`)
+	assert.Contains(t, body, "TOOL BLOCKED BY ORGANIZATION POLICY")
+	assert.Contains(t, body, "data: [DONE]")
+
+	chunks := sseDataLines(t, body)
+	require.NotEmpty(t, chunks)
+	last := chunks[len(chunks)-1]["choices"].([]any)[0].(map[string]any)
+	assert.Equal(t, "stop", last["finish_reason"])
+
+	entries := queue.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "shell", entries[0].Payload["tool_name"])
+	assert.Equal(t, "call_1", entries[0].Payload["tool_id"])
+	assert.Equal(t, true, entries[0].Payload["blocked"])
+	assert.Equal(t, "openai", entries[0].Payload["provider"])
+}
+
+func TestPolicyHandler_OpenAIChat_AllowedToolPassesThrough(t *testing.T) {
+	h := NewPolicyHandlerWithDenyList(map[string]string{"Bash": "no shell"})
+
+	res := openAISSEResponse("/v1/chat/completions", openAIChatToolStream)
+	result := h.HandleResponse(NewHandlerContext("emp-1", "org-1", "sess-1"), res)
+	require.NotNil(t, result.ModifiedResponse)
+
+	body := string(drainBody(t, result.ModifiedResponse))
+	assert.NotContains(t, body, "TOOL BLOCKED")
+
+	var input strings.Builder
+	for _, chunk := range sseDataLines(t, body) {
+		for _, c := range chunk["choices"].([]any) {
+			delta := c.(map[string]any)["delta"].(map[string]any)
+			for _, call := range toolCallsOf(delta) {
+				fn := call.(map[string]any)["function"].(map[string]any)
+				input.WriteString(fn["arguments"].(string))
+			}
+		}
+	}
+	assert.Equal(t, `{"command":"rm -rf /"}`, input.String())
+	assert.Contains(t, body, `"finish_reason":"tool_calls"`)
+}
+
+// toolCallsOf returns the tool_calls of a chat delta, if any.
+func toolCallsOf(delta map[string]any) []any {
+	calls, _ := delta["tool_calls"].([]any)
+	return calls
+}
+
+const openAIResponsesToolStream = `event: response.created
+data: {"type":"response.created","response":{"id":"resp_1","status":"in_progress","output":[]}}
+
+event: response.output_item.added
+data: {"type":"response.output_item.added","output_index":0,"item":{"id":"fc_1","type":"function_call","call_id":"call_1","name":"shell","arguments":""}}
+
+event: response.function_call_arguments.delta
+data: {"type":"response.function_call_arguments.delta","item_id":"fc_1","output_index":0,"delta":"{\"command\":\"rm -rf /\"}"}
+
+event: response.function_call_arguments.done
+data: {"type":"response.function_call_arguments.done","item_id":"fc_1","output_index":0,"arguments":"{\"command\":\"rm -rf /\"}"}
+
+event: response.output_item.done
+data: {"type":"response.output_item.done","output_index":0,"item":{"id":"fc_1","type":"function_call","call_id":"call_1","name":"shell","arguments":"{\"command\":\"rm -rf /\"}"}}
+
+event: response.completed
+data: {"type":"response.completed","response":{"id":"resp_1","status":"completed","output":[{"id":"fc_1","type":"function_call","call_id":"call_1","name":"shell","arguments":"{\"command\":\"rm -rf /\"}"}]}}
+
+`
+
+func TestPolicyHandler_OpenAIResponses_BlockedTool(t *testing.T) {
+	h := NewPolicyHandlerWithDenyList(map[string]string{"shell": "Shell is disabled"})
+
+	res := openAISSEResponse("/v1/responses", openAIResponsesToolStream)
+	result := h.HandleResponse(NewHandlerContext("emp-1", "org-1", "sess-1"), res)
+	require.NotNil(t, result.ModifiedResponse)
+
+	body := string(drainBody(t, result.ModifiedResponse))
+	assert.NotContains(t, body, `"type":"function_call"`)
+	assert.NotContains(t, body, "response.function_call_arguments")
+	assert.Contains(t, body, "event: response.output_text.delta")
+	assert.Contains(t, body, "Shell is disabled")
+
+	chunks := sseDataLines(t, body)
+	completed := chunks[len(chunks)-1]
+	assert.Equal(t, "response.completed", completed["type"])
+	output := completed["response"].(map[string]any)["output"].([]any)
+	require.Len(t, output, 1)
+	assert.Equal(t, "message", output[0].(map[string]any)["type"])
+}
+
+func TestToolCallLoggerHandler_OpenAIResponses(t *testing.T) {
+	queue := &mockToolLoggerQueue{}
+	h := NewToolCallLoggerHandler(queue)
+	ctx := &HandlerContext{EmployeeID: "emp-1", OrgID: "org-1", ClientName: "codex"}
+
+	res := openAISSEResponse("/v1/responses", openAIResponsesToolStream)
+	h.HandleResponse(ctx, res)
+	drainBody(t, res)
+
+	entries := queue.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "shell", entries[0].Payload["tool_name"])
+	assert.Equal(t, "call_1", entries[0].Payload["tool_id"])
+	assert.Equal(t, "openai", entries[0].Payload["provider"])
+	assert.Equal(t, map[string]interface{}{"command": "rm -rf /"}, entries[0].Payload["tool_input"])
+}
+
+func TestPolicyHandler_OpenAIChat_JSONBlockedTool(t *testing.T) {
+	queue := &mockToolLoggerQueue{}
+	h := NewPolicyHandlerWithDenyList(map[string]string{"shell": "Shell is disabled"})
+	h.SetQueue(queue)
+
+	body := `{"id":"chatcmpl-1","object":"chat.completion","model":"gpt-4o","choices":[{"index":0,` +
+		`"message":{"role":"assistant","content":null,"tool_calls":[` +
+		`{"id":"call_1","type":"function","function":{"name":"shell","arguments":"{\"command\":\"ls\"}"}},` +
+		`{"id":"call_2","type":"function","function":{"name":"read_file","arguments":"{\"path\":\"a\"}"}}]},` +
+		`"finish_reason":"tool_calls"}]}`
+	result := h.HandleResponse(NewHandlerContext("emp-1", "org-1", "sess-1"), jsonResponse("https://api.openai.com/v1/chat/completions", body))
+	require.NotNil(t, result.ModifiedResponse)
+
+	var msg map[string]any
+	require.NoError(t, json.Unmarshal(drainBody(t, result.ModifiedResponse), &msg))
+	choice := msg["choices"].([]any)[0].(map[string]any)
+	message := choice["message"].(map[string]any)
+	assert.Contains(t, message["content"], "Shell is disabled")
+	require.Len(t, message["tool_calls"], 1)
+	assert.Equal(t, "call_2", message["tool_calls"].([]any)[0].(map[string]any)["id"])
+	assert.Equal(t, "tool_calls", choice["finish_reason"])
+
+	entries := queue.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "openai", entries[0].Payload["provider"])
+	assert.Equal(t, map[string]interface{}{"command": "ls"}, entries[0].Payload["tool_input"])
+}
+
+func TestPolicyHandler_OpenAIResponses_JSONBlockedTool(t *testing.T) {
+	h := NewPolicyHandlerWithDenyList(map[string]string{"shell": "Shell is disabled"})
+
+	body := `{"id":"resp_1","object":"response","status":"completed","output":[` +
+		`{"type":"function_call","id":"fc_1","call_id":"call_1","name":"shell","arguments":"{}"}]}`
+	result := h.HandleResponse(NewHandlerContext("emp-1", "org-1", "sess-1"), jsonResponse("https://api.openai.com/v1/responses", body))
+	require.NotNil(t, result.ModifiedResponse)
+
+	var msg map[string]any
+	require.NoError(t, json.Unmarshal(drainBody(t, result.ModifiedResponse), &msg))
+	item := msg["output"].([]any)[0].(map[string]any)
+	assert.Equal(t, "message", item["type"])
+	assert.Contains(t, item["content"].([]any)[0].(map[string]any)["text"], "Shell is disabled")
+}
@@ -1,7 +1,6 @@
 package control
 
 import (
-	"bytes"
 	"compress/gzip"
 	"context"
 	"crypto/rand"
@@ -9,6 +8,7 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/hex"
 	"encoding/pem"
 	"fmt"
 	"io"
@@ -30,9 +30,11 @@ const (
 	MaxPort = 8091
 )
 
-// llmHostRegex matches LLM provider hosts to intercept
-// Currently focused on Anthropic (Claude Code)
-var llmHostRegex = regexp.MustCompile(`api\.anthropic\.com`)
+// llmHostRegex matches the built-in LLM provider hosts to intercept.
+// Each host needs a matching entry in providerRoutes. Organizations can add
+// more through InterceptHosts.
+var llmHostRegex = regexp.MustCompile(`api\.anthropic\.com|api\.openai\.com|generativelanguage\.googleapis\.com|cloudcode-pa\.googleapis\.com|` +
+	`bedrock-runtime(-fips)?\.[a-z0-9-]+\.amazonaws\.com|([a-z0-9-]+-)?aiplatform\.googleapis\.com`)
 
 // ControlledProxy provides in-process HTTPS interception integrated with the Control Service.
 // All intercepted requests/responses flow through the Control Service pipeline.
@@ -43,6 +45,9 @@ type ControlledProxy struct {
 	port     int
 	certPath string
 	keyPath  string
+
+	// controlToken authenticates requests to the local approvals API
+	controlToken string
 }
 
 // NewControlledProxy creates a new proxy integrated with a Control Service.
@@ -63,6 +68,11 @@ func (p *ControlledProxy) Start() error {
 	// Configure interception rules
 	p.configureRules()
 
+	// Serve the local approvals API on the proxy port
+	if err := p.configureControl(); err != nil {
+		return fmt.Errorf("failed to setup control API: %w", err)
+	}
+
 	// Try to find an available port
 	for port := MinPort; port <= MaxPort; port++ {
 		if err := p.tryStart(port); err == nil {
@@ -130,6 +140,11 @@ func (p *ControlledProxy) GetProxyURL() string {
 	return fmt.Sprintf("http://127.0.0.1:%d", p.port)
 }
 
+// GetControlToken returns the token for the proxy's local approvals API.
+func (p *ControlledProxy) GetControlToken() string {
+	return p.controlToken
+}
+
 // GetCertPath returns the path to the CA certificate.
 func (p *ControlledProxy) GetCertPath() string {
 	return p.certPath
@@ -241,7 +256,7 @@ func (p *ControlledProxy) configureGoproxyCA(caCert *tls.Certificate) {
 
 	// Only MITM LLM API hosts - let other traffic pass through directly
 	p.goproxy.OnRequest().HandleConnectFunc(func(host string, ctx *goproxy.ProxyCtx) (*goproxy.ConnectAction, string) {
-		if llmHostRegex.MatchString(host) {
+		if p.interceptHosts().Match(host) {
 			return goproxy.MitmConnect, host
 		}
 		// Pass through without interception
@@ -251,19 +266,48 @@ func (p *ControlledProxy) configureGoproxyCA(caCert *tls.Certificate) {
 
 // configureRules sets up interception rules for LLM providers.
 func (p *ControlledProxy) configureRules() {
+	intercepted := p.interceptHosts().reqCondition()
+
 	// Intercept LLM API requests
-	p.goproxy.OnRequest(goproxy.ReqHostMatches(llmHostRegex)).DoFunc(
+	p.goproxy.OnRequest(intercepted).DoFunc(
 		func(r *http.Request, ctx *goproxy.ProxyCtx) (*http.Request, *http.Response) {
 			return p.handleRequest(r)
 		})
 
 	// Intercept LLM API responses
-	p.goproxy.OnResponse(goproxy.ReqHostMatches(llmHostRegex)).DoFunc(
+	p.goproxy.OnResponse(intercepted).DoFunc(
 		func(resp *http.Response, ctx *goproxy.ProxyCtx) *http.Response {
 			return p.handleResponse(resp)
 		})
 }
 
+// configureControl serves the local approvals API to requests sent to the
+// proxy directly rather than through it.
+func (p *ControlledProxy) configureControl() error {
+	if p.service == nil {
+		return nil
+	}
+
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return err
+	}
+	p.controlToken = hex.EncodeToString(token)
+
+	p.goproxy.NonproxyHandler = newApprovalsHandler(p.service.Approvals(), p.controlToken)
+	return nil
+}
+
+// interceptHosts returns the service's host set; nil (built-in hosts only)
+// without a service. The set is consulted per connection, so hosts the
+// organization configures apply without restarting the proxy.
+func (p *ControlledProxy) interceptHosts() *InterceptHosts {
+	if p.service == nil {
+		return nil
+	}
+	return p.service.InterceptHosts()
+}
+
 // handleRequest processes an intercepted request through the Control Service pipeline.
 func (p *ControlledProxy) handleRequest(r *http.Request) (*http.Request, *http.Response) {
 	if p.service == nil {
@@ -272,9 +316,9 @@ func (p *ControlledProxy) handleRequest(r *http.Request) (*http.Request, *http.R
 
 	result := p.service.HandleRequest(r)
 
-	// If blocked, return an error response
+	// If blocked, answer with the provider's own error instead of forwarding
 	if result.Action == ActionBlock {
-		return r, goproxy.NewResponse(r, goproxy.ContentTypeText, http.StatusForbidden, result.Reason)
+		return r, p.service.InterceptHosts().ProviderFor(r).BlockedResponse(r, result.Reason)
 	}
 
 	// Use modified request if provided
@@ -291,28 +335,13 @@ func (p *ControlledProxy) handleResponse(resp *http.Response) *http.Response {
 		return resp
 	}
 
-	// Decompress gzip responses before passing to pipeline
+	// Decompress gzip responses as they're read, so streams stay streams
 	if resp.Header.Get("Content-Encoding") == "gzip" && resp.Body != nil {
-		bodyBytes, err := io.ReadAll(resp.Body)
-		if err == nil {
-			reader, err := gzip.NewReader(bytes.NewBuffer(bodyBytes))
-			if err == nil {
-				decodedBody, err := io.ReadAll(reader)
-				_ = reader.Close()
-				if err == nil {
-					// Replace body with decompressed content
-					resp.Body = io.NopCloser(bytes.NewBuffer(decodedBody))
-					resp.Header.Del("Content-Encoding")
-					resp.ContentLength = int64(len(decodedBody))
-				} else {
-					// Restore original body if decompression fails
-					resp.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
-				}
-			} else {
-				// Restore original body if gzip reader fails
-				resp.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
-			}
-		}
+		resp.Body = &gzipBody{body: resp.Body}
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+		resp.Uncompressed = true
 	}
 
 	result := p.service.HandleResponse(resp)
@@ -327,3 +356,28 @@ func (p *ControlledProxy) handleResponse(resp *http.Response) *http.Response {
 
 	return resp
 }
+
+// gzipBody decompresses a response body as it is read. The gzip header is
+// read on the first Read rather than up front, so wrapping a stream doesn't
+// wait for its first bytes.
+type gzipBody struct {
+	body io.ReadCloser
+	zr   *gzip.Reader
+}
+
+// Read implements io.Reader.
+func (g *gzipBody) Read(p []byte) (int, error) {
+	if g.zr == nil {
+		zr, err := gzip.NewReader(g.body)
+		if err != nil {
+			return 0, err
+		}
+		g.zr = zr
+	}
+	return g.zr.Read(p)
+}
+
+// Close closes the underlying body.
+func (g *gzipBody) Close() error {
+	return g.body.Close()
+}
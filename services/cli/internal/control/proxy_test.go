@@ -1,11 +1,15 @@
 package control
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
@@ -232,3 +236,76 @@ func mustParseURL(rawURL string) *url.URL {
 	}
 	return u
 }
+
+func TestControlledProxy_BlocksWhenPolicyUnavailable(t *testing.T) {
+	svc, err := NewService(ServiceConfig{
+		EmployeeID: "emp-123",
+		OrgID:      "org-456",
+		QueueDir:   t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	// Client never connected - still waiting for initial policies
+	svc.policyHandler.SetPolicyClient(NewPolicyClient(PolicyClientConfig{APIURL: "http://localhost:0"}))
+
+	proxy := NewControlledProxy(svc)
+	req, _ := http.NewRequest("POST", "https://api.anthropic.com/v1/messages", bytes.NewBufferString(`{}`))
+	_, resp := proxy.handleRequest(req)
+	require.NotNil(t, resp)
+
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var body struct {
+		Type  string `json:"type"`
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "error", body.Type)
+	assert.Equal(t, "permission_error", body.Error.Type)
+	assert.Contains(t, body.Error.Message, "Waiting for policy server connection")
+}
+
+func TestControlledProxy_DecompressesGzipStream(t *testing.T) {
+	svc, err := NewService(ServiceConfig{EmployeeID: "emp-123", OrgID: "org-456", QueueDir: t.TempDir()})
+	require.NoError(t, err)
+	proxy := NewControlledProxy(svc)
+
+	// The upstream sends one event, then holds the stream open
+	pr, pw := io.Pipe()
+	release := make(chan struct{})
+	go func() {
+		zw := gzip.NewWriter(pw)
+		_, _ = zw.Write([]byte("event: ping\ndata: {\"type\":\"ping\"}\n\n"))
+		_ = zw.Flush()
+		<-release
+		_, _ = zw.Write([]byte("event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"))
+		_ = zw.Close()
+		_ = pw.Close()
+	}()
+
+	req, _ := http.NewRequest("POST", "https://api.anthropic.com/v1/messages", nil)
+	resp := &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Content-Type": []string{"text/event-stream"}, "Content-Encoding": []string{"gzip"}},
+		Body:          pr,
+		ContentLength: -1,
+		Request:       req,
+	}
+	resp = proxy.handleResponse(resp)
+	assert.Empty(t, resp.Header.Get("Content-Encoding"))
+
+	// The first event is readable before the upstream finishes
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "event: ping\n", line)
+
+	close(release)
+	rest, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Contains(t, string(rest), "message_stop")
+}
@@ -0,0 +1,199 @@
+package control
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rastrigin-systems/arfa/services/cli/internal/api"
+)
+
+// What a rate_limit policy counts calls against
+const (
+	rateLimitPerSession  = "session"
+	rateLimitPerEmployee = "employee"
+)
+
+// maxRateLimitWindow is the longest window the API accepts. Saved call times
+// older than this can never count again.
+const maxRateLimitWindow = 24 * time.Hour
+
+// rateLimitRule is a rate_limit policy: at most MaxCalls matching tool calls
+// per Window, counted per session or per employee. The limit is kept in the
+// policy's conditions under "rate_limit"; the remaining conditions narrow
+// which calls count.
+type rateLimitRule struct {
+	policyRule
+	MaxCalls int
+	Window   time.Duration
+	Per      string
+}
+
+// rateLimitConfig is the "rate_limit" entry of a policy's conditions.
+type rateLimitConfig struct {
+	MaxCalls      int    `json:"max_calls"`
+	WindowSeconds int    `json:"window_seconds"`
+	Per           string `json:"per,omitempty"`
+}
+
+// newRateLimitRule converts a rate_limit policy from the API. Policies with a
+// missing or invalid limit are skipped.
+func newRateLimitRule(policy api.ToolPolicy) (rateLimitRule, bool) {
+	data, err := json.Marshal(policy.Conditions["rate_limit"])
+	if err != nil {
+		return rateLimitRule{}, false
+	}
+	var config rateLimitConfig
+	if err := json.Unmarshal(data, &config); err != nil || config.MaxCalls < 1 || config.WindowSeconds < 1 {
+		return rateLimitRule{}, false
+	}
+
+	window := time.Duration(config.WindowSeconds) * time.Second
+	if window > maxRateLimitWindow {
+		return rateLimitRule{}, false
+	}
+
+	per := config.Per
+	switch per {
+	case "":
+		per = rateLimitPerSession
+	case rateLimitPerSession, rateLimitPerEmployee:
+	default:
+		return rateLimitRule{}, false
+	}
+
+	rule := rateLimitRule{
+		policyRule: newPolicyRule(policy),
+		MaxCalls:   config.MaxCalls,
+		Window:     window,
+		Per:        per,
+	}
+
+	// Everything but the limit is a parameter condition
+	rule.Conditions = make(map[string]interface{}, len(policy.Conditions))
+	for param, condition := range policy.Conditions {
+		if param != "rate_limit" {
+			rule.Conditions[param] = condition
+		}
+	}
+	return rule, true
+}
+
+// key identifies the window a call is counted in.
+func (r rateLimitRule) key(ctx *HandlerContext) string {
+	if r.Per == rateLimitPerEmployee {
+		return rateLimitPerEmployee + ":" + ctx.EmployeeID + ":" + r.ID
+	}
+	return rateLimitPerSession + ":" + ctx.SessionID + ":" + r.ID
+}
+
+// rateLimiter keeps a sliding window of call times per key. Employee windows
+// can be saved to a file so they outlast the proxy process; session windows
+// end with it.
+type rateLimiter struct {
+	mu      sync.Mutex
+	windows map[string][]time.Time // key -> call times, oldest first
+	path    string
+	now     func() time.Time
+}
+
+// newRateLimiter creates a limiter that keeps windows in memory only.
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		windows: make(map[string][]time.Time),
+		now:     time.Now,
+	}
+}
+
+// rateLimitCheck is one window a call has to fit in.
+type rateLimitCheck struct {
+	key      string
+	maxCalls int
+	window   time.Duration
+}
+
+// take counts a call against every window if it fits in all of them. If not,
+// nothing is counted and take returns the index of the first full window and
+// how long until it has room again.
+func (l *rateLimiter) take(checks []rateLimitCheck) (int, time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	for i, check := range checks {
+		calls := pruneWindow(l.windows[check.key], now.Add(-check.window))
+		l.windows[check.key] = calls
+		if len(calls) >= check.maxCalls {
+			// Room frees up when the oldest call that keeps it full leaves the window
+			oldest := calls[len(calls)-check.maxCalls]
+			return i, oldest.Add(check.window).Sub(now), false
+		}
+	}
+
+	persist := false
+	for _, check := range checks {
+		l.windows[check.key] = append(l.windows[check.key], now)
+		persist = persist || strings.HasPrefix(check.key, rateLimitPerEmployee+":")
+	}
+	if persist {
+		l.saveLocked(now)
+	}
+	return -1, 0, true
+}
+
+// pruneWindow drops call times before since.
+func pruneWindow(calls []time.Time, since time.Time) []time.Time {
+	i := 0
+	for i < len(calls) && !calls[i].After(since) {
+		i++
+	}
+	return calls[i:]
+}
+
+// setFile loads saved employee windows from path and saves them there from
+// now on. A missing or unreadable file starts empty.
+func (l *rateLimiter) setFile(path string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.path = path
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	var saved map[string][]time.Time
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return
+	}
+	for key, calls := range saved {
+		if strings.HasPrefix(key, rateLimitPerEmployee+":") {
+			l.windows[key] = calls
+		}
+	}
+}
+
+// saveLocked writes employee windows to the file, if one is set. Call times
+// older than the longest window are dropped. Caller must hold l.mu.
+func (l *rateLimiter) saveLocked(now time.Time) {
+	if l.path == "" {
+		return
+	}
+
+	saved := make(map[string][]time.Time)
+	for key, calls := range l.windows {
+		if !strings.HasPrefix(key, rateLimitPerEmployee+":") {
+			continue
+		}
+		if calls = pruneWindow(calls, now.Add(-maxRateLimitWindow)); len(calls) > 0 {
+			saved[key] = calls
+		}
+	}
+
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(l.path, data, 0600)
+}
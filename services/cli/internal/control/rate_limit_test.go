@@ -0,0 +1,96 @@
+package control
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rastrigin-systems/arfa/services/cli/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_SlidingWindow(t *testing.T) {
+	now := time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)
+	l := newRateLimiter()
+	l.now = func() time.Time { return now }
+	check := []rateLimitCheck{{key: "session:s1:pol-1", maxCalls: 2, window: time.Minute}}
+
+	_, _, ok := l.take(check)
+	assert.True(t, ok)
+	now = now.Add(20 * time.Second)
+	_, _, ok = l.take(check)
+	assert.True(t, ok)
+
+	// Full until the first call leaves the window
+	now = now.Add(20 * time.Second)
+	i, retryAfter, ok := l.take(check)
+	assert.False(t, ok)
+	assert.Equal(t, 0, i)
+	assert.Equal(t, 20*time.Second, retryAfter)
+
+	now = now.Add(21 * time.Second)
+	_, _, ok = l.take(check)
+	assert.True(t, ok)
+}
+
+func TestRateLimiter_BlockedCallIsNotCounted(t *testing.T) {
+	l := newRateLimiter()
+	loose := rateLimitCheck{key: "session:s1:pol-loose", maxCalls: 10, window: time.Minute}
+	strict := rateLimitCheck{key: "session:s1:pol-strict", maxCalls: 1, window: time.Minute}
+
+	_, _, ok := l.take([]rateLimitCheck{loose, strict})
+	require.True(t, ok)
+
+	i, _, ok := l.take([]rateLimitCheck{loose, strict})
+	assert.False(t, ok)
+	assert.Equal(t, 1, i)
+	assert.Len(t, l.windows[loose.key], 1, "a blocked call doesn't use up other limits")
+}
+
+func TestRateLimiter_EmployeeWindowsOutlastRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rate_limits.json")
+
+	l := newRateLimiter()
+	l.setFile(path)
+	_, _, ok := l.take([]rateLimitCheck{
+		{key: "employee:emp-1:pol-1", maxCalls: 1, window: time.Hour},
+		{key: "session:s1:pol-2", maxCalls: 1, window: time.Hour},
+	})
+	require.True(t, ok)
+
+	restarted := newRateLimiter()
+	restarted.setFile(path)
+
+	_, _, ok = restarted.take([]rateLimitCheck{{key: "employee:emp-1:pol-1", maxCalls: 1, window: time.Hour}})
+	assert.False(t, ok, "employee windows are saved")
+	_, _, ok = restarted.take([]rateLimitCheck{{key: "session:s1:pol-2", maxCalls: 1, window: time.Hour}})
+	assert.True(t, ok, "session windows are not")
+}
+
+func TestNewRateLimitRule(t *testing.T) {
+	rule, ok := newRateLimitRule(api.ToolPolicy{
+		ID:       "pol-1",
+		ToolName: "Bash",
+		Action:   api.ToolPolicyActionRateLimit,
+		Conditions: map[string]interface{}{
+			"rate_limit": map[string]interface{}{"max_calls": float64(20), "window_seconds": float64(60)},
+			"command":    "^git",
+		},
+	})
+	require.True(t, ok)
+	assert.Equal(t, 20, rule.MaxCalls)
+	assert.Equal(t, time.Minute, rule.Window)
+	assert.Equal(t, rateLimitPerSession, rule.Per)
+	assert.Equal(t, map[string]interface{}{"command": "^git"}, rule.Conditions)
+
+	for _, limit := range []map[string]interface{}{
+		nil,
+		{"max_calls": 0, "window_seconds": 60},
+		{"max_calls": 5, "window_seconds": 0},
+		{"max_calls": 5, "window_seconds": 60, "per": "team"},
+	} {
+		_, ok := newRateLimitRule(api.ToolPolicy{ToolName: "Bash", Conditions: map[string]interface{}{"rate_limit": limit}})
+		assert.False(t, ok, "%v", limit)
+	}
+}
@@ -0,0 +1,36 @@
+package control
+
+import (
+	"time"
+
+	"github.com/rastrigin-systems/arfa/pkg/policy"
+	"github.com/rastrigin-systems/arfa/services/cli/internal/api"
+)
+
+// The server sends every policy, scheduled or not, with the organization's
+// timezone, and pushes scheduled policies again as they start and stop. The
+// handler applies schedules itself on a timer, so a policy starts and stops
+// applying on time even while the connection is down; the pushes only make
+// the change land sooner.
+
+// ActivePolicies returns the policies that apply at now, reading schedule
+// windows in loc, and when that may next change (zero if it never will).
+// Policies whose schedule doesn't parse always apply.
+func ActivePolicies(policies []api.ToolPolicy, now time.Time, loc *time.Location) ([]api.ToolPolicy, time.Time) {
+	active := make([]api.ToolPolicy, 0, len(policies))
+	var next time.Time
+	for _, p := range policies {
+		schedule, err := policy.ParseSchedule(p.Conditions)
+		if err != nil || schedule == nil {
+			active = append(active, p)
+			continue
+		}
+		if schedule.ActiveAt(now, loc) {
+			active = append(active, p)
+		}
+		if at, ok := schedule.NextChange(now, loc); ok && (next.IsZero() || at.Before(next)) {
+			next = at
+		}
+	}
+	return active, next
+}
@@ -0,0 +1,49 @@
+package control
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rastrigin-systems/arfa/services/cli/internal/api"
+)
+
+func TestActivePolicies(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	policies := []api.ToolPolicy{
+		{ID: "always", ToolName: "Bash", Action: api.ToolPolicyActionAudit},
+		{ID: "ended", ToolName: "WebFetch", Action: api.ToolPolicyActionDeny,
+			Conditions: map[string]interface{}{"schedule": map[string]interface{}{"active_until": "2026-10-01T00:00:00Z"}}},
+		{ID: "starting", ToolName: "mcp__deploy__%", Action: api.ToolPolicyActionDeny,
+			Conditions: map[string]interface{}{"schedule": map[string]interface{}{"active_from": "2026-10-15T00:00:00Z"}}},
+	}
+
+	active, next := ActivePolicies(policies, now, time.UTC)
+	require.Len(t, active, 1)
+	assert.Equal(t, "always", active[0].ID)
+	assert.Equal(t, time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC), next)
+
+	active, next = ActivePolicies(policies, next, time.UTC)
+	assert.Len(t, active, 2)
+	assert.True(t, next.IsZero(), "nothing changes after the last boundary")
+}
+
+func TestActivePolicies_Windows(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	freeze := []api.ToolPolicy{{ID: "freeze", ToolName: "mcp__deploy__%", Action: api.ToolPolicyActionDeny,
+		Conditions: map[string]interface{}{"schedule": map[string]interface{}{
+			"windows": []interface{}{map[string]interface{}{"start": "0 16 * * 5", "end": "0 8 * * 1"}},
+		}}}}
+
+	// 2026-10-16 is a Friday; windows are read in the organization's timezone
+	active, next := ActivePolicies(freeze, time.Date(2026, 10, 16, 15, 0, 0, 0, berlin), berlin)
+	assert.Empty(t, active)
+	assert.True(t, next.Equal(time.Date(2026, 10, 16, 16, 0, 0, 0, berlin)))
+
+	active, next = ActivePolicies(freeze, next, berlin)
+	assert.Len(t, active, 1)
+	assert.True(t, next.Equal(time.Date(2026, 10, 19, 8, 0, 0, 0, berlin)))
+}
@@ -25,6 +25,9 @@ type ServiceConfig struct {
 	// PolicyClient configuration (optional, for real-time policy updates)
 	APIURL string // API base URL for WebSocket connection
 	Token  string // JWT token for authentication
+
+	// RateLimitFile keeps per-employee rate limit windows across restarts (optional)
+	RateLimitFile string
 }
 
 // Service is the main Control Service that orchestrates the pipeline.
@@ -36,6 +39,9 @@ type Service struct {
 	queue         *DiskQueue
 	policyClient  *PolicyClient
 	policyHandler *PolicyHandler
+	approvals     *Approvals
+	hosts         *InterceptHosts
+	budgets       *Budgets
 }
 
 // NewService creates a new Control Service.
@@ -80,8 +86,17 @@ func NewService(config ServiceConfig) (*Service, error) {
 	// Register policy handler (policies loaded via WebSocket when EnableRealtimePolicies is called)
 	policyHandler := NewPolicyHandler()
 	policyHandler.SetQueue(queue) // Enable logging of blocked tools
+	approvals := NewApprovals(DefaultApprovalTimeout)
+	policyHandler.SetApprovals(approvals)
+	if config.RateLimitFile != "" {
+		policyHandler.SetRateLimitFile(config.RateLimitFile)
+	}
 	pipeline.Register(policyHandler)
 
+	// Register budget handler (budgets arrive via WebSocket when EnableRealtimePolicies is called)
+	budgets := NewBudgets()
+	pipeline.Register(NewBudgetHandler(budgets, queue))
+
 	// Register tool call logger (extracts and logs tool_use events)
 	toolCallLogger := NewToolCallLoggerHandler(queue)
 	pipeline.Register(toolCallLogger)
@@ -93,6 +108,9 @@ func NewService(config ServiceConfig) (*Service, error) {
 		pipeline:      pipeline,
 		queue:         queue,
 		policyHandler: policyHandler,
+		approvals:     approvals,
+		hosts:         NewInterceptHosts(),
+		budgets:       budgets,
 	}, nil
 }
 
@@ -101,7 +119,8 @@ func (s *Service) SessionID() string {
 	return s.sessionID
 }
 
-// Context returns the handler context with ownership fields.
+// Context returns the session-level handler context with ownership fields.
+// Requests are processed with per-request copies of it (see HandlerContext.ForRequest).
 func (s *Service) Context() *HandlerContext {
 	return s.ctx
 }
@@ -118,14 +137,43 @@ func (s *Service) RegisterHandler(h Handler) {
 
 // HandleRequest processes an outgoing request through the pipeline.
 // Returns the result indicating whether to continue or block.
+// Each request gets its own HandlerContext, which is attached to the forwarded
+// request (ModifiedRequest) so HandleResponse can pick it up again.
 func (s *Service) HandleRequest(req *http.Request) Result {
-	return s.pipeline.ExecuteRequest(s.ctx, req)
+	hctx := s.ctx.ForRequest()
+	hctx.Provider = s.hosts.ProviderFor(req)
+
+	result := s.pipeline.ExecuteRequest(hctx, req)
+	if result.ShouldBlock() {
+		return result
+	}
+
+	forwarded := req
+	if result.ModifiedRequest != nil {
+		forwarded = result.ModifiedRequest
+	}
+	result.ModifiedRequest = forwarded.WithContext(WithHandlerContext(forwarded.Context(), hctx))
+
+	return result
 }
 
 // HandleResponse processes an incoming response through the pipeline.
 // Returns the result indicating whether to continue or block.
+// The response is processed with the context of the request that produced it.
 func (s *Service) HandleResponse(res *http.Response) Result {
-	return s.pipeline.ExecuteResponse(s.ctx, res)
+	var hctx *HandlerContext
+	if res.Request != nil {
+		hctx, _ = HandlerContextFrom(res.Request.Context())
+	}
+	if hctx == nil {
+		// Request wasn't seen by this service - no timing available
+		hctx = s.ctx.ForRequest()
+		hctx.RequestStart = time.Time{}
+		hctx.Provider = s.hosts.ProviderFor(res.Request)
+	}
+	hctx.ResponseStart = time.Now()
+
+	return s.pipeline.ExecuteResponse(hctx, res)
 }
 
 // Start starts the background workers (queue uploader).
@@ -167,17 +215,35 @@ func (s *Service) EnablePolicyBlocking(denyList map[string]string) {
 // This replaces file-based policy loading with live updates from the server.
 // Call this before Start() to enable real-time policy enforcement.
 func (s *Service) EnableRealtimePolicies(ctx context.Context, apiURL, token string) error {
+	// Grace period and fail mode use client defaults until the server's
+	// init message delivers the organization's enforcement settings.
 	clientConfig := PolicyClientConfig{
 		APIURL:           apiURL,
 		Token:            token,
-		GracePeriod:      5 * time.Minute,
 		ReconnectBackoff: 1 * time.Second,
 		MaxReconnectWait: 30 * time.Second,
 	}
 
 	s.policyClient = NewPolicyClient(clientConfig)
+	s.policyClient.SetOnInterceptHostsChanged(s.hosts.Set)
+	s.policyClient.SetBudgets(s.budgets)
 	s.policyHandler.SetPolicyClient(s.policyClient)
 
+	// Report paused tool calls so admins can decide remotely, and withdraw
+	// them once decided here or timed out
+	client := s.policyClient
+	client.SetOnApprovalDecision(func(decision ApprovalDecision) {
+		_ = s.approvals.Decide(decision)
+	})
+	s.approvals.OnRequest(func(req ApprovalRequest) {
+		_ = client.SendApprovalRequest(req)
+	})
+	s.approvals.OnResolved(func(req ApprovalRequest, decision ApprovalDecision) {
+		if decision.Source != ApprovalSourceRemote {
+			_ = client.SendApprovalResolved(req.ID)
+		}
+	})
+
 	// Start connection with retry in background
 	go s.policyClient.ConnectWithRetry(ctx)
 
@@ -197,6 +263,17 @@ func (s *Service) WaitForPolicies(ctx context.Context, timeout time.Duration) er
 	return s.policyClient.WaitReady(ctx)
 }
 
+// InterceptHosts returns the hosts the proxy intercepts, including any the
+// organization configured.
+func (s *Service) InterceptHosts() *InterceptHosts {
+	return s.hosts
+}
+
+// Approvals returns the tool calls waiting for an approval decision.
+func (s *Service) Approvals() *Approvals {
+	return s.approvals
+}
+
 // PolicyClient returns the policy client (for status checks).
 func (s *Service) PolicyClient() *PolicyClient {
 	return s.policyClient
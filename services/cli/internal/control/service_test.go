@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
@@ -267,3 +268,69 @@ func TestServiceConfig_Defaults(t *testing.T) {
 }
 
 // testHandler is defined in pipeline_test.go and reused here
+
+func TestService_ResponseUsesRequestContext(t *testing.T) {
+	svc, err := NewService(ServiceConfig{
+		EmployeeID: "emp-123",
+		OrgID:      "org-456",
+		QueueDir:   t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	var reqCtx, resCtx *HandlerContext
+	svc.RegisterHandler(&testHandler{
+		name:     "capture",
+		priority: 10,
+		onRequest: func(ctx *HandlerContext, req *http.Request) Result {
+			reqCtx = ctx
+			return ContinueResult()
+		},
+		onResponse: func(ctx *HandlerContext, res *http.Response) Result {
+			resCtx = ctx
+			return ContinueResult()
+		},
+	})
+
+	req := httptest.NewRequest("POST", "https://api.anthropic.com/v1/messages", nil)
+	req.Header.Set("User-Agent", "claude-cli/1.0.25 (external, cli)")
+	result := svc.HandleRequest(req)
+	require.NotNil(t, result.ModifiedRequest)
+
+	svc.HandleResponse(&http.Response{StatusCode: 200, Request: result.ModifiedRequest})
+
+	require.NotNil(t, reqCtx)
+	assert.Same(t, reqCtx, resCtx)
+	assert.NotEmpty(t, resCtx.RequestID)
+	assert.Equal(t, "claude-code", resCtx.ClientName)
+	assert.False(t, resCtx.ResponseStart.Before(resCtx.RequestStart))
+
+	// Session-level context is never mutated by requests
+	assert.Empty(t, svc.Context().ClientName)
+	assert.Empty(t, svc.Context().RequestID)
+}
+
+func TestService_ConcurrentRequestsHaveSeparateContexts(t *testing.T) {
+	svc, err := NewService(ServiceConfig{
+		EmployeeID: "emp-123",
+		OrgID:      "org-456",
+		QueueDir:   t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	agents := []string{"claude-cli/1.0.25", "Cursor/0.42.0", "aider/0.50.0"}
+	var wg sync.WaitGroup
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func(ua string) {
+			defer wg.Done()
+			req := httptest.NewRequest("POST", "https://api.anthropic.com/v1/messages", nil)
+			req.Header.Set("User-Agent", ua)
+			result := svc.HandleRequest(req)
+
+			hctx, ok := HandlerContextFrom(result.ModifiedRequest.Context())
+			require.True(t, ok)
+			assert.Equal(t, DetectClient(ua).Name, hctx.ClientName)
+		}(agents[i%len(agents)])
+	}
+	wg.Wait()
+}
@@ -0,0 +1,40 @@
+package control
+
+import (
+	"testing"
+
+	"github.com/rastrigin-systems/arfa/services/cli/internal/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyHandler_ShellCondition(t *testing.T) {
+	reason := "Force pushes are not allowed"
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{{
+		ToolName: "Bash",
+		Action:   api.ToolPolicyActionDeny,
+		Reason:   &reason,
+		Conditions: map[string]interface{}{
+			"command": map[string]interface{}{
+				"shell": map[string]interface{}{"command": "git", "args": []interface{}{"push"}, "flags": []interface{}{"--force|-f"}},
+			},
+		},
+	}})
+
+	got, blocked := h.evaluateConditions("Bash", `{"command":"git status && git push -f origin HEAD"}`)
+	assert.True(t, blocked)
+	assert.Equal(t, reason, got)
+
+	_, blocked = h.evaluateConditions("Bash", `{"command":"git push origin HEAD"}`)
+	assert.False(t, blocked)
+}
+
+func TestPolicyHandler_ShellCondition_Invalid(t *testing.T) {
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{{
+		ToolName:   "Bash",
+		Action:     api.ToolPolicyActionDeny,
+		Conditions: map[string]interface{}{"command": map[string]interface{}{"shell": map[string]interface{}{"flags": []interface{}{"-f"}}}},
+	}})
+
+	_, blocked := h.evaluateConditions("Bash", `{"command":"rm -f x"}`)
+	assert.False(t, blocked, "a condition without a command never matches")
+}
@@ -0,0 +1,247 @@
+package control
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// SSEEvent is a single server-sent event from a streaming LLM response.
+type SSEEvent struct {
+	Event string
+	Data  string
+
+	// raw holds the original bytes of a frame that a non-SSE codec passes
+	// through without decoding (such as an AWS event-stream exception).
+	raw []byte
+}
+
+// writeTo serializes the event in SSE wire format.
+func (e SSEEvent) writeTo(w *bytes.Buffer) {
+	if e.Event != "" {
+		w.WriteString("event: ")
+		w.WriteString(e.Event)
+		w.WriteString("\n")
+	}
+	for _, line := range strings.Split(e.Data, "\n") {
+		w.WriteString("data: ")
+		w.WriteString(line)
+		w.WriteString("\n")
+	}
+	w.WriteString("\n")
+}
+
+// SSEProcessor observes or rewrites the events of one streaming response.
+// Handlers attach processors in HandleResponse via AttachSSEProcessor instead of
+// reading the body, so events reach the client as soon as every processor lets
+// them through.
+type SSEProcessor interface {
+	// ProcessEvent is called for each event in stream order. It returns the events
+	// to forward downstream: nil drops the event, and several events may be
+	// returned to flush previously held ones.
+	ProcessEvent(event SSEEvent) []SSEEvent
+
+	// Finish is called once when the upstream body ends, with the read error if
+	// any (nil on a clean EOF), or when the body is closed first, with
+	// errStreamClosed. It returns events that are still held.
+	Finish(err error) []SSEEvent
+}
+
+// errStreamClosed is passed to Finish when the body is closed before the
+// upstream stream ends, as when the client disconnects mid-response.
+var errStreamClosed = errors.New("event stream closed before it ended")
+
+// eventCodec reads events from an upstream body and writes them back in the
+// same wire format.
+type eventCodec interface {
+	// readEvent returns the next upstream event, or an error (io.EOF at the end).
+	readEvent() (SSEEvent, error)
+
+	// writeEvent serializes an event for the client.
+	writeEvent(w *bytes.Buffer, ev SSEEvent)
+}
+
+// SSEStream is a response body that parses an upstream event stream once and
+// runs every event through a chain of processors, in the order they were
+// attached. Output is produced incrementally as the client reads.
+type SSEStream struct {
+	src        io.ReadCloser
+	codec      eventCodec
+	processors []SSEProcessor
+
+	out  bytes.Buffer
+	err  error
+	done bool
+}
+
+// NewSSEStream wraps an SSE body for incremental processing.
+func NewSSEStream(body io.ReadCloser) *SSEStream {
+	return newEventStream(body, newSSECodec(body))
+}
+
+// newEventStream wraps a body whose events are framed by codec.
+func newEventStream(body io.ReadCloser, codec eventCodec) *SSEStream {
+	return &SSEStream{
+		src:   body,
+		codec: codec,
+	}
+}
+
+// AttachSSEProcessor adds a processor to the response's event stream, wrapping
+// the body in an SSEStream on first use so all handlers share a single parse.
+func AttachSSEProcessor(res *http.Response, p SSEProcessor) *SSEStream {
+	stream, ok := res.Body.(*SSEStream)
+	if !ok {
+		if isAWSEventStream(res) {
+			stream = newEventStream(res.Body, newAWSEventStreamCodec(res.Body))
+		} else {
+			stream = NewSSEStream(res.Body)
+		}
+		res.Body = stream
+		// Length is unknown once events can be rewritten
+		res.ContentLength = -1
+		res.Header.Del("Content-Length")
+	}
+	stream.processors = append(stream.processors, p)
+	return stream
+}
+
+// isEventStream reports whether the response is a stream of events handlers can
+// process: server-sent events, or AWS event-stream frames from Bedrock.
+func isEventStream(res *http.Response) bool {
+	return strings.Contains(res.Header.Get("Content-Type"), "text/event-stream") || isAWSEventStream(res)
+}
+
+// Read implements io.Reader, pulling upstream events until there is output.
+func (s *SSEStream) Read(p []byte) (int, error) {
+	for s.out.Len() == 0 && !s.done {
+		s.step()
+	}
+	if s.out.Len() > 0 {
+		return s.out.Read(p)
+	}
+	if s.err != nil {
+		return 0, s.err
+	}
+	return 0, io.EOF
+}
+
+// Close closes the upstream body. Processors of a stream closed before it
+// ended are finished then, so they still see the events delivered so far.
+func (s *SSEStream) Close() error {
+	if !s.done {
+		s.done = true
+		s.finish(errStreamClosed)
+	}
+	return s.src.Close()
+}
+
+// step reads one event from upstream and runs it through the processors.
+func (s *SSEStream) step() {
+	ev, err := s.codec.readEvent()
+	if err == nil {
+		s.emit(0, []SSEEvent{ev})
+		return
+	}
+
+	// Upstream ended - flush processors
+	if err != io.EOF {
+		s.err = err
+		s.finish(err)
+	} else {
+		s.finish(nil)
+	}
+	s.done = true
+}
+
+// emit passes events through processors starting at index from and writes
+// whatever survives to the output buffer.
+func (s *SSEStream) emit(from int, events []SSEEvent) {
+	for i := from; i < len(s.processors) && len(events) > 0; i++ {
+		var next []SSEEvent
+		for _, ev := range events {
+			next = append(next, s.processors[i].ProcessEvent(ev)...)
+		}
+		events = next
+	}
+	for _, ev := range events {
+		s.codec.writeEvent(&s.out, ev)
+	}
+}
+
+// finish flushes each processor in order, feeding its held events to the rest
+// of the chain before finishing the next one.
+func (s *SSEStream) finish(err error) {
+	for i, p := range s.processors {
+		s.emit(i+1, p.Finish(err))
+	}
+}
+
+// sseCodec parses and writes text/event-stream framing.
+type sseCodec struct {
+	reader  *bufio.Reader
+	current SSEEvent
+	pending bool
+	err     error
+}
+
+// newSSECodec creates a codec reading SSE lines from body.
+func newSSECodec(body io.Reader) *sseCodec {
+	return &sseCodec{reader: bufio.NewReader(body)}
+}
+
+// readEvent reads lines until an event is complete. An unterminated event at
+// the end of the body is still returned.
+func (c *sseCodec) readEvent() (SSEEvent, error) {
+	for c.err == nil {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			c.err = err
+		}
+		if line != "" && c.parseLine(strings.TrimRight(line, "\r\n")) {
+			return c.take(), nil
+		}
+	}
+
+	if c.pending {
+		return c.take(), nil
+	}
+	return SSEEvent{}, c.err
+}
+
+// parseLine handles a single SSE line, reporting whether it completed an event.
+func (c *sseCodec) parseLine(line string) bool {
+	switch {
+	case strings.HasPrefix(line, "event: "):
+		c.current.Event = strings.TrimPrefix(line, "event: ")
+		c.current.Data = ""
+		c.pending = true
+	case strings.HasPrefix(line, "data: "):
+		data := strings.TrimPrefix(line, "data: ")
+		if c.current.Data != "" {
+			c.current.Data += "\n" + data
+		} else {
+			c.current.Data = data
+		}
+		c.pending = true
+	case line == "" && c.pending:
+		return true
+	}
+	return false
+}
+
+// take returns the current event and resets the parser.
+func (c *sseCodec) take() SSEEvent {
+	ev := c.current
+	c.current = SSEEvent{}
+	c.pending = false
+	return ev
+}
+
+// writeEvent writes the event in SSE format.
+func (c *sseCodec) writeEvent(w *bytes.Buffer, ev SSEEvent) {
+	ev.writeTo(w)
+}
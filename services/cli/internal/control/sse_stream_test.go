@@ -0,0 +1,224 @@
+package control
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rastrigin-systems/arfa/services/cli/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingProcessor records events it sees and forwards them unchanged.
+type recordingProcessor struct {
+	events    []SSEEvent
+	finished  bool
+	finishErr error
+}
+
+func (p *recordingProcessor) ProcessEvent(ev SSEEvent) []SSEEvent {
+	p.events = append(p.events, ev)
+	return []SSEEvent{ev}
+}
+
+func (p *recordingProcessor) Finish(err error) []SSEEvent {
+	p.finished = true
+	p.finishErr = err
+	return nil
+}
+
+// readEvent reads one SSE event (up to the blank line) from r.
+func readEvent(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+	var sb strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		sb.WriteString(line)
+		if line == "\n" {
+			return sb.String()
+		}
+	}
+}
+
+func TestSSEStream_PassThrough(t *testing.T) {
+	input := "event: message_start\ndata: {\"type\":\"message_start\"}\n\nevent: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"
+
+	stream := NewSSEStream(io.NopCloser(strings.NewReader(input)))
+	rec := &recordingProcessor{}
+	stream.processors = append(stream.processors, rec)
+
+	output, err := io.ReadAll(stream)
+	require.NoError(t, err)
+
+	assert.Equal(t, input, string(output))
+	require.Len(t, rec.events, 2)
+	assert.Equal(t, "message_start", rec.events[0].Event)
+	assert.True(t, rec.finished)
+}
+
+func TestSSEStream_UnterminatedEventDispatchedAtEOF(t *testing.T) {
+	stream := NewSSEStream(io.NopCloser(strings.NewReader("event: ping\ndata: {}")))
+	rec := &recordingProcessor{}
+	stream.processors = append(stream.processors, rec)
+
+	output, err := io.ReadAll(stream)
+	require.NoError(t, err)
+
+	assert.Equal(t, "event: ping\ndata: {}\n\n", string(output))
+	require.Len(t, rec.events, 1)
+}
+
+func TestSSEStream_CloseFinishesProcessors(t *testing.T) {
+	input := "event: message_start\ndata: {\"type\":\"message_start\"}\n\nevent: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"
+
+	stream := NewSSEStream(io.NopCloser(strings.NewReader(input)))
+	rec := &recordingProcessor{}
+	stream.processors = append(stream.processors, rec)
+
+	readEvent(t, bufio.NewReaderSize(stream, 16))
+	require.NoError(t, stream.Close())
+
+	assert.True(t, rec.finished, "a stream closed early is finished on close")
+	assert.ErrorIs(t, rec.finishErr, errStreamClosed)
+
+	// Closing a stream that already ended doesn't finish it again
+	stream = NewSSEStream(io.NopCloser(strings.NewReader(input)))
+	rec = &recordingProcessor{}
+	stream.processors = append(stream.processors, rec)
+	_, err := io.ReadAll(stream)
+	require.NoError(t, err)
+	require.NoError(t, stream.Close())
+	assert.NoError(t, rec.finishErr)
+}
+
+func TestAttachSSEProcessor_SharesOneStream(t *testing.T) {
+	res := &http.Response{
+		Header:        http.Header{"Content-Type": []string{"text/event-stream"}, "Content-Length": []string{"10"}},
+		ContentLength: 10,
+		Body:          io.NopCloser(strings.NewReader("event: ping\ndata: {}\n\n")),
+	}
+
+	first := AttachSSEProcessor(res, &recordingProcessor{})
+	second := AttachSSEProcessor(res, &recordingProcessor{})
+
+	assert.Same(t, first, second)
+	assert.Len(t, first.processors, 2)
+	assert.Equal(t, int64(-1), res.ContentLength)
+	assert.Empty(t, res.Header.Get("Content-Length"))
+}
+
+// TestPolicyHandler_StreamsTextBeforeToolCompletes verifies that text deltas reach
+// the client while a conditionally-checked tool_use block is still held.
+func TestPolicyHandler_StreamsTextBeforeToolCompletes(t *testing.T) {
+	reason := "Dangerous command"
+	h := NewPolicyHandlerWithPolicies([]api.ToolPolicy{
+		{
+			ToolName:   "Bash",
+			Action:     api.ToolPolicyActionDeny,
+			Reason:     &reason,
+			Conditions: map[string]interface{}{"command": `rm\s+-rf`},
+		},
+	})
+	ctx := NewHandlerContext("emp-1", "org-1", "sess-1")
+
+	upstream, writer := io.Pipe()
+	res := &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+		Body:       upstream,
+	}
+	result := h.HandleResponse(ctx, res)
+	require.NotNil(t, result.ModifiedResponse)
+
+	client := bufio.NewReader(result.ModifiedResponse.Body)
+
+	// Text delta is delivered before the rest of the stream exists
+	go func() {
+		_, _ = io.WriteString(writer, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hello\"}}\n\n")
+	}()
+	assert.Contains(t, readEvent(t, client), "Hello")
+
+	// Tool block is held until content_block_stop, then blocked
+	go func() {
+		_, _ = io.WriteString(writer, "event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":1,\"content_block\":{\"type\":\"tool_use\",\"id\":\"toolu_1\",\"name\":\"Bash\",\"input\":{}}}\n\n")
+		_, _ = io.WriteString(writer, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"{\\\"command\\\":\\\"rm -rf /\\\"}\"}}\n\n")
+		_, _ = io.WriteString(writer, "event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":1}\n\n")
+		_ = writer.Close()
+	}()
+
+	rest, err := io.ReadAll(client)
+	require.NoError(t, err)
+	assert.NotContains(t, string(rest), `"type":"tool_use"`)
+	assert.Contains(t, string(rest), "TOOL BLOCKED")
+}
+
+// TestPipeline_SSEHandlersShareStream verifies the policy and tool-call loggers
+// run over one parsed stream and the logger sees the policy's rewritten output.
+func TestPipeline_SSEHandlersShareStream(t *testing.T) {
+	queue := &mockToolLoggerQueue{}
+	policy := NewPolicyHandlerWithDenyList(map[string]string{"Bash": "no shell"})
+	policy.SetQueue(queue)
+
+	p := NewPipeline()
+	p.Register(policy)
+	p.Register(NewLoggerHandler(queue))
+	p.Register(NewToolCallLoggerHandler(queue))
+
+	sseStream := `event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"Bash","input":{}}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_2","name":"Read","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"file_path\":\"/a\"}"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":1}
+
+`
+	req, _ := http.NewRequest("POST", "https://api.anthropic.com/v1/messages", nil)
+	res := &http.Response{
+		StatusCode: 200,
+		Request:    req,
+		Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+		Body:       io.NopCloser(strings.NewReader(sseStream)),
+	}
+
+	result := p.ExecuteResponse(NewHandlerContext("emp-1", "org-1", "sess-1"), res)
+	require.NotNil(t, result.ModifiedResponse)
+
+	stream, ok := result.ModifiedResponse.Body.(*SSEStream)
+	require.True(t, ok)
+	assert.Len(t, stream.processors, 3)
+
+	body := drainBody(t, result.ModifiedResponse)
+	assert.Contains(t, string(body), "TOOL BLOCKED")
+
+	// One blocked Bash entry, one allowed Read entry, one api_response
+	var toolCalls, responses int
+	for _, entry := range queue.Entries() {
+		switch entry.EventType {
+		case "tool_call":
+			toolCalls++
+			if entry.Payload["tool_name"] == "Read" {
+				assert.Equal(t, false, entry.Payload["blocked"])
+			} else {
+				assert.Equal(t, "Bash", entry.Payload["tool_name"])
+				assert.Equal(t, true, entry.Payload["blocked"])
+			}
+		case "api_response":
+			responses++
+			assert.Contains(t, entry.Payload["body"], "TOOL BLOCKED")
+		}
+	}
+	assert.Equal(t, 2, toolCalls)
+	assert.Equal(t, 1, responses)
+}
@@ -1,17 +1,14 @@
 package control
 
 import (
-	"bufio"
-	"bytes"
 	"encoding/json"
-	"io"
 	"net/http"
 	"strings"
 	"time"
 )
 
-// ToolCallLoggerHandler extracts tool_use events from SSE streams and logs them.
-// This provides structured visibility into what tools Claude is invoking.
+// ToolCallLoggerHandler extracts tool calls from SSE streams and logs them.
+// This provides structured visibility into what tools the agent is invoking.
 type ToolCallLoggerHandler struct {
 	queue LoggerQueue
 }
@@ -39,130 +36,84 @@ func (h *ToolCallLoggerHandler) HandleRequest(ctx *HandlerContext, req *http.Req
 	return ContinueResult()
 }
 
-// HandleResponse parses SSE stream and logs tool_use events.
+// HandleResponse observes the SSE stream and logs tool_use events as each
+// block completes. The body is not consumed here; logging happens as the
+// client reads the stream.
 func (h *ToolCallLoggerHandler) HandleResponse(ctx *HandlerContext, res *http.Response) Result {
-	if res == nil || res.Body == nil {
+	if res == nil || res.Body == nil || h.queue == nil {
 		return ContinueResult()
 	}
 
 	// Only process SSE streams
-	contentType := res.Header.Get("Content-Type")
-	if !strings.Contains(contentType, "text/event-stream") {
+	if !isEventStream(res) {
 		return ContinueResult()
 	}
 
-	// Read entire body
-	bodyBytes, err := io.ReadAll(res.Body)
-	_ = res.Body.Close()
-	if err != nil {
-		// Restore empty body and continue
-		res.Body = io.NopCloser(bytes.NewReader([]byte{}))
-		return ContinueResult()
+	provider := contextProvider(ctx, res.Request)
+	AttachSSEProcessor(res, &toolCallStreamProcessor{
+		h:            h,
+		ctx:          ctx,
+		provider:     provider,
+		parser:       provider.NewStreamParser(),
+		pendingCalls: make(map[int]*pendingToolCall),
+	})
+
+	return Result{
+		Action:           ActionContinue,
+		ModifiedResponse: res,
 	}
-
-	// Parse and log tool calls
-	h.extractAndLogToolCalls(ctx, bodyBytes)
-
-	// Restore body for downstream handlers
-	res.Body = io.NopCloser(bytes.NewReader(bodyBytes))
-	res.ContentLength = int64(len(bodyBytes))
-
-	return ContinueResult()
 }
 
-// pendingToolCall tracks tool_use blocks during SSE parsing.
+// pendingToolCall tracks tool calls during SSE parsing.
 type pendingToolCall struct {
 	toolName  string
 	toolID    string
 	inputJSON strings.Builder
 }
 
-// extractAndLogToolCalls parses SSE events and logs each tool_use block.
-func (h *ToolCallLoggerHandler) extractAndLogToolCalls(ctx *HandlerContext, data []byte) {
-	if h.queue == nil {
-		return
-	}
-
-	scanner := bufio.NewScanner(bytes.NewReader(data))
-	scanner.Buffer(make([]byte, 1024*1024), 1024*1024) // 1MB buffer
-
-	var currentEvent string
-	var currentData string
-	pendingCalls := make(map[int]*pendingToolCall) // index -> pending call
-
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		if strings.HasPrefix(line, "event: ") {
-			currentEvent = strings.TrimPrefix(line, "event: ")
-			currentData = ""
-		} else if strings.HasPrefix(line, "data: ") {
-			currentData = strings.TrimPrefix(line, "data: ")
-		} else if line == "" && currentEvent != "" {
-			// End of SSE event - process it
-			h.processSSEEvent(currentEvent, currentData, pendingCalls, ctx)
-			currentEvent = ""
-			currentData = ""
-		}
-	}
+// toolCallStreamProcessor logs tool calls from one SSE response without
+// altering the stream.
+type toolCallStreamProcessor struct {
+	h            *ToolCallLoggerHandler
+	ctx          *HandlerContext
+	provider     Provider
+	parser       StreamParser
+	pendingCalls map[int]*pendingToolCall // tool index -> pending call
 }
 
-// processSSEEvent handles a single SSE event during parsing.
-func (h *ToolCallLoggerHandler) processSSEEvent(event, data string, pendingCalls map[int]*pendingToolCall, ctx *HandlerContext) {
-	// Clean up data - Anthropic SSE sometimes has trailing whitespace and extra braces
-	data = cleanSSEData(data)
-
-	switch event {
-	case "content_block_start":
-		var block struct {
-			Index        int `json:"index"`
-			ContentBlock struct {
-				Type string `json:"type"`
-				ID   string `json:"id"`
-				Name string `json:"name"`
-			} `json:"content_block"`
-		}
-		if err := json.Unmarshal([]byte(data), &block); err == nil {
-			if block.ContentBlock.Type == "tool_use" {
-				pendingCalls[block.Index] = &pendingToolCall{
-					toolName: block.ContentBlock.Name,
-					toolID:   block.ContentBlock.ID,
-				}
+// ProcessEvent records tool call events and forwards the event unchanged.
+func (p *toolCallStreamProcessor) ProcessEvent(ev SSEEvent) []SSEEvent {
+	for _, te := range p.parser.Parse(ev) {
+		switch te.Type {
+		case ToolStart:
+			p.pendingCalls[te.Index] = &pendingToolCall{
+				toolName: te.ToolName,
+				toolID:   te.ToolID,
 			}
-		}
 
-	case "content_block_delta":
-		var delta struct {
-			Index int `json:"index"`
-			Delta struct {
-				Type        string `json:"type"`
-				PartialJSON string `json:"partial_json"`
-			} `json:"delta"`
-		}
-		if err := json.Unmarshal([]byte(data), &delta); err == nil {
-			if pending, ok := pendingCalls[delta.Index]; ok {
-				if delta.Delta.Type == "input_json_delta" {
-					pending.inputJSON.WriteString(delta.Delta.PartialJSON)
-				}
+		case ToolInput:
+			if pending, ok := p.pendingCalls[te.Index]; ok {
+				pending.inputJSON.WriteString(te.Input)
 			}
-		}
 
-	case "content_block_stop":
-		var stop struct {
-			Index int `json:"index"`
-		}
-		if err := json.Unmarshal([]byte(data), &stop); err == nil {
-			if pending, ok := pendingCalls[stop.Index]; ok {
+		case ToolStop:
+			if pending, ok := p.pendingCalls[te.Index]; ok {
 				// Tool call complete - log it
-				h.logToolCall(ctx, pending)
-				delete(pendingCalls, stop.Index)
+				p.h.logToolCall(p.ctx, p.provider, pending)
+				delete(p.pendingCalls, te.Index)
 			}
 		}
 	}
+	return []SSEEvent{ev}
+}
+
+// Finish discards tool calls that never completed.
+func (p *toolCallStreamProcessor) Finish(err error) []SSEEvent {
+	return nil
 }
 
 // logToolCall creates and enqueues a log entry for a tool call.
-func (h *ToolCallLoggerHandler) logToolCall(ctx *HandlerContext, call *pendingToolCall) {
+func (h *ToolCallLoggerHandler) logToolCall(ctx *HandlerContext, provider Provider, call *pendingToolCall) {
 	// Parse accumulated JSON input
 	var toolInput map[string]interface{}
 	inputStr := call.inputJSON.String()
@@ -186,6 +137,7 @@ func (h *ToolCallLoggerHandler) logToolCall(ctx *HandlerContext, call *pendingTo
 			"tool_id":    call.toolID,
 			"tool_input": toolInput,
 			"blocked":    false,
+			"provider":   string(provider.Name()),
 		},
 	}
 
@@ -30,6 +30,15 @@ func (q *mockToolLoggerQueue) Entries() []LogEntry {
 	return append([]LogEntry{}, q.entries...)
 }
 
+// drainBody reads the response body to the end, as the proxy does when
+// forwarding it. Streaming handlers log as events are read.
+func drainBody(t *testing.T, res *http.Response) []byte {
+	t.Helper()
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return body
+}
+
 func TestNewToolCallLoggerHandler(t *testing.T) {
 	queue := &mockToolLoggerQueue{}
 	h := NewToolCallLoggerHandler(queue)
@@ -94,6 +103,7 @@ data: {"type":"content_block_stop","index":0}
 	}
 
 	result := h.HandleResponse(ctx, res)
+	drainBody(t, res)
 
 	assert.Equal(t, ActionContinue, result.Action)
 
@@ -148,6 +158,7 @@ data: {"type":"content_block_stop","index":1}
 	}
 
 	result := h.HandleResponse(ctx, res)
+	drainBody(t, res)
 
 	assert.Equal(t, ActionContinue, result.Action)
 
@@ -186,6 +197,7 @@ data: {"type":"content_block_stop","index":0}
 	}
 
 	result := h.HandleResponse(ctx, res)
+	drainBody(t, res)
 
 	assert.Equal(t, ActionContinue, result.Action)
 	assert.Empty(t, queue.Entries()) // No tool calls logged
@@ -211,6 +223,7 @@ data: {"type":"content_block_stop","index":0}
 	}
 
 	result := h.HandleResponse(ctx, res)
+	drainBody(t, res)
 
 	assert.Equal(t, ActionContinue, result.Action)
 
@@ -245,6 +258,7 @@ data: {"type":"content_block_stop","index":0}
 	}
 
 	result := h.HandleResponse(ctx, res)
+	drainBody(t, res)
 
 	assert.Equal(t, ActionContinue, result.Action)
 
@@ -389,6 +403,7 @@ data: {"type":"content_block_stop","index":0}
 	}
 
 	result := h.HandleResponse(ctx, res)
+	drainBody(t, res)
 
 	assert.Equal(t, ActionContinue, result.Action)
 
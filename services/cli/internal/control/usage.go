@@ -0,0 +1,35 @@
+package control
+
+import "github.com/rastrigin-systems/arfa/pkg/pricing"
+
+// Usage is the token usage an LLM API reports for one response.
+type Usage struct {
+	Model        string
+	InputTokens  int // Including cached prompt tokens
+	OutputTokens int
+}
+
+// IsZero reports whether no usage was found.
+func (u Usage) IsZero() bool {
+	return u.InputTokens == 0 && u.OutputTokens == 0
+}
+
+// Tokens returns the input and output tokens together.
+func (u Usage) Tokens() int {
+	return u.InputTokens + u.OutputTokens
+}
+
+// Cost returns the estimated cost of the usage in USD.
+func (u Usage) Cost() float64 {
+	return pricing.EstimateCost(u.Model, u.InputTokens, u.OutputTokens)
+}
+
+// merge folds the usage reported by a later event of the same stream into u.
+// Streams report running totals, so the larger count wins.
+func (u *Usage) merge(other Usage) {
+	if other.Model != "" {
+		u.Model = other.Model
+	}
+	u.InputTokens = max(u.InputTokens, other.InputTokens)
+	u.OutputTokens = max(u.OutputTokens, other.OutputTokens)
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/rastrigin-systems/arfa/pkg/pricing"
 	"github.com/rastrigin-systems/arfa/services/cli/internal/types"
 )
 
@@ -254,7 +255,7 @@ func (f *Formatter) calculateSummary(entries []types.ClassifiedLogEntry) types.S
 	}
 
 	// Estimate cost (rough estimates based on Claude pricing)
-	summary.CostEstimate = estimateCost(summary.Model, summary.TokensInput, summary.TokensOutput)
+	summary.CostEstimate = pricing.EstimateCost(summary.Model, summary.TokensInput, summary.TokensOutput)
 
 	return summary
 }
@@ -269,30 +270,3 @@ func truncateString(s string, maxLen int) string {
 	}
 	return s[:maxLen-3] + "..."
 }
-
-// estimateCost returns estimated cost in USD based on model and tokens
-func estimateCost(model string, inputTokens, outputTokens int) float64 {
-	// Pricing per 1M tokens (as of 2024)
-	var inputPrice, outputPrice float64
-
-	switch {
-	case strings.Contains(model, "opus"):
-		inputPrice = 15.0  // $15 per 1M input
-		outputPrice = 75.0 // $75 per 1M output
-	case strings.Contains(model, "sonnet"):
-		inputPrice = 3.0   // $3 per 1M input
-		outputPrice = 15.0 // $15 per 1M output
-	case strings.Contains(model, "haiku"):
-		inputPrice = 0.25  // $0.25 per 1M input
-		outputPrice = 1.25 // $1.25 per 1M output
-	default:
-		// Default to sonnet pricing
-		inputPrice = 3.0
-		outputPrice = 15.0
-	}
-
-	inputCost := (float64(inputTokens) / 1_000_000) * inputPrice
-	outputCost := (float64(outputTokens) / 1_000_000) * outputPrice
-
-	return inputCost + outputCost
-}
@@ -0,0 +1,200 @@
+package logparser
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/rastrigin-systems/arfa/services/cli/internal/types"
+)
+
+// GeminiParser parses Google Gemini generateContent request/response JSON
+type GeminiParser struct{}
+
+// NewGeminiParser creates a new Gemini parser
+func NewGeminiParser() *GeminiParser {
+	return &GeminiParser{}
+}
+
+// Provider returns the provider this parser handles
+func (p *GeminiParser) Provider() types.LogProvider {
+	return types.LogProviderGoogle
+}
+
+// geminiRequest represents a generateContent request.
+// Gemini CLI's Code Assist endpoint wraps it as {"model": ..., "request": {...}}.
+type geminiRequest struct {
+	Model    string           `json:"model"`
+	Contents []geminiContent  `json:"contents"`
+	Request  *json.RawMessage `json:"request,omitempty"`
+}
+
+// geminiContent represents a turn in the conversation
+type geminiContent struct {
+	Role  string       `json:"role"`
+	Parts []geminiPart `json:"parts"`
+}
+
+// geminiPart represents one part of a turn
+type geminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	Thought          bool                    `json:"thought,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+// geminiFunctionCall represents a tool call made by the model
+type geminiFunctionCall struct {
+	ID   string         `json:"id,omitempty"`
+	Name string         `json:"name"`
+	Args map[string]any `json:"args,omitempty"`
+}
+
+// geminiFunctionResponse represents a tool result sent back to the model
+type geminiFunctionResponse struct {
+	ID       string          `json:"id,omitempty"`
+	Name     string          `json:"name"`
+	Response json.RawMessage `json:"response,omitempty"`
+}
+
+// geminiResponse represents a generateContent response.
+// The Code Assist endpoint wraps it as {"response": {...}}.
+type geminiResponse struct {
+	Candidates    []geminiCandidate `json:"candidates"`
+	UsageMetadata geminiUsage       `json:"usageMetadata"`
+	ModelVersion  string            `json:"modelVersion"`
+	Error         *geminiError      `json:"error,omitempty"`
+	Response      *json.RawMessage  `json:"response,omitempty"`
+}
+
+// geminiCandidate represents one generated candidate
+type geminiCandidate struct {
+	Content      geminiContent `json:"content"`
+	FinishReason string        `json:"finishReason"`
+}
+
+// geminiUsage represents token usage
+type geminiUsage struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+}
+
+// geminiError represents an API error
+type geminiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+// ParseRequest parses a Gemini generateContent request body
+func (p *GeminiParser) ParseRequest(body []byte) ([]types.ClassifiedLogEntry, error) {
+	var req geminiRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("failed to parse Gemini request: %w", err)
+	}
+
+	// Unwrap Code Assist requests
+	if wrapped := req.Request; wrapped != nil {
+		model := req.Model
+		req = geminiRequest{}
+		if err := json.Unmarshal(*wrapped, &req); err != nil {
+			return nil, fmt.Errorf("failed to parse Gemini request: %w", err)
+		}
+		if req.Model == "" {
+			req.Model = model
+		}
+	}
+
+	var entries []types.ClassifiedLogEntry
+
+	// Only the last user turn is new: either a prompt or tool results
+	for i := len(req.Contents) - 1; i >= 0; i-- {
+		content := req.Contents[i]
+		if content.Role != "user" {
+			continue
+		}
+
+		for _, part := range content.Parts {
+			switch {
+			case part.FunctionResponse != nil:
+				entries = append(entries, types.ClassifiedLogEntry{
+					EntryType:  types.LogTypeToolResult,
+					Provider:   types.LogProviderGoogle,
+					Model:      req.Model,
+					ToolName:   part.FunctionResponse.Name,
+					ToolID:     part.FunctionResponse.ID,
+					ToolOutput: string(part.FunctionResponse.Response),
+				})
+			case part.Text != "":
+				entries = append(entries, types.ClassifiedLogEntry{
+					EntryType: types.LogTypeUserPrompt,
+					Provider:  types.LogProviderGoogle,
+					Model:     req.Model,
+					Content:   part.Text,
+				})
+			}
+		}
+		break
+	}
+
+	return entries, nil
+}
+
+// ParseResponse parses a Gemini generateContent response body
+func (p *GeminiParser) ParseResponse(body []byte) ([]types.ClassifiedLogEntry, error) {
+	var resp geminiResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse Gemini response: %w", err)
+	}
+
+	// Unwrap Code Assist responses
+	if wrapped := resp.Response; wrapped != nil {
+		resp = geminiResponse{}
+		if err := json.Unmarshal(*wrapped, &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse Gemini response: %w", err)
+		}
+	}
+
+	if resp.Error != nil {
+		entry := types.ClassifiedLogEntry{
+			EntryType:    types.LogTypeError,
+			Provider:     types.LogProviderGoogle,
+			ErrorCode:    resp.Error.Status,
+			ErrorMessage: resp.Error.Message,
+		}
+		return []types.ClassifiedLogEntry{entry}, nil
+	}
+
+	var entries []types.ClassifiedLogEntry
+
+	// Clients request a single candidate
+	if len(resp.Candidates) == 0 {
+		return entries, nil
+	}
+
+	for _, part := range resp.Candidates[0].Content.Parts {
+		switch {
+		case part.FunctionCall != nil:
+			entries = append(entries, types.ClassifiedLogEntry{
+				EntryType:    types.LogTypeToolCall,
+				Provider:     types.LogProviderGoogle,
+				Model:        resp.ModelVersion,
+				ToolName:     part.FunctionCall.Name,
+				ToolID:       part.FunctionCall.ID,
+				ToolInput:    part.FunctionCall.Args,
+				TokensInput:  resp.UsageMetadata.PromptTokenCount,
+				TokensOutput: resp.UsageMetadata.CandidatesTokenCount,
+			})
+		case part.Text != "" && !part.Thought:
+			entries = append(entries, types.ClassifiedLogEntry{
+				EntryType:    types.LogTypeAIText,
+				Provider:     types.LogProviderGoogle,
+				Model:        resp.ModelVersion,
+				Content:      part.Text,
+				TokensInput:  resp.UsageMetadata.PromptTokenCount,
+				TokensOutput: resp.UsageMetadata.CandidatesTokenCount,
+			})
+		}
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,136 @@
+package logparser
+
+import (
+	"testing"
+
+	"github.com/rastrigin-systems/arfa/services/cli/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeminiParser_Provider(t *testing.T) {
+	parser := NewGeminiParser()
+	assert.Equal(t, types.LogProviderGoogle, parser.Provider())
+}
+
+func TestGeminiParser_ParseRequest_LastUserTurn(t *testing.T) {
+	parser := NewGeminiParser()
+
+	requestBody := `{
+		"contents": [
+			{"role": "user", "parts": [{"text": "Fix the bug in auth.go"}]},
+			{"role": "model", "parts": [{"text": "Done."}]},
+			{"role": "user", "parts": [{"text": "Now add tests"}]}
+		]
+	}`
+
+	entries, err := parser.ParseRequest([]byte(requestBody))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	assert.Equal(t, types.LogTypeUserPrompt, entries[0].EntryType)
+	assert.Equal(t, "Now add tests", entries[0].Content)
+	assert.Equal(t, types.LogProviderGoogle, entries[0].Provider)
+}
+
+func TestGeminiParser_ParseRequest_CodeAssistFunctionResponse(t *testing.T) {
+	parser := NewGeminiParser()
+
+	// Gemini CLI wraps the request for the Code Assist endpoint
+	requestBody := `{
+		"model": "gemini-2.5-pro",
+		"request": {
+			"contents": [
+				{"role": "user", "parts": [{"text": "List files"}]},
+				{"role": "model", "parts": [{"functionCall": {"id": "call_1", "name": "list_directory", "args": {"path": "."}}}]},
+				{"role": "user", "parts": [{"functionResponse": {"id": "call_1", "name": "list_directory", "response": {"output": "main.go"}}}]}
+			]
+		}
+	}`
+
+	entries, err := parser.ParseRequest([]byte(requestBody))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	entry := entries[0]
+	assert.Equal(t, types.LogTypeToolResult, entry.EntryType)
+	assert.Equal(t, "gemini-2.5-pro", entry.Model)
+	assert.Equal(t, "list_directory", entry.ToolName)
+	assert.Equal(t, "call_1", entry.ToolID)
+	assert.JSONEq(t, `{"output": "main.go"}`, entry.ToolOutput)
+}
+
+func TestGeminiParser_ParseResponse_WithFunctionCall(t *testing.T) {
+	parser := NewGeminiParser()
+
+	responseBody := `{
+		"candidates": [{
+			"content": {
+				"role": "model",
+				"parts": [
+					{"text": "Thinking about it", "thought": true},
+					{"text": "I'll read the file first."},
+					{"functionCall": {"name": "read_file", "args": {"absolute_path": "/app/auth.go"}}}
+				]
+			},
+			"finishReason": "STOP"
+		}],
+		"usageMetadata": {"promptTokenCount": 150, "candidatesTokenCount": 50},
+		"modelVersion": "gemini-2.5-pro"
+	}`
+
+	entries, err := parser.ParseResponse([]byte(responseBody))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, types.LogTypeAIText, entries[0].EntryType)
+	assert.Equal(t, "I'll read the file first.", entries[0].Content)
+
+	assert.Equal(t, types.LogTypeToolCall, entries[1].EntryType)
+	assert.Equal(t, "read_file", entries[1].ToolName)
+	assert.Equal(t, "/app/auth.go", entries[1].ToolInput["absolute_path"])
+	assert.Equal(t, "gemini-2.5-pro", entries[1].Model)
+	assert.Equal(t, 150, entries[1].TokensInput)
+	assert.Equal(t, 50, entries[1].TokensOutput)
+}
+
+func TestGeminiParser_ParseResponse_CodeAssistWrapper(t *testing.T) {
+	parser := NewGeminiParser()
+
+	responseBody := `{"response": {"candidates": [{"content": {"role": "model", "parts": [{"text": "Hello"}]}}]}}`
+
+	entries, err := parser.ParseResponse([]byte(responseBody))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "Hello", entries[0].Content)
+}
+
+func TestGeminiParser_ParseResponse_Error(t *testing.T) {
+	parser := NewGeminiParser()
+
+	responseBody := `{
+		"error": {
+			"code": 429,
+			"message": "Resource has been exhausted",
+			"status": "RESOURCE_EXHAUSTED"
+		}
+	}`
+
+	entries, err := parser.ParseResponse([]byte(responseBody))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	assert.Equal(t, types.LogTypeError, entries[0].EntryType)
+	assert.Equal(t, "RESOURCE_EXHAUSTED", entries[0].ErrorCode)
+	assert.Equal(t, "Resource has been exhausted", entries[0].ErrorMessage)
+}
+
+func TestGeminiParser_InvalidJSON(t *testing.T) {
+	parser := NewGeminiParser()
+
+	_, err := parser.ParseRequest([]byte("not valid json"))
+	assert.Error(t, err)
+
+	_, err = parser.ParseResponse([]byte("not valid json"))
+	assert.Error(t, err)
+}